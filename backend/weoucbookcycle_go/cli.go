@@ -0,0 +1,356 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"weoucbookcycle_go/config"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
+
+	"github.com/joho/godotenv"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+// newRootCommand 命令行入口：不带子命令时启动服务（与 serve 相同），
+// db/uploads/redis 子命令用于备份和数据维护，可以直接放进系统定时任务（cron、systemd timer）
+func newRootCommand() *cobra.Command {
+	root := &cobra.Command{
+		Use:          "weoucbookcycle_go",
+		Short:        "WeOUC BookCycle backend",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE:         serve,
+	}
+	root.AddCommand(
+		&cobra.Command{
+			Use:   "serve",
+			Short: "Start the API server and background workers (default)",
+			Args:  cobra.NoArgs,
+			RunE:  serve,
+		},
+		newDBCommand(),
+		newUploadsCommand(),
+		newRedisCommand(),
+	)
+	return root
+}
+
+// serve 启动服务
+func serve(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	runServer(cfg)
+	return nil
+}
+
+// loadConfig 加载 .env 文件并校验配置，之后各组件只从 cfg 读取，不再直接读取环境变量
+func loadConfig() (*config.Config, error) {
+	if err := godotenv.Load(); err != nil {
+		log.Println("⚠️  No .env file found, using system environment variables")
+	} else {
+		log.Println("✅ .env file loaded successfully")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	return cfg, nil
+}
+
+// ==================== 数据库 ====================
+
+func newDBCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db",
+		Short: "Back up and restore the MySQL database",
+	}
+
+	var output string
+	dump := &cobra.Command{
+		Use:   "dump",
+		Short: "Dump the schema and data of every table as SQL",
+		Example: "  weoucbookcycle_go db dump -o /var/backups/bookcycle/db-$(date +%F).sql.gz\n" +
+			"  weoucbookcycle_go db dump -o - | mysql -h replica weoucbookcycle",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, db, err := connectDatabase()
+			if err != nil {
+				return err
+			}
+			defer config.CloseDatabase(db)
+
+			ctx, stop := signalContext(cmd)
+			defer stop()
+
+			path := output
+			if path == "" {
+				path = fmt.Sprintf("%s-%s.sql.gz", cfg.Database.DBName, time.Now().Format("20060102-150405"))
+			}
+			start := time.Now()
+			var stats *services.DumpStats
+			if err := writeArchive(path, func(w io.Writer) (err error) {
+				stats, err = services.DumpDatabase(ctx, db, w)
+				return err
+			}); err != nil {
+				return err
+			}
+			log.Printf("Dumped %d tables (%d rows) to %s in %s", stats.Tables, stats.Rows, path, time.Since(start).Round(time.Millisecond))
+			return nil
+		},
+	}
+	dump.Flags().StringVarP(&output, "output", "o", "", `output file, ".gz" suffix compresses, "-" writes to stdout (default "<db>-<time>.sql.gz")`)
+
+	var yes bool
+	restore := &cobra.Command{
+		Use:   "restore <file>",
+		Short: "Restore a dump created by \"db dump\"",
+		Long: "Restore a dump created by \"db dump\". Every table in the dump is dropped and recreated, " +
+			"so stop the server first. Gzip-compressed dumps are detected automatically; use \"-\" to read from stdin.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !yes {
+				return errors.New("restore replaces existing tables, pass --yes to continue")
+			}
+			in, err := openArchive(args[0])
+			if err != nil {
+				return err
+			}
+			defer in.Close()
+
+			_, db, err := connectDatabase()
+			if err != nil {
+				return err
+			}
+			defer config.CloseDatabase(db)
+
+			ctx, stop := signalContext(cmd)
+			defer stop()
+
+			start := time.Now()
+			executed, err := services.RestoreDatabase(ctx, db, in)
+			if err != nil {
+				return err
+			}
+			log.Printf("Restored %s (%d statements) in %s", args[0], executed, time.Since(start).Round(time.Millisecond))
+			return nil
+		},
+	}
+	restore.Flags().BoolVar(&yes, "yes", false, "confirm that existing tables may be dropped")
+
+	cmd.AddCommand(dump, restore)
+	return cmd
+}
+
+// connectDatabase 加载配置并连接数据库（不做迁移）
+func connectDatabase() (*config.Config, *gorm.DB, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	db, err := config.InitDatabase(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, db, nil
+}
+
+// ==================== 上传文件 ====================
+
+func newUploadsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "uploads",
+		Short: "Back up uploaded files",
+	}
+
+	var output string
+	snapshot := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Archive every uploaded file (local upload directory or object storage bucket) as a tar file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			if err := config.InitializeStorage(&cfg.Storage); err != nil {
+				return fmt.Errorf("failed to initialize object storage: %w", err)
+			}
+			utils.InitStorage(cfg)
+			storage := utils.GetStorage()
+
+			ctx, stop := signalContext(cmd)
+			defer stop()
+
+			path := output
+			if path == "" {
+				path = fmt.Sprintf("uploads-%s.tar.gz", time.Now().Format("20060102-150405"))
+			}
+			start := time.Now()
+			var stats *services.SnapshotStats
+			if err := writeArchive(path, func(w io.Writer) (err error) {
+				stats, err = services.SnapshotStorage(ctx, storage, w)
+				return err
+			}); err != nil {
+				return err
+			}
+			log.Printf("Archived %d files (%d bytes) from %s storage to %s in %s",
+				stats.Files, stats.Bytes, storage.Name(), path, time.Since(start).Round(time.Millisecond))
+			return nil
+		},
+	}
+	snapshot.Flags().StringVarP(&output, "output", "o", "", `output file, ".gz" suffix compresses, "-" writes to stdout (default "uploads-<time>.tar.gz")`)
+
+	cmd.AddCommand(snapshot)
+	return cmd
+}
+
+// ==================== Redis ====================
+
+func newRedisCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "redis",
+		Short: "Maintain Redis data",
+	}
+
+	var dryRun bool
+	prune := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete keys that should have expired but have no TTL",
+		Long: "Delete keys that would otherwise live forever: caches and short-lived keys " +
+			"(rate limits, verification codes, locks) whose TTL was never set, and daily counters " +
+			"and monthly rankings past their retention. Keys with a TTL are left alone.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			redisClient, err := config.InitializeRedis(&cfg.Redis)
+			if err != nil {
+				return err
+			}
+			defer config.CloseRedis(redisClient)
+
+			ctx, stop := signalContext(cmd)
+			defer stop()
+
+			result, err := services.PruneRedis(ctx, redisClient, dryRun)
+			if err != nil {
+				return err
+			}
+			action := "Deleted"
+			if dryRun {
+				action = "Would delete"
+			}
+			reasons := make([]string, 0, len(result.Deleted))
+			var total int64
+			for reason, n := range result.Deleted {
+				reasons = append(reasons, fmt.Sprintf("%s=%d", reason, n))
+				total += n
+			}
+			sort.Strings(reasons)
+			log.Printf("Scanned %d keys. %s %d keys %s", result.Scanned, action, total, strings.Join(reasons, " "))
+			return nil
+		},
+	}
+	prune.Flags().BoolVar(&dryRun, "dry-run", false, "only count the keys that would be deleted")
+
+	cmd.AddCommand(prune)
+	return cmd
+}
+
+// ==================== 辅助方法 ====================
+
+// signalContext 收到 SIGINT/SIGTERM 时取消，中断的备份不会留下文件
+func signalContext(cmd *cobra.Command) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+}
+
+// writeArchive 把 write 的输出写入 path：以 .gz 结尾时压缩，为 - 时写到标准输出
+// 先写入同目录下的临时文件（仅所有者可读），成功后再重命名，失败时不会留下不完整的备份
+func writeArchive(path string, write func(w io.Writer) error) error {
+	if path == "-" {
+		return write(os.Stdout)
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	var w io.Writer = tmp
+	var gz *gzip.Writer
+	if strings.HasSuffix(path, ".gz") {
+		gz = gzip.NewWriter(tmp)
+		w = gz
+	}
+	if err := write(w); err != nil {
+		return err
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// openArchive 打开备份文件，gzip 压缩的文件自动解压，为 - 时读取标准输入
+func openArchive(path string) (io.ReadCloser, error) {
+	var f *os.File
+	if path == "-" {
+		f = os.Stdin
+	} else {
+		var err error
+		if f, err = os.Open(path); err != nil {
+			return nil, err
+		}
+	}
+
+	br := bufio.NewReader(f)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		return &archiveReader{Reader: gz, close: func() error { gz.Close(); return f.Close() }}, nil
+	}
+	return &archiveReader{Reader: br, close: f.Close}, nil
+}
+
+// archiveReader 关闭时同时关闭解压器和文件
+type archiveReader struct {
+	io.Reader
+	close func() error
+}
+
+func (r *archiveReader) Close() error {
+	return r.close()
+}
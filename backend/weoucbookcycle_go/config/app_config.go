@@ -0,0 +1,358 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config 应用配置，启动时由 Load 从环境变量读取一次并校验，之后由 main 注入到各组件
+// 字段的 env 标签为环境变量名，default 标签为变量未设置或为空时使用的值
+type Config struct {
+	// Env 部署环境（development/test/production），用于日志和链路追踪
+	Env     string `env:"API_ENV" default:"development"`
+	APIBase string `env:"API_BASE" default:"http://localhost:8080"`
+	// ShareBase 分享链接和二维码指向的前端地址，为空时使用 API_BASE（由后端托管 Web 前端时）
+	ShareBase string `env:"SHARE_BASE"`
+	// UseCloud 是否使用微信云开发，当前后端只支持自建MySQL
+	UseCloud        bool   `env:"USE_CLOUD" default:"false"`
+	AutoMigrate     bool   `env:"ENABLE_AUTO_MIGRATE" default:"false"`
+	DefaultLanguage string `env:"DEFAULT_LANGUAGE" default:"zh-CN"`
+	// ProcessMode 进程角色：all 同时提供API和处理后台任务，api 只提供API（任务只入队），worker 只处理后台任务
+	ProcessMode string `env:"PROCESS_MODE" default:"all"`
+
+	Server       ServerConfig
+	Database     DatabaseConfig
+	Redis        RedisConfig
+	JWT          JWTConfig
+	SMTP         SMTPConfig
+	Storage      StorageConfig
+	Upload       UploadConfig
+	Moderation   ModerationConfig
+	Push         PushConfig
+	WeChat       WeChatConfig
+	Tracing      TracingConfig
+	Notification NotificationConfig
+	Search       SearchConfig
+	Jobs         JobsConfig
+	Breaker      BreakerConfig
+	Log          LogConfig
+	RateLimit    RateLimitConfig
+	GRPC         GRPCConfig
+	Sentry       SentryConfig
+}
+
+// 进程角色（PROCESS_MODE）
+const (
+	ProcessModeAll    = "all"
+	ProcessModeAPI    = "api"
+	ProcessModeWorker = "worker"
+)
+
+// RedisConfig Redis连接配置
+type RedisConfig struct {
+	Addr     string `env:"REDIS_ADDR" default:"localhost:6379"`
+	Password string `env:"REDIS_PASSWORD"`
+	DB       int    `env:"REDIS_DB" default:"0"`
+}
+
+// SMTPConfig 邮件发送配置
+type SMTPConfig struct {
+	Host      string `env:"SMTP_HOST" default:"smtp.gmail.com"`
+	Port      int    `env:"SMTP_PORT" default:"587"`
+	User      string `env:"SMTP_USER"`
+	Password  string `env:"SMTP_PASSWORD"`
+	FromEmail string `env:"FROM_EMAIL" default:"noreply@weoucbookcycle.com"`
+	FromName  string `env:"FROM_NAME" default:"WeOUC BookCycle"`
+}
+
+// UploadConfig 本地上传、图片处理、配额和病毒扫描配置
+type UploadConfig struct {
+	Path      string `env:"UPLOAD_PATH" default:"./uploads"`
+	PublicURL string `env:"UPLOAD_PUBLIC_URL"`
+	// FileURLSecret 私有文件签名URL的密钥，为空时使用 JWT_SECRET
+	FileURLSecret string `env:"FILE_URL_SECRET"`
+	MinDimension  int    `env:"UPLOAD_MIN_DIMENSION" default:"100"`
+	MaxDimension  int    `env:"UPLOAD_MAX_DIMENSION" default:"10000"`
+	ConvertToWebP bool   `env:"UPLOAD_WEBP" default:"true"`
+	WebPQuality   int    `env:"UPLOAD_WEBP_QUALITY" default:"80"`
+	KeepOriginal  bool   `env:"UPLOAD_KEEP_ORIGINAL" default:"false"`
+	// QuotaMB / QuotaFiles 每个用户的上传配额，0 表示不限制
+	QuotaMB    int `env:"UPLOAD_QUOTA_MB" default:"200"`
+	QuotaFiles int `env:"UPLOAD_QUOTA_FILES" default:"1000"`
+
+	ClamdAddr           string `env:"CLAMD_ADDR"`
+	ClamdTimeoutSeconds int    `env:"CLAMD_TIMEOUT_SECONDS" default:"30"`
+	ClamdFailClosed     bool   `env:"CLAMD_FAIL_CLOSED" default:"false"`
+}
+
+// ModerationConfig 图片内容审核和举报升级配置
+type ModerationConfig struct {
+	ImageProvider  string `env:"IMAGE_MODERATION_PROVIDER"`
+	ImageEndpoint  string `env:"IMAGE_MODERATION_ENDPOINT" default:"http://localhost:5000/classify"`
+	ImageThreshold int    `env:"IMAGE_MODERATION_THRESHOLD" default:"80"`
+
+	AliyunAccessKeyID     string `env:"ALIYUN_ACCESS_KEY_ID"`
+	AliyunAccessKeySecret string `env:"ALIYUN_ACCESS_KEY_SECRET"`
+	AliyunGreenRegion     string `env:"ALIYUN_GREEN_REGION" default:"cn-shanghai"`
+
+	TencentSecretID  string `env:"TENCENT_SECRET_ID"`
+	TencentSecretKey string `env:"TENCENT_SECRET_KEY"`
+	TencentCIBucket  string `env:"TENCENT_CI_BUCKET"`
+	TencentCIRegion  string `env:"TENCENT_CI_REGION" default:"ap-shanghai"`
+
+	// EscalateReports 同一内容被举报达到该次数时自动升级审核，0 表示不自动升级
+	EscalateReports int `env:"MODERATION_ESCALATE_REPORTS" default:"5"`
+}
+
+// PushConfig 移动端和浏览器推送配置
+type PushConfig struct {
+	FCMCredentialsFile string `env:"FCM_CREDENTIALS_FILE"`
+	APNSKeyFile        string `env:"APNS_KEY_FILE"`
+	APNSKeyID          string `env:"APNS_KEY_ID"`
+	APNSTeamID         string `env:"APNS_TEAM_ID"`
+	APNSTopic          string `env:"APNS_TOPIC"`
+	APNSProduction     bool   `env:"APNS_PRODUCTION" default:"false"`
+
+	VAPIDPublicKey  string `env:"VAPID_PUBLIC_KEY"`
+	VAPIDPrivateKey string `env:"VAPID_PRIVATE_KEY"`
+	VAPIDSubject    string `env:"VAPID_SUBJECT" default:"mailto:admin@example.com"`
+}
+
+// WeChatConfig 微信小程序登录和订阅消息配置
+type WeChatConfig struct {
+	AppID               string `env:"WECHAT_APPID"`
+	Secret              string `env:"WECHAT_SECRET"`
+	TemplateChat        string `env:"WECHAT_TEMPLATE_CHAT"`
+	TemplateChatFields  string `env:"WECHAT_TEMPLATE_CHAT_FIELDS" default:"thing1=title,thing2=body,time3=time"`
+	TemplateOrder       string `env:"WECHAT_TEMPLATE_ORDER"`
+	TemplateOrderFields string `env:"WECHAT_TEMPLATE_ORDER_FIELDS" default:"thing1=title,phrase2=status_label,thing3=body"`
+	SubscribePage       string `env:"WECHAT_SUBSCRIBE_PAGE" default:"pages/index/index"`
+	MiniProgramState    string `env:"WECHAT_MINIPROGRAM_STATE" default:"formal"`
+}
+
+// TracingConfig OpenTelemetry 配置
+// 端点只用于判断是否开启导出，导出器本身仍读取标准的 OTEL_EXPORTER_OTLP_* 环境变量
+type TracingConfig struct {
+	ServiceName    string  `env:"OTEL_SERVICE_NAME" default:"weoucbookcycle-api"`
+	SampleRatio    float64 `env:"OTEL_TRACES_SAMPLE_RATIO" default:"1"`
+	Endpoint       string  `env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	TracesEndpoint string  `env:"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"`
+}
+
+// NotificationConfig 通知频率和保存搜索提醒配置
+type NotificationConfig struct {
+	// HourlyCap 每个用户每小时最多收到的通知数，0 表示不限制
+	HourlyCap                  int `env:"NOTIFICATION_HOURLY_CAP" default:"30"`
+	SavedSearchIntervalMinutes int `env:"SAVED_SEARCH_INTERVAL_MINUTES" default:"10"`
+	SavedSearchDailyCap        int `env:"SAVED_SEARCH_DAILY_CAP" default:"5"`
+}
+
+// SearchConfig 搜索配置
+type SearchConfig struct {
+	// CorrectionThreshold 搜索结果少于该数量时尝试给出拼写纠正建议
+	CorrectionThreshold int `env:"SEARCH_CORRECTION_THRESHOLD" default:"3"`
+}
+
+// JobsConfig 持久化后台任务队列（asynq）配置
+type JobsConfig struct {
+	// Concurrency 每个worker进程同时处理的任务数
+	Concurrency int `env:"JOB_CONCURRENCY" default:"10"`
+}
+
+// LogConfig 访问日志配置
+type LogConfig struct {
+	// RedactFields 在默认字段之外需要脱敏的字段名（逗号分隔，不区分大小写），
+	// 查询参数和请求体中同名字段的值记录为 [REDACTED]
+	RedactFields string `env:"LOG_REDACT_FIELDS"`
+	// SampleRules 高频路由的日志采样率，格式为 "路由=比例"，逗号分隔；路由不含版本号，
+	// 如 /api/books/:id=0.1 同时作用于 /api、/api/v1、/api/v2。错误响应和慢请求总是记录
+	SampleRules string `env:"LOG_SAMPLE_RULES" default:"/metrics=0.1,/api/chats/unread=0.1,/api/notifications/unread-count=0.1"`
+	// CaptureBody 在访问日志中记录脱敏后的请求体（最多4KB），只在 GIN_MODE=debug 时生效
+	CaptureBody bool `env:"LOG_CAPTURE_BODY" default:"false"`
+}
+
+// SampleRates 解析 LOG_SAMPLE_RULES，返回 路由 -> 采样率（0~1）
+func (c *LogConfig) SampleRates() (map[string]float64, error) {
+	rates := make(map[string]float64)
+	for _, rule := range strings.Split(c.SampleRules, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		route, raw, ok := strings.Cut(rule, "=")
+		rate, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if !ok || err != nil || rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("invalid rule %q, expected route=rate with rate between 0 and 1", rule)
+		}
+		rates[strings.TrimSpace(route)] = rate
+	}
+	return rates, nil
+}
+
+// RateLimitConfig 接口限流配置，格式为 "次数/单位"（单位 s、m、h），如 "30/m"，留空表示该维度不限流
+// 按令牌桶计数：最多连续请求"次数"次，之后按平均速率放行
+type RateLimitConfig struct {
+	Enabled bool `env:"RATE_LIMIT_ENABLED" default:"true"`
+	// 登录、注册、验证码等接口只按IP计数
+	AuthIP string `env:"RATE_LIMIT_AUTH_IP" default:"20/m"`
+	// 校园网出口IP由很多用户共用，IP维度的上限应明显高于用户维度
+	SearchUser   string `env:"RATE_LIMIT_SEARCH_USER" default:"60/m"`
+	SearchIP     string `env:"RATE_LIMIT_SEARCH_IP" default:"300/m"`
+	ChatSendUser string `env:"RATE_LIMIT_CHAT_SEND_USER" default:"30/m"`
+	ChatSendIP   string `env:"RATE_LIMIT_CHAT_SEND_IP" default:"300/m"`
+}
+
+// ParseRate 解析 "次数/单位" 格式的限流规则，空字符串返回 0
+func ParseRate(spec string) (int, time.Duration, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return 0, 0, nil
+	}
+	rawLimit, unit, ok := strings.Cut(spec, "/")
+	limit, err := strconv.Atoi(strings.TrimSpace(rawLimit))
+	if !ok || err != nil || limit < 1 {
+		return 0, 0, fmt.Errorf("invalid rate %q, expected count/unit such as 30/m", spec)
+	}
+	periods := map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour}
+	period, ok := periods[strings.TrimSpace(unit)]
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid rate unit in %q, expected s, m or h", spec)
+	}
+	return limit, period, nil
+}
+
+// GRPCConfig 内部 gRPC 接口配置，供推荐、审核等内部服务调用，不应暴露到公网
+type GRPCConfig struct {
+	// Addr 监听地址，如 ":9090"，留空不启动
+	Addr string `env:"GRPC_ADDR"`
+	// AuthToken 调用方在 metadata 中携带 "authorization: Bearer <token>"
+	AuthToken string `env:"GRPC_AUTH_TOKEN"`
+}
+
+// SentryConfig 错误上报配置：panic、5xx 响应、后台任务和定时任务的失败上报到 Sentry
+type SentryConfig struct {
+	// DSN 留空时不上报，错误只写日志
+	DSN string `env:"SENTRY_DSN"`
+	// Environment 为空时使用 API_ENV；Release 一般在构建时设置为版本号或提交哈希
+	Environment string `env:"SENTRY_ENVIRONMENT"`
+	Release     string `env:"SENTRY_RELEASE"`
+	// SampleRate 上报事件的采样比例
+	SampleRate float64 `env:"SENTRY_SAMPLE_RATE" default:"1"`
+}
+
+// BreakerConfig Redis、SMTP 熔断器配置
+type BreakerConfig struct {
+	// FailureThreshold 连续失败多少次后熔断，熔断期间调用直接失败不再等待超时
+	FailureThreshold int `env:"BREAKER_FAILURE_THRESHOLD" default:"5"`
+	// OpenTimeout 熔断持续时间，之后放行一次调用探测依赖是否恢复
+	OpenTimeout time.Duration `env:"BREAKER_OPEN_TIMEOUT" default:"15s"`
+}
+
+// RunsAPI 当前进程是否提供HTTP API
+func (c *Config) RunsAPI() bool {
+	return c.ProcessMode != ProcessModeWorker
+}
+
+// RunsWorker 当前进程是否处理后台任务
+func (c *Config) RunsWorker() bool {
+	return c.ProcessMode != ProcessModeAPI
+}
+
+// Load 读取并校验配置
+func Load() (*Config, error) {
+	cfg, err := FromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.JWT.SecretKey == "" {
+		// 非 release 模式允许不配置，使用进程内随机密钥，重启后已签发的token全部失效
+		cfg.JWT.SecretKey = randomSecret()
+		log.Println("⚠️  JWT_SECRET is not set, using a random secret for this process")
+	}
+	return cfg, nil
+}
+
+// FromEnv 按 env/default 标签从环境变量构造配置，不做校验
+// 无法解析的值会汇总到返回的错误中，对应字段保持零值
+func FromEnv() (*Config, error) {
+	cfg := &Config{}
+	err := loadEnv(reflect.ValueOf(cfg).Elem())
+	return cfg, err
+}
+
+// loadEnv 递归填充结构体：带 env 标签的字段从环境变量读取，不带标签的结构体字段继续向下展开
+func loadEnv(v reflect.Value) error {
+	var errs []error
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+		key, ok := field.Tag.Lookup("env")
+		if !ok {
+			if value.Kind() == reflect.Struct {
+				if err := loadEnv(value); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			continue
+		}
+
+		raw := os.Getenv(key)
+		if strings.TrimSpace(raw) == "" {
+			raw = field.Tag.Get("default")
+		}
+		if raw == "" {
+			continue
+		}
+		if err := setValue(value, strings.TrimSpace(raw)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// setValue 按字段类型解析字符串
+func setValue(v reflect.Value, raw string) error {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported config type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/mysql"
+)
+
+// 未设置的变量使用 default 标签，设置的变量按字段类型解析
+func TestFromEnv(t *testing.T) {
+	t.Setenv("SERVER_PORT", "9090")
+	t.Setenv("UPLOAD_WEBP", "false")
+	t.Setenv("OTEL_TRACES_SAMPLE_RATIO", "0.25")
+	t.Setenv("JWT_EXPIRATION", "2h")
+	t.Setenv("REDIS_DB", "")
+
+	cfg, err := FromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Port != "9090" || cfg.Upload.ConvertToWebP || cfg.Tracing.SampleRatio != 0.25 {
+		t.Fatalf("unexpected parsed values: %+v", cfg)
+	}
+	if cfg.JWT.ExpirationTime != 2*time.Hour || cfg.JWT.Issuer != "weoucbookcycle" {
+		t.Fatalf("unexpected jwt config: %+v", cfg.JWT)
+	}
+	if cfg.Redis.DB != 0 || cfg.Database.Charset != "utf8mb4" || cfg.Upload.QuotaMB != 200 {
+		t.Fatalf("expected defaults, got %+v", cfg)
+	}
+
+	t.Setenv("UPLOAD_QUOTA_MB", "lots")
+	if _, err := FromEnv(); err == nil || !strings.Contains(err.Error(), "UPLOAD_QUOTA_MB") {
+		t.Fatalf("expected parse error naming the variable, got %v", err)
+	}
+}
+
+// release 模式下缺少 JWT 密钥时启动失败，其他模式允许
+func TestValidate(t *testing.T) {
+	t.Setenv("GIN_MODE", "release")
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("DB_PASSWORD", "secret")
+
+	cfg, err := FromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "JWT_SECRET") {
+		t.Fatalf("expected missing JWT_SECRET error, got %v", err)
+	}
+
+	cfg.JWT.SecretKey = "release-secret"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+
+	cfg.Server.Mode = "debug"
+	cfg.JWT.SecretKey = ""
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("missing JWT secret should be allowed in debug mode, got %v", err)
+	}
+
+	cfg.Server.Mode = "production"
+	cfg.Server.TLSCertFile = "cert.pem"
+	cfg.ProcessMode = "scheduler"
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "GIN_MODE") || !strings.Contains(err.Error(), "TLS_KEY_FILE") ||
+		!strings.Contains(err.Error(), "PROCESS_MODE") {
+		t.Fatalf("expected all problems to be reported, got %v", err)
+	}
+}
+
+// 启用对象存储时必须配置单独的私有存储桶
+func TestValidateStoragePrivateBucket(t *testing.T) {
+	cfg, err := FromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Storage = StorageConfig{Provider: "s3", Endpoint: "s3.example.com", AccessKey: "ak", SecretKey: "sk", Bucket: "books"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "STORAGE_PRIVATE_BUCKET") {
+		t.Fatalf("expected missing private bucket error, got %v", err)
+	}
+
+	cfg.Storage.PrivateBucket = "books"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "STORAGE_PRIVATE_BUCKET") {
+		t.Fatalf("expected shared bucket error, got %v", err)
+	}
+
+	cfg.Storage.PrivateBucket = "books-private"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+}
+
+// 副本地址未带端口时使用主库端口，未配置副本账号时使用主库账号
+func TestReplicaDialectors(t *testing.T) {
+	cfg := &DatabaseConfig{
+		Port: "3306", User: "app", Password: "pw", DBName: "books", Charset: "utf8mb4",
+		Replicas: "replica-1, replica-2:3307,",
+	}
+
+	dialectors := cfg.replicaDialectors()
+	if len(dialectors) != 2 {
+		t.Fatalf("expected 2 replicas, got %d", len(dialectors))
+	}
+	want := []string{
+		"app:pw@tcp(replica-1:3306)/books?charset=utf8mb4&parseTime=True&loc=Local",
+		"app:pw@tcp(replica-2:3307)/books?charset=utf8mb4&parseTime=True&loc=Local",
+	}
+	for i, d := range dialectors {
+		if got := d.(*mysql.Dialector).DSN; got != want[i] {
+			t.Errorf("replica %d dsn = %s, want %s", i, got, want[i])
+		}
+	}
+
+	cfg.ReplicaUser, cfg.ReplicaPassword = "reader", "ro"
+	if got := cfg.replicaDialectors()[0].(*mysql.Dialector).DSN; !strings.HasPrefix(got, "reader:ro@") {
+		t.Fatalf("expected replica credentials, got %s", got)
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	limit, period, err := ParseRate(" 30/m ")
+	if err != nil || limit != 30 || period != time.Minute {
+		t.Fatalf("ParseRate(30/m) = %d, %s, %v", limit, period, err)
+	}
+	if limit, _, err := ParseRate(""); err != nil || limit != 0 {
+		t.Fatalf("empty rate should disable the limit, got %d, %v", limit, err)
+	}
+	for _, spec := range []string{"30", "0/m", "x/m", "10/d"} {
+		if _, _, err := ParseRate(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
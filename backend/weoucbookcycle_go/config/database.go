@@ -3,46 +3,64 @@ package config
 import (
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
+	"gorm.io/plugin/dbresolver"
 )
 
-var DB *gorm.DB
+// replicaResolver 只读副本在 dbresolver 中注册的名称
+// 只注册为命名解析器：未显式调用 ReadReplica 的查询始终走主库，避免复制延迟影响写后读
+const replicaResolver = "read_replica"
+
+// replicasEnabled 是否配置了只读副本
+var replicasEnabled bool
 
 // DatabaseConfig 数据库配置结构
 type DatabaseConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DBName   string
-	Charset  string
+	Host     string `env:"DB_HOST" default:"localhost"`
+	Port     string `env:"DB_PORT" default:"3306"`
+	User     string `env:"DB_USER" default:"root"`
+	Password string `env:"DB_PASSWORD"`
+	DBName   string `env:"DB_NAME" default:"weoucbookcycle"`
+	Charset  string `env:"DB_CHARSET" default:"utf8mb4"`
+
+	// Replicas 只读副本地址（host 或 host:port，逗号分隔），为空时所有查询都走主库
+	// 副本使用与主库相同的库名，账号密码未单独配置时也与主库相同
+	Replicas        string `env:"DB_REPLICAS"`
+	ReplicaUser     string `env:"DB_REPLICA_USER"`
+	ReplicaPassword string `env:"DB_REPLICA_PASSWORD"`
 }
 
-// GetDatabaseConfig 从环境变量获取数据库配置
-func GetDatabaseConfig() *DatabaseConfig {
-	host := GetEnv("DB_HOST", "localhost")
-	port := GetEnv("DB_PORT", "3306")
-	user := GetEnv("DB_USER", "root")
-	password := GetEnv("DB_PASSWORD", "")
-	dbName := GetEnv("DB_NAME", "weoucbookcycle")
-	charset := GetEnv("DB_CHARSET", "utf8mb4")
+// dsn 生成MySQL连接字符串
+func (c *DatabaseConfig) dsn(host, port, user, password string) string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=%s&parseTime=True&loc=Local",
+		user, password, host, port, c.DBName, c.Charset)
+}
 
-	// 调试输出：显示实际读取的值（不输出明文密码）
-	log.Printf("📋 Database Config Loaded: Host=%s Port=%s User=%s DBName=%s Charset=%s",
-		host, port, user, dbName, charset)
-
-	return &DatabaseConfig{
-		Host:     host,
-		Port:     port,
-		User:     user,
-		Password: password,
-		DBName:   dbName,
-		Charset:  charset,
+// replicaDialectors 按 DB_REPLICAS 生成只读副本的连接
+func (c *DatabaseConfig) replicaDialectors() []gorm.Dialector {
+	user, password := c.ReplicaUser, c.ReplicaPassword
+	if user == "" {
+		user, password = c.User, c.Password
 	}
+
+	var dialectors []gorm.Dialector
+	for _, addr := range strings.Split(c.Replicas, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		host, port, ok := strings.Cut(addr, ":")
+		if !ok {
+			port = c.Port
+		}
+		dialectors = append(dialectors, mysql.Open(c.dsn(host, port, user, password)))
+	}
+	return dialectors
 }
 
 // maskPassword 掩盖密码（只显示前2个字符）
@@ -56,29 +74,23 @@ func maskPassword(pwd string) string {
 	return pwd[:2] + "***"
 }
 
-// InitDatabase 初始化数据库连接
-func InitDatabase() error {
-	config := GetDatabaseConfig()
+// InitDatabase 连接数据库，debug 模式下输出SQL日志
+func InitDatabase(cfg *Config) (*gorm.DB, error) {
+	config := cfg.Database
+	log.Printf("📋 Database Config Loaded: Host=%s Port=%s User=%s DBName=%s Charset=%s",
+		config.Host, config.Port, config.User, config.DBName, config.Charset)
 
 	// 构建MySQL连接字符串
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=%s&parseTime=True&loc=Local",
-		config.User,
-		config.Password,
-		config.Host,
-		config.Port,
-		config.DBName,
-		config.Charset,
-	)
+	dsn := config.dsn(config.Host, config.Port, config.User, config.Password)
 
 	// 配置Gorm日志
 	logLevel := logger.Silent
-	if GetEnv("GIN_MODE", "release") == "debug" {
+	if cfg.Server.Mode == "debug" {
 		logLevel = logger.Info
 	}
 
 	// 连接数据库
-	var err error
-	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
 		Logger: logger.Default.LogMode(logLevel),
 		NowFunc: func() time.Time {
 			return time.Now().Local()
@@ -86,13 +98,13 @@ func InitDatabase() error {
 	})
 
 	if err != nil {
-		return fmt.Errorf("failed to connect to database: %w", err)
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
 	// 获取底层的sql.DB实例
-	sqlDB, err := DB.DB()
+	sqlDB, err := db.DB()
 	if err != nil {
-		return fmt.Errorf("failed to get database instance: %w", err)
+		return nil, fmt.Errorf("failed to get database instance: %w", err)
 	}
 
 	// 设置连接池参数
@@ -100,13 +112,38 @@ func InitDatabase() error {
 	sqlDB.SetMaxOpenConns(100)
 	sqlDB.SetConnMaxLifetime(time.Hour)
 
+	// 注册只读副本，连接池参数与主库相同
+	if replicas := config.replicaDialectors(); len(replicas) > 0 {
+		resolver := dbresolver.Register(dbresolver.Config{Replicas: replicas}, replicaResolver).
+			SetMaxIdleConns(10).
+			SetMaxOpenConns(100).
+			SetConnMaxLifetime(time.Hour)
+		if err := db.Use(resolver); err != nil {
+			return nil, fmt.Errorf("failed to connect to read replicas: %w", err)
+		}
+		replicasEnabled = true
+		log.Printf("✅ Read replicas enabled (%d)", len(replicas))
+	}
+
 	log.Println("✅ Database connected successfully")
-	return nil
+	return db, nil
+}
+
+// ReadReplica 把查询路由到只读副本（随机选择），用于能容忍复制延迟的大查询，如书籍列表、搜索和聊天记录
+// 未配置 DB_REPLICAS 时原样返回；事务内的查询和写操作始终走主库
+func ReadReplica(db *gorm.DB) *gorm.DB {
+	if !replicasEnabled {
+		return db
+	}
+	return db.Clauses(dbresolver.Use(replicaResolver))
 }
 
 // CloseDatabase 关闭数据库连接
-func CloseDatabase() error {
-	sqlDB, err := DB.DB()
+func CloseDatabase(db *gorm.DB) error {
+	if db == nil {
+		return nil
+	}
+	sqlDB, err := db.DB()
 	if err != nil {
 		return err
 	}
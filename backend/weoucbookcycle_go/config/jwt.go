@@ -1,6 +1,8 @@
 package config
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"time"
@@ -10,22 +12,19 @@ import (
 
 // JWTConfig JWT配置结构
 type JWTConfig struct {
-	SecretKey      string
-	ExpirationTime time.Duration
-	Issuer         string
+	// SecretKey release 模式下必须配置，其他模式未配置时由 Load 生成随机密钥
+	SecretKey      string        `env:"JWT_SECRET"`
+	ExpirationTime time.Duration `env:"JWT_EXPIRATION" default:"168h"` // 7天
+	Issuer         string        `env:"JWT_ISSUER" default:"weoucbookcycle"`
 }
 
-// GetJWTConfig 获取JWT配置
-func GetJWTConfig() *JWTConfig {
-	secret := GetEnv("JWT_SECRET", "")
-	if secret == "" {
-		panic("FATAL: JWT_SECRET environment variable is not set. Set it before starting the server.")
-	}
-	return &JWTConfig{
-		SecretKey:      secret,
-		ExpirationTime: time.Hour * 24 * 7, // 7天
-		Issuer:         "weoucbookcycle",
+// randomSecret 生成随机的JWT签名密钥
+func randomSecret() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("FATAL: failed to generate JWT secret: %v", err))
 	}
+	return base64.RawStdEncoding.EncodeToString(b)
 }
 
 // Claims JWT声明结构
@@ -34,6 +33,9 @@ type Claims struct {
 	Username string   `json:"username"`
 	Email    string   `json:"email"`
 	Roles    []string `json:"roles"`
+	// 管理员代登录时设置，ID 为代登录会话ID
+	ImpersonatorID string `json:"impersonator_id,omitempty"`
+	Scope          string `json:"scope,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -43,9 +45,12 @@ type JWTService struct {
 }
 
 // NewJWTService 创建JWT服务实例
-func NewJWTService() *JWTService {
+func NewJWTService(cfg *JWTConfig) *JWTService {
+	if cfg.SecretKey == "" {
+		panic("FATAL: JWT_SECRET environment variable is not set. Set it before starting the server.")
+	}
 	return &JWTService{
-		config: GetJWTConfig(),
+		config: cfg,
 	}
 }
 
@@ -68,6 +73,31 @@ func (s *JWTService) GenerateToken(userID, username, email string, roles []strin
 	return token.SignedString([]byte(s.config.SecretKey))
 }
 
+// GenerateImpersonationToken 生成管理员代登录token
+// token 标记代登录的管理员和会话ID，有效期由调用方指定，不能刷新
+func (s *JWTService) GenerateImpersonationToken(userID, username, email string, roles []string,
+	impersonatorID, scope, sessionID string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := &Claims{
+		UserID:         userID,
+		Username:       username,
+		Email:          email,
+		Roles:          roles,
+		ImpersonatorID: impersonatorID,
+		Scope:          scope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        sessionID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    s.config.Issuer,
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(s.config.SecretKey))
+}
+
 // ValidateToken 验证JWT token
 func (s *JWTService) ValidateToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
@@ -97,17 +127,10 @@ func (s *JWTService) RefreshToken(tokenString string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	if claims.ImpersonatorID != "" {
+		return "", errors.New("impersonation tokens cannot be refreshed")
+	}
 
 	// Token仍有效，允许刷新
 	return s.GenerateToken(claims.UserID, claims.Username, claims.Email, claims.Roles)
 }
-
-// GetJWTService 获取JWT服务实例（全局单例）
-var jwtService *JWTService
-
-func GetJWTService() *JWTService {
-	if jwtService == nil {
-		jwtService = NewJWTService()
-	}
-	return jwtService
-}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
@@ -10,28 +11,17 @@ import (
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9" // 使用最新的 go-redis/v9
+	"github.com/sony/gobreaker/v2"
+	"gorm.io/gorm"
 )
 
-// RedisClient 全局 Redis 客户端实例
-var RedisClient *redis.Client
-
-// InitializeRedis 初始化 Redis 客户端
-func InitializeRedis() error {
-	redisAddr := GetEnv("REDIS_ADDR", "localhost:6379")
-	redisPassword := GetEnv("REDIS_PASSWORD", "")
-	redisDB := GetEnv("REDIS_DB", "0")
-
-	// 解析数据库编号
-	db := 0
-	if redisDB != "" {
-		fmt.Sscanf(redisDB, "%d", &db)
-	}
-
+// InitializeRedis 创建 Redis 客户端并测试连接
+func InitializeRedis(cfg *RedisConfig) (*redis.Client, error) {
 	// 创建Redis客户端
-	RedisClient = redis.NewClient(&redis.Options{
-		Addr:         redisAddr,
-		Password:     redisPassword,
-		DB:           db,
+	client := redis.NewClient(&redis.Options{
+		Addr:         cfg.Addr,
+		Password:     cfg.Password,
+		DB:           cfg.DB,
 		PoolSize:     10,              // 连接池大小
 		MinIdleConns: 5,               // 最小空闲连接
 		MaxRetries:   3,               // 最大重试次数
@@ -45,47 +35,61 @@ func InitializeRedis() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err := RedisClient.Ping(ctx).Err(); err != nil {
-		return fmt.Errorf("failed to connect to Redis: %w", err)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
 	log.Println("✅ Redis client initialized successfully")
-	return nil
+	return client, nil
 }
 
 // CloseRedis 关闭 Redis 连接
-func CloseRedis() error {
-	if RedisClient != nil {
-		return RedisClient.Close()
+func CloseRedis(client *redis.Client) error {
+	if client != nil {
+		return client.Close()
 	}
 	return nil
 }
 
 // ServerConfig 服务器配置结构
 type ServerConfig struct {
-	Port         string
-	Mode         string
-	ReadTimeout  int
-	WriteTimeout int
-	RedisEnabled bool // Redis是否启用
-}
-
-// GetServerConfig 获取服务器配置
-func GetServerConfig() *ServerConfig {
-	redisEnabled := GetEnv("REDIS_ENABLED", "true") == "true"
-
-	return &ServerConfig{
-		Port:         GetEnv("SERVER_PORT", "8080"),
-		Mode:         GetEnv("GIN_MODE", "debug"),
-		ReadTimeout:  30,
-		WriteTimeout: 30,
-		RedisEnabled: redisEnabled,
-	}
+	Port string `env:"SERVER_PORT" default:"8080"`
+	// Mode Gin 运行模式：debug/release/test
+	Mode         string `env:"GIN_MODE" default:"debug"`
+	RedisEnabled bool   `env:"REDIS_ENABLED" default:"true"` // Redis是否启用
+	// ShutdownTimeout 优雅关闭时等待请求和队列处理完成的秒数
+	ShutdownTimeout int `env:"SHUTDOWN_TIMEOUT_SECONDS" default:"15"`
+
+	// TLSCertFile / TLSKeyFile 同时配置时以 HTTPS 启动
+	TLSCertFile string `env:"TLS_CERT_FILE"`
+	TLSKeyFile  string `env:"TLS_KEY_FILE"`
+
+	// DisableCORS 关闭 CORS 中间件；AllowOrigins 为逗号分隔的允许域列表，"*" 表示所有
+	DisableCORS  bool   `env:"DISABLE_CORS" default:"false"`
+	AllowOrigins string `env:"ALLOW_ORIGINS" default:"http://localhost:3000,http://localhost:5173,http://localhost:4173"`
+
+	// ServeWeb 由后端托管 Web 前端的静态文件
+	ServeWeb    bool   `env:"SERVE_WEB" default:"false"`
+	WebDistPath string `env:"WEB_DIST_PATH" default:"../frontend/web/dist"`
+
+	// MetricsToken 设置后 /metrics 需要 Authorization: Bearer <token>
+	MetricsToken string `env:"METRICS_TOKEN"`
+
+	// ServeDocs 在 /docs 提供 Swagger UI 和 OpenAPI 文档（/docs/doc.json）
+	ServeDocs bool `env:"SERVE_DOCS" default:"false"`
+
+	// RequestTimeout / MaxBodyBytes API请求的处理超时和请求体上限，超过时返回408/413
+	RequestTimeout time.Duration `env:"REQUEST_TIMEOUT" default:"15s"`
+	MaxBodyBytes   int64         `env:"MAX_BODY_BYTES" default:"1048576"`
+	// UploadTimeout / MaxUploadBytes 上传和文件访问接口的超时和请求体上限
+	UploadTimeout  time.Duration `env:"UPLOAD_TIMEOUT" default:"2m"`
+	MaxUploadBytes int64         `env:"MAX_UPLOAD_BYTES" default:"52428800"`
 }
 
-// SetupRouter 设置路由
-func SetupRouter() *gin.Engine {
-	serverConfig := GetServerConfig()
+// SetupRouter 设置路由，db 和 redisClient 用于健康检查
+func SetupRouter(cfg *Config, db *gorm.DB, redisClient *redis.Client) *gin.Engine {
+	serverConfig := cfg.Server
 
 	// 根据环境设置Gin模式
 	gin.SetMode(serverConfig.Mode)
@@ -94,13 +98,13 @@ func SetupRouter() *gin.Engine {
 	r := gin.New()
 
 	// 全局中间件
-	r.Use(gin.Recovery()) // 恢复panic
+	r.Use(gin.Recovery()) // 兜底恢复panic，处理器中的panic由 middleware.Recovery 恢复并上报
 	r.Use(gin.Logger())   // 日志记录
 
 	// CORS配置（可以通过环境变量 DISABLE_CORS=true 关闭，ALLOW_ORIGINS 指定允许的域列表）
-	if GetEnv("DISABLE_CORS", "false") != "true" {
-		// origins 用逗号分隔列表，空值或"*"表示允许所有
-		allowList := GetEnv("ALLOW_ORIGINS", "http://localhost:3000,http://localhost:5173,http://localhost:4173")
+	if !serverConfig.DisableCORS {
+		// origins 用逗号分隔列表，"*"表示允许所有
+		allowList := serverConfig.AllowOrigins
 		origins := []string{}
 		if allowList == "*" || allowList == "" {
 			origins = []string{"*"}
@@ -110,10 +114,11 @@ func SetupRouter() *gin.Engine {
 			}
 		}
 		r.Use(cors.New(cors.Config{
-			AllowOrigins:     origins,
-			AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-			AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-Requested-With"},
-			ExposeHeaders:    []string{"Content-Length", "Content-Type"},
+			AllowOrigins: origins,
+			AllowMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
+			AllowHeaders: []string{"Origin", "Content-Type", "Authorization", "X-Requested-With", "Idempotency-Key", "If-None-Match"},
+			ExposeHeaders: []string{"Content-Length", "Content-Type", "Idempotent-Replayed", "API-Version", "ETag",
+				"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "Retry-After"},
 			AllowCredentials: true,
 			MaxAge:           12 * time.Hour,
 		}))
@@ -122,12 +127,11 @@ func SetupRouter() *gin.Engine {
 	}
 
 	// 打印当前环境（API 环境）以便排查
-	apiEnv := GetEnv("API_ENV", "development")
-	log.Printf("API_ENV=%s, GIN_MODE=%s", apiEnv, serverConfig.Mode)
+	log.Printf("API_ENV=%s, GIN_MODE=%s", cfg.Env, serverConfig.Mode)
 
 	// 可选：如果后端也需要托管 Web 前端（默认false），可以通过环境变量开启
-	if GetEnv("SERVE_WEB", "false") == "true" {
-		webPath := GetEnv("WEB_DIST_PATH", "../frontend/web/dist")
+	if serverConfig.ServeWeb {
+		webPath := serverConfig.WebDistPath
 		log.Printf("Serving static web files from %s", webPath)
 		r.StaticFS("/", gin.Dir(webPath, false))
 	}
@@ -140,8 +144,8 @@ func SetupRouter() *gin.Engine {
 		}
 
 		// 检查数据库状态
-		if DB != nil {
-			sqlDB, err := DB.DB()
+		if db != nil {
+			sqlDB, err := db.DB()
 			if err == nil {
 				if err := sqlDB.Ping(); err == nil {
 					health["database"] = "connected"
@@ -156,11 +160,14 @@ func SetupRouter() *gin.Engine {
 		}
 
 		// 检查Redis状态
-		if RedisClient != nil {
+		if redisClient != nil {
 			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 			defer cancel()
-			if err := RedisClient.Ping(ctx).Err(); err == nil {
+			if err := redisClient.Ping(ctx).Err(); err == nil {
 				health["redis"] = "connected"
+			} else if errors.Is(err, gobreaker.ErrOpenState) {
+				// 熔断中，探测恢复前不会真正连接Redis
+				health["redis"] = "circuit open"
 			} else {
 				health["redis"] = "disconnected"
 			}
@@ -181,20 +188,7 @@ func SetupRouter() *gin.Engine {
 	return r
 }
 
-// StartServer 已弃用，请在 main.go 中进行资源初始化
-// 保留该函数以兼容旧版本调用
-func StartServer() error {
-	log.Println("⚠️  警告: StartServer() 已弃用，请在 main.go 初始化资源。")
-	return nil
-}
-
 // GetServer 获取Gin实例（用于测试）
-func GetServer() *gin.Engine {
-	return SetupRouter()
-}
-
-// GetRedisClient 获取Redis客户端实例（供其他包使用）
-// 这个函数可以在控制器中调用，而不是每个controller都自己创建redis客户端
-func GetRedisClient() *redis.Client {
-	return RedisClient
+func GetServer(cfg *Config, db *gorm.DB, redisClient *redis.Client) *gin.Engine {
+	return SetupRouter(cfg, db, redisClient)
 }
@@ -1,44 +1,118 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"log"
-	"os"
 )
 
 /**
- * ValidateRequiredEnv 验证必需的环境变量
+ * Validate 校验配置，在启动时尽早发现错误
  *
- * 检查以下环境变量：
- * - JWT_SECRET: JWT签名密钥（必需）
- * - DB_HOST: 数据库主机（必需）
- * - DB_USER: 数据库用户（必需）
- * - DB_PASSWORD: 数据库密码（必需）
- * - DB_NAME: 数据库名称（必需）
- * - REDIS_HOST: Redis主机（可选，作为缓存）
+ * 检查以下内容：
+ * - GIN_MODE: 只能是 debug/release/test
+ * - JWT_SECRET、DB_PASSWORD: release 模式下必须设置
+ * - DB_HOST/DB_USER/DB_NAME、SERVER_PORT: 不能为空
+ * - TLS_CERT_FILE 与 TLS_KEY_FILE: 需同时设置
+ * - OTEL_TRACES_SAMPLE_RATIO: 取值 0~1
+ * - PROCESS_MODE: 只能是 all/api/worker；JOB_CONCURRENCY 至少为1
+ * - BREAKER_FAILURE_THRESHOLD 至少为1；BREAKER_OPEN_TIMEOUT 必须大于0
+ * - REQUEST_TIMEOUT、UPLOAD_TIMEOUT、MAX_BODY_BYTES、MAX_UPLOAD_BYTES 必须大于0
+ * - LOG_SAMPLE_RULES: 每条规则为 路由=比例，比例在 0~1 之间
+ * - RATE_LIMIT_*: 次数/单位（s、m、h）或留空
+ * - GRPC_AUTH_TOKEN: 设置了 GRPC_ADDR 时必须设置
+ * - SENTRY_SAMPLE_RATE: 取值 0~1
+ * - STORAGE_PRIVATE_BUCKET: 启用对象存储时必须设置，且不能与 STORAGE_BUCKET 相同
  *
- * @return error 如果缺少必需的环境变量，返回错误
+ * @return error 汇总所有不合法的配置项
  */
-func ValidateRequiredEnv() error {
-	requiredEnvs := []string{
-		"JWT_SECRET",
-		"DB_HOST",
-		"DB_USER",
-		"DB_PASSWORD",
-		"DB_NAME",
-	}
-
-	var missingEnvs []string
-	for _, env := range requiredEnvs {
-		if value := os.Getenv(env); value == "" {
-			missingEnvs = append(missingEnvs, env)
+func (c *Config) Validate() error {
+	var errs []error
+
+	switch c.Server.Mode {
+	case "debug", "release", "test":
+	default:
+		errs = append(errs, fmt.Errorf("GIN_MODE: must be debug, release or test, got %q", c.Server.Mode))
+	}
+
+	if c.Server.Mode == "release" {
+		if c.JWT.SecretKey == "" {
+			errs = append(errs, errors.New("JWT_SECRET: required in release mode"))
+		}
+		if c.Database.Password == "" {
+			errs = append(errs, errors.New("DB_PASSWORD: required in release mode"))
 		}
 	}
 
-	if len(missingEnvs) > 0 {
-		return fmt.Errorf("缺少必需的环境变量: %v", missingEnvs)
+	for _, field := range []struct{ key, value string }{
+		{"DB_HOST", c.Database.Host},
+		{"DB_USER", c.Database.User},
+		{"DB_NAME", c.Database.DBName},
+		{"SERVER_PORT", c.Server.Port},
+	} {
+		if field.value == "" {
+			errs = append(errs, fmt.Errorf("%s: required", field.key))
+		}
 	}
 
+	if (c.Server.TLSCertFile == "") != (c.Server.TLSKeyFile == "") {
+		errs = append(errs, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together"))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("OTEL_TRACES_SAMPLE_RATIO: must be between 0 and 1, got %v", c.Tracing.SampleRatio))
+	}
+
+	switch c.ProcessMode {
+	case ProcessModeAll, ProcessModeAPI, ProcessModeWorker:
+	default:
+		errs = append(errs, fmt.Errorf("PROCESS_MODE: must be all, api or worker, got %q", c.ProcessMode))
+	}
+	if c.Jobs.Concurrency < 1 {
+		errs = append(errs, fmt.Errorf("JOB_CONCURRENCY: must be at least 1, got %d", c.Jobs.Concurrency))
+	}
+	if c.Server.RequestTimeout <= 0 || c.Server.UploadTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("REQUEST_TIMEOUT and UPLOAD_TIMEOUT: must be positive, got %s and %s", c.Server.RequestTimeout, c.Server.UploadTimeout))
+	}
+	if c.Server.MaxBodyBytes <= 0 || c.Server.MaxUploadBytes <= 0 {
+		errs = append(errs, fmt.Errorf("MAX_BODY_BYTES and MAX_UPLOAD_BYTES: must be positive, got %d and %d", c.Server.MaxBodyBytes, c.Server.MaxUploadBytes))
+	}
+	if _, err := c.Log.SampleRates(); err != nil {
+		errs = append(errs, fmt.Errorf("LOG_SAMPLE_RULES: %w", err))
+	}
+	for _, rate := range []struct{ key, value string }{
+		{"RATE_LIMIT_AUTH_IP", c.RateLimit.AuthIP},
+		{"RATE_LIMIT_SEARCH_USER", c.RateLimit.SearchUser},
+		{"RATE_LIMIT_SEARCH_IP", c.RateLimit.SearchIP},
+		{"RATE_LIMIT_CHAT_SEND_USER", c.RateLimit.ChatSendUser},
+		{"RATE_LIMIT_CHAT_SEND_IP", c.RateLimit.ChatSendIP},
+	} {
+		if _, _, err := ParseRate(rate.value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", rate.key, err))
+		}
+	}
+	if c.GRPC.Addr != "" && c.GRPC.AuthToken == "" {
+		errs = append(errs, errors.New("GRPC_AUTH_TOKEN: required when GRPC_ADDR is set"))
+	}
+
+	if c.Sentry.SampleRate < 0 || c.Sentry.SampleRate > 1 {
+		errs = append(errs, fmt.Errorf("SENTRY_SAMPLE_RATE: must be between 0 and 1, got %v", c.Sentry.SampleRate))
+	}
+
+	// 私有文件和隔离图片放在公开存储桶中可以直接通过URL访问
+	if c.Storage.Enabled() && (c.Storage.PrivateBucket == "" || c.Storage.PrivateBucket == c.Storage.Bucket) {
+		errs = append(errs, errors.New("STORAGE_PRIVATE_BUCKET: required when object storage is enabled and must differ from STORAGE_BUCKET"))
+	}
+
+	if c.Breaker.FailureThreshold < 1 {
+		errs = append(errs, fmt.Errorf("BREAKER_FAILURE_THRESHOLD: must be at least 1, got %d", c.Breaker.FailureThreshold))
+	}
+	if c.Breaker.OpenTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("BREAKER_OPEN_TIMEOUT: must be positive, got %s", c.Breaker.OpenTimeout))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("配置校验失败: %w", errors.Join(errs...))
+	}
 	return nil
 }
 
@@ -103,33 +177,29 @@ func ValidateRedis(redisClient interface{}) error {
  *
  * 输出应用启动时的配置信息，便于调试和监控
  *
- * @param port string 应用监听端口
- * @param apiBase string API基础路径
- * @param useCloud bool 是否使用云函数
+ * @param cfg *Config 已加载的配置
  */
-func PrintStartupInfo(port string, apiBase string, useCloud bool) {
+func PrintStartupInfo(cfg *Config) {
 	fmt.Println("\n========== Book Cycle Application ==========")
-	fmt.Printf("📍 Server Port: %s\n", port)
-	fmt.Printf("🔗 API Base: %s\n", apiBase)
+	fmt.Printf("📍 Server Port: %s\n", cfg.Server.Port)
+	fmt.Printf("🔗 API Base: %s\n", cfg.APIBase)
+	fmt.Printf("⚙️  Process Mode: %s\n", cfg.ProcessMode)
 
-	if useCloud {
+	if cfg.UseCloud {
 		fmt.Println("☁️  Cloud Functions: ENABLED")
 	} else {
 		fmt.Println("☁️  Cloud Functions: DISABLED")
 	}
 
-	fmt.Printf("🔐 JWT Secret: %s\n", maskString(os.Getenv("JWT_SECRET")))
+	fmt.Printf("🔐 JWT Secret: %s\n", maskString(cfg.JWT.SecretKey))
 	fmt.Printf("🗄️  Database: %s@%s/%s\n",
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_HOST"),
-		os.Getenv("DB_NAME"),
+		cfg.Database.User,
+		cfg.Database.Host,
+		cfg.Database.DBName,
 	)
 
-	if os.Getenv("REDIS_HOST") != "" {
-		fmt.Printf("💾 Redis: %s:%s\n",
-			os.Getenv("REDIS_HOST"),
-			os.Getenv("REDIS_PORT"),
-		)
+	if cfg.Server.RedisEnabled {
+		fmt.Printf("💾 Redis: %s\n", cfg.Redis.Addr)
 	}
 
 	fmt.Println("=============================================")
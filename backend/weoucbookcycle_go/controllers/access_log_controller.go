@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxAccessLogWindow 查询时间窗口上限，流中也只保留最近的10万条
+const maxAccessLogWindow = 7 * 24 * 60
+
+// AccessLogController 访问日志控制器（管理员）
+type AccessLogController struct {
+	accessLogService *services.AccessLogService
+}
+
+// NewAccessLogController 创建访问日志控制器实例
+func NewAccessLogController(accessLogService *services.AccessLogService) *AccessLogController {
+	return &AccessLogController{
+		accessLogService: accessLogService,
+	}
+}
+
+// windowStart 解析 minutes 参数（默认60分钟）
+func (ac *AccessLogController) windowStart(c *gin.Context) time.Time {
+	minutes, _ := strconv.Atoi(c.DefaultQuery("minutes", "60"))
+	if minutes < 1 || minutes > maxAccessLogWindow {
+		minutes = 60
+	}
+	return time.Now().Add(-time.Duration(minutes) * time.Minute)
+}
+
+// ListAccessLogs 查询访问日志
+// @Summary 查询访问日志
+// @Description 从 access_logs 流中倒序查询近期请求，使用 cursor 向前翻页
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param path query string false "请求路径，以 * 结尾按前缀匹配，如 /api/books*"
+// @Param method query string false "请求方法"
+// @Param status query string false "状态码，如 404 或 5xx"
+// @Param user_id query string false "用户ID"
+// @Param trace_id query string false "trace ID（响应头 X-Trace-ID）"
+// @Param min_latency_ms query int false "最小耗时（毫秒）"
+// @Param minutes query int false "最近多少分钟" default(60)
+// @Param cursor query string false "上一页返回的 next_cursor"
+// @Param limit query int false "每页数量" default(50)
+// @Success 200 {object} utils.PageResponse{data=[]services.AccessLogEntry}
+// @Router /api/admin/access-logs [get]
+func (ac *AccessLogController) ListAccessLogs(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+	minLatency, _ := strconv.ParseInt(c.Query("min_latency_ms"), 10, 64)
+
+	page, err := ac.accessLogService.Query(c.Request.Context(), &services.AccessLogQuery{
+		Path:       c.Query("path"),
+		Method:     c.Query("method"),
+		Status:     c.Query("status"),
+		UserID:     c.Query("user_id"),
+		TraceID:    c.Query("trace_id"),
+		MinLatency: minLatency,
+		Since:      ac.windowStart(c),
+		Cursor:     c.Query("cursor"),
+		Limit:      limit,
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// meta.scanned 为本次检查的日志条数
+	utils.WritePage(c, page.Logs, utils.NewCursorPagination(c, limit, page.NextCursor), gin.H{"scanned": page.Scanned})
+}
+
+// GetAccessLogSummary 访问汇总
+// @Summary 访问汇总
+// @Description 时间窗口内的整体错误率、最慢的端点（按p95）和5xx比例最高的端点，路径中的ID合并为 :id
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param minutes query int false "最近多少分钟" default(60)
+// @Param top query int false "每个排行返回的端点数" default(10)
+// @Success 200 {object} services.AccessLogSummary
+// @Router /api/admin/access-logs/summary [get]
+func (ac *AccessLogController) GetAccessLogSummary(c *gin.Context) {
+	top, _ := strconv.Atoi(c.DefaultQuery("top", "10"))
+	if top < 1 || top > 50 {
+		top = 10
+	}
+
+	summary, err := ac.accessLogService.Summary(c.Request.Context(), ac.windowStart(c), top)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 20000, "message": "Success", "data": summary})
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"net/http"
+	"weoucbookcycle_go/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccountController 账号停用控制器
+type AccountController struct {
+	accountService *services.AccountService
+}
+
+// NewAccountController 创建账号控制器实例
+func NewAccountController(accountService *services.AccountService) *AccountController {
+	return &AccountController{
+		accountService: accountService,
+	}
+}
+
+// Deactivate 停用账号
+// @Summary 停用账号
+// @Description 暂时停用自己的账号：资料、书籍和发布对他人隐藏，不再收到通知和推送，其他人也不能发起新的聊天。
+// @Description 与注销不同，数据全部保留，停用期间仍可登录，调用重新启用接口即可恢复
+// @Tags users
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} map[string]interface{}
+// @Router /api/users/me/deactivate [post]
+func (ac *AccountController) Deactivate(c *gin.Context) {
+	user, err := ac.accountService.Deactivate(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Account deactivated",
+		"data":    user,
+	})
+}
+
+// Reactivate 重新启用账号
+// @Summary 重新启用账号
+// @Tags users
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} map[string]interface{}
+// @Router /api/users/me/reactivate [post]
+func (ac *AccountController) Reactivate(c *gin.Context) {
+	user, err := ac.accountService.Reactivate(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Account reactivated",
+		"data":    user,
+	})
+}
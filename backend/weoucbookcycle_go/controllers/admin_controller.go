@@ -0,0 +1,360 @@
+package controllers
+
+import (
+	"net/http"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminController 管理后台控制器（管理员）
+type AdminController struct {
+	adminService *services.AdminService
+}
+
+// NewAdminController 创建管理后台控制器实例
+func NewAdminController(adminService *services.AdminService) *AdminController {
+	return &AdminController{
+		adminService: adminService,
+	}
+}
+
+// parseQuery 解析通用的分页和筛选参数
+func (ac *AdminController) parseQuery(c *gin.Context) *services.AdminQuery {
+	page, limit := utils.PageParams(c, utils.DefaultPageLimit)
+	return &services.AdminQuery{
+		Page:    page,
+		Limit:   limit,
+		Keyword: c.Query("keyword"),
+		Status:  c.Query("status"),
+		UserID:  c.Query("user_id"),
+	}
+}
+
+// ==================== 用户管理 ====================
+
+// ListUsers 用户列表
+// @Summary 用户列表
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param keyword query string false "用户名或邮箱"
+// @Param status query int false "状态: 1=正常, 0=禁用"
+// @Param role query string false "角色: user, admin"
+// @Param page query int false "页码" default(1)
+// @Param limit query int false "每页数量" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/users [get]
+func (ac *AdminController) ListUsers(c *gin.Context) {
+	q := ac.parseQuery(c)
+	users, total, err := ac.adminService.ListUsers(q, c.Query("role"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	utils.Paginate(c, users, total, q.Page, q.Limit)
+}
+
+// UpdateUserStatusRequest 修改用户状态请求
+type UpdateUserStatusRequest struct {
+	Status *int `json:"status" binding:"required,oneof=0 1"`
+}
+
+// UpdateUserStatus 封禁/解封用户
+// @Summary 封禁或解封用户
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "用户ID"
+// @Param request body UpdateUserStatusRequest true "状态: 1=正常, 0=禁用"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/users/{id}/status [put]
+func (ac *AdminController) UpdateUserStatus(c *gin.Context) {
+	var req UpdateUserStatusRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	user, err := ac.adminService.SetUserStatus(c.Request.Context(), c.GetString("user_id"), c.Param("id"), *req.Status)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 20000, "message": "User status updated", "data": user})
+}
+
+// UpdateUserRoleRequest 修改用户角色请求
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=user admin"`
+}
+
+// UpdateUserRole 修改用户角色
+// @Summary 修改用户角色
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "用户ID"
+// @Param request body UpdateUserRoleRequest true "角色"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/users/{id}/role [put]
+func (ac *AdminController) UpdateUserRole(c *gin.Context) {
+	var req UpdateUserRoleRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	user, err := ac.adminService.SetUserRole(c.GetString("user_id"), c.Param("id"), req.Role)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 20000, "message": "User role updated", "data": user})
+}
+
+// ==================== 书籍管理 ====================
+
+// ListBooks 书籍列表
+// @Summary 书籍列表（含已下架）
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param keyword query string false "书名/作者/ISBN"
+// @Param status query int false "状态: 1=可售, 0=已售, 2=下架"
+// @Param user_id query string false "卖家ID"
+// @Param page query int false "页码" default(1)
+// @Param limit query int false "每页数量" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/books [get]
+func (ac *AdminController) ListBooks(c *gin.Context) {
+	q := ac.parseQuery(c)
+	books, total, err := ac.adminService.ListBooks(q)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	utils.Paginate(c, books, total, q.Page, q.Limit)
+}
+
+// UpdateBookStatusRequest 修改书籍状态请求
+type UpdateBookStatusRequest struct {
+	Status *int `json:"status" binding:"required,oneof=0 1 2"`
+}
+
+// UpdateBookStatus 修改书籍状态（如下架违规书籍）
+// @Summary 修改书籍状态
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "书籍ID"
+// @Param request body UpdateBookStatusRequest true "状态: 1=可售, 0=已售, 2=下架"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/books/{id}/status [put]
+func (ac *AdminController) UpdateBookStatus(c *gin.Context) {
+	var req UpdateBookStatusRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	book, err := ac.adminService.SetBookStatus(c.Request.Context(), c.Param("id"), *req.Status)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 20000, "message": "Book status updated", "data": book})
+}
+
+// DeleteBook 删除书籍
+// @Summary 删除书籍
+// @Description 软删除书籍并取消其未完成的发布
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param id path string true "书籍ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/books/{id} [delete]
+func (ac *AdminController) DeleteBook(c *gin.Context) {
+	if err := ac.adminService.DeleteBook(c.Request.Context(), c.Param("id")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 20000, "message": "Book deleted"})
+}
+
+// ==================== 发布管理 ====================
+
+// ListListings 发布列表
+// @Summary 发布列表
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param status query string false "状态: available, reserved, sold, cancelled"
+// @Param user_id query string false "卖家ID"
+// @Param page query int false "页码" default(1)
+// @Param limit query int false "每页数量" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/listings [get]
+func (ac *AdminController) ListListings(c *gin.Context) {
+	q := ac.parseQuery(c)
+	listings, total, err := ac.adminService.ListListings(q)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	utils.Paginate(c, listings, total, q.Page, q.Limit)
+}
+
+// AdminListingStatusRequest 修改发布状态请求
+type AdminListingStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=available reserved sold cancelled"`
+}
+
+// UpdateListingStatus 强制修改发布状态
+// @Summary 修改发布状态
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "发布ID"
+// @Param request body AdminListingStatusRequest true "状态"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/listings/{id}/status [put]
+func (ac *AdminController) UpdateListingStatus(c *gin.Context) {
+	var req AdminListingStatusRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	listing, err := ac.adminService.SetListingStatus(c.Request.Context(), c.Param("id"), req.Status)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 20000, "message": "Listing status updated", "data": listing})
+}
+
+// ==================== 聊天管理 ====================
+
+// ListChats 会话列表
+// @Summary 会话列表
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param user_id query string false "参与者ID"
+// @Param page query int false "页码" default(1)
+// @Param limit query int false "每页数量" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/chats [get]
+func (ac *AdminController) ListChats(c *gin.Context) {
+	q := ac.parseQuery(c)
+	chats, total, err := ac.adminService.ListChats(q)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	utils.Paginate(c, chats, total, q.Page, q.Limit)
+}
+
+// GetChatMessages 查看会话消息
+// @Summary 查看会话消息
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param id path string true "会话ID"
+// @Param page query int false "页码" default(1)
+// @Param limit query int false "每页数量" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/chats/{id}/messages [get]
+func (ac *AdminController) GetChatMessages(c *gin.Context) {
+	q := ac.parseQuery(c)
+	messages, total, err := ac.adminService.GetChatMessages(c.Param("id"), q)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	utils.Paginate(c, messages, total, q.Page, q.Limit)
+}
+
+// DeleteMessage 删除消息
+// @Summary 删除违规消息
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param id path string true "消息ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/messages/{id} [delete]
+func (ac *AdminController) DeleteMessage(c *gin.Context) {
+	if err := ac.adminService.DeleteMessage(c.Param("id")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 20000, "message": "Message deleted"})
+}
+
+// ==================== 举报管理 ====================
+
+// ListReports 举报列表
+// @Summary 举报列表
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param status query string false "状态: pending, resolved, dismissed"
+// @Param target_type query string false "对象类型: user, book, listing, chat, message"
+// @Param page query int false "页码" default(1)
+// @Param limit query int false "每页数量" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/reports [get]
+func (ac *AdminController) ListReports(c *gin.Context) {
+	q := ac.parseQuery(c)
+	reports, total, err := ac.adminService.ListReports(q, c.Query("target_type"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	utils.Paginate(c, reports, total, q.Page, q.Limit)
+}
+
+// HandleReportRequest 处理举报请求
+type HandleReportRequest struct {
+	Status     string `json:"status" binding:"required,oneof=resolved dismissed"`
+	Resolution string `json:"resolution" binding:"max=500"`
+}
+
+// HandleReport 处理举报
+// @Summary 处理举报
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "举报ID"
+// @Param request body HandleReportRequest true "处理结果"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/reports/{id} [put]
+func (ac *AdminController) HandleReport(c *gin.Context) {
+	var req HandleReportRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	report, err := ac.adminService.HandleReport(c.GetString("user_id"), c.Param("id"), req.Status, req.Resolution)
+	if err != nil {
+		c.Error(utils.WithDefaultStatus(err, http.StatusConflict))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 20000, "message": "Report handled", "data": report})
+}
@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"net/http"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AnnouncementController 公告控制器
+type AnnouncementController struct {
+	announcementService *services.AnnouncementService
+}
+
+// NewAnnouncementController 创建公告控制器实例
+func NewAnnouncementController(announcementService *services.AnnouncementService) *AnnouncementController {
+	return &AnnouncementController{
+		announcementService: announcementService,
+	}
+}
+
+// GetActiveAnnouncements 获取当前公告
+// @Summary 获取当前公告
+// @Description 返回正在展示期内的全站公告，前端轮询后显示为横幅，critical 在前
+// @Tags announcements
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/announcements [get]
+func (ac *AnnouncementController) GetActiveAnnouncements(c *gin.Context) {
+	announcements, err := ac.announcementService.Active(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=30")
+	utils.PaginateAll(c, announcements)
+}
+
+// ListAnnouncements 公告列表
+// @Summary 公告列表
+// @Description 包括已过期和已禁用的公告（管理员）
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param page query int false "页码" default(1)
+// @Param limit query int false "每页数量" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/announcements [get]
+func (ac *AnnouncementController) ListAnnouncements(c *gin.Context) {
+	page, limit := utils.PageParams(c, utils.DefaultPageLimit)
+
+	announcements, total, err := ac.announcementService.List(page, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	utils.Paginate(c, announcements, total, page, limit)
+}
+
+// CreateAnnouncement 创建公告
+// @Summary 创建公告
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body services.AnnouncementRequest true "公告内容和展示时间"
+// @Success 201 {object} models.Announcement
+// @Router /api/admin/announcements [post]
+func (ac *AnnouncementController) CreateAnnouncement(c *gin.Context) {
+	var req services.AnnouncementRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	announcement, err := ac.announcementService.Create(c.Request.Context(), c.GetString("user_id"), &req)
+	if err != nil {
+		c.Error(utils.NewError(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"code":    20000,
+		"message": "Announcement created",
+		"data":    announcement,
+	})
+}
+
+// UpdateAnnouncement 更新公告
+// @Summary 更新公告
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "公告ID"
+// @Param request body services.AnnouncementRequest true "公告内容和展示时间"
+// @Success 200 {object} models.Announcement
+// @Router /api/admin/announcements/{id} [put]
+func (ac *AnnouncementController) UpdateAnnouncement(c *gin.Context) {
+	var req services.AnnouncementRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	announcement, err := ac.announcementService.Update(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		c.Error(utils.WithDefaultStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Announcement updated",
+		"data":    announcement,
+	})
+}
+
+// DeleteAnnouncement 删除公告
+// @Summary 删除公告
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param id path string true "公告ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/announcements/{id} [delete]
+func (ac *AnnouncementController) DeleteAnnouncement(c *gin.Context) {
+	if err := ac.announcementService.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Announcement deleted",
+	})
+}
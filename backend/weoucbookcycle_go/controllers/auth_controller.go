@@ -3,6 +3,7 @@ package controllers
 import (
 	"net/http"
 	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,22 +14,25 @@ type AuthController struct {
 }
 
 // NewAuthController 创建认证控制器实例
-func NewAuthController() *AuthController {
+func NewAuthController(authService *services.AuthService) *AuthController {
 	return &AuthController{
-		authService: services.NewAuthService(),
+		authService: authService,
 	}
 }
 
 // RegisterRequest 注册请求结构
 type RegisterRequest struct {
-	Username string `json:"username" binding:"required,min=3,max=50"`
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=8"`
+	Username   string `json:"username" binding:"required,username"`
+	Email      string `json:"email" binding:"required,email"`
+	Password   string `json:"password" binding:"required,password"`
+	InviteCode string `json:"invite_code" binding:"omitempty,max=16"`
+	DeviceID   string `json:"device_id" binding:"omitempty,max=64"`
 }
 
 // LoginRequest 登录请求结构
 type LoginRequest struct {
-	Email    string `json:"email" binding:"required,email"`
+	Email    string `json:"email" binding:"required_without=Phone,omitempty,email"`
+	Phone    string `json:"phone" binding:"required_without=Email"`
 	Password string `json:"password" binding:"required"`
 }
 
@@ -37,13 +41,6 @@ type LoginRequest struct {
 // 后端使用 code 向微信官方接口换取 openid 并执行登录
 // 如果用户不存在则自动创建
 // 注意：由于小程序本身无跨域限制，前端可直接调用此接口
-// @Summary 微信小程序登录
-// @Tags auth
-// @Accept json
-// @Produce json
-// @Param request body WeChatLoginRequest true "微信登录信息"
-// @Success 200 {object} map[string]interface{}
-// @Router /api/auth/wechat [post]
 type WeChatLoginRequest struct {
 	Code string `json:"code" binding:"required"`
 }
@@ -68,7 +65,7 @@ type SendPasswordResetRequest struct {
 type ResetPasswordRequest struct {
 	Email       string `json:"email" binding:"required,email"`
 	Token       string `json:"token" binding:"required"`
-	NewPassword string `json:"new_password" binding:"required,min=8"`
+	NewPassword string `json:"new_password" binding:"required,password"`
 }
 
 // Register 用户注册
@@ -79,17 +76,17 @@ type ResetPasswordRequest struct {
 // @Produce json
 // @Param request body RegisterRequest true "注册信息"
 // @Success 200 {object} map[string]interface{}
-// @Router /api/v1/auth/register [post]
+// @Router /api/auth/register [post]
 func (ac *AuthController) Register(c *gin.Context) {
 	var req services.RegisterRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 40000, "message": err.Error()})
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
 		return
 	}
 
-	user, token, err := ac.authService.Register(&req, c.ClientIP())
+	user, token, err := ac.authService.Register(c.Request.Context(), &req, c.ClientIP(), utils.RequestLang(c))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 40000, "message": err.Error()})
+		c.Error(utils.NewError(http.StatusBadRequest, err.Error()))
 		return
 	}
 
@@ -111,23 +108,23 @@ func (ac *AuthController) Register(c *gin.Context) {
 
 // Login 用户登录
 // @Summary 用户登录
-// @Description 用户登录获取JWT token
+// @Description 用户登录获取JWT token，可以使用账号绑定的任意一个邮箱或手机号（email 和 phone 二选一）
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param request body LoginRequest true "登录信息"
 // @Success 200 {object} map[string]interface{}
-// @Router /api/v1/auth/login [post]
+// @Router /api/auth/login [post]
 func (ac *AuthController) Login(c *gin.Context) {
 	var req services.LoginRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 40000, "message": err.Error()})
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
 		return
 	}
 
-	user, token, err := ac.authService.Login(&req, c.ClientIP(), c.Request.UserAgent())
+	user, token, err := ac.authService.Login(c.Request.Context(), &req, c.ClientIP(), c.Request.UserAgent())
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"code": 40100, "message": err.Error()})
+		c.Error(utils.NewError(http.StatusUnauthorized, err.Error()))
 		return
 	}
 
@@ -156,11 +153,11 @@ func (ac *AuthController) Login(c *gin.Context) {
 // @Produce json
 // @Security Bearer
 // @Success 200 {object} map[string]interface{}
-// @Router /api/v1/auth/refresh [post]
+// @Router /api/auth/refresh [post]
 func (ac *AuthController) RefreshToken(c *gin.Context) {
 	tokenString := c.GetHeader("Authorization")
 	if tokenString == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"code": 40100, "message": "Authorization header required"})
+		c.Error(utils.NewError(http.StatusUnauthorized, "Authorization header required"))
 		return
 	}
 
@@ -169,9 +166,9 @@ func (ac *AuthController) RefreshToken(c *gin.Context) {
 		tokenString = tokenString[7:]
 	}
 
-	newToken, userInfo, err := ac.authService.RefreshToken(tokenString)
+	newToken, userInfo, err := ac.authService.RefreshToken(c.Request.Context(), tokenString)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"code": 40100, "message": "Failed to refresh token"})
+		c.Error(utils.NewError(http.StatusUnauthorized, "Failed to refresh token"))
 		return
 	}
 
@@ -194,11 +191,11 @@ func (ac *AuthController) RefreshToken(c *gin.Context) {
 // @Produce json
 // @Security Bearer
 // @Success 200 {object} map[string]interface{}
-// @Router /api/v1/auth/logout [post]
+// @Router /api/auth/logout [post]
 func (ac *AuthController) Logout(c *gin.Context) {
 	tokenString := c.GetHeader("Authorization")
 	if tokenString == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"code": 40100, "message": "Authorization header required"})
+		c.Error(utils.NewError(http.StatusUnauthorized, "Authorization header required"))
 		return
 	}
 
@@ -209,8 +206,8 @@ func (ac *AuthController) Logout(c *gin.Context) {
 
 	userID := c.GetString("user_id")
 
-	if err := ac.authService.Logout(tokenString, userID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 50000, "message": err.Error()})
+	if err := ac.authService.Logout(c.Request.Context(), tokenString, userID); err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -220,9 +217,10 @@ func (ac *AuthController) Logout(c *gin.Context) {
 	})
 }
 
-// WeChatLoginRequest 微信登录请求
+// WeChatLogin 微信小程序登录
 // 注意：小程序端应先调用 wx.login 获取 code，然后将 code 发送到此接口
 // 服务端使用 appid/secret 向微信接口换取 openid 并查找/创建用户
+// @Summary 微信小程序登录
 // @Description 使用微信小程序 code 登录，返回 JWT token
 // @Tags auth
 // @Accept json
@@ -232,14 +230,14 @@ func (ac *AuthController) Logout(c *gin.Context) {
 // @Router /api/auth/wechat [post]
 func (ac *AuthController) WeChatLogin(c *gin.Context) {
 	var req WeChatLoginRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 40000, "message": err.Error()})
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
 		return
 	}
 
-	user, token, err := ac.authService.WeChatLogin(req.Code, c.ClientIP())
+	user, token, err := ac.authService.WeChatLogin(c.Request.Context(), req.Code, c.ClientIP())
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"code": 40100, "message": err.Error()})
+		c.Error(utils.NewError(http.StatusUnauthorized, err.Error()))
 		return
 	}
 
@@ -267,16 +265,16 @@ func (ac *AuthController) WeChatLogin(c *gin.Context) {
 // @Produce json
 // @Param request body VerifyEmailRequest true "验证信息"
 // @Success 200 {object} map[string]interface{}
-// @Router /api/v1/auth/verify-email [post]
+// @Router /api/auth/verify-email [post]
 func (ac *AuthController) VerifyEmail(c *gin.Context) {
 	var req VerifyEmailRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 40000, "message": err.Error()})
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
 		return
 	}
 
-	if err := ac.authService.VerifyEmail(req.Email, req.Code); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 40000, "message": err.Error()})
+	if err := ac.authService.VerifyEmail(c.Request.Context(), req.Email, req.Code); err != nil {
+		c.Error(utils.NewError(http.StatusBadRequest, err.Error()))
 		return
 	}
 
@@ -294,16 +292,16 @@ func (ac *AuthController) VerifyEmail(c *gin.Context) {
 // @Produce json
 // @Param request body ResendVerificationRequest true "邮箱地址"
 // @Success 200 {object} map[string]interface{}
-// @Router /api/v1/auth/resend-verification [post]
+// @Router /api/auth/resend-verification [post]
 func (ac *AuthController) ResendVerificationCode(c *gin.Context) {
 	var req ResendVerificationRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 40000, "message": err.Error()})
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
 		return
 	}
 
-	if err := ac.authService.ResendVerificationCode(req.Email); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 40000, "message": err.Error()})
+	if err := ac.authService.ResendVerificationCode(c.Request.Context(), req.Email, utils.RequestLang(c)); err != nil {
+		c.Error(utils.NewError(http.StatusBadRequest, err.Error()))
 		return
 	}
 
@@ -321,16 +319,16 @@ func (ac *AuthController) ResendVerificationCode(c *gin.Context) {
 // @Produce json
 // @Param request body SendPasswordResetRequest true "邮箱地址"
 // @Success 200 {object} map[string]interface{}
-// @Router /api/v1/auth/send-password-reset [post]
+// @Router /api/auth/send-password-reset [post]
 func (ac *AuthController) SendPasswordResetToken(c *gin.Context) {
 	var req SendPasswordResetRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 40000, "message": err.Error()})
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
 		return
 	}
 
-	if err := ac.authService.SendPasswordResetToken(req.Email); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 50000, "message": err.Error()})
+	if err := ac.authService.SendPasswordResetToken(c.Request.Context(), req.Email, utils.RequestLang(c)); err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -348,16 +346,16 @@ func (ac *AuthController) SendPasswordResetToken(c *gin.Context) {
 // @Produce json
 // @Param request body ResetPasswordRequest true "重置信息"
 // @Success 200 {object} map[string]interface{}
-// @Router /api/v1/auth/reset-password [post]
+// @Router /api/auth/reset-password [post]
 func (ac *AuthController) ResetPassword(c *gin.Context) {
 	var req ResetPasswordRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 40000, "message": err.Error()})
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
 		return
 	}
 
-	if err := ac.authService.ResetPassword(req.Email, req.Token, req.NewPassword); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 40000, "message": err.Error()})
+	if err := ac.authService.ResetPassword(c.Request.Context(), req.Email, req.Token, req.NewPassword, utils.RequestLang(c)); err != nil {
+		c.Error(utils.NewError(http.StatusBadRequest, err.Error()))
 		return
 	}
 
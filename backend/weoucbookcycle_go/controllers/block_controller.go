@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"net/http"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BlockController 用户屏蔽控制器
+type BlockController struct {
+	blockService *services.BlockService
+}
+
+// NewBlockController 创建屏蔽控制器实例
+func NewBlockController(blockService *services.BlockService) *BlockController {
+	return &BlockController{
+		blockService: blockService,
+	}
+}
+
+// ListBlocks 获取我屏蔽的用户
+// @Summary 获取屏蔽列表
+// @Tags users
+// @Produce json
+// @Security Bearer
+// @Param page query int false "页码" default(1)
+// @Param limit query int false "每页数量" default(20)
+// @Success 200 {object} utils.PageResponse{data=[]services.BlockedUser}
+// @Router /api/users/me/blocks [get]
+func (bc *BlockController) ListBlocks(c *gin.Context) {
+	page, limit := utils.PageParams(c, utils.DefaultPageLimit)
+
+	users, total, err := bc.blockService.List(c.Request.Context(), c.GetString("user_id"), page, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	utils.Paginate(c, users, total, page, limit)
+}
+
+// BlockUser 屏蔽用户
+// @Summary 屏蔽用户
+// @Description 屏蔽后对方不能和我聊天、关注我、收藏我的发布，也看不到我的联系方式；双方的关注会被解除
+// @Tags users
+// @Produce json
+// @Security Bearer
+// @Param id path string true "用户ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/users/{id}/block [post]
+func (bc *BlockController) BlockUser(c *gin.Context) {
+	if err := bc.blockService.Block(c.Request.Context(), c.GetString("user_id"), c.Param("id")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "User blocked",
+		"data":    gin.H{"blocked": true},
+	})
+}
+
+// UnblockUser 取消屏蔽
+// @Summary 取消屏蔽
+// @Tags users
+// @Produce json
+// @Security Bearer
+// @Param id path string true "用户ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/users/{id}/block [delete]
+func (bc *BlockController) UnblockUser(c *gin.Context) {
+	if err := bc.blockService.Unblock(c.Request.Context(), c.GetString("user_id"), c.Param("id")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "User unblocked",
+		"data":    gin.H{"blocked": false},
+	})
+}
@@ -1,107 +1,63 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
-	"sync"
 	"time"
 	"weoucbookcycle_go/config"
 	"weoucbookcycle_go/models"
 	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
 )
 
 // BookController 书籍控制器
 type BookController struct {
-	redisClient *redis.Client
-	// 统计更新队列
-	statsQueue chan BookStatUpdate
-	workerWg   sync.WaitGroup
-}
-
-// BookStatUpdate 书籍统计更新任务
-type BookStatUpdate struct {
-	BookID string
-	Type   string // "view", "like"
+	bookService        *services.BookService
+	followService      *services.FollowService
+	blockService       *services.BlockService
+	campusService      *services.CampusService
+	searchIndexService *services.SearchIndexService
+	synonymService     *services.SynonymService
+	settingsService    *services.SystemSettingsService
+	db                 *gorm.DB
+	redisClient        *redis.Client
 }
 
 // NewBookController 创建书籍控制器实例
-func NewBookController() *BookController {
-	bc := &BookController{
-		redisClient: initRedis(),
-		statsQueue:  make(chan BookStatUpdate, 1000), // 缓冲队列
+func NewBookController(bookService *services.BookService, followService *services.FollowService, blockService *services.BlockService, campusService *services.CampusService,
+	searchIndexService *services.SearchIndexService, synonymService *services.SynonymService, settingsService *services.SystemSettingsService,
+	db *gorm.DB, redisClient *redis.Client) *BookController {
+	return &BookController{
+		bookService:        bookService,
+		followService:      followService,
+		blockService:       blockService,
+		campusService:      campusService,
+		searchIndexService: searchIndexService,
+		synonymService:     synonymService,
+		settingsService:    settingsService,
+		db:                 db,
+		redisClient:        redisClient,
 	}
-
-	// 启动统计worker池（使用goroutine）
-	bc.startStatsWorkers()
-
-	return bc
-}
-
-// initRedis 初始化Redis客户端
-func initRedis() *redis.Client {
-	return redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
-	})
-}
-
-// startStatsWorkers 启动统计更新worker池
-// 使用goroutine和channel实现异步统计更新
-func (bc *BookController) startStatsWorkers() {
-	workerCount := 5 // 启动5个worker
-
-	for i := 0; i < workerCount; i++ {
-		bc.workerWg.Add(1)
-		go bc.statsWorker(i)
-	}
-}
-
-// statsWorker 统计更新worker
-// 每个worker从channel中获取任务并处理
-func (bc *BookController) statsWorker(workerID int) {
-	defer bc.workerWg.Done()
-
-	for stat := range bc.statsQueue {
-		if err := bc.updateBookStats(stat); err != nil {
-			// 可以添加错误日志
-		}
-	}
-}
-
-// updateBookStats 更新书籍统计信息
-func (bc *BookController) updateBookStats(stat BookStatUpdate) error {
-	switch stat.Type {
-	case "view":
-		// 原子操作增加浏览次数
-		config.DB.Exec("UPDATE books SET view_count = view_count + 1 WHERE id = ?", stat.BookID)
-
-		// 同时更新Redis中的浏览统计（用于排行榜）
-		bc.redisClient.ZIncrBy(ctx, "rank:book:views", 1, stat.BookID)
-
-	case "like":
-		config.DB.Exec("UPDATE books SET like_count = like_count + 1 WHERE id = ?", stat.BookID)
-		bc.redisClient.ZIncrBy(ctx, "rank:book:likes", 1, stat.BookID)
-	}
-
-	return nil
 }
 
 // CreateBookRequest 创建书籍请求结构
 type CreateBookRequest struct {
 	Title       string   `json:"title" binding:"required,max=200"`
 	Author      string   `json:"author" binding:"required,max=100"`
-	ISBN        string   `json:"isbn" binding:"omitempty"`
+	ISBN        string   `json:"isbn" binding:"omitempty,isbn"`
 	Category    string   `json:"category" binding:"required"`
 	Price       float64  `json:"price" binding:"required,gt=0"`
 	Description string   `json:"description"`
 	Images      []string `json:"images"`
 	Condition   string   `json:"condition" binding:"required,oneof=全新 九成新 八成新 七成新 其他"`
+	CampusID    string   `json:"campus_id"` // 为空时使用卖家所在校区
 }
 
 // UpdateBookRequest 更新书籍请求结构
@@ -114,6 +70,7 @@ type UpdateBookRequest struct {
 	Images      []string `json:"images"`
 	Condition   string   `json:"condition" binding:"omitempty,oneof=全新 九成新 八成新 七成新 其他"`
 	Status      int      `json:"status" binding:"omitempty,oneof=0 1 2"`
+	CampusID    string   `json:"campus_id"`
 }
 
 // GetBooks 获取书籍列表
@@ -126,19 +83,21 @@ type UpdateBookRequest struct {
 // @Param limit query int false "每页数量" default(20)
 // @Param category query string false "书籍分类"
 // @Param author query string false "作者"
+// @Param campus_id query string false "所在校区，默认为登录用户所在校区，all 表示不限"
 // @Param sort query string false "排序方式" default(created_at)
 // @Success 200 {object} map[string]interface{}
-// @Router /api/v1/books [get]
+// @Router /api/books [get]
 func (bc *BookController) GetBooks(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	offset := (page - 1) * limit
+	ctx := c.Request.Context()
+	page, limit := utils.PageParams(c, utils.DefaultPageLimit)
 	category := c.Query("category")
 	author := c.Query("author")
 	sort := c.DefaultQuery("sort", "created_at")
 
 	// 构建查询
-	query := config.DB.Model(&models.Book{}).Where("status = ?", 1)
+	campusID := bc.campusService.PickupCampus(ctx, c.GetString("user_id"), c.Query("campus_id"))
+	query := bc.db.WithContext(ctx).Model(&models.Book{}).Where("status = ?", 1).
+		Scopes(services.VisibleUsers("books.seller_id"), services.InCampus("books.campus_id", campusID))
 
 	if category != "" {
 		query = query.Where("category = ?", category)
@@ -157,80 +116,165 @@ func (bc *BookController) GetBooks(c *gin.Context) {
 		Preload("Seller").
 		Order(sort + " DESC").
 		Limit(limit).
-		Offset(offset).
+		Offset(utils.PageOffset(page, limit)).
 		Find(&books).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get books"})
+		c.Error(utils.NewError(http.StatusInternalServerError, "Failed to get books"))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"books": books,
-		"total": total,
-		"page":  page,
-		"limit": limit,
+	utils.Paginate(c, books, total, page, limit)
+}
+
+// GetBooksV2 获取书籍列表（游标分页）
+// @Summary 获取书籍列表（游标分页）
+// @Description 按发布时间倒序，使用上一页返回的 next_cursor 翻页，不返回总数
+// @Tags books
+// @Produce json
+// @Param cursor query string false "上一页返回的 next_cursor"
+// @Param limit query int false "每页数量" default(20)
+// @Param category query string false "书籍分类"
+// @Param author query string false "作者"
+// @Param campus_id query string false "所在校区，默认为登录用户所在校区，all 表示不限"
+// @Success 200 {object} utils.PageResponse{data=[]models.Book}
+// @Router /api/v2/books [get]
+func (bc *BookController) GetBooksV2(c *gin.Context) {
+	ctx := c.Request.Context()
+	cursor, limit, err := utils.CursorParams(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	campusID := bc.campusService.PickupCampus(ctx, c.GetString("user_id"), c.Query("campus_id"))
+	query := config.ReadReplica(bc.db.WithContext(ctx)).Model(&models.Book{}).Where("status = ?", 1).
+		Scopes(services.VisibleUsers("books.seller_id"), services.InCampus("books.campus_id", campusID))
+	if category := c.Query("category"); category != "" {
+		query = query.Where("category = ?", category)
+	}
+	if author := c.Query("author"); author != "" {
+		query = query.Where("author LIKE ?", "%"+author+"%")
+	}
+
+	var books []models.Book
+	if err := utils.ApplyCursor(query.Preload("Seller"), "books", cursor, limit).Find(&books).Error; err != nil {
+		c.Error(utils.WrapError(http.StatusInternalServerError, "Failed to get books", err))
+		return
+	}
+
+	utils.PaginateCursor(c, books, limit, func(b models.Book) (time.Time, string) {
+		return b.CreatedAt, b.ID
 	})
 }
 
 // GetBook 获取书籍详情
 // @Summary 获取书籍详情
-// @Description 根据书籍ID获取详细信息
+// @Description 根据书籍ID获取详细信息，卖家的联系方式只返回给已登录且未被卖家屏蔽的用户
 // @Tags books
 // @Accept json
 // @Produce json
 // @Param id path string true "书籍ID"
+// @Param If-None-Match header string false "上次响应的 ETag，内容未变化时返回304"
 // @Success 200 {object} models.Book
-// @Router /api/v1/books/{id} [get]
+// @Header 200 {string} ETag "响应内容的哈希"
+// @Success 304 "内容未变化"
+// @Router /api/books/{id} [get]
 func (bc *BookController) GetBook(c *gin.Context) {
+	ctx := c.Request.Context()
 	bookID := c.Param("id")
 
-	// 先尝试从Redis缓存获取
+	// 先尝试从Redis缓存获取，缓存的JSON直接作为响应体，ETag 为其哈希
 	cacheKey := "book:" + bookID
-	cached, err := bc.redisClient.Get(ctx, cacheKey).Result()
-	if err == nil {
-		var book models.Book
-		if json.Unmarshal([]byte(cached), &book) == nil {
-			// 异步更新浏览统计（不阻塞响应）
-			bc.statsQueue <- BookStatUpdate{BookID: bookID, Type: "view"}
-			c.JSON(http.StatusOK, book)
-			return
-		}
+	cached, err := bc.redisClient.Get(ctx, cacheKey).Bytes()
+	if err == nil && json.Valid(cached) {
+		// 异步更新浏览统计（不阻塞响应）
+		bc.bookService.RecordView(ctx, bookID, c.GetString("user_id"))
+		utils.ServeJSONWithETag(c, bc.withSellerContact(c, cached))
+		utils.RecordCacheHit("books")
+		return
 	}
+	utils.RecordCacheMiss("books")
 
 	// 缓存未命中，从数据库查询
 	var book models.Book
-	if err := config.DB.Preload("Seller").First(&book, "id = ?", bookID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+	if err := bc.db.WithContext(ctx).Preload("Seller").First(&book, "id = ?", bookID).Error; err != nil {
+		c.Error(utils.NewError(http.StatusNotFound, "Book not found"))
+		return
+	}
+
+	data, err := json.Marshal(book)
+	if err != nil {
+		c.Error(utils.WrapError(http.StatusInternalServerError, "Failed to encode book", err))
+		return
+	}
+
+	// 卖家停用账号后书籍只对本人可见，也不写入共用的缓存
+	if book.Seller.Status == models.UserStatusDeactivated {
+		if book.SellerID != c.GetString("user_id") {
+			c.Error(utils.NewError(http.StatusNotFound, "Book not found"))
+			return
+		}
+		utils.ServeJSONWithETag(c, bc.withSellerContact(c, data))
 		return
 	}
 
 	// 异步更新浏览统计
-	bc.statsQueue <- BookStatUpdate{BookID: bookID, Type: "view"}
+	bc.bookService.RecordView(ctx, bookID, c.GetString("user_id"))
 
 	// 异步缓存到Redis（使用goroutine）
 	go func() {
-		data, _ := json.Marshal(book)
+		ctx := context.WithoutCancel(ctx)
 		bc.redisClient.Set(ctx, cacheKey, data, time.Minute*10)
 	}()
 
-	c.JSON(http.StatusOK, book)
+	utils.ServeJSONWithETag(c, bc.withSellerContact(c, data))
+}
+
+// withSellerContact 已登录且未被卖家屏蔽的用户在详情中看到卖家的联系方式
+// 缓存的是所有人共用的响应体，只含卖家的公开信息，因此在输出前按请求用户补上
+func (bc *BookController) withSellerContact(c *gin.Context, data []byte) []byte {
+	viewerID := c.GetString("user_id")
+	if viewerID == "" {
+		return data
+	}
+	var book models.Book
+	if err := json.Unmarshal(data, &book); err != nil {
+		return data
+	}
+	contact := bc.blockService.VisibleContact(c.Request.Context(), book.SellerID, viewerID)
+	if contact == nil {
+		return data
+	}
+	book.RevealSellerContact(contact)
+	if revealed, err := json.Marshal(book); err == nil {
+		return revealed
+	}
+	return data
 }
 
 // CreateBook 创建书籍
 // @Summary 创建书籍
-// @Description 创建新的书籍信息
+// @Description 创建新的书籍信息，未指定校区时使用卖家所在校区
 // @Tags books
 // @Accept json
 // @Produce json
 // @Security Bearer
 // @Param request body CreateBookRequest true "书籍信息"
 // @Success 201 {object} models.Book
-// @Router /api/v1/books [post]
+// @Router /api/books [post]
 func (bc *BookController) CreateBook(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID := c.GetString("user_id")
 
 	var req CreateBookRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if req.CampusID == "" {
+		req.CampusID, _ = bc.campusService.UserLocation(ctx, userID)
+	} else if err := bc.campusService.ValidateLocation(ctx, req.CampusID, ""); err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -248,18 +292,29 @@ func (bc *BookController) CreateBook(c *gin.Context) {
 		Condition:   req.Condition,
 		SellerID:    userID,
 		Status:      1,
+		CampusID:    req.CampusID,
 	}
 
-	if err := config.DB.Create(&book).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create book"})
+	if err := bc.db.WithContext(ctx).Create(&book).Error; err != nil {
+		c.Error(utils.NewError(http.StatusInternalServerError, "Failed to create book"))
 		return
 	}
 
 	// 清除热门书籍缓存
 	go func() {
-		bc.redisClient.Del(ctx, "hot:books")
+		ctx := context.WithoutCancel(ctx)
+		bc.redisClient.Del(ctx, services.DashboardCacheKey(userID))
+		utils.InvalidateCacheTag(ctx, bc.redisClient, utils.CacheTagHotBooks)
 	}()
 
+	// 加入搜索纠错词表
+	go bc.searchIndexService.AddToVocabulary(context.WithoutCancel(ctx), book.Title, book.Author)
+
+	services.RecordBookCreated(ctx, bc.redisClient, &book)
+
+	// 推送到关注者的动态
+	bc.followService.PublishToFeed(ctx, userID, models.FeedTypeBook, book.ID, book.CreatedAt)
+
 	c.JSON(http.StatusCreated, book)
 }
 
@@ -273,26 +328,27 @@ func (bc *BookController) CreateBook(c *gin.Context) {
 // @Param id path string true "书籍ID"
 // @Param request body UpdateBookRequest true "书籍信息"
 // @Success 200 {object} models.Book
-// @Router /api/v1/books/{id} [put]
+// @Router /api/books/{id} [put]
 func (bc *BookController) UpdateBook(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID := c.GetString("user_id")
 	bookID := c.Param("id")
 
 	var book models.Book
-	if err := config.DB.First(&book, "id = ?", bookID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+	if err := bc.db.WithContext(ctx).First(&book, "id = ?", bookID).Error; err != nil {
+		c.Error(utils.NewError(http.StatusNotFound, "Book not found"))
 		return
 	}
 
 	// 检查权限
 	if book.SellerID != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to update this book"})
+		c.Error(utils.NewError(http.StatusForbidden, "You don't have permission to update this book"))
 		return
 	}
 
 	var req UpdateBookRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -320,19 +376,27 @@ func (bc *BookController) UpdateBook(c *gin.Context) {
 	if req.Condition != "" {
 		updates["condition"] = req.Condition
 	}
+	if req.CampusID != "" {
+		if err := bc.campusService.ValidateLocation(ctx, req.CampusID, ""); err != nil {
+			c.Error(err)
+			return
+		}
+		updates["campus_id"] = req.CampusID
+	}
 	if req.Status >= 0 {
 		updates["status"] = req.Status
 	}
 
-	if err := config.DB.Model(&book).Updates(updates).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update book"})
+	if err := bc.db.WithContext(ctx).Model(&book).Updates(updates).Error; err != nil {
+		c.Error(utils.NewError(http.StatusInternalServerError, "Failed to update book"))
 		return
 	}
 
 	// 删除缓存
 	go func() {
-		bc.redisClient.Del(ctx, "book:"+bookID)
-		bc.redisClient.Del(ctx, "hot:books")
+		ctx := context.WithoutCancel(ctx)
+		bc.redisClient.Del(ctx, "book:"+bookID, services.DashboardCacheKey(userID))
+		utils.InvalidateCacheTag(ctx, bc.redisClient, utils.CacheTagHotBooks)
 	}()
 
 	c.JSON(http.StatusOK, book)
@@ -347,32 +411,34 @@ func (bc *BookController) UpdateBook(c *gin.Context) {
 // @Security Bearer
 // @Param id path string true "书籍ID"
 // @Success 200 {object} map[string]interface{}
-// @Router /api/v1/books/{id} [delete]
+// @Router /api/books/{id} [delete]
 func (bc *BookController) DeleteBook(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID := c.GetString("user_id")
 	bookID := c.Param("id")
 
 	var book models.Book
-	if err := config.DB.First(&book, "id = ?", bookID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+	if err := bc.db.WithContext(ctx).First(&book, "id = ?", bookID).Error; err != nil {
+		c.Error(utils.NewError(http.StatusNotFound, "Book not found"))
 		return
 	}
 
 	// 检查权限
 	if book.SellerID != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to delete this book"})
+		c.Error(utils.NewError(http.StatusForbidden, "You don't have permission to delete this book"))
 		return
 	}
 
-	if err := config.DB.Delete(&book).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete book"})
+	if err := bc.db.WithContext(ctx).Delete(&book).Error; err != nil {
+		c.Error(utils.NewError(http.StatusInternalServerError, "Failed to delete book"))
 		return
 	}
 
 	// 删除缓存
 	go func() {
-		bc.redisClient.Del(ctx, "book:"+bookID)
-		bc.redisClient.Del(ctx, "hot:books")
+		ctx := context.WithoutCancel(ctx)
+		bc.redisClient.Del(ctx, "book:"+bookID, services.DashboardCacheKey(userID))
+		utils.InvalidateCacheTag(ctx, bc.redisClient, utils.CacheTagHotBooks)
 	}()
 
 	c.JSON(http.StatusOK, gin.H{"message": "Book deleted successfully"})
@@ -380,45 +446,63 @@ func (bc *BookController) DeleteBook(c *gin.Context) {
 
 // GetHotBooks 获取热门书籍
 // @Summary 获取热门书籍
-// @Description 获取热门/推荐书籍列表（从Redis缓存）
+// @Description 获取热门/推荐书籍列表（从Redis缓存），每个校区单独统计
 // @Tags books
 // @Accept json
 // @Produce json
 // @Param limit query int false "数量" default(10)
-// @Success 200 {array} models.Book
-// @Router /api/v1/books/hot [get]
+// @Param campus_id query string false "所在校区，默认为登录用户所在校区，all 表示不限"
+// @Param If-None-Match header string false "上次响应的 ETag，内容未变化时返回304"
+// @Success 200 {object} map[string]interface{} "{books: [...]}"
+// @Header 200 {string} ETag "响应内容的哈希"
+// @Success 304 "内容未变化"
+// @Router /api/books/hot [get]
 func (bc *BookController) GetHotBooks(c *gin.Context) {
+	ctx := c.Request.Context()
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 
-	// 先从Redis获取缓存
-	cacheKey := "hot:books"
-	cached, err := bc.redisClient.Get(ctx, cacheKey).Result()
-	if err == nil {
-		var books []models.Book
-		if json.Unmarshal([]byte(cached), &books) == nil {
-			c.JSON(http.StatusOK, gin.H{"books": books})
-			return
-		}
+	// 先从Redis获取缓存，每个校区一份
+	campusID := bc.campusService.PickupCampus(ctx, c.GetString("user_id"), c.Query("campus_id"))
+	cacheKey := services.HotBooksCacheKey(campusID)
+	cached, err := bc.redisClient.Get(ctx, cacheKey).Bytes()
+	if err == nil && json.Valid(cached) {
+		serveHotBooks(c, cached)
+		utils.RecordCacheHit("hot_books")
+		return
 	}
+	utils.RecordCacheMiss("hot_books")
 
 	// 缓存未命中，从数据库获取热门书籍
 	var books []models.Book
-	if err := config.DB.
-		Where("status = ?", 1).
+	if err := bc.db.WithContext(ctx).
+		Where("status = ?", 1).Scopes(services.InCampus("campus_id", campusID)).
 		Order("view_count DESC, like_count DESC, created_at DESC").
 		Limit(limit).
 		Find(&books).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get hot books"})
+		c.Error(utils.NewError(http.StatusInternalServerError, "Failed to get hot books"))
+		return
+	}
+
+	data, err := json.Marshal(books)
+	if err != nil {
+		c.Error(utils.WrapError(http.StatusInternalServerError, "Failed to encode hot books", err))
 		return
 	}
 
-	// 异步缓存到Redis
+	// 异步缓存到Redis，登记到标签以便书籍变化时清除所有校区的缓存
 	go func() {
-		data, _ := json.Marshal(books)
-		bc.redisClient.Set(ctx, cacheKey, data, time.Minute*10)
+		ctx := context.WithoutCancel(ctx)
+		ttl := time.Duration(bc.settingsService.Int(ctx, services.SettingHotBooksTTLSeconds)) * time.Second
+		utils.SetTaggedCache(ctx, bc.redisClient, utils.CacheTagHotBooks, cacheKey, data, ttl)
 	}()
 
-	c.JSON(http.StatusOK, gin.H{"books": books})
+	serveHotBooks(c, data)
+}
+
+// serveHotBooks 把缓存的书籍数组包装为 {"books": [...]} 响应
+func serveHotBooks(c *gin.Context, books []byte) {
+	data, _ := json.Marshal(gin.H{"books": json.RawMessage(books)})
+	utils.ServeJSONWithETag(c, data)
 }
 
 // SearchBooks 搜索书籍
@@ -430,71 +514,72 @@ func (bc *BookController) GetHotBooks(c *gin.Context) {
 // @Param q query string true "搜索关键词"
 // @Param page query int false "页码" default(1)
 // @Param limit query int false "每页数量" default(20)
+// @Param campus_id query string false "所在校区，默认为登录用户所在校区，all 表示不限"
 // @Success 200 {object} map[string]interface{}
-// @Router /api/v1/books/search [get]
+// @Router /api/books/search [get]
 func (bc *BookController) SearchBooks(c *gin.Context) {
+	ctx := c.Request.Context()
 	query := c.Query("q")
 	if query == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query is required"})
+		c.Error(utils.NewError(http.StatusBadRequest, "Search query is required"))
 		return
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	offset := (page - 1) * limit
+	page, limit := utils.PageParams(c, utils.DefaultPageLimit)
+	campusID := bc.campusService.PickupCampus(ctx, c.GetString("user_id"), c.Query("campus_id"))
 
 	// 先检查Redis缓存
-	cacheKey := "search:" + query + ":" + strconv.Itoa(page)
-	cached, err := bc.redisClient.Get(ctx, cacheKey).Result()
-	if err == nil {
-		var result map[string]interface{}
-		if json.Unmarshal([]byte(cached), &result) == nil {
-			c.JSON(http.StatusOK, result)
-			return
-		}
+	cacheKey := searchPageCacheKey("books", query, page, limit, campusKey(campusID))
+	if result, ok := loadSearchPage[models.Book](ctx, bc.redisClient, cacheKey); ok {
+		bc.writeSearchPage(c, query, page, limit, result)
+		utils.RecordCacheHit("search")
+		return
 	}
+	utils.RecordCacheMiss("search")
 
 	// 记录搜索关键词（用于热门搜索统计）
 	go func() {
+		ctx := context.WithoutCancel(ctx)
 		bc.redisClient.ZIncrBy(ctx, "search:hot", 1, query)
 		bc.redisClient.Expire(ctx, "search:hot", time.Hour*24)
 	}()
 
-	// 数据库搜索
-	searchPattern := "%" + query + "%"
-	var books []models.Book
-	var total int64
+	// 数据库搜索（含同义词扩展）
+	condition, args := services.KeywordCondition(bc.synonymService.ExpandQuery(ctx, query), "title", "author", "description", "category")
+	result := &searchPageCache[models.Book]{}
 
-	baseQuery := config.DB.Model(&models.Book{}).Where("status = ?", 1).
-		Where("title LIKE ? OR author LIKE ? OR description LIKE ? OR category LIKE ?",
-			searchPattern, searchPattern, searchPattern, searchPattern)
+	baseQuery := bc.db.WithContext(ctx).Model(&models.Book{}).Where("status = ?", 1).
+		Scopes(services.VisibleUsers("books.seller_id"), services.InCampus("books.campus_id", campusID)).
+		Where(condition, args...)
 
-	baseQuery.Count(&total)
+	baseQuery.Count(&result.Total)
 
 	if err := baseQuery.
 		Preload("Seller").
 		Limit(limit).
-		Offset(offset).
-		Find(&books).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search books"})
+		Offset(utils.PageOffset(page, limit)).
+		Find(&result.Items).Error; err != nil {
+		c.Error(utils.NewError(http.StatusInternalServerError, "Failed to search books"))
 		return
 	}
 
-	result := gin.H{
-		"books": books,
-		"total": total,
-		"page":  page,
-		"limit": limit,
-		"query": query,
-	}
+	// 结果过少时给出纠错建议及其结果（例如：高等数写 -> 高等数学）
+	result.DidYouMean = bookSearchCorrection(ctx, bc.db, bc.searchIndexService, bc.synonymService, query, result.Total, limit, "", campusID)
 
 	// 异步缓存搜索结果
 	go func() {
+		ctx := context.WithoutCancel(ctx)
 		data, _ := json.Marshal(result)
-		bc.redisClient.Set(ctx, cacheKey, data, time.Minute*5)
+		utils.SetTaggedCache(ctx, bc.redisClient, utils.CacheTagSearch, cacheKey, data, time.Minute*5)
 	}()
 
-	c.JSON(http.StatusOK, result)
+	bc.writeSearchPage(c, query, page, limit, result)
+}
+
+// writeSearchPage 输出书籍搜索结果（不记录搜索事件，也不做个性化重排，见 /search/books）
+func (bc *BookController) writeSearchPage(c *gin.Context, query string, page, limit int, result *searchPageCache[models.Book]) {
+	meta := SearchMeta{Query: query, DidYouMean: result.DidYouMean}
+	utils.WritePage(c, result.Items, utils.NewPagination(c, "page", page, limit, result.Total), meta)
 }
 
 // LikeBookRequest 点赞请求结构
@@ -511,16 +596,14 @@ type LikeBookRequest struct {
 // @Security Bearer
 // @Param id path string true "书籍ID"
 // @Success 200 {object} map[string]interface{}
-// @Router /api/v1/books/:id/like [post]
+// @Router /api/books/{id}/like [post]
 func (bc *BookController) LikeBook(c *gin.Context) {
 	userID := c.GetString("user_id")
 	bookID := c.Param("id")
 
-	bookService := services.NewBookService()
-
-	liked, err := bookService.LikeBook(userID, bookID)
+	liked, err := bc.bookService.LikeBook(c.Request.Context(), userID, bookID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 50000, "message": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -548,26 +631,18 @@ func (bc *BookController) LikeBook(c *gin.Context) {
 // @Security Bearer
 // @Param limit query int false "数量" default(10)
 // @Success 200 {object} map[string]interface{}
-// @Router /api/v1/books/recommendations [get]
+// @Router /api/books/recommendations [get]
 func (bc *BookController) GetRecommendations(c *gin.Context) {
 	userID := c.GetString("user_id")
 	limit := bc.parseIntQuery(c.DefaultQuery("limit", "10"))
 
-	bookService := services.NewBookService()
-
-	books, err := bookService.GetRecommendations(userID, limit)
+	books, err := bc.bookService.GetRecommendations(c.Request.Context(), userID, limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 50000, "message": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"code":    20000,
-		"message": "Success",
-		"data": gin.H{
-			"books": books,
-		},
-	})
+	utils.PaginateAll(c, books)
 }
 
 // parseIntQuery 解析整型查询参数
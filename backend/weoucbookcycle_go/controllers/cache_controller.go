@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"net/http"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CacheController 缓存管理控制器（管理员）
+type CacheController struct {
+	cacheAdminService *services.CacheAdminService
+}
+
+// NewCacheController 创建缓存管理控制器实例
+func NewCacheController(cacheAdminService *services.CacheAdminService) *CacheController {
+	return &CacheController{
+		cacheAdminService: cacheAdminService,
+	}
+}
+
+// GetCacheStats 缓存命中统计
+// @Summary 缓存命中统计
+// @Description 当前实例各类缓存的命中/未命中次数，以及可清除的缓存标签
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/cache/stats [get]
+func (cc *CacheController) GetCacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Success",
+		"data": gin.H{
+			"stats": cc.cacheAdminService.Stats(),
+			"tags":  cc.cacheAdminService.Tags(),
+		},
+	})
+}
+
+// ResetCacheStats 清空缓存命中统计
+// @Summary 清空缓存命中统计
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/cache/stats [delete]
+func (cc *CacheController) ResetCacheStats(c *gin.Context) {
+	cc.cacheAdminService.ResetStats()
+	c.JSON(http.StatusOK, gin.H{"code": 20000, "message": "Cache stats reset"})
+}
+
+// InvalidateCache 清除缓存
+// @Summary 清除缓存
+// @Description 按key（如 book:{id}、hot:books）或标签（books、search、users 等）清除缓存，不能删除封禁标记、索引状态等非缓存key
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body services.InvalidateCacheRequest true "要清除的key和标签"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/cache/invalidate [post]
+func (cc *CacheController) InvalidateCache(c *gin.Context) {
+	var req services.InvalidateCacheRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+	if len(req.Keys) == 0 && len(req.Tags) == 0 {
+		c.Error(utils.NewError(http.StatusBadRequest, "keys or tags is required"))
+		return
+	}
+
+	deleted, err := cc.cacheAdminService.Invalidate(c.Request.Context(), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Cache invalidated",
+		"data":    gin.H{"deleted": deleted},
+	})
+}
@@ -0,0 +1,335 @@
+package controllers
+
+import (
+	"net/http"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CampusController 校区和用户位置控制器
+type CampusController struct {
+	campusService *services.CampusService
+}
+
+// NewCampusController 创建校区控制器实例
+func NewCampusController(campusService *services.CampusService) *CampusController {
+	return &CampusController{
+		campusService: campusService,
+	}
+}
+
+// ListCampuses 获取校区列表
+// @Summary 获取校区列表
+// @Description 返回启用的校区及其宿舍区和面交地点，用于设置所在位置和发布的面交地点
+// @Tags campuses
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/campuses [get]
+func (cc *CampusController) ListCampuses(c *gin.Context) {
+	campuses, err := cc.campusService.List(c.Request.Context(), true)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Success",
+		"data":    campuses,
+	})
+}
+
+// UpdateMyLocation 设置我所在的校区和宿舍区
+// @Summary 设置所在位置
+// @Description 设置后浏览和搜索发布默认只显示本校区的，创建发布时默认以此作为面交地点；两项都为空表示清除
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body services.LocationRequest true "校区和宿舍区"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/users/me/location [put]
+func (cc *CampusController) UpdateMyLocation(c *gin.Context) {
+	var req services.LocationRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	user, err := cc.campusService.SetUserLocation(c.Request.Context(), c.GetString("user_id"), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Location updated",
+		"data": gin.H{
+			"campus_id":    user.CampusID,
+			"dorm_area_id": user.DormAreaID,
+		},
+	})
+}
+
+// AdminListCampuses 获取全部校区（含停用的）
+// @Summary 获取全部校区
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/campuses [get]
+func (cc *CampusController) AdminListCampuses(c *gin.Context) {
+	campuses, err := cc.campusService.List(c.Request.Context(), false)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	utils.PaginateAll(c, campuses)
+}
+
+// CreateCampus 创建校区
+// @Summary 创建校区
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body services.CampusRequest true "校区"
+// @Success 201 {object} map[string]interface{}
+// @Router /api/admin/campuses [post]
+func (cc *CampusController) CreateCampus(c *gin.Context) {
+	var req services.CampusRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	campus, err := cc.campusService.CreateCampus(c.Request.Context(), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"code":    20000,
+		"message": "Campus created",
+		"data":    campus,
+	})
+}
+
+// UpdateCampus 更新校区
+// @Summary 更新校区
+// @Description 停用（enabled=false）后不能再被选择，已设置该校区的用户和发布保持不变
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "校区ID"
+// @Param request body services.CampusRequest true "校区"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/campuses/{id} [put]
+func (cc *CampusController) UpdateCampus(c *gin.Context) {
+	var req services.CampusRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	campus, err := cc.campusService.UpdateCampus(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Campus updated",
+		"data":    campus,
+	})
+}
+
+// DeleteCampus 删除校区
+// @Summary 删除校区
+// @Description 只能删除没有用户和发布引用的校区，否则返回409，请改为停用
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param id path string true "校区ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/campuses/{id} [delete]
+func (cc *CampusController) DeleteCampus(c *gin.Context) {
+	if err := cc.campusService.DeleteCampus(c.Request.Context(), c.Param("id")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Campus deleted",
+	})
+}
+
+// CreateDormArea 创建宿舍区
+// @Summary 创建宿舍区
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "校区ID"
+// @Param request body services.DormAreaRequest true "宿舍区"
+// @Success 201 {object} map[string]interface{}
+// @Router /api/admin/campuses/{id}/dorm-areas [post]
+func (cc *CampusController) CreateDormArea(c *gin.Context) {
+	var req services.DormAreaRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	area, err := cc.campusService.CreateDormArea(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"code":    20000,
+		"message": "Dorm area created",
+		"data":    area,
+	})
+}
+
+// UpdateDormArea 更新宿舍区
+// @Summary 更新宿舍区
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "宿舍区ID"
+// @Param request body services.DormAreaRequest true "宿舍区"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/dorm-areas/{id} [put]
+func (cc *CampusController) UpdateDormArea(c *gin.Context) {
+	var req services.DormAreaRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	area, err := cc.campusService.UpdateDormArea(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Dorm area updated",
+		"data":    area,
+	})
+}
+
+// DeleteDormArea 删除宿舍区
+// @Summary 删除宿舍区
+// @Description 设置了该宿舍区的用户和发布只保留校区
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param id path string true "宿舍区ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/dorm-areas/{id} [delete]
+func (cc *CampusController) DeleteDormArea(c *gin.Context) {
+	if err := cc.campusService.DeleteDormArea(c.Request.Context(), c.Param("id")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Dorm area deleted",
+	})
+}
+
+// CreatePickupPoint 创建面交地点
+// @Summary 创建面交地点
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "校区ID"
+// @Param request body services.PickupPointRequest true "面交地点"
+// @Success 201 {object} map[string]interface{}
+// @Router /api/admin/campuses/{id}/pickup-points [post]
+func (cc *CampusController) CreatePickupPoint(c *gin.Context) {
+	var req services.PickupPointRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	point, err := cc.campusService.CreatePickupPoint(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"code":    20000,
+		"message": "Pickup point created",
+		"data":    point,
+	})
+}
+
+// UpdatePickupPoint 更新面交地点
+// @Summary 更新面交地点
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "面交地点ID"
+// @Param request body services.PickupPointRequest true "面交地点"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/pickup-points/{id} [put]
+func (cc *CampusController) UpdatePickupPoint(c *gin.Context) {
+	var req services.PickupPointRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	point, err := cc.campusService.UpdatePickupPoint(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Pickup point updated",
+		"data":    point,
+	})
+}
+
+// DeletePickupPoint 删除面交地点
+// @Summary 删除面交地点
+// @Description 选择了该面交地点的发布只保留校区和宿舍区
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param id path string true "面交地点ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/pickup-points/{id} [delete]
+func (cc *CampusController) DeletePickupPoint(c *gin.Context) {
+	if err := cc.campusService.DeletePickupPoint(c.Request.Context(), c.Param("id")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Pickup point deleted",
+	})
+}
@@ -3,123 +3,61 @@ package controllers
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 	"sync"
 	"time"
+	"unicode/utf8"
 	"weoucbookcycle_go/config"
 	"weoucbookcycle_go/models"
 	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
 )
 
-var ctx = context.Background()
-
 // ChatController 聊天控制器
 type ChatController struct {
-	redisClient *redis.Client
-	upgrader    websocket.Upgrader
+	chatService     *services.ChatService
+	blockService    *services.BlockService
+	badgeService    *services.BadgeService
+	settingsService *services.SystemSettingsService
+	db              *gorm.DB
+	redisClient     *redis.Client
+	upgrader        websocket.Upgrader
 	// 在线用户连接管理
 	clients   map[string]*websocket.Conn // userID -> connection
 	clientsMu sync.RWMutex
-	// 消息队列
-	messageQueue chan MessageTask
-}
-
-// MessageTask 消息任务
-type MessageTask struct {
-	ChatID  string
-	UserID  string
-	Content string
 }
 
 // NewChatController 创建聊天控制器实例
-func NewChatController() *ChatController {
+func NewChatController(chatService *services.ChatService, blockService *services.BlockService, badgeService *services.BadgeService,
+	settingsService *services.SystemSettingsService, db *gorm.DB, redisClient *redis.Client) *ChatController {
 	cc := &ChatController{
-		redisClient:  initRedis(),
-		upgrader:     websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }},
-		clients:      make(map[string]*websocket.Conn),
-		messageQueue: make(chan MessageTask, 1000),
+		chatService:     chatService,
+		blockService:    blockService,
+		badgeService:    badgeService,
+		settingsService: settingsService,
+		db:              db,
+		redisClient:     redisClient,
+		upgrader:        websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }},
+		clients:         make(map[string]*websocket.Conn),
 	}
 
-	// 启动消息处理worker池
-	cc.startMessageWorkers()
-
 	// 启动心跳检测
 	go cc.heartbeatCheck()
 
 	return cc
 }
 
-// startMessageWorkers 启动消息处理worker池
-// 使用goroutine和channel实现异步消息处理
-func (cc *ChatController) startMessageWorkers() {
-	workerCount := 3 // 启动3个worker处理消息
-
-	for i := 0; i < workerCount; i++ {
-		go cc.messageWorker(i)
-	}
-}
-
-// messageWorker 消息处理worker
-func (cc *ChatController) messageWorker(workerID int) {
-	for task := range cc.messageQueue {
-		// 处理消息逻辑
-		cc.processMessage(task)
-	}
-}
-
-// processMessage 处理消息
-func (cc *ChatController) processMessage(task MessageTask) error {
-	// 创建消息记录
-	message := models.Message{
-		ChatID:   task.ChatID,
-		SenderID: task.UserID,
-		Content:  task.Content,
-		IsRead:   false,
-	}
-
-	if err := config.DB.Create(&message).Error; err != nil {
-		return err
-	}
-
-	// 获取聊天参与者
-	var chatUsers []models.ChatUser
-	config.DB.Where("chat_id = ?", task.ChatID).Find(&chatUsers)
-
-	// 推送消息给在线用户（使用goroutine并发推送）
-	for _, chatUser := range chatUsers {
-		if chatUser.UserID != task.UserID {
-			go func(receiverID string) {
-				cc.sendMessageToUser(receiverID, message)
-			}(chatUser.UserID)
-		}
-	}
-
-	return nil
-}
-
-// sendMessageToUser 发送消息给指定用户
-func (cc *ChatController) sendMessageToUser(userID string, message models.Message) {
-	cc.clientsMu.RLock()
-	conn, exists := cc.clients[userID]
-	cc.clientsMu.RUnlock()
-
-	if exists {
-		conn.WriteJSON(message)
-	}
-
-	// 增加未读计数
-	cc.redisClient.Incr(ctx, "unread:"+userID+":"+message.ChatID)
-	cc.redisClient.Expire(ctx, "unread:"+userID+":"+message.ChatID, time.Hour*24*7)
-}
-
 // heartbeatCheck 心跳检测
 // 定期检查连接是否存活
 func (cc *ChatController) heartbeatCheck() {
+	ctx := context.Background()
 	ticker := time.NewTicker(time.Minute * 1)
 	defer ticker.Stop()
 
@@ -144,21 +82,22 @@ func (cc *ChatController) heartbeatCheck() {
 // @Accept json
 // @Produce json
 // @Security Bearer
-// @Success 200 {array} ChatResponse
-// @Router /api/v1/chats [get]
+// @Success 200 {object} map[string]interface{}
+// @Router /api/chats [get]
 func (cc *ChatController) GetChats(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID := c.GetString("user_id")
 
 	// 获取用户参与的聊天关系（包含数据库中的未读数）
 	var chatUsers []models.ChatUser
-	if err := config.DB.Where("user_id = ?", userID).Find(&chatUsers).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get chats"})
+	if err := cc.db.WithContext(ctx).Where("user_id = ?", userID).Find(&chatUsers).Error; err != nil {
+		c.Error(utils.NewError(http.StatusInternalServerError, "Failed to get chats"))
 		return
 	}
 
 	// 如果没有聊天，返回空数组
 	if len(chatUsers) == 0 {
-		c.JSON(http.StatusOK, gin.H{"chats": []models.ChatResponse{}})
+		utils.PaginateAll(c, []models.ChatResponse{})
 		return
 	}
 
@@ -179,7 +118,7 @@ func (cc *ChatController) GetChats(c *gin.Context) {
 			defer wg.Done()
 
 			var chat models.Chat
-			if err := config.DB.
+			if err := cc.db.WithContext(ctx).
 				Preload("Users").
 				Preload("Users.User").
 				Where("id = ?", id).
@@ -188,9 +127,8 @@ func (cc *ChatController) GetChats(c *gin.Context) {
 				// 从Redis获取最新的未读数（如果有）
 				var unreadCount int64
 
-				if config.RedisClient != nil {
-					unreadKey := "unread:" + userID + ":" + id
-					unread, err := config.RedisClient.Get(ctx, unreadKey).Int64()
+				if cc.redisClient != nil {
+					unread, err := utils.GetUnread(ctx, cc.redisClient, userID, id)
 					if err == nil {
 						unreadCount = unread
 					}
@@ -220,7 +158,16 @@ func (cc *ChatController) GetChats(c *gin.Context) {
 
 	wg.Wait()
 
-	c.JSON(http.StatusOK, gin.H{"chats": chats})
+	// 聊天头部展示对方的徽章
+	var users []*models.User
+	for i := range chats {
+		for j := range chats[i].Users {
+			users = append(users, &chats[i].Users[j].User)
+		}
+	}
+	cc.badgeService.Fill(ctx, users...)
+
+	utils.PaginateAll(c, chats)
 }
 
 // GetChat 获取聊天详情
@@ -232,15 +179,16 @@ func (cc *ChatController) GetChats(c *gin.Context) {
 // @Param id path string true "聊天ID"
 // @Security Bearer
 // @Success 200 {object} models.Chat
-// @Router /api/v1/chats/{id} [get]
+// @Router /api/chats/{id} [get]
 func (cc *ChatController) GetChat(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID := c.GetString("user_id")
 	chatID := c.Param("id")
 
 	// 检查用户是否有权限访问该聊天
 	var chatUser models.ChatUser
-	if err := config.DB.Where("chat_id = ? AND user_id = ?", chatID, userID).First(&chatUser).Error; err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to access this chat"})
+	if err := cc.db.WithContext(ctx).Where("chat_id = ? AND user_id = ?", chatID, userID).First(&chatUser).Error; err != nil {
+		c.Error(utils.NewError(http.StatusForbidden, "You don't have permission to access this chat"))
 		return
 	}
 
@@ -251,24 +199,32 @@ func (cc *ChatController) GetChat(c *gin.Context) {
 		var chat models.Chat
 		if json.Unmarshal([]byte(cached), &chat) == nil {
 			c.JSON(http.StatusOK, chat)
+			utils.RecordCacheHit("chats")
 			return
 		}
 	}
+	utils.RecordCacheMiss("chats")
 
 	// 从数据库查询
 	var chat models.Chat
-	if err := config.DB.
+	if err := cc.db.WithContext(ctx).
 		Preload("Users").
 		Preload("Users.User").
 		Preload("Messages").
 		Preload("Messages.Sender").
 		First(&chat, "id = ?", chatID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Chat not found"})
+		c.Error(utils.NewError(http.StatusNotFound, "Chat not found"))
 		return
 	}
+	users := make([]*models.User, len(chat.Users))
+	for i := range chat.Users {
+		users[i] = &chat.Users[i].User
+	}
+	cc.badgeService.Fill(ctx, users...)
 
 	// 异步缓存到Redis
 	go func() {
+		ctx := context.WithoutCancel(ctx)
 		data, _ := json.Marshal(chat)
 		cc.redisClient.Set(ctx, cacheKey, data, time.Minute*10)
 	}()
@@ -278,29 +234,37 @@ func (cc *ChatController) GetChat(c *gin.Context) {
 
 // CreateChat 创建新聊天
 // @Summary 创建新聊天
-// @Description 创建新的聊天会话
+// @Description 创建新的聊天会话，任一方屏蔽了对方时返回403，data 中为屏蔽状态（services.BlockState）；
+// @Description 对方关闭了 allow_stranger_chat 且没有关注我时也返回403
 // @Tags chats
 // @Accept json
 // @Produce json
 // @Security Bearer
 // @Param request body map[string]interface{} true "聊天信息" example='{"user_id":"target-user-id"}'
 // @Success 201 {object} models.Chat
-// @Router /api/v1/chats [post]
+// @Router /api/chats [post]
 func (cc *ChatController) CreateChat(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID := c.GetString("user_id")
 
 	var req struct {
 		UserID string `json:"user_id" binding:"required"`
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
 		return
 	}
 
-	// 检查目标用户是否存在
+	// 检查目标用户是否存在（已停用账号的用户视为不存在）
 	var targetUser models.User
-	if err := config.DB.First(&targetUser, "id = ?", req.UserID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Target user not found"})
+	if err := cc.db.WithContext(ctx).First(&targetUser, "id = ? AND status <> ?", req.UserID, models.UserStatusDeactivated).Error; err != nil {
+		c.Error(utils.NewError(http.StatusNotFound, "Target user not found"))
+		return
+	}
+
+	// 任一方屏蔽了对方时不能聊天，错误的 data 中给出屏蔽状态
+	if err := cc.blockService.Check(ctx, userID, req.UserID); err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -308,14 +272,14 @@ func (cc *ChatController) CreateChat(c *gin.Context) {
 	var existingChat models.Chat
 	var existingChatUser models.ChatUser
 
-	err := config.DB.
+	err := cc.db.WithContext(ctx).
 		Joins("JOIN chat_users ON chat_users.chat_id = chats.id").
 		Where("chat_users.user_id = ?", userID).
 		First(&existingChat).Error
 
 	if err == nil {
 		// 检查是否也包含目标用户
-		err = config.DB.
+		err = cc.db.WithContext(ctx).
 			Where("chat_id = ? AND user_id = ?", existingChat.ID, req.UserID).
 			First(&existingChatUser).Error
 
@@ -326,31 +290,27 @@ func (cc *ChatController) CreateChat(c *gin.Context) {
 		}
 	}
 
-	// 创建新聊天
-	chat := models.Chat{}
-
-	if err := config.DB.Create(&chat).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create chat"})
+	// 对方只接受关注的人发起聊天
+	if err := cc.chatService.CheckStrangerChat(ctx, userID, req.UserID); err != nil {
+		c.Error(err)
 		return
 	}
 
-	// 添加聊天用户（使用goroutine并发插入）
-	var wg sync.WaitGroup
-	users := []string{userID, req.UserID}
-
-	for _, uid := range users {
-		wg.Add(1)
-		go func(id string) {
-			defer wg.Done()
-			chatUser := models.ChatUser{
-				ChatID:      chat.ID,
-				UserID:      id,
-				UnreadCount: 0,
-			}
-			config.DB.Create(&chatUser)
-		}(uid)
+	// 创建新聊天和双方的聊天用户，任一失败时整体回滚
+	chat := models.Chat{}
+	err = services.WithTx(ctx, cc.db, func(ctx context.Context, tx *gorm.DB) error {
+		if err := tx.Create(&chat).Error; err != nil {
+			return err
+		}
+		return tx.Create([]models.ChatUser{
+			{ChatID: chat.ID, UserID: userID},
+			{ChatID: chat.ID, UserID: req.UserID},
+		}).Error
+	})
+	if err != nil {
+		c.Error(utils.NewError(http.StatusInternalServerError, "Failed to create chat"))
+		return
 	}
-	wg.Wait()
 
 	c.JSON(http.StatusCreated, chat)
 }
@@ -366,51 +326,48 @@ func (cc *ChatController) CreateChat(c *gin.Context) {
 // @Param limit query int false "每页数量" default(50)
 // @Security Bearer
 // @Success 200 {object} map[string]interface{}
-// @Router /api/v1/chats/{id}/messages [get]
+// @Router /api/chats/{id}/messages [get]
 func (cc *ChatController) GetMessages(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID := c.GetString("user_id")
 	chatID := c.Param("id")
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
-	offset := (page - 1) * limit
+	page, limit := utils.PageParams(c, 50)
 
 	// 检查权限
 	var chatUser models.ChatUser
-	if err := config.DB.Where("chat_id = ? AND user_id = ?", chatID, userID).First(&chatUser).Error; err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to access this chat"})
+	if err := cc.db.WithContext(ctx).Where("chat_id = ? AND user_id = ?", chatID, userID).First(&chatUser).Error; err != nil {
+		c.Error(utils.NewError(http.StatusForbidden, "You don't have permission to access this chat"))
 		return
 	}
 
 	// 从Redis获取缓存消息
-	cacheKey := "chat:" + chatID + ":messages:page:" + strconv.Itoa(page)
+	cacheKey := "chat:" + chatID + ":messages:page:" + strconv.Itoa(page) + ":" + strconv.Itoa(limit)
 	cached, err := cc.redisClient.Get(ctx, cacheKey).Result()
 	if err == nil {
-		var messages []models.Message
-		if json.Unmarshal([]byte(cached), &messages) == nil {
-			c.JSON(http.StatusOK, gin.H{
-				"messages": messages,
-				"page":     page,
-				"limit":    limit,
-			})
+		var result messagePage
+		if json.Unmarshal([]byte(cached), &result) == nil {
+			utils.Paginate(c, result.Messages, result.Total, page, limit)
+			utils.RecordCacheHit("chats")
 			return
 		}
 	}
+	utils.RecordCacheMiss("chats")
 
 	// 从数据库查询
 	var messages []models.Message
 	var total int64
 
-	config.DB.Model(&models.Message{}).Where("chat_id = ?", chatID).Count(&total)
+	cc.db.WithContext(ctx).Model(&models.Message{}).Where("chat_id = ?", chatID).Count(&total)
 
-	if err := config.DB.
+	if err := cc.db.WithContext(ctx).
 		Preload("Sender").
 		Where("chat_id = ?", chatID).
 		Order("created_at DESC").
 		Limit(limit).
-		Offset(offset).
+		Offset(utils.PageOffset(page, limit)).
 		Find(&messages).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get messages"})
+		c.Error(utils.NewError(http.StatusInternalServerError, "Failed to get messages"))
 		return
 	}
 
@@ -420,26 +377,80 @@ func (cc *ChatController) GetMessages(c *gin.Context) {
 	}
 
 	// 标记消息为已读
-	go func() {
-		config.DB.Model(&models.Message{}).
+	utils.Go(ctx, "mark_read", func(ctx context.Context) error {
+		err := cc.db.WithContext(ctx).Model(&models.Message{}).
 			Where("chat_id = ? AND sender_id != ?", chatID, userID).
-			Update("is_read", true)
+			Update("is_read", true).Error
 
 		// 清除Redis中的未读计数
-		cc.redisClient.Del(ctx, "unread:"+userID+":"+chatID)
-	}()
+		utils.ClearUnread(ctx, cc.redisClient, userID, chatID)
+		return err
+	})
 
 	// 异步缓存消息
 	go func() {
-		data, _ := json.Marshal(messages)
-		cc.redisClient.Set(ctx, cacheKey, data, time.Minute*5)
+		ctx := context.WithoutCancel(ctx)
+		data, _ := json.Marshal(messagePage{Messages: messages, Total: total})
+		utils.SetTaggedCache(ctx, cc.redisClient, utils.ChatCacheTag(chatID), cacheKey, data, time.Minute*5)
 	}()
 
-	c.JSON(http.StatusOK, gin.H{
-		"messages": messages,
-		"total":    total,
-		"page":     page,
-		"limit":    limit,
+	utils.Paginate(c, messages, total, page, limit)
+}
+
+// messagePage 消息分页的缓存内容
+type messagePage struct {
+	Messages []models.Message `json:"messages"`
+	Total    int64            `json:"total"`
+}
+
+// GetMessagesV2 获取聊天消息（游标分页）
+// @Summary 获取聊天消息（游标分页）
+// @Description 从最新消息开始倒序返回，向上滚动时使用 next_cursor 加载更早的消息
+// @Tags chats
+// @Produce json
+// @Param id path string true "聊天ID"
+// @Param cursor query string false "上一页返回的 next_cursor"
+// @Param limit query int false "每页数量" default(20)
+// @Security Bearer
+// @Success 200 {object} utils.PageResponse{data=[]models.Message}
+// @Router /api/v2/chats/{id}/messages [get]
+func (cc *ChatController) GetMessagesV2(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID := c.GetString("user_id")
+	chatID := c.Param("id")
+
+	cursor, limit, err := utils.CursorParams(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var chatUser models.ChatUser
+	if err := cc.db.WithContext(ctx).Where("chat_id = ? AND user_id = ?", chatID, userID).First(&chatUser).Error; err != nil {
+		c.Error(utils.NewError(http.StatusForbidden, "You don't have permission to access this chat"))
+		return
+	}
+
+	var messages []models.Message
+	if err := utils.ApplyCursor(config.ReadReplica(cc.db.WithContext(ctx)).Preload("Sender").Where("chat_id = ?", chatID), "messages", cursor, limit).
+		Find(&messages).Error; err != nil {
+		c.Error(utils.WrapError(http.StatusInternalServerError, "Failed to get messages", err))
+		return
+	}
+
+	// 第一页包含最新消息，标记已读
+	if cursor == nil {
+		utils.Go(ctx, "mark_read", func(ctx context.Context) error {
+			err := cc.db.WithContext(ctx).Model(&models.Message{}).
+				Where("chat_id = ? AND sender_id != ?", chatID, userID).
+				Update("is_read", true).Error
+			utils.ClearUnread(ctx, cc.redisClient, userID, chatID)
+			return err
+		})
+	}
+
+	utils.PaginateCursor(c, messages, limit, func(m models.Message) (time.Time, string) {
+		return m.CreatedAt, m.ID
 	})
 }
 
@@ -453,41 +464,42 @@ func (cc *ChatController) GetMessages(c *gin.Context) {
 // @Param request body map[string]interface{} true "消息内容" example='{"content":"Hello"}'
 // @Security Bearer
 // @Success 201 {object} models.Message
-// @Router /api/v1/chats/{id}/messages [post]
+// @Router /api/chats/{id}/messages [post]
 func (cc *ChatController) SendMessage(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID := c.GetString("user_id")
 	chatID := c.Param("id")
 
 	var req struct {
-		Content string `json:"content" binding:"required,max=1000"`
+		Content string `json:"content" binding:"required"`
+	}
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if maxLength := cc.settingsService.Int(ctx, services.SettingMaxMessageLength); utf8.RuneCountInString(req.Content) > maxLength {
+		c.Error(utils.NewError(http.StatusBadRequest, fmt.Sprintf("message content is too long (max %d characters)", maxLength)))
 		return
 	}
 
 	// 检查权限
 	var chatUser models.ChatUser
-	if err := config.DB.Where("chat_id = ? AND user_id = ?", chatID, userID).First(&chatUser).Error; err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to send messages in this chat"})
+	if err := cc.db.WithContext(ctx).Where("chat_id = ? AND user_id = ?", chatID, userID).First(&chatUser).Error; err != nil {
+		c.Error(utils.NewError(http.StatusForbidden, "You don't have permission to send messages in this chat"))
 		return
 	}
 
-	// 将消息任务放入队列（异步处理）
-	task := MessageTask{
-		ChatID:  chatID,
-		UserID:  userID,
-		Content: req.Content,
+	// 提交消息创建任务（异步处理）
+	message, err := cc.chatService.SendMessage(ctx, chatID, userID, req.Content)
+	if err != nil {
+		c.Error(utils.WithDefaultStatus(err, http.StatusInternalServerError))
+		return
 	}
-
-	select {
-	case cc.messageQueue <- task:
+	if message.ID == "" {
 		c.JSON(http.StatusAccepted, gin.H{"message": "Message queued for delivery"})
-	default:
-		// 队列满了，直接处理
-		cc.processMessage(task)
-		c.JSON(http.StatusCreated, gin.H{"message": "Message sent successfully"})
+		return
 	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Message sent successfully"})
 }
 
 // HandleWebSocket WebSocket连接处理
@@ -497,9 +509,10 @@ func (cc *ChatController) SendMessage(c *gin.Context) {
 // @Param user_id query string true "用户ID"
 // @Router /ws [get]
 func (cc *ChatController) HandleWebSocket(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID := c.Query("user_id")
 	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "User ID is required"})
+		c.Error(utils.NewError(http.StatusBadRequest, "User ID is required"))
 		return
 	}
 
@@ -519,7 +532,7 @@ func (cc *ChatController) HandleWebSocket(c *gin.Context) {
 	cc.redisClient.Set(ctx, "online:"+userID, "1", time.Minute*5)
 
 	// 发送未读消息
-	go cc.sendUnreadMessages(conn, userID)
+	go cc.sendUnreadMessages(ctx, conn, userID)
 
 	// 监听消息
 	for {
@@ -539,12 +552,9 @@ func (cc *ChatController) HandleWebSocket(c *gin.Context) {
 		case "message":
 			if chatID, ok := msg["chat_id"].(string); ok {
 				if content, ok := msg["content"].(string); ok {
-					task := MessageTask{
-						ChatID:  chatID,
-						UserID:  userID,
-						Content: content,
+					if _, err := cc.chatService.SendMessage(ctx, chatID, userID, content); err != nil {
+						conn.WriteJSON(gin.H{"type": "error", "chat_id": chatID, "message": err.Error()})
 					}
-					cc.messageQueue <- task
 				}
 			}
 		case "ping":
@@ -562,15 +572,11 @@ func (cc *ChatController) HandleWebSocket(c *gin.Context) {
 }
 
 // sendUnreadMessages 发送未读消息
-func (cc *ChatController) sendUnreadMessages(conn *websocket.Conn, userID string) {
-	// 获取所有未读消息的key
-	pattern := "unread:" + userID + ":*"
-	keys, _ := cc.redisClient.Keys(ctx, pattern).Result()
-
-	for _, key := range keys {
-		// 提取chat_id
-		chatID := key[len("unread:"+userID+":"):]
+func (cc *ChatController) sendUnreadMessages(ctx context.Context, conn *websocket.Conn, userID string) {
+	// 获取有未读消息的会话
+	unread, _ := utils.GetAllUnread(ctx, cc.redisClient, userID)
 
+	for chatID := range unread {
 		// 获取最后几条消息
 		cacheKey := "chat:" + chatID + ":last_messages"
 		cached, err := cc.redisClient.LRange(ctx, cacheKey, 0, -1).Result()
@@ -590,25 +596,20 @@ func (cc *ChatController) sendUnreadMessages(conn *websocket.Conn, userID string
 // @Produce json
 // @Security Bearer
 // @Success 200 {object} map[string]interface{}
-// @Router /api/v1/chats/unread [get]
+// @Router /api/chats/unread [get]
 func (cc *ChatController) GetUnreadCount(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID := c.GetString("user_id")
 
-	// 获取所有未读key
-	pattern := "unread:" + userID + ":*"
-	keys, _ := cc.redisClient.Keys(ctx, pattern).Result()
+	// 获取各会话未读数
+	chatUnread, err := utils.GetAllUnread(ctx, cc.redisClient, userID)
+	if err != nil {
+		chatUnread = make(map[string]int64)
+	}
 
 	totalUnread := 0
-	chatUnread := make(map[string]int64)
-
-	for _, key := range keys {
-		// 提取chat_id
-		chatID := key[len("unread:"+userID+":"):]
-
-		// 获取未读数
-		count, _ := cc.redisClient.Get(ctx, key).Int64()
+	for _, count := range chatUnread {
 		totalUnread += int(count)
-		chatUnread[chatID] = count
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -625,30 +626,22 @@ func (cc *ChatController) GetUnreadCount(c *gin.Context) {
 // @Produce json
 // @Security Bearer
 // @Success 200 {object} map[string]interface{}
-// @Router /api/v1/chats/online-users [get]
+// @Router /api/chats/online-users [get]
 func (cc *ChatController) GetOnlineUsers(c *gin.Context) {
-	chatService := services.NewChatService()
-
-	onlineUsers, err := chatService.GetOnlineUsers()
+	ctx := c.Request.Context()
+	onlineUsers, err := cc.chatService.GetOnlineUsers(ctx)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 50000, "message": err.Error()})
+		c.Error(err)
 		return
 	}
 
 	// 获取在线用户详细信息
 	var users []models.User
 	if len(onlineUsers) > 0 {
-		config.DB.Where("id IN ?", onlineUsers).Find(&users)
+		cc.db.WithContext(ctx).Where("id IN ?", onlineUsers).Find(&users)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"code":    20000,
-		"message": "Success",
-		"data": gin.H{
-			"online_users": users,
-			"count":        len(onlineUsers),
-		},
-	})
+	utils.PaginateAll(c, users)
 }
 
 // MarkAsRead 标记消息为已读
@@ -660,15 +653,13 @@ func (cc *ChatController) GetOnlineUsers(c *gin.Context) {
 // @Security Bearer
 // @Param id path string true "聊天ID"
 // @Success 200 {object} map[string]interface{}
-// @Router /api/v1/chats/:id/read [put]
+// @Router /api/chats/{id}/read [put]
 func (cc *ChatController) MarkAsRead(c *gin.Context) {
 	userID := c.GetString("user_id")
 	chatID := c.Param("id")
 
-	chatService := services.NewChatService()
-
-	if err := chatService.MarkAsRead(chatID, userID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 50000, "message": err.Error()})
+	if err := cc.chatService.MarkAsRead(c.Request.Context(), chatID, userID); err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -687,15 +678,13 @@ func (cc *ChatController) MarkAsRead(c *gin.Context) {
 // @Security Bearer
 // @Param id path string true "聊天ID"
 // @Success 200 {object} map[string]interface{}
-// @Router /api/v1/chats/:id [delete]
+// @Router /api/chats/{id} [delete]
 func (cc *ChatController) DeleteChat(c *gin.Context) {
 	userID := c.GetString("user_id")
 	chatID := c.Param("id")
 
-	chatService := services.NewChatService()
-
-	if err := chatService.DeleteChat(chatID, userID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 50000, "message": err.Error()})
+	if err := cc.chatService.DeleteChat(c.Request.Context(), chatID, userID); err != nil {
+		c.Error(err)
 		return
 	}
 
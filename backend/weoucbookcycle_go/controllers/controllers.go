@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"weoucbookcycle_go/services"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// Controllers 路由使用的控制器实例，由 NewControllers 统一创建一次
+// 聊天控制器带有心跳goroutine，路由中不能再单独调用 NewXxxController
+type Controllers struct {
+	AccessLog       *AccessLogController
+	Account         *AccountController
+	Admin           *AdminController
+	Announcement    *AnnouncementController
+	Auth            *AuthController
+	Block           *BlockController
+	Book            *BookController
+	Cache           *CacheController
+	Campus          *CampusController
+	Chat            *ChatController
+	DataExport      *DataExportController
+	Donation        *DonationController
+	EmailDeadLetter *EmailDeadLetterController
+	Export          *ExportController
+	File            *FileController
+	Follow          *FollowController
+	Identity        *IdentityController
+	Impersonation   *ImpersonationController
+	Leaderboard     *LeaderboardController
+	Listing         *ListingController
+	Moderation      *ModerationController
+	Monitor         *MonitorController
+	Notification    *NotificationController
+	Points          *PointsController
+	QRCode          *QRCodeController
+	Referral        *ReferralController
+	Report          *ReportController
+	Reporting       *ReportingController
+	Reputation      *ReputationController
+	SavedSearch     *SavedSearchController
+	Search          *SearchController
+	SearchAnalytics *SearchAnalyticsController
+	SearchIndex     *SearchIndexController
+	Security        *SecurityController
+	Share           *ShareController
+	Stats           *StatsController
+	Synonym         *SynonymController
+	SystemSettings  *SystemSettingsController
+	Task            *TaskController
+	Upload          *UploadController
+	User            *UserController
+	Webhook         *WebhookController
+}
+
+// NewControllers 使用已创建的服务组装全部控制器
+func NewControllers(svc *services.Services, db *gorm.DB, redisClient *redis.Client) *Controllers {
+	return &Controllers{
+		AccessLog:       NewAccessLogController(svc.AccessLog),
+		Account:         NewAccountController(svc.Account),
+		Admin:           NewAdminController(svc.Admin),
+		Announcement:    NewAnnouncementController(svc.Announcement),
+		Auth:            NewAuthController(svc.Auth),
+		Block:           NewBlockController(svc.Block),
+		Book:            NewBookController(svc.Book, svc.Follow, svc.Block, svc.Campus, svc.SearchIndex, svc.Synonym, svc.SystemSettings, db, redisClient),
+		Cache:           NewCacheController(svc.CacheAdmin),
+		Campus:          NewCampusController(svc.Campus),
+		Chat:            NewChatController(svc.Chat, svc.Block, svc.Badge, svc.SystemSettings, db, redisClient),
+		DataExport:      NewDataExportController(svc.DataExport),
+		Donation:        NewDonationController(svc.Donation, svc.Campus, db),
+		EmailDeadLetter: NewEmailDeadLetterController(svc.EmailDeadLetter),
+		Export:          NewExportController(svc.Export),
+		File:            NewFileController(svc.File),
+		Follow:          NewFollowController(svc.Follow),
+		Identity:        NewIdentityController(svc.Identity),
+		Impersonation:   NewImpersonationController(svc.Impersonation),
+		Leaderboard:     NewLeaderboardController(svc.Leaderboard),
+		Listing:         NewListingController(svc.Push, svc.Follow, svc.Block, svc.Campus, db, redisClient),
+		Moderation:      NewModerationController(svc.Moderation),
+		Monitor:         NewMonitorController(svc.QueueMonitor, svc.Scheduler),
+		Notification:    NewNotificationController(svc.Notification, svc.Push),
+		Points:          NewPointsController(svc.Points),
+		QRCode:          NewQRCodeController(svc.QRCode),
+		Referral:        NewReferralController(svc.Referral),
+		Report:          NewReportController(svc.Report),
+		Reporting:       NewReportingController(svc.Reporting),
+		Reputation:      NewReputationController(svc.Reputation),
+		SavedSearch:     NewSavedSearchController(svc.SavedSearch),
+		Search:          NewSearchController(svc.SearchAnalytics, svc.Campus, svc.SearchIndex, svc.Synonym, svc.UserSettings, db, redisClient),
+		SearchAnalytics: NewSearchAnalyticsController(svc.SearchAnalytics),
+		SearchIndex:     NewSearchIndexController(svc.SearchIndex, redisClient),
+		Security:        NewSecurityController(svc.SecurityEvent),
+		Share:           NewShareController(svc.ShareCard),
+		Stats:           NewStatsController(svc.Stats),
+		Synonym:         NewSynonymController(svc.Synonym),
+		SystemSettings:  NewSystemSettingsController(svc.SystemSettings),
+		Task:            NewTaskController(redisClient),
+		Upload:          NewUploadController(svc.Thumbnail, svc.ChunkedUpload, redisClient),
+		User:            NewUserController(svc.Chat, svc.StorageUsage, svc.UserSettings, svc.Reputation, svc.Dashboard, svc.Username, svc.Profile, svc.Badge, db, redisClient),
+		Webhook:         NewWebhookController(svc.Webhook),
+	}
+}
@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+	"weoucbookcycle_go/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DataExportController 个人数据导出控制器
+type DataExportController struct {
+	dataExportService *services.DataExportService
+}
+
+// NewDataExportController 创建个人数据导出控制器实例
+func NewDataExportController(dataExportService *services.DataExportService) *DataExportController {
+	return &DataExportController{
+		dataExportService: dataExportService,
+	}
+}
+
+// RequestExport 申请导出我的数据
+// @Summary 申请导出我的数据
+// @Description 异步把个人资料、书籍、发布、聊天记录和上传的文件打包为zip，每24小时只能申请一次（超过时返回429和 retry_after）；
+// @Description 通过 /api/users/me/export/{id} 查询状态，完成后调用下载接口获取临时链接，归档保留7天
+// @Tags users
+// @Produce json
+// @Security Bearer
+// @Success 202 {object} map[string]interface{}
+// @Router /api/users/me/export [post]
+func (dc *DataExportController) RequestExport(c *gin.Context) {
+	export, err := dc.dataExportService.Request(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"code":    20000,
+		"message": "Export started",
+		"data":    export,
+	})
+}
+
+// GetExport 查询导出状态
+// @Summary 查询数据导出状态
+// @Description 状态为 pending、running、completed、failed 或 expired
+// @Tags users
+// @Produce json
+// @Security Bearer
+// @Param id path string true "导出ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/users/me/export/{id} [get]
+func (dc *DataExportController) GetExport(c *gin.Context) {
+	export, err := dc.dataExportService.Get(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Success",
+		"data":    export,
+	})
+}
+
+// DownloadExport 获取数据归档的临时下载链接
+// @Summary 下载我的数据
+// @Description 导出完成后返回有效期15分钟的下载链接；归档过期后返回410
+// @Tags users
+// @Produce json
+// @Security Bearer
+// @Param id path string true "导出ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/users/me/export/{id}/download [get]
+func (dc *DataExportController) DownloadExport(c *gin.Context) {
+	url, err := dc.dataExportService.DownloadURL(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Success",
+		"data": gin.H{
+			"url":        url,
+			"expires_at": time.Now().Add(services.ExportURLTTL),
+		},
+	})
+}
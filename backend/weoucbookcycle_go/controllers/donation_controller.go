@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"net/http"
+	"weoucbookcycle_go/config"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// DonationController 赠书控制器
+type DonationController struct {
+	donationService *services.DonationService
+	campusService   *services.CampusService
+	db              *gorm.DB
+}
+
+// NewDonationController 创建赠书控制器实例
+func NewDonationController(donationService *services.DonationService, campusService *services.CampusService, db *gorm.DB) *DonationController {
+	return &DonationController{
+		donationService: donationService,
+		campusService:   campusService,
+		db:              db,
+	}
+}
+
+// GetFreeBooks 免费书架
+// @Summary 免费书架
+// @Description 可以认领的赠书（is_donation 且还没有人认领的发布），按发布时间倒序（擦亮过的按擦亮时间）
+// @Tags books
+// @Produce json
+// @Param page query int false "页码" default(1)
+// @Param limit query int false "每页数量" default(20)
+// @Param campus_id query string false "面交校区，默认为登录用户所在校区，all 表示不限"
+// @Success 200 {object} utils.PageResponse{data=[]models.Listing}
+// @Router /api/books/free [get]
+func (dc *DonationController) GetFreeBooks(c *gin.Context) {
+	ctx := c.Request.Context()
+	page, limit := utils.PageParams(c, utils.DefaultPageLimit)
+
+	query := config.ReadReplica(dc.db.WithContext(ctx)).Model(&models.Listing{}).
+		Scopes(services.VisibleUsers("listings.seller_id")).
+		Where("listings.is_donation = ? AND listings.status = ?", true, "available")
+	if campusID := dc.campusService.PickupCampus(ctx, c.GetString("user_id"), c.Query("campus_id")); campusID != "" {
+		query = query.Where("listings.campus_id = ?", campusID)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var listings []models.Listing
+	if err := query.
+		Preload("Book").
+		Preload("Seller").
+		Order(listingActiveAt + " DESC").
+		Limit(limit).
+		Offset(utils.PageOffset(page, limit)).
+		Find(&listings).Error; err != nil {
+		c.Error(utils.WrapError(http.StatusInternalServerError, "Failed to get listings", err))
+		return
+	}
+
+	utils.Paginate(c, listings, total, page, limit)
+}
+
+// ClaimDonation 认领赠书
+// @Summary 认领赠书
+// @Description 先到先得，认领后发布变为 reserved，赠送人会收到通知；当面交接后由赠送人把状态改为 sold 完成赠送。
+// @Description 同时最多有3本待交接的认领
+// @Tags listings
+// @Produce json
+// @Security Bearer
+// @Param id path string true "发布ID"
+// @Success 200 {object} models.Listing
+// @Router /api/listings/{id}/claim [post]
+func (dc *DonationController) ClaimDonation(c *gin.Context) {
+	listing, err := dc.donationService.Claim(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Book claimed",
+		"data":    listing,
+	})
+}
+
+// CancelClaim 取消认领
+// @Summary 取消认领
+// @Description 赠送人或认领人都可以取消，取消后赠书重新出现在免费书架上
+// @Tags listings
+// @Produce json
+// @Security Bearer
+// @Param id path string true "发布ID"
+// @Success 200 {object} models.Listing
+// @Router /api/listings/{id}/claim [delete]
+func (dc *DonationController) CancelClaim(c *gin.Context) {
+	listing, err := dc.donationService.CancelClaim(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Claim cancelled",
+		"data":    listing,
+	})
+}
+
+// GetDonationStats 获取用户的赠书统计
+// @Summary 获取赠书统计
+// @Tags users
+// @Produce json
+// @Param id path string true "用户ID"
+// @Success 200 {object} services.DonationStats
+// @Router /api/users/{id}/donations [get]
+func (dc *DonationController) GetDonationStats(c *gin.Context) {
+	stats, err := dc.donationService.Stats(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Success",
+		"data":    stats,
+	})
+}
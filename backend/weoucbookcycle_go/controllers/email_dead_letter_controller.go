@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"net/http"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EmailDeadLetterController 死信邮件控制器（管理员）
+type EmailDeadLetterController struct {
+	deadLetterService *services.EmailDeadLetterService
+}
+
+// NewEmailDeadLetterController 创建死信邮件控制器实例
+func NewEmailDeadLetterController(deadLetterService *services.EmailDeadLetterService) *EmailDeadLetterController {
+	return &EmailDeadLetterController{
+		deadLetterService: deadLetterService,
+	}
+}
+
+// ListDeadLetters 死信邮件列表
+// @Summary 死信邮件列表
+// @Description 重试用尽仍发送失败的邮件（不含正文）
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param status query string false "failed, retried, discarded"
+// @Param type query string false "邮件类型"
+// @Param to_email query string false "收件人"
+// @Param page query int false "页码" default(1)
+// @Param limit query int false "每页数量" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/emails/dead-letters [get]
+func (dc *EmailDeadLetterController) ListDeadLetters(c *gin.Context) {
+	page, limit := utils.PageParams(c, utils.DefaultPageLimit)
+
+	letters, total, err := dc.deadLetterService.List(&services.EmailDeadLetterQuery{
+		Status:  c.Query("status"),
+		Type:    c.Query("type"),
+		ToEmail: c.Query("to_email"),
+		Page:    page,
+		Limit:   limit,
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	utils.Paginate(c, letters, total, page, limit)
+}
+
+// GetDeadLetter 死信邮件详情
+// @Summary 死信邮件详情
+// @Description 包含邮件正文和最后一次失败原因
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param id path string true "死信ID"
+// @Success 200 {object} models.EmailDeadLetter
+// @Router /api/admin/emails/dead-letters/{id} [get]
+func (dc *EmailDeadLetterController) GetDeadLetter(c *gin.Context) {
+	letter, err := dc.deadLetterService.Get(c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Success",
+		"data":    letter,
+	})
+}
+
+// RetryDeadLetter 重新发送死信邮件
+// @Summary 重新发送死信邮件
+// @Description 立即同步重发，成功后标记为 retried；失败时返回最新错误，可再次重试
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param id path string true "死信ID"
+// @Success 200 {object} models.EmailDeadLetter
+// @Router /api/admin/emails/dead-letters/{id}/retry [post]
+func (dc *EmailDeadLetterController) RetryDeadLetter(c *gin.Context) {
+	letter, err := dc.deadLetterService.Retry(c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		if letter != nil {
+			// 已记录本次失败
+			c.JSON(http.StatusBadGateway, gin.H{"code": 50000, "message": err.Error(), "data": letter})
+			return
+		}
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Email sent",
+		"data":    letter,
+	})
+}
+
+// DiscardDeadLetter 放弃死信邮件
+// @Summary 放弃死信邮件
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param id path string true "死信ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/emails/dead-letters/{id} [delete]
+func (dc *EmailDeadLetterController) DiscardDeadLetter(c *gin.Context) {
+	if err := dc.deadLetterService.Discard(c.GetString("user_id"), c.Param("id")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Dead letter discarded",
+	})
+}
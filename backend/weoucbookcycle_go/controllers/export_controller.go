@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportController 管理后台数据导出控制器
+type ExportController struct {
+	exportService *services.ExportService
+}
+
+// NewExportController 创建导出控制器实例
+func NewExportController(exportService *services.ExportService) *ExportController {
+	return &ExportController{
+		exportService: exportService,
+	}
+}
+
+// CreateExport 创建导出任务
+// @Summary 创建数据导出任务
+// @Description 异步导出用户、书籍、发布或订单（已成交发布）为 CSV/JSON，返回 task_id，通过 /api/tasks/{id} 查询进度，完成后调用下载接口获取临时链接
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body services.ExportRequest true "导出类型、格式和筛选条件"
+// @Success 202 {object} map[string]interface{}
+// @Router /api/admin/exports [post]
+func (ec *ExportController) CreateExport(c *gin.Context) {
+	var req services.ExportRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	taskID, err := ec.exportService.StartExport(c.Request.Context(), c.GetString("user_id"), &req)
+	if err != nil {
+		c.Error(utils.NewError(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"code":    20000,
+		"message": "Export started",
+		"data":    gin.H{"task_id": taskID},
+	})
+}
+
+// DownloadExport 获取导出文件的临时下载链接
+// @Summary 下载导出文件
+// @Description 导出任务完成后返回有效期15分钟的下载链接
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param id path string true "任务ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/exports/{id}/download [get]
+func (ec *ExportController) DownloadExport(c *gin.Context) {
+	url, err := ec.exportService.DownloadURL(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, services.ErrAdminTargetNotFound) {
+			err = utils.NewError(http.StatusNotFound, "Export not found")
+		}
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Success",
+		"data": gin.H{
+			"url":        url,
+			"expires_at": time.Now().Add(services.ExportURLTTL),
+		},
+	})
+}
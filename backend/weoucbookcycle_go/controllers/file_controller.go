@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"mime"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FileController 文件访问控制器
+type FileController struct {
+	fileService *services.FileService
+}
+
+// NewFileController 创建文件控制器实例
+func NewFileController(fileService *services.FileService) *FileController {
+	return &FileController{
+		fileService: fileService,
+	}
+}
+
+// GetFile 获取文件下载地址
+// @Summary 获取文件下载地址
+// @Description 公开文件返回公开URL；私有文件仅上传者和管理员可访问，返回10分钟内有效的签名URL
+// @Tags files
+// @Produce json
+// @Security Bearer
+// @Param id path string true "文件ID"
+// @Success 200 {object} services.FileDownload
+// @Router /api/files/{id} [get]
+func (fc *FileController) GetFile(c *gin.Context) {
+	roles, _ := c.Get("roles")
+	userRoles, _ := roles.([]string)
+
+	download, err := fc.fileService.GetDownloadURL(c.Request.Context(), c.GetString("user_id"), slices.Contains(userRoles, "admin"), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Success",
+		"data":    download,
+	})
+}
+
+// ServeSignedFile 通过签名URL读取本地存储的文件
+// @Summary 读取签名文件
+// @Description 校验 expires 和 signature 参数后返回文件内容，无需登录
+// @Tags files
+// @Produce octet-stream
+// @Param key path string true "文件key"
+// @Param expires query int true "过期时间（Unix秒）"
+// @Param signature query string true "签名"
+// @Success 200 {file} file
+// @Router /api/files/signed/{key} [get]
+func (fc *FileController) ServeSignedFile(c *gin.Context) {
+	key := strings.TrimLeft(path.Clean("/"+c.Param("key")), "/")
+
+	if err := utils.VerifyFileSignature(key, c.Query("expires"), c.Query("signature")); err != nil {
+		c.Error(utils.NewError(http.StatusForbidden, err.Error()))
+		return
+	}
+
+	file, err := utils.GetStorage().Open(c.Request.Context(), key)
+	if err != nil {
+		c.Error(utils.NewError(http.StatusNotFound, "File not found"))
+		return
+	}
+	defer file.Close()
+
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	// 签名URL可被转发，不允许共享缓存
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.DataFromReader(http.StatusOK, -1, contentType, file, nil)
+}
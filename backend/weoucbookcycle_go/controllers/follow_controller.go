@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FollowController 关注和关注动态控制器
+type FollowController struct {
+	followService *services.FollowService
+}
+
+// NewFollowController 创建关注控制器实例
+func NewFollowController(followService *services.FollowService) *FollowController {
+	return &FollowController{
+		followService: followService,
+	}
+}
+
+// FollowUser 关注用户
+// @Summary 关注用户
+// @Description 关注后对方新发布的书籍和上架会出现在 /api/feed 中，重复关注不报错
+// @Tags users
+// @Produce json
+// @Security Bearer
+// @Param id path string true "用户ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/users/{id}/follow [post]
+func (fc *FollowController) FollowUser(c *gin.Context) {
+	if err := fc.followService.Follow(c.Request.Context(), c.GetString("user_id"), c.Param("id")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Followed",
+		"data":    gin.H{"following": true},
+	})
+}
+
+// UnfollowUser 取消关注
+// @Summary 取消关注
+// @Description 取消关注并从动态中移除对方的内容，未关注时不报错
+// @Tags users
+// @Produce json
+// @Security Bearer
+// @Param id path string true "用户ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/users/{id}/follow [delete]
+func (fc *FollowController) UnfollowUser(c *gin.Context) {
+	if err := fc.followService.Unfollow(c.Request.Context(), c.GetString("user_id"), c.Param("id")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Unfollowed",
+		"data":    gin.H{"following": false},
+	})
+}
+
+// GetFollowers 获取用户的粉丝
+// @Summary 获取粉丝列表
+// @Tags users
+// @Produce json
+// @Param id path string true "用户ID"
+// @Param page query int false "页码" default(1)
+// @Param limit query int false "每页数量" default(20)
+// @Success 200 {object} utils.PageResponse{data=[]models.PublicUser}
+// @Router /api/users/{id}/followers [get]
+func (fc *FollowController) GetFollowers(c *gin.Context) {
+	page, limit := utils.PageParams(c, utils.DefaultPageLimit)
+
+	users, total, err := fc.followService.Followers(c.Request.Context(), c.Param("id"), page, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	utils.Paginate(c, users, total, page, limit)
+}
+
+// GetFollowing 获取用户关注的人
+// @Summary 获取关注列表
+// @Tags users
+// @Produce json
+// @Param id path string true "用户ID"
+// @Param page query int false "页码" default(1)
+// @Param limit query int false "每页数量" default(20)
+// @Success 200 {object} utils.PageResponse{data=[]models.PublicUser}
+// @Router /api/users/{id}/following [get]
+func (fc *FollowController) GetFollowing(c *gin.Context) {
+	page, limit := utils.PageParams(c, utils.DefaultPageLimit)
+
+	users, total, err := fc.followService.Following(c.Request.Context(), c.Param("id"), page, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	utils.Paginate(c, users, total, page, limit)
+}
+
+// GetFeed 获取关注动态
+// @Summary 获取关注动态
+// @Description 我关注的人最近发布的书籍和上架，最新的在前，按游标翻页
+// @Tags feed
+// @Produce json
+// @Security Bearer
+// @Param cursor query string false "上一页返回的 next_cursor"
+// @Param limit query int false "每页数量" default(20)
+// @Success 200 {object} utils.PageResponse{data=[]models.FeedItem}
+// @Router /api/feed [get]
+func (fc *FollowController) GetFeed(c *gin.Context) {
+	cursor, limit, err := utils.CursorParams(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	items, err := fc.followService.Feed(c.Request.Context(), c.GetString("user_id"), cursor, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	utils.PaginateCursor(c, items, limit, func(item models.FeedItem) (time.Time, string) {
+		return item.CreatedAt, item.ID
+	})
+}
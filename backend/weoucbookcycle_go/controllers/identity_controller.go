@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"net/http"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdentityController 登录身份绑定控制器
+type IdentityController struct {
+	identityService *services.IdentityService
+}
+
+// NewIdentityController 创建登录身份控制器实例
+func NewIdentityController(identityService *services.IdentityService) *IdentityController {
+	return &IdentityController{
+		identityService: identityService,
+	}
+}
+
+// ListIdentities 获取我绑定的登录身份
+// @Summary 获取绑定的登录身份
+// @Description 返回账号绑定的邮箱、手机号和微信，可以用其中任意一个登录
+// @Tags users
+// @Produce json
+// @Security Bearer
+// @Success 200 {array} models.UserIdentity
+// @Router /api/users/me/identities [get]
+func (ic *IdentityController) ListIdentities(c *gin.Context) {
+	identities, err := ic.identityService.List(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Success",
+		"data":    identities,
+	})
+}
+
+// LinkIdentity 绑定邮箱或手机号
+// @Summary 绑定邮箱或手机号
+// @Description 分两步：先不带 code 请求，向该邮箱或手机号发送验证码（返回202，1分钟内只能发送一次）；
+// @Description 再带上收到的 code 请求完成绑定（返回201）。已被其他账号绑定时返回409，未接入短信服务时不能绑定手机号（503）
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body services.IdentityRequest true "要绑定的邮箱或手机号"
+// @Success 201 {object} models.UserIdentity
+// @Success 202 {object} map[string]interface{}
+// @Router /api/users/me/identities [post]
+func (ic *IdentityController) LinkIdentity(c *gin.Context) {
+	var req services.IdentityRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	userID := c.GetString("user_id")
+	if req.Code == "" {
+		if err := ic.identityService.SendCode(c.Request.Context(), userID, req.Provider, req.Subject, utils.RequestLang(c)); err != nil {
+			c.Error(err)
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{
+			"code":    20000,
+			"message": "Verification code sent",
+		})
+		return
+	}
+
+	identity, err := ic.identityService.Confirm(c.Request.Context(), userID, req.Provider, req.Subject, req.Code)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"code":    20000,
+		"message": "Identity linked",
+		"data":    identity,
+	})
+}
+
+// LinkWeChat 绑定微信
+// @Summary 绑定微信
+// @Description 使用小程序 wx.login 获取的 code 把微信绑定到当前账号，之后可以用微信登录
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body services.WeChatIdentityRequest true "微信登录code"
+// @Success 201 {object} models.UserIdentity
+// @Router /api/users/me/identities/wechat [post]
+func (ic *IdentityController) LinkWeChat(c *gin.Context) {
+	var req services.WeChatIdentityRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	identity, err := ic.identityService.LinkWeChat(c.Request.Context(), c.GetString("user_id"), req.Code)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"code":    20000,
+		"message": "Identity linked",
+		"data":    identity,
+	})
+}
+
+// UnlinkIdentity 解绑登录身份
+// @Summary 解绑登录身份
+// @Description 解绑后账号必须仍有可用的登录方式，否则返回409；解绑主邮箱前需要先绑定另一个邮箱，之后它成为主邮箱
+// @Tags users
+// @Produce json
+// @Security Bearer
+// @Param id path string true "身份ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/users/me/identities/{id} [delete]
+func (ic *IdentityController) UnlinkIdentity(c *gin.Context) {
+	if err := ic.identityService.Unlink(c.Request.Context(), c.GetString("user_id"), c.Param("id")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Identity unlinked",
+	})
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"net/http"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImpersonationController 管理员代登录控制器
+type ImpersonationController struct {
+	impersonationService *services.ImpersonationService
+}
+
+// NewImpersonationController 创建代登录控制器实例
+func NewImpersonationController(impersonationService *services.ImpersonationService) *ImpersonationController {
+	return &ImpersonationController{
+		impersonationService: impersonationService,
+	}
+}
+
+// pagination 解析分页参数
+func (ic *ImpersonationController) pagination(c *gin.Context) (int, int) {
+	page, limit := utils.PageParams(c, utils.DefaultPageLimit)
+	return page, limit
+}
+
+// StartImpersonation 代登录用户
+// @Summary 代登录用户
+// @Description 为排查问题获取用户身份的临时token。默认只读、30分钟有效，不能刷新；期间的请求都会记录审计日志
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body services.StartImpersonationRequest true "代登录信息"
+// @Success 201 {object} map[string]interface{}
+// @Router /api/admin/impersonations [post]
+func (ic *ImpersonationController) StartImpersonation(c *gin.Context) {
+	var req services.StartImpersonationRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	token, session, err := ic.impersonationService.Start(c.Request.Context(), c.GetString("user_id"), c.ClientIP(), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"code":    20000,
+		"message": "Impersonation started",
+		"data": gin.H{
+			"token":   token,
+			"session": session,
+		},
+	})
+}
+
+// EndImpersonation 结束代登录
+// @Summary 结束代登录
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param id path string true "代登录会话ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/impersonations/{id} [delete]
+func (ic *ImpersonationController) EndImpersonation(c *gin.Context) {
+	if err := ic.impersonationService.End(c.Request.Context(), c.GetString("user_id"), c.Param("id")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 20000, "message": "Impersonation ended"})
+}
+
+// ListSessions 代登录会话列表
+// @Summary 代登录会话列表
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param admin_id query string false "管理员ID"
+// @Param user_id query string false "被代登录的用户ID"
+// @Param page query int false "页码" default(1)
+// @Param limit query int false "每页数量" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/impersonations [get]
+func (ic *ImpersonationController) ListSessions(c *gin.Context) {
+	page, limit := ic.pagination(c)
+
+	sessions, total, err := ic.impersonationService.ListSessions(c.Query("admin_id"), c.Query("user_id"), page, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	utils.Paginate(c, sessions, total, page, limit)
+}
+
+// ListAuditLogs 代登录审计日志
+// @Summary 代登录审计日志
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param id path string true "代登录会话ID"
+// @Param page query int false "页码" default(1)
+// @Param limit query int false "每页数量" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/impersonations/{id}/logs [get]
+func (ic *ImpersonationController) ListAuditLogs(c *gin.Context) {
+	page, limit := ic.pagination(c)
+
+	logs, total, err := ic.impersonationService.ListAuditLogs(c.Param("id"), page, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	utils.Paginate(c, logs, total, page, limit)
+}
@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"net/http"
+	"weoucbookcycle_go/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LeaderboardController 排行榜控制器
+type LeaderboardController struct {
+	leaderboardService *services.LeaderboardService
+}
+
+// NewLeaderboardController 创建排行榜控制器实例
+func NewLeaderboardController(leaderboardService *services.LeaderboardService) *LeaderboardController {
+	return &LeaderboardController{
+		leaderboardService: leaderboardService,
+	}
+}
+
+// GetLeaderboards 获取排行榜
+// @Summary 获取排行榜
+// @Description 本月最佳卖家（售出数）、浏览最多的书籍和最活跃的回收达人（新发布 + 送出），每10分钟刷新一次。
+// @Description 自买自卖、同一对买卖双方超过3笔的成交、每天超过5本的新发布、本人和重复的浏览不计入
+// @Tags leaderboards
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/leaderboards [get]
+func (lc *LeaderboardController) GetLeaderboards(c *gin.Context) {
+	boards, err := lc.leaderboardService.Get(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Success",
+		"data":    boards,
+	})
+}
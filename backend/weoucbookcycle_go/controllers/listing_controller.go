@@ -1,34 +1,58 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
-	"strconv"
 	"time"
 	"weoucbookcycle_go/config"
 	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
 )
 
 // ListingController 发布控制器
 type ListingController struct {
-	redisClient *redis.Client
+	pushService   *services.PushService
+	followService *services.FollowService
+	blockService  *services.BlockService
+	campusService *services.CampusService
+	db            *gorm.DB
+	redisClient   *redis.Client
 }
 
 // NewListingController 创建发布控制器实例
-func NewListingController() *ListingController {
+func NewListingController(pushService *services.PushService, followService *services.FollowService, blockService *services.BlockService, campusService *services.CampusService,
+	db *gorm.DB, redisClient *redis.Client) *ListingController {
 	return &ListingController{
-		redisClient: initRedis(),
+		pushService:   pushService,
+		followService: followService,
+		blockService:  blockService,
+		campusService: campusService,
+		db:            db,
+		redisClient:   redisClient,
 	}
 }
 
+// listingActiveAt 发布列表的排序时间，擦亮过的发布按擦亮时间排在前面
+const listingActiveAt = "COALESCE(listings.bumped_at, listings.created_at)"
+
 // CreateListingRequest 创建发布请求结构
 type CreateListingRequest struct {
 	BookID string  `json:"book_id" binding:"required"`
-	Price  float64 `json:"price" binding:"required,gt=0"`
+	Price  float64 `json:"price" binding:"gte=0"`
 	Note   string  `json:"note" binding:"max=500"`
+	// IsDonation 免费赠送，价格固定为0，其他用户可直接认领
+	IsDonation bool `json:"is_donation"`
+	// 面交地点，不填时使用卖家资料中的校区和宿舍区
+	CampusID   string `json:"campus_id"`
+	DormAreaID string `json:"dorm_area_id"`
+	// PickupPointID 面交校区内的面交地点，可选
+	PickupPointID string `json:"pickup_point_id"`
 }
 
 // UpdateListingStatusRequest 更新发布状态请求结构
@@ -39,27 +63,30 @@ type UpdateListingStatusRequest struct {
 
 // GetListings 获取发布列表
 // @Summary 获取发布列表
-// @Description 分页获取发布列表
+// @Description 分页获取发布列表，按发布时间倒序（擦亮过的按擦亮时间）
 // @Tags listings
 // @Accept json
 // @Produce json
 // @Param page query int false "页码" default(1)
 // @Param limit query int false "每页数量" default(20)
 // @Param status query string false "状态筛选"
+// @Param campus_id query string false "面交校区，默认为登录用户所在校区，all 表示不限"
 // @Success 200 {object} map[string]interface{}
-// @Router /api/v1/listings [get]
+// @Router /api/listings [get]
 func (lc *ListingController) GetListings(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	offset := (page - 1) * limit
+	ctx := c.Request.Context()
+	page, limit := utils.PageParams(c, utils.DefaultPageLimit)
 	status := c.Query("status")
 
 	// 构建查询
-	query := config.DB.Model(&models.Listing{})
+	query := config.ReadReplica(lc.db.WithContext(ctx)).Model(&models.Listing{}).Scopes(services.VisibleUsers("listings.seller_id"))
 
 	if status != "" {
 		query = query.Where("status = ?", status)
 	}
+	if campusID := lc.campusService.PickupCampus(ctx, c.GetString("user_id"), c.Query("campus_id")); campusID != "" {
+		query = query.Where("listings.campus_id = ?", campusID)
+	}
 
 	// 获取总数
 	var total int64
@@ -72,118 +99,219 @@ func (lc *ListingController) GetListings(c *gin.Context) {
 		Preload("Book.Seller").
 		Preload("Seller").
 		Preload("Buyer").
-		Order("created_at DESC").
+		Order(listingActiveAt + " DESC").
 		Limit(limit).
-		Offset(offset).
+		Offset(utils.PageOffset(page, limit)).
 		Find(&listings).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get listings"})
+		c.Error(utils.NewError(http.StatusInternalServerError, "Failed to get listings"))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"listings": listings,
-		"total":    total,
-		"page":     page,
-		"limit":    limit,
+	utils.Paginate(c, listings, total, page, limit)
+}
+
+// GetListingsV2 获取发布列表（游标分页）
+// @Summary 获取发布列表（游标分页）
+// @Description 按发布时间倒序（擦亮过的按擦亮时间），使用上一页返回的 next_cursor 翻页，不返回总数
+// @Tags listings
+// @Produce json
+// @Param cursor query string false "上一页返回的 next_cursor"
+// @Param limit query int false "每页数量" default(20)
+// @Param status query string false "状态筛选"
+// @Param campus_id query string false "面交校区，默认为登录用户所在校区，all 表示不限"
+// @Success 200 {object} utils.PageResponse{data=[]models.Listing}
+// @Router /api/v2/listings [get]
+func (lc *ListingController) GetListingsV2(c *gin.Context) {
+	ctx := c.Request.Context()
+	cursor, limit, err := utils.CursorParams(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	query := config.ReadReplica(lc.db.WithContext(ctx)).Model(&models.Listing{}).Scopes(services.VisibleUsers("listings.seller_id"))
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if campusID := lc.campusService.PickupCampus(ctx, c.GetString("user_id"), c.Query("campus_id")); campusID != "" {
+		query = query.Where("listings.campus_id = ?", campusID)
+	}
+
+	var listings []models.Listing
+	if err := utils.ApplyCursorBy(query.
+		Preload("Book").
+		Preload("Book.Seller").
+		Preload("Seller").
+		Preload("Buyer"), listingActiveAt, "listings.id", cursor, limit).
+		Find(&listings).Error; err != nil {
+		c.Error(utils.WrapError(http.StatusInternalServerError, "Failed to get listings", err))
+		return
+	}
+
+	utils.PaginateCursor(c, listings, limit, func(l models.Listing) (time.Time, string) {
+		return l.ActiveAt(), l.ID
 	})
 }
 
 // GetListing 获取发布详情
 // @Summary 获取发布详情
-// @Description 根据发布ID获取详细信息
+// @Description 根据发布ID获取详细信息，卖家的联系方式只返回给已登录且未被卖家屏蔽的用户
 // @Tags listings
 // @Accept json
 // @Produce json
 // @Param id path string true "发布ID"
+// @Param If-None-Match header string false "上次响应的 ETag，内容未变化时返回304"
 // @Success 200 {object} models.Listing
-// @Router /api/v1/listings/{id} [get]
+// @Header 200 {string} ETag "响应内容的哈希"
+// @Success 304 "内容未变化"
+// @Router /api/listings/{id} [get]
 func (lc *ListingController) GetListing(c *gin.Context) {
+	ctx := c.Request.Context()
 	listingID := c.Param("id")
 
-	// 先尝试从Redis缓存获取
+	// 先尝试从Redis缓存获取，缓存的JSON直接作为响应体，ETag 为其哈希
 	cacheKey := "listing:" + listingID
-	cached, err := lc.redisClient.Get(ctx, cacheKey).Result()
-	if err == nil {
-		var listing models.Listing
-		if json.Unmarshal([]byte(cached), &listing) == nil {
-			c.JSON(http.StatusOK, listing)
-			return
-		}
+	cached, err := lc.redisClient.Get(ctx, cacheKey).Bytes()
+	if err == nil && json.Valid(cached) {
+		utils.ServeJSONWithETag(c, lc.withSellerContact(c, cached))
+		utils.RecordCacheHit("listings")
+		return
 	}
+	utils.RecordCacheMiss("listings")
 
 	// 从数据库查询
 	var listing models.Listing
-	if err := config.DB.
+	if err := lc.db.WithContext(ctx).
 		Preload("Book").
 		Preload("Book.Seller").
 		Preload("Seller").
 		Preload("Buyer").
 		First(&listing, "id = ?", listingID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found"})
+		c.Error(utils.NewError(http.StatusNotFound, "Listing not found"))
+		return
+	}
+
+	data, err := json.Marshal(listing)
+	if err != nil {
+		c.Error(utils.WrapError(http.StatusInternalServerError, "Failed to encode listing", err))
+		return
+	}
+
+	// 卖家停用账号后发布只对本人可见，也不写入共用的缓存
+	if listing.Seller.Status == models.UserStatusDeactivated {
+		if listing.SellerID != c.GetString("user_id") {
+			c.Error(utils.NewError(http.StatusNotFound, "Listing not found"))
+			return
+		}
+		utils.ServeJSONWithETag(c, lc.withSellerContact(c, data))
 		return
 	}
 
 	// 异步缓存到Redis
 	go func() {
-		data, _ := json.Marshal(listing)
+		ctx := context.WithoutCancel(ctx)
 		lc.redisClient.Set(ctx, cacheKey, data, time.Minute*10)
 	}()
 
-	c.JSON(http.StatusOK, listing)
+	utils.ServeJSONWithETag(c, lc.withSellerContact(c, data))
+}
+
+// withSellerContact 已登录且未被卖家屏蔽的用户在详情中看到卖家的联系方式
+// 缓存的是所有人共用的响应体，只含卖家的公开信息，因此在输出前按请求用户补上
+func (lc *ListingController) withSellerContact(c *gin.Context, data []byte) []byte {
+	viewerID := c.GetString("user_id")
+	if viewerID == "" {
+		return data
+	}
+	var listing models.Listing
+	if err := json.Unmarshal(data, &listing); err != nil {
+		return data
+	}
+	contact := lc.blockService.VisibleContact(c.Request.Context(), listing.SellerID, viewerID)
+	if contact == nil {
+		return data
+	}
+	listing.RevealSellerContact(contact)
+	if revealed, err := json.Marshal(listing); err == nil {
+		return revealed
+	}
+	return data
 }
 
 // CreateListing 创建发布
 // @Summary 创建发布
-// @Description 创建新的书籍发布
+// @Description 创建新的书籍发布，is_donation 为 true 时免费赠送（价格为0，出现在 /api/books/free 中），否则价格必须大于0；
+// @Description pickup_point_id 为面交校区内的面交地点（见 /api/campuses），不属于该校区时返回400
 // @Tags listings
 // @Accept json
 // @Produce json
 // @Security Bearer
 // @Param request body CreateListingRequest true "发布信息"
 // @Success 201 {object} models.Listing
-// @Router /api/v1/listings [post]
+// @Router /api/listings [post]
 func (lc *ListingController) CreateListing(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID := c.GetString("user_id")
 
 	var req CreateListingRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+	if req.IsDonation {
+		req.Price = 0
+	} else if req.Price <= 0 {
+		c.Error(utils.NewError(http.StatusBadRequest, "price must be greater than 0 unless the book is donated"))
 		return
 	}
 
 	// 检查书籍是否存在
 	var book models.Book
-	if err := config.DB.First(&book, "id = ?", req.BookID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+	if err := lc.db.WithContext(ctx).First(&book, "id = ?", req.BookID).Error; err != nil {
+		c.Error(utils.NewError(http.StatusNotFound, "Book not found"))
 		return
 	}
 
 	// 检查是否已有发布的listing
 	var existingListing models.Listing
-	if err := config.DB.Where("book_id = ? AND seller_id = ? AND status IN ?",
+	if err := lc.db.WithContext(ctx).Where("book_id = ? AND seller_id = ? AND status IN ?",
 		req.BookID, userID, []string{"available", "reserved"}).First(&existingListing).Error; err == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "This book is already listed"})
+		c.Error(utils.NewError(http.StatusConflict, "This book is already listed"))
+		return
+	}
+
+	// 面交地点默认取卖家资料中的位置（校区停用后仍保留），指定时需要是有效的位置
+	if req.CampusID == "" && req.DormAreaID == "" {
+		req.CampusID, req.DormAreaID = lc.campusService.UserLocation(ctx, userID)
+	} else if err := lc.campusService.ValidateLocation(ctx, req.CampusID, req.DormAreaID); err != nil {
+		c.Error(err)
+		return
+	}
+	if err := lc.campusService.ValidatePickupPoint(ctx, req.CampusID, req.PickupPointID); err != nil {
+		c.Error(err)
 		return
 	}
 
 	listing := models.Listing{
-		BookID:   req.BookID,
-		SellerID: userID,
-		Price:    req.Price,
-		Note:     req.Note,
-		Status:   "available",
+		BookID:        req.BookID,
+		SellerID:      userID,
+		Price:         req.Price,
+		Note:          req.Note,
+		IsDonation:    req.IsDonation,
+		Status:        "available",
+		CampusID:      req.CampusID,
+		DormAreaID:    req.DormAreaID,
+		PickupPointID: req.PickupPointID,
 	}
 
-	if err := config.DB.Create(&listing).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create listing"})
+	if err := lc.db.WithContext(ctx).Create(&listing).Error; err != nil {
+		c.Error(utils.NewError(http.StatusInternalServerError, "Failed to create listing"))
 		return
 	}
 
-	// 异步发送通知给关注者（使用goroutine）
-	go func() {
-		// 这里可以添加通知逻辑
-		// notifyFollowers(userID, listing.ID)
-	}()
+	// 推送到关注者的动态
+	lc.followService.PublishToFeed(ctx, userID, models.FeedTypeListing, listing.ID, listing.CreatedAt)
+	lc.redisClient.Del(context.WithoutCancel(ctx), services.DashboardCacheKey(userID))
 
 	c.JSON(http.StatusCreated, listing)
 }
@@ -198,30 +326,41 @@ func (lc *ListingController) CreateListing(c *gin.Context) {
 // @Param id path string true "发布ID"
 // @Param request body UpdateListingStatusRequest true "状态更新信息"
 // @Success 200 {object} models.Listing
-// @Router /api/v1/listings/{id}/status [put]
+// @Router /api/listings/{id}/status [put]
 func (lc *ListingController) UpdateListingStatus(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID := c.GetString("user_id")
 	listingID := c.Param("id")
 
 	var listing models.Listing
-	if err := config.DB.First(&listing, "id = ?", listingID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found"})
+	if err := lc.db.WithContext(ctx).First(&listing, "id = ?", listingID).Error; err != nil {
+		c.Error(utils.NewError(http.StatusNotFound, "Listing not found"))
 		return
 	}
 
 	var req UpdateListingStatusRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
 		return
 	}
 
 	// 检查权限：只有卖家可以修改状态
 	if listing.SellerID != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to update this listing"})
+		c.Error(utils.NewError(http.StatusForbidden, "You don't have permission to update this listing"))
 		return
 	}
 
+	// 买家必须是与卖家有过会话或已认领该赠书的正常用户，成交事件会据此发放积分和邀请奖励
+	if req.Status == "sold" && req.BuyerID != "" {
+		claimed := listing.IsDonation && listing.Status == "reserved" && listing.BuyerID == req.BuyerID
+		if err := services.VerifyBuyer(lc.db.WithContext(ctx), userID, req.BuyerID, claimed); err != nil {
+			c.Error(err)
+			return
+		}
+	}
+
 	// 更新状态
+	previousStatus, buyerID := listing.Status, listing.BuyerID
 	updates := map[string]interface{}{
 		"status": req.Status,
 	}
@@ -231,21 +370,40 @@ func (lc *ListingController) UpdateListingStatus(c *gin.Context) {
 		updates["buyer_id"] = req.BuyerID
 	}
 
-	if err := config.DB.Model(&listing).Updates(updates).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update listing status"})
+	// 发布状态和售出后的书籍状态在同一事务中更新
+	err := services.WithTx(ctx, lc.db, func(ctx context.Context, tx *gorm.DB) error {
+		if err := tx.Model(&listing).Updates(updates).Error; err != nil {
+			return err
+		}
+		if req.Status != "sold" {
+			return nil
+		}
+		return tx.Model(&models.Book{}).Where("id = ?", listing.BookID).Update("status", 0).Error
+	})
+	if err != nil {
+		c.Error(utils.NewError(http.StatusInternalServerError, "Failed to update listing status"))
 		return
 	}
 
-	// 如果是sold状态，更新书籍状态
-	if req.Status == "sold" {
-		go func() {
-			config.DB.Model(&models.Book{}).Where("id = ?", listing.BookID).Update("status", 0)
-		}()
+	if req.Status == "sold" && previousStatus != "sold" {
+		services.RecordDailyStat(ctx, lc.redisClient, services.StatListingsSold)
+	}
+
+	// 通知买家交易状态变化
+	if req.BuyerID != "" {
+		buyerID = req.BuyerID
+	}
+	if req.Status == "sold" && previousStatus != "sold" {
+		services.RecordListingSold(ctx, lc.redisClient, listingID, userID, buyerID, listing.IsDonation)
+	}
+	if buyerID != "" && req.Status != previousStatus {
+		lc.pushService.PushListingStatus(ctx, buyerID, listingID, req.Status)
 	}
 
 	// 删除缓存
 	go func() {
-		lc.redisClient.Del(ctx, "listing:"+listingID)
+		ctx := context.WithoutCancel(ctx)
+		lc.redisClient.Del(ctx, "listing:"+listingID, services.DashboardCacheKey(userID))
 	}()
 
 	c.JSON(http.StatusOK, listing)
@@ -253,27 +411,62 @@ func (lc *ListingController) UpdateListingStatus(c *gin.Context) {
 
 // GetMyListings 获取我的发布列表
 // @Summary 获取我的发布列表
-// @Description 获取当前登录用户的发布列表
+// @Description 获取当前登录用户的全部发布（v1 不分页，/api/v2/listings/mine 按页返回）
 // @Tags listings
 // @Accept json
 // @Produce json
 // @Security Bearer
-// @Success 200 {array} models.Listing
-// @Router /api/v1/listings/mine [get]
+// @Success 200 {object} map[string]interface{}
+// @Router /api/listings/mine [get]
 func (lc *ListingController) GetMyListings(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID := c.GetString("user_id")
 
 	var listings []models.Listing
-	if err := config.DB.
+	if err := lc.db.WithContext(ctx).
 		Preload("Book").
 		Where("seller_id = ?", userID).
 		Order("created_at DESC").
 		Find(&listings).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get my listings"})
+		c.Error(utils.NewError(http.StatusInternalServerError, "Failed to get my listings"))
+		return
+	}
+
+	utils.PaginateAll(c, listings)
+}
+
+// GetMyListingsV2 获取我的发布列表（分页）
+// @Summary 获取我的发布列表（分页）
+// @Description 分页获取当前登录用户的发布列表
+// @Tags listings
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param page query int false "页码" default(1)
+// @Param limit query int false "每页数量" default(20)
+// @Success 200 {object} utils.PageResponse{data=[]models.Listing}
+// @Router /api/v2/listings/mine [get]
+func (lc *ListingController) GetMyListingsV2(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID := c.GetString("user_id")
+	page, limit := utils.PageParams(c, utils.DefaultPageLimit)
+
+	query := lc.db.WithContext(ctx).Model(&models.Listing{}).Where("seller_id = ?", userID)
+	var total int64
+	query.Count(&total)
+
+	var listings []models.Listing
+	if err := query.
+		Preload("Book").
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(utils.PageOffset(page, limit)).
+		Find(&listings).Error; err != nil {
+		c.Error(utils.NewError(http.StatusInternalServerError, "Failed to get my listings"))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"listings": listings})
+	utils.Paginate(c, listings, total, page, limit)
 }
 
 // FavoriteListing 收藏/取消收藏发布
@@ -285,46 +478,57 @@ func (lc *ListingController) GetMyListings(c *gin.Context) {
 // @Security Bearer
 // @Param id path string true "发布ID"
 // @Success 200 {object} map[string]interface{}
-// @Router /api/v1/listings/{id}/favorite [post]
+// @Router /api/listings/{id}/favorite [post]
 func (lc *ListingController) FavoriteListing(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID := c.GetString("user_id")
 	listingID := c.Param("id")
 
+	var listing models.Listing
+	if err := lc.db.WithContext(ctx).Select("id", "seller_id").First(&listing, "id = ?", listingID).Error; err != nil {
+		c.Error(utils.NewError(http.StatusNotFound, "Listing not found"))
+		return
+	}
+
 	// 检查是否已收藏
 	var favorite models.Favorite
-	err := config.DB.Where("user_id = ? AND listing_id = ?", userID, listingID).First(&favorite).Error
+	err := lc.db.WithContext(ctx).Where("user_id = ? AND listing_id = ?", userID, listingID).First(&favorite).Error
 
 	if err == nil {
 		// 已收藏，取消收藏
-		if err := config.DB.Delete(&favorite).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unfavorite"})
+		if err := lc.db.WithContext(ctx).Delete(&favorite).Error; err != nil {
+			c.Error(utils.NewError(http.StatusInternalServerError, "Failed to unfavorite"))
 			return
 		}
 
 		// 减少收藏计数
-		go func() {
-			config.DB.Exec("UPDATE listings SET favorite_count = favorite_count - 1 WHERE id = ?", listingID)
-		}()
+		utils.Go(ctx, "favorite_count", func(ctx context.Context) error {
+			return lc.db.WithContext(ctx).Exec("UPDATE listings SET favorite_count = favorite_count - 1 WHERE id = ?", listingID).Error
+		})
 
 		c.JSON(http.StatusOK, gin.H{"message": "Unfavorited successfully"})
 		return
 	}
 
-	// 未收藏，添加收藏
+	// 未收藏，添加收藏；被卖家屏蔽（或屏蔽了卖家）时不能收藏
+	if err := lc.blockService.Check(ctx, userID, listing.SellerID); err != nil {
+		c.Error(err)
+		return
+	}
 	favorite = models.Favorite{
 		UserID:    userID,
 		ListingID: listingID,
 	}
 
-	if err := config.DB.Create(&favorite).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to favorite"})
+	if err := lc.db.WithContext(ctx).Create(&favorite).Error; err != nil {
+		c.Error(utils.NewError(http.StatusInternalServerError, "Failed to favorite"))
 		return
 	}
 
 	// 增加收藏计数
-	go func() {
-		config.DB.Exec("UPDATE listings SET favorite_count = favorite_count + 1 WHERE id = ?", listingID)
-	}()
+	utils.Go(ctx, "favorite_count", func(ctx context.Context) error {
+		return lc.db.WithContext(ctx).Exec("UPDATE listings SET favorite_count = favorite_count + 1 WHERE id = ?", listingID).Error
+	})
 
 	c.JSON(http.StatusOK, gin.H{"message": "Favorited successfully"})
 }
@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"net/http"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ModerationController 统一审核队列控制器（管理员）
+type ModerationController struct {
+	moderationService *services.ModerationService
+}
+
+// NewModerationController 创建审核队列控制器实例
+func NewModerationController(moderationService *services.ModerationService) *ModerationController {
+	return &ModerationController{
+		moderationService: moderationService,
+	}
+}
+
+// ListQueue 审核队列
+// @Summary 审核队列
+// @Description 用户举报和自动审核的待处理内容，默认返回 pending 和 escalated 状态
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param status query string false "状态: pending, escalated, approved, rejected"
+// @Param target_type query string false "对象类型: user, book, listing, chat, message, image"
+// @Param source query string false "来源: report, image_moderation"
+// @Param assigned_to query string false "处理人ID，me 表示自己"
+// @Param page query int false "页码" default(1)
+// @Param limit query int false "每页数量" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/moderation [get]
+func (mc *ModerationController) ListQueue(c *gin.Context) {
+	page, limit := utils.PageParams(c, utils.DefaultPageLimit)
+
+	assignedTo := c.Query("assigned_to")
+	if assignedTo == "me" {
+		assignedTo = c.GetString("user_id")
+	}
+
+	items, total, err := mc.moderationService.List(&services.ModerationQuery{
+		Page:       page,
+		Limit:      limit,
+		Status:     c.Query("status"),
+		TargetType: c.Query("target_type"),
+		Source:     c.Query("source"),
+		AssignedTo: assignedTo,
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	utils.Paginate(c, items, total, page, limit)
+}
+
+// GetItem 审核条目详情
+// @Summary 审核条目详情
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param id path string true "审核条目ID"
+// @Success 200 {object} services.ModerationItemDetail
+// @Router /api/admin/moderation/{id} [get]
+func (mc *ModerationController) GetItem(c *gin.Context) {
+	item, err := mc.moderationService.Get(c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 20000, "message": "Success", "data": item})
+}
+
+// AssignModerationRequest 分配审核条目请求
+type AssignModerationRequest struct {
+	AssigneeID string `json:"assignee_id"`
+}
+
+// AssignItem 分配处理人
+// @Summary 分配审核条目
+// @Description assignee_id 为空时分配给自己
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "审核条目ID"
+// @Param request body AssignModerationRequest false "处理人"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/moderation/{id}/assign [post]
+func (mc *ModerationController) AssignItem(c *gin.Context) {
+	var req AssignModerationRequest
+	_ = c.ShouldBindJSON(&req)
+
+	item, err := mc.moderationService.Assign(c.GetString("user_id"), c.Param("id"), req.AssigneeID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 20000, "message": "Moderation item assigned", "data": item})
+}
+
+// EscalateModerationRequest 升级审核条目请求
+type EscalateModerationRequest struct {
+	Note string `json:"note" binding:"max=500"`
+}
+
+// EscalateItem 升级审核条目
+// @Summary 升级审核条目
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "审核条目ID"
+// @Param request body EscalateModerationRequest false "升级说明"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/moderation/{id}/escalate [post]
+func (mc *ModerationController) EscalateItem(c *gin.Context) {
+	var req EscalateModerationRequest
+	if err := utils.BindAndValidate(c, &req); err != nil && c.Request.ContentLength > 0 {
+		c.Error(err)
+		return
+	}
+
+	item, err := mc.moderationService.Escalate(c.GetString("user_id"), c.Param("id"), req.Note)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 20000, "message": "Moderation item escalated", "data": item})
+}
+
+// ResolveItem 处理审核条目
+// @Summary 处理审核条目
+// @Description approve 驳回举报；reject 确认违规，可选 remove 移除内容或 ban 封禁所有者。处理后通知举报人和内容所有者
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "审核条目ID"
+// @Param request body services.ResolveModerationRequest true "处理结果"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/moderation/{id}/resolve [post]
+func (mc *ModerationController) ResolveItem(c *gin.Context) {
+	var req services.ResolveModerationRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	item, err := mc.moderationService.Resolve(c.Request.Context(), c.GetString("user_id"), c.Param("id"), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 20000, "message": "Moderation item resolved", "data": item})
+}
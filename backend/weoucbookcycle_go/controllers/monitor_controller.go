@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MonitorController 运行监控控制器（管理员）
+type MonitorController struct {
+	queueMonitorService *services.QueueMonitorService
+	scheduler           *services.Scheduler
+}
+
+// NewMonitorController 创建运行监控控制器实例
+func NewMonitorController(queueMonitorService *services.QueueMonitorService, scheduler *services.Scheduler) *MonitorController {
+	return &MonitorController{
+		queueMonitorService: queueMonitorService,
+		scheduler:           scheduler,
+	}
+}
+
+// GetQueues 队列积压情况
+// @Summary 队列积压情况
+// @Description Redis流长度、各消费组的未确认数和滞后量，以及当前实例进程内队列（邮件、消息、统计、广播等）的深度
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} services.QueueMonitorReport
+// @Router /api/admin/monitor/queues [get]
+func (mc *MonitorController) GetQueues(c *gin.Context) {
+	report, err := mc.queueMonitorService.Snapshot(c.Request.Context())
+	if err != nil && !errors.Is(err, services.ErrMonitorUnavailable) {
+		c.Error(err)
+		return
+	}
+
+	// Redis 不可用时仍返回进程内队列
+	message := "Success"
+	if err != nil {
+		message = err.Error()
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": message,
+		"data":    report,
+	})
+}
+
+// GetCronJobs 定时任务状态
+// @Summary 定时任务状态
+// @Description 各定时任务的执行计划、开关（运行时参数 cron_<name>_enabled）、本实例下一次触发时间和最近一次执行结果
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Success 200 {array} services.CronJobStatus
+// @Router /api/admin/monitor/cron [get]
+func (mc *MonitorController) GetCronJobs(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Success",
+		"data":    mc.scheduler.Status(c.Request.Context()),
+	})
+}
+
+// GetBreakers 熔断器状态
+// @Summary 熔断器状态
+// @Description Redis、SMTP 熔断器的当前状态（closed/half-open/open）和连续失败次数，只反映当前实例
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Success 200 {array} utils.BreakerState
+// @Router /api/admin/monitor/breakers [get]
+func (mc *MonitorController) GetBreakers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Success",
+		"data":    utils.BreakerStates(),
+	})
+}
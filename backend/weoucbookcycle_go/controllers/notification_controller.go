@@ -0,0 +1,367 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationController 站内通知控制器
+type NotificationController struct {
+	notificationService *services.NotificationService
+	pushService         *services.PushService
+}
+
+// NewNotificationController 创建通知控制器实例
+func NewNotificationController(notificationService *services.NotificationService, pushService *services.PushService) *NotificationController {
+	return &NotificationController{
+		notificationService: notificationService,
+		pushService:         pushService,
+	}
+}
+
+// GetNotifications 获取我的通知
+// @Summary 获取通知列表
+// @Tags notifications
+// @Produce json
+// @Security Bearer
+// @Param page query int false "页码" default(1)
+// @Param limit query int false "每页数量" default(20)
+// @Param unread query bool false "仅未读"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/notifications [get]
+func (nc *NotificationController) GetNotifications(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	page, limit := utils.PageParams(c, utils.DefaultPageLimit)
+	unreadOnly := c.Query("unread") == "true"
+
+	notifications, total, err := nc.notificationService.ListNotifications(userID, page, limit, unreadOnly)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	utils.Paginate(c, notifications, total, page, limit)
+}
+
+// GetNotificationsV2 获取我的通知（游标分页）
+// @Summary 获取通知列表（游标分页）
+// @Tags notifications
+// @Produce json
+// @Security Bearer
+// @Param cursor query string false "上一页返回的 next_cursor"
+// @Param limit query int false "每页数量" default(20)
+// @Param unread query bool false "仅未读"
+// @Success 200 {object} utils.PageResponse{data=[]models.Notification}
+// @Router /api/v2/notifications [get]
+func (nc *NotificationController) GetNotificationsV2(c *gin.Context) {
+	cursor, limit, err := utils.CursorParams(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	notifications, err := nc.notificationService.ListNotificationsAfter(c.GetString("user_id"), cursor, limit, c.Query("unread") == "true")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	utils.PaginateCursor(c, notifications, limit, func(n models.Notification) (time.Time, string) {
+		return n.CreatedAt, n.ID
+	})
+}
+
+// MarkNotificationRead 标记通知为已读
+// @Summary 标记通知已读
+// @Tags notifications
+// @Produce json
+// @Security Bearer
+// @Param id path string true "通知ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/notifications/{id}/read [put]
+func (nc *NotificationController) MarkNotificationRead(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	if err := nc.notificationService.MarkAsRead(c.Request.Context(), userID, c.Param("id")); err != nil {
+		c.Error(utils.NewError(http.StatusNotFound, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Notification marked as read",
+	})
+}
+
+// GetUnreadCount 获取未读通知数
+// @Summary 获取未读通知数
+// @Description 返回未读总数和按类型的未读数，用于通知角标
+// @Tags notifications
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} services.UnreadCount
+// @Router /api/notifications/unread-count [get]
+func (nc *NotificationController) GetUnreadCount(c *gin.Context) {
+	count, err := nc.notificationService.GetUnreadCount(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Success",
+		"data":    count,
+	})
+}
+
+// MarkAllNotificationsRead 全部标记为已读
+// @Summary 全部标记已读
+// @Tags notifications
+// @Produce json
+// @Security Bearer
+// @Param type query string false "只标记该类型的通知"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/notifications/read-all [put]
+func (nc *NotificationController) MarkAllNotificationsRead(c *gin.Context) {
+	updated, err := nc.notificationService.MarkAllAsRead(c.Request.Context(), c.GetString("user_id"), c.Query("type"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Notifications marked as read",
+		"data":    gin.H{"updated": updated},
+	})
+}
+
+// DeleteNotification 删除通知
+// @Summary 删除通知
+// @Tags notifications
+// @Produce json
+// @Security Bearer
+// @Param id path string true "通知ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/notifications/{id} [delete]
+func (nc *NotificationController) DeleteNotification(c *gin.Context) {
+	if err := nc.notificationService.DeleteNotification(c.Request.Context(), c.GetString("user_id"), c.Param("id")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Notification deleted",
+	})
+}
+
+// DeleteReadNotifications 清空已读通知
+// @Summary 清空已读通知
+// @Tags notifications
+// @Produce json
+// @Security Bearer
+// @Param type query string false "只删除该类型的通知"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/notifications [delete]
+func (nc *NotificationController) DeleteReadNotifications(c *gin.Context) {
+	deleted, err := nc.notificationService.DeleteReadNotifications(c.GetString("user_id"), c.Query("type"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Read notifications deleted",
+		"data":    gin.H{"deleted": deleted},
+	})
+}
+
+// RegisterDeviceRequest 登记推送设备请求
+type RegisterDeviceRequest struct {
+	Platform   string `json:"platform" binding:"required,oneof=ios android"`
+	Token      string `json:"token" binding:"required,max=255"`
+	AppVersion string `json:"app_version" binding:"max=30"`
+}
+
+// RegisterDevice 登记移动端推送设备
+// @Summary 登记推送设备
+// @Description App 启动或推送令牌刷新时调用，用户不在线时聊天和交易通知会推送到该设备
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body RegisterDeviceRequest true "设备信息"
+// @Success 200 {object} models.DeviceToken
+// @Router /api/notifications/devices [post]
+func (nc *NotificationController) RegisterDevice(c *gin.Context) {
+	var req RegisterDeviceRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	device, err := nc.pushService.RegisterDevice(c.GetString("user_id"), req.Platform, req.Token, req.AppVersion)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Device registered",
+		"data":    device,
+	})
+}
+
+// UnregisterDevice 注销推送设备
+// @Summary 注销推送设备
+// @Description 退出登录时调用，之后该设备不再收到推送
+// @Tags notifications
+// @Produce json
+// @Security Bearer
+// @Param token path string true "推送令牌"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/notifications/devices/{token} [delete]
+func (nc *NotificationController) UnregisterDevice(c *gin.Context) {
+	if err := nc.pushService.UnregisterDevice(c.GetString("user_id"), c.Param("token")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Device unregistered",
+	})
+}
+
+// WebPushSubscriptionRequest 浏览器推送订阅，与前端 PushSubscription.toJSON() 的结构一致
+type WebPushSubscriptionRequest struct {
+	Endpoint string `json:"endpoint" binding:"required,url,max=500"`
+	Keys     struct {
+		P256dh string `json:"p256dh" binding:"required,max=255"`
+		Auth   string `json:"auth" binding:"required,max=100"`
+	} `json:"keys"`
+}
+
+// GetWebPushKey 获取 Web Push 的 VAPID 公钥
+// @Summary 获取Web Push公钥
+// @Description 前端订阅时作为 applicationServerKey 使用，未启用 Web Push 时 enabled 为 false
+// @Tags notifications
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} map[string]interface{}
+// @Router /api/notifications/webpush/key [get]
+func (nc *NotificationController) GetWebPushKey(c *gin.Context) {
+	publicKey := nc.pushService.WebPushPublicKey()
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Success",
+		"data": gin.H{
+			"enabled":    publicKey != "",
+			"public_key": publicKey,
+		},
+	})
+}
+
+// SubscribeWebPush 保存浏览器推送订阅
+// @Summary 订阅Web Push
+// @Description Service Worker 订阅成功后提交 PushSubscription，标签页关闭时聊天通知会推送到浏览器
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body WebPushSubscriptionRequest true "PushSubscription"
+// @Success 200 {object} models.WebPushSubscription
+// @Router /api/notifications/webpush/subscriptions [post]
+func (nc *NotificationController) SubscribeWebPush(c *gin.Context) {
+	var req WebPushSubscriptionRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	sub, err := nc.pushService.SubscribeWebPush(c.GetString("user_id"), req.Endpoint, req.Keys.P256dh, req.Keys.Auth, c.Request.UserAgent())
+	if err != nil {
+		c.Error(utils.NewError(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Subscribed",
+		"data":    sub,
+	})
+}
+
+// UnsubscribeWebPush 取消浏览器推送订阅
+// @Summary 取消Web Push订阅
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body map[string]interface{} true "订阅地址" example='{"endpoint":"https://..."}'
+// @Success 200 {object} map[string]interface{}
+// @Router /api/notifications/webpush/subscriptions [delete]
+func (nc *NotificationController) UnsubscribeWebPush(c *gin.Context) {
+	var req struct {
+		Endpoint string `json:"endpoint" binding:"required"`
+	}
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := nc.pushService.UnsubscribeWebPush(c.GetString("user_id"), req.Endpoint); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Unsubscribed",
+	})
+}
+
+// ReplayEventsRequest 回放事件请求
+type ReplayEventsRequest struct {
+	Stream string `json:"stream" binding:"required,oneof=user_events book_events chat_events"`
+	FromID string `json:"from_id"`
+}
+
+// ReplayEvents 从指定位置重新投递领域事件（管理员）
+// @Summary 回放通知事件
+// @Description 把通知消费者在事件流上的读取位置移到 from_id（默认从头），用于修复投递问题后补发通知，已生成过的通知不会重复
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body ReplayEventsRequest true "事件流和起始ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/notifications/replay [post]
+func (nc *NotificationController) ReplayEvents(c *gin.Context) {
+	var req ReplayEventsRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := nc.notificationService.ReplayEvents(c.Request.Context(), req.Stream, req.FromID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Replay scheduled",
+	})
+}
@@ -0,0 +1,235 @@
+package controllers
+
+import (
+	"net/http"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PointsController 绿色积分控制器
+type PointsController struct {
+	pointsService *services.PointsService
+}
+
+// NewPointsController 创建积分控制器实例
+func NewPointsController(pointsService *services.PointsService) *PointsController {
+	return &PointsController{
+		pointsService: pointsService,
+	}
+}
+
+// GetMyPoints 获取我的积分余额
+// @Summary 获取积分余额
+// @Description 返回余额、今天已获得的积分和每日上限。指定了买家的成交为卖家和买家各发放积分，
+// @Description 同一对买卖双方30天内只有前几笔交易计分，每天获得的积分有上限（均可由管理员调整）
+// @Tags points
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} services.PointsSummary
+// @Router /api/users/me/points [get]
+func (pc *PointsController) GetMyPoints(c *gin.Context) {
+	summary, err := pc.pointsService.Summary(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Success",
+		"data":    summary,
+	})
+}
+
+// GetMyPointsLedger 获取我的积分流水
+// @Summary 获取积分流水
+// @Tags points
+// @Produce json
+// @Security Bearer
+// @Param page query int false "页码" default(1)
+// @Param limit query int false "每页数量" default(20)
+// @Success 200 {object} utils.PageResponse{data=[]models.PointsEntry}
+// @Router /api/users/me/points/ledger [get]
+func (pc *PointsController) GetMyPointsLedger(c *gin.Context) {
+	page, limit := utils.PageParams(c, utils.DefaultPageLimit)
+	entries, total, err := pc.pointsService.Ledger(c.Request.Context(), c.GetString("user_id"), page, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	utils.Paginate(c, entries, total, page, limit)
+}
+
+// ListPerks 获取可兑换的权益
+// @Summary 获取权益目录
+// @Tags points
+// @Produce json
+// @Success 200 {array} services.PointsPerk
+// @Router /api/points/perks [get]
+func (pc *PointsController) ListPerks(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Success",
+		"data":    pc.pointsService.Perks(utils.RequestLang(c)),
+	})
+}
+
+// Redeem 兑换权益
+// @Summary 兑换权益
+// @Description 立即扣除积分，权益由管理员线下发放；积分不足时返回409
+// @Tags points
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body services.RedeemRequest true "权益"
+// @Success 201 {object} models.PointsRedemption
+// @Router /api/points/redemptions [post]
+func (pc *PointsController) Redeem(c *gin.Context) {
+	var req services.RedeemRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	redemption, err := pc.pointsService.Redeem(c.Request.Context(), c.GetString("user_id"), req.Perk)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"code":    20000,
+		"message": "Perk redeemed",
+		"data":    redemption,
+	})
+}
+
+// ListMyRedemptions 获取我的兑换记录
+// @Summary 获取兑换记录
+// @Tags points
+// @Produce json
+// @Security Bearer
+// @Param page query int false "页码" default(1)
+// @Param limit query int false "每页数量" default(20)
+// @Success 200 {object} utils.PageResponse{data=[]models.PointsRedemption}
+// @Router /api/points/redemptions [get]
+func (pc *PointsController) ListMyRedemptions(c *gin.Context) {
+	page, limit := utils.PageParams(c, utils.DefaultPageLimit)
+	redemptions, total, err := pc.pointsService.MyRedemptions(c.Request.Context(), c.GetString("user_id"), page, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	utils.Paginate(c, redemptions, total, page, limit)
+}
+
+// BumpListing 擦亮发布
+// @Summary 擦亮发布
+// @Description 消耗积分把自己在售的发布排到列表前面，同一发布24小时内只能擦亮一次（否则返回429）；积分不足时返回409
+// @Tags listings
+// @Produce json
+// @Security Bearer
+// @Param id path string true "发布ID"
+// @Success 200 {object} models.Listing
+// @Router /api/listings/{id}/bump [post]
+func (pc *PointsController) BumpListing(c *gin.Context) {
+	listing, err := pc.pointsService.BumpListing(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Listing bumped",
+		"data":    listing,
+	})
+}
+
+// AdminListRedemptions 兑换记录列表
+// @Summary 兑换记录列表
+// @Description 按兑换时间正序，先兑换的先处理
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param status query string false "pending, fulfilled, cancelled"
+// @Param page query int false "页码" default(1)
+// @Param limit query int false "每页数量" default(20)
+// @Success 200 {object} utils.PageResponse{data=[]models.PointsRedemption}
+// @Router /api/admin/points/redemptions [get]
+func (pc *PointsController) AdminListRedemptions(c *gin.Context) {
+	page, limit := utils.PageParams(c, utils.DefaultPageLimit)
+	redemptions, total, err := pc.pointsService.ListRedemptions(c.Request.Context(), c.Query("status"), page, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	utils.Paginate(c, redemptions, total, page, limit)
+}
+
+// HandleRedemption 处理兑换
+// @Summary 处理兑换
+// @Description fulfilled 表示已发放，cancelled 取消并退回积分；只能处理待发放的兑换
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "兑换ID"
+// @Param request body services.RedemptionStatusRequest true "处理结果"
+// @Success 200 {object} models.PointsRedemption
+// @Router /api/admin/points/redemptions/{id} [put]
+func (pc *PointsController) HandleRedemption(c *gin.Context) {
+	var req services.RedemptionStatusRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	redemption, err := pc.pointsService.HandleRedemption(c.Request.Context(), c.GetString("user_id"), c.Param("id"), req.Status)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Redemption updated",
+		"data":    redemption,
+	})
+}
+
+// AdjustPoints 调整用户积分
+// @Summary 调整用户积分
+// @Description 用于补发或扣回积分，amount 为负数时扣除，扣除后余额不能为负
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "用户ID"
+// @Param request body services.PointsAdjustRequest true "调整"
+// @Success 200 {object} models.PointsEntry
+// @Router /api/admin/users/{id}/points [post]
+func (pc *PointsController) AdjustPoints(c *gin.Context) {
+	var req services.PointsAdjustRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	entry, err := pc.pointsService.Adjust(c.Request.Context(), c.GetString("user_id"), c.Param("id"), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Points adjusted",
+		"data":    entry,
+	})
+}
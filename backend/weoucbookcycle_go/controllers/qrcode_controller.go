@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"weoucbookcycle_go/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QRCodeController 分享二维码控制器
+type QRCodeController struct {
+	qrCodeService *services.QRCodeService
+}
+
+// NewQRCodeController 创建二维码控制器实例
+func NewQRCodeController(qrCodeService *services.QRCodeService) *QRCodeController {
+	return &QRCodeController{
+		qrCodeService: qrCodeService,
+	}
+}
+
+// GetBookQRCode 获取书籍分享二维码
+// @Summary 获取书籍分享二维码
+// @Description 返回指向书籍分享链接的PNG二维码，可打印后贴在公告栏
+// @Tags books
+// @Produce png
+// @Param id path string true "书籍ID"
+// @Param size query int false "边长（像素），128~1024" default(256)
+// @Success 200 {file} file
+// @Router /api/books/{id}/qrcode [get]
+func (qc *QRCodeController) GetBookQRCode(c *gin.Context) {
+	qc.serve(c, services.ShareTargetBook)
+}
+
+// GetListingQRCode 获取发布分享二维码
+// @Summary 获取发布分享二维码
+// @Description 返回指向发布分享链接的PNG二维码，可打印后贴在公告栏
+// @Tags listings
+// @Produce png
+// @Param id path string true "发布ID"
+// @Param size query int false "边长（像素），128~1024" default(256)
+// @Success 200 {file} file
+// @Router /api/listings/{id}/qrcode [get]
+func (qc *QRCodeController) GetListingQRCode(c *gin.Context) {
+	qc.serve(c, services.ShareTargetListing)
+}
+
+// serve 输出二维码图片，内容只取决于ID和尺寸，可以长时间缓存
+func (qc *QRCodeController) serve(c *gin.Context, target string) {
+	size, _ := strconv.Atoi(c.Query("size"))
+	png, err := qc.qrCodeService.QRCode(c.Request.Context(), target, c.Param("id"), size)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Data(http.StatusOK, "image/png", png)
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"net/http"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReferralController 邀请码控制器
+type ReferralController struct {
+	referralService *services.ReferralService
+}
+
+// NewReferralController 创建邀请控制器实例
+func NewReferralController(referralService *services.ReferralService) *ReferralController {
+	return &ReferralController{
+		referralService: referralService,
+	}
+}
+
+// GetMyReferrals 获取我的邀请码和邀请情况
+// @Summary 获取邀请码和邀请统计
+// @Description 新用户注册时填写邀请码，完成首笔交易（对方不是邀请人）后双方获得绿色积分。
+// @Description 与邀请人或其他被邀请人共用IP、设备重复注册的邀请不发放积分
+// @Tags referrals
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} services.ReferralStats
+// @Router /api/users/me/referrals [get]
+func (rc *ReferralController) GetMyReferrals(c *gin.Context) {
+	stats, err := rc.referralService.Stats(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Success",
+		"data":    stats,
+	})
+}
+
+// ListMyInvitees 获取我邀请的用户
+// @Summary 获取我邀请的用户
+// @Tags referrals
+// @Produce json
+// @Security Bearer
+// @Param page query int false "页码" default(1)
+// @Param limit query int false "每页数量" default(20)
+// @Success 200 {object} utils.PageResponse{data=[]services.ReferralInvitee}
+// @Router /api/users/me/referrals/invitees [get]
+func (rc *ReferralController) ListMyInvitees(c *gin.Context) {
+	page, limit := utils.PageParams(c, utils.DefaultPageLimit)
+	invitees, total, err := rc.referralService.Invitees(c.Request.Context(), c.GetString("user_id"), page, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	utils.Paginate(c, invitees, total, page, limit)
+}
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"net/http"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReportController 举报控制器
+type ReportController struct {
+	reportService *services.ReportService
+}
+
+// NewReportController 创建举报控制器实例
+func NewReportController(reportService *services.ReportService) *ReportController {
+	return &ReportController{
+		reportService: reportService,
+	}
+}
+
+// CreateReport 提交举报
+// @Summary 举报违规内容
+// @Description 举报用户、书籍、发布、会话或消息，由管理员处理
+// @Tags reports
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body services.CreateReportRequest true "举报内容"
+// @Success 201 {object} models.Report
+// @Router /api/reports [post]
+func (rc *ReportController) CreateReport(c *gin.Context) {
+	var req services.CreateReportRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	report, err := rc.reportService.CreateReport(c.Request.Context(), c.GetString("user_id"), &req)
+	if err != nil {
+		c.Error(utils.WithDefaultStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"code": 20000, "message": "Report submitted", "data": report})
+}
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// reportingMaxDays 报表汇总单次查询的最大天数
+const reportingMaxDays = 366
+
+// ReportingController 报表控制器（管理员），数据来自从Redis流导入的报表表
+type ReportingController struct {
+	reportingService *services.ReportingETLService
+}
+
+// NewReportingController 创建报表控制器实例
+func NewReportingController(reportingService *services.ReportingETLService) *ReportingController {
+	return &ReportingController{
+		reportingService: reportingService,
+	}
+}
+
+// GetSummary 报表汇总
+// @Summary 报表汇总
+// @Description 按天汇总领域事件（注册、新书、聊天等）、登录和请求，默认最近30天，最多366天
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param from query string false "开始日期 YYYY-MM-DD"
+// @Param to query string false "结束日期 YYYY-MM-DD（包含）"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/reporting/summary [get]
+func (rc *ReportingController) GetSummary(c *gin.Context) {
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if v := c.Query("to"); v != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			c.Error(utils.NewError(http.StatusBadRequest, "invalid to date"))
+			return
+		}
+		to = parsed
+	}
+	from := to.AddDate(0, 0, -29)
+	if v := c.Query("from"); v != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			c.Error(utils.NewError(http.StatusBadRequest, "invalid from date"))
+			return
+		}
+		from = parsed
+	}
+	end := to.AddDate(0, 0, 1)
+	if !from.Before(end) || end.Sub(from) > reportingMaxDays*24*time.Hour {
+		c.Error(utils.NewError(http.StatusBadRequest, "invalid date range"))
+		return
+	}
+
+	summary, err := rc.reportingService.Summary(c.Request.Context(), from, end)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Success",
+		"data":    summary,
+	})
+}
+
+// ListEvents 报表领域事件
+// @Summary 报表领域事件列表
+// @Description 分页查询已导入的领域事件（user_events、book_events、chat_events）
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param stream query string false "事件流: user_events, book_events, chat_events"
+// @Param event query string false "事件类型，如 register、book_created、chat_created"
+// @Param user_id query string false "用户ID"
+// @Param from query string false "开始时间 RFC3339"
+// @Param to query string false "结束时间 RFC3339"
+// @Param page query int false "页码" default(1)
+// @Param limit query int false "每页数量" default(20)
+// @Success 200 {object} utils.PageResponse{data=[]models.ReportEvent}
+// @Router /api/admin/reporting/events [get]
+func (rc *ReportingController) ListEvents(c *gin.Context) {
+	page, limit := utils.PageParams(c, utils.DefaultPageLimit)
+
+	q := &services.ReportingQuery{
+		Stream: c.Query("stream"),
+		Event:  c.Query("event"),
+		UserID: c.Query("user_id"),
+		Page:   page,
+		Limit:  limit,
+	}
+	for param, target := range map[string]*time.Time{"from": &q.From, "to": &q.To} {
+		if v := c.Query(param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				c.Error(utils.NewError(http.StatusBadRequest, "invalid "+param+" time, expected RFC3339"))
+				return
+			}
+			*target = t
+		}
+	}
+
+	events, total, err := rc.reportingService.Events(c.Request.Context(), q)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	utils.Paginate(c, events, total, page, limit)
+}
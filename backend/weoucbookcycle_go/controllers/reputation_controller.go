@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"net/http"
+	"weoucbookcycle_go/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReputationController 卖家信誉分控制器
+type ReputationController struct {
+	reputationService *services.ReputationService
+}
+
+// NewReputationController 创建信誉分控制器实例
+func NewReputationController(reputationService *services.ReputationService) *ReputationController {
+	return &ReputationController{
+		reputationService: reputationService,
+	}
+}
+
+// GetReputation 获取卖家信誉分明细
+// @Summary 获取信誉分明细
+// @Description 返回综合信誉分及成交、评价、回复速度、举报各项的原始数据和得分，每天更新一次
+// @Tags users
+// @Produce json
+// @Param id path string true "用户ID"
+// @Success 200 {object} models.SellerReputation
+// @Router /api/users/{id}/reputation [get]
+func (rc *ReputationController) GetReputation(c *gin.Context) {
+	rep, err := rc.reputationService.Breakdown(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Success",
+		"data":    rep,
+	})
+}
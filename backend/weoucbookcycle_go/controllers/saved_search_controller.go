@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"net/http"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SavedSearchController 保存的搜索控制器
+type SavedSearchController struct {
+	savedSearchService *services.SavedSearchService
+}
+
+// NewSavedSearchController 创建保存的搜索控制器实例
+func NewSavedSearchController(savedSearchService *services.SavedSearchService) *SavedSearchController {
+	return &SavedSearchController{
+		savedSearchService: savedSearchService,
+	}
+}
+
+// ListSavedSearches 获取我保存的搜索
+// @Summary 获取保存的搜索
+// @Tags search
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} map[string]interface{}
+// @Router /api/search/saved [get]
+func (sc *SavedSearchController) ListSavedSearches(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	searches, err := sc.savedSearchService.List(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	utils.PaginateAll(c, searches)
+}
+
+// CreateSavedSearch 保存一个搜索
+// @Summary 保存搜索
+// @Description 保存关键词和筛选条件，有新上架的匹配书籍时按频率发送通知
+// @Tags search
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body services.SavedSearchRequest true "保存的搜索"
+// @Success 201 {object} models.SavedSearch
+// @Router /api/search/saved [post]
+func (sc *SavedSearchController) CreateSavedSearch(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req services.SavedSearchRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	search, err := sc.savedSearchService.Create(userID, &req)
+	if err != nil {
+		c.Error(utils.NewError(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"code":    20000,
+		"message": "Search saved",
+		"data":    search,
+	})
+}
+
+// UpdateSavedSearch 更新保存的搜索
+// @Summary 更新保存的搜索
+// @Tags search
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "保存的搜索ID"
+// @Param request body services.SavedSearchRequest true "保存的搜索"
+// @Success 200 {object} models.SavedSearch
+// @Router /api/search/saved/{id} [put]
+func (sc *SavedSearchController) UpdateSavedSearch(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req services.SavedSearchRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	search, err := sc.savedSearchService.Update(userID, c.Param("id"), &req)
+	if err != nil {
+		c.Error(utils.NewError(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Saved search updated",
+		"data":    search,
+	})
+}
+
+// DeleteSavedSearch 删除保存的搜索
+// @Summary 删除保存的搜索
+// @Tags search
+// @Produce json
+// @Security Bearer
+// @Param id path string true "保存的搜索ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/search/saved/{id} [delete]
+func (sc *SavedSearchController) DeleteSavedSearch(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	if err := sc.savedSearchService.Delete(userID, c.Param("id")); err != nil {
+		c.Error(utils.NewError(http.StatusNotFound, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Saved search deleted",
+	})
+}
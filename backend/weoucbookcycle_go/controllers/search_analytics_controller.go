@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"weoucbookcycle_go/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SearchAnalyticsController 搜索分析控制器（管理员）
+type SearchAnalyticsController struct {
+	analyticsService *services.SearchAnalyticsService
+}
+
+// NewSearchAnalyticsController 创建搜索分析控制器实例
+func NewSearchAnalyticsController(analyticsService *services.SearchAnalyticsService) *SearchAnalyticsController {
+	return &SearchAnalyticsController{
+		analyticsService: analyticsService,
+	}
+}
+
+// GetTopQueries 获取热门查询
+// @Summary 热门查询报表
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param days query int false "统计天数" default(7)
+// @Param limit query int false "数量" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/search/analytics/top-queries [get]
+func (sac *SearchAnalyticsController) GetTopQueries(c *gin.Context) {
+	since, limit := sac.parseRange(c)
+
+	stats, err := sac.analyticsService.TopQueries(since, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Success",
+		"data": gin.H{
+			"since":   since,
+			"queries": stats,
+		},
+	})
+}
+
+// GetZeroResultQueries 获取零结果查询
+// @Summary 零结果查询报表
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param days query int false "统计天数" default(7)
+// @Param limit query int false "数量" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/search/analytics/zero-results [get]
+func (sac *SearchAnalyticsController) GetZeroResultQueries(c *gin.Context) {
+	since, limit := sac.parseRange(c)
+
+	stats, err := sac.analyticsService.ZeroResultQueries(since, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Success",
+		"data": gin.H{
+			"since":   since,
+			"queries": stats,
+		},
+	})
+}
+
+// GetClickThroughRate 获取搜索点击率
+// @Summary 搜索点击率报表
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param days query int false "统计天数" default(7)
+// @Param limit query int false "数量" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/search/analytics/ctr [get]
+func (sac *SearchAnalyticsController) GetClickThroughRate(c *gin.Context) {
+	since, limit := sac.parseRange(c)
+
+	report, err := sac.analyticsService.ClickThroughRate(since, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	report["since"] = since
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Success",
+		"data":    report,
+	})
+}
+
+// parseRange 解析统计时间范围和数量
+func (sac *SearchAnalyticsController) parseRange(c *gin.Context) (time.Time, int) {
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "7"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if days < 1 || days > 365 {
+		days = 7
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	return time.Now().AddDate(0, 0, -days), limit
+}
@@ -1,163 +1,444 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 	"weoucbookcycle_go/config"
 	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
 )
 
 // SearchController 搜索控制器
 type SearchController struct {
-	redisClient *redis.Client
+	analyticsService    *services.SearchAnalyticsService
+	campusService       *services.CampusService
+	searchIndexService  *services.SearchIndexService
+	synonymService      *services.SynonymService
+	userSettingsService *services.UserSettingsService
+	db                  *gorm.DB
+	redisClient         *redis.Client
 }
 
 // NewSearchController 创建搜索控制器实例
-func NewSearchController() *SearchController {
+func NewSearchController(analyticsService *services.SearchAnalyticsService, campusService *services.CampusService,
+	searchIndexService *services.SearchIndexService, synonymService *services.SynonymService, userSettingsService *services.UserSettingsService,
+	db *gorm.DB, redisClient *redis.Client) *SearchController {
 	return &SearchController{
-		redisClient: initRedis(),
+		analyticsService:    analyticsService,
+		campusService:       campusService,
+		searchIndexService:  searchIndexService,
+		synonymService:      synonymService,
+		userSettingsService: userSettingsService,
+		db:                  db,
+		redisClient:         redisClient,
 	}
 }
 
 // SearchResult 搜索结果结构
 type SearchResult struct {
-	Books    []models.Book    `json:"books,omitempty"`
-	Users    []models.User    `json:"users,omitempty"`
-	Listings []models.Listing `json:"listings,omitempty"`
-	Total    int              `json:"total"`
-	Query    string           `json:"query"`
+	Books    []models.Book       `json:"books,omitempty"`
+	Users    []models.PublicUser `json:"users,omitempty"`
+	Listings []models.Listing    `json:"listings,omitempty"`
+	Total    int                 `json:"total"`
+	Query    string              `json:"query"`
+	SearchID string              `json:"search_id,omitempty"`
+	// 各类型的分页信息，key 为 books/users/listings，链接中的页码参数为 {type}_page
+	Pagination map[string]utils.Pagination `json:"pagination"`
+	// 结果过少时的纠错建议
+	DidYouMean *SearchCorrection `json:"did_you_mean,omitempty"`
+	// 书籍结果是否按用户偏好重排
+	Personalized bool `json:"personalized,omitempty"`
 }
 
+// SearchPage 全局搜索单个结果类型的分页参数和总数
+type SearchPage struct {
+	Page  int
+	Limit int
+	Total int64
+}
+
+// legacySearchPage v1 全局搜索中单个结果类型的分页信息
+type legacySearchPage struct {
+	Page    int   `json:"page"`
+	Limit   int   `json:"limit"`
+	Total   int64 `json:"total"`
+	HasMore bool  `json:"has_more"`
+}
+
+// writeGlobalSearch 输出全局搜索结果，v1 的 pagination 保持原来的 page/limit/total/has_more 格式
+func writeGlobalSearch(c *gin.Context, result SearchResult) {
+	if !utils.IsLegacyList(c) {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+	legacy := struct {
+		SearchResult
+		Pagination map[string]legacySearchPage `json:"pagination"`
+	}{SearchResult: result, Pagination: make(map[string]legacySearchPage, len(result.Pagination))}
+	for t, p := range result.Pagination {
+		legacy.Pagination[t] = legacySearchPage{Page: p.Page, Limit: p.Limit, Total: *p.Total, HasMore: p.HasNext}
+	}
+	c.JSON(http.StatusOK, legacy)
+}
+
+// SearchCorrection 纠错建议及纠正后的书籍结果
+type SearchCorrection struct {
+	Query string        `json:"query"`
+	Total int64         `json:"total"`
+	Books []models.Book `json:"books"`
+}
+
+// SearchMeta 单类型搜索接口分页响应中的 meta
+type SearchMeta struct {
+	Query    string `json:"query"`
+	SearchID string `json:"search_id,omitempty"`
+	// 结果过少时的纠错建议（只有书籍搜索）
+	DidYouMean *SearchCorrection `json:"did_you_mean,omitempty"`
+	// 书籍结果是否按用户偏好重排
+	Personalized bool `json:"personalized,omitempty"`
+}
+
+// searchPageCache 单类型搜索的缓存内容；search_id、个性化重排和分页链接每次请求单独生成
+type searchPageCache[T any] struct {
+	Items      []T               `json:"items"`
+	Total      int64             `json:"total"`
+	DidYouMean *SearchCorrection `json:"did_you_mean,omitempty"`
+}
+
+// loadSearchPage 读取单类型搜索的缓存
+func loadSearchPage[T any](ctx context.Context, client *redis.Client, key string) (*searchPageCache[T], bool) {
+	cached, err := client.Get(ctx, key).Bytes()
+	if err != nil {
+		return nil, false
+	}
+	var result searchPageCache[T]
+	if json.Unmarshal(cached, &result) != nil {
+		return nil, false
+	}
+	return &result, true
+}
+
+// searchPageCacheKey 单类型搜索的缓存键，extra 为影响结果的其他参数（如分类）
+func searchPageCacheKey(kind, query string, page, limit int, extra ...string) string {
+	key := fmt.Sprintf("search:%s:%s:%d,%d", kind, query, page, limit)
+	for _, e := range extra {
+		if e != "" {
+			key += ":" + e
+		}
+	}
+	return key
+}
+
+// campusKey 缓存key中的校区筛选部分，不筛选时为空
+func campusKey(campusID string) string {
+	if campusID == "" {
+		return ""
+	}
+	return "campus=" + campusID
+}
+
+// bookSearchCorrection 结果过少时查询纠错建议的书籍结果，纠正后结果更多时返回
+func bookSearchCorrection(ctx context.Context, db *gorm.DB, searchIndex *services.SearchIndexService, synonyms *services.SynonymService,
+	query string, total int64, limit int, category, campusID string) *SearchCorrection {
+	suggestion := searchIndex.SuggestCorrection(ctx, query, total)
+	if suggestion == "" {
+		return nil
+	}
+	correction := &SearchCorrection{Query: suggestion}
+	condition, args := services.KeywordCondition(synonyms.ExpandQuery(ctx, suggestion), "title", "author", "description", "category")
+	correctedQuery := config.ReadReplica(db.WithContext(ctx)).Model(&models.Book{}).Where("status = ?", 1).
+		Scopes(services.VisibleUsers("books.seller_id"), services.InCampus("books.campus_id", campusID)).
+		Where(condition, args...)
+	if category != "" {
+		correctedQuery = correctedQuery.Where("category = ?", category)
+	}
+	correctedQuery.Count(&correction.Total)
+	correctedQuery.Preload("Seller").Limit(limit).Find(&correction.Books)
+	if correction.Total <= total {
+		return nil
+	}
+	return correction
+}
+
+// writeBookSearchPage 输出书籍搜索结果：记录搜索事件，按用户偏好重排（缓存中保存的是通用排序）
+func (sc *SearchController) writeBookSearchPage(c *gin.Context, query string, page, limit int, result *searchPageCache[models.Book]) {
+	userID := c.GetString("user_id")
+	meta := SearchMeta{
+		Query:      query,
+		SearchID:   sc.analyticsService.RecordSearch(c.Request.Context(), userID, query, "books", result.Total),
+		DidYouMean: result.DidYouMean,
+	}
+	meta.Personalized = sc.userSettingsService.PersonalizeBooks(c.Request.Context(), userID, result.Items)
+	utils.WritePage(c, result.Items, utils.NewPagination(c, "page", page, limit, result.Total), meta)
+}
+
+// globalSearchTypes 全局搜索支持的结果类型
+var globalSearchTypes = []string{"books", "users", "listings"}
+
 // GlobalSearch 全局搜索
 // @Summary 全局搜索
-// @Description 跨多个模块进行搜索
+// @Description 跨多个模块进行搜索。type 指定只搜索某些类型（逗号分隔），
+// @Description 每种类型可以用 {type}_page / {type}_limit 单独分页，用于"查看更多"标签页
 // @Tags search
 // @Accept json
 // @Produce json
 // @Param q query string true "搜索关键词"
+// @Param type query string false "结果类型: books,users,listings（默认全部）"
 // @Param page query int false "页码" default(1)
 // @Param limit query int false "每页数量" default(20)
+// @Param books_page query int false "书籍页码（默认同page）"
+// @Param books_limit query int false "书籍每页数量（默认同limit）"
+// @Param users_page query int false "用户页码（默认同page）"
+// @Param users_limit query int false "用户每页数量（默认同limit）"
+// @Param listings_page query int false "发布页码（默认同page）"
+// @Param listings_limit query int false "发布每页数量（默认同limit）"
+// @Param campus_id query string false "书籍所在校区和发布的面交校区，默认为登录用户所在校区，all 表示不限"
 // @Success 200 {object} SearchResult
-// @Router /api/v1/search [get]
+// @Router /api/search [get]
 func (sc *SearchController) GlobalSearch(c *gin.Context) {
+	ctx := c.Request.Context()
 	query := c.Query("q")
 	if query == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query is required"})
+		c.Error(utils.NewError(http.StatusBadRequest, "Search query is required"))
 		return
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	// 解析要搜索的类型及各自的分页参数
+	pages, err := sc.parseSearchPages(c)
+	if err != nil {
+		c.Error(utils.NewError(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	// 书籍按所在校区、发布按面交校区筛选
+	var campusID string
+	_, withBooks := pages["books"]
+	if _, withListings := pages["listings"]; withBooks || withListings {
+		campusID = sc.campusService.PickupCampus(ctx, c.GetString("user_id"), c.Query("campus_id"))
+	}
 
-	// 检查Redis缓存
-	cacheKey := "search:global:" + query + ":" + strconv.Itoa(page)
+	// 检查Redis缓存（key 包含类型和各自的分页参数，以及校区筛选）
+	cacheKey := "search:global:" + query
+	for _, t := range globalSearchTypes {
+		if p, ok := pages[t]; ok {
+			cacheKey += fmt.Sprintf(":%s=%d,%d", t, p.Page, p.Limit)
+		}
+	}
+	if campusID != "" {
+		cacheKey += ":" + campusKey(campusID)
+	}
 	cached, err := sc.redisClient.Get(ctx, cacheKey).Result()
 	if err == nil {
 		var result SearchResult
 		if json.Unmarshal([]byte(cached), &result) == nil {
-			c.JSON(http.StatusOK, result)
+			// 分页链接按本次请求的地址生成
+			for t, p := range result.Pagination {
+				result.Pagination[t] = utils.NewPagination(c, t+"_page", p.Page, p.Limit, *p.Total)
+			}
+			result.SearchID = sc.analyticsService.RecordSearch(ctx, c.GetString("user_id"), query, "global", int64(result.Total))
+			result.Personalized = sc.userSettingsService.PersonalizeBooks(ctx, c.GetString("user_id"), result.Books)
+			writeGlobalSearch(c, result)
+			utils.RecordCacheHit("search")
 			return
 		}
 	}
+	utils.RecordCacheMiss("search")
 
 	// 记录搜索关键词（异步）
 	go func() {
+		ctx := context.WithoutCancel(ctx)
 		sc.redisClient.ZIncrBy(ctx, "search:hot", 1, query)
 		sc.redisClient.Expire(ctx, "search:hot", time.Hour*24)
 	}()
 
+	// 同义词扩展（例如：高数 -> 高等数学）
+	terms := sc.synonymService.ExpandQuery(ctx, query)
+
 	// 使用goroutine并发搜索多个数据源
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 
 	result := SearchResult{
-		Query: query,
+		Query:      query,
+		Pagination: make(map[string]utils.Pagination, len(pages)),
 	}
+	counted := make(map[string]SearchPage, len(pages))
 
 	// 并发搜索书籍
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
+	if p, ok := pages["books"]; ok {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			condition, args := services.KeywordCondition(terms, "title", "author", "description")
+			var books []models.Book
+
+			baseQuery := sc.db.WithContext(ctx).Model(&models.Book{}).
+				Where("status = ?", 1).Scopes(services.VisibleUsers("books.seller_id"), services.InCampus("books.campus_id", campusID)).
+				Where(condition, args...)
+			baseQuery.Count(&p.Total)
+			baseQuery.
+				Limit(p.Limit).
+				Offset((p.Page - 1) * p.Limit).
+				Find(&books)
+
+			mu.Lock()
+			result.Books = books
+			counted["books"] = p
+			mu.Unlock()
+		}()
+	}
 
-		searchPattern := "%" + query + "%"
-		var books []models.Book
+	// 并发搜索用户
+	if p, ok := pages["users"]; ok {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// 只匹配用户名和简介，并排除关闭了可被搜索的用户
+			searchPattern := "%" + query + "%"
+			var users []models.User
+
+			baseQuery := sc.db.WithContext(ctx).Model(&models.User{}).
+				Scopes(services.DiscoverableUsers).
+				Where("username LIKE ? OR bio LIKE ?", searchPattern, searchPattern)
+			baseQuery.Count(&p.Total)
+			baseQuery.
+				Limit(p.Limit).
+				Offset((p.Page - 1) * p.Limit).
+				Find(&users)
+
+			publicUsers := make([]models.PublicUser, 0, len(users))
+			for i := range users {
+				publicUsers = append(publicUsers, users[i].Public())
+			}
+
+			mu.Lock()
+			result.Users = publicUsers
+			counted["users"] = p
+			mu.Unlock()
+		}()
+	}
 
-		config.DB.
-			Where("status = ?", 1).
-			Where("title LIKE ? OR author LIKE ? OR description LIKE ?",
-				searchPattern, searchPattern, searchPattern).
-			Limit(limit).
-			Find(&books)
+	// 并发搜索发布
+	if p, ok := pages["listings"]; ok {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			condition, args := services.KeywordCondition(terms, "books.title", "books.author", "listings.note")
+			var listings []models.Listing
+
+			baseQuery := sc.db.WithContext(ctx).Model(&models.Listing{}).
+				Joins("JOIN books ON listings.book_id = books.id").
+				Where("listings.status = ?", "available").Scopes(services.VisibleUsers("listings.seller_id")).
+				Where(condition, args...)
+			baseQuery = baseQuery.Scopes(services.InCampus("listings.campus_id", campusID))
+			baseQuery.Count(&p.Total)
+			baseQuery.
+				Preload("Book").
+				Limit(p.Limit).
+				Offset((p.Page - 1) * p.Limit).
+				Find(&listings)
+
+			mu.Lock()
+			result.Listings = listings
+			counted["listings"] = p
+			mu.Unlock()
+		}()
+	}
 
-		mu.Lock()
-		result.Books = books
-		result.Total += len(books)
-		mu.Unlock()
-	}()
+	wg.Wait()
 
-	// 并发搜索用户
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
+	for t, p := range counted {
+		result.Pagination[t] = utils.NewPagination(c, t+"_page", p.Page, p.Limit, p.Total)
+		result.Total += int(p.Total)
+	}
 
-		searchPattern := "%" + query + "%"
-		var users []models.User
+	// 结果过少时给出纠错建议
+	if p, ok := pages["books"]; ok {
+		if suggestion := sc.searchIndexService.SuggestCorrection(ctx, query, int64(result.Total)); suggestion != "" {
+			correction := &SearchCorrection{Query: suggestion}
+
+			condition, args := services.KeywordCondition(sc.synonymService.ExpandQuery(ctx, suggestion), "title", "author", "description")
+			correctedQuery := config.ReadReplica(sc.db.WithContext(ctx)).Model(&models.Book{}).Where("status = ?", 1).
+				Scopes(services.VisibleUsers("books.seller_id"), services.InCampus("books.campus_id", campusID)).
+				Where(condition, args...)
+			correctedQuery.Count(&correction.Total)
+			correctedQuery.Limit(p.Limit).Find(&correction.Books)
+
+			if correction.Total > int64(result.Total) {
+				result.DidYouMean = correction
+			}
+		}
+	}
 
-		config.DB.
-			Where("username LIKE ? OR email LIKE ? OR bio LIKE ?",
-				searchPattern, searchPattern, searchPattern).
-			Limit(limit).
-			Find(&users)
+	// 异步缓存搜索结果
+	data, _ := json.Marshal(result)
+	go utils.SetTaggedCache(ctx, sc.redisClient, utils.CacheTagSearch, cacheKey, data, time.Minute*5)
 
-		mu.Lock()
-		result.Users = users
-		result.Total += len(users)
-		mu.Unlock()
-	}()
+	// 记录搜索事件，search_id 用于上报点击
+	result.SearchID = sc.analyticsService.RecordSearch(ctx, c.GetString("user_id"), query, "global", int64(result.Total))
 
-	// 并发搜索发布
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
+	// 个性化重排（在缓存之后进行，缓存中保存的是通用排序）
+	result.Personalized = sc.userSettingsService.PersonalizeBooks(ctx, c.GetString("user_id"), result.Books)
 
-		searchPattern := "%" + query + "%"
-		var listings []models.Listing
-
-		config.DB.
-			Preload("Book").
-			Where("status = ?", "available").
-			Joins("JOIN books ON listings.book_id = books.id").
-			Where("books.title LIKE ? OR books.author LIKE ? OR listings.note LIKE ?",
-				searchPattern, searchPattern, searchPattern).
-			Limit(limit).
-			Find(&listings)
+	writeGlobalSearch(c, result)
+}
 
-		mu.Lock()
-		result.Listings = listings
-		result.Total += len(listings)
-		mu.Unlock()
-	}()
+// parseSearchPages 解析全局搜索的类型过滤和各类型分页参数
+func (sc *SearchController) parseSearchPages(c *gin.Context) (map[string]SearchPage, error) {
+	types := globalSearchTypes
+	if raw := c.Query("type"); raw != "" {
+		types = nil
+		for _, t := range strings.Split(raw, ",") {
+			t = strings.TrimSpace(t)
+			if !slices.Contains(globalSearchTypes, t) {
+				return nil, fmt.Errorf("invalid search type: %s", t)
+			}
+			if !slices.Contains(types, t) {
+				types = append(types, t)
+			}
+		}
+	}
 
-	wg.Wait()
+	page := sc.boundedQueryInt(c, "page", 1, 1, 1000)
+	limit := sc.boundedQueryInt(c, "limit", 20, 1, 100)
 
-	// 异步缓存搜索结果
-	go func() {
-		data, _ := json.Marshal(result)
-		sc.redisClient.Set(ctx, cacheKey, data, time.Minute*5)
-	}()
+	pages := make(map[string]SearchPage, len(types))
+	for _, t := range types {
+		pages[t] = SearchPage{
+			Page:  sc.boundedQueryInt(c, t+"_page", page, 1, 1000),
+			Limit: sc.boundedQueryInt(c, t+"_limit", limit, 1, 100),
+		}
+	}
+	return pages, nil
+}
 
-	c.JSON(http.StatusOK, result)
+// boundedQueryInt 读取整数查询参数，缺失或越界时使用默认值
+func (sc *SearchController) boundedQueryInt(c *gin.Context, key string, def, minValue, maxValue int) int {
+	value, err := strconv.Atoi(c.Query(key))
+	if err != nil || value < minValue || value > maxValue {
+		return def
+	}
+	return value
 }
 
 // SearchUsers 搜索用户
 // @Summary 搜索用户
-// @Description 搜索用户
+// @Description 按用户名和简介搜索用户，只返回公开信息，不包含关闭了“可被搜索”的用户
 // @Tags search
 // @Accept json
 // @Produce json
@@ -165,59 +446,57 @@ func (sc *SearchController) GlobalSearch(c *gin.Context) {
 // @Param page query int false "页码" default(1)
 // @Param limit query int false "每页数量" default(20)
 // @Success 200 {object} map[string]interface{}
-// @Router /api/v1/search/users [get]
+// @Router /api/search/users [get]
 func (sc *SearchController) SearchUsers(c *gin.Context) {
+	ctx := c.Request.Context()
 	query := c.Query("q")
 	if query == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query is required"})
+		c.Error(utils.NewError(http.StatusBadRequest, "Search query is required"))
 		return
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	offset := (page - 1) * limit
+	page, limit := utils.PageParams(c, utils.DefaultPageLimit)
 
 	// 检查缓存
-	cacheKey := "search:users:" + query + ":" + strconv.Itoa(page)
-	cached, err := sc.redisClient.Get(ctx, cacheKey).Result()
-	if err == nil {
-		var result map[string]interface{}
-		if json.Unmarshal([]byte(cached), &result) == nil {
-			c.JSON(http.StatusOK, result)
-			return
-		}
-	}
+	cacheKey := searchPageCacheKey("users", query, page, limit)
+	result, ok := loadSearchPage[models.PublicUser](ctx, sc.redisClient, cacheKey)
+	if ok {
+		utils.RecordCacheHit("search")
+	} else {
+		utils.RecordCacheMiss("search")
+
+		// 只匹配用户名和简介，并排除关闭了可被搜索的用户
+		searchPattern := "%" + query + "%"
+		var users []models.User
+		result = &searchPageCache[models.PublicUser]{}
 
-	searchPattern := "%" + query + "%"
-	var users []models.User
-	var total int64
+		baseQuery := config.ReadReplica(sc.db.WithContext(ctx)).Model(&models.User{}).
+			Scopes(services.DiscoverableUsers).
+			Where("username LIKE ? OR bio LIKE ?", searchPattern, searchPattern)
 
-	config.DB.Model(&models.User{}).
-		Where("username LIKE ? OR email LIKE ? OR bio LIKE ?",
-			searchPattern, searchPattern, searchPattern).
-		Count(&total)
+		baseQuery.Count(&result.Total)
 
-	config.DB.Where("username LIKE ? OR email LIKE ? OR bio LIKE ?",
-		searchPattern, searchPattern, searchPattern).
-		Limit(limit).
-		Offset(offset).
-		Find(&users)
+		baseQuery.
+			Limit(limit).
+			Offset(utils.PageOffset(page, limit)).
+			Find(&users)
 
-	result := gin.H{
-		"users": users,
-		"total": total,
-		"page":  page,
-		"limit": limit,
-		"query": query,
-	}
+		// 只返回公开字段，不暴露邮箱和手机号
+		result.Items = make([]models.PublicUser, 0, len(users))
+		for i := range users {
+			result.Items = append(result.Items, users[i].Public())
+		}
 
-	// 异步缓存
-	go func() {
+		// 异步缓存
 		data, _ := json.Marshal(result)
-		sc.redisClient.Set(ctx, cacheKey, data, time.Minute*5)
-	}()
+		go utils.SetTaggedCache(ctx, sc.redisClient, utils.CacheTagSearch, cacheKey, data, time.Minute*5)
+	}
 
-	c.JSON(http.StatusOK, result)
+	meta := SearchMeta{
+		Query:    query,
+		SearchID: sc.analyticsService.RecordSearch(ctx, c.GetString("user_id"), query, "users", result.Total),
+	}
+	utils.WritePage(c, result.Items, utils.NewPagination(c, "page", page, limit, result.Total), meta)
 }
 
 // SearchBooks 搜索书籍
@@ -230,77 +509,64 @@ func (sc *SearchController) SearchUsers(c *gin.Context) {
 // @Param page query int false "页码" default(1)
 // @Param limit query int false "每页数量" default(20)
 // @Param category query string false "分类筛选"
+// @Param campus_id query string false "所在校区，默认为登录用户所在校区，all 表示不限"
 // @Success 200 {object} map[string]interface{}
-// @Router /api/v1/search/books [get]
+// @Router /api/search/books [get]
 func (sc *SearchController) SearchBooks(c *gin.Context) {
+	ctx := c.Request.Context()
 	query := c.Query("q")
 	if query == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query is required"})
+		c.Error(utils.NewError(http.StatusBadRequest, "Search query is required"))
 		return
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	offset := (page - 1) * limit
+	page, limit := utils.PageParams(c, utils.DefaultPageLimit)
 	category := c.Query("category")
+	campusID := sc.campusService.PickupCampus(ctx, c.GetString("user_id"), c.Query("campus_id"))
 
 	// 检查缓存
-	cacheKey := "search:books:" + query + ":" + strconv.Itoa(page)
-	if category != "" {
-		cacheKey += ":" + category
-	}
-
-	cached, err := sc.redisClient.Get(ctx, cacheKey).Result()
-	if err == nil {
-		var result map[string]interface{}
-		if json.Unmarshal([]byte(cached), &result) == nil {
-			c.JSON(http.StatusOK, result)
-			return
-		}
+	cacheKey := searchPageCacheKey("books", query, page, limit, category, campusKey(campusID))
+	if result, ok := loadSearchPage[models.Book](ctx, sc.redisClient, cacheKey); ok {
+		sc.writeBookSearchPage(c, query, page, limit, result)
+		utils.RecordCacheHit("search")
+		return
 	}
+	utils.RecordCacheMiss("search")
 
 	// 记录搜索
 	go func() {
+		ctx := context.WithoutCancel(ctx)
 		sc.redisClient.ZIncrBy(ctx, "search:hot", 1, query)
 	}()
 
-	searchPattern := "%" + query + "%"
-	var books []models.Book
-	var total int64
+	// 同义词扩展
+	condition, args := services.KeywordCondition(sc.synonymService.ExpandQuery(ctx, query), "title", "author", "description", "category")
+	result := &searchPageCache[models.Book]{}
 
-	baseQuery := config.DB.Model(&models.Book{}).Where("status = ?", 1).
-		Where("title LIKE ? OR author LIKE ? OR description LIKE ? OR category LIKE ?",
-			searchPattern, searchPattern, searchPattern, searchPattern)
+	baseQuery := config.ReadReplica(sc.db.WithContext(ctx)).Model(&models.Book{}).Where("status = ?", 1).
+		Scopes(services.VisibleUsers("books.seller_id"), services.InCampus("books.campus_id", campusID)).
+		Where(condition, args...)
 
 	if category != "" {
 		baseQuery = baseQuery.Where("category = ?", category)
 	}
 
-	baseQuery.Count(&total)
+	baseQuery.Count(&result.Total)
 
-	config.DB.Where("status = ?", 1).
-		Where("title LIKE ? OR author LIKE ? OR description LIKE ? OR category LIKE ?",
-			searchPattern, searchPattern, searchPattern, searchPattern).
+	baseQuery.
 		Preload("Seller").
 		Limit(limit).
-		Offset(offset).
-		Find(&books)
+		Offset(utils.PageOffset(page, limit)).
+		Find(&result.Items)
 
-	result := gin.H{
-		"books": books,
-		"total": total,
-		"page":  page,
-		"limit": limit,
-		"query": query,
-	}
+	// 结果过少时给出纠错建议及其结果
+	result.DidYouMean = bookSearchCorrection(ctx, sc.db, sc.searchIndexService, sc.synonymService, query, result.Total, limit, category, campusID)
 
 	// 异步缓存
-	go func() {
-		data, _ := json.Marshal(result)
-		sc.redisClient.Set(ctx, cacheKey, data, time.Minute*5)
-	}()
+	data, _ := json.Marshal(result)
+	go utils.SetTaggedCache(ctx, sc.redisClient, utils.CacheTagSearch, cacheKey, data, time.Minute*5)
 
-	c.JSON(http.StatusOK, result)
+	sc.writeBookSearchPage(c, query, page, limit, result)
 }
 
 // GetHotSearchKeywords 获取热门搜索词
@@ -310,21 +576,20 @@ func (sc *SearchController) SearchBooks(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param limit query int false "数量" default(10)
-// @Success 200 {array} string
-// @Router /api/v1/search/hot [get]
+// @Success 200 {object} map[string]interface{}
+// @Router /api/search/hot [get]
 func (sc *SearchController) GetHotSearchKeywords(c *gin.Context) {
+	ctx := c.Request.Context()
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 
 	// 从Redis获取热门搜索（使用sorted set）
 	keywords, err := sc.redisClient.ZRevRange(ctx, "search:hot", 0, int64(limit-1)).Result()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get hot search keywords"})
+		c.Error(utils.NewError(http.StatusInternalServerError, "Failed to get hot search keywords"))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"keywords": keywords,
-	})
+	utils.PaginateAll(c, keywords)
 }
 
 // GetSuggestions 获取搜索建议
@@ -335,11 +600,12 @@ func (sc *SearchController) GetHotSearchKeywords(c *gin.Context) {
 // @Produce json
 // @Param q query string true "输入关键词"
 // @Success 200 {array} string
-// @Router /api/v1/search/suggestions [get]
+// @Router /api/search/suggestions [get]
 func (sc *SearchController) GetSuggestions(c *gin.Context) {
+	ctx := c.Request.Context()
 	query := c.Query("q")
 	if query == "" || len(query) < 2 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Query must be at least 2 characters"})
+		c.Error(utils.NewError(http.StatusBadRequest, "Query must be at least 2 characters"))
 		return
 	}
 
@@ -350,9 +616,11 @@ func (sc *SearchController) GetSuggestions(c *gin.Context) {
 		var suggestions []string
 		if json.Unmarshal([]byte(cached), &suggestions) == nil {
 			c.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
+			utils.RecordCacheHit("search")
 			return
 		}
 	}
+	utils.RecordCacheMiss("search")
 
 	searchPattern := query + "%"
 
@@ -367,8 +635,8 @@ func (sc *SearchController) GetSuggestions(c *gin.Context) {
 		defer wg.Done()
 
 		var titles []string
-		config.DB.Model(&models.Book{}).
-			Where("title LIKE ? AND status = ?", searchPattern, 1).
+		sc.db.WithContext(ctx).Model(&models.Book{}).
+			Where("title LIKE ? AND status = ?", searchPattern, 1).Scopes(services.VisibleUsers("books.seller_id")).
 			Limit(5).
 			Pluck("title", &titles)
 
@@ -383,8 +651,8 @@ func (sc *SearchController) GetSuggestions(c *gin.Context) {
 		defer wg.Done()
 
 		var authors []string
-		config.DB.Model(&models.Book{}).
-			Where("author LIKE ? AND status = ?", searchPattern, 1).
+		sc.db.WithContext(ctx).Model(&models.Book{}).
+			Where("author LIKE ? AND status = ?", searchPattern, 1).Scopes(services.VisibleUsers("books.seller_id")).
 			Group("author").
 			Limit(5).
 			Pluck("author", &authors)
@@ -408,9 +676,37 @@ func (sc *SearchController) GetSuggestions(c *gin.Context) {
 
 	// 异步缓存
 	go func() {
+		ctx := context.WithoutCancel(ctx)
 		data, _ := json.Marshal(result)
-		sc.redisClient.Set(ctx, cacheKey, data, time.Minute*30)
+		utils.SetTaggedCache(ctx, sc.redisClient, utils.CacheTagSearch, cacheKey, data, time.Minute*30)
 	}()
 
 	c.JSON(http.StatusOK, gin.H{"suggestions": result})
 }
+
+// RecordClick 上报搜索结果点击
+// @Summary 上报搜索结果点击
+// @Description 使用搜索接口返回的 search_id 上报用户点击的结果，用于计算点击率
+// @Tags search
+// @Accept json
+// @Produce json
+// @Param request body services.SearchClickRequest true "点击信息"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/search/click [post]
+func (sc *SearchController) RecordClick(c *gin.Context) {
+	var req services.SearchClickRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := sc.analyticsService.RecordClick(c.Request.Context(), c.GetString("user_id"), &req); err != nil {
+		c.Error(utils.NewError(http.StatusInternalServerError, "Failed to record click"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Success",
+	})
+}
@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// SearchIndexController 搜索索引管理控制器（管理员）
+type SearchIndexController struct {
+	indexService *services.SearchIndexService
+	redisClient  *redis.Client
+}
+
+// NewSearchIndexController 创建搜索索引控制器实例
+func NewSearchIndexController(indexService *services.SearchIndexService, redisClient *redis.Client) *SearchIndexController {
+	return &SearchIndexController{
+		indexService: indexService,
+		redisClient:  redisClient,
+	}
+}
+
+// Reindex 重建搜索索引
+// @Summary 重建搜索索引
+// @Description 在后台重建搜索索引，可以全量重建、只重建词表或只重建单本书。返回 task_id，通过 /api/tasks/{id} 查询进度
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body services.ReindexRequest false "重建范围"
+// @Success 202 {object} map[string]interface{}
+// @Router /api/admin/search/reindex [post]
+func (sic *SearchIndexController) Reindex(c *gin.Context) {
+	var req services.ReindexRequest
+	if c.Request.ContentLength > 0 {
+		if err := utils.BindAndValidate(c, &req); err != nil {
+			c.Error(err)
+			return
+		}
+	}
+
+	if err := sic.indexService.AcquireReindexLock(c.Request.Context()); err != nil {
+		c.Error(err)
+		return
+	}
+
+	// 重建在请求结束后继续执行
+	ctx := context.WithoutCancel(c.Request.Context())
+	utils.AsyncTaskResponse(c, sic.redisClient, func(progress utils.ProgressFunc) error {
+		return sic.indexService.Reindex(ctx, &req, progress)
+	})
+}
+
+// GetIndexHealth 获取搜索索引健康状况
+// @Summary 搜索索引健康状况
+// @Description 返回书籍数量、已索引文档数、词表大小、待处理索引队列长度和最近一次重建信息
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} services.IndexHealth
+// @Router /api/admin/search/index/health [get]
+func (sic *SearchIndexController) GetIndexHealth(c *gin.Context) {
+	health, err := sic.indexService.Health(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Success",
+		"data":    health,
+	})
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SecurityController 安全事件控制器（管理员）
+type SecurityController struct {
+	securityService *services.SecurityEventService
+}
+
+// NewSecurityController 创建安全事件控制器实例
+func NewSecurityController(securityService *services.SecurityEventService) *SecurityController {
+	return &SecurityController{
+		securityService: securityService,
+	}
+}
+
+// ListEvents 安全事件归档
+// @Summary 安全事件列表
+// @Description 分页查询已归档的安全事件（IP封禁、登录失败、可疑注册等）
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param stream query string false "事件流: security_events, login_failures"
+// @Param event query string false "事件类型，如 ip_blocked、login_failure、suspicious_activity"
+// @Param ip query string false "IP"
+// @Param email query string false "邮箱"
+// @Param user_id query string false "用户ID"
+// @Param from query string false "开始时间 RFC3339"
+// @Param to query string false "结束时间 RFC3339"
+// @Param page query int false "页码" default(1)
+// @Param limit query int false "每页数量" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/security/events [get]
+func (sc *SecurityController) ListEvents(c *gin.Context) {
+	page, limit := utils.PageParams(c, utils.DefaultPageLimit)
+
+	q := &services.SecurityEventQuery{
+		Stream: c.Query("stream"),
+		Event:  c.Query("event"),
+		IP:     c.Query("ip"),
+		Email:  c.Query("email"),
+		UserID: c.Query("user_id"),
+		Page:   page,
+		Limit:  limit,
+	}
+	for param, target := range map[string]*time.Time{"from": &q.From, "to": &q.To} {
+		if v := c.Query(param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				c.Error(utils.NewError(http.StatusBadRequest, "invalid "+param+" time, expected RFC3339"))
+				return
+			}
+			*target = t
+		}
+	}
+
+	events, total, err := sc.securityService.List(q)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	utils.Paginate(c, events, total, page, limit)
+}
+
+// ListLiveEvents 近期安全事件
+// @Summary 实时安全事件
+// @Description 直接读取Redis流中的近期事件（包括尚未归档的），使用 cursor 向前翻页
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param stream query string false "事件流: security_events, login_failures" default(security_events)
+// @Param cursor query string false "上一页返回的 next_cursor"
+// @Param event query string false "事件类型"
+// @Param ip query string false "IP"
+// @Param limit query int false "每页数量" default(50)
+// @Success 200 {object} services.LiveSecurityEvents
+// @Router /api/admin/security/events/live [get]
+func (sc *SecurityController) ListLiveEvents(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	events, err := sc.securityService.Live(c.Request.Context(), c.DefaultQuery("stream", "security_events"),
+		c.Query("cursor"), c.Query("event"), c.Query("ip"), limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 20000, "message": "Success", "data": events})
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"net/http"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ShareController 分享预览控制器
+type ShareController struct {
+	shareCardService *services.ShareCardService
+}
+
+// NewShareController 创建分享预览控制器实例
+func NewShareController(shareCardService *services.ShareCardService) *ShareController {
+	return &ShareController{
+		shareCardService: shareCardService,
+	}
+}
+
+// GetBookShareCard 书籍分享预览
+// @Summary 书籍分享预览
+// @Description 返回带 Open Graph 标签（书名、价格、封面缩略图）的页面，供微信、QQ等生成链接预览，浏览器打开时跳转到分享链接
+// @Tags share
+// @Produce html
+// @Param id path string true "书籍ID"
+// @Success 200 {string} string "HTML"
+// @Router /api/share/books/{id} [get]
+func (sc *ShareController) GetBookShareCard(c *gin.Context) {
+	sc.serve(c, services.ShareTargetBook)
+}
+
+// GetListingShareCard 发布分享预览
+// @Summary 发布分享预览
+// @Description 返回带 Open Graph 标签（书名、价格、封面缩略图）的页面，供微信、QQ等生成链接预览，浏览器打开时跳转到分享链接
+// @Tags share
+// @Produce html
+// @Param id path string true "发布ID"
+// @Success 200 {string} string "HTML"
+// @Router /api/share/listings/{id} [get]
+func (sc *ShareController) GetListingShareCard(c *gin.Context) {
+	sc.serve(c, services.ShareTargetListing)
+}
+
+// serve 输出预览页面，抓取方和CDN可以缓存一段时间
+func (sc *ShareController) serve(c *gin.Context, target string) {
+	page, err := sc.shareCardService.Render(c.Request.Context(), target, c.Param("id"), utils.RequestLang(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=600")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page)
+}
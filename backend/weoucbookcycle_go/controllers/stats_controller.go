@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StatsController 平台统计控制器（管理员）
+type StatsController struct {
+	statsService *services.StatsService
+}
+
+// NewStatsController 创建统计控制器实例
+func NewStatsController(statsService *services.StatsService) *StatsController {
+	return &StatsController{
+		statsService: statsService,
+	}
+}
+
+// GetOverview 平台概览
+// @Summary 平台概览
+// @Description 用户、书籍、发布总量，在线人数、待审核数和今日数据
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} services.PlatformOverview
+// @Router /api/admin/stats/overview [get]
+func (sc *StatsController) GetOverview(c *gin.Context) {
+	overview, err := sc.statsService.Overview(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 20000, "message": "Success", "data": overview})
+}
+
+// GetDailyStats 每日统计
+// @Summary 每日统计
+// @Description 每日注册、活跃用户、新书、成交和消息数，默认最近30天
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param from query string false "开始日期 YYYY-MM-DD"
+// @Param to query string false "结束日期 YYYY-MM-DD"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/stats/daily [get]
+func (sc *StatsController) GetDailyStats(c *gin.Context) {
+	to := time.Now()
+	if v := c.Query("to"); v != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			c.Error(utils.NewError(http.StatusBadRequest, "invalid to date"))
+			return
+		}
+		to = parsed
+	}
+	from := to.AddDate(0, 0, -29)
+	if v := c.Query("from"); v != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			c.Error(utils.NewError(http.StatusBadRequest, "invalid from date"))
+			return
+		}
+		from = parsed
+	}
+
+	stats, err := sc.statsService.Daily(c.Request.Context(), from, to)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Success",
+		"data": gin.H{
+			"from":  from.Format("2006-01-02"),
+			"to":    to.Format("2006-01-02"),
+			"stats": stats,
+		},
+	})
+}
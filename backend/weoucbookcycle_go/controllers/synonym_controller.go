@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"net/http"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SynonymController 搜索同义词管理控制器（管理员）
+type SynonymController struct {
+	synonymService *services.SynonymService
+}
+
+// NewSynonymController 创建同义词控制器实例
+func NewSynonymController(synonymService *services.SynonymService) *SynonymController {
+	return &SynonymController{
+		synonymService: synonymService,
+	}
+}
+
+// ListSynonyms 获取同义词列表
+// @Summary 获取同义词列表
+// @Description 获取全部搜索同义词组（管理员）
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/search/synonyms [get]
+func (sc *SynonymController) ListSynonyms(c *gin.Context) {
+	synonyms, err := sc.synonymService.List()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	utils.PaginateAll(c, synonyms)
+}
+
+// CreateSynonym 创建同义词组
+// @Summary 创建同义词组
+// @Description 新增一组互为同义的搜索词，例如 ["高数", "高等数学"]
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body services.SynonymRequest true "同义词组"
+// @Success 201 {object} models.SearchSynonym
+// @Router /api/admin/search/synonyms [post]
+func (sc *SynonymController) CreateSynonym(c *gin.Context) {
+	var req services.SynonymRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	synonym, err := sc.synonymService.Create(c.Request.Context(), &req)
+	if err != nil {
+		c.Error(utils.NewError(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"code":    20000,
+		"message": "Synonym created",
+		"data":    synonym,
+	})
+}
+
+// UpdateSynonym 更新同义词组
+// @Summary 更新同义词组
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "同义词组ID"
+// @Param request body services.SynonymRequest true "同义词组"
+// @Success 200 {object} models.SearchSynonym
+// @Router /api/admin/search/synonyms/{id} [put]
+func (sc *SynonymController) UpdateSynonym(c *gin.Context) {
+	var req services.SynonymRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	synonym, err := sc.synonymService.Update(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		c.Error(utils.NewError(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Synonym updated",
+		"data":    synonym,
+	})
+}
+
+// DeleteSynonym 删除同义词组
+// @Summary 删除同义词组
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param id path string true "同义词组ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/search/synonyms/{id} [delete]
+func (sc *SynonymController) DeleteSynonym(c *gin.Context) {
+	if err := sc.synonymService.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		c.Error(utils.NewError(http.StatusNotFound, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Synonym deleted",
+	})
+}
+
+// ReloadSynonyms 重新加载同义词缓存
+// @Summary 重新加载同义词缓存
+// @Description 从数据库重新加载同义词并通知所有实例刷新
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/search/synonyms/reload [post]
+func (sc *SynonymController) ReloadSynonyms(c *gin.Context) {
+	count, err := sc.synonymService.Reload(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Synonyms reloaded",
+		"data": gin.H{
+			"groups": count,
+		},
+	})
+}
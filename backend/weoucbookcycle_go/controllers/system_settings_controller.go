@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"net/http"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SystemSettingsController 运行时参数控制器（管理员）
+type SystemSettingsController struct {
+	settingsService *services.SystemSettingsService
+}
+
+// NewSystemSettingsController 创建运行时参数控制器实例
+func NewSystemSettingsController(settingsService *services.SystemSettingsService) *SystemSettingsController {
+	return &SystemSettingsController{
+		settingsService: settingsService,
+	}
+}
+
+// UpdateSystemSettingRequest 修改参数请求
+type UpdateSystemSettingRequest struct {
+	Value string `json:"value" binding:"required"`
+}
+
+// ListSettings 获取运行时参数
+// @Summary 获取运行时参数
+// @Description 列出所有可在运行时调整的参数、当前值和默认值
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/settings [get]
+func (sc *SystemSettingsController) ListSettings(c *gin.Context) {
+	settings, err := sc.settingsService.List()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 20000, "message": "Success", "data": gin.H{"settings": settings}})
+}
+
+// UpdateSetting 修改运行时参数
+// @Summary 修改运行时参数
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param key path string true "参数名"
+// @Param request body UpdateSystemSettingRequest true "参数值"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/settings/{key} [put]
+func (sc *SystemSettingsController) UpdateSetting(c *gin.Context) {
+	var req UpdateSystemSettingRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := sc.settingsService.Update(c.Request.Context(), c.GetString("user_id"), c.Param("key"), req.Value); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 20000, "message": "Setting updated"})
+}
+
+// ResetSetting 恢复参数默认值
+// @Summary 恢复参数默认值
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param key path string true "参数名"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/settings/{key} [delete]
+func (sc *SystemSettingsController) ResetSetting(c *gin.Context) {
+	if err := sc.settingsService.Reset(c.Request.Context(), c.GetString("user_id"), c.Param("key")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 20000, "message": "Setting reset to default"})
+}
@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// TaskController 异步任务控制器
+type TaskController struct {
+	redisClient *redis.Client
+}
+
+// NewTaskController 创建任务控制器实例
+func NewTaskController(redisClient *redis.Client) *TaskController {
+	return &TaskController{redisClient: redisClient}
+}
+
+// GetTask 查询异步任务状态
+// @Summary 查询异步任务状态
+// @Description 查询后台任务的状态和进度，只能查询自己提交的任务（管理员可查询全部）
+// @Tags tasks
+// @Produce json
+// @Security Bearer
+// @Param id path string true "任务ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/tasks/{id} [get]
+func (tc *TaskController) GetTask(c *gin.Context) {
+	status, err := utils.CheckTaskStatus(tc.redisClient, c.Param("id"))
+	if err != nil {
+		c.Error(utils.NewError(http.StatusNotFound, "Task not found"))
+		return
+	}
+
+	// 任务归属校验，没有记录提交者的任务只有管理员可以查询
+	roles, _ := c.Get("roles")
+	userRoles, _ := roles.([]string)
+	if owner := status["user_id"]; (owner == "" || owner != c.GetString("user_id")) && !slices.Contains(userRoles, "admin") {
+		c.Error(utils.NewError(http.StatusNotFound, "Task not found"))
+		return
+	}
+
+	// 逐项结果以JSON保存，原样返回为数组
+	data := make(gin.H, len(status))
+	for k, v := range status {
+		data[k] = v
+	}
+	if results, ok := status["results"]; ok && json.Valid([]byte(results)) {
+		data["results"] = json.RawMessage(results)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Success",
+		"data":    data,
+	})
+}
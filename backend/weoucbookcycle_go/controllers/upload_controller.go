@@ -0,0 +1,291 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// UploadController 文件上传控制器
+type UploadController struct {
+	uploader             *utils.FileUploader
+	thumbnailService     *services.ThumbnailService
+	chunkedUploadService *services.ChunkedUploadService
+	redisClient          *redis.Client
+}
+
+// NewUploadController 创建上传控制器实例
+func NewUploadController(thumbnailService *services.ThumbnailService, chunkedUploadService *services.ChunkedUploadService, redisClient *redis.Client) *UploadController {
+	return &UploadController{
+		uploader:             utils.NewFileUploader(redisClient),
+		thumbnailService:     thumbnailService,
+		chunkedUploadService: chunkedUploadService,
+		redisClient:          redisClient,
+	}
+}
+
+// UploadImage 上传单张图片
+// @Summary 上传图片
+// @Description 上传单张图片，返回可直接访问的URL（本地存储或对象存储/CDN）
+// @Tags uploads
+// @Accept multipart/form-data
+// @Produce json
+// @Security Bearer
+// @Param file formData file true "图片文件"
+// @Success 200 {object} utils.UploadResult
+// @Router /api/uploads/images [post]
+func (uc *UploadController) UploadImage(c *gin.Context) {
+	result, err := uc.uploader.UploadFile(c, "file")
+	if err != nil {
+		respondUploadError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Upload successful",
+		"data":    result,
+	})
+}
+
+// UploadPrivateImage 上传私有图片
+// @Summary 上传私有图片
+// @Description 上传学生证照片、纠纷证据等不公开的图片，返回10分钟内有效的签名URL，之后通过 /api/files/{id} 重新获取
+// @Tags uploads
+// @Accept multipart/form-data
+// @Produce json
+// @Security Bearer
+// @Param file formData file true "图片文件"
+// @Param purpose formData string true "用途：verification（学生认证）、evidence（纠纷证据）"
+// @Success 200 {object} utils.UploadResult
+// @Router /api/uploads/private [post]
+func (uc *UploadController) UploadPrivateImage(c *gin.Context) {
+	purpose := c.PostForm("purpose")
+	if purpose != "verification" && purpose != "evidence" {
+		c.Error(utils.NewError(http.StatusBadRequest, "purpose must be verification or evidence"))
+		return
+	}
+
+	result, err := uc.uploader.Private(purpose).UploadFile(c, "file")
+	if err != nil {
+		respondUploadError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Upload successful",
+		"data":    result,
+	})
+}
+
+// UploadImages 批量上传图片
+// @Summary 批量上传图片
+// @Tags uploads
+// @Accept multipart/form-data
+// @Produce json
+// @Security Bearer
+// @Description async=true 时立即返回 task_id，通过 /api/tasks/{id} 查询进度和每个文件的结果
+// @Param files formData file true "图片文件（可多个）"
+// @Param async query bool false "是否异步处理"
+// @Success 200 {array} utils.UploadResult
+// @Success 202 {object} map[string]interface{}
+// @Router /api/uploads/images/batch [post]
+func (uc *UploadController) UploadImages(c *gin.Context) {
+	if c.Query("async") == "true" {
+		taskID, count, err := uc.uploader.UploadFilesAsync(c, "files")
+		if err != nil {
+			respondUploadError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"code":    20000,
+			"message": "Upload task submitted",
+			"data": gin.H{
+				"task_id": taskID,
+				"total":   count,
+			},
+		})
+		return
+	}
+
+	results, err := uc.uploader.UploadFiles(c, "files")
+	if err != nil && len(results) == 0 {
+		respondUploadError(c, err)
+		return
+	}
+
+	response := gin.H{
+		"code":    20000,
+		"message": "Upload successful",
+		"data":    results,
+	}
+	// 部分失败时返回成功的结果和错误信息
+	if err != nil {
+		response["message"] = "Some uploads failed"
+		response["error"] = err.Error()
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// BackfillThumbnails 为已有图片补生成缩略图（管理员）
+// @Summary 补生成缩略图
+// @Description 在后台为书籍图片中缺少缩略图的文件生成缩略图，返回 task_id，通过 /api/tasks/{id} 查询进度
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Success 202 {object} map[string]interface{}
+// @Router /api/admin/uploads/thumbnails/backfill [post]
+func (uc *UploadController) BackfillThumbnails(c *gin.Context) {
+	if err := uc.thumbnailService.AcquireBackfillLock(c.Request.Context()); err != nil {
+		c.Error(err)
+		return
+	}
+
+	// 补生成在请求结束后继续执行
+	ctx := context.WithoutCancel(c.Request.Context())
+	utils.AsyncTaskResponse(c, uc.redisClient, func(progress utils.ProgressFunc) error {
+		return uc.thumbnailService.Backfill(ctx, progress)
+	})
+}
+
+// InitChunkedUpload 初始化分片上传
+// @Summary 初始化分片上传
+// @Description 创建分片上传会话，返回 upload_id、分片大小和 task_id（可通过 /api/tasks/{id} 查询上传进度）
+// @Tags uploads
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body services.InitChunkedUploadRequest true "文件信息"
+// @Success 200 {object} services.ChunkedUploadSession
+// @Router /api/uploads/chunked [post]
+func (uc *UploadController) InitChunkedUpload(c *gin.Context) {
+	var req services.InitChunkedUploadRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	session, err := uc.chunkedUploadService.InitUpload(c.Request.Context(), c.GetString("user_id"), &req)
+	if err != nil {
+		respondUploadError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Upload session created",
+		"data":    session,
+	})
+}
+
+// GetChunkedUpload 查询分片上传会话
+// @Summary 查询分片上传会话
+// @Description 返回已上传的分片序号，客户端据此续传缺失的分片
+// @Tags uploads
+// @Produce json
+// @Security Bearer
+// @Param id path string true "上传ID"
+// @Success 200 {object} services.ChunkedUploadSession
+// @Router /api/uploads/chunked/{id} [get]
+func (uc *UploadController) GetChunkedUpload(c *gin.Context) {
+	session, err := uc.chunkedUploadService.GetSession(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		respondUploadError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Success",
+		"data":    session,
+	})
+}
+
+// UploadChunk 上传分片
+// @Summary 上传分片
+// @Description 请求体为分片的原始字节（application/octet-stream），重复上传同一分片会覆盖
+// @Tags uploads
+// @Accept octet-stream
+// @Produce json
+// @Security Bearer
+// @Param id path string true "上传ID"
+// @Param index path int true "分片序号（从0开始）"
+// @Success 200 {object} services.ChunkedUploadSession
+// @Router /api/uploads/chunked/{id}/chunks/{index} [put]
+func (uc *UploadController) UploadChunk(c *gin.Context) {
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil {
+		c.Error(utils.NewError(http.StatusBadRequest, "Invalid chunk index"))
+		return
+	}
+
+	session, err := uc.chunkedUploadService.PutChunk(c.Request.Context(), c.GetString("user_id"), c.Param("id"), index, c.Request.Body)
+	if err != nil {
+		respondUploadError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Chunk uploaded",
+		"data":    session,
+	})
+}
+
+// CompleteChunkedUpload 完成分片上传
+// @Summary 完成分片上传
+// @Description 合并全部分片并按普通图片上传处理，返回上传结果
+// @Tags uploads
+// @Produce json
+// @Security Bearer
+// @Param id path string true "上传ID"
+// @Success 200 {object} utils.UploadResult
+// @Router /api/uploads/chunked/{id}/complete [post]
+func (uc *UploadController) CompleteChunkedUpload(c *gin.Context) {
+	result, err := uc.chunkedUploadService.Complete(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		respondUploadError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Upload successful",
+		"data":    result,
+	})
+}
+
+// AbortChunkedUpload 取消分片上传
+// @Summary 取消分片上传
+// @Tags uploads
+// @Produce json
+// @Security Bearer
+// @Param id path string true "上传ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/uploads/chunked/{id} [delete]
+func (uc *UploadController) AbortChunkedUpload(c *gin.Context) {
+	if err := uc.chunkedUploadService.Abort(c.Request.Context(), c.GetString("user_id"), c.Param("id")); err != nil {
+		respondUploadError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 20000, "message": "Upload aborted"})
+}
+
+// respondUploadError 上传错误响应，服务层的 AppError 按其状态码返回，其余视为请求错误
+func respondUploadError(c *gin.Context, err error) {
+	if errors.Is(err, utils.ErrInfectedFile) {
+		c.Error(utils.WrapError(http.StatusUnprocessableEntity, "File rejected by virus scan", err))
+		return
+	}
+	c.Error(utils.WithDefaultStatus(err, http.StatusBadRequest))
+}
@@ -4,121 +4,286 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
-	"strconv"
 	"time"
-	"weoucbookcycle_go/config"
 	"weoucbookcycle_go/models"
 	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
 )
 
 // UserController 用户控制器
-type UserController struct{}
+type UserController struct {
+	uploader            *utils.FileUploader
+	chatService         *services.ChatService
+	storageService      *services.StorageUsageService
+	userSettingsService *services.UserSettingsService
+	reputationService   *services.ReputationService
+	dashboardService    *services.DashboardService
+	usernameService     *services.UsernameService
+	profileService      *services.ProfileService
+	badgeService        *services.BadgeService
+	db                  *gorm.DB
+	redisClient         *redis.Client
+}
 
 // NewUserController 创建用户控制器实例
-func NewUserController() *UserController {
-	return &UserController{}
+func NewUserController(chatService *services.ChatService, storageService *services.StorageUsageService, userSettingsService *services.UserSettingsService,
+	reputationService *services.ReputationService, dashboardService *services.DashboardService,
+	usernameService *services.UsernameService, profileService *services.ProfileService, badgeService *services.BadgeService,
+	db *gorm.DB, redisClient *redis.Client) *UserController {
+	return &UserController{
+		uploader:            utils.NewFileUploader(redisClient),
+		chatService:         chatService,
+		storageService:      storageService,
+		userSettingsService: userSettingsService,
+		reputationService:   reputationService,
+		dashboardService:    dashboardService,
+		usernameService:     usernameService,
+		profileService:      profileService,
+		badgeService:        badgeService,
+		db:                  db,
+		redisClient:         redisClient,
+	}
 }
 
-// UpdateProfileRequest 更新用户资料请求结构
-type UpdateProfileRequest struct {
-	Username string `json:"username" binding:"omitempty,min=3,max=50"`
-	Avatar   string `json:"avatar" binding:"omitempty"`
-	Phone    string `json:"phone" binding:"omitempty"`
-	Bio      string `json:"bio" binding:"omitempty,max=500"`
-}
+// publicProfileItems 个人主页展示的在售书籍和发布数量
+const publicProfileItems = 20
 
 // GetUserProfile 获取用户资料
 // @Summary 获取用户资料
-// @Description 根据用户ID获取用户详细信息
+// @Description 本人查看时返回完整资料（同 /api/users/me），其他人只能看到公开信息、获得的徽章和最近在售的书籍、发布（用户关闭 show_books_on_profile 时为空）
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param id path string true "用户ID"
-// @Success 200 {object} models.User
-// @Router /api/v1/users/{id} [get]
+// @Success 200 {object} models.PublicProfile
+// @Router /api/users/{id} [get]
 func (uc *UserController) GetUserProfile(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID := c.Param("id")
-	// 先尝试从Redis缓存获取
+	if userID == c.GetString("user_id") {
+		uc.GetMyProfile(c)
+		return
+	}
+
+	// 先尝试从Redis缓存获取（只缓存公开资料）
 	cacheKey := "user:" + userID
 
-	cachedData, err := config.RedisClient.Get(context.Background(), cacheKey).Result()
+	cachedData, err := uc.redisClient.Get(ctx, cacheKey).Bytes()
 	if err == nil {
-		var cachedUser models.User
-		if err := json.Unmarshal([]byte(cachedData), &cachedUser); err == nil {
-			c.JSON(http.StatusOK, cachedUser)
+		var cached models.PublicProfile
+		if err := json.Unmarshal(cachedData, &cached); err == nil {
+			c.JSON(http.StatusOK, cached)
+			utils.RecordCacheHit("users")
 			return
 		}
 	}
+	utils.RecordCacheMiss("users")
 
+	// 已停用账号的资料对他人隐藏
 	var user models.User
-	if err := config.DB.Preload("Books").Preload("Listings").First(&user, "id = ?", userID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	if err := uc.db.WithContext(ctx).First(&user, "id = ? AND status <> ?", userID, models.UserStatusDeactivated).Error; err != nil {
+		c.Error(utils.NewError(http.StatusNotFound, "User not found"))
+		return
+	}
+
+	uc.badgeService.Fill(ctx, &user)
+	profile := models.PublicProfile{PublicUser: user.Public(), Books: []models.Book{}, Listings: []models.Listing{}}
+	settings, err := uc.userSettingsService.GetSettings(userID)
+	if err != nil {
+		c.Error(err)
 		return
 	}
+	// 用户可以在隐私设置中隐藏个人主页上的书籍和发布
+	if settings.ShowBooksOnProfile {
+		if err := uc.db.WithContext(ctx).Where("seller_id = ? AND status = ?", userID, 1).
+			Order("created_at DESC").Limit(publicProfileItems).Find(&profile.Books).Error; err != nil {
+			c.Error(err)
+			return
+		}
+		if err := uc.db.WithContext(ctx).Preload("Book").Where("seller_id = ? AND status = ?", userID, "available").
+			Order("created_at DESC").Limit(publicProfileItems).Find(&profile.Listings).Error; err != nil {
+			c.Error(err)
+			return
+		}
+	}
 
 	//异步缓存用户信息到Redis（使用goroutine）
-	go func() {
-		config.RedisClient.Set(context.Background(), cacheKey, user, time.Minute*30)
-	}()
+	if data, err := json.Marshal(profile); err == nil {
+		go func() {
+			ctx := context.WithoutCancel(ctx)
+			uc.redisClient.Set(ctx, cacheKey, data, time.Minute*30)
+		}()
+	}
 
-	c.JSON(http.StatusOK, user)
+	c.JSON(http.StatusOK, profile)
 }
 
 // UpdateUserProfile 更新用户资料
 // @Summary 更新用户资料
-// @Description 更新当前登录用户的资料信息
+// @Description 更新当前登录用户的资料信息，只修改填写了的字段，返回更新后的资料。
+// @Description 手机号需为有效的大陆手机号，简介会去掉HTML标签；修改用户名与 PUT /api/users/me/username 相同（检查唯一性和30天冷却期），
+// @Description 任一字段不合法时都不会修改
 // @Tags users
 // @Accept json
 // @Produce json
 // @Security Bearer
-// @Param request body UpdateProfileRequest true "用户资料"
-// @Success 200 {object} map[string]interface{}
-// @Router /api/v1/users/profile [put]
+// @Param request body services.ProfileRequest true "用户资料"
+// @Success 200 {object} models.User
+// @Router /api/users/profile [put]
 func (uc *UserController) UpdateUserProfile(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID := c.GetString("user_id") // 从中间件获取
 
-	var req UpdateProfileRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var req services.ProfileRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	user, err := uc.profileService.Update(ctx, userID, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Profile updated successfully",
+		"data":    user,
+	})
+}
+
+// UploadAvatar 上传头像
+// @Summary 上传头像
+// @Description 上传图片并设为头像：与普通图片上传一样经过格式校验、病毒扫描和缩略图生成，头像使用缩略图（生成失败时使用原图），
+// @Description 上传的文件计入存储用量
+// @Tags users
+// @Accept multipart/form-data
+// @Produce json
+// @Security Bearer
+// @Param file formData file true "头像图片"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/users/me/avatar [post]
+func (uc *UserController) UploadAvatar(c *gin.Context) {
+	result, err := uc.uploader.UploadFile(c, "file")
+	if err != nil {
+		respondUploadError(c, err)
 		return
 	}
 
-	// 构建更新map
-	updates := make(map[string]interface{})
-	if req.Username != "" {
-		updates["username"] = req.Username
+	avatar := result.ThumbURL
+	if avatar == "" {
+		avatar = result.OriginalURL
 	}
-	if req.Avatar != "" {
-		updates["avatar"] = req.Avatar
+	user, err := uc.profileService.SetAvatar(c.Request.Context(), c.GetString("user_id"), avatar)
+	if err != nil {
+		c.Error(err)
+		return
 	}
-	if req.Phone != "" {
-		updates["phone"] = req.Phone
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Avatar updated",
+		"data": gin.H{
+			"user":   user,
+			"upload": result,
+		},
+	})
+}
+
+// CheckUsername 检查用户名是否可用
+// @Summary 检查用户名是否可用
+// @Description 不可用时 reason 为 invalid（格式不符）、taken（已被使用）或 unchanged（就是自己当前的用户名）
+// @Tags users
+// @Produce json
+// @Param username query string true "用户名"
+// @Success 200 {object} services.UsernameAvailability
+// @Router /api/users/username/available [get]
+func (uc *UserController) CheckUsername(c *gin.Context) {
+	result, err := uc.usernameService.Check(c.Request.Context(), c.GetString("user_id"), c.Query("username"))
+	if err != nil {
+		c.Error(err)
+		return
 	}
-	if req.Bio != "" {
-		updates["bio"] = req.Bio
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Success",
+		"data":    result,
+	})
+}
+
+// ChangeUsername 修改用户名
+// @Summary 修改用户名
+// @Description 新用户名需要未被使用，两次修改至少间隔30天，冷却期内返回429和 retry_after。
+// @Description 已签发的登录令牌中仍是旧用户名，刷新令牌后更新
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body services.UsernameRequest true "新用户名"
+// @Success 200 {object} models.User
+// @Router /api/users/me/username [put]
+func (uc *UserController) ChangeUsername(c *gin.Context) {
+	var req services.UsernameRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
 	}
 
-	if len(updates) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No fields to update"})
+	user, err := uc.usernameService.Change(c.Request.Context(), c.GetString("user_id"), req.Username)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	var user models.User
-	if err := config.DB.Model(&user).Where("id = ?", userID).Updates(updates).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Username updated",
+		"data":    user,
+	})
+}
+
+// GetUsernameHistory 获取我的改名记录
+// @Summary 获取改名记录
+// @Tags users
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} utils.PageResponse{data=[]models.UsernameChange}
+// @Router /api/users/me/username/history [get]
+func (uc *UserController) GetUsernameHistory(c *gin.Context) {
+	changes, err := uc.usernameService.History(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	// 删除Redis缓存
-	// go func() {
-	//     config.Redis.Del(context.Background(), "user:"+userID)
-	// }()
+	utils.PaginateAll(c, changes)
+}
+
+// GetUserBadges 获取用户获得的徽章
+// @Summary 获取用户徽章
+// @Description 徽章包括 first_sale（第一笔成交）、books_recycled_10（累计售出10本）、verified_student（验证过学校邮箱）、fast_responder（聊天回复及时），按展示顺序返回
+// @Tags users
+// @Produce json
+// @Param id path string true "用户ID"
+// @Success 200 {array} models.UserBadge
+// @Router /api/users/{id}/badges [get]
+func (uc *UserController) GetUserBadges(c *gin.Context) {
+	badges, err := uc.badgeService.List(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Profile updated successfully",
-		"user":    user,
+		"code":    20000,
+		"message": "Success",
+		"data":    badges,
 	})
 }
 
@@ -130,28 +295,31 @@ func (uc *UserController) UpdateUserProfile(c *gin.Context) {
 // @Produce json
 // @Param page query int false "页码" default(1)
 // @Param limit query int false "每页数量" default(20)
-// @Success 200 {array} models.User
-// @Router /api/v1/users/active [get]
+// @Success 200 {object} map[string]interface{}
+// @Router /api/users/active [get]
 func (uc *UserController) GetActiveUsers(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	offset := (page - 1) * limit
+	ctx := c.Request.Context()
+	page, limit := utils.PageParams(c, utils.DefaultPageLimit)
+
+	query := uc.db.WithContext(ctx).Model(&models.User{}).Where("status <> ?", models.UserStatusDeactivated)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.Error(utils.NewError(http.StatusInternalServerError, "Failed to get users"))
+		return
+	}
 
 	var users []models.User
-	if err := config.DB.
+	if err := query.
 		Order("last_login DESC").
 		Limit(limit).
-		Offset(offset).
+		Offset(utils.PageOffset(page, limit)).
 		Find(&users).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get users"})
+		c.Error(utils.NewError(http.StatusInternalServerError, "Failed to get users"))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"users": users,
-		"page":  page,
-		"limit": limit,
-	})
+	utils.Paginate(c, users, total, page, limit)
 }
 
 // GetOnlineUsers 获取在线用户列表
@@ -161,62 +329,95 @@ func (uc *UserController) GetActiveUsers(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Success 200 {object} map[string]interface{}
-// @Router /api/v1/users/online [get]
+// @Router /api/users/online [get]
 func (uc *UserController) GetOnlineUsers(c *gin.Context) {
-	chatService := services.NewChatService()
-
-	onlineUsers, err := chatService.GetOnlineUsers()
+	onlineUsers, err := uc.chatService.GetOnlineUsers(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 50000, "message": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"code":    20000,
-		"message": "Success",
-		"data": gin.H{
-			"online_users": onlineUsers,
-			"count":        len(onlineUsers),
-		},
-	})
+	utils.PaginateAll(c, onlineUsers)
 }
 
 // GetMyProfile 获取当前登录用户资料
+// @Summary 获取当前用户资料
+// @Description 获取当前登录用户的完整资料（含发布的书籍和发布记录）
+// @Tags users
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} models.User
+// @Router /api/users/me [get]
 func (uc *UserController) GetMyProfile(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID := c.GetString("user_id")
 	if userID == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		c.Error(utils.NewError(http.StatusUnauthorized, "unauthorized"))
 		return
 	}
 
 	var user models.User
-	if err := config.DB.Preload("Books").Preload("Listings").First(&user, "id = ?", userID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	if err := uc.db.WithContext(ctx).Preload("Books").Preload("Listings").First(&user, "id = ?", userID).Error; err != nil {
+		c.Error(utils.NewError(http.StatusNotFound, "User not found"))
 		return
 	}
+	uc.badgeService.Fill(ctx, &user)
 
 	c.JSON(http.StatusOK, user)
 }
 
+// GetMyStats 获取当前用户的个人统计
+// @Summary 获取个人统计
+// @Description "我的"页面一次请求所需的汇总：在售书籍、进行中的发布、收到的收藏、聊天数、未读消息和通知数以及成交情况。
+// @Description 书籍和发布的汇总缓存几分钟，updated_at 为汇总时间；未读数为实时值
+// @Tags users
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} services.DashboardStats
+// @Router /api/users/me/stats [get]
+func (uc *UserController) GetMyStats(c *gin.Context) {
+	stats, err := uc.dashboardService.Get(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Success",
+		"data":    stats,
+	})
+}
+
 // ToggleWishlist 切换心愿单中的书籍
+// @Summary 切换心愿单
+// @Description 书籍已在心愿单中则移除，否则加入，返回更新后的书籍ID列表
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body map[string]interface{} true "书籍ID" example='{"bookId":"..."}'
+// @Success 200 {array} string
+// @Router /api/users/wishlist/toggle [post]
 func (uc *UserController) ToggleWishlist(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID := c.GetString("user_id")
 	if userID == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		c.Error(utils.NewError(http.StatusUnauthorized, "unauthorized"))
 		return
 	}
 
 	var body struct {
 		BookID string `json:"bookId" binding:"required"`
 	}
-	if err := c.ShouldBindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := utils.BindAndValidate(c, &body); err != nil {
+		c.Error(err)
 		return
 	}
 
 	var user models.User
-	if err := config.DB.First(&user, "id = ?", userID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	if err := uc.db.WithContext(ctx).First(&user, "id = ?", userID).Error; err != nil {
+		c.Error(utils.NewError(http.StatusNotFound, "User not found"))
 		return
 	}
 
@@ -243,8 +444,8 @@ func (uc *UserController) ToggleWishlist(c *gin.Context) {
 	b, _ := json.Marshal(list)
 	user.Wishlist = b
 
-	if err := config.DB.Model(&user).Update("wishlist", user.Wishlist).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update wishlist"})
+	if err := uc.db.WithContext(ctx).Model(&user).Update("wishlist", user.Wishlist).Error; err != nil {
+		c.Error(utils.NewError(http.StatusInternalServerError, "failed to update wishlist"))
 		return
 	}
 
@@ -252,10 +453,20 @@ func (uc *UserController) ToggleWishlist(c *gin.Context) {
 }
 
 // EvaluateUser 评价卖家并调整信任分
+// @Summary 评价卖家
+// @Description 好评信任分+1，差评-5，范围 0-100；评价同时计入卖家信誉分（见 /api/users/{id}/reputation）
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body map[string]interface{} true "评价" example='{"seller_id":"...","is_good":true}'
+// @Success 200 {object} map[string]interface{}
+// @Router /api/evaluate [post]
 func (uc *UserController) EvaluateUser(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID := c.GetString("user_id")
 	if userID == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		c.Error(utils.NewError(http.StatusUnauthorized, "unauthorized"))
 		return
 	}
 
@@ -263,14 +474,14 @@ func (uc *UserController) EvaluateUser(c *gin.Context) {
 		SellerID string `json:"seller_id" binding:"required"`
 		IsGood   bool   `json:"is_good"`
 	}
-	if err := c.ShouldBindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := utils.BindAndValidate(c, &body); err != nil {
+		c.Error(err)
 		return
 	}
 
 	var seller models.User
-	if err := config.DB.First(&seller, "id = ?", body.SellerID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Seller not found"})
+	if err := uc.db.WithContext(ctx).First(&seller, "id = ?", body.SellerID).Error; err != nil {
+		c.Error(utils.NewError(http.StatusNotFound, "Seller not found"))
 		return
 	}
 
@@ -287,10 +498,120 @@ func (uc *UserController) EvaluateUser(c *gin.Context) {
 		seller.TrustScore = 100
 	}
 
-	if err := config.DB.Model(&seller).Update("trust_score", seller.TrustScore).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update trust score"})
+	if err := uc.db.WithContext(ctx).Model(&seller).Update("trust_score", seller.TrustScore).Error; err != nil {
+		c.Error(utils.NewError(http.StatusInternalServerError, "failed to update trust score"))
+		return
+	}
+
+	// 评价计入信誉分（只有在该卖家处买过书的评价有效），在下次计算时生效
+	if err := uc.reputationService.Rate(ctx, userID, body.SellerID, body.IsGood); err != nil {
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, seller)
 }
+
+// GetMyStorage 获取当前用户的存储用量和上传的文件
+// @Summary 获取存储用量
+// @Description 返回已用空间、文件数、配额和分页的文件列表
+// @Tags users
+// @Produce json
+// @Security Bearer
+// @Param page query int false "页码" default(1)
+// @Param limit query int false "每页数量" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/users/me/storage [get]
+func (uc *UserController) GetMyStorage(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	page, limit := utils.PageParams(c, utils.DefaultPageLimit)
+
+	usage, err := uc.storageService.GetUsage(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	files, total, err := uc.storageService.ListFiles(userID, page, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	utils.WritePage(c, files, utils.NewPagination(c, "page", page, limit, total), gin.H{"usage": usage})
+}
+
+// DeleteMyFile 删除当前用户上传的文件
+// @Summary 删除上传的文件
+// @Description 释放存储空间，仍被书籍引用的图片不能删除
+// @Tags users
+// @Produce json
+// @Security Bearer
+// @Param id path string true "文件ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/users/me/storage/files/{id} [delete]
+func (uc *UserController) DeleteMyFile(c *gin.Context) {
+	err := uc.storageService.DeleteFile(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 20000, "message": "File deleted"})
+}
+
+// GetMySettings 获取当前用户的设置
+// @Summary 获取用户设置
+// @Tags users
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} models.UserSettings
+// @Router /api/users/settings [get]
+func (uc *UserController) GetMySettings(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	settings, err := uc.userSettingsService.GetSettings(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Success",
+		"data":    settings,
+	})
+}
+
+// UpdateMySettings 更新当前用户的设置
+// @Summary 更新用户设置
+// @Description 支持设置是否允许在用户搜索中被找到、是否个性化搜索结果；隐私开关：是否显示在线状态、个人主页是否展示书籍、是否允许未关注的用户发起聊天
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body services.UpdateSettingsRequest true "设置"
+// @Success 200 {object} models.UserSettings
+// @Router /api/users/settings [put]
+func (uc *UserController) UpdateMySettings(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req services.UpdateSettingsRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	settings, err := uc.userSettingsService.UpdateSettings(c.Request.Context(), userID, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Settings updated",
+		"data":    settings,
+	})
+}
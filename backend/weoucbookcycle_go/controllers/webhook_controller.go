@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"net/http"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookController 外部应用 webhook 订阅控制器
+type WebhookController struct {
+	webhookService *services.WebhookService
+}
+
+// NewWebhookController 创建 webhook 控制器实例
+func NewWebhookController(webhookService *services.WebhookService) *WebhookController {
+	return &WebhookController{
+		webhookService: webhookService,
+	}
+}
+
+// ListWebhooks 获取 webhook 订阅列表
+// @Summary 获取 webhook 订阅列表
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/webhooks [get]
+func (wc *WebhookController) ListWebhooks(c *gin.Context) {
+	subs, err := wc.webhookService.List(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	utils.PaginateAll(c, subs)
+}
+
+// CreateWebhook 登记 webhook 订阅
+// @Summary 登记 webhook 订阅
+// @Description 可订阅 user.registered、book.created、order.completed。每次投递以 POST 发送JSON，
+// @Description X-Webhook-Signature 为 sha256=hex(HMAC-SHA256(secret, X-Webhook-Timestamp + "." + 请求体))。
+// @Description 非2xx响应按退避间隔重试。secret 只在此时返回一次
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body services.WebhookRequest true "订阅"
+// @Success 201 {object} map[string]interface{}
+// @Router /api/admin/webhooks [post]
+func (wc *WebhookController) CreateWebhook(c *gin.Context) {
+	var req services.WebhookRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	sub, err := wc.webhookService.Create(c.Request.Context(), c.GetString("user_id"), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"code":    20000,
+		"message": "Webhook created",
+		"data":    sub,
+	})
+}
+
+// UpdateWebhook 更新 webhook 订阅
+// @Summary 更新 webhook 订阅
+// @Description 停用后不再产生新的投递，等待重试的投递也不再发送
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "订阅ID"
+// @Param request body services.WebhookRequest true "订阅"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/webhooks/{id} [put]
+func (wc *WebhookController) UpdateWebhook(c *gin.Context) {
+	var req services.WebhookRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	sub, err := wc.webhookService.Update(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Webhook updated",
+		"data":    sub,
+	})
+}
+
+// DeleteWebhook 删除 webhook 订阅
+// @Summary 删除 webhook 订阅
+// @Description 投递记录保留，仍可查询
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param id path string true "订阅ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/webhooks/{id} [delete]
+func (wc *WebhookController) DeleteWebhook(c *gin.Context) {
+	if err := wc.webhookService.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Webhook deleted",
+	})
+}
+
+// RotateWebhookSecret 重置 webhook 签名密钥
+// @Summary 重置 webhook 签名密钥
+// @Description 旧密钥立即失效，新密钥只在此时返回一次
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param id path string true "订阅ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/webhooks/{id}/secret [post]
+func (wc *WebhookController) RotateWebhookSecret(c *gin.Context) {
+	sub, err := wc.webhookService.RotateSecret(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    20000,
+		"message": "Webhook secret rotated",
+		"data":    sub,
+	})
+}
+
+// ListWebhookDeliveries 获取 webhook 投递记录
+// @Summary 获取 webhook 投递记录
+// @Description 最近的在前，每条投递包含每次尝试的状态码、错误和耗时
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param id path string true "订阅ID"
+// @Param status query string false "pending, succeeded, failed"
+// @Param page query int false "页码" default(1)
+// @Param limit query int false "每页数量" default(20)
+// @Success 200 {object} utils.PageResponse{data=[]models.WebhookDelivery}
+// @Router /api/admin/webhooks/{id}/deliveries [get]
+func (wc *WebhookController) ListWebhookDeliveries(c *gin.Context) {
+	page, limit := utils.PageParams(c, utils.DefaultPageLimit)
+
+	deliveries, total, err := wc.webhookService.Deliveries(c.Request.Context(), c.Param("id"), c.Query("status"), page, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	utils.Paginate(c, deliveries, total, page, limit)
+}
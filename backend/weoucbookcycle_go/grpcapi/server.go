@@ -0,0 +1,250 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"runtime/debug"
+	"strings"
+	"weoucbookcycle_go/config"
+	"weoucbookcycle_go/grpcapi/pb"
+	"weoucbookcycle_go/models"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+)
+
+// 内部 gRPC 接口：推荐、审核等内部服务通过这里查询用户、书籍和在线状态，不经过 HTTP/JSON 接口
+// 接口定义见 proto/internal_api.proto，只读，查询走只读副本
+
+// maxBatchSize 批量查询一次最多的ID数
+const maxBatchSize = 100
+
+// PresenceChecker 查询用户是否在线，由 services.ChatService 实现
+type PresenceChecker interface {
+	IsUserOnline(ctx context.Context, userID string) bool
+}
+
+// NewServer 创建 gRPC 服务器并注册全部内部服务
+// 所有调用都需要在 metadata 中携带 "authorization: Bearer <GRPC_AUTH_TOKEN>"
+func NewServer(cfg *config.GRPCConfig, db *gorm.DB, presence PresenceChecker) *grpc.Server {
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(recoverInterceptor, authInterceptor(cfg.AuthToken)))
+	pb.RegisterUserServiceServer(server, &userServer{db: db})
+	pb.RegisterBookServiceServer(server, &bookServer{db: db})
+	pb.RegisterPresenceServiceServer(server, &presenceServer{presence: presence})
+	return server
+}
+
+// Start 在 cfg.Addr 上启动 gRPC 服务，返回的 stop 等待处理中的调用完成，ctx 到期后强制关闭
+func Start(cfg *config.GRPCConfig, db *gorm.DB, presence PresenceChecker) (stop func(ctx context.Context), err error) {
+	listener, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen %s: %w", cfg.Addr, err)
+	}
+	server := NewServer(cfg, db, presence)
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			log.Printf("gRPC server stopped unexpectedly: %v", err)
+		}
+	}()
+
+	return func(ctx context.Context) {
+		done := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			server.Stop()
+		}
+	}, nil
+}
+
+// authInterceptor 校验调用方携带的共享令牌
+func authInterceptor(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get("authorization")
+		if len(values) == 0 {
+			return nil, status.Error(codes.Unauthenticated, "missing authorization")
+		}
+		given, ok := strings.CutPrefix(values[0], "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// recoverInterceptor 处理函数 panic 时返回 Internal，不让整个进程退出
+func recoverInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("gRPC panic in %s: %v\n%s", info.FullMethod, r, debug.Stack())
+			err = status.Error(codes.Internal, "internal error")
+		}
+	}()
+	return handler(ctx, req)
+}
+
+// checkIDs 校验批量查询的ID列表
+func checkIDs(ids []string) error {
+	if len(ids) > maxBatchSize {
+		return status.Errorf(codes.InvalidArgument, "at most %d ids per call, got %d", maxBatchSize, len(ids))
+	}
+	return nil
+}
+
+// queryError 把数据库错误转换为 gRPC 状态
+func queryError(err error, what, id string) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return status.Errorf(codes.NotFound, "%s %s not found", what, id)
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	default:
+		log.Printf("gRPC query %s failed: %v", what, err)
+		return status.Error(codes.Internal, "query failed")
+	}
+}
+
+// ==================== 用户 ====================
+
+type userServer struct {
+	pb.UnimplementedUserServiceServer
+	db *gorm.DB
+}
+
+func (s *userServer) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.User, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	var user models.User
+	if err := config.ReadReplica(s.db.WithContext(ctx)).Where("id = ?", req.GetId()).First(&user).Error; err != nil {
+		return nil, queryError(err, "user", req.GetId())
+	}
+	return userToPB(&user), nil
+}
+
+func (s *userServer) BatchGetUsers(ctx context.Context, req *pb.BatchGetUsersRequest) (*pb.BatchGetUsersResponse, error) {
+	if err := checkIDs(req.GetIds()); err != nil {
+		return nil, err
+	}
+	resp := &pb.BatchGetUsersResponse{}
+	if len(req.GetIds()) == 0 {
+		return resp, nil
+	}
+	var users []models.User
+	if err := config.ReadReplica(s.db.WithContext(ctx)).Where("id IN ?", req.GetIds()).Find(&users).Error; err != nil {
+		return nil, queryError(err, "users", "")
+	}
+	for i := range users {
+		resp.Users = append(resp.Users, userToPB(&users[i]))
+	}
+	return resp, nil
+}
+
+// userToPB 只转换公开字段
+func userToPB(u *models.User) *pb.User {
+	return &pb.User{
+		Id:         u.ID,
+		Username:   u.Username,
+		Avatar:     u.Avatar,
+		Bio:        u.Bio,
+		TrustScore: int32(u.TrustScore),
+		Status:     int32(u.Status),
+		Role:       u.Role,
+		CreatedAt:  timestamppb.New(u.CreatedAt),
+	}
+}
+
+// ==================== 书籍 ====================
+
+type bookServer struct {
+	pb.UnimplementedBookServiceServer
+	db *gorm.DB
+}
+
+func (s *bookServer) GetBook(ctx context.Context, req *pb.GetBookRequest) (*pb.Book, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	var book models.Book
+	if err := config.ReadReplica(s.db.WithContext(ctx)).Where("id = ?", req.GetId()).First(&book).Error; err != nil {
+		return nil, queryError(err, "book", req.GetId())
+	}
+	return bookToPB(&book), nil
+}
+
+func (s *bookServer) BatchGetBooks(ctx context.Context, req *pb.BatchGetBooksRequest) (*pb.BatchGetBooksResponse, error) {
+	if err := checkIDs(req.GetIds()); err != nil {
+		return nil, err
+	}
+	resp := &pb.BatchGetBooksResponse{}
+	if len(req.GetIds()) == 0 {
+		return resp, nil
+	}
+	var books []models.Book
+	if err := config.ReadReplica(s.db.WithContext(ctx)).Where("id IN ?", req.GetIds()).Find(&books).Error; err != nil {
+		return nil, queryError(err, "books", "")
+	}
+	for i := range books {
+		resp.Books = append(resp.Books, bookToPB(&books[i]))
+	}
+	return resp, nil
+}
+
+func bookToPB(b *models.Book) *pb.Book {
+	var images []string
+	if b.Images != "" {
+		// 图片列表以JSON数组存储，格式不对时按没有图片处理
+		_ = json.Unmarshal([]byte(b.Images), &images)
+	}
+	return &pb.Book{
+		Id:          b.ID,
+		Title:       b.Title,
+		Author:      b.Author,
+		Isbn:        b.ISBN,
+		Category:    b.Category,
+		Price:       b.Price,
+		Description: b.Description,
+		Images:      images,
+		Condition:   b.Condition,
+		SellerId:    b.SellerID,
+		Status:      int32(b.Status),
+		ViewCount:   b.ViewCount,
+		LikeCount:   b.LikeCount,
+		CreatedAt:   timestamppb.New(b.CreatedAt),
+		UpdatedAt:   timestamppb.New(b.UpdatedAt),
+	}
+}
+
+// ==================== 在线状态 ====================
+
+type presenceServer struct {
+	pb.UnimplementedPresenceServiceServer
+	presence PresenceChecker
+}
+
+func (s *presenceServer) GetPresence(ctx context.Context, req *pb.GetPresenceRequest) (*pb.GetPresenceResponse, error) {
+	if err := checkIDs(req.GetUserIds()); err != nil {
+		return nil, err
+	}
+	resp := &pb.GetPresenceResponse{Online: make(map[string]bool, len(req.GetUserIds()))}
+	for _, id := range req.GetUserIds() {
+		resp.Online[id] = s.presence.IsUserOnline(ctx, id)
+	}
+	return resp, nil
+}
@@ -0,0 +1,81 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"weoucbookcycle_go/config"
+	"weoucbookcycle_go/grpcapi/pb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type fakePresence map[string]bool
+
+func (f fakePresence) IsUserOnline(ctx context.Context, userID string) bool {
+	return f[userID]
+}
+
+// dial 在内存连接上启动服务器（不连接数据库，只能调用不查库的接口）
+func dial(t *testing.T) *grpc.ClientConn {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server := NewServer(&config.GRPCConfig{AuthToken: "secret"}, nil, fakePresence{"u1": true})
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestAuth(t *testing.T) {
+	client := pb.NewPresenceServiceClient(dial(t))
+	req := &pb.GetPresenceRequest{UserIds: []string{"u1"}}
+
+	for name, ctx := range map[string]context.Context{
+		"missing": context.Background(),
+		"wrong":   withToken("nope"),
+	} {
+		if _, err := client.GetPresence(ctx, req); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("%s token: got %v, want Unauthenticated", name, err)
+		}
+	}
+	if _, err := client.GetPresence(withToken("secret"), req); err != nil {
+		t.Errorf("valid token: %v", err)
+	}
+}
+
+func TestGetPresence(t *testing.T) {
+	client := pb.NewPresenceServiceClient(dial(t))
+	resp, err := client.GetPresence(withToken("secret"), &pb.GetPresenceRequest{UserIds: []string{"u1", "u2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Online["u1"] || resp.Online["u2"] || len(resp.Online) != 2 {
+		t.Errorf("got %v, want u1 online and u2 offline", resp.Online)
+	}
+}
+
+func TestBatchLimit(t *testing.T) {
+	client := pb.NewUserServiceClient(dial(t))
+	ids := strings.Split(strings.Repeat("x,", maxBatchSize), ",") // maxBatchSize+1 个
+	if _, err := client.BatchGetUsers(withToken("secret"), &pb.BatchGetUsersRequest{Ids: ids}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("got %v, want InvalidArgument", err)
+	}
+}
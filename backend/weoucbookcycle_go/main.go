@@ -1,64 +1,109 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"weoucbookcycle_go/config"
+	"weoucbookcycle_go/controllers"
+	"weoucbookcycle_go/grpcapi"
 	"weoucbookcycle_go/middleware"
 	"weoucbookcycle_go/models"
 	"weoucbookcycle_go/routes"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
 	"weoucbookcycle_go/websocket"
 
-	"github.com/joho/godotenv"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
 )
 
+//go:generate swag init --generalInfo main.go --output docs --outputTypes go,json --parseDependency --parseInternal
+
+// @title WeOUC BookCycle API
+// @version 1.0
+// @description 校园二手书交易平台后端接口。/api 与 /api/v1 相同；/api/v2 只包含有破坏性变更的接口
+// @BasePath /
+// @securityDefinitions.apikey Bearer
+// @in header
+// @name Authorization
+// @description Bearer <access token>
 func main() {
-	// 加载 .env 文件
-	if err := godotenv.Load(); err != nil {
-		log.Println("⚠️  No .env file found, using system environment variables")
-	} else {
-		log.Println("✅ .env file loaded successfully")
+	// 不带子命令时启动服务，备份等维护命令见 cli.go
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(1)
 	}
+}
+
+// runServer 启动API服务和后台任务，收到退出信号后优雅关闭
+func runServer(cfg *config.Config) {
+	var err error
 
-	//设置环境
-	env := os.Getenv("GIN_MODE")
-	if env == "" {
-		os.Setenv("GIN_MODE", "debug")
-	} // 检查是否意外启用了云开发模式
-	if config.GetUseCloud() {
+	// 检查是否意外启用了云开发模式
+	if cfg.UseCloud {
 		log.Println("⚠️  USE_CLOUD=true 已启用，但当前后端只支持自建MySQL，请在 .env 中将其设为 false。")
 	}
 	// 初始化日志系统
-	if err := middleware.InitLogger(env); err != nil {
+	if err := middleware.InitLogger(cfg.Server.Mode); err != nil {
 		log.Fatalf("Failed to initialize logger: %v", err)
 	}
-	defer middleware.FlushLogger()
+	// 接口文案和校验错误的默认语言
+	utils.SetDefaultLang(cfg.DefaultLanguage)
 
-	// 验证必需的环境变量
-	if err := config.ValidateRequiredEnv(); err != nil {
-		log.Fatalf("环境变量验证失败: %v", err)
+	// 初始化错误上报（未配置 SENTRY_DSN 时只写日志）
+	flushErrorReports, err := utils.InitErrorReporting(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize error reporting: %v", err)
+	}
+
+	// 初始化链路追踪（未配置 OTLP 端点时只生成trace ID，不上报）
+	shutdownTracing, err := utils.InitTracing(context.Background(), cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
 	}
 
 	// 初始化数据库
-	if err := config.InitDatabase(); err != nil {
+	db, err := config.InitDatabase(cfg)
+	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
-	defer config.CloseDatabase()
+	if err := utils.InstrumentGORM(db); err != nil {
+		log.Printf("Warning: failed to register database metrics: %v", err)
+	}
+	if err := utils.TraceGORM(db); err != nil {
+		log.Printf("Warning: failed to register database tracing: %v", err)
+	}
 
 	// 验证数据库连接
-	if err := config.ValidateDatabase(config.DB); err != nil {
+	if err := config.ValidateDatabase(db); err != nil {
 		log.Fatalf("数据库连接验证失败: %v", err)
 	}
 
 	// 打印启动信息
-	config.PrintStartupInfo(config.GetServerConfig().Port, os.Getenv("API_BASE"), config.GetUseCloud())
+	config.PrintStartupInfo(cfg)
 
 	// 自动迁移：仅在非生产环境或显式开启时运行（避免生产环境意外修改）
-	enableAuto := os.Getenv("ENABLE_AUTO_MIGRATE")
-	ginMode := os.Getenv("GIN_MODE")
-	if enableAuto == "true" || ginMode != "release" {
-		if err := config.DB.AutoMigrate(&models.User{}, &models.Book{}, &models.Listing{}, &models.Message{}, &models.Chat{}); err != nil {
+	if cfg.AutoMigrate || cfg.Server.Mode != "release" {
+		if err := db.AutoMigrate(&models.User{}, &models.Book{}, &models.Listing{}, &models.Message{}, &models.Chat{},
+			&models.SearchSynonym{}, &models.SavedSearch{}, &models.Notification{},
+			&models.SearchEvent{}, &models.SearchClick{}, &models.UserSettings{},
+			&models.ModerationQueueItem{}, &models.UploadedFile{}, &models.DeviceToken{},
+			&models.WebPushSubscription{}, &models.Report{}, &models.DailyStat{}, &models.SecurityEvent{},
+			&models.SystemSetting{}, &models.ImpersonationSession{}, &models.ImpersonationAuditLog{},
+			&models.Announcement{}, &models.EmailDeadLetter{},
+			&models.Follow{}, &models.FeedItem{}, &models.UserBlock{}, &models.SellerRating{}, &models.SellerReputation{},
+			&models.DataExport{}, &models.Campus{}, &models.DormArea{}, &models.PickupPoint{}, &models.UsernameChange{}, &models.UserIdentity{},
+			&models.UserBadge{}, &models.PointsEntry{}, &models.PointsRedemption{},
+			&models.WebhookSubscription{}, &models.WebhookDelivery{}, &models.WebhookDeliveryAttempt{},
+			&models.ReportEvent{}, &models.ReportLogin{}, &models.ReportAccessLog{},
+			&models.ReferralCode{}, &models.Referral{},
+		); err != nil {
 			log.Printf("Warning: auto migrate failed: %v", err)
 		}
 	} else {
@@ -66,48 +111,194 @@ func main() {
 	}
 
 	// 初始化Redis
-	if err := config.InitializeRedis(); err != nil {
+	redisClient, err := config.InitializeRedis(&cfg.Redis)
+	if err != nil {
 		log.Fatalf("Failed to initialize Redis: %v", err)
 	}
-	defer config.CloseRedis()
+	utils.InstrumentRedis(redisClient)
+	if err := utils.TraceRedis(redisClient); err != nil {
+		log.Printf("Warning: failed to register Redis tracing: %v", err)
+	}
+	utils.ProtectRedis(redisClient, &cfg.Breaker)
+
+	// 连接持久化后台任务队列
+	utils.InitJobs(&cfg.Redis)
 
 	// 初始化对象存储（S3/MinIO等）
-	if err := config.InitializeStorage(); err != nil {
+	if err := config.InitializeStorage(&cfg.Storage); err != nil {
 		log.Fatalf("Failed to initialize object storage: %v", err)
 	}
+	utils.InitStorage(cfg)
+
+	// 收到 SIGINT/SIGTERM 时取消 ctx，后台任务随之停止
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// 创建服务：连接由这里注入，服务在创建时注册后台任务的处理函数，进程内只创建一次
+	svc := services.NewServices(services.Deps{Config: cfg, DB: db, Redis: redisClient})
+
+	// 启动搜索分析事件消费者
+	svc.SearchAnalytics.Start(ctx)
+
+	// 记录用户上传并启用上传配额
+	svc.StorageUsage.StartUploadTracking()
+
+	// 启动图片内容审核（配置了 IMAGE_MODERATION_PROVIDER 时）
+	svc.Moderation.StartImageModeration(ctx)
+
+	// 启动移动端推送（配置了 FCM / APNs 时）
+	svc.Push.Start(ctx)
+
+	// 启动领域事件通知消费者
+	svc.Notification.Start(ctx)
+
+	// 启动安全事件归档
+	svc.SecurityEvent.Start(ctx)
+
+	// 启动定时任务（集群任务由抢到锁的实例执行，见 /api/admin/monitor/cron）
+	svc.Scheduler.Start()
+
+	// 启动徽章规则：根据成交、邮箱验证和信誉分更新事件授予徽章
+	svc.Badge.Start(ctx)
+
+	// 启动积分规则：成交后为买卖双方发放绿色积分
+	svc.Points.Start(ctx)
+
+	// 启动邀请奖励：被邀请人完成首笔交易后为邀请双方发放积分
+	svc.Referral.Start(ctx)
+
+	// 启动 webhook 转发：把注册、新书和成交事件投递给订阅的外部应用
+	svc.Webhook.Start(ctx)
+
+	// 启动报表导入：领域事件、登录和访问日志写入MySQL，导入后裁剪Redis流
+	svc.Reporting.Start(ctx)
+
+	// 处理后台任务（PROCESS_MODE=all/worker）
+	stopWorker := func() {}
+	if cfg.RunsWorker() {
+		if stopWorker, err = utils.StartJobWorker(cfg); err != nil {
+			log.Fatalf("Failed to start job worker: %v", err)
+		}
+		log.Printf("🛠️  Job worker started (concurrency=%d)", cfg.Jobs.Concurrency)
+	}
+
+	// 只处理后台任务的进程不提供HTTP服务
+	if !cfg.RunsAPI() {
+		<-ctx.Done()
+		stop()
+		log.Println("Shutdown signal received, finishing running jobs...")
+		shutdown(nil, nil, time.Duration(cfg.Server.ShutdownTimeout)*time.Second, svc.Scheduler, stopWorker, shutdownTracing, flushErrorReports, db, redisClient)
+		return
+	}
 
 	//初始化websocket
-	if err := websocket.InitWebSocket(); err != nil {
+	if err := websocket.InitWebSocket(ctx, redisClient); err != nil {
 		log.Fatalf("Failed to initialize WebSocket: %v", err)
 	}
-	defer websocket.CloseWebSocket()
+
+	ctrl := controllers.NewControllers(svc, db, redisClient)
+
+	// 启动内部 gRPC 接口（配置了 GRPC_ADDR 时）
+	stopGRPC := func(context.Context) {}
+	if cfg.GRPC.Addr != "" {
+		if stopGRPC, err = grpcapi.Start(&cfg.GRPC, db, svc.Chat); err != nil {
+			log.Fatalf("Failed to start gRPC server: %v", err)
+		}
+		log.Printf("🔌 Internal gRPC API listening on %s", cfg.GRPC.Addr)
+	}
 
 	// 设置路由
-	r := config.SetupRouter()
+	r := config.SetupRouter(cfg, db, redisClient)
 
 	// 注册自定义路由
-	routes.SetupRoutes(r)
+	routes.SetupRoutes(r, ctrl, cfg, db, redisClient)
 
 	// 启动服务器
-	port := config.GetServerConfig().Port
-	addr := ":" + port
+	serverConfig := cfg.Server
+	server := &http.Server{
+		Addr:              ":" + serverConfig.Port,
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
 
 	// If TLS cert/key provided, start HTTPS server
-	certFile := os.Getenv("TLS_CERT_FILE")
-	keyFile := os.Getenv("TLS_KEY_FILE")
+	certFile := serverConfig.TLSCertFile
+	keyFile := serverConfig.TLSKeyFile
 
-	log.Printf("🚀 Server starting on port %s (mode=%s)", port, ginMode)
-	log.Printf("📚 API health: http://localhost:%s/health", port)
+	log.Printf("🚀 Server starting on port %s (mode=%s)", serverConfig.Port, serverConfig.Mode)
+	log.Printf("📚 API health: http://localhost:%s/health", serverConfig.Port)
 
-	if certFile != "" && keyFile != "" {
-		log.Println("Starting HTTPS server with provided TLS certificate")
-		if err := r.RunTLS(addr, certFile, keyFile); err != nil {
-			log.Fatalf("Failed to start HTTPS server: %v", err)
+	serverErr := make(chan error, 1)
+	go func() {
+		var err error
+		if certFile != "" && keyFile != "" {
+			log.Println("Starting HTTPS server with provided TLS certificate")
+			err = server.ListenAndServeTLS(certFile, keyFile)
+		} else {
+			err = server.ListenAndServe()
 		}
-	} else {
-		if err := r.Run(addr); err != nil {
-			log.Fatalf("Failed to start server: %v", err)
+		if !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
 		}
+	}()
+
+	select {
+	case err := <-serverErr:
+		log.Printf("Server stopped unexpectedly: %v", err)
+		stop()
+	case <-ctx.Done():
+		// 恢复默认信号处理，关闭过程中再次 Ctrl+C 可以强制退出
+		stop()
+		log.Println("Shutdown signal received, draining requests...")
+	}
+
+	shutdown(server, stopGRPC, time.Duration(serverConfig.ShutdownTimeout)*time.Second, svc.Scheduler, stopWorker, shutdownTracing, flushErrorReports, db, redisClient)
+}
+
+// shutdown 优雅关闭：停止接收新请求并等待处理中的HTTP请求和gRPC调用完成，关闭WebSocket，
+// 等待进程内队列处理完积压任务，停止定时任务和后台任务worker（未完成的任务回到队列），
+// 写完访问日志，导出剩余span并发送未上报的错误，最后关闭数据库和Redis
+// 只处理后台任务的进程 server 和 stopGRPC 为 nil
+func shutdown(server *http.Server, stopGRPC func(context.Context), timeout time.Duration, scheduler *services.Scheduler, stopWorker func(), shutdownTracing func(context.Context) error, flushErrorReports func(context.Context), db *gorm.DB, redisClient *redis.Client) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if server != nil {
+		// WebSocket连接已被劫持，Shutdown不会等待它们，需要单独关闭
+		server.RegisterOnShutdown(websocket.CloseWebSocket)
+		if err := server.Shutdown(ctx); err != nil {
+			log.Printf("HTTP server shutdown: %v", err)
+		}
+	}
+	if stopGRPC != nil {
+		stopGRPC(ctx)
+	}
+
+	if pending := utils.WaitQueuesDrained(ctx); len(pending) > 0 {
+		for _, q := range pending {
+			log.Printf("Queue %s still has %d pending tasks at shutdown", q.Name, q.Depth)
+		}
+	}
+
+	scheduler.Stop(ctx)
+	stopWorker()
+	if err := utils.CloseJobs(); err != nil {
+		log.Printf("Failed to close job queue: %v", err)
+	}
+
+	middleware.CloseLogger(ctx)
+
+	if err := shutdownTracing(ctx); err != nil {
+		log.Printf("Failed to flush traces: %v", err)
+	}
+	flushErrorReports(ctx)
+
+	if err := config.CloseRedis(redisClient); err != nil {
+		log.Printf("Failed to close Redis: %v", err)
+	}
+	if err := config.CloseDatabase(db); err != nil {
+		log.Printf("Failed to close database: %v", err)
 	}
 
+	log.Println("Server exited")
 }
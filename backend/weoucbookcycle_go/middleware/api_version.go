@@ -0,0 +1,12 @@
+package middleware
+
+import "github.com/gin-gonic/gin"
+
+// APIVersion 记录请求命中的API版本，写入上下文并在 API-Version 响应头中返回
+func APIVersion(version string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("api_version", version)
+		c.Header("API-Version", version)
+		c.Next()
+	}
+}
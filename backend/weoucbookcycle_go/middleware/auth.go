@@ -3,17 +3,22 @@ package middleware
 import (
 	"net/http"
 	"strings"
+	"time"
 	"weoucbookcycle_go/config"
+	"weoucbookcycle_go/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
 )
 
-// AuthMiddleware JWT认证中间件
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware JWT认证中间件，redisClient 用于封禁检查和日活统计，db 在没有Redis时校验代登录会话
+func AuthMiddleware(cfg *config.JWTConfig, db *gorm.DB, redisClient *redis.Client) gin.HandlerFunc {
+	jwtService := config.NewJWTService(cfg)
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+			c.Error(utils.NewError(http.StatusUnauthorized, "Authorization header required"))
 			c.Abort()
 			return
 		}
@@ -21,25 +26,114 @@ func AuthMiddleware() gin.HandlerFunc {
 		// 提取token
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 		if tokenString == authHeader {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
+			c.Error(utils.NewError(http.StatusUnauthorized, "Invalid authorization header format"))
 			c.Abort()
 			return
 		}
 
 		// 验证token
-		claims, err := config.GetJWTService().ValidateToken(tokenString)
+		claims, err := jwtService.ValidateToken(tokenString)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Error(utils.NewError(http.StatusUnauthorized, "Invalid token"))
 			c.Abort()
 			return
 		}
 
+		// 被管理员封禁的账号立即失效，无需等待token过期
+		if redisClient != nil {
+			if disabled, _ := redisClient.Exists(c.Request.Context(), "user:disabled:"+claims.UserID).Result(); disabled > 0 {
+				c.Error(utils.NewError(http.StatusForbidden, "Account is disabled"))
+				c.Abort()
+				return
+			}
+		}
+
+		// 管理员代登录：校验会话和权限范围
+		if claims.ImpersonatorID != "" {
+			if status, msg := checkImpersonation(c, db, redisClient, claims); status != 0 {
+				c.Error(utils.NewError(status, msg))
+				c.Abort()
+				return
+			}
+		}
+
+		// 记录日活（HyperLogLog，由 services.StatsService 汇总到 daily_stats），代登录不计入
+		if redisClient != nil && claims.ImpersonatorID == "" {
+			activeKey := "stats:active:" + time.Now().Format("2006-01-02")
+			pipe := redisClient.Pipeline()
+			pipe.PFAdd(c.Request.Context(), activeKey, claims.UserID)
+			pipe.Expire(c.Request.Context(), activeKey, 40*24*time.Hour)
+			pipe.Exec(c.Request.Context())
+		}
+
 		// 将用户信息存入context
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("email", claims.Email)
 		c.Set("roles", claims.Roles)
 
+		if claims.ImpersonatorID != "" {
+			auditImpersonation(c, db, claims)
+			return
+		}
+
 		c.Next()
 	}
 }
+
+// OptionalAuthMiddleware 可选认证中间件
+// 携带有效token时写入用户信息，未登录或token无效时直接放行
+func OptionalAuthMiddleware(cfg *config.JWTConfig, db *gorm.DB, redisClient *redis.Client) gin.HandlerFunc {
+	jwtService := config.NewJWTService(cfg)
+	return func(c *gin.Context) {
+		tokenString := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if tokenString != "" {
+			claims, err := jwtService.ValidateToken(tokenString)
+			if err == nil && claims.ImpersonatorID != "" {
+				// 代登录会话已结束时按未登录处理
+				if status, _ := checkImpersonation(c, db, redisClient, claims); status != 0 {
+					claims = nil
+				}
+			}
+			if err == nil && claims != nil {
+				c.Set("user_id", claims.UserID)
+				c.Set("username", claims.Username)
+				c.Set("email", claims.Email)
+				c.Set("roles", claims.Roles)
+
+				if claims.ImpersonatorID != "" {
+					auditImpersonation(c, db, claims)
+					return
+				}
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// RequireRole 角色校验中间件，需在 AuthMiddleware 之后使用
+// 当前用户拥有任一指定角色即放行
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := c.Get("roles")
+		userRoles, ok := value.([]string)
+		if !exists || !ok {
+			c.Error(utils.NewError(http.StatusForbidden, "Insufficient permissions"))
+			c.Abort()
+			return
+		}
+
+		for _, required := range roles {
+			for _, role := range userRoles {
+				if role == required {
+					c.Next()
+					return
+				}
+			}
+		}
+
+		c.Error(utils.NewError(http.StatusForbidden, "Insufficient permissions"))
+		c.Abort()
+	}
+}
@@ -26,8 +26,8 @@ func GetDefaultCORSConfig() *CORSConfig {
 			"http://localhost:4173",
 		},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-Requested-With"},
-		ExposeHeaders:    []string{"Content-Length", "Content-Type"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-Requested-With", IdempotencyKeyHeader},
+		ExposeHeaders:    []string{"Content-Length", "Content-Type", "Idempotent-Replayed", "API-Version"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}
@@ -38,8 +38,8 @@ func GetProductionCORSConfig(allowedOrigins []string) *CORSConfig {
 	return &CORSConfig{
 		AllowOrigins:     allowedOrigins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", IdempotencyKeyHeader},
+		ExposeHeaders:    []string{"Content-Length", "Idempotent-Replayed", "API-Version"},
 		AllowCredentials: true,
 		MaxAge:           24 * time.Hour,
 	}
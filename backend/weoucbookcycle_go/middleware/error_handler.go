@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ErrorHandler 把处理器通过 c.Error 上报的错误统一转换为 utils.Response
+// utils.AppError 使用其中的状态码、消息和附加数据；utils.ValidationError 返回422并在 data.errors 中给出字段错误；
+// 记录不存在映射为404；utils.RateLimitError 返回429并在 data.retry_after 中给出等待秒数；请求超时（context.DeadlineExceeded）为408，请求体超过上限为413；其他错误为500；消息按请求语言翻译，
+// release 模式下不向客户端暴露内部错误信息
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		lang := utils.RequestLang(c)
+		status, code, message := http.StatusInternalServerError, utils.CodeInternalServerError, err.Error()
+		var data interface{}
+
+		var appErr *utils.AppError
+		var validationErr *utils.ValidationError
+		var maxBytesErr *http.MaxBytesError
+		var rateLimitErr *utils.RateLimitError
+		switch {
+		// 超时和请求体过大可能被包装成其他状态码的错误，优先判断
+		case errors.Is(err, context.DeadlineExceeded):
+			status, code, message = http.StatusRequestTimeout, utils.CodeRequestTimeout, utils.CodeMessageIn(lang, utils.CodeRequestTimeout)
+		case errors.As(err, &maxBytesErr):
+			status, code, message = http.StatusRequestEntityTooLarge, utils.CodeRequestTooLarge, utils.CodeMessageIn(lang, utils.CodeRequestTooLarge)
+		case errors.As(err, &rateLimitErr):
+			status, code, message = http.StatusTooManyRequests, utils.CodeTooManyRequests, utils.CodeMessageIn(lang, utils.CodeTooManyRequests)
+			data = gin.H{"retry_after": rateLimitErr.RetryAfterSeconds()}
+		case errors.As(err, &appErr):
+			status, code, data = appErr.Status, appErr.Code, appErr.Data
+		case errors.As(err, &validationErr):
+			status, code, message = http.StatusUnprocessableEntity, utils.CodeValidationError, utils.CodeMessageIn(lang, utils.CodeValidationError)
+			data = validationErr.Localize(lang)
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			status, code, message = http.StatusNotFound, utils.CodeNotFound, utils.CodeMessageIn(lang, utils.CodeNotFound)
+		}
+
+		if status >= http.StatusInternalServerError {
+			span := trace.SpanFromContext(c.Request.Context())
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			ErrorLogger("request failed", zap.String("path", c.FullPath()), zap.Int("status", status), zap.Error(err))
+			if appErr == nil && gin.Mode() == gin.ReleaseMode {
+				message = utils.CodeMessageIn(lang, code)
+			}
+		}
+
+		c.JSON(status, utils.Response{Code: code, Message: utils.TranslateMessage(lang, message), Data: data})
+	}
+}
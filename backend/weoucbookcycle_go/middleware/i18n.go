@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// I18n 根据 Accept-Language 选择响应语言（zh-CN/en），写入 gin 上下文和请求 context，
+// 错误信息、验证错误以及请求中触发的邮件都使用该语言
+func I18n() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lang := utils.ParseAcceptLanguage(c.GetHeader("Accept-Language"))
+		c.Set("lang", lang)
+		c.Request = c.Request.WithContext(utils.WithLang(c.Request.Context(), lang))
+		c.Header("Content-Language", lang)
+		c.Header("Vary", "Accept-Language")
+
+		c.Next()
+	}
+}
@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// IdempotencyKeyHeader 客户端为每个创建操作生成的唯一键（如UUID），重试时保持不变
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// idempotencyTTL 首次响应的保存时间
+	idempotencyTTL = 24 * time.Hour
+	// idempotencyLockTTL 处理中标记的过期时间，防止请求异常退出后键被永久占用
+	idempotencyLockTTL   = 30 * time.Second
+	maxIdempotencyKeyLen = 255
+)
+
+// idempotentResponse Redis中保存的首次响应
+type idempotentResponse struct {
+	Fingerprint string `json:"fingerprint"`
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
+
+// responseRecorder 在写出响应的同时保留一份响应体
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency 支持 Idempotency-Key 请求头的POST接口
+// 同一用户用同一个键重试时直接返回首次的成功响应（带 Idempotent-Replayed: true），不会重复创建；
+// 首次请求仍在处理时返回409，同一个键用于不同的请求体时返回422。
+// 只保存2xx响应，失败的请求可以用同一个键重试。需要注册在 AuthMiddleware 之后
+func Idempotency(redisClient *redis.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" || c.Request.Method != http.MethodPost || redisClient == nil {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			c.Error(utils.NewError(http.StatusBadRequest, "Idempotency-Key is too long"))
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.Error(utils.NewError(http.StatusBadRequest, "Failed to read request body"))
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		fingerprint := requestFingerprint(c.Request.Method, c.Request.URL.Path, body)
+		redisKey := "idempotency:" + c.GetString("user_id") + ":" + key
+
+		if replaySavedResponse(c, redisClient, redisKey, fingerprint) {
+			return
+		}
+
+		lockKey := redisKey + ":lock"
+		locked, err := redisClient.SetNX(ctx, lockKey, 1, idempotencyLockTTL).Result()
+		if err != nil {
+			// Redis不可用时不阻塞请求
+			c.Next()
+			return
+		}
+		if !locked {
+			c.Error(utils.NewError(http.StatusConflict, "A request with this Idempotency-Key is still being processed"))
+			c.Abort()
+			return
+		}
+		defer redisClient.Del(context.WithoutCancel(ctx), lockKey)
+
+		// 首次请求可能在上面的查询之后、加锁之前完成并释放了锁，加锁后再查一次
+		if replaySavedResponse(c, redisClient, redisKey, fingerprint) {
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// 通过 c.Error 上报的错误此时尚未由 ErrorHandler 写出，不缓存
+		status := recorder.Status()
+		if len(c.Errors) > 0 || !recorder.Written() || status < 200 || status >= 300 {
+			return
+		}
+		data, _ := json.Marshal(idempotentResponse{
+			Fingerprint: fingerprint,
+			Status:      status,
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		})
+		redisClient.Set(context.WithoutCancel(ctx), redisKey, data, idempotencyTTL)
+	}
+}
+
+// replaySavedResponse 键已有保存的响应时直接返回它（请求体不同时返回422）并中止请求，返回是否已处理
+func replaySavedResponse(c *gin.Context, redisClient *redis.Client, redisKey, fingerprint string) bool {
+	data, err := redisClient.Get(c.Request.Context(), redisKey).Bytes()
+	if err != nil {
+		return false
+	}
+	var saved idempotentResponse
+	if json.Unmarshal(data, &saved) != nil {
+		return false
+	}
+	if saved.Fingerprint != fingerprint {
+		c.Error(utils.NewError(http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request"))
+		c.Abort()
+		return true
+	}
+	c.Header("Idempotent-Replayed", "true")
+	c.Data(saved.Status, saved.ContentType, saved.Body)
+	c.Abort()
+	return true
+}
+
+// requestFingerprint 请求方法、路径和请求体的摘要，用于识别键被误用于其他请求
+func requestFingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// beforeLockHook 在第一次加锁（SET NX）执行前调用 run，用来构造两个请求交错执行的时序
+// run 中的请求自己加锁时不再触发
+type beforeLockHook struct {
+	fired atomic.Bool
+	run   func()
+}
+
+func (h *beforeLockHook) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h *beforeLockHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if args := cmd.Args(); cmd.Name() == "set" && args[len(args)-1] == "nx" {
+			if h.fired.CompareAndSwap(false, true) {
+				h.run()
+			}
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h *beforeLockHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func newIdempotencyRouter(t *testing.T, client *redis.Client, handler gin.HandlerFunc) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ErrorHandler(), func(c *gin.Context) {
+		c.Set("user_id", "u1")
+		c.Next()
+	}, Idempotency(client))
+	r.POST("/books", handler)
+	return r
+}
+
+func postBook(r http.Handler, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/books", strings.NewReader(`{"title":"Go"}`))
+	req.Header.Set(IdempotencyKeyHeader, key)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// 第二个请求查询保存的响应时首次请求尚未完成，加锁时首次请求已经完成并释放了锁：
+// 第二个请求应返回首次的响应，不能再次执行处理器
+func TestIdempotencyConcurrentRequestFinishedBeforeLock(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	var created atomic.Int64
+	r := newIdempotencyRouter(t, client, func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"id": created.Add(1)})
+	})
+
+	var first *httptest.ResponseRecorder
+	client.AddHook(&beforeLockHook{run: func() { first = postBook(r, "k1") }})
+	second := postBook(r, "k1")
+
+	if n := created.Load(); n != 1 {
+		t.Fatalf("handler ran %d times, want 1", n)
+	}
+	if first.Code != http.StatusCreated || first.Body.String() != `{"id":1}` {
+		t.Errorf("first: status = %d, body = %s", first.Code, first.Body.String())
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != `{"id":1}` || second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("second: status = %d, body = %s, replayed = %q", second.Code, second.Body.String(), second.Header().Get("Idempotent-Replayed"))
+	}
+}
+
+// 首次请求仍在处理时，同一个键的第二个请求返回409
+func TestIdempotencyConcurrentRequestInFlight(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	entered, release := make(chan struct{}), make(chan struct{})
+	r := newIdempotencyRouter(t, client, func(c *gin.Context) {
+		close(entered)
+		<-release
+		c.JSON(http.StatusCreated, gin.H{"id": 1})
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- postBook(r, "k1") }()
+	<-entered
+
+	if w := postBook(r, "k1"); w.Code != http.StatusConflict {
+		t.Errorf("in-flight: status = %d, want 409", w.Code)
+	}
+	close(release)
+	if w := <-done; w.Code != http.StatusCreated {
+		t.Errorf("first: status = %d, want 201", w.Code)
+	}
+}
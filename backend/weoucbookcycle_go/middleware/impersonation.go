@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"weoucbookcycle_go/config"
+	"weoucbookcycle_go/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// impersonationSessionKey 有效的代登录会话，结束代登录时删除（与 services.ImpersonationService 一致）
+const impersonationSessionKey = "impersonation:session:"
+
+// checkImpersonation 校验代登录token：会话未结束，且只读范围只允许只读请求
+// 校验失败时返回状态码和错误信息
+func checkImpersonation(c *gin.Context, db *gorm.DB, redisClient *redis.Client, claims *config.Claims) (int, string) {
+	active := false
+	if redisClient != nil {
+		exists, err := redisClient.Exists(c.Request.Context(), impersonationSessionKey+claims.ID).Result()
+		active = err == nil && exists > 0
+	} else if db != nil {
+		var count int64
+		db.WithContext(c.Request.Context()).Model(&models.ImpersonationSession{}).
+			Where("id = ? AND ended_at IS NULL", claims.ID).Count(&count)
+		active = count > 0
+	}
+	if !active {
+		return http.StatusUnauthorized, "Impersonation session has ended"
+	}
+
+	if claims.Scope != models.ImpersonationScopeWrite {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			return http.StatusForbidden, "Impersonation session is read-only"
+		}
+	}
+
+	return 0, ""
+}
+
+// auditImpersonation 处理请求并记录代登录期间的访问
+func auditImpersonation(c *gin.Context, db *gorm.DB, claims *config.Claims) {
+	c.Set("impersonator_id", claims.ImpersonatorID)
+	c.Set("impersonation_id", claims.ID)
+	c.Header("X-Impersonated-By", claims.ImpersonatorID)
+
+	c.Next()
+
+	entry := &models.ImpersonationAuditLog{
+		SessionID:  claims.ID,
+		AdminID:    claims.ImpersonatorID,
+		UserID:     claims.UserID,
+		Method:     c.Request.Method,
+		Path:       c.Request.URL.Path,
+		Query:      c.Request.URL.RawQuery,
+		StatusCode: c.Writer.Status(),
+		IP:         c.ClientIP(),
+		RequestID:  c.GetString("request_id"),
+	}
+	if len(entry.Query) > 500 {
+		entry.Query = entry.Query[:500]
+	}
+
+	go func() {
+		if db == nil {
+			return
+		}
+		if err := db.Create(entry).Error; err != nil {
+			log.Printf("impersonation audit: failed to record %s %s: %v", entry.Method, entry.Path, err)
+		}
+	}()
+}
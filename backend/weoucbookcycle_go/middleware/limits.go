@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestLimits 为路由组设置请求超时和请求体大小上限，同一个请求只应经过一次
+// 超时写入请求的context，处理器把 c.Request.Context() 传给 GORM/Redis 后，超时的查询会被取消；
+// 超时后还没有写出响应的请求返回408。请求体超过上限返回413：Content-Length 超限时直接拒绝，
+// 分块传输的请求在读取超过上限时报错
+func RequestLimits(timeout time.Duration, maxBodyBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > maxBodyBytes {
+			c.Error(&http.MaxBytesError{Limit: maxBodyBytes})
+			c.Abort()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBodyBytes)
+
+		c.Next()
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !c.Writer.Written() {
+			c.Error(ctx.Err())
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 超时后未写出响应返回408，请求体超过上限返回413（无论是否带 Content-Length）
+func TestRequestLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ErrorHandler(), RequestLimits(20*time.Millisecond, 16))
+	r.GET("/slow", func(c *gin.Context) {
+		<-c.Request.Context().Done()
+		c.Error(c.Request.Context().Err())
+	})
+	r.POST("/echo", func(c *gin.Context) {
+		var body map[string]string
+		if err := utils.BindAndValidate(c, &body); err != nil {
+			c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, body)
+	})
+
+	cases := []struct {
+		name   string
+		req    func() *http.Request
+		status int
+	}{
+		{"timeout", func() *http.Request { return httptest.NewRequest(http.MethodGet, "/slow", nil) }, http.StatusRequestTimeout},
+		{"small body", func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(`{"a":"b"}`))
+		}, http.StatusOK},
+		{"content length", func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(`{"a":"`+strings.Repeat("x", 32)+`"}`))
+		}, http.StatusRequestEntityTooLarge},
+		{"chunked", func() *http.Request {
+			req := httptest.NewRequest(http.MethodPost, "/echo", io.NopCloser(strings.NewReader(`{"a":"`+strings.Repeat("x", 32)+`"}`)))
+			req.ContentLength = -1
+			return req
+		}, http.StatusRequestEntityTooLarge},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, tc.req())
+		if w.Code != tc.status {
+			t.Errorf("%s: status = %d, want %d (%s)", tc.name, w.Code, tc.status, w.Body.String())
+		}
+	}
+}
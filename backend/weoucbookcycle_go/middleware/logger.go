@@ -4,10 +4,16 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
 	"time"
 	"weoucbookcycle_go/config"
+	"weoucbookcycle_go/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
@@ -18,23 +24,48 @@ import (
 var (
 	logger           *zap.Logger
 	accessLogChannel chan *AccessLog
+	// accessLogWorkers 关闭时等待队列中的日志写完
+	accessLogWorkers sync.WaitGroup
+	// accessLogMu 保护 accessLogClosed，避免关闭channel后仍有请求写入
+	accessLogMu     sync.RWMutex
+	accessLogClosed bool
 )
 
 // AccessLog 访问日志结构
 type AccessLog struct {
-	Time       time.Time `json:"time"`
-	Method     string    `json:"method"`
-	Path       string    `json:"path"`
-	Query      string    `json:"query,omitempty"`
-	IP         string    `json:"ip"`
-	UserAgent  string    `json:"user_agent,omitempty"`
-	StatusCode int       `json:"status_code"`
-	Latency    int64     `json:"latency_ms"`
-	UserID     string    `json:"user_id,omitempty"`
-	RequestID  string    `json:"request_id,omitempty"`
-	Error      string    `json:"error,omitempty"`
+	Time           time.Time `json:"time"`
+	Method         string    `json:"method"`
+	Path           string    `json:"path"`
+	Query          string    `json:"query,omitempty"`
+	IP             string    `json:"ip"`
+	UserAgent      string    `json:"user_agent,omitempty"`
+	StatusCode     int       `json:"status_code"`
+	Latency        int64     `json:"latency_ms"`
+	UserID         string    `json:"user_id,omitempty"`
+	ImpersonatorID string    `json:"impersonator_id,omitempty"` // 管理员代登录时为管理员ID
+	RequestID      string    `json:"request_id,omitempty"`
+	TraceID        string    `json:"trace_id,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	// Route 路由模板（如 /api/v1/books/:id），未匹配路由时为空
+	Route string `json:"route,omitempty"`
+	// RequestBody 脱敏后的请求体，只在 debug 模式开启 LOG_CAPTURE_BODY 时记录
+	RequestBody string `json:"request_body,omitempty"`
+	// SampleRate 小于1时表示该路由按比例采样，只有被采样的请求写入结构化日志
+	SampleRate float64 `json:"sample_rate,omitempty"`
+
+	// sampledOut 未被采样：仍写入 access_logs 流（访问统计需要完整数据），不写结构化日志
+	sampledOut bool
+	// redisClient 写入 access_logs 流，为nil时只写结构化日志
+	redisClient *redis.Client
 }
 
+const (
+	// maxLoggedBodyBytes 访问日志中记录的请求体上限，超过时不记录（截断的JSON无法可靠脱敏）
+	maxLoggedBodyBytes = 4 << 10
+	// slowRequestThreshold 超过该耗时的请求不参与采样，总是记录
+	slowRequestThreshold = time.Second
+)
+
 // InitLogger 初始化日志系统
 func InitLogger(mode string) error {
 	var err error
@@ -58,6 +89,7 @@ func InitLogger(mode string) error {
 
 	// 启动日志处理worker池
 	accessLogChannel = make(chan *AccessLog, 1000)
+	utils.RegisterQueue("access_log", accessLogChannel)
 	go startLogWorkers()
 
 	return nil
@@ -69,7 +101,9 @@ func startLogWorkers() {
 	workerCount := 3 // 3个worker并发处理日志
 
 	for i := 0; i < workerCount; i++ {
+		accessLogWorkers.Add(1)
 		go func(workerID int) {
+			defer accessLogWorkers.Done()
 			for accessLog := range accessLogChannel {
 				processAccessLog(workerID, accessLog)
 			}
@@ -80,45 +114,62 @@ func startLogWorkers() {
 // processAccessLog 处理单条访问日志
 func (al *AccessLog) processAccessLog() {
 	// 使用zap记录结构化日志
-	logger.Info("access_log",
+	if !al.sampledOut {
+		al.writeLog()
+	}
+
+	// 将日志写入Redis（用于日志分析和监控）
+	// 已在worker中异步执行，同步写入保证关闭时队列中的日志不会丢失
+	if al.redisClient != nil {
+		ctx := context.Background()
+		logData, _ := json.Marshal(al)
+
+		// 使用Redis Stream存储日志（适合实时分析）
+		al.redisClient.XAdd(ctx, &redis.XAddArgs{
+			Stream: "access_logs",
+			Values: map[string]interface{}{
+				"timestamp":   al.Time.Unix(),
+				"method":      al.Method,
+				"path":        al.Path,
+				"status_code": al.StatusCode,
+				"latency_ms":  al.Latency,
+				"ip":          al.IP,
+				"user_id":     al.UserID,
+				"trace_id":    al.TraceID,
+				"full_data":   string(logData),
+			},
+		})
+
+		// 设置Stream最大长度（保留最近7天的日志）
+		al.redisClient.XTrimMaxLen(ctx, "access_logs", 100000)
+	}
+}
+
+// writeLog 写入结构化日志，查询参数和请求体在构建日志时已脱敏
+func (al *AccessLog) writeLog() {
+	fields := []zap.Field{
 		zap.String("time", al.Time.Format(time.RFC3339)),
 		zap.String("method", al.Method),
 		zap.String("path", al.Path),
+		zap.String("route", al.Route),
 		zap.String("query", al.Query),
 		zap.String("ip", al.IP),
 		zap.String("user_agent", al.UserAgent),
 		zap.Int("status_code", al.StatusCode),
 		zap.Int64("latency_ms", al.Latency),
 		zap.String("user_id", al.UserID),
+		zap.String("impersonator_id", al.ImpersonatorID),
 		zap.String("request_id", al.RequestID),
+		zap.String("trace_id", al.TraceID),
 		zap.String("error", al.Error),
-	)
-
-	// 异步将日志写入Redis（用于日志分析和监控）
-	go func() {
-		if config.RedisClient != nil {
-			ctx := context.Background()
-			logData, _ := json.Marshal(al)
-
-			// 使用Redis Stream存储日志（适合实时分析）
-			config.RedisClient.XAdd(ctx, &redis.XAddArgs{
-				Stream: "access_logs",
-				Values: map[string]interface{}{
-					"timestamp":   al.Time.Unix(),
-					"method":      al.Method,
-					"path":        al.Path,
-					"status_code": al.StatusCode,
-					"latency_ms":  al.Latency,
-					"ip":          al.IP,
-					"user_id":     al.UserID,
-					"full_data":   string(logData),
-				},
-			})
-
-			// 设置Stream最大长度（保留最近7天的日志）
-			config.RedisClient.XTrimMaxLen(ctx, "access_logs", 100000)
-		}
-	}()
+	}
+	if al.RequestBody != "" {
+		fields = append(fields, zap.String("request_body", al.RequestBody))
+	}
+	if al.SampleRate > 0 {
+		fields = append(fields, zap.Float64("sample_rate", al.SampleRate))
+	}
+	logger.Info("access_log", fields...)
 }
 
 // processAccessLog 处理单条访问日志（独立函数）
@@ -127,11 +178,24 @@ func processAccessLog(workerID int, accessLog *AccessLog) {
 }
 
 // Logger 返回日志中间件
-func Logger() gin.HandlerFunc {
+// 查询参数和请求体中的密码、token等字段按脱敏规则替换；LOG_SAMPLE_RULES 中的高频路由按比例写入结构化日志，
+// 错误响应和慢请求总是记录；请求体只在 debug 模式且开启 LOG_CAPTURE_BODY 时记录
+// 每条日志同时写入 redisClient 的 access_logs 流（供访问统计使用），redisClient 为nil时不写
+func Logger(cfg *config.Config, redisClient *redis.Client) gin.HandlerFunc {
+	redact := newRedactor(cfg.Log.RedactFields)
+	// 配置在启动时已校验
+	sampleRates, _ := cfg.Log.SampleRates()
+	captureBody := cfg.Log.CaptureBody && cfg.Server.Mode == gin.DebugMode
+
 	return func(c *gin.Context) {
 		// 记录开始时间
 		start := time.Now()
 
+		var requestBody string
+		if captureBody {
+			requestBody = captureRequestBody(c, redact)
+		}
+
 		// 生成请求ID
 		requestID := c.GetHeader("X-Request-ID")
 		if requestID == "" {
@@ -139,12 +203,10 @@ func Logger() gin.HandlerFunc {
 		}
 		c.Set("request_id", requestID)
 
-		// 读取请求体（用于记录POST/PUT请求）
-		var requestBody []byte
-		if c.Request.Body != nil {
-			requestBody, _ = io.ReadAll(c.Request.Body)
-			// 重新设置请求体，让后续处理器可以读取
-			c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
+		// otelgin 已为请求创建span，在响应头中返回trace ID，便于按trace检索慢请求
+		traceID := utils.TraceID(c.Request.Context())
+		if traceID != "" {
+			c.Header("X-Trace-ID", traceID)
 		}
 
 		// 处理请求
@@ -155,16 +217,21 @@ func Logger() gin.HandlerFunc {
 
 		// 构建访问日志
 		accessLog := &AccessLog{
-			Time:       start,
-			Method:     c.Request.Method,
-			Path:       c.Request.URL.Path,
-			Query:      c.Request.URL.RawQuery,
-			IP:         c.ClientIP(),
-			UserAgent:  c.Request.UserAgent(),
-			StatusCode: c.Writer.Status(),
-			Latency:    duration.Milliseconds(),
-			UserID:     c.GetString("user_id"),
-			RequestID:  requestID,
+			Time:           start,
+			Method:         c.Request.Method,
+			Path:           c.Request.URL.Path,
+			Query:          redact.Query(c.Request.URL.RawQuery),
+			IP:             c.ClientIP(),
+			UserAgent:      c.Request.UserAgent(),
+			StatusCode:     c.Writer.Status(),
+			Latency:        duration.Milliseconds(),
+			UserID:         c.GetString("user_id"),
+			ImpersonatorID: c.GetString("impersonator_id"),
+			RequestID:      requestID,
+			TraceID:        traceID,
+			Route:          c.FullPath(),
+			RequestBody:    requestBody,
+			redisClient:    redisClient,
 		}
 
 		// 如果有错误，记录错误信息
@@ -172,19 +239,61 @@ func Logger() gin.HandlerFunc {
 			accessLog.Error = c.Errors.String()
 		}
 
-		// 将日志放入队列（异步处理）
-		select {
-		case accessLogChannel <- accessLog:
-		default:
-			// 队列满，直接丢弃（保证请求不被阻塞）
-			log.Printf("Log channel is full, dropping log: %s %s", accessLog.Method, accessLog.Path)
+		// 高频路由采样，错误和慢请求总是记录
+		if rate, ok := sampleRates[unversionedRoute(accessLog.Route)]; ok && rate < 1 {
+			accessLog.SampleRate = rate
+			accessLog.sampledOut = accessLog.StatusCode < http.StatusBadRequest && duration < slowRequestThreshold &&
+				rand.Float64() >= rate
 		}
 
+		// 将日志放入队列（异步处理）
+		enqueueAccessLog(accessLog)
+
 		// 在响应头中添加请求ID
 		c.Header("X-Request-ID", requestID)
 	}
 }
 
+// captureRequestBody 读取并还原请求体，返回脱敏后的内容
+// 只记录JSON和表单，文件上传等其他类型和超过 maxLoggedBodyBytes 的请求体只记录大小
+func captureRequestBody(c *gin.Context, redact *redactor) string {
+	if c.Request.Body == nil || c.Request.ContentLength == 0 {
+		return ""
+	}
+	contentType := c.ContentType()
+	if contentType != gin.MIMEJSON && contentType != gin.MIMEPOSTForm {
+		return fmt.Sprintf("[%s, %d bytes]", contentType, c.Request.ContentLength)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxLoggedBodyBytes+1))
+	c.Request.Body = readCloser{io.MultiReader(bytes.NewReader(data), c.Request.Body), c.Request.Body}
+	if err != nil || len(data) > maxLoggedBodyBytes {
+		return fmt.Sprintf("[body larger than %d bytes]", maxLoggedBodyBytes)
+	}
+	if contentType == gin.MIMEPOSTForm {
+		return redact.Query(string(data))
+	}
+	return redact.JSON(data)
+}
+
+// readCloser 已读出部分内容的请求体，关闭时关闭原请求体
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// unversionedRoute 去掉路由模板中的版本号（/api/v1/books -> /api/books），采样规则对所有版本生效
+func unversionedRoute(route string) string {
+	rest, ok := strings.CutPrefix(route, "/api/v")
+	if !ok || rest == "" || rest[0] < '0' || rest[0] > '9' {
+		return route
+	}
+	if i := strings.IndexByte(rest, '/'); i >= 0 {
+		return "/api" + rest[i:]
+	}
+	return "/api"
+}
+
 // generateRequestID 生成请求ID
 func generateRequestID() string {
 	return time.Now().Format("20060102150405") + "-" + randomString(8)
@@ -221,6 +330,50 @@ func DebugLogger(msg string, fields ...zap.Field) {
 	}
 }
 
+// enqueueAccessLog 将日志放入队列，日志系统已关闭时只写zap
+func enqueueAccessLog(accessLog *AccessLog) {
+	accessLogMu.RLock()
+	defer accessLogMu.RUnlock()
+
+	if accessLogClosed || accessLogChannel == nil {
+		if logger != nil {
+			logger.Info("access_log", zap.String("method", accessLog.Method), zap.String("path", accessLog.Path),
+				zap.Int("status_code", accessLog.StatusCode), zap.Int64("latency_ms", accessLog.Latency))
+		}
+		return
+	}
+
+	select {
+	case accessLogChannel <- accessLog:
+	default:
+		// 队列满，直接丢弃（保证请求不被阻塞）
+		log.Printf("Log channel is full, dropping log: %s %s", accessLog.Method, accessLog.Path)
+	}
+}
+
+// CloseLogger 停止接收访问日志，等待队列中的日志写完（或ctx超时）后刷新zap缓冲
+func CloseLogger(ctx context.Context) {
+	accessLogMu.Lock()
+	if !accessLogClosed && accessLogChannel != nil {
+		accessLogClosed = true
+		close(accessLogChannel)
+	}
+	accessLogMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		accessLogWorkers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Printf("Timed out waiting for %d access logs to be written", len(accessLogChannel))
+	}
+
+	FlushLogger()
+}
+
 // FlushLogger 刷新日志缓冲区
 func FlushLogger() {
 	if logger != nil {
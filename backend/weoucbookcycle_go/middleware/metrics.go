@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"time"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics 记录请求数、耗时和处理中的请求数
+// 使用路由模板（如 /api/books/:id）作为标签，未匹配的路由统一记为 unmatched，避免标签数量失控
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		utils.HTTPRequestsInFlight.Inc()
+		defer utils.HTTPRequestsInFlight.Dec()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := c.Request.Method
+		utils.HTTPRequestsTotal.WithLabelValues(method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		utils.HTTPRequestDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// MetricsHandler Prometheus 抓取端点
+// token 非空（配置了 METRICS_TOKEN）时要求 Authorization: Bearer <token>
+func MetricsHandler(token string) gin.HandlerFunc {
+	handler := promhttp.Handler()
+
+	return func(c *gin.Context) {
+		if token != "" {
+			expected := "Bearer " + token
+			if subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), []byte(expected)) != 1 {
+				c.AbortWithStatus(http.StatusUnauthorized)
+				return
+			}
+		}
+		handler.ServeHTTP(c.Writer, c.Request)
+	}
+}
@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"weoucbookcycle_go/config"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// stubTokens 用固定结果替换取令牌，按限流键的维度返回
+func stubTokens(t *testing.T, results map[string]*utils.RateLimitResult, calls *[]string) {
+	t.Helper()
+	prevTake := takeToken
+	takeToken = func(ctx context.Context, client *redis.Client, key string, rate utils.Rate) (*utils.RateLimitResult, error) {
+		*calls = append(*calls, key)
+		for dim, result := range results {
+			if strings.Contains(key, ":"+dim+":") {
+				return result, nil
+			}
+		}
+		return nil, errors.New("redis unavailable")
+	}
+	t.Cleanup(func() { takeToken = prevTake })
+}
+
+func newRateLimitRouter(t *testing.T, cfg *config.RateLimitConfig) *gin.Engine {
+	t.Helper()
+	// 只需要非空的客户端，取令牌已被替换，不会连接Redis
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:0"})
+	t.Cleanup(func() { client.Close() })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ErrorHandler(), func(c *gin.Context) {
+		c.Set("user_id", "u1")
+		c.Next()
+	}, RateLimit(cfg, client, "search", "5/m", "100/m"))
+	r.GET("/search", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+// 响应头取剩余次数最少的维度，Reset 向上取整到秒
+func TestRateLimitHeadersUseTightestDimension(t *testing.T) {
+	var calls []string
+	stubTokens(t, map[string]*utils.RateLimitResult{
+		"user": {Allowed: true, Limit: 5, Remaining: 2, ResetAfter: 1500 * time.Millisecond},
+		"ip":   {Allowed: true, Limit: 100, Remaining: 40, ResetAfter: 36 * time.Second},
+	}, &calls)
+
+	w := httptest.NewRecorder()
+	newRateLimitRouter(t, &config.RateLimitConfig{Enabled: true}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/search", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	if len(calls) != 2 || calls[0] != "ratelimit:search:user:u1" {
+		t.Errorf("keys = %v", calls)
+	}
+	for header, want := range map[string]string{"X-RateLimit-Limit": "5", "X-RateLimit-Remaining": "2", "X-RateLimit-Reset": "2"} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+	if got := w.Header().Get("Retry-After"); got != "" {
+		t.Errorf("Retry-After = %q on allowed request", got)
+	}
+}
+
+// 任一维度超限返回429，不再检查后续维度，Retry-After 和 data.retry_after 为向上取整的秒数
+func TestRateLimitRejectsWith429(t *testing.T) {
+	var calls []string
+	stubTokens(t, map[string]*utils.RateLimitResult{
+		"user": {Allowed: false, Limit: 5, Remaining: 0, ResetAfter: 59 * time.Second, RetryAfter: 11500 * time.Millisecond},
+		"ip":   {Allowed: true, Limit: 100, Remaining: 40},
+	}, &calls)
+
+	w := httptest.NewRecorder()
+	newRateLimitRouter(t, &config.RateLimitConfig{Enabled: true}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/search", nil))
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", w.Code)
+	}
+	if len(calls) != 1 {
+		t.Errorf("keys = %v, want only the user dimension", calls)
+	}
+	for header, want := range map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "59", "Retry-After": "12"} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+	if !strings.Contains(w.Body.String(), `"retry_after":12`) {
+		t.Errorf("body = %s", w.Body.String())
+	}
+}
+
+// 关闭限流或取令牌失败时放行且不写限流头
+func TestRateLimitFailsOpen(t *testing.T) {
+	var calls []string
+	stubTokens(t, nil, &calls)
+
+	for _, enabled := range []bool{false, true} {
+		w := httptest.NewRecorder()
+		newRateLimitRouter(t, &config.RateLimitConfig{Enabled: enabled}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/search", nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("enabled=%v: status = %d, want 200", enabled, w.Code)
+		}
+		if got := w.Header().Get("X-RateLimit-Limit"); got != "" {
+			t.Errorf("enabled=%v: X-RateLimit-Limit = %q", enabled, got)
+		}
+	}
+	if len(calls) != 2 {
+		t.Errorf("keys = %v, want both dimensions tried once", calls)
+	}
+}
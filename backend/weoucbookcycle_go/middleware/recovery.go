@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"weoucbookcycle_go/config"
+	"weoucbookcycle_go/utils"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Recovery 恢复处理器中的 panic 并返回500，panic 和所有5xx响应上报到 Sentry（配置了 SENTRY_DSN 时）
+// 事件带有 request_id、user_id、route、method、status 标签，查询参数按访问日志的规则脱敏
+// 需要放在 Logger 之后（使用其生成的请求ID）、ErrorHandler 之前（在其写出响应后读取状态码），
+// config.SetupRouter 中的 gin.Recovery 只兜底处理这些中间件之前的 panic
+func Recovery(cfg *config.Config) gin.HandlerFunc {
+	redact := newRedactor(cfg.Log.RedactFields)
+
+	return func(c *gin.Context) {
+		// 每个请求使用独立的hub，处理器和其中启动的 utils.Go 任务上报时沿用请求的标签
+		hub := sentry.CurrentHub().Clone()
+		hub.ConfigureScope(func(scope *sentry.Scope) {
+			scope.SetTag("request_id", c.GetString("request_id"))
+			scope.SetTag("method", c.Request.Method)
+			scope.SetContext("request", sentry.Context{
+				"path":  c.Request.URL.Path,
+				"query": redact.Query(c.Request.URL.RawQuery),
+			})
+		})
+		c.Request = c.Request.WithContext(sentry.SetHubOnContext(c.Request.Context(), hub))
+
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if err, ok := recovered.(error); ok && isBrokenPipe(err) {
+				// 客户端已断开，无法再写响应
+				c.Abort()
+				return
+			}
+
+			ErrorLogger("panic recovered", zap.String("path", c.FullPath()), zap.Any("panic", recovered),
+				zap.ByteString("stack", debug.Stack()))
+			utils.CapturePanic(c.Request.Context(), recovered, requestTags(c, http.StatusInternalServerError))
+
+			lang := utils.RequestLang(c)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, utils.Response{
+				Code:    utils.CodeInternalServerError,
+				Message: utils.CodeMessageIn(lang, utils.CodeInternalServerError),
+			})
+		}()
+
+		c.Next()
+
+		if status := c.Writer.Status(); status >= http.StatusInternalServerError {
+			err := fmt.Errorf("%s %s responded %d", c.Request.Method, c.FullPath(), status)
+			if len(c.Errors) > 0 {
+				err = c.Errors.Last().Err
+			}
+			utils.CaptureError(c.Request.Context(), err, requestTags(c, status))
+		}
+	}
+}
+
+// requestTags 请求处理完成后才能确定的标签（用户由认证中间件设置，路由在匹配后才有）
+func requestTags(c *gin.Context, status int) map[string]string {
+	return map[string]string{
+		"user_id": c.GetString("user_id"),
+		"route":   c.FullPath(),
+		"status":  strconv.Itoa(status),
+	}
+}
+
+// isBrokenPipe 写响应时客户端已断开连接（与 gin.Recovery 相同的判断），不需要上报
+func isBrokenPipe(err error) bool {
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		return false
+	}
+	msg := strings.ToLower(opErr.Error())
+	return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+}
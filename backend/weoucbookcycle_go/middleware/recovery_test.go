@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"weoucbookcycle_go/config"
+	"weoucbookcycle_go/utils"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/gin-gonic/gin"
+)
+
+// panic 返回500并上报，处理器返回的5xx错误上报时带有请求和用户标签，4xx不上报
+func TestRecovery(t *testing.T) {
+	transport := &sentry.MockTransport{}
+	if err := sentry.Init(sentry.ClientOptions{Dsn: "https://key@sentry.invalid/1", Transport: transport}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sentry.CurrentHub().BindClient(nil) })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("request_id", "req-1")
+		c.Set("user_id", "u1")
+	}, Recovery(&config.Config{}), ErrorHandler())
+	r.GET("/panic", func(c *gin.Context) { panic("boom") })
+	r.GET("/fail", func(c *gin.Context) { c.Error(errors.New("db down")) })
+	r.GET("/missing", func(c *gin.Context) { c.Error(utils.NewError(http.StatusNotFound, "not found")) })
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path+"?token=secret", nil))
+		return w
+	}
+
+	w := serve("/panic")
+	var body utils.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Code != http.StatusInternalServerError ||
+		body.Code != utils.CodeInternalServerError {
+		t.Fatalf("panic: status %d, body %s", w.Code, w.Body.String())
+	}
+
+	if w := serve("/fail"); w.Code != http.StatusInternalServerError {
+		t.Fatalf("fail: status %d", w.Code)
+	}
+	if w := serve("/missing"); w.Code != http.StatusNotFound {
+		t.Fatalf("missing: status %d", w.Code)
+	}
+
+	events := transport.Events()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2 (panic and 500)", len(events))
+	}
+	for _, event := range events {
+		if event.Tags["request_id"] != "req-1" || event.Tags["user_id"] != "u1" || event.Tags["status"] != "500" {
+			t.Errorf("missing request tags: %v", event.Tags)
+		}
+		if query, _ := event.Contexts["request"]["query"].(string); strings.Contains(query, "secret") {
+			t.Errorf("query not redacted: %v", query)
+		}
+	}
+	if events[0].Tags["route"] != "/panic" || events[1].Tags["route"] != "/fail" {
+		t.Errorf("unexpected routes: %s, %s", events[0].Tags["route"], events[1].Tags["route"])
+	}
+	if len(events[1].Exception) == 0 || events[1].Exception[0].Value != "db down" {
+		t.Errorf("500 should report the handler error, got %+v", events[1].Exception)
+	}
+}
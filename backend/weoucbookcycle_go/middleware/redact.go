@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// redactedValue 替换敏感字段值
+const redactedValue = "[REDACTED]"
+
+// defaultRedactFields 总是脱敏的字段（小写），LOG_REDACT_FIELDS 在此基础上追加
+var defaultRedactFields = []string{
+	"password", "old_password", "new_password", "confirm_password",
+	"token", "access_token", "refresh_token", "id_token",
+	"code",      // 邮箱验证码、微信登录code
+	"signature", // 文件签名URL
+	"secret", "api_key", "authorization",
+}
+
+// redactor 按字段名脱敏查询参数和请求体
+type redactor struct {
+	fields map[string]bool
+}
+
+// newRedactor 创建脱敏器，extra 为逗号分隔的额外字段名
+func newRedactor(extra string) *redactor {
+	r := &redactor{fields: make(map[string]bool)}
+	for _, field := range defaultRedactFields {
+		r.fields[field] = true
+	}
+	for _, field := range strings.Split(extra, ",") {
+		if field = strings.ToLower(strings.TrimSpace(field)); field != "" {
+			r.fields[field] = true
+		}
+	}
+	return r
+}
+
+func (r *redactor) sensitive(key string) bool {
+	return r.fields[strings.ToLower(key)]
+}
+
+// Query 脱敏URL查询参数（也用于 application/x-www-form-urlencoded 请求体），无法解析时整体丢弃
+func (r *redactor) Query(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		return fmt.Sprintf("[%d bytes, unparsable]", len(raw))
+	}
+	for key := range values {
+		if r.sensitive(key) {
+			values[key] = []string{redactedValue}
+		}
+	}
+	return values.Encode()
+}
+
+// JSON 脱敏JSON请求体中任意层级的敏感字段；不是合法JSON（包括被截断的）时整体丢弃，避免泄露
+func (r *redactor) JSON(body []byte) string {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return fmt.Sprintf("[%d bytes, not valid JSON]", len(body))
+	}
+	data, _ := json.Marshal(r.walk(value))
+	return string(data)
+}
+
+func (r *redactor) walk(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if r.sensitive(key) {
+				v[key] = redactedValue
+			} else {
+				v[key] = r.walk(child)
+			}
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = r.walk(child)
+		}
+	}
+	return value
+}
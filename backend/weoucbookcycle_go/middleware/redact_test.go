@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"strings"
+	"testing"
+)
+
+// 查询参数和任意层级的JSON字段按名称脱敏，无法解析的请求体整体丢弃
+func TestRedactor(t *testing.T) {
+	r := newRedactor("Phone, ")
+
+	if got := r.Query("q=go&Token=abc&expires=1&signature=xyz"); got != "Token=%5BREDACTED%5D&expires=1&q=go&signature=%5BREDACTED%5D" {
+		t.Errorf("unexpected query: %s", got)
+	}
+
+	got := r.JSON([]byte(`{"email":"a@b.c","password":"p","profile":{"phone":"123"},"items":[{"refresh_token":"t"}]}`))
+	for _, secret := range []string{`"p"`, "123", `"t"`} {
+		if strings.Contains(got, secret) {
+			t.Errorf("secret %s leaked: %s", secret, got)
+		}
+	}
+	if !strings.Contains(got, "a@b.c") {
+		t.Errorf("non-sensitive field removed: %s", got)
+	}
+
+	if got := r.JSON([]byte(`{"password":"trunc`)); strings.Contains(got, "trunc") {
+		t.Errorf("truncated body leaked: %s", got)
+	}
+}
+
+func TestUnversionedRoute(t *testing.T) {
+	cases := map[string]string{
+		"/api/v1/books/:id": "/api/books/:id",
+		"/api/v2/books":     "/api/books",
+		"/api/books":        "/api/books",
+		"/api/v2":           "/api",
+		"/api/verify":       "/api/verify",
+		"/metrics":          "/metrics",
+	}
+	for route, want := range cases {
+		if got := unversionedRoute(route); got != want {
+			t.Errorf("unversionedRoute(%q) = %q, want %q", route, got, want)
+		}
+	}
+}
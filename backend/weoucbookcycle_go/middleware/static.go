@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UploadHeaders 本地上传文件的响应头
+// 上传文件名带时间戳和随机串，内容不会变化，可以长期缓存
+func UploadHeaders() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 隔离的图片、上传分片和私有文件不对外提供访问
+		if utils.IsPrivateKey(c.Param("filepath")) {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+		// 禁止浏览器猜测类型，webp 等图片按注册的 Content-Type 返回
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Next()
+	}
+}
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"net/http"
+	"weoucbookcycle_go/config"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
+
+// Tracing 为每个请求创建span，并从 traceparent 请求头继承上游的trace
+// 需要注册在 Logger 之前，Logger 才能拿到trace ID
+func Tracing(cfg *config.TracingConfig) gin.HandlerFunc {
+	return otelgin.Middleware(cfg.ServiceName,
+		// Prometheus 抓取不需要追踪
+		otelgin.WithFilter(func(r *http.Request) bool {
+			return r.URL.Path != "/metrics"
+		}),
+	)
+}
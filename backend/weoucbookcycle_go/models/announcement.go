@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 公告级别
+const (
+	AnnouncementSeverityInfo     = "info"
+	AnnouncementSeverityWarning  = "warning"
+	AnnouncementSeverityCritical = "critical"
+)
+
+// Announcement 全站公告横幅，在 StartsAt~EndsAt 期间展示（为空表示不限）
+type Announcement struct {
+	ID        string     `gorm:"type:varchar(36);primaryKey" json:"id"`
+	Text      string     `gorm:"type:varchar(500);not null" json:"text"`
+	Link      string     `gorm:"type:varchar(500)" json:"link,omitempty"`
+	Severity  string     `gorm:"type:varchar(20);default:info;comment:info,warning,critical" json:"severity"`
+	StartsAt  *time.Time `gorm:"index" json:"starts_at,omitempty"`
+	EndsAt    *time.Time `gorm:"index" json:"ends_at,omitempty"`
+	Enabled   bool       `gorm:"default:true" json:"enabled"`
+	CreatedBy string     `gorm:"type:varchar(36)" json:"created_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Announcement) TableName() string {
+	return "announcements"
+}
+
+// BeforeCreate 创建前钩子
+func (a *Announcement) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == "" {
+		a.ID = generateUUID()
+	}
+	return nil
+}
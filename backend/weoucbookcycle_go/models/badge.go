@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// 徽章，由领域事件触发规则检查后授予
+const (
+	BadgeFirstSale       = "first_sale"        // 完成第一笔交易
+	BadgeBooksRecycled10 = "books_recycled_10" // 累计售出10本书
+	BadgeVerifiedStudent = "verified_student"  // 验证过学校邮箱
+	BadgeFastResponder   = "fast_responder"    // 聊天回复及时
+)
+
+// Badges 全部徽章，按展示顺序排列
+var Badges = []string{BadgeVerifiedStudent, BadgeFirstSale, BadgeBooksRecycled10, BadgeFastResponder}
+
+// UserBadge 用户获得的徽章，授予后不会收回
+type UserBadge struct {
+	UserID    string    `gorm:"type:varchar(36);primaryKey" json:"user_id"`
+	Badge     string    `gorm:"type:varchar(40);primaryKey" json:"badge"`
+	AwardedAt time.Time `gorm:"not null;comment:获得时间" json:"awarded_at"`
+}
+
+// TableName 指定表名
+func (UserBadge) TableName() string {
+	return "user_badges"
+}
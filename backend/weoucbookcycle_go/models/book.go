@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"gorm.io/gorm"
@@ -19,6 +20,7 @@ type Book struct {
 	Condition   string         `gorm:"type:varchar(20);comment:全新,九成新,八成新,七成新,其他" json:"condition"`
 	SellerID    string         `gorm:"type:varchar(36);index;not null" json:"seller_id"`
 	Status      int            `gorm:"default:1;comment:1=可售,0=已售,2=下架" json:"status"`
+	CampusID    string         `gorm:"type:varchar(36);index;comment:所在校区，默认为卖家所在校区" json:"campus_id,omitempty"`
 	ViewCount   int64          `gorm:"default:0" json:"view_count"`
 	LikeCount   int64          `gorm:"default:0" json:"like_count"`
 	CreatedAt   time.Time      `json:"created_at"`
@@ -28,6 +30,25 @@ type Book struct {
 	// 关联关系
 	Seller   User      `gorm:"foreignKey:SellerID" json:"seller,omitempty"`
 	Listings []Listing `gorm:"foreignKey:BookID" json:"listings,omitempty"`
+
+	// showSellerContact 输出时是否包含卖家的联系方式，见 RevealSellerContact
+	showSellerContact bool
+}
+
+// MarshalJSON 卖家只输出 SellerProfile，调用 RevealSellerContact 后才包含联系方式
+func (b Book) MarshalJSON() ([]byte, error) {
+	type book Book
+	return json.Marshal(struct {
+		book
+		Seller *SellerProfile `json:"seller,omitempty"`
+	}{book(b), b.Seller.SellerProfile(b.showSellerContact)})
+}
+
+// RevealSellerContact 输出时包含 contact 中的卖家联系方式，只用于已登录且未被卖家屏蔽的查看者
+func (b *Book) RevealSellerContact(contact *User) {
+	b.Seller.Email = contact.Email
+	b.Seller.Phone = contact.Phone
+	b.showSellerContact = true
 }
 
 // TableName 指定表名
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// 书籍和发布中的卖家、买家默认只输出公开信息，RevealSellerContact 后才包含卖家的联系方式
+func TestSellerContactOnlyWhenRevealed(t *testing.T) {
+	seller := User{ID: "u1", Username: "seller", Email: "seller@example.com", Phone: "13800000000", WeChatOpenID: "openid"}
+	book := Book{ID: "b1", SellerID: seller.ID, Seller: seller}
+	listing := Listing{ID: "l1", SellerID: seller.ID, Seller: seller, Book: book,
+		Buyer: &User{ID: "u2", Username: "buyer", Email: "buyer@example.com", Phone: "13900000000"}}
+
+	for name, v := range map[string]interface{}{"book": book, "listing": listing} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, secret := range []string{"@example.com", "1380", "1390", "openid"} {
+			if strings.Contains(string(data), secret) {
+				t.Errorf("%s: response contains %q: %s", name, secret, data)
+			}
+		}
+		if !strings.Contains(string(data), `"username":"seller"`) {
+			t.Errorf("%s: seller profile missing: %s", name, data)
+		}
+	}
+
+	book.RevealSellerContact(&seller)
+	data, _ := json.Marshal(book)
+	if !strings.Contains(string(data), `"email":"seller@example.com"`) || !strings.Contains(string(data), `"phone":"13800000000"`) {
+		t.Errorf("revealed book has no seller contact: %s", data)
+	}
+	if strings.Contains(string(data), "openid") {
+		t.Errorf("revealed book contains openid: %s", data)
+	}
+
+	listing.RevealSellerContact(&seller)
+	data, _ = json.Marshal(listing)
+	if strings.Count(string(data), "seller@example.com") != 1 || strings.Contains(string(data), "buyer@example.com") {
+		t.Errorf("revealed listing should only contain the listing seller's contact: %s", data)
+	}
+}
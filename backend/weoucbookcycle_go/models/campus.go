@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Campus 校区，由管理员维护；用户资料、书籍和发布的面交地点引用校区ID
+type Campus struct {
+	ID        string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	Name      string    `gorm:"type:varchar(100);uniqueIndex;not null" json:"name"`
+	SortOrder int       `gorm:"default:0;comment:排序，小的在前" json:"sort_order"`
+	Enabled   bool      `gorm:"default:true;comment:停用后不能再被选择，已有引用保留" json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// 关联关系
+	DormAreas    []DormArea    `gorm:"foreignKey:CampusID" json:"dorm_areas"`
+	PickupPoints []PickupPoint `gorm:"foreignKey:CampusID" json:"pickup_points"`
+}
+
+// DormArea 校区内的宿舍区
+type DormArea struct {
+	ID        string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	CampusID  string    `gorm:"type:varchar(36);not null;uniqueIndex:idx_dorm_area_name" json:"campus_id"`
+	Name      string    `gorm:"type:varchar(100);not null;uniqueIndex:idx_dorm_area_name" json:"name"`
+	SortOrder int       `gorm:"default:0" json:"sort_order"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PickupPoint 校区内的常用面交地点（图书馆门口、食堂等）
+type PickupPoint struct {
+	ID        string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	CampusID  string    `gorm:"type:varchar(36);not null;uniqueIndex:idx_pickup_point_name" json:"campus_id"`
+	Name      string    `gorm:"type:varchar(100);not null;uniqueIndex:idx_pickup_point_name" json:"name"`
+	SortOrder int       `gorm:"default:0" json:"sort_order"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Campus) TableName() string {
+	return "campuses"
+}
+
+func (DormArea) TableName() string {
+	return "dorm_areas"
+}
+
+func (PickupPoint) TableName() string {
+	return "pickup_points"
+}
+
+// BeforeCreate 创建前钩子
+func (c *Campus) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == "" {
+		c.ID = generateUUID()
+	}
+	return nil
+}
+
+// BeforeCreate 创建前钩子
+func (d *DormArea) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == "" {
+		d.ID = generateUUID()
+	}
+	return nil
+}
+
+// BeforeCreate 创建前钩子
+func (p *PickupPoint) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == "" {
+		p.ID = generateUUID()
+	}
+	return nil
+}
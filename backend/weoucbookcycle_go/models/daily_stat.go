@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// DailyStat 平台每日统计（由统计汇总任务从Redis计数器写入）
+type DailyStat struct {
+	Date          string    `gorm:"type:char(10);primaryKey;comment:日期 YYYY-MM-DD" json:"date"`
+	Registrations int64     `gorm:"default:0;comment:新注册用户" json:"registrations"`
+	ActiveUsers   int64     `gorm:"default:0;comment:活跃用户(近似值)" json:"active_users"`
+	NewBooks      int64     `gorm:"default:0;comment:新发布书籍" json:"new_books"`
+	ListingsSold  int64     `gorm:"default:0;comment:成交数" json:"listings_sold"`
+	Messages      int64     `gorm:"default:0;comment:消息数" json:"messages"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (DailyStat) TableName() string {
+	return "daily_stats"
+}
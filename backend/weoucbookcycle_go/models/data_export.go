@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DataExport 用户申请的个人数据导出（资料、书籍、发布、聊天和上传文件打包为zip）
+// 归档存放在私有存储，过期后由定时任务 data_export_cleanup 删除
+type DataExport struct {
+	ID        string     `gorm:"type:varchar(36);primaryKey" json:"id"`
+	UserID    string     `gorm:"type:varchar(36);index;not null" json:"user_id"`
+	Status    string     `gorm:"type:varchar(20);not null;index;comment:pending,running,completed,failed,expired" json:"status"`
+	Key       string     `gorm:"type:varchar(255);comment:存储key" json:"-"`
+	Size      int64      `gorm:"default:0;comment:归档字节数" json:"size"`
+	Error     string     `gorm:"type:text" json:"error,omitempty"`
+	ExpiresAt *time.Time `gorm:"index;comment:归档过期时间" json:"expires_at,omitempty"`
+	CreatedAt time.Time  `gorm:"index" json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// 数据导出状态
+const (
+	DataExportPending   = "pending"
+	DataExportRunning   = "running"
+	DataExportCompleted = "completed"
+	DataExportFailed    = "failed"
+	DataExportExpired   = "expired"
+)
+
+// TableName 指定表名
+func (DataExport) TableName() string {
+	return "data_exports"
+}
+
+// BeforeCreate 创建前钩子
+func (e *DataExport) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = generateUUID()
+	}
+	return nil
+}
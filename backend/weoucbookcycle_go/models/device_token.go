@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DeviceToken 移动端推送设备令牌（FCM / APNs）
+type DeviceToken struct {
+	ID         string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	UserID     string    `gorm:"type:varchar(36);index;not null" json:"user_id"`
+	Platform   string    `gorm:"type:varchar(10);not null;comment:ios,android" json:"platform"`
+	Token      string    `gorm:"type:varchar(255);uniqueIndex;not null" json:"token"`
+	AppVersion string    `gorm:"type:varchar(30)" json:"app_version,omitempty"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// 推送平台
+const (
+	PushPlatformIOS     = "ios"
+	PushPlatformAndroid = "android"
+)
+
+// TableName 指定表名
+func (DeviceToken) TableName() string {
+	return "device_tokens"
+}
+
+// BeforeCreate 创建前钩子
+func (d *DeviceToken) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == "" {
+		d.ID = generateUUID()
+	}
+	return nil
+}
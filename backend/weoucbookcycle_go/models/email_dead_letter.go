@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 死信邮件状态
+const (
+	EmailDeadLetterFailed    = "failed"    // 等待处理
+	EmailDeadLetterRetried   = "retried"   // 重试发送成功
+	EmailDeadLetterDiscarded = "discarded" // 管理员放弃重试
+)
+
+// EmailDeadLetter 重试用尽仍发送失败的邮件
+// 正文可能包含验证码/重置链接，只通过管理接口查看
+type EmailDeadLetter struct {
+	ID          string     `gorm:"type:varchar(36);primaryKey" json:"id"`
+	Type        string     `gorm:"type:varchar(50);index" json:"type"`
+	ToEmail     string     `gorm:"type:varchar(100);index;not null" json:"to_email"`
+	Subject     string     `gorm:"type:varchar(255)" json:"subject"`
+	Body        string     `gorm:"type:text" json:"body,omitempty"`
+	HTMLBody    string     `gorm:"type:text" json:"html_body,omitempty"`
+	Error       string     `gorm:"type:text" json:"error"`
+	Attempts    int        `gorm:"default:0;comment:累计发送次数" json:"attempts"`
+	Status      string     `gorm:"type:varchar(20);default:failed;index" json:"status"`
+	QueuedAt    time.Time  `gorm:"comment:最初入队时间" json:"queued_at"`
+	LastRetryAt *time.Time `json:"last_retry_at,omitempty"`
+	RetriedBy   string     `gorm:"type:varchar(36);comment:最后处理的管理员" json:"retried_by,omitempty"`
+	CreatedAt   time.Time  `gorm:"index" json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (EmailDeadLetter) TableName() string {
+	return "email_dead_letters"
+}
+
+// BeforeCreate 创建前钩子
+func (e *EmailDeadLetter) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = generateUUID()
+	}
+	return nil
+}
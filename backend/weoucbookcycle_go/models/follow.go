@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Follow 关注关系，FollowerID 关注了 FolloweeID
+type Follow struct {
+	FollowerID string    `gorm:"type:varchar(36);primaryKey;comment:关注者" json:"follower_id"`
+	FolloweeID string    `gorm:"type:varchar(36);primaryKey;index;comment:被关注者" json:"followee_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// FeedItem 关注动态，发布书籍或上架时由后台任务写入每个关注者的收件箱
+type FeedItem struct {
+	ID        string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	UserID    string    `gorm:"type:varchar(36);not null;uniqueIndex:idx_feed_target,priority:1;index:idx_feed_user_time,priority:1;comment:动态接收者" json:"-"`
+	ActorID   string    `gorm:"type:varchar(36);not null;index;comment:发布者" json:"actor_id"`
+	Type      string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_feed_target,priority:2;comment:book,listing" json:"type"`
+	TargetID  string    `gorm:"type:varchar(36);not null;uniqueIndex:idx_feed_target,priority:3" json:"target_id"`
+	CreatedAt time.Time `gorm:"index:idx_feed_user_time,priority:2" json:"created_at"`
+
+	// 读取动态时填充，不存储
+	Actor   *PublicUser `gorm:"-" json:"actor,omitempty"`
+	Book    *Book       `gorm:"-" json:"book,omitempty"`
+	Listing *Listing    `gorm:"-" json:"listing,omitempty"`
+}
+
+// 动态类型
+const (
+	FeedTypeBook    = "book"
+	FeedTypeListing = "listing"
+)
+
+// TableName 指定表名
+func (Follow) TableName() string {
+	return "follows"
+}
+
+func (FeedItem) TableName() string {
+	return "feed_items"
+}
+
+// BeforeCreate 创建前钩子
+func (f *FeedItem) BeforeCreate(tx *gorm.DB) error {
+	if f.ID == "" {
+		f.ID = generateUUID()
+	}
+	return nil
+}
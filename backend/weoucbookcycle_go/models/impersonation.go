@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 代登录权限范围
+const (
+	ImpersonationScopeRead  = "read"  // 只允许只读请求
+	ImpersonationScopeWrite = "write" // 允许以用户身份修改数据
+)
+
+// ImpersonationSession 管理员代登录会话
+// ID 同时作为代登录token的 jti
+type ImpersonationSession struct {
+	ID        string     `gorm:"type:varchar(36);primaryKey" json:"id"`
+	AdminID   string     `gorm:"type:varchar(36);index;not null" json:"admin_id"`
+	UserID    string     `gorm:"type:varchar(36);index;not null" json:"user_id"`
+	Reason    string     `gorm:"type:varchar(500);not null;comment:代登录原因" json:"reason"`
+	Scope     string     `gorm:"type:varchar(10);not null;comment:read,write" json:"scope"`
+	IP        string     `gorm:"type:varchar(45)" json:"ip"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+	CreatedAt time.Time  `gorm:"index" json:"created_at"`
+}
+
+// ImpersonationAuditLog 代登录期间的请求记录
+type ImpersonationAuditLog struct {
+	ID         string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	SessionID  string    `gorm:"type:varchar(36);index;not null" json:"session_id"`
+	AdminID    string    `gorm:"type:varchar(36);index;not null" json:"admin_id"`
+	UserID     string    `gorm:"type:varchar(36);not null" json:"user_id"`
+	Method     string    `gorm:"type:varchar(10)" json:"method"`
+	Path       string    `gorm:"type:varchar(255)" json:"path"`
+	Query      string    `gorm:"type:varchar(500)" json:"query,omitempty"`
+	StatusCode int       `json:"status_code"`
+	IP         string    `gorm:"type:varchar(45)" json:"ip"`
+	RequestID  string    `gorm:"type:varchar(64)" json:"request_id,omitempty"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName 指定表名
+func (ImpersonationSession) TableName() string {
+	return "impersonation_sessions"
+}
+
+// TableName 指定表名
+func (ImpersonationAuditLog) TableName() string {
+	return "impersonation_audit_logs"
+}
+
+// BeforeCreate 创建前钩子
+func (s *ImpersonationSession) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == "" {
+		s.ID = generateUUID()
+	}
+	return nil
+}
+
+// BeforeCreate 创建前钩子
+func (l *ImpersonationAuditLog) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == "" {
+		l.ID = generateUUID()
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"gorm.io/gorm"
@@ -16,6 +17,11 @@ type Listing struct {
 	Status        string         `gorm:"type:varchar(20);default:available;comment:available,reserved,sold,cancelled" json:"status"`
 	Note          string         `gorm:"type:text" json:"note,omitempty"`
 	FavoriteCount int64          `gorm:"default:0" json:"favorite_count"`
+	IsDonation    bool           `gorm:"default:false;index;comment:免费赠送" json:"is_donation"`
+	CampusID      string         `gorm:"type:varchar(36);index;comment:面交校区" json:"campus_id,omitempty"`
+	DormAreaID    string         `gorm:"type:varchar(36);comment:面交宿舍区" json:"dorm_area_id,omitempty"`
+	PickupPointID string         `gorm:"type:varchar(36);comment:面交地点，属于面交校区" json:"pickup_point_id,omitempty"`
+	BumpedAt      *time.Time     `gorm:"index;comment:最近一次擦亮时间" json:"bumped_at,omitempty"`
 	CreatedAt     time.Time      `json:"created_at"`
 	UpdatedAt     time.Time      `json:"updated_at"`
 	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
@@ -25,6 +31,31 @@ type Listing struct {
 	Seller    User       `gorm:"foreignKey:SellerID" json:"seller,omitempty"`
 	Buyer     *User      `gorm:"foreignKey:BuyerID" json:"buyer,omitempty"`
 	Favorites []Favorite `gorm:"foreignKey:ListingID" json:"favorites,omitempty"`
+
+	// showSellerContact 输出时是否包含卖家的联系方式，见 RevealSellerContact
+	showSellerContact bool
+}
+
+// MarshalJSON 卖家只输出 SellerProfile（调用 RevealSellerContact 后才包含联系方式），买家只输出公开信息
+func (l Listing) MarshalJSON() ([]byte, error) {
+	type listing Listing
+	var buyer *PublicUser
+	if l.Buyer != nil {
+		public := l.Buyer.Public()
+		buyer = &public
+	}
+	return json.Marshal(struct {
+		listing
+		Seller *SellerProfile `json:"seller,omitempty"`
+		Buyer  *PublicUser    `json:"buyer,omitempty"`
+	}{listing(l), l.Seller.SellerProfile(l.showSellerContact), buyer})
+}
+
+// RevealSellerContact 输出时包含 contact 中的卖家联系方式，只用于已登录且未被卖家屏蔽的查看者
+func (l *Listing) RevealSellerContact(contact *User) {
+	l.Seller.Email = contact.Email
+	l.Seller.Phone = contact.Phone
+	l.showSellerContact = true
 }
 
 // Favorite 收藏模型
@@ -48,6 +79,14 @@ func (Favorite) TableName() string {
 	return "favorites"
 }
 
+// ActiveAt 列表排序使用的时间：擦亮过的按擦亮时间，否则按发布时间
+func (l *Listing) ActiveAt() time.Time {
+	if l.BumpedAt != nil {
+		return *l.BumpedAt
+	}
+	return l.CreatedAt
+}
+
 // BeforeCreate 创建前钩子
 func (l *Listing) BeforeCreate(tx *gorm.DB) error {
 	if l.ID == "" {
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// 审核队列状态
+const (
+	ModerationStatusPending  = "pending"
+	ModerationStatusApproved = "approved"
+	ModerationStatusRejected = "rejected"
+	// ModerationStatusEscalated 需要更高级别复核，仍在队列中
+	ModerationStatusEscalated = "escalated"
+)
+
+// ModerationQueueItem 待人工审核的内容
+// TargetType 为 user、book、listing、chat、message 或 image（尚未被引用的图片，TargetID 为存储key）
+// 同一对象的多条用户举报合并为一个条目，ReportCount 为举报次数
+type ModerationQueueItem struct {
+	ID          string         `gorm:"type:varchar(36);primaryKey" json:"id"`
+	TargetType  string         `gorm:"type:varchar(20);not null;index:idx_moderation_target,priority:1;comment:book,message,image" json:"target_type"`
+	TargetID    string         `gorm:"type:varchar(255);not null;index:idx_moderation_target,priority:2" json:"target_id"`
+	UserID      string         `gorm:"type:varchar(36);index;comment:内容所有者" json:"user_id,omitempty"`
+	Source      string         `gorm:"type:varchar(30);comment:来源(image_moderation,report)" json:"source"`
+	Reason      string         `gorm:"type:varchar(100);comment:审核原因/标签" json:"reason"`
+	Details     datatypes.JSON `gorm:"type:json;comment:审核详情" json:"details,omitempty"`
+	Status      string         `gorm:"type:varchar(20);default:pending;index;comment:pending,approved,rejected,escalated" json:"status"`
+	ReportCount int            `gorm:"default:0;comment:用户举报次数" json:"report_count"`
+	AssignedTo  string         `gorm:"type:varchar(36);index;comment:处理人" json:"assigned_to,omitempty"`
+	AssignedAt  *time.Time     `json:"assigned_at,omitempty"`
+	EscalatedAt *time.Time     `json:"escalated_at,omitempty"`
+	ReviewedBy  string         `gorm:"type:varchar(36)" json:"reviewed_by,omitempty"`
+	ReviewedAt  *time.Time     `json:"reviewed_at,omitempty"`
+	Resolution  string         `gorm:"type:varchar(30);comment:处理原因(spam,fraud等)" json:"resolution,omitempty"`
+	Action      string         `gorm:"type:varchar(20);comment:处理措施(none,remove,ban)" json:"action,omitempty"`
+	ReviewNote  string         `gorm:"type:varchar(500)" json:"review_note,omitempty"`
+	CreatedAt   time.Time      `gorm:"index" json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (ModerationQueueItem) TableName() string {
+	return "moderation_queue"
+}
+
+// BeforeCreate 创建前钩子
+func (m *ModerationQueueItem) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == "" {
+		m.ID = generateUUID()
+	}
+	return nil
+}
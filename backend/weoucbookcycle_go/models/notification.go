@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Notification 站内通知模型
+type Notification struct {
+	ID        string         `gorm:"type:varchar(36);primaryKey" json:"id"`
+	UserID    string         `gorm:"type:varchar(36);not null;index:idx_notifications_user_read,priority:1" json:"user_id"`
+	Type      string         `gorm:"type:varchar(50);index;comment:通知类型" json:"type"`
+	Title     string         `gorm:"type:varchar(200);not null" json:"title"`
+	Content   string         `gorm:"type:text" json:"content,omitempty"`
+	Data      datatypes.JSON `gorm:"type:json;comment:附加数据" json:"data,omitempty"`
+	IsRead    bool           `gorm:"default:false;index:idx_notifications_user_read,priority:2" json:"is_read"`
+	ReadAt    *time.Time     `json:"read_at,omitempty"`
+	CreatedAt time.Time      `gorm:"index" json:"created_at"`
+}
+
+// TableName 指定表名
+func (Notification) TableName() string {
+	return "notifications"
+}
+
+// BeforeCreate 创建前钩子
+func (n *Notification) BeforeCreate(tx *gorm.DB) error {
+	if n.ID == "" {
+		n.ID = generateUUID()
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 绿色积分变动原因
+const (
+	PointsSale     = "sale"     // 卖出书籍
+	PointsPurchase = "purchase" // 买入二手书
+	PointsDonation = "donation" // 捐赠书籍
+	PointsBump     = "bump"     // 擦亮发布
+	PointsPerk     = "perk"     // 兑换权益
+	PointsRefund   = "refund"   // 兑换取消后退回
+	PointsAdjust   = "adjust"   // 管理员调整
+	PointsReferral = "referral" // 邀请奖励（邀请人和被邀请人各一条）
+)
+
+// 权益兑换状态
+const (
+	RedemptionPending   = "pending"
+	RedemptionFulfilled = "fulfilled"
+	RedemptionCancelled = "cancelled"
+)
+
+// PointsEntry 绿色积分流水，余额为全部流水之和，同时记录在 users.green_points
+// 获得积分的流水按 (UserID, Reason, RefID) 去重，同一笔交易或捐赠只计一次
+type PointsEntry struct {
+	ID             string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	UserID         string    `gorm:"type:varchar(36);not null;index:idx_points_user_ref" json:"user_id"`
+	Amount         int       `gorm:"not null;comment:正数为获得，负数为消费" json:"amount"`
+	Reason         string    `gorm:"type:varchar(20);not null;index:idx_points_user_ref" json:"reason"`
+	RefID          string    `gorm:"type:varchar(36);index:idx_points_user_ref;comment:关联的发布、捐赠、兑换ID或操作的管理员" json:"ref_id,omitempty"`
+	CounterpartyID string    `gorm:"type:varchar(36);comment:交易对方" json:"counterparty_id,omitempty"`
+	Note           string    `gorm:"type:varchar(255)" json:"note,omitempty"`
+	Balance        int       `gorm:"not null;comment:变动后的余额" json:"balance"`
+	CreatedAt      time.Time `gorm:"index" json:"created_at"`
+}
+
+// PointsRedemption 积分兑换的权益，由管理员线下发放后标记完成，取消时退回积分
+type PointsRedemption struct {
+	ID        string     `gorm:"type:varchar(36);primaryKey" json:"id"`
+	UserID    string     `gorm:"type:varchar(36);index;not null" json:"user_id"`
+	Perk      string     `gorm:"type:varchar(40);not null" json:"perk"`
+	Cost      int        `gorm:"not null" json:"cost"`
+	Status    string     `gorm:"type:varchar(20);index;default:pending;comment:pending,fulfilled,cancelled" json:"status"`
+	HandledBy string     `gorm:"type:varchar(36)" json:"handled_by,omitempty"`
+	HandledAt *time.Time `json:"handled_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+
+	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// TableName 指定表名
+func (PointsEntry) TableName() string {
+	return "points_entries"
+}
+
+func (PointsRedemption) TableName() string {
+	return "points_redemptions"
+}
+
+// BeforeCreate 创建前钩子
+func (e *PointsEntry) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = generateUUID()
+	}
+	return nil
+}
+
+// BeforeCreate 创建前钩子
+func (r *PointsRedemption) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = generateUUID()
+	}
+	return nil
+}
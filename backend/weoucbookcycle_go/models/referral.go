@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 邀请状态
+const (
+	ReferralPending  = "pending"  // 已注册，等待被邀请人完成首笔交易
+	ReferralRewarded = "rewarded" // 双方已获得积分
+	ReferralRejected = "rejected" // 未通过防刷检查，不发放积分
+)
+
+// 邀请未通过防刷检查的原因
+const (
+	ReferralRejectIPReuse     = "ip_reuse"     // 与邀请人或其他被邀请人使用相同IP
+	ReferralRejectDeviceReuse = "device_reuse" // 设备已用于其他邀请注册
+)
+
+// ReferralCode 用户的邀请码，首次查看邀请信息时生成
+type ReferralCode struct {
+	UserID    string    `gorm:"type:varchar(36);primaryKey" json:"user_id"`
+	Code      string    `gorm:"type:varchar(16);not null;uniqueIndex" json:"code"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Referral 邀请关系，每个被邀请人只能有一个邀请人
+// 被邀请人完成首笔交易（对方不是邀请人）后双方获得积分
+type Referral struct {
+	ID                  string     `gorm:"type:varchar(36);primaryKey" json:"id"`
+	InviterID           string     `gorm:"type:varchar(36);not null;index" json:"inviter_id"`
+	InviteeID           string     `gorm:"type:varchar(36);not null;uniqueIndex" json:"invitee_id"`
+	Code                string     `gorm:"type:varchar(16);not null" json:"code"`
+	Status              string     `gorm:"type:varchar(20);not null;default:pending;index;comment:pending,rewarded,rejected" json:"status"`
+	RejectReason        string     `gorm:"type:varchar(30);comment:ip_reuse,device_reuse" json:"-"`
+	RegisterIP          string     `gorm:"type:varchar(45);index" json:"-"`
+	DeviceID            string     `gorm:"type:varchar(64);index" json:"-"`
+	QualifyingListingID string     `gorm:"type:varchar(36);comment:触发奖励的成交" json:"qualifying_listing_id,omitempty"`
+	RewardedAt          *time.Time `json:"rewarded_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+
+	Invitee User `gorm:"foreignKey:InviteeID" json:"-"`
+}
+
+// TableName 指定表名
+func (ReferralCode) TableName() string {
+	return "referral_codes"
+}
+
+func (Referral) TableName() string {
+	return "referrals"
+}
+
+// BeforeCreate 创建前钩子
+func (r *Referral) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = generateUUID()
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 举报状态
+const (
+	ReportStatusPending   = "pending"
+	ReportStatusResolved  = "resolved"
+	ReportStatusDismissed = "dismissed"
+)
+
+// Report 用户举报
+// TargetType 为 user、book、listing、chat 或 message
+type Report struct {
+	ID          string     `gorm:"type:varchar(36);primaryKey" json:"id"`
+	ReporterID  string     `gorm:"type:varchar(36);index;not null" json:"reporter_id"`
+	TargetType  string     `gorm:"type:varchar(20);not null;index:idx_report_target,priority:1;comment:user,book,listing,chat,message" json:"target_type"`
+	TargetID    string     `gorm:"type:varchar(36);not null;index:idx_report_target,priority:2" json:"target_id"`
+	Reason      string     `gorm:"type:varchar(50);not null;comment:举报原因" json:"reason"`
+	Description string     `gorm:"type:varchar(1000)" json:"description,omitempty"`
+	Status      string     `gorm:"type:varchar(20);default:pending;index;comment:pending,resolved,dismissed" json:"status"`
+	QueueItemID string     `gorm:"type:varchar(36);index;comment:审核队列条目" json:"queue_item_id,omitempty"`
+	HandledBy   string     `gorm:"type:varchar(36)" json:"handled_by,omitempty"`
+	HandledAt   *time.Time `json:"handled_at,omitempty"`
+	Resolution  string     `gorm:"type:varchar(500);comment:处理说明" json:"resolution,omitempty"`
+	CreatedAt   time.Time  `gorm:"index" json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	Reporter User `gorm:"foreignKey:ReporterID" json:"reporter,omitempty"`
+}
+
+// TableName 指定表名
+func (Report) TableName() string {
+	return "reports"
+}
+
+// BeforeCreate 创建前钩子
+func (r *Report) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = generateUUID()
+	}
+	return nil
+}
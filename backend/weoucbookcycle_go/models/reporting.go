@@ -0,0 +1,89 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// 报表表由 Redis 流导入（见 services.ReportingETLService），导入后流会被裁剪，
+// 长期的管理端统计从这些表读取。StreamID 为流消息ID，用于去重
+
+// ReportEvent 领域事件（user_events、book_events、chat_events）
+type ReportEvent struct {
+	ID         string         `gorm:"type:varchar(36);primaryKey" json:"id"`
+	Stream     string         `gorm:"type:varchar(30);not null;uniqueIndex:idx_report_event_stream_id,priority:1;index:idx_report_event_stream_time,priority:1" json:"stream"`
+	StreamID   string         `gorm:"type:varchar(30);not null;uniqueIndex:idx_report_event_stream_id,priority:2;comment:Redis流消息ID" json:"stream_id"`
+	Event      string         `gorm:"type:varchar(50);not null;index;comment:register,book_created,chat_created等" json:"event"`
+	UserID     string         `gorm:"type:varchar(36);index;comment:触发事件的用户" json:"user_id,omitempty"`
+	ObjectID   string         `gorm:"type:varchar(36);index;comment:事件对象（书籍、聊天等）ID" json:"object_id,omitempty"`
+	Details    datatypes.JSON `gorm:"type:json;comment:原始事件字段" json:"details,omitempty"`
+	OccurredAt time.Time      `gorm:"index;index:idx_report_event_stream_time,priority:2;comment:事件发生时间" json:"occurred_at"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// ReportLogin 登录记录（login_logs）
+type ReportLogin struct {
+	ID         string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	StreamID   string    `gorm:"type:varchar(30);not null;uniqueIndex;comment:Redis流消息ID" json:"stream_id"`
+	UserID     string    `gorm:"type:varchar(36);index" json:"user_id"`
+	Username   string    `gorm:"type:varchar(50)" json:"username"`
+	IP         string    `gorm:"type:varchar(45);index" json:"ip"`
+	UserAgent  string    `gorm:"type:varchar(255)" json:"user_agent,omitempty"`
+	Success    bool      `json:"success"`
+	OccurredAt time.Time `gorm:"index" json:"occurred_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ReportAccessLog 访问日志（access_logs）
+type ReportAccessLog struct {
+	ID         string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	StreamID   string    `gorm:"type:varchar(30);not null;uniqueIndex;comment:Redis流消息ID" json:"stream_id"`
+	Method     string    `gorm:"type:varchar(10);not null" json:"method"`
+	Path       string    `gorm:"type:varchar(255);not null;index" json:"path"`
+	StatusCode int       `gorm:"index" json:"status_code"`
+	LatencyMs  int64     `json:"latency_ms"`
+	IP         string    `gorm:"type:varchar(45)" json:"ip"`
+	UserID     string    `gorm:"type:varchar(36);index" json:"user_id,omitempty"`
+	TraceID    string    `gorm:"type:varchar(32)" json:"trace_id,omitempty"`
+	OccurredAt time.Time `gorm:"index" json:"occurred_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (ReportEvent) TableName() string {
+	return "report_events"
+}
+
+func (ReportLogin) TableName() string {
+	return "report_logins"
+}
+
+func (ReportAccessLog) TableName() string {
+	return "report_access_logs"
+}
+
+// BeforeCreate 创建前钩子
+func (e *ReportEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = generateUUID()
+	}
+	return nil
+}
+
+// BeforeCreate 创建前钩子
+func (l *ReportLogin) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == "" {
+		l.ID = generateUUID()
+	}
+	return nil
+}
+
+// BeforeCreate 创建前钩子
+func (l *ReportAccessLog) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == "" {
+		l.ID = generateUUID()
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// SellerRating 买家对卖家的评价，同一买家对同一卖家只保留最近一次
+// 计算信誉分时只统计在该卖家处完成过交易的买家的评价
+type SellerRating struct {
+	SellerID  string    `gorm:"type:varchar(36);primaryKey" json:"seller_id"`
+	RaterID   string    `gorm:"type:varchar(36);primaryKey;index" json:"rater_id"`
+	IsGood    bool      `gorm:"not null" json:"is_good"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SellerReputation 卖家信誉分及各项明细，由定时任务 reputation_rollup 计算
+// 总分同时写入 users.reputation_score，列表和资料接口直接读取用户上的分数
+type SellerReputation struct {
+	UserID string `gorm:"type:varchar(36);primaryKey" json:"user_id"`
+	Score  int    `gorm:"not null;comment:综合信誉分 0-100" json:"score"`
+
+	CompletedOrders int64 `gorm:"comment:已完成的订单数" json:"completed_orders"`
+	OrdersScore     int   `json:"orders_score"`
+
+	GoodRatings  int64 `gorm:"comment:有效好评数" json:"good_ratings"`
+	BadRatings   int64 `gorm:"comment:有效差评数" json:"bad_ratings"`
+	RatingsScore int   `json:"ratings_score"`
+
+	// AvgResponseMinutes 对方发起聊天后首次回复的平均分钟数，没有聊天时为空
+	AvgResponseMinutes *float64 `json:"avg_response_minutes,omitempty"`
+	ResponseChats      int64    `gorm:"comment:参与统计的聊天数" json:"response_chats"`
+	ResponseScore      int      `json:"response_score"`
+
+	UpheldDisputes int64 `gorm:"comment:成立的举报数" json:"upheld_disputes"`
+	DisputesScore  int   `json:"disputes_score"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (SellerRating) TableName() string {
+	return "seller_ratings"
+}
+
+func (SellerReputation) TableName() string {
+	return "seller_reputations"
+}
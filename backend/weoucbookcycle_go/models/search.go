@@ -0,0 +1,108 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// SearchSynonym 搜索同义词组
+// 同一组内的词互为同义词（例如：高数, 高等数学）
+type SearchSynonym struct {
+	ID        string         `gorm:"type:varchar(36);primaryKey" json:"id"`
+	Terms     datatypes.JSON `gorm:"type:json;not null;comment:同义词列表(JSON数组)" json:"terms"`
+	Note      string         `gorm:"type:varchar(255);comment:备注" json:"note,omitempty"`
+	Enabled   bool           `gorm:"default:true;comment:是否启用" json:"enabled"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (SearchSynonym) TableName() string {
+	return "search_synonyms"
+}
+
+// BeforeCreate 创建前钩子
+func (s *SearchSynonym) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == "" {
+		s.ID = generateUUID()
+	}
+	return nil
+}
+
+// SavedSearch 用户保存的搜索（关键词 + 筛选条件）
+type SavedSearch struct {
+	ID             string         `gorm:"type:varchar(36);primaryKey" json:"id"`
+	UserID         string         `gorm:"type:varchar(36);index;not null" json:"user_id"`
+	Name           string         `gorm:"type:varchar(100)" json:"name"`
+	Query          string         `gorm:"type:varchar(200);not null" json:"query"`
+	Filters        datatypes.JSON `gorm:"type:json;comment:筛选条件" json:"filters,omitempty"`
+	Frequency      string         `gorm:"type:varchar(20);default:daily;comment:hourly,daily,weekly" json:"frequency"`
+	Enabled        bool           `gorm:"default:true" json:"enabled"`
+	LastCheckedAt  *time.Time     `json:"last_checked_at,omitempty"`
+	LastNotifiedAt *time.Time     `json:"last_notified_at,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (SavedSearch) TableName() string {
+	return "saved_searches"
+}
+
+// BeforeCreate 创建前钩子
+func (s *SavedSearch) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == "" {
+		s.ID = generateUUID()
+	}
+	return nil
+}
+
+// SearchEvent 搜索事件（每次搜索一条，ID即返回给前端的 search_id）
+type SearchEvent struct {
+	ID              string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	UserID          string    `gorm:"type:varchar(36);index" json:"user_id,omitempty"`
+	Query           string    `gorm:"type:varchar(200);not null" json:"query"`
+	NormalizedQuery string    `gorm:"type:varchar(200);index;comment:小写去空白后的查询" json:"normalized_query"`
+	SearchType      string    `gorm:"type:varchar(20);index;comment:global,books,users" json:"search_type"`
+	ResultCount     int64     `gorm:"default:0" json:"result_count"`
+	CreatedAt       time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName 指定表名
+func (SearchEvent) TableName() string {
+	return "search_events"
+}
+
+// BeforeCreate 创建前钩子
+func (e *SearchEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = generateUUID()
+	}
+	return nil
+}
+
+// SearchClick 搜索结果点击记录
+type SearchClick struct {
+	ID         string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	SearchID   string    `gorm:"type:varchar(36);index;not null" json:"search_id"`
+	UserID     string    `gorm:"type:varchar(36);index" json:"user_id,omitempty"`
+	ResultType string    `gorm:"type:varchar(20);comment:book,user,listing" json:"result_type"`
+	ResultID   string    `gorm:"type:varchar(36)" json:"result_id"`
+	Position   int       `gorm:"default:0;comment:结果中的位置(从1开始)" json:"position"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName 指定表名
+func (SearchClick) TableName() string {
+	return "search_clicks"
+}
+
+// BeforeCreate 创建前钩子
+func (sc *SearchClick) BeforeCreate(tx *gorm.DB) error {
+	if sc.ID == "" {
+		sc.ID = generateUUID()
+	}
+	return nil
+}
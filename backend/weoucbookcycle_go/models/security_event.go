@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// SecurityEvent 安全事件归档
+// 由 security_events、login_failures 两个Redis流持久化而来，StreamID 用于去重
+type SecurityEvent struct {
+	ID         string         `gorm:"type:varchar(36);primaryKey" json:"id"`
+	Stream     string         `gorm:"type:varchar(30);not null;uniqueIndex:idx_security_stream_id,priority:1" json:"stream"`
+	StreamID   string         `gorm:"type:varchar(30);not null;uniqueIndex:idx_security_stream_id,priority:2;comment:Redis流消息ID" json:"stream_id"`
+	Event      string         `gorm:"type:varchar(50);not null;index;comment:ip_blocked,login_failure等" json:"event"`
+	IP         string         `gorm:"type:varchar(45);index" json:"ip,omitempty"`
+	Email      string         `gorm:"type:varchar(100);index" json:"email,omitempty"`
+	UserID     string         `gorm:"type:varchar(36);index" json:"user_id,omitempty"`
+	Reason     string         `gorm:"type:varchar(255)" json:"reason,omitempty"`
+	Details    datatypes.JSON `gorm:"type:json;comment:原始事件字段" json:"details,omitempty"`
+	OccurredAt time.Time      `gorm:"index;comment:事件发生时间" json:"occurred_at"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// TableName 指定表名
+func (SecurityEvent) TableName() string {
+	return "security_events"
+}
+
+// BeforeCreate 创建前钩子
+func (e *SecurityEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = generateUUID()
+	}
+	return nil
+}
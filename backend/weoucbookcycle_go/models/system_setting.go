@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// SystemSetting 运行时可调整的系统参数，未写入的参数使用代码中的默认值
+type SystemSetting struct {
+	Key       string    `gorm:"type:varchar(100);primaryKey" json:"key"`
+	Value     string    `gorm:"type:varchar(255);not null" json:"value"`
+	UpdatedBy string    `gorm:"type:varchar(36);comment:最后修改的管理员" json:"updated_by,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (SystemSetting) TableName() string {
+	return "system_settings"
+}
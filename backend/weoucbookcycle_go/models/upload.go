@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UploadedFile 用户上传的文件，用于统计存储用量和配额
+type UploadedFile struct {
+	ID          string         `gorm:"type:varchar(36);primaryKey" json:"id"`
+	UserID      string         `gorm:"type:varchar(36);index;not null" json:"user_id"`
+	Key         string         `gorm:"type:varchar(255);uniqueIndex;not null;comment:存储key" json:"key"`
+	URL         string         `gorm:"type:varchar(500);comment:公开URL，私有文件为空" json:"url,omitempty"`
+	ThumbURL    string         `gorm:"type:varchar(500)" json:"thumb_url,omitempty"`
+	FileName    string         `gorm:"type:varchar(255)" json:"file_name"`
+	ContentType string         `gorm:"type:varchar(100)" json:"content_type"`
+	Size        int64          `gorm:"not null;default:0;comment:字节数" json:"size"`
+	Visibility  string         `gorm:"type:varchar(10);default:public;comment:public,private" json:"visibility"`
+	Purpose     string         `gorm:"type:varchar(30);comment:私有文件用途(verification,evidence)" json:"purpose,omitempty"`
+	Width       int            `json:"width,omitempty"`
+	Height      int            `json:"height,omitempty"`
+	CreatedAt   time.Time      `gorm:"index" json:"created_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// 文件可见性
+const (
+	FileVisibilityPublic  = "public"
+	FileVisibilityPrivate = "private"
+)
+
+// TableName 指定表名
+func (UploadedFile) TableName() string {
+	return "uploaded_files"
+}
+
+// BeforeCreate 创建前钩子
+func (f *UploadedFile) BeforeCreate(tx *gorm.DB) error {
+	if f.ID == "" {
+		f.ID = generateUUID()
+	}
+	return nil
+}
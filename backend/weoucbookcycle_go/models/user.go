@@ -18,9 +18,11 @@ type User struct {
 	Bio           string         `gorm:"type:text;comment:个人简介" json:"bio,omitempty"`
 	EmailVerified bool           `gorm:"default:false;comment:邮箱是否已验证" json:"email_verified"`
 	VerifiedAt    *time.Time     `gorm:"comment:验证时间" json:"verified_at,omitempty"`
-	Status        int            `gorm:"default:1;comment:状态: 1=正常, 0=禁用" json:"status"`
+	Status        int            `gorm:"default:1;comment:状态: 1=正常, 0=禁用, 2=用户自行停用" json:"status"`
+	Role          string         `gorm:"type:varchar(20);default:user;comment:角色: user, admin" json:"role"`
 	LastLogin     *time.Time     `gorm:"comment:最后登录时间" json:"last_login,omitempty"`
 	LoginCount    int            `gorm:"default:0;comment:登录次数" json:"login_count"`
+	DeactivatedAt *time.Time     `gorm:"comment:停用时间" json:"deactivated_at,omitempty"`
 	CreatedAt     time.Time      `gorm:"comment:创建时间" json:"created_at"`
 	UpdatedAt     time.Time      `gorm:"comment:更新时间" json:"updated_at"`
 	DeletedAt     gorm.DeletedAt `gorm:"index;comment:删除时间" json:"-"` // 软删除
@@ -34,9 +36,61 @@ type User struct {
 	Wishlist datatypes.JSON `gorm:"type:json" json:"wishlist,omitempty"`
 	// TrustScore 用户的信任分（0-100）
 	TrustScore int `gorm:"default:80" json:"trustScore"`
+	// FollowerCount / FollowingCount 粉丝数和关注数，关注和取消关注时同步更新
+	FollowerCount  int64 `gorm:"default:0;comment:粉丝数" json:"follower_count"`
+	FollowingCount int64 `gorm:"default:0;comment:关注数" json:"following_count"`
+	// ReputationScore 卖家综合信誉分（0-100），由定时任务根据成交、评价、回复速度和举报计算，明细见 SellerReputation
+	ReputationScore int `gorm:"default:0;comment:信誉分" json:"reputation_score"`
+	// GreenPoints 绿色积分余额，与 PointsEntry 流水同时更新
+	GreenPoints int `gorm:"default:0;comment:绿色积分余额" json:"green_points"`
+
+	// CampusID / DormAreaID 所在校区和宿舍区，浏览和搜索发布时默认按校区筛选；宿舍区只对本人可见
+	CampusID   string `gorm:"type:varchar(36);index;comment:校区ID" json:"campus_id,omitempty"`
+	DormAreaID string `gorm:"type:varchar(36);comment:宿舍区ID" json:"dorm_area_id,omitempty"`
 
 	// 微信开放平台openid，用于小程序登录
 	WeChatOpenID string `gorm:"type:varchar(100);uniqueIndex;comment:微信openid" json:"wechat_openid,omitempty"`
+
+	// Badges 获得的徽章（见 UserBadge），不是数据库列，资料和聊天接口按需填充
+	Badges []string `gorm:"-" json:"badges,omitempty"`
+}
+
+// 用户状态
+// 停用（deactivated）由用户自己发起，可以随时重新启用：资料、书籍和发布对他人隐藏，也不再收到通知
+const (
+	UserStatusDisabled    = 0
+	UserStatusActive      = 1
+	UserStatusDeactivated = 2
+)
+
+// PublicUser 对外公开的用户信息（不含邮箱、手机号等隐私字段）
+type PublicUser struct {
+	ID              string    `json:"id"`
+	Username        string    `json:"username"`
+	Avatar          string    `json:"avatar,omitempty"`
+	Bio             string    `json:"bio,omitempty"`
+	TrustScore      int       `json:"trustScore"`
+	FollowerCount   int64     `json:"follower_count"`
+	FollowingCount  int64     `json:"following_count"`
+	ReputationScore int       `json:"reputation_score"`
+	CampusID        string    `json:"campus_id,omitempty"`
+	Badges          []string  `json:"badges,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// SellerProfile 书籍和发布中返回的卖家信息：公开信息，联系方式只返回给已登录且未被卖家屏蔽的用户
+type SellerProfile struct {
+	PublicUser
+	Email string `json:"email,omitempty"`
+	Phone string `json:"phone,omitempty"`
+}
+
+// PublicProfile 其他用户查看的个人主页：公开信息加上在售的书籍和发布
+// 本人查看时返回完整的 User
+type PublicProfile struct {
+	PublicUser
+	Books    []Book    `json:"books"`
+	Listings []Listing `json:"listings"`
 }
 
 // TableName 指定表名
@@ -44,6 +98,45 @@ func (User) TableName() string {
 	return "users"
 }
 
+// Roles 返回写入JWT的角色列表
+func (u *User) Roles() []string {
+	if u.Role == "admin" {
+		return []string{"user", "admin"}
+	}
+	return []string{"user"}
+}
+
+// Public 转换为公开的用户信息
+func (u *User) Public() PublicUser {
+	return PublicUser{
+		ID:              u.ID,
+		Username:        u.Username,
+		Avatar:          u.Avatar,
+		Bio:             u.Bio,
+		TrustScore:      u.TrustScore,
+		FollowerCount:   u.FollowerCount,
+		FollowingCount:  u.FollowingCount,
+		ReputationScore: u.ReputationScore,
+		CampusID:        u.CampusID,
+		Badges:          u.Badges,
+		CreatedAt:       u.CreatedAt,
+	}
+}
+
+// SellerProfile 转换为书籍和发布中的卖家信息，withContact 为 false 时不含联系方式
+// 未加载（ID为空）时返回nil
+func (u *User) SellerProfile(withContact bool) *SellerProfile {
+	if u.ID == "" {
+		return nil
+	}
+	seller := &SellerProfile{PublicUser: u.Public()}
+	if withContact {
+		seller.Email = u.Email
+		seller.Phone = u.Phone
+	}
+	return seller
+}
+
 // BeforeCreate 创建前钩子
 func (u *User) BeforeCreate(tx *gorm.DB) error {
 	if u.ID == "" {
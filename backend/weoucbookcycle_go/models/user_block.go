@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// UserBlock 屏蔽关系，BlockerID 屏蔽了 BlockedID
+// 被屏蔽的用户不能与屏蔽者聊天、关注屏蔽者、收藏其发布，也看不到其联系方式
+type UserBlock struct {
+	BlockerID string    `gorm:"type:varchar(36);primaryKey;comment:屏蔽者" json:"blocker_id"`
+	BlockedID string    `gorm:"type:varchar(36);primaryKey;index;comment:被屏蔽者" json:"blocked_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (UserBlock) TableName() string {
+	return "user_blocks"
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 登录身份类型
+const (
+	IdentityEmail  = "email"
+	IdentityPhone  = "phone"
+	IdentityWeChat = "wechat"
+)
+
+// UserIdentity 账号绑定的登录身份，一个账号可以绑定多个邮箱、手机号和第三方账号，用其中任意一个登录
+// Subject 为规范化后的邮箱、手机号或第三方平台的用户标识（如微信openid）
+type UserIdentity struct {
+	ID         string     `gorm:"type:varchar(36);primaryKey" json:"id"`
+	UserID     string     `gorm:"type:varchar(36);index;not null" json:"user_id"`
+	Provider   string     `gorm:"type:varchar(20);not null;uniqueIndex:idx_identity_subject;comment:email,phone,wechat" json:"provider"`
+	Subject    string     `gorm:"type:varchar(191);not null;uniqueIndex:idx_identity_subject" json:"subject"`
+	LastUsedAt *time.Time `gorm:"comment:最后一次用于登录的时间" json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// TableName 指定表名
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
+
+// BeforeCreate 创建前钩子
+func (i *UserIdentity) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == "" {
+		i.ID = generateUUID()
+	}
+	return nil
+}
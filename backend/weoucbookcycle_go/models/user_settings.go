@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// UserSettings 用户偏好与隐私设置
+// 每个用户最多一行，没有记录时使用默认值
+type UserSettings struct {
+	UserID               string    `gorm:"type:varchar(36);primaryKey" json:"user_id"`
+	DiscoverableInSearch bool      `gorm:"default:true;comment:是否允许在用户搜索中被找到" json:"discoverable_in_search"`
+	PersonalizedSearch   bool      `gorm:"default:true;comment:是否根据浏览/购买记录个性化搜索结果" json:"personalized_search"`
+	ShowOnlineStatus     bool      `gorm:"default:true;comment:是否在在线用户列表中显示" json:"show_online_status"`
+	ShowBooksOnProfile   bool      `gorm:"default:true;comment:个人主页是否向他人展示书籍和发布" json:"show_books_on_profile"`
+	AllowStrangerChat    bool      `gorm:"default:true;comment:是否允许未关注的用户发起聊天" json:"allow_stranger_chat"`
+	PushMobile           bool      `gorm:"default:true;comment:是否接收App推送" json:"push_mobile"`
+	PushWeb              bool      `gorm:"default:true;comment:是否接收浏览器推送" json:"push_web"`
+	PushWeChat           bool      `gorm:"default:true;comment:是否接收微信订阅消息" json:"push_wechat"`
+	Language             string    `gorm:"type:varchar(10);default:'';comment:通知和邮件语言，空为默认语言" json:"language"`
+	CreatedAt            time.Time `json:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (UserSettings) TableName() string {
+	return "user_settings"
+}
+
+// DefaultUserSettings 返回用户的默认设置
+func DefaultUserSettings(userID string) *UserSettings {
+	return &UserSettings{
+		UserID:               userID,
+		DiscoverableInSearch: true,
+		PersonalizedSearch:   true,
+		ShowOnlineStatus:     true,
+		ShowBooksOnProfile:   true,
+		AllowStrangerChat:    true,
+		PushMobile:           true,
+		PushWeb:              true,
+		PushWeChat:           true,
+	}
+}
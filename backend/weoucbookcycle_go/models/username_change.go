@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UsernameChange 用户名修改记录，用于修改冷却期和查看改名历史
+type UsernameChange struct {
+	ID          string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	UserID      string    `gorm:"type:varchar(36);index:idx_username_change_user;not null" json:"user_id"`
+	OldUsername string    `gorm:"type:varchar(50);not null" json:"old_username"`
+	NewUsername string    `gorm:"type:varchar(50);not null" json:"new_username"`
+	CreatedAt   time.Time `gorm:"index:idx_username_change_user" json:"created_at"`
+}
+
+// TableName 指定表名
+func (UsernameChange) TableName() string {
+	return "username_changes"
+}
+
+// BeforeCreate 创建前钩子
+func (u *UsernameChange) BeforeCreate(tx *gorm.DB) error {
+	if u.ID == "" {
+		u.ID = generateUUID()
+	}
+	return nil
+}
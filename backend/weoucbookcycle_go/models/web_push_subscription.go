@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WebPushSubscription 浏览器 Web Push 订阅（Service Worker 的 PushSubscription）
+type WebPushSubscription struct {
+	ID        string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	UserID    string    `gorm:"type:varchar(36);index;not null" json:"user_id"`
+	Endpoint  string    `gorm:"type:varchar(500);uniqueIndex;not null;comment:推送服务地址" json:"endpoint"`
+	P256dh    string    `gorm:"type:varchar(255);not null" json:"-"`
+	Auth      string    `gorm:"type:varchar(100);not null" json:"-"`
+	UserAgent string    `gorm:"type:varchar(255)" json:"user_agent,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (WebPushSubscription) TableName() string {
+	return "web_push_subscriptions"
+}
+
+// BeforeCreate 创建前钩子
+func (s *WebPushSubscription) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == "" {
+		s.ID = generateUUID()
+	}
+	return nil
+}
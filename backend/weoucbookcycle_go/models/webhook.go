@@ -0,0 +1,107 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// 可订阅的平台事件
+const (
+	WebhookUserRegistered = "user.registered"
+	WebhookBookCreated    = "book.created"
+	WebhookOrderCompleted = "order.completed" // 发布成交（含赠书送出）
+)
+
+// WebhookEvents 全部可订阅的事件
+var WebhookEvents = []string{WebhookUserRegistered, WebhookBookCreated, WebhookOrderCompleted}
+
+// 投递状态
+const (
+	WebhookDeliveryPending   = "pending"   // 等待投递或重试中
+	WebhookDeliverySucceeded = "succeeded" // 对方返回2xx
+	WebhookDeliveryFailed    = "failed"    // 重试用尽或订阅已停用
+)
+
+// WebhookSubscription 外部应用的 webhook 订阅，由管理员登记
+// 投递时用 Secret 对请求体做 HMAC-SHA256 签名，Secret 只在创建和重置时返回一次
+type WebhookSubscription struct {
+	ID        string         `gorm:"type:varchar(36);primaryKey" json:"id"`
+	Name      string         `gorm:"type:varchar(100);not null;comment:应用名称" json:"name"`
+	URL       string         `gorm:"type:varchar(500);not null" json:"url"`
+	Secret    string         `gorm:"type:varchar(64);not null" json:"-"`
+	Events    datatypes.JSON `gorm:"type:json;not null;comment:订阅的事件(JSON数组)" json:"events"`
+	Enabled   bool           `gorm:"default:true;index" json:"enabled"`
+	CreatedBy string         `gorm:"type:varchar(36)" json:"created_by"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// WebhookDelivery 一个事件对一个订阅的投递，按 (SubscriptionID, EventID) 去重
+type WebhookDelivery struct {
+	ID             string         `gorm:"type:varchar(36);primaryKey" json:"id"`
+	SubscriptionID string         `gorm:"type:varchar(36);not null;uniqueIndex:idx_webhook_delivery_event;index:idx_webhook_delivery_sub" json:"subscription_id"`
+	EventID        string         `gorm:"type:varchar(40);not null;uniqueIndex:idx_webhook_delivery_event;comment:领域事件流中的消息ID" json:"event_id"`
+	Event          string         `gorm:"type:varchar(50);not null" json:"event"`
+	Payload        datatypes.JSON `gorm:"type:json;not null;comment:发送的请求体" json:"payload"`
+	Status         string         `gorm:"type:varchar(20);default:pending;index" json:"status"`
+	Attempts       int            `gorm:"default:0" json:"attempts"`
+	LastStatusCode int            `json:"last_status_code,omitempty"`
+	LastError      string         `gorm:"type:varchar(500)" json:"last_error,omitempty"`
+	DeliveredAt    *time.Time     `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time      `gorm:"index:idx_webhook_delivery_sub" json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+
+	AttemptLog []WebhookDeliveryAttempt `gorm:"foreignKey:DeliveryID" json:"attempt_log,omitempty"`
+}
+
+// WebhookDeliveryAttempt 每次投递尝试的结果
+type WebhookDeliveryAttempt struct {
+	ID             string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	DeliveryID     string    `gorm:"type:varchar(36);not null;index" json:"delivery_id"`
+	SubscriptionID string    `gorm:"type:varchar(36);not null;index" json:"subscription_id"`
+	Attempt        int       `gorm:"not null;comment:第几次尝试，从1开始" json:"attempt"`
+	StatusCode     int       `gorm:"comment:对方的HTTP状态码，请求失败时为0" json:"status_code"`
+	Error          string    `gorm:"type:varchar(500)" json:"error,omitempty"`
+	DurationMs     int64     `json:"duration_ms"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (WebhookSubscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+func (WebhookDeliveryAttempt) TableName() string {
+	return "webhook_delivery_attempts"
+}
+
+// BeforeCreate 创建前钩子
+func (s *WebhookSubscription) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == "" {
+		s.ID = generateUUID()
+	}
+	return nil
+}
+
+// BeforeCreate 创建前钩子
+func (d *WebhookDelivery) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == "" {
+		d.ID = generateUUID()
+	}
+	return nil
+}
+
+// BeforeCreate 创建前钩子
+func (a *WebhookDeliveryAttempt) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == "" {
+		a.ID = generateUUID()
+	}
+	return nil
+}
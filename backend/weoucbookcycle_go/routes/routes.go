@@ -1,110 +1,414 @@
 package routes
 
 import (
-	"os"
+	"net/http"
+	"weoucbookcycle_go/config"
 	"weoucbookcycle_go/controllers"
+	_ "weoucbookcycle_go/docs" // 注册生成的 OpenAPI 文档
 	"weoucbookcycle_go/middleware"
+	"weoucbookcycle_go/utils"
 	"weoucbookcycle_go/websocket"
 
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+	"gorm.io/gorm"
 )
 
-// SetupRoutes 设置路由
-func SetupRoutes(r *gin.Engine) {
+// SetupRoutes 设置路由，ctrl 由 controllers.NewControllers 在 main 中创建，cfg 为启动时加载的配置，
+// db 和 redisClient 供认证、限流、幂等和访问日志中间件使用
+func SetupRoutes(r *gin.Engine, ctrl *controllers.Controllers, cfg *config.Config, db *gorm.DB, redisClient *redis.Client) {
 	// Note: CORS, Logger, and Recovery middleware are already applied in config/server.go:SetupRouter()
 	// Do NOT apply them again here to avoid duplication and conflicts
 
-	// API 路由组（弃用版本号或与前端环境变量保持一致）
-	// 之前使用 /api/v1，如果前端直接请求 /api，可以在这里修改。
-	api := r.Group("/api")
-	{
-		// ====== 认证路由 (无需认证) ======
-		auth := api.Group("/auth")
-		{
-			auth.POST("/register", controllers.NewAuthController().Register)
-			auth.POST("/login", controllers.NewAuthController().Login)
-			// 微信小程序登录，无需邮箱密码
-			auth.POST("/wechat", controllers.NewAuthController().WeChatLogin)
-			auth.POST("/refresh", controllers.NewAuthController().RefreshToken)
-			auth.POST("/logout", controllers.NewAuthController().Logout)
-			auth.POST("/verify-email", controllers.NewAuthController().VerifyEmail)
-			auth.POST("/resend-verification", controllers.NewAuthController().ResendVerificationCode)
-			auth.POST("/send-password-reset", controllers.NewAuthController().SendPasswordResetToken)
-			auth.POST("/reset-password", controllers.NewAuthController().ResetPassword)
-		}
-
-		// ====== 用户路由 ======
-		users := api.Group("/users")
-		{
-			users.GET("/me", middleware.AuthMiddleware(), controllers.NewUserController().GetMyProfile)
-			users.GET("/active", controllers.NewUserController().GetActiveUsers)
-			users.GET("/online", controllers.NewUserController().GetOnlineUsers)
-			users.GET("/:id", controllers.NewUserController().GetUserProfile)
-			users.PUT("/profile", middleware.AuthMiddleware(), controllers.NewUserController().UpdateUserProfile)
-			users.POST("/wishlist/toggle", middleware.AuthMiddleware(), controllers.NewUserController().ToggleWishlist)
-		}
-
-		// ====== 书籍路由 ======
-		books := api.Group("/books")
-		{
-			books.GET("", controllers.NewBookController().GetBooks)
-			books.GET("/hot", controllers.NewBookController().GetHotBooks)
-			books.GET("/search", controllers.NewBookController().SearchBooks)
-			books.GET("/recommendations", middleware.AuthMiddleware(), controllers.NewBookController().GetRecommendations)
-			books.GET("/:id", controllers.NewBookController().GetBook)
-			books.POST("", middleware.AuthMiddleware(), controllers.NewBookController().CreateBook)
-			books.PUT("/:id", middleware.AuthMiddleware(), controllers.NewBookController().UpdateBook)
-			books.DELETE("/:id", middleware.AuthMiddleware(), controllers.NewBookController().DeleteBook)
-			books.POST("/:id/like", middleware.AuthMiddleware(), controllers.NewBookController().LikeBook)
-		}
-
-		// ====== 发布路由 ======
-		listings := api.Group("/listings")
-		{
-			listings.GET("", controllers.NewListingController().GetListings)
-			listings.GET("/mine", middleware.AuthMiddleware(), controllers.NewListingController().GetMyListings)
-			listings.GET("/:id", controllers.NewListingController().GetListing)
-			listings.POST("", middleware.AuthMiddleware(), controllers.NewListingController().CreateListing)
-			listings.PUT("/:id/status", middleware.AuthMiddleware(), controllers.NewListingController().UpdateListingStatus)
-			listings.POST("/:id/favorite", middleware.AuthMiddleware(), controllers.NewListingController().FavoriteListing)
-		}
-
-		// ====== 聊天路由 ======
-		chats := api.Group("/chats")
-		{
-			chats.GET("", middleware.AuthMiddleware(), controllers.NewChatController().GetChats)
-			chats.GET("/unread", middleware.AuthMiddleware(), controllers.NewChatController().GetUnreadCount)
-			chats.GET("/online-users", middleware.AuthMiddleware(), controllers.NewChatController().GetOnlineUsers)
-			chats.GET("/:id", middleware.AuthMiddleware(), controllers.NewChatController().GetChat)
-			chats.GET("/:id/messages", middleware.AuthMiddleware(), controllers.NewChatController().GetMessages)
-			chats.POST("", middleware.AuthMiddleware(), controllers.NewChatController().CreateChat)
-			chats.POST("/:id/messages", middleware.AuthMiddleware(), controllers.NewChatController().SendMessage)
-			chats.PUT("/:id/read", middleware.AuthMiddleware(), controllers.NewChatController().MarkAsRead)
-			chats.DELETE("/:id", middleware.AuthMiddleware(), controllers.NewChatController().DeleteChat)
-		}
-
-		// ====== 搜索路由 ======
-		search := api.Group("/search")
-		{
-			search.GET("", controllers.NewSearchController().GlobalSearch)
-			search.GET("/users", controllers.NewSearchController().SearchUsers)
-			search.GET("/books", controllers.NewSearchController().SearchBooks)
-			search.GET("/hot", controllers.NewSearchController().GetHotSearchKeywords)
-			search.GET("/suggestions", controllers.NewSearchController().GetSuggestions)
-		}
-
-		// 评价卖家
-		api.POST("/evaluate", middleware.AuthMiddleware(), controllers.NewUserController().EvaluateUser)
-
-		// 对于前端自动发现后端地址或其他运行时配置
-		api.GET("/config", func(c *gin.Context) {
-			c.JSON(200, gin.H{
-				"apiBase": os.Getenv("API_BASE"),
-			})
+	// 链路追踪、访问日志（写入 access_logs 流）、Prometheus 指标、panic 恢复和错误上报、统一错误响应
+	r.Use(middleware.Tracing(&cfg.Tracing), middleware.Logger(cfg, redisClient), middleware.Metrics(), middleware.I18n(), middleware.Recovery(cfg), middleware.ErrorHandler())
+	r.GET("/metrics", middleware.MetricsHandler(cfg.Server.MetricsToken))
+
+	// ====== 接口文档 ======
+	// 文档由 main.go 中的 go:generate 根据控制器注释生成到 docs 包
+	if cfg.Server.ServeDocs {
+		r.GET("/docs", func(c *gin.Context) {
+			c.Redirect(http.StatusMovedPermanently, "/docs/index.html")
 		})
+		r.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	}
+
+	// API 路由：同一张路由表按版本挂载
+	// /api 与 /api/v1 相同，兼容未带版本号的客户端；/api/v2 只替换有破坏性变更的处理器
+	for _, mount := range apiMounts {
+		api := r.Group(mount.prefix, middleware.APIVersion(mount.version.String()))
+		registerAPIRoutes(api, ctrl, cfg, db, redisClient, mount.version)
+	}
+
+	// ====== 本地上传文件 ======
+	// 使用对象存储时文件由存储服务/CDN直接提供
+	if utils.GetStorage().Name() == "local" {
+		utils.RegisterImageMimeTypes()
+		r.Group("/uploads", middleware.UploadHeaders()).Static("/", cfg.Upload.Path)
 	}
 
 	// ====== WebSocket路由 ======
 	r.GET("/ws", websocket.HandleConnection)
 	r.GET("/ws/chat", websocket.HandleConnection)
 }
+
+// registerAPIRoutes 注册一个版本的API路由，v.handler 按版本选择处理器
+func registerAPIRoutes(api *gin.RouterGroup, ctrl *controllers.Controllers, cfg *config.Config, db *gorm.DB, redisClient *redis.Client, v apiVersion) {
+	// 上传和文件访问使用更长的超时和更大的请求体上限，其余接口使用默认限制
+	transfer := api.Group("", middleware.RequestLimits(cfg.Server.UploadTimeout, cfg.Server.MaxUploadBytes))
+	api = api.Group("", middleware.RequestLimits(cfg.Server.RequestTimeout, cfg.Server.MaxBodyBytes))
+
+	// 限流：认证接口按IP，搜索和发送消息按用户和IP
+	authLimit := middleware.RateLimit(&cfg.RateLimit, redisClient, "auth", "", cfg.RateLimit.AuthIP)
+	searchLimit := middleware.RateLimit(&cfg.RateLimit, redisClient, "search", cfg.RateLimit.SearchUser, cfg.RateLimit.SearchIP)
+	chatSendLimit := middleware.RateLimit(&cfg.RateLimit, redisClient, "chat_send", cfg.RateLimit.ChatSendUser, cfg.RateLimit.ChatSendIP)
+
+	// ====== 认证路由 (无需认证) ======
+	auth := api.Group("/auth", authLimit)
+	{
+		auth.POST("/register", ctrl.Auth.Register)
+		auth.POST("/login", ctrl.Auth.Login)
+		// 微信小程序登录，无需邮箱密码
+		auth.POST("/wechat", ctrl.Auth.WeChatLogin)
+		auth.POST("/refresh", ctrl.Auth.RefreshToken)
+		auth.POST("/logout", ctrl.Auth.Logout)
+		auth.POST("/verify-email", ctrl.Auth.VerifyEmail)
+		auth.POST("/resend-verification", ctrl.Auth.ResendVerificationCode)
+		auth.POST("/send-password-reset", ctrl.Auth.SendPasswordResetToken)
+		auth.POST("/reset-password", ctrl.Auth.ResetPassword)
+	}
+
+	// ====== 用户路由 ======
+	users := api.Group("/users")
+	{
+		users.GET("/me", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.User.GetMyProfile)
+		users.GET("/me/stats", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.User.GetMyStats)
+		users.GET("/me/points", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Points.GetMyPoints)
+		users.GET("/me/points/ledger", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Points.GetMyPointsLedger)
+		users.GET("/me/referrals", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Referral.GetMyReferrals)
+		users.GET("/me/referrals/invitees", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Referral.ListMyInvitees)
+		users.GET("/me/storage", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), v.list(utils.LegacyList{Key: "files", Wrap: true}, ctrl.User.GetMyStorage))
+		users.DELETE("/me/storage/files/:id", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.User.DeleteMyFile)
+		users.GET("/me/blocks", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Block.ListBlocks)
+		users.POST("/me/export", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.DataExport.RequestExport)
+		users.POST("/me/deactivate", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Account.Deactivate)
+		users.POST("/me/reactivate", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Account.Reactivate)
+		users.PUT("/me/username", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.User.ChangeUsername)
+		users.GET("/me/identities", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Identity.ListIdentities)
+		users.POST("/me/identities", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Identity.LinkIdentity)
+		users.POST("/me/identities/wechat", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Identity.LinkWeChat)
+		users.DELETE("/me/identities/:id", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Identity.UnlinkIdentity)
+		users.GET("/me/username/history", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.User.GetUsernameHistory)
+		users.GET("/username/available", middleware.OptionalAuthMiddleware(&cfg.JWT, db, redisClient), ctrl.User.CheckUsername)
+		users.PUT("/me/location", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Campus.UpdateMyLocation)
+		users.GET("/me/export/:id", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.DataExport.GetExport)
+		users.GET("/me/export/:id/download", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.DataExport.DownloadExport)
+		users.GET("/settings", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.User.GetMySettings)
+		users.PUT("/settings", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.User.UpdateMySettings)
+		users.GET("/active", v.list(utils.LegacyList{Key: "users"}, ctrl.User.GetActiveUsers))
+		users.GET("/online", v.list(utils.LegacyList{Key: "online_users", Wrap: true, Count: "count"}, ctrl.User.GetOnlineUsers))
+		users.GET("/:id", middleware.OptionalAuthMiddleware(&cfg.JWT, db, redisClient), ctrl.User.GetUserProfile)
+		users.GET("/:id/followers", ctrl.Follow.GetFollowers)
+		users.GET("/:id/following", ctrl.Follow.GetFollowing)
+		users.GET("/:id/reputation", ctrl.Reputation.GetReputation)
+		users.GET("/:id/badges", ctrl.User.GetUserBadges)
+		users.GET("/:id/donations", ctrl.Donation.GetDonationStats)
+		users.POST("/:id/follow", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Follow.FollowUser)
+		users.DELETE("/:id/follow", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Follow.UnfollowUser)
+		users.POST("/:id/block", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Block.BlockUser)
+		users.DELETE("/:id/block", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Block.UnblockUser)
+		users.PUT("/profile", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.User.UpdateUserProfile)
+		users.POST("/wishlist/toggle", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.User.ToggleWishlist)
+	}
+
+	// ====== 书籍路由 ======
+	books := api.Group("/books")
+	{
+		books.GET("", middleware.OptionalAuthMiddleware(&cfg.JWT, db, redisClient), v.list(utils.LegacyList{Key: "books"}, ctrl.Book.GetBooks, ctrl.Book.GetBooksV2))
+		books.GET("/hot", middleware.OptionalAuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Book.GetHotBooks)
+		books.GET("/free", middleware.OptionalAuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Donation.GetFreeBooks)
+		books.GET("/search", middleware.OptionalAuthMiddleware(&cfg.JWT, db, redisClient), searchLimit, v.list(utils.LegacyList{Key: "books"}, ctrl.Book.SearchBooks))
+		books.GET("/recommendations", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), v.list(utils.LegacyList{Key: "books", Wrap: true}, ctrl.Book.GetRecommendations))
+		books.GET("/:id", middleware.OptionalAuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Book.GetBook)
+		books.GET("/:id/qrcode", ctrl.QRCode.GetBookQRCode)
+		books.POST("", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), middleware.Idempotency(redisClient), ctrl.Book.CreateBook)
+		books.PUT("/:id", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Book.UpdateBook)
+		books.DELETE("/:id", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Book.DeleteBook)
+		books.POST("/:id/like", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Book.LikeBook)
+	}
+
+	// ====== 发布路由 ======
+	listings := api.Group("/listings")
+	{
+		listings.GET("", middleware.OptionalAuthMiddleware(&cfg.JWT, db, redisClient), v.list(utils.LegacyList{Key: "listings"}, ctrl.Listing.GetListings, ctrl.Listing.GetListingsV2))
+		listings.GET("/mine", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), v.list(utils.LegacyList{Key: "listings"}, ctrl.Listing.GetMyListings, ctrl.Listing.GetMyListingsV2))
+		listings.GET("/:id", middleware.OptionalAuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Listing.GetListing)
+		listings.GET("/:id/qrcode", ctrl.QRCode.GetListingQRCode)
+		listings.POST("", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), middleware.Idempotency(redisClient), ctrl.Listing.CreateListing)
+		listings.PUT("/:id/status", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Listing.UpdateListingStatus)
+		listings.POST("/:id/favorite", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Listing.FavoriteListing)
+		listings.POST("/:id/bump", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Points.BumpListing)
+		listings.POST("/:id/claim", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Donation.ClaimDonation)
+		listings.DELETE("/:id/claim", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Donation.CancelClaim)
+	}
+
+	// ====== 绿色积分路由 ======
+	points := api.Group("/points")
+	{
+		points.GET("/perks", ctrl.Points.ListPerks)
+		points.GET("/redemptions", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Points.ListMyRedemptions)
+		points.POST("/redemptions", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), middleware.Idempotency(redisClient), ctrl.Points.Redeem)
+	}
+
+	// ====== 聊天路由 ======
+	chats := api.Group("/chats")
+	{
+		chats.GET("", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), v.list(utils.LegacyList{Key: "chats"}, ctrl.Chat.GetChats))
+		chats.GET("/unread", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Chat.GetUnreadCount)
+		chats.GET("/online-users", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), v.list(utils.LegacyList{Key: "online_users", Wrap: true, Count: "count"}, ctrl.Chat.GetOnlineUsers))
+		chats.GET("/:id", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Chat.GetChat)
+		chats.GET("/:id/messages", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), v.list(utils.LegacyList{Key: "messages"}, ctrl.Chat.GetMessages, ctrl.Chat.GetMessagesV2))
+		chats.POST("", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), middleware.Idempotency(redisClient), ctrl.Chat.CreateChat)
+		chats.POST("/:id/messages", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), chatSendLimit, middleware.Idempotency(redisClient), ctrl.Chat.SendMessage)
+		chats.PUT("/:id/read", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Chat.MarkAsRead)
+		chats.DELETE("/:id", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Chat.DeleteChat)
+	}
+
+	// ====== 搜索路由 ======
+	search := api.Group("/search")
+	{
+		// 全局搜索不是单个列表，v1 只保留各类型原来的 pagination 格式
+		search.GET("", middleware.OptionalAuthMiddleware(&cfg.JWT, db, redisClient), searchLimit, v.list(utils.LegacyList{}, ctrl.Search.GlobalSearch))
+		search.GET("/users", middleware.OptionalAuthMiddleware(&cfg.JWT, db, redisClient), searchLimit, v.list(utils.LegacyList{Key: "users"}, ctrl.Search.SearchUsers))
+		search.GET("/books", middleware.OptionalAuthMiddleware(&cfg.JWT, db, redisClient), searchLimit, v.list(utils.LegacyList{Key: "books"}, ctrl.Search.SearchBooks))
+		search.POST("/click", middleware.OptionalAuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Search.RecordClick)
+		search.GET("/hot", v.list(utils.LegacyList{Key: "keywords"}, ctrl.Search.GetHotSearchKeywords))
+		search.GET("/suggestions", middleware.OptionalAuthMiddleware(&cfg.JWT, db, redisClient), searchLimit, ctrl.Search.GetSuggestions)
+
+		// 保存的搜索
+		search.GET("/saved", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), v.list(utils.LegacyList{Key: "searches", Wrap: true, Count: "total"}, ctrl.SavedSearch.ListSavedSearches))
+		search.POST("/saved", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.SavedSearch.CreateSavedSearch)
+		search.PUT("/saved/:id", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.SavedSearch.UpdateSavedSearch)
+		search.DELETE("/saved/:id", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.SavedSearch.DeleteSavedSearch)
+	}
+
+	// ====== 通知路由 ======
+	notifications := api.Group("/notifications", middleware.AuthMiddleware(&cfg.JWT, db, redisClient))
+	{
+		notifications.GET("", v.list(utils.LegacyList{Key: "notifications", Wrap: true}, ctrl.Notification.GetNotifications, ctrl.Notification.GetNotificationsV2))
+		notifications.GET("/unread-count", ctrl.Notification.GetUnreadCount)
+		notifications.PUT("/read-all", ctrl.Notification.MarkAllNotificationsRead)
+		notifications.PUT("/:id/read", ctrl.Notification.MarkNotificationRead)
+		notifications.DELETE("", ctrl.Notification.DeleteReadNotifications)
+		notifications.DELETE("/:id", ctrl.Notification.DeleteNotification)
+
+		// 移动端推送设备
+		notifications.POST("/devices", ctrl.Notification.RegisterDevice)
+		notifications.DELETE("/devices/:token", ctrl.Notification.UnregisterDevice)
+
+		// 浏览器推送（Web Push）
+		notifications.GET("/webpush/key", ctrl.Notification.GetWebPushKey)
+		notifications.POST("/webpush/subscriptions", ctrl.Notification.SubscribeWebPush)
+		notifications.DELETE("/webpush/subscriptions", ctrl.Notification.UnsubscribeWebPush)
+	}
+
+	// ====== 管理员路由 ======
+	admin := api.Group("/admin", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), middleware.RequireRole("admin"))
+	{
+		// 运行时参数
+		admin.GET("/settings", ctrl.SystemSettings.ListSettings)
+		admin.PUT("/settings/:key", ctrl.SystemSettings.UpdateSetting)
+		admin.DELETE("/settings/:key", ctrl.SystemSettings.ResetSetting)
+
+		// 平台统计
+		admin.GET("/stats/overview", ctrl.Stats.GetOverview)
+		admin.GET("/stats/daily", ctrl.Stats.GetDailyStats)
+
+		// 长期报表（从Redis流导入MySQL）
+		admin.GET("/reporting/summary", ctrl.Reporting.GetSummary)
+		admin.GET("/reporting/events", ctrl.Reporting.ListEvents)
+
+		// 数据导出
+		admin.POST("/exports", ctrl.Export.CreateExport)
+		admin.GET("/exports/:id/download", ctrl.Export.DownloadExport)
+
+		// 队列监控
+		admin.GET("/monitor/queues", ctrl.Monitor.GetQueues)
+		admin.GET("/monitor/cron", ctrl.Monitor.GetCronJobs)
+		admin.GET("/monitor/breakers", ctrl.Monitor.GetBreakers)
+
+		// 缓存管理
+		admin.GET("/cache/stats", ctrl.Cache.GetCacheStats)
+		admin.DELETE("/cache/stats", ctrl.Cache.ResetCacheStats)
+		admin.POST("/cache/invalidate", ctrl.Cache.InvalidateCache)
+
+		// 公告
+		admin.GET("/announcements", v.list(utils.LegacyList{Key: "announcements", Wrap: true}, ctrl.Announcement.ListAnnouncements))
+		admin.POST("/announcements", ctrl.Announcement.CreateAnnouncement)
+		admin.PUT("/announcements/:id", ctrl.Announcement.UpdateAnnouncement)
+		admin.DELETE("/announcements/:id", ctrl.Announcement.DeleteAnnouncement)
+
+		// 校区和宿舍区
+		admin.GET("/campuses", ctrl.Campus.AdminListCampuses)
+		admin.POST("/campuses", ctrl.Campus.CreateCampus)
+		admin.PUT("/campuses/:id", ctrl.Campus.UpdateCampus)
+		admin.DELETE("/campuses/:id", ctrl.Campus.DeleteCampus)
+		admin.POST("/campuses/:id/dorm-areas", ctrl.Campus.CreateDormArea)
+		admin.PUT("/dorm-areas/:id", ctrl.Campus.UpdateDormArea)
+		admin.DELETE("/dorm-areas/:id", ctrl.Campus.DeleteDormArea)
+		admin.POST("/campuses/:id/pickup-points", ctrl.Campus.CreatePickupPoint)
+		admin.PUT("/pickup-points/:id", ctrl.Campus.UpdatePickupPoint)
+		admin.DELETE("/pickup-points/:id", ctrl.Campus.DeletePickupPoint)
+
+		// 发送失败的邮件
+		admin.GET("/emails/dead-letters", v.list(utils.LegacyList{Key: "dead_letters", Wrap: true}, ctrl.EmailDeadLetter.ListDeadLetters))
+		admin.GET("/emails/dead-letters/:id", ctrl.EmailDeadLetter.GetDeadLetter)
+		admin.POST("/emails/dead-letters/:id/retry", ctrl.EmailDeadLetter.RetryDeadLetter)
+		admin.DELETE("/emails/dead-letters/:id", ctrl.EmailDeadLetter.DiscardDeadLetter)
+
+		// 外部应用 webhook 订阅
+		admin.GET("/webhooks", ctrl.Webhook.ListWebhooks)
+		admin.POST("/webhooks", ctrl.Webhook.CreateWebhook)
+		admin.PUT("/webhooks/:id", ctrl.Webhook.UpdateWebhook)
+		admin.DELETE("/webhooks/:id", ctrl.Webhook.DeleteWebhook)
+		admin.POST("/webhooks/:id/secret", ctrl.Webhook.RotateWebhookSecret)
+		admin.GET("/webhooks/:id/deliveries", ctrl.Webhook.ListWebhookDeliveries)
+
+		// 访问日志
+		admin.GET("/access-logs", v.list(utils.LegacyList{Key: "logs", Wrap: true}, ctrl.AccessLog.ListAccessLogs))
+		admin.GET("/access-logs/summary", ctrl.AccessLog.GetAccessLogSummary)
+
+		// 安全事件
+		admin.GET("/security/events", v.list(utils.LegacyList{Key: "events", Wrap: true}, ctrl.Security.ListEvents))
+		admin.GET("/security/events/live", ctrl.Security.ListLiveEvents)
+
+		// 用户管理
+		admin.GET("/users", v.list(utils.LegacyList{Key: "users", Wrap: true}, ctrl.Admin.ListUsers))
+		admin.PUT("/users/:id/status", ctrl.Admin.UpdateUserStatus)
+		admin.PUT("/users/:id/role", ctrl.Admin.UpdateUserRole)
+
+		// 代登录（排查用户问题）
+		admin.POST("/impersonations", ctrl.Impersonation.StartImpersonation)
+		admin.GET("/impersonations", v.list(utils.LegacyList{Key: "sessions", Wrap: true}, ctrl.Impersonation.ListSessions))
+		admin.GET("/impersonations/:id/logs", v.list(utils.LegacyList{Key: "logs", Wrap: true}, ctrl.Impersonation.ListAuditLogs))
+		admin.DELETE("/impersonations/:id", ctrl.Impersonation.EndImpersonation)
+
+		// 书籍与发布管理
+		admin.GET("/books", v.list(utils.LegacyList{Key: "books", Wrap: true}, ctrl.Admin.ListBooks))
+		admin.PUT("/books/:id/status", ctrl.Admin.UpdateBookStatus)
+		admin.DELETE("/books/:id", ctrl.Admin.DeleteBook)
+		admin.GET("/listings", v.list(utils.LegacyList{Key: "listings", Wrap: true}, ctrl.Admin.ListListings))
+		admin.PUT("/listings/:id/status", ctrl.Admin.UpdateListingStatus)
+
+		// 聊天管理
+		admin.GET("/chats", v.list(utils.LegacyList{Key: "chats", Wrap: true}, ctrl.Admin.ListChats))
+		admin.GET("/chats/:id/messages", v.list(utils.LegacyList{Key: "messages", Wrap: true}, ctrl.Admin.GetChatMessages))
+		admin.DELETE("/messages/:id", ctrl.Admin.DeleteMessage)
+
+		// 举报处理
+		admin.GET("/reports", v.list(utils.LegacyList{Key: "reports", Wrap: true}, ctrl.Admin.ListReports))
+		admin.PUT("/reports/:id", ctrl.Admin.HandleReport)
+
+		// 统一审核队列
+		admin.GET("/moderation", v.list(utils.LegacyList{Key: "items", Wrap: true}, ctrl.Moderation.ListQueue))
+		admin.GET("/moderation/:id", ctrl.Moderation.GetItem)
+		admin.POST("/moderation/:id/assign", ctrl.Moderation.AssignItem)
+		admin.POST("/moderation/:id/escalate", ctrl.Moderation.EscalateItem)
+		admin.POST("/moderation/:id/resolve", ctrl.Moderation.ResolveItem)
+
+		// 搜索同义词管理
+		admin.GET("/search/synonyms", v.list(utils.LegacyList{Key: "synonyms", Wrap: true, Count: "total"}, ctrl.Synonym.ListSynonyms))
+		admin.POST("/search/synonyms", ctrl.Synonym.CreateSynonym)
+		admin.POST("/search/synonyms/reload", ctrl.Synonym.ReloadSynonyms)
+		admin.PUT("/search/synonyms/:id", ctrl.Synonym.UpdateSynonym)
+		admin.DELETE("/search/synonyms/:id", ctrl.Synonym.DeleteSynonym)
+
+		// 搜索分析
+		admin.GET("/search/analytics/top-queries", ctrl.SearchAnalytics.GetTopQueries)
+		admin.GET("/search/analytics/zero-results", ctrl.SearchAnalytics.GetZeroResultQueries)
+		admin.GET("/search/analytics/ctr", ctrl.SearchAnalytics.GetClickThroughRate)
+
+		// 搜索索引管理
+		admin.POST("/search/reindex", ctrl.SearchIndex.Reindex)
+		admin.GET("/search/index/health", ctrl.SearchIndex.GetIndexHealth)
+
+		// 上传文件维护
+		admin.POST("/uploads/thumbnails/backfill", ctrl.Upload.BackfillThumbnails)
+
+		// 通知事件回放
+		admin.POST("/notifications/replay", ctrl.Notification.ReplayEvents)
+
+		// 绿色积分
+		admin.GET("/points/redemptions", ctrl.Points.AdminListRedemptions)
+		admin.PUT("/points/redemptions/:id", ctrl.Points.HandleRedemption)
+		admin.POST("/users/:id/points", ctrl.Points.AdjustPoints)
+	}
+
+	// ====== 上传路由 ======
+	uploads := transfer.Group("/uploads", middleware.AuthMiddleware(&cfg.JWT, db, redisClient))
+	{
+		uploads.POST("/images", ctrl.Upload.UploadImage)
+		uploads.POST("/images/batch", ctrl.Upload.UploadImages)
+		uploads.POST("/private", ctrl.Upload.UploadPrivateImage)
+
+		// 分片上传（断点续传）
+		uploads.POST("/chunked", ctrl.Upload.InitChunkedUpload)
+		uploads.GET("/chunked/:id", ctrl.Upload.GetChunkedUpload)
+		uploads.PUT("/chunked/:id/chunks/:index", ctrl.Upload.UploadChunk)
+		uploads.POST("/chunked/:id/complete", ctrl.Upload.CompleteChunkedUpload)
+		uploads.DELETE("/chunked/:id", ctrl.Upload.AbortChunkedUpload)
+	}
+
+	// 头像上传同样按上传接口的超时和大小限制
+	transfer.POST("/users/me/avatar", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.User.UploadAvatar)
+
+	// ====== 文件访问 ======
+	files := transfer.Group("/files")
+	{
+		// 签名URL自带授权，无需登录
+		files.GET("/signed/*key", ctrl.File.ServeSignedFile)
+		files.GET("/:id", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.File.GetFile)
+	}
+
+	// ====== 全站公告 ======
+	api.GET("/announcements", v.list(utils.LegacyList{Key: "announcements", Wrap: true}, ctrl.Announcement.GetActiveAnnouncements))
+
+	// ====== 校区 ======
+	api.GET("/campuses", ctrl.Campus.ListCampuses)
+
+	// ====== 排行榜 ======
+	api.GET("/leaderboards", ctrl.Leaderboard.GetLeaderboards)
+
+	// ====== 分享预览（供微信、QQ等抓取，无需登录） ======
+	share := api.Group("/share")
+	{
+		share.GET("/books/:id", ctrl.Share.GetBookShareCard)
+		share.GET("/listings/:id", ctrl.Share.GetListingShareCard)
+	}
+
+	// ====== 关注动态 ======
+	api.GET("/feed", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Follow.GetFeed)
+
+	// ====== 举报 ======
+	api.POST("/reports", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), middleware.Idempotency(redisClient), ctrl.Report.CreateReport)
+
+	// ====== 异步任务 ======
+	api.GET("/tasks/:id", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.Task.GetTask)
+
+	// 评价卖家
+	api.POST("/evaluate", middleware.AuthMiddleware(&cfg.JWT, db, redisClient), ctrl.User.EvaluateUser)
+
+	// 对于前端自动发现后端地址或其他运行时配置
+	api.GET("/config", func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"apiBase": cfg.APIBase,
+			// 小程序调用 wx.requestSubscribeMessage 时使用的订阅消息模板
+			"wechatTemplates": gin.H{
+				"chat":  cfg.WeChat.TemplateChat,
+				"order": cfg.WeChat.TemplateOrder,
+			},
+		})
+	})
+}
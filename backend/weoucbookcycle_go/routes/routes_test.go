@@ -0,0 +1,76 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"weoucbookcycle_go/config"
+	"weoucbookcycle_go/controllers"
+	"weoucbookcycle_go/services"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupTestRouter(t *testing.T, serveDocs bool) *gin.Engine {
+	t.Helper()
+	os.Setenv("JWT_SECRET", "test-secret")
+	cfg, err := config.FromEnv()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	cfg.Server.ServeDocs = serveDocs
+	utils.InitStorage(cfg)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	SetupRoutes(r, controllers.NewControllers(services.NewServices(services.Deps{Config: cfg}), nil, nil), cfg, nil, nil)
+	return r
+}
+
+func getDocs(t *testing.T, r *gin.Engine) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs/doc.json", nil))
+	return w
+}
+
+func TestDocsDisabledByDefault(t *testing.T) {
+	if w := getDocs(t, setupTestRouter(t, false)); w.Code != http.StatusNotFound {
+		t.Errorf("docs served with SERVE_DOCS=false: status %d", w.Code)
+	}
+}
+
+// TestDocsMatchRoutes 文档中的每个接口都必须是实际注册的路由，防止注释中的路径过时
+func TestDocsMatchRoutes(t *testing.T) {
+	r := setupTestRouter(t, true)
+	w := getDocs(t, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /docs/doc.json: status %d", w.Code)
+	}
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	// gin 的 :id 和 *key 在文档中都写作 {id}、{key}
+	param := regexp.MustCompile(`[:*]([A-Za-z_]+)`)
+	registered := make(map[string]bool)
+	for _, route := range r.Routes() {
+		registered[route.Method+" "+param.ReplaceAllString(route.Path, "{$1}")] = true
+	}
+
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			if key := strings.ToUpper(method) + " " + path; !registered[key] {
+				t.Errorf("documented route %s is not registered", key)
+			}
+		}
+	}
+}
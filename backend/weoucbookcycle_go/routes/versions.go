@@ -0,0 +1,50 @@
+package routes
+
+import (
+	"strconv"
+	"weoucbookcycle_go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiVersion API版本号
+type apiVersion int
+
+const (
+	apiV1 apiVersion = 1
+	// apiV2 列表接口改为游标分页（cursor/next_cursor），不再返回 total/page；
+	// 其余列表接口统一返回 utils.PageResponse
+	apiV2 apiVersion = 2
+)
+
+// apiMounts 各版本的路由前缀，不带版本号的 /api 等同于 v1
+var apiMounts = []struct {
+	prefix  string
+	version apiVersion
+}{
+	{"/api", apiV1},
+	{"/api/v1", apiV1},
+	{"/api/v2", apiV2},
+}
+
+func (v apiVersion) String() string {
+	return strconv.Itoa(int(v))
+}
+
+// handler 按版本选择处理器，handlers[i] 对应 v(i+1)
+// 新版本没有变更的接口不需要重复传入，沿用最近一个旧版本的处理器
+func (v apiVersion) handler(handlers ...gin.HandlerFunc) gin.HandlerFunc {
+	if int(v) <= len(handlers) {
+		return handlers[v-1]
+	}
+	return handlers[len(handlers)-1]
+}
+
+// list 在v2引入统一分页响应之前就有的列表接口：v1 按 legacy 的原格式输出，之后的版本使用统一分页响应
+// handlers 同 handler，v1 的处理器会被 legacy 包装
+func (v apiVersion) list(legacy utils.LegacyList, handlers ...gin.HandlerFunc) gin.HandlerFunc {
+	if v == apiV1 {
+		return legacy.Handler(handlers[0])
+	}
+	return v.handler(handlers...)
+}
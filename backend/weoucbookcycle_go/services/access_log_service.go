@@ -0,0 +1,344 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"weoucbookcycle_go/utils"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	accessLogStream = "access_logs"
+	// accessLogReadBatch 每次从流中读取的条数
+	accessLogReadBatch = 1000
+	// accessLogMaxScan 单次查询最多扫描的日志条数，过滤条件很严格时避免扫完整个流
+	accessLogMaxScan = 20000
+	// accessLogMinRequests 计算端点错误率时要求的最少请求数，避免偶发一次失败排到最前
+	accessLogMinRequests = 10
+)
+
+var (
+	// uuidSegment/numericSegment 路径中的资源ID，聚合时替换为 :id
+	uuidSegment    = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	numericSegment = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// ErrInvalidStatusFilter 状态码过滤格式错误
+var ErrInvalidStatusFilter = utils.NewError(http.StatusBadRequest, "invalid status filter, expected e.g. 404 or 5xx")
+
+// AccessLogEntry 访问日志（与 middleware.AccessLog 的JSON一致）
+type AccessLogEntry struct {
+	ID             string    `json:"id"`
+	Time           time.Time `json:"time"`
+	Method         string    `json:"method"`
+	Path           string    `json:"path"`
+	Query          string    `json:"query,omitempty"`
+	IP             string    `json:"ip"`
+	UserAgent      string    `json:"user_agent,omitempty"`
+	StatusCode     int       `json:"status_code"`
+	Latency        int64     `json:"latency_ms"`
+	UserID         string    `json:"user_id,omitempty"`
+	ImpersonatorID string    `json:"impersonator_id,omitempty"`
+	RequestID      string    `json:"request_id,omitempty"`
+	TraceID        string    `json:"trace_id,omitempty"`
+	Error          string    `json:"error,omitempty"`
+}
+
+// AccessLogQuery 访问日志查询条件
+// Path 以 * 结尾时按前缀匹配；Status 可以是具体状态码（404）或状态类（5xx）
+type AccessLogQuery struct {
+	Path       string
+	Method     string
+	Status     string
+	UserID     string
+	TraceID    string
+	MinLatency int64
+	Since      time.Time
+	Cursor     string
+	Limit      int
+}
+
+// AccessLogPage 访问日志查询结果，NextCursor 为空表示已到达时间窗口起点
+type AccessLogPage struct {
+	Logs       []AccessLogEntry `json:"logs"`
+	Scanned    int              `json:"scanned"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+// EndpointStat 端点的请求统计，Endpoint 形如 "GET /api/books/:id"
+type EndpointStat struct {
+	Endpoint     string  `json:"endpoint"`
+	Requests     int64   `json:"requests"`
+	ServerErrors int64   `json:"server_errors"`
+	ClientErrors int64   `json:"client_errors"`
+	ErrorRate    float64 `json:"error_rate"` // 5xx 比例
+	AvgLatency   int64   `json:"avg_latency_ms"`
+	P95Latency   int64   `json:"p95_latency_ms"`
+	MaxLatency   int64   `json:"max_latency_ms"`
+
+	latencies []int64
+}
+
+// AccessLogSummary 时间窗口内的访问汇总
+type AccessLogSummary struct {
+	Since        time.Time      `json:"since"`
+	Requests     int64          `json:"requests"`
+	ServerErrors int64          `json:"server_errors"`
+	ClientErrors int64          `json:"client_errors"`
+	ErrorRate    float64        `json:"error_rate"`
+	Slowest      []EndpointStat `json:"slowest"`     // 按 p95 延迟倒序
+	MostErrors   []EndpointStat `json:"most_errors"` // 按 5xx 比例倒序
+}
+
+// AccessLogService 访问日志查询服务
+type AccessLogService struct {
+	redisClient *redis.Client
+}
+
+// NewAccessLogService 创建访问日志服务实例
+func NewAccessLogService(deps Deps) *AccessLogService {
+	return &AccessLogService{
+		redisClient: deps.Redis,
+	}
+}
+
+// Query 从新到旧扫描 access_logs 流，返回满足条件的日志
+// 过滤在读取后进行，单次最多扫描 accessLogMaxScan 条，未扫完时通过 NextCursor 继续
+func (als *AccessLogService) Query(ctx context.Context, q *AccessLogQuery) (*AccessLogPage, error) {
+	if als.redisClient == nil {
+		return nil, errors.New("redis not available")
+	}
+	statusMatch, err := parseStatusFilter(q.Status)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &AccessLogPage{Logs: []AccessLogEntry{}}
+	end := "+"
+	if q.Cursor != "" {
+		end = "(" + q.Cursor
+	}
+	start := "-"
+	if !q.Since.IsZero() {
+		start = strconv.FormatInt(q.Since.UnixMilli(), 10)
+	}
+
+	for page.Scanned < accessLogMaxScan {
+		messages, err := als.redisClient.XRevRangeN(ctx, accessLogStream, end, start, accessLogReadBatch).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read access logs: %w", err)
+		}
+
+		for i, msg := range messages {
+			page.Scanned++
+			entry := accessLogFromMessage(msg)
+			if !q.matches(&entry, statusMatch) {
+				continue
+			}
+			page.Logs = append(page.Logs, entry)
+			if len(page.Logs) == q.Limit {
+				// 本批还有未检查的消息或流中还有更早的消息
+				if i < len(messages)-1 || len(messages) == accessLogReadBatch {
+					page.NextCursor = msg.ID
+				}
+				return page, nil
+			}
+		}
+
+		if len(messages) < accessLogReadBatch {
+			return page, nil
+		}
+		end = "(" + messages[len(messages)-1].ID
+	}
+
+	page.NextCursor = strings.TrimPrefix(end, "(")
+	return page, nil
+}
+
+// matches 日志是否满足查询条件
+func (q *AccessLogQuery) matches(e *AccessLogEntry, statusMatch func(int) bool) bool {
+	if q.Path != "" {
+		if prefix, ok := strings.CutSuffix(q.Path, "*"); ok {
+			if !strings.HasPrefix(e.Path, prefix) {
+				return false
+			}
+		} else if e.Path != q.Path {
+			return false
+		}
+	}
+	if q.Method != "" && !strings.EqualFold(e.Method, q.Method) {
+		return false
+	}
+	if q.UserID != "" && e.UserID != q.UserID {
+		return false
+	}
+	if q.TraceID != "" && e.TraceID != q.TraceID {
+		return false
+	}
+	if q.MinLatency > 0 && e.Latency < q.MinLatency {
+		return false
+	}
+	return statusMatch(e.StatusCode)
+}
+
+// parseStatusFilter 解析状态码过滤：404 / 4xx / 空
+func parseStatusFilter(status string) (func(int) bool, error) {
+	if status == "" {
+		return func(int) bool { return true }, nil
+	}
+	if len(status) == 3 && strings.HasSuffix(strings.ToLower(status), "xx") {
+		class := int(status[0] - '0')
+		if class < 1 || class > 5 {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidStatusFilter, status)
+		}
+		return func(code int) bool { return code/100 == class }, nil
+	}
+	code, err := strconv.Atoi(status)
+	if err != nil || code < 100 || code > 599 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidStatusFilter, status)
+	}
+	return func(c int) bool { return c == code }, nil
+}
+
+// Summary 统计时间窗口内的错误率和最慢的端点
+// 流最多保留10万条日志（见 middleware.Logger），全部读入内存统计
+func (als *AccessLogService) Summary(ctx context.Context, since time.Time, top int) (*AccessLogSummary, error) {
+	if als.redisClient == nil {
+		return nil, errors.New("redis not available")
+	}
+
+	summary := &AccessLogSummary{Since: since}
+	endpoints := make(map[string]*EndpointStat)
+
+	end := "+"
+	start := strconv.FormatInt(since.UnixMilli(), 10)
+	for {
+		messages, err := als.redisClient.XRevRangeN(ctx, accessLogStream, end, start, accessLogReadBatch).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read access logs: %w", err)
+		}
+
+		for _, msg := range messages {
+			entry := accessLogFromMessage(msg)
+			key := entry.Method + " " + normalizeEndpointPath(entry.Path)
+			stat, ok := endpoints[key]
+			if !ok {
+				stat = &EndpointStat{Endpoint: key}
+				endpoints[key] = stat
+			}
+			stat.add(entry)
+
+			summary.Requests++
+			switch {
+			case entry.StatusCode >= 500:
+				summary.ServerErrors++
+			case entry.StatusCode >= 400:
+				summary.ClientErrors++
+			}
+		}
+
+		if len(messages) < accessLogReadBatch {
+			break
+		}
+		end = "(" + messages[len(messages)-1].ID
+	}
+	summary.ErrorRate = ratio(summary.ServerErrors, summary.Requests)
+
+	stats := make([]EndpointStat, 0, len(endpoints))
+	for _, stat := range endpoints {
+		stat.finish()
+		stats = append(stats, *stat)
+	}
+
+	slowest := slices.Clone(stats)
+	slices.SortFunc(slowest, func(a, b EndpointStat) int {
+		if a.P95Latency != b.P95Latency {
+			return int(b.P95Latency - a.P95Latency)
+		}
+		return strings.Compare(a.Endpoint, b.Endpoint)
+	})
+	summary.Slowest = slowest[:min(top, len(slowest))]
+
+	errorProne := slices.DeleteFunc(stats, func(s EndpointStat) bool {
+		return s.ServerErrors == 0 || s.Requests < accessLogMinRequests
+	})
+	slices.SortFunc(errorProne, func(a, b EndpointStat) int {
+		if a.ErrorRate != b.ErrorRate {
+			if a.ErrorRate > b.ErrorRate {
+				return -1
+			}
+			return 1
+		}
+		return int(b.ServerErrors - a.ServerErrors)
+	})
+	summary.MostErrors = errorProne[:min(top, len(errorProne))]
+
+	return summary, nil
+}
+
+// add 累加一条日志
+func (s *EndpointStat) add(e AccessLogEntry) {
+	s.Requests++
+	switch {
+	case e.StatusCode >= 500:
+		s.ServerErrors++
+	case e.StatusCode >= 400:
+		s.ClientErrors++
+	}
+	s.latencies = append(s.latencies, e.Latency)
+}
+
+// finish 计算延迟分位数和错误率
+func (s *EndpointStat) finish() {
+	s.ErrorRate = ratio(s.ServerErrors, s.Requests)
+	if len(s.latencies) == 0 {
+		return
+	}
+	slices.Sort(s.latencies)
+	var sum int64
+	for _, l := range s.latencies {
+		sum += l
+	}
+	s.AvgLatency = sum / int64(len(s.latencies))
+	s.P95Latency = s.latencies[(len(s.latencies)*95+99)/100-1]
+	s.MaxLatency = s.latencies[len(s.latencies)-1]
+	s.latencies = nil
+}
+
+// normalizeEndpointPath 把路径中的ID段替换为 :id，使同一接口的请求聚合在一起
+func normalizeEndpointPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if uuidSegment.MatchString(seg) || numericSegment.MatchString(seg) {
+			segments[i] = ":id"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// accessLogFromMessage 解析流消息，优先使用完整的 full_data
+func accessLogFromMessage(msg redis.XMessage) AccessLogEntry {
+	var entry AccessLogEntry
+	if data := streamString(msg.Values["full_data"]); data == "" || json.Unmarshal([]byte(data), &entry) != nil {
+		entry = AccessLogEntry{
+			Time:       streamTime(msg.Values["timestamp"]),
+			Method:     streamString(msg.Values["method"]),
+			Path:       streamString(msg.Values["path"]),
+			IP:         streamString(msg.Values["ip"]),
+			StatusCode: int(streamInt64(msg.Values["status_code"])),
+			Latency:    streamInt64(msg.Values["latency_ms"]),
+			UserID:     streamString(msg.Values["user_id"]),
+		}
+	}
+	entry.ID = msg.ID
+	return entry
+}
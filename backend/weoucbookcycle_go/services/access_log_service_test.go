@@ -0,0 +1,54 @@
+package services
+
+import "testing"
+
+// 路径中的UUID和数字ID合并为 :id
+func TestNormalizeEndpointPath(t *testing.T) {
+	cases := map[string]string{
+		"/api/books/0b8f6a52-1c3e-4f4e-9a43-2f5d3c1b7e90":            "/api/books/:id",
+		"/api/chats/42/messages":                                     "/api/chats/:id/messages",
+		"/api/books/hot":                                             "/api/books/hot",
+		"/api/admin/users/0B8F6A52-1C3E-4F4E-9A43-2F5D3C1B7E90/role": "/api/admin/users/:id/role",
+	}
+	for path, want := range cases {
+		if got := normalizeEndpointPath(path); got != want {
+			t.Errorf("normalizeEndpointPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+// 状态码过滤支持具体状态码和状态类
+func TestParseStatusFilter(t *testing.T) {
+	match, err := parseStatusFilter("5xx")
+	if err != nil || !match(502) || match(404) {
+		t.Fatalf("5xx filter mismatch: err=%v", err)
+	}
+	match, err = parseStatusFilter("404")
+	if err != nil || !match(404) || match(403) {
+		t.Fatalf("404 filter mismatch: err=%v", err)
+	}
+	for _, bad := range []string{"6xx", "abc", "99"} {
+		if _, err := parseStatusFilter(bad); err == nil {
+			t.Errorf("parseStatusFilter(%q) should fail", bad)
+		}
+	}
+}
+
+// p95 取排序后第95百分位的延迟
+func TestEndpointStatFinish(t *testing.T) {
+	stat := &EndpointStat{}
+	for i := int64(1); i <= 100; i++ {
+		status := 200
+		if i%10 == 0 {
+			status = 500
+		}
+		stat.add(AccessLogEntry{StatusCode: status, Latency: i})
+	}
+	stat.finish()
+	if stat.P95Latency != 95 || stat.MaxLatency != 100 || stat.AvgLatency != 50 {
+		t.Fatalf("unexpected latency stats: %+v", stat)
+	}
+	if stat.ServerErrors != 10 || stat.ErrorRate != 0.1 {
+		t.Fatalf("unexpected error stats: %+v", stat)
+	}
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAccountNotActive      = utils.NewError(http.StatusConflict, "only active accounts can be deactivated")
+	ErrAccountNotDeactivated = utils.NewError(http.StatusConflict, "account is not deactivated")
+	// ErrRecipientDeactivated 接收者已停用账号，通知被丢弃
+	ErrRecipientDeactivated = errors.New("recipient account is deactivated")
+)
+
+// AccountService 账号停用和重新启用
+// 停用与注销不同：数据全部保留，用户登录后可以随时重新启用
+type AccountService struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+}
+
+// NewAccountService 创建账号服务实例
+func NewAccountService(deps Deps) *AccountService {
+	return &AccountService{
+		db:          deps.DB,
+		redisClient: deps.Redis,
+	}
+}
+
+// Deactivate 停用自己的账号，只有正常状态的账号可以停用
+func (as *AccountService) Deactivate(ctx context.Context, userID string) (*models.User, error) {
+	return as.setStatus(ctx, userID, models.UserStatusActive, models.UserStatusDeactivated, ErrAccountNotActive)
+}
+
+// Reactivate 重新启用已停用的账号
+func (as *AccountService) Reactivate(ctx context.Context, userID string) (*models.User, error) {
+	return as.setStatus(ctx, userID, models.UserStatusDeactivated, models.UserStatusActive, ErrAccountNotDeactivated)
+}
+
+// setStatus 在状态为 from 时改为 to，并清除展示该用户数据的缓存
+func (as *AccountService) setStatus(ctx context.Context, userID string, from, to int, conflict error) (*models.User, error) {
+	updates := map[string]interface{}{"status": to, "deactivated_at": nil}
+	if to == models.UserStatusDeactivated {
+		updates["deactivated_at"] = time.Now()
+	}
+	result := as.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND status = ?", userID, from).Updates(updates)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to update account status: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, conflict
+	}
+
+	var user models.User
+	if err := as.db.WithContext(ctx).First(&user, "id = ?", userID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+	as.invalidateCaches(ctx, userID)
+	return &user, nil
+}
+
+// invalidateCaches 清除用户资料、书籍和发布详情以及列表缓存
+func (as *AccountService) invalidateCaches(ctx context.Context, userID string) {
+	if as.redisClient == nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+
+	keys := []string{"user:" + userID}
+	var bookIDs, listingIDs []string
+	as.db.WithContext(ctx).Model(&models.Book{}).Where("seller_id = ?", userID).Pluck("id", &bookIDs)
+	as.db.WithContext(ctx).Model(&models.Listing{}).Where("seller_id = ?", userID).Pluck("id", &listingIDs)
+	for _, id := range bookIDs {
+		keys = append(keys, "book:"+id)
+	}
+	for _, id := range listingIDs {
+		keys = append(keys, "listing:"+id)
+	}
+	as.redisClient.Del(ctx, keys...)
+
+	utils.InvalidateCacheTag(ctx, as.redisClient, utils.CacheTagSearch)
+	utils.InvalidateCacheTag(ctx, as.redisClient, utils.CacheTagRecommendations)
+	utils.InvalidateCacheTag(ctx, as.redisClient, utils.CacheTagHotBooks)
+}
+
+// VisibleUsers 查询作用域：排除属于已停用账号的记录，column 为用户ID所在的列（如 books.seller_id）
+func VisibleUsers(column string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("NOT EXISTS (SELECT 1 FROM users WHERE users.id = "+column+" AND users.status = ?)",
+			models.UserStatusDeactivated)
+	}
+}
+
+// isDeactivated 用户是否已停用账号，查询失败时视为未停用
+func isDeactivated(ctx context.Context, db *gorm.DB, userID string) bool {
+	if db == nil {
+		return false
+	}
+	var count int64
+	db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND status = ?", userID, models.UserStatusDeactivated).Count(&count)
+	return count > 0
+}
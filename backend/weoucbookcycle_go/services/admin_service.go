@@ -0,0 +1,363 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrAdminTargetNotFound 管理操作的对象不存在
+	ErrAdminTargetNotFound = utils.NewError(http.StatusNotFound, "resource not found")
+	// ErrAdminSelfOperation 管理员不能封禁自己或取消自己的管理员角色
+	ErrAdminSelfOperation = utils.NewError(http.StatusForbidden, "cannot perform this operation on your own account")
+)
+
+// disabledUserKey 被封禁用户的标记，AuthMiddleware 据此立即拒绝已签发的token
+const disabledUserKey = "user:disabled:%s"
+
+// AdminService 管理后台服务
+type AdminService struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+	searchIndex *SearchIndexService
+}
+
+// NewAdminService 创建管理后台服务实例
+func NewAdminService(deps Deps, searchIndex *SearchIndexService) *AdminService {
+	return &AdminService{
+		db:          deps.DB,
+		redisClient: deps.Redis,
+		searchIndex: searchIndex,
+	}
+}
+
+// AdminQuery 管理后台列表查询条件
+type AdminQuery struct {
+	Page    int
+	Limit   int
+	Keyword string
+	Status  string
+	UserID  string
+}
+
+// offset 计算分页偏移
+func (q *AdminQuery) offset() int {
+	return (q.Page - 1) * q.Limit
+}
+
+// ==================== 用户管理 ====================
+
+// ListUsers 用户列表，Keyword 匹配用户名或邮箱，Status 为 1(正常)/0(禁用)，role 为角色筛选
+func (as *AdminService) ListUsers(q *AdminQuery, role string) ([]models.User, int64, error) {
+	query := as.db.Model(&models.User{})
+	if q.Keyword != "" {
+		like := "%" + q.Keyword + "%"
+		query = query.Where("username LIKE ? OR email LIKE ?", like, like)
+	}
+	if q.Status != "" {
+		query = query.Where("status = ?", q.Status)
+	}
+	if role != "" {
+		query = query.Where("role = ?", role)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	var users []models.User
+	if err := query.Order("created_at DESC").Offset(q.offset()).Limit(q.Limit).Find(&users).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list users: %w", err)
+	}
+	return users, total, nil
+}
+
+// SetUserStatus 封禁(0)或解封(1)用户，封禁立即生效
+func (as *AdminService) SetUserStatus(ctx context.Context, adminID, userID string, status int) (*models.User, error) {
+	if adminID == userID {
+		return nil, ErrAdminSelfOperation
+	}
+
+	var user models.User
+	if err := as.db.WithContext(ctx).First(&user, "id = ?", userID).Error; err != nil {
+		return nil, ErrAdminTargetNotFound
+	}
+	if err := as.db.WithContext(ctx).Model(&user).Update("status", status).Error; err != nil {
+		return nil, fmt.Errorf("failed to update user status: %w", err)
+	}
+
+	if as.redisClient != nil {
+		key := fmt.Sprintf(disabledUserKey, userID)
+		if status == 0 {
+			as.redisClient.Set(ctx, key, "1", 0)
+			as.redisClient.ZRem(ctx, "users:active", userID)
+		} else {
+			as.redisClient.Del(ctx, key)
+		}
+	}
+
+	return &user, nil
+}
+
+// SetUserRole 修改用户角色（user/admin），新角色在用户下次登录或刷新token后生效
+func (as *AdminService) SetUserRole(adminID, userID, role string) (*models.User, error) {
+	if adminID == userID && role != "admin" {
+		return nil, ErrAdminSelfOperation
+	}
+
+	var user models.User
+	if err := as.db.First(&user, "id = ?", userID).Error; err != nil {
+		return nil, ErrAdminTargetNotFound
+	}
+	if err := as.db.Model(&user).Update("role", role).Error; err != nil {
+		return nil, fmt.Errorf("failed to update user role: %w", err)
+	}
+	return &user, nil
+}
+
+// ==================== 书籍管理 ====================
+
+// ListBooks 书籍列表，包含所有状态，Keyword 匹配书名/作者/ISBN，UserID 为卖家
+func (as *AdminService) ListBooks(q *AdminQuery) ([]models.Book, int64, error) {
+	query := as.db.Model(&models.Book{})
+	if q.Keyword != "" {
+		like := "%" + q.Keyword + "%"
+		query = query.Where("title LIKE ? OR author LIKE ? OR isbn LIKE ?", like, like, like)
+	}
+	if q.Status != "" {
+		query = query.Where("status = ?", q.Status)
+	}
+	if q.UserID != "" {
+		query = query.Where("seller_id = ?", q.UserID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count books: %w", err)
+	}
+
+	var books []models.Book
+	if err := query.Preload("Seller").Order("created_at DESC").Offset(q.offset()).Limit(q.Limit).Find(&books).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list books: %w", err)
+	}
+	// 管理员处理举报时需要联系卖家
+	for i := range books {
+		books[i].RevealSellerContact(&books[i].Seller)
+	}
+	return books, total, nil
+}
+
+// SetBookStatus 修改书籍状态（1=可售, 0=已售, 2=下架）
+func (as *AdminService) SetBookStatus(ctx context.Context, bookID string, status int) (*models.Book, error) {
+	var book models.Book
+	if err := as.db.First(&book, "id = ?", bookID).Error; err != nil {
+		return nil, ErrAdminTargetNotFound
+	}
+	if err := as.db.Model(&book).Update("status", status).Error; err != nil {
+		return nil, fmt.Errorf("failed to update book status: %w", err)
+	}
+
+	go func() {
+		ctx := context.WithoutCancel(ctx)
+		invalidateBookCaches(ctx, as.redisClient, bookID)
+		if status == 1 {
+			as.searchIndex.indexBookDocument(ctx, &book)
+		} else {
+			as.searchIndex.removeBookDocument(ctx, bookID)
+		}
+	}()
+
+	return &book, nil
+}
+
+// DeleteBook 删除书籍（软删除），同时下架相关的发布
+func (as *AdminService) DeleteBook(ctx context.Context, bookID string) error {
+	return WithTx(ctx, as.db, func(ctx context.Context, tx *gorm.DB) error {
+		result := tx.Delete(&models.Book{}, "id = ?", bookID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrAdminTargetNotFound
+		}
+		if err := tx.Model(&models.Listing{}).
+			Where("book_id = ? AND status IN ?", bookID, []string{"available", "reserved"}).
+			Update("status", "cancelled").Error; err != nil {
+			return err
+		}
+
+		// 被组合进外层事务时，等整个事务提交后再清缓存和索引
+		AfterCommit(ctx, func() {
+			go func() {
+				ctx := context.WithoutCancel(ctx)
+				invalidateBookCaches(ctx, as.redisClient, bookID)
+				as.searchIndex.removeBookDocument(ctx, bookID)
+			}()
+		})
+		return nil
+	})
+}
+
+// ==================== 发布管理 ====================
+
+// ListListings 发布列表，UserID 为卖家
+func (as *AdminService) ListListings(q *AdminQuery) ([]models.Listing, int64, error) {
+	query := as.db.Model(&models.Listing{})
+	if q.Status != "" {
+		query = query.Where("status = ?", q.Status)
+	}
+	if q.UserID != "" {
+		query = query.Where("seller_id = ?", q.UserID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count listings: %w", err)
+	}
+
+	var listings []models.Listing
+	if err := query.Preload("Book").Preload("Seller").
+		Order("created_at DESC").Offset(q.offset()).Limit(q.Limit).Find(&listings).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list listings: %w", err)
+	}
+	for i := range listings {
+		listings[i].RevealSellerContact(&listings[i].Seller)
+	}
+	return listings, total, nil
+}
+
+// SetListingStatus 强制修改发布状态
+func (as *AdminService) SetListingStatus(ctx context.Context, listingID, status string) (*models.Listing, error) {
+	var listing models.Listing
+	if err := as.db.WithContext(ctx).First(&listing, "id = ?", listingID).Error; err != nil {
+		return nil, ErrAdminTargetNotFound
+	}
+	previousStatus := listing.Status
+	if err := as.db.WithContext(ctx).Model(&listing).Update("status", status).Error; err != nil {
+		return nil, fmt.Errorf("failed to update listing status: %w", err)
+	}
+	if status == "sold" && previousStatus != "sold" {
+		RecordDailyStat(ctx, as.redisClient, StatListingsSold)
+		RecordListingSold(ctx, as.redisClient, listing.ID, listing.SellerID, listing.BuyerID, listing.IsDonation)
+	}
+
+	if as.redisClient != nil {
+		as.redisClient.Del(ctx, "listing:"+listingID)
+	}
+
+	return &listing, nil
+}
+
+// ==================== 聊天管理 ====================
+
+// ListChats 会话列表，UserID 为参与者
+func (as *AdminService) ListChats(q *AdminQuery) ([]models.Chat, int64, error) {
+	query := as.db.Model(&models.Chat{})
+	if q.UserID != "" {
+		query = query.Where("id IN (?)", as.db.Model(&models.ChatUser{}).Select("chat_id").Where("user_id = ?", q.UserID))
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count chats: %w", err)
+	}
+
+	var chats []models.Chat
+	if err := query.Preload("Users.User").
+		Order("updated_at DESC").Offset(q.offset()).Limit(q.Limit).Find(&chats).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list chats: %w", err)
+	}
+	return chats, total, nil
+}
+
+// GetChatMessages 查看会话消息（按时间倒序）
+func (as *AdminService) GetChatMessages(chatID string, q *AdminQuery) ([]models.Message, int64, error) {
+	var count int64
+	as.db.Model(&models.Chat{}).Where("id = ?", chatID).Count(&count)
+	if count == 0 {
+		return nil, 0, ErrAdminTargetNotFound
+	}
+
+	query := as.db.Model(&models.Message{}).Where("chat_id = ?", chatID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count messages: %w", err)
+	}
+
+	var messages []models.Message
+	if err := query.Preload("Sender").
+		Order("created_at DESC").Offset(q.offset()).Limit(q.Limit).Find(&messages).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list messages: %w", err)
+	}
+	return messages, total, nil
+}
+
+// DeleteMessage 删除违规消息（软删除）
+func (as *AdminService) DeleteMessage(messageID string) error {
+	result := as.db.Delete(&models.Message{}, "id = ?", messageID)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete message: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrAdminTargetNotFound
+	}
+	return nil
+}
+
+// ==================== 举报管理 ====================
+
+// ListReports 举报列表，targetType 为举报对象类型筛选
+func (as *AdminService) ListReports(q *AdminQuery, targetType string) ([]models.Report, int64, error) {
+	query := as.db.Model(&models.Report{})
+	if q.Status != "" {
+		query = query.Where("status = ?", q.Status)
+	}
+	if targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count reports: %w", err)
+	}
+
+	var reports []models.Report
+	if err := query.Preload("Reporter").
+		Order("created_at DESC").Offset(q.offset()).Limit(q.Limit).Find(&reports).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list reports: %w", err)
+	}
+	return reports, total, nil
+}
+
+// HandleReport 处理举报，status 为 resolved 或 dismissed
+func (as *AdminService) HandleReport(adminID, reportID, status, resolution string) (*models.Report, error) {
+	var report models.Report
+	if err := as.db.First(&report, "id = ?", reportID).Error; err != nil {
+		return nil, ErrAdminTargetNotFound
+	}
+	if report.Status != models.ReportStatusPending {
+		return nil, errors.New("report has already been handled")
+	}
+
+	now := time.Now()
+	if err := as.db.Model(&report).Updates(map[string]interface{}{
+		"status":     status,
+		"handled_by": adminID,
+		"handled_at": now,
+		"resolution": resolution,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to update report: %w", err)
+	}
+
+	return &report, nil
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	// activeAnnouncementsKey 当前展示中的公告缓存，前端轮询频繁，避免每次查库
+	activeAnnouncementsKey = "announcements:active"
+	activeAnnouncementsTTL = time.Minute
+)
+
+// ErrAnnouncementNotFound 公告不存在
+var ErrAnnouncementNotFound = utils.NewError(http.StatusNotFound, "announcement not found")
+
+// AnnouncementService 公告服务
+type AnnouncementService struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+}
+
+// NewAnnouncementService 创建公告服务实例
+func NewAnnouncementService(deps Deps) *AnnouncementService {
+	return &AnnouncementService{
+		db:          deps.DB,
+		redisClient: deps.Redis,
+	}
+}
+
+// AnnouncementRequest 公告创建/更新请求
+type AnnouncementRequest struct {
+	Text     string     `json:"text" binding:"required,max=500"`
+	Link     string     `json:"link" binding:"omitempty,url,max=500"`
+	Severity string     `json:"severity" binding:"omitempty,oneof=info warning critical"`
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"`
+	Enabled  *bool      `json:"enabled"`
+}
+
+// validate 校验展示时间段
+func (r *AnnouncementRequest) validate() error {
+	if r.StartsAt != nil && r.EndsAt != nil && !r.EndsAt.After(*r.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	if r.Severity == "" {
+		r.Severity = models.AnnouncementSeverityInfo
+	}
+	return nil
+}
+
+// Active 获取当前展示中的公告，critical 优先
+func (as *AnnouncementService) Active(ctx context.Context) ([]models.Announcement, error) {
+	if as.redisClient != nil {
+		if cached, err := as.redisClient.Get(ctx, activeAnnouncementsKey).Result(); err == nil {
+			var announcements []models.Announcement
+			if json.Unmarshal([]byte(cached), &announcements) == nil {
+				return announcements, nil
+			}
+		}
+	}
+
+	now := time.Now()
+	announcements := []models.Announcement{}
+	if err := as.db.WithContext(ctx).
+		Where("enabled = ?", true).
+		Where("starts_at IS NULL OR starts_at <= ?", now).
+		Where("ends_at IS NULL OR ends_at > ?", now).
+		Order("FIELD(severity, 'critical', 'warning', 'info')").
+		Order("created_at DESC").
+		Find(&announcements).Error; err != nil {
+		return nil, fmt.Errorf("failed to get announcements: %w", err)
+	}
+
+	if as.redisClient != nil {
+		// 缓存不能跨过最近的开始/结束时间，否则公告会晚于预定时间出现或消失
+		ttl := activeAnnouncementsTTL
+		if next := as.nextBoundary(now); next != nil && next.Sub(now) < ttl {
+			ttl = max(next.Sub(now), time.Second)
+		}
+		data, _ := json.Marshal(announcements)
+		as.redisClient.Set(ctx, activeAnnouncementsKey, data, ttl)
+	}
+
+	return announcements, nil
+}
+
+// nextBoundary 最近一个即将到来的开始或结束时间
+func (as *AnnouncementService) nextBoundary(now time.Time) *time.Time {
+	var next *time.Time
+	for _, column := range []string{"starts_at", "ends_at"} {
+		var announcement models.Announcement
+		err := as.db.Select(column).
+			Where("enabled = ? AND "+column+" > ?", true, now).
+			Order(column).
+			First(&announcement).Error
+		if err != nil {
+			continue
+		}
+		t := announcement.StartsAt
+		if column == "ends_at" {
+			t = announcement.EndsAt
+		}
+		if t != nil && (next == nil || t.Before(*next)) {
+			next = t
+		}
+	}
+	return next
+}
+
+// List 获取全部公告（管理员）
+func (as *AnnouncementService) List(page, limit int) ([]models.Announcement, int64, error) {
+	var total int64
+	as.db.Model(&models.Announcement{}).Count(&total)
+
+	var announcements []models.Announcement
+	if err := as.db.Order("created_at DESC").
+		Offset((page - 1) * limit).Limit(limit).
+		Find(&announcements).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list announcements: %w", err)
+	}
+	return announcements, total, nil
+}
+
+// Create 创建公告
+func (as *AnnouncementService) Create(ctx context.Context, adminID string, req *AnnouncementRequest) (*models.Announcement, error) {
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+
+	announcement := models.Announcement{
+		Text:      req.Text,
+		Link:      req.Link,
+		Severity:  req.Severity,
+		StartsAt:  req.StartsAt,
+		EndsAt:    req.EndsAt,
+		Enabled:   true,
+		CreatedBy: adminID,
+	}
+	if err := as.db.Create(&announcement).Error; err != nil {
+		return nil, fmt.Errorf("failed to create announcement: %w", err)
+	}
+
+	// 禁用状态需要单独更新（零值不会写入）
+	if req.Enabled != nil && !*req.Enabled {
+		as.db.Model(&announcement).Update("enabled", false)
+		announcement.Enabled = false
+	}
+
+	as.invalidateActiveAnnouncements(ctx)
+	return &announcement, nil
+}
+
+// Update 更新公告
+func (as *AnnouncementService) Update(ctx context.Context, id string, req *AnnouncementRequest) (*models.Announcement, error) {
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+
+	var announcement models.Announcement
+	if err := as.db.First(&announcement, "id = ?", id).Error; err != nil {
+		return nil, ErrAnnouncementNotFound
+	}
+
+	updates := map[string]interface{}{
+		"text":      req.Text,
+		"link":      req.Link,
+		"severity":  req.Severity,
+		"starts_at": req.StartsAt,
+		"ends_at":   req.EndsAt,
+	}
+	if req.Enabled != nil {
+		updates["enabled"] = *req.Enabled
+	}
+	if err := as.db.Model(&announcement).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update announcement: %w", err)
+	}
+	if err := as.db.First(&announcement, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+
+	as.invalidateActiveAnnouncements(ctx)
+	return &announcement, nil
+}
+
+// Delete 删除公告
+func (as *AnnouncementService) Delete(ctx context.Context, id string) error {
+	result := as.db.Delete(&models.Announcement{}, "id = ?", id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete announcement: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrAnnouncementNotFound
+	}
+
+	as.invalidateActiveAnnouncements(ctx)
+	return nil
+}
+
+// invalidateActiveAnnouncements 公告变更后清除展示缓存
+func (as *AnnouncementService) invalidateActiveAnnouncements(ctx context.Context) {
+	if as.redisClient != nil {
+		as.redisClient.Del(ctx, activeAnnouncementsKey)
+	}
+}
@@ -7,20 +7,21 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"net/mail"
 	"net/smtp"
+	"net/textproto"
 	"sync"
 	"time"
 	"weoucbookcycle_go/config"
 	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
 
+	"github.com/hibiken/asynq"
 	"github.com/redis/go-redis/v9"
 	"golang.org/x/crypto/bcrypt"
-)
-
-var (
-	redisCtx = context.Background()
+	"gorm.io/gorm"
 )
 
 // EmailConfig 邮件配置
@@ -31,29 +32,41 @@ type EmailConfig struct {
 	SMTPPassword string
 	FromEmail    string
 	FromName     string
+	// breaker SMTP熔断器，所有邮件配置共用
+	breaker *utils.Breaker
+	// deferDelay 熔断期间邮件延后发送的间隔（BREAKER_OPEN_TIMEOUT）
+	deferDelay time.Duration
 }
 
 // AuthConfig 认证配置
 type AuthConfig struct {
-	MaxLoginAttempts     int           // 最大登录失败次数
-	LoginBlockDuration   time.Duration // 登录封禁时长
-	RegisterLimitPerHour int           // 每小时最大注册次数
+	MaxLoginAttempts   int           // 最大登录失败次数
+	LoginBlockDuration time.Duration // 登录封禁时长
 }
 
 // AuthService 认证服务
 type AuthService struct {
-	jwtService  *config.JWTService
-	emailConfig *EmailConfig
-	authConfig  *AuthConfig
-	// 邮件发送队列（使用goroutine异步处理）
-	emailQueue   chan *EmailTask
-	emailWorkers int
-	// 登录失败记录队列
-	loginFailureQueue chan *LoginFailure
+	db           *gorm.DB
+	redisClient  *redis.Client
+	jwtService   *config.JWTService
+	emailConfig  *EmailConfig
+	authConfig   *AuthConfig
+	wechatConfig *config.WeChatConfig
+	settings     *SystemSettingsService
 	// IP封禁检查缓存
 	ipBlockCache sync.Map // IP -> BlockInfo
 }
 
+// 认证相关后台任务类型
+const (
+	JobEmailSend         = "email:send"
+	JobAuthLoginFailure  = "auth:login_failure"
+	emailMaxRetry        = 3
+	loginFailureMaxRetry = 3
+	// emailDeferLimit SMTP熔断时邮件延后发送的最长时间，超过后写入死信
+	emailDeferLimit = 6 * time.Hour
+)
+
 // EmailTask 邮件发送任务
 type EmailTask struct {
 	Type      string // "welcome", "verification", "password_reset", "password_changed"
@@ -62,7 +75,7 @@ type EmailTask struct {
 	Body      string
 	HTMLBody  string
 	Timestamp time.Time
-	Retries   int
+	Retries   int // 写入死信时的发送次数
 }
 
 // LoginFailure 登录失败记录
@@ -79,98 +92,129 @@ type BlockInfo struct {
 	Reason      string
 }
 
-// NewAuthService 创建认证服务实例
-func NewAuthService() *AuthService {
-	emailConfig := &EmailConfig{
-		SMTPHost:     config.GetEnv("SMTP_HOST", "smtp.gmail.com"),
-		SMTPPort:     587,
-		SMTPUser:     config.GetEnv("SMTP_USER", ""),
-		SMTPPassword: config.GetEnv("SMTP_PASSWORD", ""),
-		FromEmail:    config.GetEnv("FROM_EMAIL", "noreply@weoucbookcycle.com"),
-		FromName:     config.GetEnv("FROM_NAME", "WeOUC BookCycle"),
+// newEmailConfig 由应用配置中的SMTP和熔断设置生成邮件配置
+func newEmailConfig(cfg *config.Config) *EmailConfig {
+	return &EmailConfig{
+		SMTPHost:     cfg.SMTP.Host,
+		SMTPPort:     cfg.SMTP.Port,
+		SMTPUser:     cfg.SMTP.User,
+		SMTPPassword: cfg.SMTP.Password,
+		FromEmail:    cfg.SMTP.FromEmail,
+		FromName:     cfg.SMTP.FromName,
+		breaker:      utils.GetBreaker(utils.BreakerSMTP, &cfg.Breaker, isSMTPFailure),
+		deferDelay:   cfg.Breaker.OpenTimeout,
 	}
+}
+
+// isSMTPFailure 服务器拒收（地址不存在、内容被拒等）说明SMTP可用，不计入熔断
+func isSMTPFailure(err error) bool {
+	var protoErr *textproto.Error
+	return !errors.As(err, &protoErr)
+}
+
+// NewAuthService 创建认证服务实例，并注册邮件和登录失败后台任务的处理函数
+// 进程内只应创建一次（见 NewServices）
+func NewAuthService(deps Deps, settings *SystemSettingsService) *AuthService {
+	cfg := deps.Config
+	emailConfig := newEmailConfig(cfg)
 
 	authConfig := &AuthConfig{
-		MaxLoginAttempts:     5,
-		LoginBlockDuration:   15 * time.Minute,
-		RegisterLimitPerHour: 3,
+		MaxLoginAttempts:   5,
+		LoginBlockDuration: 15 * time.Minute,
 	}
 
 	authService := &AuthService{
-		jwtService:        config.GetJWTService(),
-		emailConfig:       emailConfig,
-		authConfig:        authConfig,
-		emailQueue:        make(chan *EmailTask, 1000),
-		emailWorkers:      5,
-		loginFailureQueue: make(chan *LoginFailure, 1000),
+		db:           deps.DB,
+		redisClient:  deps.Redis,
+		jwtService:   config.NewJWTService(&cfg.JWT),
+		emailConfig:  emailConfig,
+		authConfig:   authConfig,
+		wechatConfig: &cfg.WeChat,
+		settings:     settings,
 	}
 
-	// 启动邮件发送worker池
-	authService.startEmailWorkers()
-
-	// 启动登录失败处理worker
-	authService.startLoginFailureWorker()
-
-	// 启动IP封禁检查清理goroutine
-	go authService.cleanupIPBlocks()
+	utils.HandleJob(JobEmailSend, authService.processEmail,
+		asynq.Queue(utils.JobQueueCritical), asynq.MaxRetry(emailMaxRetry), asynq.Timeout(time.Minute))
+	utils.HandleJob(JobAuthLoginFailure, authService.processLoginFailure,
+		asynq.Queue(utils.JobQueueCritical), asynq.MaxRetry(loginFailureMaxRetry))
 
 	return authService
 }
 
 // RegisterRequest 注册请求
 type RegisterRequest struct {
-	Username string `json:"username" binding:"required,min=3,max=50"`
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=8,max=100"`
+	Username   string `json:"username" binding:"required,username"`
+	Email      string `json:"email" binding:"required,email"`
+	Password   string `json:"password" binding:"required,password,max=100"`
+	InviteCode string `json:"invite_code" binding:"omitempty,max=16"`
+	// DeviceID 客户端生成并持久保存的设备标识，用于邀请防刷
+	DeviceID string `json:"device_id" binding:"omitempty,max=64"`
 }
 
-// LoginRequest 登录请求
+// LoginRequest 登录请求，使用账号绑定的任意一个邮箱或手机号登录
 type LoginRequest struct {
-	Email    string `json:"email" binding:"required,email"`
+	Email    string `json:"email" binding:"required_without=Phone,omitempty,email"`
+	Phone    string `json:"phone" binding:"required_without=Email"`
 	Password string `json:"password" binding:"required"`
 }
 
+// identity 登录使用的身份类型和规范化后的值，同时填写时以邮箱为准
+func (req *LoginRequest) identity() (string, string) {
+	if req.Email != "" {
+		return models.IdentityEmail, normalizeEmail(req.Email)
+	}
+	return models.IdentityPhone, normalizePhone(req.Phone)
+}
+
 // ==================== 注册相关方法 ====================
 
 // Register 用户注册
-func (as *AuthService) Register(req *RegisterRequest, clientIP string) (*models.User, string, error) {
+func (as *AuthService) Register(ctx context.Context, req *RegisterRequest, clientIP, lang string) (*models.User, string, error) {
 	// 1. 检查IP是否被封禁
-	if as.isIPBlocked(clientIP) {
+	if as.isIPBlocked(ctx, clientIP) {
 		return nil, "", errors.New("your IP has been blocked due to suspicious activity")
 	}
 
 	// 2. 检查用户名是否已存在
 	var existingUser models.User
-	if err := config.DB.Where("username = ?", req.Username).First(&existingUser).Error; err == nil {
+	if err := as.db.WithContext(ctx).Where("username = ?", req.Username).First(&existingUser).Error; err == nil {
 		return nil, "", errors.New("username already exists")
 	}
 
-	// 3. 检查邮箱是否已存在
-	if err := config.DB.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
+	// 3. 检查邮箱是否已存在（包括其他账号绑定的邮箱）
+	req.Email = normalizeEmail(req.Email)
+	if _, err := findIdentityUser(ctx, as.db.WithContext(ctx), models.IdentityEmail, req.Email); err == nil {
 		return nil, "", errors.New("email already exists")
 	}
 
 	// 4. 检查注册频率限制（使用Redis）
-	if config.RedisClient != nil {
+	if as.redisClient != nil {
 		registerLimitKey := fmt.Sprintf("register:limit:%s", clientIP)
-		count, _ := config.RedisClient.Get(redisCtx, registerLimitKey).Int64()
-		if count >= int64(as.authConfig.RegisterLimitPerHour) {
+		count, _ := as.redisClient.Get(ctx, registerLimitKey).Int64()
+		// 每小时注册上限可在管理后台调整
+		if count >= int64(as.settings.Int(ctx, SettingRegisterLimitPerHour)) {
 			// 记录可疑行为，可能封禁IP
-			as.recordSuspiciousActivity(clientIP, "too many registration attempts")
+			as.recordSuspiciousActivity(ctx, clientIP, "too many registration attempts")
 			return nil, "", fmt.Errorf("too many registration attempts, please try again later")
 		}
 	}
 
-	// 5. 密码加密
+	// 5. 查找邀请人（邀请码无效时拒绝注册，避免用户以为已被邀请）
+	inviterID, err := resolveInviteCode(ctx, as.db.WithContext(ctx), req.InviteCode)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// 6. 密码加密
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to hash password: %w", err)
 	}
 
-	// 6. 生成邮箱验证码
+	// 7. 生成邮箱验证码
 	verificationCode := as.generateVerificationCode()
 
-	// 7. 创建用户
+	// 8. 创建用户
 	user := models.User{
 		Username: req.Username,
 		Email:    req.Email,
@@ -178,78 +222,87 @@ func (as *AuthService) Register(req *RegisterRequest, clientIP string) (*models.
 		Status:   1,
 	}
 
-	if err := config.DB.Create(&user).Error; err != nil {
-		return nil, "", fmt.Errorf("failed to create user: %w", err)
+	// 9. 创建用户、记录邀请关系和生成JWT token在同一事务中，token生成失败时不留下无法登录的账号
+	var token string
+	err = WithTx(ctx, as.db.WithContext(ctx), func(ctx context.Context, tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+		if err := tx.Create(&models.UserIdentity{UserID: user.ID, Provider: models.IdentityEmail, Subject: req.Email}).Error; err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+		if inviterID != "" {
+			if err := createReferral(tx, inviterID, user.ID, req.InviteCode, clientIP, req.DeviceID); err != nil {
+				return err
+			}
+		}
+		token, err = as.jwtService.GenerateToken(user.ID, user.Username, user.Email, user.Roles())
+		if err != nil {
+			return fmt.Errorf("failed to generate token: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
 	}
 
-	// 8. 存储验证码到Redis（30分钟有效）
+	// 10. 提交后再存储验证码到Redis（30分钟有效）
 	verificationKey := fmt.Sprintf("verify:email:%s", req.Email)
-	if config.RedisClient != nil {
-		config.RedisClient.Set(redisCtx, verificationKey, verificationCode, 30*time.Minute)
+	if as.redisClient != nil {
+		as.redisClient.Set(ctx, verificationKey, verificationCode, 30*time.Minute)
 	}
 
-	// 9. 增加注册计数
-	if config.RedisClient != nil {
+	// 11. 增加注册计数
+	if as.redisClient != nil {
 		registerLimitKey := fmt.Sprintf("register:limit:%s", clientIP)
-		config.RedisClient.Incr(redisCtx, registerLimitKey)
-		config.RedisClient.Expire(redisCtx, registerLimitKey, time.Hour)
-	}
-
-	// 10. 生成JWT token
-	token, err := as.jwtService.GenerateToken(user.ID, user.Username, user.Email, []string{"user"})
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to generate token: %w", err)
+		as.redisClient.Incr(ctx, registerLimitKey)
+		as.redisClient.Expire(ctx, registerLimitKey, time.Hour)
 	}
 
-	// 11. 异步发送欢迎邮件和验证邮件（使用goroutine）
+	// 12. 异步发送欢迎邮件和验证邮件（使用goroutine）
 	go func() {
-		as.queueEmail(&EmailTask{
+		ctx := context.WithoutCancel(ctx)
+		as.queueEmail(ctx, &EmailTask{
 			Type:      "welcome",
 			ToEmail:   req.Email,
-			Subject:   "Welcome to WeOUC BookCycle",
-			Body:      fmt.Sprintf("Welcome %s! Your account has been created successfully.", req.Username),
+			Subject:   utils.T(lang, "email.welcome.subject"),
+			Body:      utils.T(lang, "email.welcome.body", req.Username),
 			Timestamp: time.Now(),
 		})
 	}()
 
 	go func() {
+		ctx := context.WithoutCancel(ctx)
 		verificationLink := fmt.Sprintf("http://localhost:5173/verify-email?email=%s&code=%s", req.Email, verificationCode)
-		as.queueEmail(&EmailTask{
-			Type:    "verification",
-			ToEmail: req.Email,
-			Subject: "Verify Your Email Address",
-			HTMLBody: fmt.Sprintf(`
-				<h2>Email Verification</h2>
-				<p>Hello %s,</p>
-				<p>Please verify your email address by clicking the link below:</p>
-				<p><a href="%s">Verify Email</a></p>
-				<p>Or use this verification code: <strong>%s</strong></p>
-				<p>This code will expire in 30 minutes.</p>
-				<p>If you did not create an account, please ignore this email.</p>
-			`, req.Username, verificationLink, verificationCode),
+		as.queueEmail(ctx, &EmailTask{
+			Type:      "verification",
+			ToEmail:   req.Email,
+			Subject:   utils.T(lang, "email.verification.subject"),
+			HTMLBody:  utils.T(lang, "email.verification.html", req.Username, verificationLink, verificationCode),
 			Timestamp: time.Now(),
 		})
 	}()
 
-	// 12. 记录注册到Redis（用于统计分析）
-	go func() {
-		if config.RedisClient != nil {
-			config.RedisClient.Incr(redisCtx, "stats:register:total")
-			config.RedisClient.Incr(redisCtx, fmt.Sprintf("stats:register:%s", time.Now().Format("2006-01-02")))
-			// 记录到Stream
-			config.RedisClient.XAdd(redisCtx, &redis.XAddArgs{
-				Stream: "user_events",
-				Values: map[string]interface{}{
-					"event":     "register",
-					"user_id":   user.ID,
-					"email":     user.Email,
-					"username":  user.Username,
-					"ip":        clientIP,
-					"timestamp": time.Now().Unix(),
-				},
-			})
+	// 13. 记录注册到Redis（用于统计分析）
+	utils.Go(ctx, "user_events", func(ctx context.Context) error {
+		if as.redisClient == nil {
+			return nil
 		}
-	}()
+		as.redisClient.Incr(ctx, "stats:register:total")
+		RecordDailyStat(ctx, as.redisClient, StatRegistrations)
+		// 记录到Stream
+		return as.redisClient.XAdd(ctx, &redis.XAddArgs{
+			Stream: "user_events",
+			Values: map[string]interface{}{
+				"event":     "register",
+				"user_id":   user.ID,
+				"email":     user.Email,
+				"username":  user.Username,
+				"ip":        clientIP,
+				"timestamp": time.Now().Unix(),
+			},
+		}).Err()
+	})
 
 	return &user, token, nil
 }
@@ -257,43 +310,46 @@ func (as *AuthService) Register(req *RegisterRequest, clientIP string) (*models.
 // ==================== 登录相关方法 ====================
 
 // Login 用户登录
-func (as *AuthService) Login(req *LoginRequest, clientIP, userAgent string) (*models.User, string, error) {
+func (as *AuthService) Login(ctx context.Context, req *LoginRequest, clientIP, userAgent string) (*models.User, string, error) {
+	provider, login := req.identity()
+
 	// 1. 检查IP是否被封禁
-	if as.isIPBlocked(clientIP) {
+	if as.isIPBlocked(ctx, clientIP) {
 		// 记录登录失败
-		as.loginFailureQueue <- &LoginFailure{
-			Email:     req.Email,
+		as.enqueueLoginFailure(ctx, &LoginFailure{
+			Email:     login,
 			IP:        clientIP,
 			Timestamp: time.Now(),
 			UserAgent: userAgent,
-		}
+		})
 		return nil, "", errors.New("your IP has been blocked due to too many failed login attempts. Please try again later")
 	}
 
 	// 2. 检查登录频率限制（基于IP和邮箱）
-	if config.RedisClient != nil {
-		loginLimitKey := fmt.Sprintf("login:limit:%s:%s", req.Email, clientIP)
-		attempts, _ := config.RedisClient.Get(redisCtx, loginLimitKey).Int64()
+	if as.redisClient != nil {
+		loginLimitKey := fmt.Sprintf("login:limit:%s:%s", login, clientIP)
+		attempts, _ := as.redisClient.Get(ctx, loginLimitKey).Int64()
 
 		if attempts >= int64(as.authConfig.MaxLoginAttempts) {
 			// 封禁IP
-			as.blockIP(clientIP, "too many failed login attempts")
+			as.blockIP(ctx, clientIP, "too many failed login attempts")
 			return nil, "", fmt.Errorf("too many login attempts. Your IP has been blocked for %v", as.authConfig.LoginBlockDuration)
 		}
 	}
 
-	// 3. 查找用户
-	var user models.User
-	if err := config.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
+	// 3. 查找绑定了该邮箱或手机号的用户
+	found, err := findIdentityUser(ctx, as.db.WithContext(ctx), provider, login)
+	if err != nil {
 		// 记录登录失败
-		as.recordLoginFailure(req.Email, clientIP, userAgent, "user not found")
+		as.recordLoginFailure(ctx, login, clientIP, userAgent, "user not found")
 		return nil, "", errors.New("invalid email or password")
 	}
+	user := *found
 
 	// 4. 验证密码
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
 		// 记录登录失败
-		as.recordLoginFailure(req.Email, clientIP, userAgent, "invalid password")
+		as.recordLoginFailure(ctx, login, clientIP, userAgent, "invalid password")
 		return nil, "", errors.New("invalid email or password")
 	}
 
@@ -301,6 +357,7 @@ func (as *AuthService) Login(req *LoginRequest, clientIP, userAgent string) (*mo
 	if user.Status == 0 {
 		return nil, "", errors.New("account is disabled. Please contact support")
 	}
+	touchIdentity(as.db.WithContext(ctx), provider, login)
 
 	// 6. 更新最后登录时间和登录次数
 	now := time.Now()
@@ -308,10 +365,10 @@ func (as *AuthService) Login(req *LoginRequest, clientIP, userAgent string) (*mo
 
 	// 从Redis获取登录次数
 	loginCountKey := fmt.Sprintf("user:login_count:%s", user.ID)
-	if config.RedisClient != nil {
-		count, _ := config.RedisClient.Get(redisCtx, loginCountKey).Int64()
+	if as.redisClient != nil {
+		count, _ := as.redisClient.Get(ctx, loginCountKey).Int64()
 		loginCount = int(count)
-		config.RedisClient.Incr(redisCtx, loginCountKey)
+		as.redisClient.Incr(ctx, loginCountKey)
 	}
 
 	updates := map[string]interface{}{
@@ -319,38 +376,40 @@ func (as *AuthService) Login(req *LoginRequest, clientIP, userAgent string) (*mo
 		"login_count": loginCount + 1,
 	}
 
-	if err := config.DB.Model(&user).Updates(updates).Error; err != nil {
+	if err := as.db.WithContext(ctx).Model(&user).Updates(updates).Error; err != nil {
 		// 不影响登录流程，只记录错误
 	}
 
 	// 7. 清除登录失败记录
-	if config.RedisClient != nil {
-		loginLimitKey := fmt.Sprintf("login:limit:%s:%s", req.Email, clientIP)
-		config.RedisClient.Del(redisCtx, loginLimitKey)
+	if as.redisClient != nil {
+		loginLimitKey := fmt.Sprintf("login:limit:%s:%s", login, clientIP)
+		as.redisClient.Del(ctx, loginLimitKey)
 
 		// 从内存缓存中移除IP封禁
 		as.ipBlockCache.Delete(clientIP)
 	}
 
 	// 8. 生成JWT token
-	token, err := as.jwtService.GenerateToken(user.ID, user.Username, user.Email, []string{"user"})
+	token, err := as.jwtService.GenerateToken(user.ID, user.Username, user.Email, user.Roles())
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to generate token: %w", err)
 	}
 
 	// 9. 异步记录登录日志（使用goroutine）
 	go func() {
-		as.recordLoginLog(&user, clientIP, userAgent, true)
+		ctx := context.WithoutCancel(ctx)
+		as.recordLoginLog(ctx, &user, clientIP, userAgent, true)
 	}()
 
 	// 10. 记录活跃用户到Redis（用于在线统计）
 	go func() {
-		if config.RedisClient != nil {
-			config.RedisClient.ZAdd(redisCtx, "users:active", redis.Z{
+		ctx := context.WithoutCancel(ctx)
+		if as.redisClient != nil {
+			as.redisClient.ZAdd(ctx, "users:active", redis.Z{
 				Score:  float64(time.Now().Unix()),
 				Member: user.ID,
 			})
-			config.RedisClient.Expire(redisCtx, "users:active", 7*24*time.Hour)
+			as.redisClient.Expire(ctx, "users:active", 7*24*time.Hour)
 		}
 	}()
 
@@ -361,21 +420,56 @@ func (as *AuthService) Login(req *LoginRequest, clientIP, userAgent string) (*mo
 // code 由前端 wx.login 获取并发送到后台
 // 服务端调用微信接口换取 openid, session_key
 // 如果用户已存在则返回该用户，否则自动创建
-func (as *AuthService) WeChatLogin(code, clientIP string) (*models.User, string, error) {
+func (as *AuthService) WeChatLogin(ctx context.Context, code, clientIP string) (*models.User, string, error) {
+	openID, err := as.wechatOpenID(code)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// 查找绑定了该微信的用户，不存在则创建
+	user, err := findIdentityUser(ctx, as.db.WithContext(ctx), models.IdentityWeChat, openID)
+	if err != nil {
+		user = &models.User{
+			Username:     "wx_" + openID[:8],
+			WeChatOpenID: openID,
+			Status:       1,
+		}
+		err := WithTx(ctx, as.db.WithContext(ctx), func(ctx context.Context, tx *gorm.DB) error {
+			if err := tx.Create(user).Error; err != nil {
+				return err
+			}
+			return tx.Create(&models.UserIdentity{UserID: user.ID, Provider: models.IdentityWeChat, Subject: openID}).Error
+		})
+		if err != nil {
+			return nil, "", fmt.Errorf("创建微信用户失败: %w", err)
+		}
+	}
+	touchIdentity(as.db.WithContext(ctx), models.IdentityWeChat, openID)
+
+	token, err := as.jwtService.GenerateToken(user.ID, user.Username, user.Email, user.Roles())
+	if err != nil {
+		return nil, "", fmt.Errorf("生成token失败: %w", err)
+	}
+
+	return user, token, nil
+}
+
+// wechatOpenID 用小程序 wx.login 获取的 code 换取 openid
+func (as *AuthService) wechatOpenID(code string) (string, error) {
 	if code == "" {
-		return nil, "", errors.New("code为空")
+		return "", errors.New("code为空")
 	}
 
-	appid := config.GetEnv("WECHAT_APPID", "")
-	secret := config.GetEnv("WECHAT_SECRET", "")
+	appid := as.wechatConfig.AppID
+	secret := as.wechatConfig.Secret
 	if appid == "" || secret == "" {
-		return nil, "", errors.New("微信配置未设置")
+		return "", errors.New("微信配置未设置")
 	}
 
 	url := fmt.Sprintf("https://api.weixin.qq.com/sns/jscode2session?appid=%s&secret=%s&js_code=%s&grant_type=authorization_code", appid, secret, code)
 	resp, err := http.Get(url)
 	if err != nil {
-		return nil, "", fmt.Errorf("请求微信接口失败: %w", err)
+		return "", fmt.Errorf("请求微信接口失败: %w", err)
 	}
 	defer resp.Body.Close()
 
@@ -387,46 +481,26 @@ func (as *AuthService) WeChatLogin(code, clientIP string) (*models.User, string,
 		ErrMsg     string `json:"errmsg"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, "", fmt.Errorf("解析微信返回失败: %w", err)
+		return "", fmt.Errorf("解析微信返回失败: %w", err)
 	}
 	if data.ErrCode != 0 {
-		return nil, "", fmt.Errorf("微信登录失败: %s", data.ErrMsg)
+		return "", fmt.Errorf("微信登录失败: %s", data.ErrMsg)
 	}
 
 	if data.OpenID == "" {
-		return nil, "", errors.New("微信未返回openid")
-	}
-
-	// 查找或创建用户
-	var user models.User
-	if err := config.DB.Where("we_chat_open_id = ?", data.OpenID).First(&user).Error; err != nil {
-		// 用户不存在则创建
-		user = models.User{
-			Username:     "wx_" + data.OpenID[:8],
-			WeChatOpenID: data.OpenID,
-			Status:       1,
-		}
-		if err := config.DB.Create(&user).Error; err != nil {
-			return nil, "", fmt.Errorf("创建微信用户失败: %w", err)
-		}
-	}
-
-	token, err := as.jwtService.GenerateToken(user.ID, user.Username, user.Email, []string{"user"})
-	if err != nil {
-		return nil, "", fmt.Errorf("生成token失败: %w", err)
+		return "", errors.New("微信未返回openid")
 	}
-
-	return &user, token, nil
+	return data.OpenID, nil
 }
 
 // ==================== Token相关方法 ====================
 
 // RefreshToken 刷新token
-func (as *AuthService) RefreshToken(tokenString string) (string, map[string]interface{}, error) {
+func (as *AuthService) RefreshToken(ctx context.Context, tokenString string) (string, map[string]interface{}, error) {
 	// 1. 检查token是否在黑名单中
-	if config.RedisClient != nil {
+	if as.redisClient != nil {
 		blacklistKey := fmt.Sprintf("token:blacklist:%s", tokenString)
-		exists, _ := config.RedisClient.Exists(redisCtx, blacklistKey).Result()
+		exists, _ := as.redisClient.Exists(ctx, blacklistKey).Result()
 		if exists > 0 {
 			return "", nil, errors.New("token has been revoked")
 		}
@@ -437,15 +511,23 @@ func (as *AuthService) RefreshToken(tokenString string) (string, map[string]inte
 	if err != nil {
 		return "", nil, err
 	}
+	if claims.ImpersonatorID != "" {
+		return "", nil, errors.New("impersonation tokens cannot be refreshed")
+	}
+	if as.redisClient != nil {
+		if disabled, _ := as.redisClient.Exists(ctx, fmt.Sprintf(disabledUserKey, claims.UserID)).Result(); disabled > 0 {
+			return "", nil, errors.New("account is disabled. Please contact support")
+		}
+	}
 
 	// 3. 将旧token加入黑名单
-	if config.RedisClient != nil {
+	if as.redisClient != nil {
 		blacklistKey := fmt.Sprintf("token:blacklist:%s", tokenString)
 
 		// 计算token剩余有效期
 		expiration := time.Until(claims.ExpiresAt.Time)
 		if expiration > 0 {
-			config.RedisClient.Set(redisCtx, blacklistKey, "1", expiration)
+			as.redisClient.Set(ctx, blacklistKey, "1", expiration)
 		}
 	}
 
@@ -472,9 +554,9 @@ func (as *AuthService) RefreshToken(tokenString string) (string, map[string]inte
 }
 
 // Logout 用户登出
-func (as *AuthService) Logout(tokenString, userID string) error {
+func (as *AuthService) Logout(ctx context.Context, tokenString, userID string) error {
 	// 1. 将token加入黑名单
-	if config.RedisClient != nil {
+	if as.redisClient != nil {
 		blacklistKey := fmt.Sprintf("token:blacklist:%s", tokenString)
 
 		// 解析token获取过期时间
@@ -485,14 +567,15 @@ func (as *AuthService) Logout(tokenString, userID string) error {
 
 		expiration := time.Until(claims.ExpiresAt.Time)
 		if expiration > 0 {
-			config.RedisClient.Set(redisCtx, blacklistKey, "1", expiration)
+			as.redisClient.Set(ctx, blacklistKey, "1", expiration)
 		}
 	}
 
 	// 2. 从在线用户列表移除
 	go func() {
-		if config.RedisClient != nil {
-			config.RedisClient.ZRem(redisCtx, "users:active", userID)
+		ctx := context.WithoutCancel(ctx)
+		if as.redisClient != nil {
+			as.redisClient.ZRem(ctx, "users:active", userID)
 		}
 	}()
 
@@ -502,10 +585,10 @@ func (as *AuthService) Logout(tokenString, userID string) error {
 // ==================== 邮箱验证方法 ====================
 
 // VerifyEmail 验证邮箱
-func (as *AuthService) VerifyEmail(email, code string) error {
+func (as *AuthService) VerifyEmail(ctx context.Context, email, code string) error {
 	// 1. 从Redis获取验证码
 	verifyKey := fmt.Sprintf("verify:email:%s", email)
-	storedCode, err := config.RedisClient.Get(redisCtx, verifyKey).Result()
+	storedCode, err := as.redisClient.Get(ctx, verifyKey).Result()
 	if err == redis.Nil {
 		return errors.New("verification code has expired")
 	}
@@ -516,15 +599,15 @@ func (as *AuthService) VerifyEmail(email, code string) error {
 	// 2. 验证验证码
 	if storedCode != code {
 		// 记录验证失败
-		as.recordVerificationFailure(email, "invalid code")
+		as.recordVerificationFailure(ctx, email, "invalid code")
 		return errors.New("invalid verification code")
 	}
 
 	// 3. 删除验证码
-	config.RedisClient.Del(redisCtx, verifyKey)
+	as.redisClient.Del(ctx, verifyKey)
 
 	// 4. 更新用户状态
-	result := config.DB.Model(&models.User{}).Where("email = ?", email).
+	result := as.db.WithContext(ctx).Model(&models.User{}).Where("email = ?", email).
 		Updates(map[string]interface{}{
 			"email_verified": true,
 			"verified_at":    time.Now(),
@@ -538,14 +621,19 @@ func (as *AuthService) VerifyEmail(email, code string) error {
 		return errors.New("user not found")
 	}
 
+	var user models.User
+	if err := as.db.WithContext(ctx).Select("id").Where("email = ?", email).First(&user).Error; err == nil {
+		recordUserEvent(ctx, as.redisClient, "email_verified", user.ID)
+	}
+
 	return nil
 }
 
 // ResendVerificationCode 重新发送验证码
-func (as *AuthService) ResendVerificationCode(email string) error {
+func (as *AuthService) ResendVerificationCode(ctx context.Context, email, lang string) error {
 	// 1. 检查用户是否存在
 	var user models.User
-	if err := config.DB.Where("email = ?", email).First(&user).Error; err != nil {
+	if err := as.db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
 		return errors.New("user not found")
 	}
 
@@ -555,9 +643,9 @@ func (as *AuthService) ResendVerificationCode(email string) error {
 	}
 
 	// 3. 检查发送频率
-	if config.RedisClient != nil {
+	if as.redisClient != nil {
 		rateLimitKey := fmt.Sprintf("verify:rate_limit:%s", email)
-		count, _ := config.RedisClient.Get(redisCtx, rateLimitKey).Int64()
+		count, _ := as.redisClient.Get(ctx, rateLimitKey).Int64()
 		if count > 0 {
 			return errors.New("please wait before requesting another verification code")
 		}
@@ -568,29 +656,23 @@ func (as *AuthService) ResendVerificationCode(email string) error {
 
 	// 5. 存储到Redis
 	verifyKey := fmt.Sprintf("verify:email:%s", email)
-	config.RedisClient.Set(redisCtx, verifyKey, verificationCode, 30*time.Minute)
+	as.redisClient.Set(ctx, verifyKey, verificationCode, 30*time.Minute)
 
 	// 6. 设置发送频率限制（1分钟内不能重复发送）
-	if config.RedisClient != nil {
+	if as.redisClient != nil {
 		rateLimitKey := fmt.Sprintf("verify:rate_limit:%s", email)
-		config.RedisClient.Set(redisCtx, rateLimitKey, "1", time.Minute)
+		as.redisClient.Set(ctx, rateLimitKey, "1", time.Minute)
 	}
 
 	// 7. 异步发送邮件
 	go func() {
+		ctx := context.WithoutCancel(ctx)
 		verificationLink := fmt.Sprintf("http://localhost:5173/verify-email?email=%s&code=%s", email, verificationCode)
-		as.queueEmail(&EmailTask{
-			Type:    "verification",
-			ToEmail: email,
-			Subject: "Verify Your Email Address",
-			HTMLBody: fmt.Sprintf(`
-				<h2>Email Verification</h2>
-				<p>Hello,</p>
-				<p>Your new verification code is: <strong>%s</strong></p>
-				<p>Or click the link below to verify:</p>
-				<p><a href="%s">Verify Email</a></p>
-				<p>This code will expire in 30 minutes.</p>
-			`, verificationCode, verificationLink),
+		as.queueEmail(ctx, &EmailTask{
+			Type:      "verification",
+			ToEmail:   email,
+			Subject:   utils.T(lang, "email.verification.subject"),
+			HTMLBody:  utils.T(lang, "email.verification_resend.html", verificationCode, verificationLink),
 			Timestamp: time.Now(),
 		})
 	}()
@@ -601,18 +683,18 @@ func (as *AuthService) ResendVerificationCode(email string) error {
 // ==================== 密码重置方法 ====================
 
 // SendPasswordResetToken 发送密码重置令牌
-func (as *AuthService) SendPasswordResetToken(email string) error {
+func (as *AuthService) SendPasswordResetToken(ctx context.Context, email, lang string) error {
 	// 1. 检查用户是否存在
 	var user models.User
-	if err := config.DB.Where("email = ?", email).First(&user).Error; err != nil {
+	if err := as.db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
 		// 为了安全，即使用户不存在也返回成功
 		return nil
 	}
 
 	// 2. 检查发送频率
-	if config.RedisClient != nil {
+	if as.redisClient != nil {
 		rateLimitKey := fmt.Sprintf("reset:rate_limit:%s", email)
-		count, _ := config.RedisClient.Get(redisCtx, rateLimitKey).Int64()
+		count, _ := as.redisClient.Get(ctx, rateLimitKey).Int64()
 		if count > 0 {
 			return errors.New("please wait before requesting another password reset")
 		}
@@ -623,30 +705,23 @@ func (as *AuthService) SendPasswordResetToken(email string) error {
 
 	// 4. 存储到Redis（30分钟有效）
 	resetKey := fmt.Sprintf("reset:password:%s:%s", email, resetToken)
-	config.RedisClient.Set(redisCtx, resetKey, "1", 30*time.Minute)
+	as.redisClient.Set(ctx, resetKey, "1", 30*time.Minute)
 
 	// 5. 设置发送频率限制（5分钟内不能重复发送）
-	if config.RedisClient != nil {
+	if as.redisClient != nil {
 		rateLimitKey := fmt.Sprintf("reset:rate_limit:%s", email)
-		config.RedisClient.Set(redisCtx, rateLimitKey, "1", 5*time.Minute)
+		as.redisClient.Set(ctx, rateLimitKey, "1", 5*time.Minute)
 	}
 
 	// 6. 异步发送邮件
 	go func() {
+		ctx := context.WithoutCancel(ctx)
 		resetLink := fmt.Sprintf("http://localhost:5173/reset-password?email=%s&token=%s", email, resetToken)
-		as.queueEmail(&EmailTask{
-			Type:    "password_reset",
-			ToEmail: email,
-			Subject: "Reset Your Password",
-			HTMLBody: fmt.Sprintf(`
-				<h2>Password Reset Request</h2>
-				<p>Hello,</p>
-				<p>We received a request to reset your password.</p>
-				<p>Click the link below to reset your password:</p>
-				<p><a href="%s">Reset Password</a></p>
-				<p>This link will expire in 30 minutes.</p>
-				<p>If you did not request a password reset, please ignore this email.</p>
-			`, resetLink),
+		as.queueEmail(ctx, &EmailTask{
+			Type:      "password_reset",
+			ToEmail:   email,
+			Subject:   utils.T(lang, "email.password_reset.subject"),
+			HTMLBody:  utils.T(lang, "email.password_reset.html", resetLink),
 			Timestamp: time.Now(),
 		})
 	}()
@@ -655,10 +730,10 @@ func (as *AuthService) SendPasswordResetToken(email string) error {
 }
 
 // ResetPassword 重置密码
-func (as *AuthService) ResetPassword(email, token, newPassword string) error {
+func (as *AuthService) ResetPassword(ctx context.Context, email, token, newPassword, lang string) error {
 	// 1. 验证重置令牌
 	resetKey := fmt.Sprintf("reset:password:%s:%s", email, token)
-	exists, _ := config.RedisClient.Exists(redisCtx, resetKey).Result()
+	exists, _ := as.redisClient.Exists(ctx, resetKey).Result()
 	if exists == 0 {
 		return errors.New("reset token has expired or is invalid")
 	}
@@ -670,7 +745,7 @@ func (as *AuthService) ResetPassword(email, token, newPassword string) error {
 
 	// 3. 查找用户
 	var user models.User
-	if err := config.DB.Where("email = ?", email).First(&user).Error; err != nil {
+	if err := as.db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
 		return errors.New("user not found")
 	}
 
@@ -681,31 +756,21 @@ func (as *AuthService) ResetPassword(email, token, newPassword string) error {
 	}
 
 	// 5. 更新密码
-	if err := config.DB.Model(&user).Update("password", string(hashedPassword)).Error; err != nil {
+	if err := as.db.WithContext(ctx).Model(&user).Update("password", string(hashedPassword)).Error; err != nil {
 		return fmt.Errorf("failed to update password: %w", err)
 	}
 
 	// 6. 删除重置令牌
-	config.RedisClient.Del(redisCtx, resetKey)
-
-	// 7. 删除所有该用户的活跃token（强制重新登录）
-	go func() {
-		if config.RedisClient != nil {
-			pattern := fmt.Sprintf("token:blacklist:%s:*", user.ID)
-			keys, _ := config.RedisClient.Keys(redisCtx, pattern).Result()
-			for _, key := range keys {
-				config.RedisClient.Del(redisCtx, key)
-			}
-		}
-	}()
+	as.redisClient.Del(ctx, resetKey)
 
-	// 8. 异步发送密码修改通知邮件
+	// 7. 异步发送密码修改通知邮件
 	go func() {
-		as.queueEmail(&EmailTask{
+		ctx := context.WithoutCancel(ctx)
+		as.queueEmail(ctx, &EmailTask{
 			Type:      "password_changed",
 			ToEmail:   email,
-			Subject:   "Your Password Has Been Changed",
-			Body:      fmt.Sprintf("Hello %s,\n\nYour password has been successfully changed. If you did not make this change, please contact support immediately.\n\nBest regards,\nWeOUC BookCycle Team", user.Username),
+			Subject:   utils.T(lang, "email.password_changed.subject"),
+			Body:      utils.T(lang, "email.password_changed.body", user.Username),
 			Timestamp: time.Now(),
 		})
 	}()
@@ -716,7 +781,7 @@ func (as *AuthService) ResetPassword(email, token, newPassword string) error {
 // ==================== IP封禁相关方法 ====================
 
 // isIPBlocked 检查IP是否被封禁
-func (as *AuthService) isIPBlocked(ip string) bool {
+func (as *AuthService) isIPBlocked(ctx context.Context, ip string) bool {
 	// 1. 检查内存缓存
 	if info, exists := as.ipBlockCache.Load(ip); exists {
 		blockInfo := info.(*BlockInfo)
@@ -728,9 +793,9 @@ func (as *AuthService) isIPBlocked(ip string) bool {
 	}
 
 	// 2. 检查Redis
-	if config.RedisClient != nil {
+	if as.redisClient != nil {
 		blockKey := fmt.Sprintf("ip:blocked:%s", ip)
-		exists, _ := config.RedisClient.Exists(redisCtx, blockKey).Result()
+		exists, _ := as.redisClient.Exists(ctx, blockKey).Result()
 		if exists > 0 {
 			return true
 		}
@@ -740,7 +805,7 @@ func (as *AuthService) isIPBlocked(ip string) bool {
 }
 
 // blockIP 封禁IP
-func (as *AuthService) blockIP(ip, reason string) {
+func (as *AuthService) blockIP(ctx context.Context, ip, reason string) {
 	unblockTime := time.Now().Add(as.authConfig.LoginBlockDuration)
 
 	// 1. 存储到内存缓存（快速检查）
@@ -750,18 +815,18 @@ func (as *AuthService) blockIP(ip, reason string) {
 	})
 
 	// 2. 存储到Redis（持久化）
-	if config.RedisClient != nil {
+	if as.redisClient != nil {
 		blockKey := fmt.Sprintf("ip:blocked:%s", ip)
 		blockData := map[string]interface{}{
 			"blocked_at": time.Now().Unix(),
 			"unblock_at": unblockTime.Unix(),
 			"reason":     reason,
 		}
-		config.RedisClient.HMSet(redisCtx, blockKey, blockData)
-		config.RedisClient.Expire(redisCtx, blockKey, as.authConfig.LoginBlockDuration)
+		as.redisClient.HMSet(ctx, blockKey, blockData)
+		as.redisClient.Expire(ctx, blockKey, as.authConfig.LoginBlockDuration)
 
 		// 记录到日志
-		config.RedisClient.XAdd(redisCtx, &redis.XAddArgs{
+		as.redisClient.XAdd(ctx, &redis.XAddArgs{
 			Stream: "security_events",
 			Values: map[string]interface{}{
 				"event":      "ip_blocked",
@@ -775,17 +840,17 @@ func (as *AuthService) blockIP(ip, reason string) {
 }
 
 // unblockIP 解封IP
-func (as *AuthService) unblockIP(ip string) {
+func (as *AuthService) unblockIP(ctx context.Context, ip string) {
 	// 1. 从内存缓存删除
 	as.ipBlockCache.Delete(ip)
 
 	// 2. 从Redis删除
-	if config.RedisClient != nil {
+	if as.redisClient != nil {
 		blockKey := fmt.Sprintf("ip:blocked:%s", ip)
-		config.RedisClient.Del(redisCtx, blockKey)
+		as.redisClient.Del(ctx, blockKey)
 
 		// 记录到日志
-		config.RedisClient.XAdd(redisCtx, &redis.XAddArgs{
+		as.redisClient.XAdd(ctx, &redis.XAddArgs{
 			Stream: "security_events",
 			Values: map[string]interface{}{
 				"event":     "ip_unblocked",
@@ -797,78 +862,96 @@ func (as *AuthService) unblockIP(ip string) {
 }
 
 // recordSuspiciousActivity 记录可疑行为
-func (as *AuthService) recordSuspiciousActivity(ip, reason string) {
+func (as *AuthService) recordSuspiciousActivity(ctx context.Context, ip, reason string) {
 	suspiciousKey := fmt.Sprintf("suspicious:%s", ip)
-	count, _ := config.RedisClient.Incr(redisCtx, suspiciousKey).Result()
-	config.RedisClient.Expire(redisCtx, suspiciousKey, time.Hour)
+	count, _ := as.redisClient.Incr(ctx, suspiciousKey).Result()
+	as.redisClient.Expire(ctx, suspiciousKey, time.Hour)
+
+	utils.LogSecurityEvent(as.redisClient, "suspicious_activity", map[string]interface{}{
+		"ip":     ip,
+		"reason": reason,
+		"count":  count,
+	})
 
 	// 如果可疑行为次数超过阈值，自动封禁
 	if count >= 3 {
-		as.blockIP(ip, "suspicious activity detected: "+reason)
+		as.blockIP(ctx, ip, "suspicious activity detected: "+reason)
 	}
 }
 
-// cleanupIPBlocks 定期清理过期的IP封禁
-func (as *AuthService) cleanupIPBlocks() {
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		as.ipBlockCache.Range(func(key, value interface{}) bool {
-			blockInfo := value.(*BlockInfo)
-			if time.Now().After(blockInfo.UnblockTime) {
-				as.ipBlockCache.Delete(key)
-			}
-			return true
-		})
-	}
+// CleanupIPBlocks 清理本实例缓存中已过期的IP封禁，由定时任务 ip_block_cleanup 调用
+func (as *AuthService) CleanupIPBlocks() {
+	as.ipBlockCache.Range(func(key, value interface{}) bool {
+		blockInfo := value.(*BlockInfo)
+		if time.Now().After(blockInfo.UnblockTime) {
+			as.ipBlockCache.Delete(key)
+		}
+		return true
+	})
 }
 
 // ==================== 邮件发送相关方法 ====================
 
-// startEmailWorkers 启动邮件发送worker池
-func (as *AuthService) startEmailWorkers() {
-	for i := 0; i < as.emailWorkers; i++ {
-		go as.emailWorker(i)
+// processEmail 邮件发送任务，失败时由任务队列重试，最后一次仍失败时写入死信表
+// SMTP熔断期间邮件重新入队延后发送，不消耗重试次数
+func (as *AuthService) processEmail(ctx context.Context, task EmailTask) error {
+	err := as.sendEmail(&task)
+	if err == nil {
+		utils.EmailsTotal.WithLabelValues(task.Type, "sent").Inc()
+		return nil
 	}
-}
-
-// emailWorker 邮件发送worker
-func (as *AuthService) emailWorker(workerID int) {
-	for task := range as.emailQueue {
-		err := as.sendEmail(task)
-		if err != nil {
-			// 重试逻辑
-			task.Retries++
-			if task.Retries < 3 {
-				time.Sleep(time.Second * time.Duration(task.Retries))
-				as.emailQueue <- task
-			} else {
-				// 记录失败日志
-				as.logEmailFailure(task, err)
-			}
+	if utils.IsCircuitOpen(err) && !utils.IsFinalJobAttempt(ctx) && time.Since(task.Timestamp) < emailDeferLimit {
+		if utils.EnqueueJob(ctx, JobEmailSend, &task, asynq.ProcessIn(as.emailConfig.deferDelay)) == nil {
+			utils.EmailsTotal.WithLabelValues(task.Type, "deferred").Inc()
+			return nil
 		}
 	}
+	if !utils.IsFinalJobAttempt(ctx) {
+		utils.EmailsTotal.WithLabelValues(task.Type, "retry").Inc()
+		return err
+	}
+
+	utils.EmailsTotal.WithLabelValues(task.Type, "failed").Inc()
+	task.Retries = utils.JobAttempt(ctx)
+	as.logEmailFailure(&task, err)
+	// 已进入死信表，由管理员重试，不再由任务队列归档
+	return nil
+}
+
+// SendIdentityCode 发送绑定邮箱的验证码
+func (as *AuthService) SendIdentityCode(ctx context.Context, email, code, lang string) {
+	as.queueEmail(ctx, &EmailTask{
+		Type:      "identity_code",
+		ToEmail:   email,
+		Subject:   utils.T(lang, "email.identity_code.subject"),
+		HTMLBody:  utils.T(lang, "email.identity_code.html", code),
+		Timestamp: time.Now(),
+	})
 }
 
-// queueEmail 将邮件任务加入队列
-func (as *AuthService) queueEmail(task *EmailTask) {
-	select {
-	case as.emailQueue <- task:
-	default:
-		// 队列满，记录日志但不阻塞
+// queueEmail 提交邮件发送任务
+func (as *AuthService) queueEmail(ctx context.Context, task *EmailTask) {
+	if err := utils.EnqueueJob(ctx, JobEmailSend, task); err != nil {
+		// 无法入队时进入死信，不阻塞调用方
+		utils.EmailsTotal.WithLabelValues(task.Type, "dropped").Inc()
+		as.logEmailFailure(task, err)
 	}
 }
 
-// sendEmail 发送邮件（实际实现）
+// sendEmail 发送邮件
 func (as *AuthService) sendEmail(task *EmailTask) error {
+	return as.emailConfig.send(task)
+}
+
+// send 通过SMTP发送邮件（实际实现）
+func (ec *EmailConfig) send(task *EmailTask) error {
 	// 如果没有配置SMTP，直接返回成功（测试环境）
-	if as.emailConfig.SMTPHost == "" || as.emailConfig.SMTPUser == "" {
+	if ec.SMTPHost == "" || ec.SMTPUser == "" {
 		return nil
 	}
 
 	// 构建邮件
-	from := mail.Address{Name: as.emailConfig.FromName, Address: as.emailConfig.FromEmail}
+	from := mail.Address{Name: ec.FromName, Address: ec.FromEmail}
 	to := mail.Address{Name: "", Address: task.ToEmail}
 
 	// 设置邮件头
@@ -893,11 +976,13 @@ func (as *AuthService) sendEmail(task *EmailTask) error {
 	}
 
 	// 连接SMTP服务器
-	smtpServer := fmt.Sprintf("%s:%d", as.emailConfig.SMTPHost, as.emailConfig.SMTPPort)
-	smtpAuth := smtp.PlainAuth("", as.emailConfig.SMTPUser, as.emailConfig.SMTPPassword, as.emailConfig.SMTPHost)
+	smtpServer := fmt.Sprintf("%s:%d", ec.SMTPHost, ec.SMTPPort)
+	smtpAuth := smtp.PlainAuth("", ec.SMTPUser, ec.SMTPPassword, ec.SMTPHost)
 
-	// 发送邮件
-	err := smtp.SendMail(smtpServer, smtpAuth, as.emailConfig.FromEmail, []string{task.ToEmail}, []byte(message))
+	// 发送邮件，SMTP连续失败时熔断，直接返回 ErrCircuitOpen
+	err := ec.breaker.Execute(func() error {
+		return smtp.SendMail(smtpServer, smtpAuth, ec.FromEmail, []string{task.ToEmail}, []byte(message))
+	})
 	if err != nil {
 		return fmt.Errorf("failed to send email: %w", err)
 	}
@@ -905,58 +990,58 @@ func (as *AuthService) sendEmail(task *EmailTask) error {
 	return nil
 }
 
-// logEmailFailure 记录邮件发送失败
+// logEmailFailure 记录最终发送失败的邮件，写入死信表供管理员查看和重试
 func (as *AuthService) logEmailFailure(task *EmailTask, err error) {
-	// 记录到日志
+	log.Printf("email %s to %s failed after %d attempts: %v", task.Type, task.ToEmail, task.Retries, err)
+	recordEmailDeadLetter(as.db, task, err)
 }
 
 // ==================== 登录失败处理方法 ====================
 
-// startLoginFailureWorker 启动登录失败处理worker
-func (as *AuthService) startLoginFailureWorker() {
-	go func() {
-		for failure := range as.loginFailureQueue {
-			as.processLoginFailure(failure)
-		}
-	}()
+// enqueueLoginFailure 提交登录失败处理任务
+func (as *AuthService) enqueueLoginFailure(ctx context.Context, failure *LoginFailure) {
+	if err := utils.EnqueueJob(ctx, JobAuthLoginFailure, failure); err != nil {
+		log.Printf("Failed to enqueue login failure from %s: %v", failure.IP, err)
+	}
 }
 
 // processLoginFailure 处理登录失败
-func (as *AuthService) processLoginFailure(failure *LoginFailure) {
-	// 1. 记录到Redis Stream
-	if config.RedisClient != nil {
-		config.RedisClient.XAdd(redisCtx, &redis.XAddArgs{
-			Stream: "login_failures",
-			Values: map[string]interface{}{
-				"email":      failure.Email,
-				"ip":         failure.IP,
-				"user_agent": failure.UserAgent,
-				"timestamp":  failure.Timestamp.Unix(),
-			},
-		})
+func (as *AuthService) processLoginFailure(ctx context.Context, failure LoginFailure) error {
+	if as.redisClient == nil {
+		return nil
+	}
+
+	// 1. 记录到Redis Stream，写入失败时重试（此时还没有计数）
+	if err := as.redisClient.XAdd(ctx, &redis.XAddArgs{
+		Stream: "login_failures",
+		Values: map[string]interface{}{
+			"email":      failure.Email,
+			"ip":         failure.IP,
+			"user_agent": failure.UserAgent,
+			"timestamp":  failure.Timestamp.Unix(),
+		},
+	}).Err(); err != nil {
+		return err
 	}
 
 	// 2. 检查该IP在短时间内的失败次数
-	if config.RedisClient != nil {
-		ipFailureKey := fmt.Sprintf("login:failures:ip:%s", failure.IP)
-		count, _ := config.RedisClient.Incr(redisCtx, ipFailureKey).Result()
-		config.RedisClient.Expire(redisCtx, ipFailureKey, time.Hour)
-
-		// 如果失败次数超过阈值，封禁IP
-		if count >= 10 {
-			as.blockIP(failure.IP, "multiple login failures")
-		}
+	ipFailureKey := fmt.Sprintf("login:failures:ip:%s", failure.IP)
+	count, _ := as.redisClient.Incr(ctx, ipFailureKey).Result()
+	as.redisClient.Expire(ctx, ipFailureKey, time.Hour)
+
+	// 如果失败次数超过阈值，封禁IP
+	if count >= 10 {
+		as.blockIP(ctx, failure.IP, "multiple login failures")
 	}
 
 	// 3. 记录到Redis用于告警
-	if config.RedisClient != nil {
-		alertKey := fmt.Sprintf("alert:login_failure:%s", failure.IP)
-		config.RedisClient.Set(redisCtx, alertKey, failure.Timestamp.Unix(), time.Hour)
-	}
+	alertKey := fmt.Sprintf("alert:login_failure:%s", failure.IP)
+	as.redisClient.Set(ctx, alertKey, failure.Timestamp.Unix(), time.Hour)
+	return nil
 }
 
 // recordLoginFailure 记录登录失败
-func (as *AuthService) recordLoginFailure(email, ip, userAgent, reason string) {
+func (as *AuthService) recordLoginFailure(ctx context.Context, email, ip, userAgent, reason string) {
 	failure := &LoginFailure{
 		Email:     email,
 		IP:        ip,
@@ -964,21 +1049,21 @@ func (as *AuthService) recordLoginFailure(email, ip, userAgent, reason string) {
 		Timestamp: time.Now(),
 	}
 
-	as.loginFailureQueue <- failure
+	as.enqueueLoginFailure(ctx, failure)
 
 	// 增加失败计数
-	if config.RedisClient != nil {
+	if as.redisClient != nil {
 		loginLimitKey := fmt.Sprintf("login:limit:%s:%s", email, ip)
-		config.RedisClient.Incr(redisCtx, loginLimitKey)
-		config.RedisClient.Expire(redisCtx, loginLimitKey, as.authConfig.LoginBlockDuration)
+		as.redisClient.Incr(ctx, loginLimitKey)
+		as.redisClient.Expire(ctx, loginLimitKey, as.authConfig.LoginBlockDuration)
 	}
 }
 
 // recordLoginLog 记录登录日志
-func (as *AuthService) recordLoginLog(user *models.User, ip, userAgent string, success bool) {
+func (as *AuthService) recordLoginLog(ctx context.Context, user *models.User, ip, userAgent string, success bool) {
 	// 记录到Redis Stream
-	if config.RedisClient != nil {
-		config.RedisClient.XAdd(redisCtx, &redis.XAddArgs{
+	if as.redisClient != nil {
+		as.redisClient.XAdd(ctx, &redis.XAddArgs{
 			Stream: "login_logs",
 			Values: map[string]interface{}{
 				"user_id":    user.ID,
@@ -994,11 +1079,11 @@ func (as *AuthService) recordLoginLog(user *models.User, ip, userAgent string, s
 }
 
 // recordVerificationFailure 记录验证失败
-func (as *AuthService) recordVerificationFailure(email, reason string) {
-	if config.RedisClient != nil {
+func (as *AuthService) recordVerificationFailure(ctx context.Context, email, reason string) {
+	if as.redisClient != nil {
 		failureKey := fmt.Sprintf("verify:failures:%s", email)
-		config.RedisClient.Incr(redisCtx, failureKey)
-		config.RedisClient.Expire(redisCtx, failureKey, time.Hour)
+		as.redisClient.Incr(ctx, failureKey)
+		as.redisClient.Expire(ctx, failureKey, time.Hour)
 	}
 }
 
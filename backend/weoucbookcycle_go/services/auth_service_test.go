@@ -1,15 +1,15 @@
 package services
 
 import (
-	"os"
 	"testing"
+	"weoucbookcycle_go/config"
 )
 
 // 示例测试：初始化服务并确保不返回 nil
-// 需要设置环境变量以满足配置要求
+// 需要提供 JWT 密钥以满足配置要求
 func TestNewAuthService(t *testing.T) {
-	os.Setenv("JWT_SECRET", "test-secret")
-	svc := NewAuthService()
+	cfg := &config.Config{JWT: config.JWTConfig{SecretKey: "test-secret"}}
+	svc := NewAuthService(Deps{Config: cfg}, NewSystemSettingsService(Deps{}))
 	if svc == nil {
 		t.Fatal("expected auth service instance, got nil")
 	}
@@ -0,0 +1,467 @@
+package services
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"weoucbookcycle_go/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// 备份和数据生命周期维护，由命令行子命令调用（见 main 包的 db/uploads/redis 命令），
+// 小型部署可以直接用系统定时任务运行，不需要安装 mysqldump 等工具
+
+const (
+	// dumpInsertRows/dumpInsertBytes 每条 INSERT 语句最多包含的行数和字节数，避免超过 max_allowed_packet
+	dumpInsertRows  = 200
+	dumpInsertBytes = 1 << 20
+	dumpTimeLayout  = "2006-01-02 15:04:05.999999"
+
+	// redisPruneBatch SCAN 每批返回的key数量
+	redisPruneBatch = 500
+)
+
+// redisPrune 清理原因
+const (
+	RedisPruneCache     = "cache"          // 没有过期时间的缓存
+	RedisPruneNoTTL     = "no_ttl"         // 应当带过期时间的限流、验证码等短期key
+	RedisPruneRetention = "past_retention" // 超过保留期的每日计数器和月度排行
+)
+
+// redisTTLPrefixes 写入时总会设置过期时间的短期key
+// 设置过期时间前进程退出会留下永不过期的key，例如限流计数器会让用户一直被限制
+var redisTTLPrefixes = []string{
+	"alert:",
+	"impersonation:session:",
+	"ip:blocked:",
+	"lock:",
+	"login:failures:",
+	"login:limit:",
+	"notify:fanout:",
+	"rank:book:viewed:",
+	"register:limit:",
+	"reset:",
+	"suspicious:",
+	"task:",
+	"token:blacklist:",
+	"verify:",
+}
+
+// DumpStats 数据库备份结果
+type DumpStats struct {
+	Tables int
+	Rows   int64
+}
+
+// SnapshotStats 文件快照结果
+type SnapshotStats struct {
+	Files int
+	Bytes int64
+}
+
+// RedisPruneResult Redis 清理结果，Deleted 按清理原因统计
+type RedisPruneResult struct {
+	Scanned int64
+	Deleted map[string]int64
+}
+
+// ==================== 数据库 ====================
+
+// DumpDatabase 把所有表的结构和数据以SQL语句写入 w，可用 RestoreDatabase 或 mysql 客户端恢复
+// 在只读的可重复读事务中读取，备份期间的写入不会造成表之间不一致
+func DumpDatabase(ctx context.Context, db *gorm.DB, w io.Writer) (*DumpStats, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	tx, err := sqlDB.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start snapshot: %w", err)
+	}
+	defer tx.Rollback()
+
+	tables, err := dumpTables(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "-- weoucbookcycle database dump, %s\n\n", time.Now().Format(time.RFC3339))
+	bw.WriteString("SET NAMES utf8mb4;\nSET FOREIGN_KEY_CHECKS = 0;\nSET UNIQUE_CHECKS = 0;\n\n")
+
+	stats := &DumpStats{}
+	for _, table := range tables {
+		var name, create string
+		if err := tx.QueryRowContext(ctx, "SHOW CREATE TABLE "+quoteIdent(table)).Scan(&name, &create); err != nil {
+			return nil, fmt.Errorf("failed to read schema of %s: %w", table, err)
+		}
+		fmt.Fprintf(bw, "-- Table %s\nDROP TABLE IF EXISTS %s;\n%s;\n\n", table, quoteIdent(table), create)
+
+		rows, err := dumpRows(ctx, tx, table, bw)
+		if err != nil {
+			return nil, err
+		}
+		stats.Tables++
+		stats.Rows += rows
+	}
+
+	bw.WriteString("SET UNIQUE_CHECKS = 1;\nSET FOREIGN_KEY_CHECKS = 1;\n")
+	if err := bw.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to write dump: %w", err)
+	}
+	return stats, nil
+}
+
+// dumpTables 列出数据库中的表（不含视图）
+func dumpTables(ctx context.Context, tx *sql.Tx) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, "SHOW FULL TABLES WHERE Table_type = 'BASE TABLE'")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name, tableType string
+		if err := rows.Scan(&name, &tableType); err != nil {
+			return nil, fmt.Errorf("failed to list tables: %w", err)
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+// dumpRows 把表中的数据写成多行 INSERT 语句，返回行数
+func dumpRows(ctx context.Context, tx *sql.Tx, table string, w *bufio.Writer) (int64, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT * FROM "+quoteIdent(table))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = quoteIdent(c)
+	}
+	prefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES ", quoteIdent(table), strings.Join(quoted, ", "))
+
+	values := make([]interface{}, len(columns))
+	ptrs := make([]interface{}, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+
+	var count int64
+	var stmt strings.Builder
+	inStmt := 0
+	flush := func() {
+		if inStmt > 0 {
+			stmt.WriteString(";\n")
+			w.WriteString(stmt.String())
+			stmt.Reset()
+			inStmt = 0
+		}
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return count, fmt.Errorf("failed to read %s: %w", table, err)
+		}
+		if inStmt == 0 {
+			stmt.WriteString(prefix)
+		} else {
+			stmt.WriteString(",")
+		}
+		stmt.WriteString("(")
+		for i, v := range values {
+			if i > 0 {
+				stmt.WriteString(",")
+			}
+			stmt.WriteString(sqlLiteral(v))
+		}
+		stmt.WriteString(")")
+		inStmt++
+		count++
+		if inStmt >= dumpInsertRows || stmt.Len() >= dumpInsertBytes {
+			flush()
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return count, fmt.Errorf("failed to read %s: %w", table, err)
+	}
+	flush()
+	if count > 0 {
+		w.WriteString("\n")
+	}
+	return count, nil
+}
+
+// RestoreDatabase 依次执行备份文件中的语句，返回执行的语句数
+// 备份会先删除再重建同名表，同一连接上执行以保证 SET FOREIGN_KEY_CHECKS 等会话设置生效
+func RestoreDatabase(ctx context.Context, db *gorm.DB, r io.Reader) (int, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return 0, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+
+	reader := newSQLStatementReader(r)
+	executed := 0
+	for {
+		stmt, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return executed, nil
+		}
+		if err != nil {
+			return executed, fmt.Errorf("failed to read dump: %w", err)
+		}
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return executed, fmt.Errorf("statement %d failed: %w (%s)", executed+1, err, truncateRunes(stmt, 120))
+		}
+		executed++
+	}
+}
+
+// sqlStatementReader 按行读取备份文件中的语句
+// 备份中字符串里的换行都已转义，只有 CREATE TABLE 跨多行，因此以分号结尾的行即为语句结束
+type sqlStatementReader struct {
+	r *bufio.Reader
+}
+
+func newSQLStatementReader(r io.Reader) *sqlStatementReader {
+	return &sqlStatementReader{r: bufio.NewReaderSize(r, 64*1024)}
+}
+
+// Next 返回下一条语句（不含结尾的分号），没有更多语句时返回 io.EOF
+func (sr *sqlStatementReader) Next() (string, error) {
+	var stmt strings.Builder
+	for {
+		line, err := sr.r.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", err
+		}
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case stmt.Len() == 0 && (trimmed == "" || strings.HasPrefix(trimmed, "--")):
+		case strings.HasSuffix(trimmed, ";"):
+			stmt.WriteString(strings.TrimSuffix(strings.TrimRight(line, " \t\r\n"), ";"))
+			return stmt.String(), nil
+		default:
+			stmt.WriteString(line)
+		}
+		if errors.Is(err, io.EOF) {
+			if strings.TrimSpace(stmt.String()) != "" {
+				return "", fmt.Errorf("unterminated statement: %s", truncateRunes(stmt.String(), 120))
+			}
+			return "", io.EOF
+		}
+	}
+}
+
+// sqlLiteral 把查询结果中的值转换为 SQL 字面量
+// 非 UTF-8 的二进制数据使用十六进制字面量，避免被连接字符集转换
+func sqlLiteral(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		if len(v) > 0 && !utf8.Valid(v) {
+			return "0x" + hex.EncodeToString(v)
+		}
+		return quoteSQLString(string(v))
+	case string:
+		return quoteSQLString(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case time.Time:
+		return quoteSQLString(v.Format(dumpTimeLayout))
+	default:
+		return quoteSQLString(fmt.Sprint(v))
+	}
+}
+
+// quoteSQLString 按 MySQL 的转义规则给字符串加引号
+func quoteSQLString(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) + 2)
+	b.WriteByte('\'')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case 0:
+			b.WriteString(`\0`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\'':
+			b.WriteString(`\'`)
+		case '"':
+			b.WriteString(`\"`)
+		case 0x1a:
+			b.WriteString(`\Z`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
+
+// quoteIdent 给表名、列名加反引号
+func quoteIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// ==================== 上传文件 ====================
+
+// SnapshotStorage 把存储中的所有文件写入 tar 归档，文件名为存储 key
+func SnapshotStorage(ctx context.Context, storage utils.Storage, w io.Writer) (*SnapshotStats, error) {
+	tw := tar.NewWriter(w)
+	stats := &SnapshotStats{}
+	err := storage.Walk(ctx, func(key string, size int64, modTime time.Time) error {
+		src, err := storage.Open(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", key, err)
+		}
+		defer src.Close()
+
+		if err := tw.WriteHeader(&tar.Header{
+			Name:     key,
+			Mode:     0644,
+			Size:     size,
+			ModTime:  modTime,
+			Typeflag: tar.TypeReg,
+		}); err != nil {
+			return err
+		}
+		if _, err := io.CopyN(tw, src, size); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", key, err)
+		}
+		stats.Files++
+		stats.Bytes += size
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// ==================== Redis ====================
+
+// PruneRedis 删除永远不会过期、但已经没有用处的key，dryRun 时只统计不删除
+// 已过期的key在 SCAN 访问时由Redis自动删除，这里处理的是设置过期时间失败留下的key
+func PruneRedis(ctx context.Context, redisClient *redis.Client, dryRun bool) (*RedisPruneResult, error) {
+	result := &RedisPruneResult{Deleted: make(map[string]int64)}
+	now := time.Now()
+
+	var cursor uint64
+	for {
+		keys, next, err := redisClient.Scan(ctx, cursor, "*", redisPruneBatch).Result()
+		if err != nil {
+			return result, fmt.Errorf("failed to scan keys: %w", err)
+		}
+		result.Scanned += int64(len(keys))
+
+		if len(keys) > 0 {
+			pipe := redisClient.Pipeline()
+			ttls := make([]*redis.DurationCmd, len(keys))
+			for i, key := range keys {
+				ttls[i] = pipe.TTL(ctx, key)
+			}
+			if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+				return result, fmt.Errorf("failed to read TTLs: %w", err)
+			}
+
+			var stale []string
+			for i, key := range keys {
+				// TTL 为 -1 表示没有过期时间，-2 表示key已不存在
+				if ttls[i].Val() != -1 {
+					continue
+				}
+				if reason := redisPruneReason(key, now); reason != "" {
+					result.Deleted[reason]++
+					stale = append(stale, key)
+				}
+			}
+			if len(stale) > 0 && !dryRun {
+				if err := redisClient.Unlink(ctx, stale...).Err(); err != nil {
+					return result, fmt.Errorf("failed to delete keys: %w", err)
+				}
+			}
+		}
+
+		if next == 0 {
+			return result, nil
+		}
+		cursor = next
+	}
+}
+
+// redisPruneReason 没有过期时间的 key 是否应删除，返回清理原因，保留时返回空字符串
+func redisPruneReason(key string, now time.Time) string {
+	if isCacheKey(key) {
+		return RedisPruneCache
+	}
+	for _, prefix := range redisTTLPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return RedisPruneNoTTL
+		}
+	}
+
+	// stats:{name}:{YYYY-MM-DD} 已汇总到 daily_stats
+	if rest, ok := strings.CutPrefix(key, "stats:"); ok {
+		if i := strings.LastIndex(rest, ":"); i >= 0 {
+			if day, err := time.ParseInLocation(statsDateLayout, rest[i+1:], now.Location()); err == nil &&
+				now.Sub(day) > statsCounterTTL {
+				return RedisPruneRetention
+			}
+		}
+		return ""
+	}
+	// rank:sellers:{YYYY-MM} 等月度排行，刷新任务只重建本月
+	for _, prefix := range []string{rankSellersKeyPrefix, rankRecyclersKeyPrefix} {
+		if month, ok := strings.CutPrefix(key, prefix); ok {
+			if start, err := time.ParseInLocation("2006-01", month, now.Location()); err == nil &&
+				now.Sub(start) > rankMonthlyTTL {
+				return RedisPruneRetention
+			}
+			return ""
+		}
+	}
+	return ""
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSQLLiteral(t *testing.T) {
+	cases := []struct {
+		in   interface{}
+		want string
+	}{
+		{nil, "NULL"},
+		{int64(-42), "-42"},
+		{uint64(18446744073709551615), "18446744073709551615"},
+		{float64(4.5), "4.5"},
+		{true, "1"},
+		{[]byte("O'Reilly \"Go\"\n\\;"), `'O\'Reilly \"Go\"\n\\;'`},
+		{[]byte{}, "''"},
+		{[]byte{0xff, 0x00, 0x1a}, "0xff001a"},
+		{"二手书\x00\x1a\r", `'二手书\0\Z\r'`},
+		{time.Date(2026, 3, 1, 8, 30, 0, 500000000, time.Local), "'2026-03-01 08:30:00.5'"},
+	}
+	for _, c := range cases {
+		if got := sqlLiteral(c.in); got != c.want {
+			t.Errorf("sqlLiteral(%#v) = %s, want %s", c.in, got, c.want)
+		}
+	}
+}
+
+// CREATE TABLE 跨多行，INSERT 中的分号和转义后的换行不会截断语句
+func TestSQLStatementReader(t *testing.T) {
+	dump := "-- dump header\n\nSET NAMES utf8mb4;\n" +
+		"-- Table books\nDROP TABLE IF EXISTS `books`;\n" +
+		"CREATE TABLE `books` (\n  `id` varchar(36) NOT NULL,\n  `title` text\n) ENGINE=InnoDB;\n\n" +
+		"INSERT INTO `books` (`id`, `title`) VALUES ('1','a; b'),('2','line\\nbreak');\n"
+	reader := newSQLStatementReader(strings.NewReader(dump))
+
+	var got []string
+	for {
+		stmt, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		got = append(got, stmt)
+	}
+	want := []string{
+		"SET NAMES utf8mb4",
+		"DROP TABLE IF EXISTS `books`",
+		"CREATE TABLE `books` (\n  `id` varchar(36) NOT NULL,\n  `title` text\n) ENGINE=InnoDB",
+		"INSERT INTO `books` (`id`, `title`) VALUES ('1','a; b'),('2','line\\nbreak')",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q, want %q", got, want)
+	}
+
+	if _, err := newSQLStatementReader(strings.NewReader("SELECT 1")).Next(); err == nil || errors.Is(err, io.EOF) {
+		t.Errorf("truncated dump should fail, got %v", err)
+	}
+}
+
+func TestRedisPruneReason(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+	cases := map[string]string{
+		"book:123":                    RedisPruneCache,
+		"book:index:123":              "",
+		"register:limit:10.0.0.1":     RedisPruneNoTTL,
+		"lock:search_reindex":         RedisPruneNoTTL,
+		"stats:books:2026-08-01":      RedisPruneRetention,
+		"stats:books:2026-10-15":      "",
+		"stats:register:total":        "",
+		"rank:sellers:2026-07":        RedisPruneRetention,
+		"rank:recyclers:2026-10":      "",
+		"rank:book:views":             "",
+		"rank:book:viewed:b-1:u-1":    RedisPruneNoTTL,
+		"user:login_count:u-1":        "",
+		"online:users":                "",
+		"impersonation:session:abc":   RedisPruneNoTTL,
+		"notify:fanout:book_events:1": RedisPruneNoTTL,
+	}
+	for key, want := range cases {
+		if got := redisPruneReason(key, now); got != want {
+			t.Errorf("redisPruneReason(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	badgeGroup = "badges"
+
+	// badgeBooksRecycledSales 获得 books_recycled_10 需要卖出或送出的数量
+	badgeBooksRecycledSales = 10
+	// fastResponderMinChats / fastResponderMaxMinutes 信誉分统计期内至少有5个聊天，且平均首次回复不超过1小时
+	fastResponderMinChats   = 5
+	fastResponderMaxMinutes = 60.0
+)
+
+// badgeEventStreams 徽章规则消费的领域事件流
+var badgeEventStreams = []string{"user_events", "listing_events"}
+
+// studentEmailSuffixes 学校邮箱的域名后缀
+var studentEmailSuffixes = []string{".edu.cn", ".edu"}
+
+// badgeRule 根据一条领域事件检查相关用户当前满足的徽章，返回用户ID和徽章
+// 规则只看数据库中的当前状态，同一事件重复处理不会重复授予
+type badgeRule func(bs *BadgeService, ctx context.Context, values map[string]interface{}) (string, []string, error)
+
+// badgeRules 事件名 -> 规则，未注册的事件直接确认
+var badgeRules = map[string]badgeRule{
+	"listing_sold":       salesBadgeRule,
+	"email_verified":     studentBadgeRule,
+	"reputation_updated": responderBadgeRule,
+}
+
+// BadgeService 用户徽章：消费领域事件授予徽章，并为资料和聊天提供徽章列表
+type BadgeService struct {
+	db                  *gorm.DB
+	redisClient         *redis.Client
+	notificationService *NotificationService
+}
+
+// NewBadgeService 创建徽章服务实例
+func NewBadgeService(deps Deps, notificationService *NotificationService) *BadgeService {
+	return &BadgeService{
+		db:                  deps.DB,
+		redisClient:         deps.Redis,
+		notificationService: notificationService,
+	}
+}
+
+// Start 启动徽章规则消费者，授予是幂等的，事件可以重复处理
+func (bs *BadgeService) Start(ctx context.Context) {
+	startEventConsumer(ctx, bs.redisClient, badgeGroup, badgeEventStreams, bs.handleEvent)
+}
+
+// handleEvent 对单条事件执行对应的规则并授予满足的徽章
+func (bs *BadgeService) handleEvent(ctx context.Context, stream string, msg redis.XMessage) error {
+	rule, ok := badgeRules[streamString(msg.Values["event"])]
+	if !ok {
+		return nil
+	}
+	userID, badges, err := rule(bs, ctx, msg.Values)
+	if err != nil || userID == "" || len(badges) == 0 {
+		return err
+	}
+	return bs.award(ctx, userID, badges)
+}
+
+// award 授予徽章，只对新获得的徽章发送通知
+func (bs *BadgeService) award(ctx context.Context, userID string, badges []string) error {
+	var awarded []string
+	for _, badge := range badges {
+		result := bs.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.UserBadge{UserID: userID, Badge: badge, AwardedAt: time.Now()})
+		if result.Error != nil {
+			return fmt.Errorf("failed to award %s to %s: %w", badge, userID, result.Error)
+		}
+		if result.RowsAffected > 0 {
+			awarded = append(awarded, badge)
+		}
+	}
+	if len(awarded) == 0 {
+		return nil
+	}
+
+	// 公开资料缓存中带有徽章
+	bs.redisClient.Del(ctx, "user:"+userID)
+
+	lang := UserLanguage(bs.db, userID)
+	for _, badge := range awarded {
+		name := utils.T(lang, "badge."+badge)
+		_, err := bs.notificationService.Notify(ctx, userID, "badge_awarded", utils.T(lang, "notification.badge_awarded.title"),
+			utils.T(lang, "notification.badge_awarded.content", name), map[string]interface{}{"badge": badge})
+		if err != nil && !errors.Is(err, ErrNotificationRateLimited) && !errors.Is(err, ErrRecipientDeactivated) {
+			log.Printf("badges: failed to notify %s of %s: %v", userID, badge, err)
+		}
+	}
+	return nil
+}
+
+// List 获取用户获得的徽章
+func (bs *BadgeService) List(ctx context.Context, userID string) ([]models.UserBadge, error) {
+	badges := []models.UserBadge{}
+	if err := bs.db.WithContext(ctx).Where("user_id = ?", userID).Find(&badges).Error; err != nil {
+		return nil, fmt.Errorf("failed to get badges: %w", err)
+	}
+	sort.SliceStable(badges, func(i, j int) bool {
+		return badgeOrder(badges[i].Badge) < badgeOrder(badges[j].Badge)
+	})
+	return badges, nil
+}
+
+// ForUsers 批量获取用户的徽章名称，用户ID -> 徽章
+func (bs *BadgeService) ForUsers(ctx context.Context, userIDs []string) (map[string][]string, error) {
+	result := make(map[string][]string, len(userIDs))
+	if len(userIDs) == 0 {
+		return result, nil
+	}
+
+	var rows []models.UserBadge
+	if err := bs.db.WithContext(ctx).Where("user_id IN ?", userIDs).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to get badges: %w", err)
+	}
+	for _, row := range rows {
+		result[row.UserID] = append(result[row.UserID], row.Badge)
+	}
+	for userID := range result {
+		sortBadges(result[userID])
+	}
+	return result, nil
+}
+
+// Fill 为用户填充 Badges 字段，查询失败时保持为空（徽章只用于展示）
+func (bs *BadgeService) Fill(ctx context.Context, users ...*models.User) {
+	ids := make([]string, 0, len(users))
+	for _, u := range users {
+		ids = append(ids, u.ID)
+	}
+	badges, err := bs.ForUsers(ctx, ids)
+	if err != nil {
+		log.Printf("badges: %v", err)
+		return
+	}
+	for _, u := range users {
+		u.Badges = badges[u.ID]
+	}
+}
+
+// ==================== 规则 ====================
+
+// salesBadgeRule 发布售出：按卖家累计卖出数授予 first_sale，按卖出和送出的总数授予 books_recycled_10
+func salesBadgeRule(bs *BadgeService, ctx context.Context, values map[string]interface{}) (string, []string, error) {
+	sellerID := streamString(values["seller_id"])
+	if sellerID == "" {
+		return "", nil, nil
+	}
+	var counts struct {
+		Sold    int64
+		Donated int64
+	}
+	if err := bs.db.WithContext(ctx).Model(&models.Listing{}).
+		Select(`COUNT(CASE WHEN is_donation = ? THEN 1 END) AS sold,
+			COUNT(CASE WHEN is_donation = ? THEN 1 END) AS donated`, false, true).
+		Where("seller_id = ? AND status = ?", sellerID, "sold").Scan(&counts).Error; err != nil {
+		return "", nil, fmt.Errorf("failed to count sales: %w", err)
+	}
+	return sellerID, salesBadges(counts.Sold, counts.Donated), nil
+}
+
+// studentBadgeRule 邮箱验证：验证过的邮箱中有学校邮箱时授予 verified_student
+func studentBadgeRule(bs *BadgeService, ctx context.Context, values map[string]interface{}) (string, []string, error) {
+	userID := streamString(values["user_id"])
+	if userID == "" {
+		return "", nil, nil
+	}
+	var user models.User
+	if err := bs.db.WithContext(ctx).Select("id", "email", "email_verified").First(&user, "id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil, nil
+		}
+		return "", nil, fmt.Errorf("failed to load user: %w", err)
+	}
+	var emails []string
+	if err := bs.db.WithContext(ctx).Model(&models.UserIdentity{}).
+		Where("user_id = ? AND provider = ?", userID, models.IdentityEmail).
+		Pluck("subject", &emails).Error; err != nil {
+		return "", nil, fmt.Errorf("failed to load identities: %w", err)
+	}
+
+	for _, email := range verifiedEmails(&user, emails) {
+		if isStudentEmail(email) {
+			return userID, []string{models.BadgeVerifiedStudent}, nil
+		}
+	}
+	return "", nil, nil
+}
+
+// responderBadgeRule 信誉分更新：回复速度达标时授予 fast_responder
+func responderBadgeRule(bs *BadgeService, ctx context.Context, values map[string]interface{}) (string, []string, error) {
+	userID := streamString(values["user_id"])
+	if userID == "" {
+		return "", nil, nil
+	}
+	var rep models.SellerReputation
+	if err := bs.db.WithContext(ctx).First(&rep, "user_id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil, nil
+		}
+		return "", nil, fmt.Errorf("failed to load reputation: %w", err)
+	}
+	if !isFastResponder(&rep) {
+		return "", nil, nil
+	}
+	return userID, []string{models.BadgeFastResponder}, nil
+}
+
+// salesBadges 累计卖出 sold 本、送出 donated 本时满足的徽章
+func salesBadges(sold, donated int64) []string {
+	var badges []string
+	if sold >= 1 {
+		badges = append(badges, models.BadgeFirstSale)
+	}
+	if sold+donated >= badgeBooksRecycledSales {
+		badges = append(badges, models.BadgeBooksRecycled10)
+	}
+	return badges
+}
+
+// verifiedEmails 用户验证过的邮箱
+// 绑定的邮箱都经过验证码确认；注册时的主邮箱只有在 email_verified 后才算
+func verifiedEmails(user *models.User, identityEmails []string) []string {
+	var emails []string
+	if user.Email != "" && user.EmailVerified {
+		emails = append(emails, user.Email)
+	}
+	for _, email := range identityEmails {
+		if email != user.Email {
+			emails = append(emails, email)
+		}
+	}
+	return emails
+}
+
+// isStudentEmail 是否为学校邮箱
+func isStudentEmail(email string) bool {
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := email[at+1:]
+	for _, suffix := range studentEmailSuffixes {
+		if strings.HasSuffix(domain, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isFastResponder 回复速度是否达到 fast_responder 的要求
+func isFastResponder(rep *models.SellerReputation) bool {
+	return rep.ResponseChats >= fastResponderMinChats &&
+		rep.AvgResponseMinutes != nil && *rep.AvgResponseMinutes <= fastResponderMaxMinutes
+}
+
+// badgeOrder 徽章的展示顺序，未知徽章排在最后
+func badgeOrder(badge string) int {
+	for i, b := range models.Badges {
+		if b == badge {
+			return i
+		}
+	}
+	return len(models.Badges)
+}
+
+// sortBadges 按展示顺序排列徽章
+func sortBadges(badges []string) {
+	sort.SliceStable(badges, func(i, j int) bool {
+		return badgeOrder(badges[i]) < badgeOrder(badges[j])
+	})
+}
+
+// ==================== 事件 ====================
+
+// RecordListingSold 记录发布售出（赠书为送出）事件（listing_events），徽章和积分规则据此检查卖家的成交
+func RecordListingSold(ctx context.Context, redisClient *redis.Client, listingID, sellerID, buyerID string, donation bool) {
+	utils.Go(ctx, "listing_events", func(ctx context.Context) error {
+		if redisClient == nil {
+			return nil
+		}
+		return redisClient.XAdd(ctx, &redis.XAddArgs{
+			Stream: "listing_events",
+			Values: map[string]interface{}{
+				"event":      "listing_sold",
+				"listing_id": listingID,
+				"seller_id":  sellerID,
+				"buyer_id":   buyerID,
+				"donation":   strconv.FormatBool(donation),
+				"timestamp":  time.Now().Unix(),
+			},
+		}).Err()
+	})
+}
+
+// recordUserEvent 记录只带用户ID的用户事件（user_events）
+func recordUserEvent(ctx context.Context, redisClient *redis.Client, event, userID string) {
+	utils.Go(ctx, "user_events", func(ctx context.Context) error {
+		if redisClient == nil {
+			return nil
+		}
+		return redisClient.XAdd(ctx, &redis.XAddArgs{
+			Stream: "user_events",
+			Values: map[string]interface{}{
+				"event":     event,
+				"user_id":   userID,
+				"timestamp": time.Now().Unix(),
+			},
+		}).Err()
+	})
+}
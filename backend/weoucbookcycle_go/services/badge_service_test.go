@@ -0,0 +1,82 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"weoucbookcycle_go/models"
+)
+
+func TestSalesBadges(t *testing.T) {
+	cases := []struct {
+		sold, donated int64
+		want          []string
+	}{
+		{0, 0, nil},
+		{1, 0, []string{models.BadgeFirstSale}},
+		{9, 0, []string{models.BadgeFirstSale}},
+		{10, 0, []string{models.BadgeFirstSale, models.BadgeBooksRecycled10}},
+		// 赠书计入循环数量，但不算成交
+		{0, 10, []string{models.BadgeBooksRecycled10}},
+		{4, 6, []string{models.BadgeFirstSale, models.BadgeBooksRecycled10}},
+	}
+	for _, tc := range cases {
+		if got := salesBadges(tc.sold, tc.donated); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("salesBadges(%d, %d) = %v, want %v", tc.sold, tc.donated, got, tc.want)
+		}
+	}
+}
+
+func TestIsStudentEmail(t *testing.T) {
+	cases := map[string]bool{
+		"alice@stu.ouc.edu.cn":   true,
+		"Bob@OUC.EDU.CN":         true,
+		"carol@mit.edu":          true,
+		"dave@qq.com":            false,
+		"eve@edu.cn.example.com": false,
+		"not-an-email":           false,
+	}
+	for email, want := range cases {
+		if got := isStudentEmail(email); got != want {
+			t.Errorf("isStudentEmail(%q) = %v, want %v", email, got, want)
+		}
+	}
+}
+
+func TestVerifiedEmails(t *testing.T) {
+	user := &models.User{Email: "a@stu.ouc.edu.cn"}
+	// 注册时的主邮箱未验证，不算
+	if got := verifiedEmails(user, []string{"a@stu.ouc.edu.cn", "b@qq.com"}); !reflect.DeepEqual(got, []string{"b@qq.com"}) {
+		t.Errorf("unverified primary: got %v", got)
+	}
+	user.EmailVerified = true
+	if got := verifiedEmails(user, []string{"a@stu.ouc.edu.cn"}); !reflect.DeepEqual(got, []string{"a@stu.ouc.edu.cn"}) {
+		t.Errorf("verified primary: got %v", got)
+	}
+}
+
+func TestIsFastResponder(t *testing.T) {
+	fast, slow := 30.0, 90.0
+	cases := []struct {
+		rep  models.SellerReputation
+		want bool
+	}{
+		{models.SellerReputation{ResponseChats: 5, AvgResponseMinutes: &fast}, true},
+		{models.SellerReputation{ResponseChats: 4, AvgResponseMinutes: &fast}, false},
+		{models.SellerReputation{ResponseChats: 20, AvgResponseMinutes: &slow}, false},
+		{models.SellerReputation{ResponseChats: 5}, false},
+	}
+	for i, tc := range cases {
+		if got := isFastResponder(&tc.rep); got != tc.want {
+			t.Errorf("case %d: isFastResponder = %v, want %v", i, got, tc.want)
+		}
+	}
+}
+
+func TestSortBadges(t *testing.T) {
+	badges := []string{"unknown", models.BadgeFastResponder, models.BadgeFirstSale, models.BadgeVerifiedStudent}
+	sortBadges(badges)
+	want := []string{models.BadgeVerifiedStudent, models.BadgeFirstSale, models.BadgeFastResponder, "unknown"}
+	if !reflect.DeepEqual(badges, want) {
+		t.Errorf("sortBadges = %v, want %v", badges, want)
+	}
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrCannotBlockSelf = utils.NewError(http.StatusBadRequest, "you cannot block yourself")
+
+// BlockState 两个用户之间的屏蔽状态，屏蔽导致操作被拒绝时在错误响应的 data 中返回
+type BlockState struct {
+	BlockedByMe bool `json:"blocked_by_me"` // 我屏蔽了对方
+	BlockedMe   bool `json:"blocked_me"`    // 对方屏蔽了我
+}
+
+// BlockedUser 屏蔽列表中的用户
+type BlockedUser struct {
+	models.PublicUser
+	BlockedAt time.Time `json:"blocked_at"`
+}
+
+// BlockService 用户屏蔽服务
+type BlockService struct {
+	db            *gorm.DB
+	followService *FollowService
+}
+
+// NewBlockService 创建屏蔽服务实例，屏蔽时通过关注服务解除双方的关注
+func NewBlockService(deps Deps, followService *FollowService) *BlockService {
+	return &BlockService{
+		db:            deps.DB,
+		followService: followService,
+	}
+}
+
+// Block 屏蔽用户并解除双方的关注，重复屏蔽不报错
+func (bs *BlockService) Block(ctx context.Context, blockerID, blockedID string) error {
+	if blockerID == blockedID {
+		return ErrCannotBlockSelf
+	}
+
+	var blocked models.User
+	if err := bs.db.WithContext(ctx).Select("id").First(&blocked, "id = ?", blockedID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+
+	return WithTx(ctx, bs.db, func(ctx context.Context, tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.UserBlock{BlockerID: blockerID, BlockedID: blockedID})
+		if result.Error != nil {
+			return fmt.Errorf("failed to block user: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		if err := bs.followService.Unfollow(ctx, blockerID, blockedID); err != nil {
+			return err
+		}
+		return bs.followService.Unfollow(ctx, blockedID, blockerID)
+	})
+}
+
+// Unblock 取消屏蔽，未屏蔽时不报错；之前解除的关注不会恢复
+func (bs *BlockService) Unblock(ctx context.Context, blockerID, blockedID string) error {
+	if err := bs.db.WithContext(ctx).
+		Where("blocker_id = ? AND blocked_id = ?", blockerID, blockedID).
+		Delete(&models.UserBlock{}).Error; err != nil {
+		return fmt.Errorf("failed to unblock user: %w", err)
+	}
+	return nil
+}
+
+// List 分页获取我屏蔽的用户，最近屏蔽的在前
+func (bs *BlockService) List(ctx context.Context, blockerID string, page, limit int) ([]BlockedUser, int64, error) {
+	query := bs.db.WithContext(ctx).Model(&models.UserBlock{}).Where("blocker_id = ?", blockerID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count blocked users: %w", err)
+	}
+
+	var blocks []models.UserBlock
+	if err := query.Order("created_at DESC").
+		Offset(utils.PageOffset(page, limit)).Limit(limit).
+		Find(&blocks).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list blocked users: %w", err)
+	}
+	if len(blocks) == 0 {
+		return []BlockedUser{}, total, nil
+	}
+
+	ids := make([]string, len(blocks))
+	for i, b := range blocks {
+		ids[i] = b.BlockedID
+	}
+	var users []models.User
+	if err := bs.db.WithContext(ctx).Find(&users, "id IN ?", ids).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to load blocked users: %w", err)
+	}
+	byID := make(map[string]*models.User, len(users))
+	for i := range users {
+		byID[users[i].ID] = &users[i]
+	}
+
+	// 已注销的用户不显示
+	result := make([]BlockedUser, 0, len(blocks))
+	for _, b := range blocks {
+		if u, ok := byID[b.BlockedID]; ok {
+			result = append(result, BlockedUser{PublicUser: u.Public(), BlockedAt: b.CreatedAt})
+		}
+	}
+	return result, total, nil
+}
+
+// HasBlocked blockerID 是否屏蔽了 blockedID，查询失败时视为未屏蔽
+func (bs *BlockService) HasBlocked(ctx context.Context, blockerID, blockedID string) bool {
+	if blockerID == "" || blockedID == "" || blockerID == blockedID {
+		return false
+	}
+	var count int64
+	bs.db.WithContext(ctx).Model(&models.UserBlock{}).
+		Where("blocker_id = ? AND blocked_id = ?", blockerID, blockedID).Count(&count)
+	return count > 0
+}
+
+// VisibleContact 返回 viewerID 可以看到的 userID 的联系方式（邮箱、手机号）
+// 未登录、被 userID 屏蔽或查询失败时返回nil
+func (bs *BlockService) VisibleContact(ctx context.Context, userID, viewerID string) *models.User {
+	if viewerID == "" || bs.HasBlocked(ctx, userID, viewerID) {
+		return nil
+	}
+	var contact models.User
+	if err := bs.db.WithContext(ctx).Select("id", "email", "phone").First(&contact, "id = ?", userID).Error; err != nil {
+		return nil
+	}
+	return &contact
+}
+
+// Check 检查 userID 与 otherIDs 之间是否有任一方向的屏蔽，有时返回带 BlockState 的403错误
+func (bs *BlockService) Check(ctx context.Context, userID string, otherIDs ...string) error {
+	return checkBlocked(ctx, bs.db, userID, otherIDs...)
+}
+
+// checkBlocked 供不依赖 BlockService 的服务（聊天、关注）直接检查屏蔽关系
+func checkBlocked(ctx context.Context, db *gorm.DB, userID string, otherIDs ...string) error {
+	if len(otherIDs) == 0 {
+		return nil
+	}
+	var blocks []models.UserBlock
+	if err := db.WithContext(ctx).
+		Where("(blocker_id = ? AND blocked_id IN ?) OR (blocked_id = ? AND blocker_id IN ?)", userID, otherIDs, userID, otherIDs).
+		Find(&blocks).Error; err != nil {
+		return fmt.Errorf("failed to check blocks: %w", err)
+	}
+	if len(blocks) == 0 {
+		return nil
+	}
+	return blockedError(blockStateOf(userID, blocks))
+}
+
+// blockStateOf 从 userID 的角度汇总屏蔽关系
+func blockStateOf(userID string, blocks []models.UserBlock) BlockState {
+	var state BlockState
+	for _, b := range blocks {
+		if b.BlockerID == userID {
+			state.BlockedByMe = true
+		} else {
+			state.BlockedMe = true
+		}
+	}
+	return state
+}
+
+// blockedError 屏蔽导致操作被拒绝的错误，自己屏蔽了对方时提示先取消屏蔽
+func blockedError(state BlockState) error {
+	message := "this user has blocked you"
+	if state.BlockedByMe {
+		message = "you have blocked this user, unblock them first"
+	}
+	err := utils.NewError(http.StatusForbidden, message)
+	err.Data = state
+	return err
+}
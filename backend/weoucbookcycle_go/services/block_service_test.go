@@ -0,0 +1,31 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+)
+
+// 屏蔽错误为403并在 Data 中给出从当前用户角度看的屏蔽状态
+func TestBlockedError(t *testing.T) {
+	cases := []struct {
+		blocks []models.UserBlock
+		want   BlockState
+	}{
+		{[]models.UserBlock{{BlockerID: "me", BlockedID: "u"}}, BlockState{BlockedByMe: true}},
+		{[]models.UserBlock{{BlockerID: "u", BlockedID: "me"}}, BlockState{BlockedMe: true}},
+		{[]models.UserBlock{{BlockerID: "me", BlockedID: "u"}, {BlockerID: "u", BlockedID: "me"}}, BlockState{BlockedByMe: true, BlockedMe: true}},
+	}
+	for _, tc := range cases {
+		err := blockedError(blockStateOf("me", tc.blocks))
+		var appErr *utils.AppError
+		if !errors.As(err, &appErr) || appErr.Status != http.StatusForbidden {
+			t.Fatalf("expected 403 AppError, got %v", err)
+		}
+		if state, ok := appErr.Data.(BlockState); !ok || state != tc.want {
+			t.Errorf("blocks %+v: got data %+v, want %+v", tc.blocks, appErr.Data, tc.want)
+		}
+	}
+}
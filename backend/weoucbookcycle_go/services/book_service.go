@@ -1,27 +1,38 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"sync"
 	"time"
 	"weoucbookcycle_go/config"
 	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
 
+	"github.com/hibiken/asynq"
 	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
 )
 
 // BookService 书籍服务
 type BookService struct {
-	// 浏览统计队列
-	viewStatsQueue chan *BookViewStat
-	// 点赞统计队列
-	likeStatsQueue chan *BookLikeStat
-	// 搜索索引队列
-	indexQueue chan *BookIndexTask
+	db          *gorm.DB
+	redisClient *redis.Client
+	searchIndex *SearchIndexService
+	synonyms    *SynonymService
+	settings    *SystemSettingsService
 }
 
+// 书籍后台任务类型
+const (
+	JobBookView  = "book:view"
+	JobBookLike  = "book:like"
+	JobBookIndex = "book:index"
+)
+
 // BookViewStat 书籍浏览统计
 type BookViewStat struct {
 	BookID    string
@@ -44,19 +55,19 @@ type BookIndexTask struct {
 	Action string // "index", "remove"
 }
 
-// NewBookService 创建书籍服务实例
-func NewBookService() *BookService {
+// NewBookService 创建书籍服务实例，并注册统计和索引后台任务的处理函数
+func NewBookService(deps Deps, searchIndex *SearchIndexService, synonyms *SynonymService, settings *SystemSettingsService) *BookService {
 	bs := &BookService{
-		viewStatsQueue: make(chan *BookViewStat, 2000),
-		likeStatsQueue: make(chan *BookLikeStat, 2000),
-		indexQueue:     make(chan *BookIndexTask, 1000),
+		db:          deps.DB,
+		redisClient: deps.Redis,
+		searchIndex: searchIndex,
+		synonyms:    synonyms,
+		settings:    settings,
 	}
 
-	// 启动统计worker池
-	bs.startStatsWorkers()
-
-	// 启动索引worker
-	bs.startIndexWorker()
+	utils.HandleJob(JobBookView, bs.processViewStat, asynq.Queue(utils.JobQueueLow), asynq.MaxRetry(3))
+	utils.HandleJob(JobBookLike, bs.processLikeStat, asynq.Queue(utils.JobQueueLow), asynq.MaxRetry(3))
+	utils.HandleJob(JobBookIndex, bs.processIndexTask, asynq.MaxRetry(5))
 
 	return bs
 }
@@ -67,7 +78,7 @@ func NewBookService() *BookService {
 type CreateBookRequest struct {
 	Title       string   `json:"title" binding:"required,max=200"`
 	Author      string   `json:"author" binding:"required,max=100"`
-	ISBN        string   `json:"isbn" binding:"omitempty"`
+	ISBN        string   `json:"isbn" binding:"omitempty,isbn"`
 	Category    string   `json:"category" binding:"required"`
 	Price       float64  `json:"price" binding:"required,gt=0"`
 	Description string   `json:"description"`
@@ -79,7 +90,7 @@ type CreateBookRequest struct {
 type UpdateBookRequest struct {
 	Title       string   `json:"title" binding:"omitempty,max=200"`
 	Author      string   `json:"author" binding:"omitempty,max=100"`
-	ISBN        string   `json:"isbn" binding:"omitempty"`
+	ISBN        string   `json:"isbn" binding:"omitempty,isbn"`
 	Category    string   `json:"category" binding:"omitempty"`
 	Price       float64  `json:"price" binding:"omitempty,gt=0"`
 	Description string   `json:"description"`
@@ -89,7 +100,7 @@ type UpdateBookRequest struct {
 }
 
 // CreateBook 创建书籍
-func (bs *BookService) CreateBook(userID string, req *CreateBookRequest) (*models.Book, error) {
+func (bs *BookService) CreateBook(ctx context.Context, userID string, req *CreateBookRequest) (*models.Book, error) {
 	// 1. 验证ISBN格式（如果提供）
 	if req.ISBN != "" {
 		if !isValidISBN(req.ISBN) {
@@ -98,7 +109,7 @@ func (bs *BookService) CreateBook(userID string, req *CreateBookRequest) (*model
 
 		// 检查ISBN是否已存在
 		var existingBook models.Book
-		if err := config.DB.Where("isbn = ?", req.ISBN).First(&existingBook).Error; err == nil {
+		if err := bs.db.WithContext(ctx).Where("isbn = ?", req.ISBN).First(&existingBook).Error; err == nil {
 			return nil, errors.New("ISBN already exists")
 		}
 	}
@@ -122,45 +133,50 @@ func (bs *BookService) CreateBook(userID string, req *CreateBookRequest) (*model
 		LikeCount:   0,
 	}
 
-	if err := config.DB.Create(&book).Error; err != nil {
+	if err := bs.db.WithContext(ctx).Create(&book).Error; err != nil {
 		return nil, fmt.Errorf("failed to create book: %w", err)
 	}
 
 	// 4. 异步清除缓存
-	go bs.clearBookCaches(book.ID)
+	go bs.clearBookCaches(context.WithoutCancel(ctx), book.ID)
 
 	// 5. 异步添加到搜索索引
-	go func() {
-		bs.indexQueue <- &BookIndexTask{
-			BookID: book.ID,
-			Action: "index",
-		}
-	}()
+	go bs.enqueueIndexTask(context.WithoutCancel(ctx), &BookIndexTask{
+		BookID: book.ID,
+		Action: "index",
+	})
 
 	// 6. 记录创建事件
-	go func() {
-		if config.RedisClient != nil {
-			config.RedisClient.XAdd(redisCtx, &redis.XAddArgs{
-				Stream: "book_events",
-				Values: map[string]interface{}{
-					"event":     "book_created",
-					"book_id":   book.ID,
-					"title":     book.Title,
-					"seller_id": userID,
-					"timestamp": time.Now().Unix(),
-				},
-			})
-		}
-	}()
+	RecordBookCreated(ctx, bs.redisClient, &book)
 
 	return &book, nil
 }
 
+// RecordBookCreated 记录书籍创建事件（book_events），webhook 据此通知订阅的应用
+func RecordBookCreated(ctx context.Context, redisClient *redis.Client, book *models.Book) {
+	utils.Go(ctx, "book_events", func(ctx context.Context) error {
+		RecordDailyStat(ctx, redisClient, StatNewBooks)
+		if redisClient == nil {
+			return nil
+		}
+		return redisClient.XAdd(ctx, &redis.XAddArgs{
+			Stream: "book_events",
+			Values: map[string]interface{}{
+				"event":     "book_created",
+				"book_id":   book.ID,
+				"title":     book.Title,
+				"seller_id": book.SellerID,
+				"timestamp": time.Now().Unix(),
+			},
+		}).Err()
+	})
+}
+
 // UpdateBook 更新书籍
-func (bs *BookService) UpdateBook(userID, bookID string, req *UpdateBookRequest) (*models.Book, error) {
+func (bs *BookService) UpdateBook(ctx context.Context, userID, bookID string, req *UpdateBookRequest) (*models.Book, error) {
 	// 1. 查找书籍
 	var book models.Book
-	if err := config.DB.First(&book, "id = ?", bookID).Error; err != nil {
+	if err := bs.db.First(&book, "id = ?", bookID).Error; err != nil {
 		return nil, errors.New("book not found")
 	}
 
@@ -176,7 +192,7 @@ func (bs *BookService) UpdateBook(userID, bookID string, req *UpdateBookRequest)
 		}
 
 		var existingBook models.Book
-		if err := config.DB.Where("isbn = ? AND id != ?", req.ISBN, bookID).First(&existingBook).Error; err == nil {
+		if err := bs.db.Where("isbn = ? AND id != ?", req.ISBN, bookID).First(&existingBook).Error; err == nil {
 			return nil, errors.New("ISBN already exists")
 		}
 	}
@@ -213,34 +229,32 @@ func (bs *BookService) UpdateBook(userID, bookID string, req *UpdateBookRequest)
 	}
 
 	// 5. 更新数据库
-	if err := config.DB.Model(&book).Updates(updates).Error; err != nil {
+	if err := bs.db.Model(&book).Updates(updates).Error; err != nil {
 		return nil, fmt.Errorf("failed to update book: %w", err)
 	}
 
 	// 6. 重新查询更新后的数据
-	if err := config.DB.First(&book, "id = ?", bookID).Error; err != nil {
+	if err := bs.db.First(&book, "id = ?", bookID).Error; err != nil {
 		return nil, err
 	}
 
 	// 7. 异步清除缓存
-	go bs.clearBookCaches(bookID)
+	go bs.clearBookCaches(context.WithoutCancel(ctx), bookID)
 
 	// 8. 异步更新搜索索引
-	go func() {
-		bs.indexQueue <- &BookIndexTask{
-			BookID: book.ID,
-			Action: "index",
-		}
-	}()
+	go bs.enqueueIndexTask(context.WithoutCancel(ctx), &BookIndexTask{
+		BookID: book.ID,
+		Action: "index",
+	})
 
 	return &book, nil
 }
 
 // DeleteBook 删除书籍
-func (bs *BookService) DeleteBook(userID, bookID string) error {
+func (bs *BookService) DeleteBook(ctx context.Context, userID, bookID string) error {
 	// 1. 查找书籍
 	var book models.Book
-	if err := config.DB.First(&book, "id = ?", bookID).Error; err != nil {
+	if err := bs.db.First(&book, "id = ?", bookID).Error; err != nil {
 		return errors.New("book not found")
 	}
 
@@ -250,20 +264,18 @@ func (bs *BookService) DeleteBook(userID, bookID string) error {
 	}
 
 	// 3. 软删除
-	if err := config.DB.Delete(&book).Error; err != nil {
+	if err := bs.db.Delete(&book).Error; err != nil {
 		return fmt.Errorf("failed to delete book: %w", err)
 	}
 
 	// 4. 异步清除所有相关缓存
-	go bs.clearBookCaches(bookID)
+	go bs.clearBookCaches(context.WithoutCancel(ctx), bookID)
 
 	// 5. 异步从搜索索引移除
-	go func() {
-		bs.indexQueue <- &BookIndexTask{
-			BookID: bookID,
-			Action: "remove",
-		}
-	}()
+	go bs.enqueueIndexTask(context.WithoutCancel(ctx), &BookIndexTask{
+		BookID: bookID,
+		Action: "remove",
+	})
 
 	return nil
 }
@@ -271,20 +283,16 @@ func (bs *BookService) DeleteBook(userID, bookID string) error {
 // ==================== 查询方法 ====================
 
 // GetBook 获取书籍详情
-func (bs *BookService) GetBook(bookID, userID string) (*models.Book, error) {
+func (bs *BookService) GetBook(ctx context.Context, bookID, userID string) (*models.Book, error) {
 	// 1. 尝试从Redis缓存获取
 	cacheKey := fmt.Sprintf("book:%s", bookID)
-	if config.RedisClient != nil {
-		cached, err := config.RedisClient.Get(redisCtx, cacheKey).Result()
+	if bs.redisClient != nil {
+		cached, err := bs.redisClient.Get(ctx, cacheKey).Result()
 		if err == nil {
 			var book models.Book
 			if json.Unmarshal([]byte(cached), &book) == nil {
 				// 异步记录浏览统计
-				bs.viewStatsQueue <- &BookViewStat{
-					BookID:    bookID,
-					UserID:    userID,
-					Timestamp: time.Now(),
-				}
+				bs.RecordView(ctx, bookID, userID)
 				return &book, nil
 			}
 		}
@@ -292,36 +300,33 @@ func (bs *BookService) GetBook(bookID, userID string) (*models.Book, error) {
 
 	// 2. 从数据库查询
 	var book models.Book
-	if err := config.DB.Preload("Seller").First(&book, "id = ?", bookID).Error; err != nil {
+	if err := bs.db.WithContext(ctx).Preload("Seller").First(&book, "id = ?", bookID).Error; err != nil {
 		return nil, errors.New("book not found")
 	}
 
 	// 3. 异步记录浏览统计
-	bs.viewStatsQueue <- &BookViewStat{
-		BookID:    bookID,
-		UserID:    userID,
-		Timestamp: time.Now(),
-	}
+	bs.RecordView(ctx, bookID, userID)
 
 	// 4. 异步缓存到Redis
 	go func() {
+		ctx := context.WithoutCancel(ctx)
 		data, _ := json.Marshal(book)
-		config.RedisClient.Set(redisCtx, cacheKey, data, 10*time.Minute)
+		bs.redisClient.Set(ctx, cacheKey, data, 10*time.Minute)
 	}()
 
 	return &book, nil
 }
 
 // GetBooks 获取书籍列表
-func (bs *BookService) GetBooks(page, limit int, filters map[string]interface{}, sort string) ([]models.Book, int64, error) {
+func (bs *BookService) GetBooks(ctx context.Context, page, limit int, filters map[string]interface{}, sort string) ([]models.Book, int64, error) {
 	offset := (page - 1) * limit
 
 	// 1. 构建缓存key
 	cacheKey := bs.buildBooksCacheKey(page, limit, filters, sort)
 
 	// 2. 尝试从Redis获取
-	if config.RedisClient != nil {
-		cached, err := config.RedisClient.Get(redisCtx, cacheKey).Result()
+	if bs.redisClient != nil {
+		cached, err := bs.redisClient.Get(ctx, cacheKey).Result()
 		if err == nil {
 			var result struct {
 				Books []models.Book `json:"books"`
@@ -333,8 +338,8 @@ func (bs *BookService) GetBooks(page, limit int, filters map[string]interface{},
 		}
 	}
 
-	// 3. 构建查询
-	query := config.DB.Model(&models.Book{}).Where("status = ?", 1)
+	// 3. 构建查询（列表查询走只读副本）
+	query := config.ReadReplica(bs.db.WithContext(ctx)).Model(&models.Book{}).Where("status = ?", 1).Scopes(VisibleUsers("books.seller_id"))
 
 	// 应用筛选条件
 	if category, ok := filters["category"].(string); ok && category != "" {
@@ -375,13 +380,14 @@ func (bs *BookService) GetBooks(page, limit int, filters map[string]interface{},
 
 	// 6. 异步缓存结果
 	go func() {
-		if config.RedisClient != nil {
+		ctx := context.WithoutCancel(ctx)
+		if bs.redisClient != nil {
 			result := struct {
 				Books []models.Book `json:"books"`
 				Total int64         `json:"total"`
 			}{books, total}
 			data, _ := json.Marshal(result)
-			config.RedisClient.Set(redisCtx, cacheKey, data, 5*time.Minute)
+			utils.SetTaggedCache(ctx, bs.redisClient, utils.CacheTagSearch, cacheKey, data, 5*time.Minute)
 		}
 	}()
 
@@ -389,12 +395,12 @@ func (bs *BookService) GetBooks(page, limit int, filters map[string]interface{},
 }
 
 // GetHotBooks 获取热门书籍
-func (bs *BookService) GetHotBooks(limit int) ([]models.Book, error) {
-	cacheKey := "hot:books"
+func (bs *BookService) GetHotBooks(ctx context.Context, limit int) ([]models.Book, error) {
+	cacheKey := HotBooksCacheKey("")
 
 	// 1. 尝试从Redis获取
-	if config.RedisClient != nil {
-		cached, err := config.RedisClient.Get(redisCtx, cacheKey).Result()
+	if bs.redisClient != nil {
+		cached, err := bs.redisClient.Get(ctx, cacheKey).Result()
 		if err == nil {
 			var books []models.Book
 			if json.Unmarshal([]byte(cached), &books) == nil {
@@ -405,8 +411,8 @@ func (bs *BookService) GetHotBooks(limit int) ([]models.Book, error) {
 
 	// 2. 从数据库获取（根据浏览数和点赞数排序）
 	var books []models.Book
-	if err := config.DB.
-		Where("status = ?", 1).
+	if err := bs.db.WithContext(ctx).
+		Where("status = ?", 1).Scopes(VisibleUsers("books.seller_id")).
 		Order("view_count DESC, like_count DESC, created_at DESC").
 		Limit(limit).
 		Find(&books).Error; err != nil {
@@ -415,9 +421,10 @@ func (bs *BookService) GetHotBooks(limit int) ([]models.Book, error) {
 
 	// 3. 异步缓存
 	go func() {
-		if config.RedisClient != nil {
+		ctx := context.WithoutCancel(ctx)
+		if bs.redisClient != nil {
 			data, _ := json.Marshal(books)
-			config.RedisClient.Set(redisCtx, cacheKey, data, 10*time.Minute)
+			utils.SetTaggedCache(ctx, bs.redisClient, utils.CacheTagHotBooks, cacheKey, data, time.Duration(bs.settings.Int(ctx, SettingHotBooksTTLSeconds))*time.Second)
 		}
 	}()
 
@@ -427,13 +434,13 @@ func (bs *BookService) GetHotBooks(limit int) ([]models.Book, error) {
 // ==================== 搜索方法 ====================
 
 // SearchBooks 搜索书籍
-func (bs *BookService) SearchBooks(query string, page, limit int) ([]models.Book, int64, error) {
+func (bs *BookService) SearchBooks(ctx context.Context, query string, page, limit int) ([]models.Book, int64, error) {
 	// 1. 构建缓存key
 	cacheKey := fmt.Sprintf("search:books:%s:%d", query, page)
 
 	// 2. 尝试从Redis获取
-	if config.RedisClient != nil {
-		cached, err := config.RedisClient.Get(redisCtx, cacheKey).Result()
+	if bs.redisClient != nil {
+		cached, err := bs.redisClient.Get(ctx, cacheKey).Result()
 		if err == nil {
 			var result struct {
 				Books []models.Book `json:"books"`
@@ -441,23 +448,22 @@ func (bs *BookService) SearchBooks(query string, page, limit int) ([]models.Book
 			}
 			if json.Unmarshal([]byte(cached), &result) == nil {
 				// 记录搜索关键词
-				go bs.recordSearchKeyword(query)
+				go bs.recordSearchKeyword(context.WithoutCancel(ctx), query)
 				return result.Books, result.Total, nil
 			}
 		}
 	}
 
 	// 3. 记录搜索关键词
-	go bs.recordSearchKeyword(query)
+	go bs.recordSearchKeyword(context.WithoutCancel(ctx), query)
 
-	// 4. 数据库搜索
-	searchPattern := "%" + query + "%"
+	// 4. 数据库搜索（含同义词扩展）
+	condition, args := KeywordCondition(bs.synonyms.ExpandQuery(ctx, query), "title", "author", "description", "category")
 	var books []models.Book
 	var total int64
 
-	baseQuery := config.DB.Model(&models.Book{}).Where("status = ?", 1).
-		Where("title LIKE ? OR author LIKE ? OR description LIKE ? OR category LIKE ?",
-			searchPattern, searchPattern, searchPattern, searchPattern)
+	baseQuery := config.ReadReplica(bs.db.WithContext(ctx)).Model(&models.Book{}).Where("status = ?", 1).Scopes(VisibleUsers("books.seller_id")).
+		Where(condition, args...)
 
 	baseQuery.Count(&total)
 
@@ -471,13 +477,14 @@ func (bs *BookService) SearchBooks(query string, page, limit int) ([]models.Book
 
 	// 5. 异步缓存结果
 	go func() {
-		if config.RedisClient != nil {
+		ctx := context.WithoutCancel(ctx)
+		if bs.redisClient != nil {
 			result := struct {
 				Books []models.Book `json:"books"`
 				Total int64         `json:"total"`
 			}{books, total}
 			data, _ := json.Marshal(result)
-			config.RedisClient.Set(redisCtx, cacheKey, data, 5*time.Minute)
+			utils.SetTaggedCache(ctx, bs.redisClient, utils.CacheTagSearch, cacheKey, data, 5*time.Minute)
 		}
 	}()
 
@@ -487,35 +494,51 @@ func (bs *BookService) SearchBooks(query string, page, limit int) ([]models.Book
 // ==================== 点赞方法 ====================
 
 // LikeBook 点赞书籍
-func (bs *BookService) LikeBook(userID, bookID string) (bool, error) {
+func (bs *BookService) LikeBook(ctx context.Context, userID, bookID string) (bool, error) {
 	// 1. 检查是否已点赞
 	likeKey := fmt.Sprintf("like:%s:%s", userID, bookID)
-	if config.RedisClient != nil {
-		exists, _ := config.RedisClient.Exists(redisCtx, likeKey).Result()
+	if bs.redisClient != nil {
+		exists, _ := bs.redisClient.Exists(ctx, likeKey).Result()
 		if exists > 0 {
 			// 取消点赞
-			config.RedisClient.Del(redisCtx, likeKey)
-			bs.likeStatsQueue <- &BookLikeStat{
+			bs.redisClient.Del(ctx, likeKey)
+			bs.enqueueLikeStat(ctx, &BookLikeStat{
 				BookID:    bookID,
 				UserID:    userID,
 				Type:      "unlike",
 				Timestamp: time.Now(),
-			}
+			})
 			return false, nil
 		}
 	}
 
 	// 2. 添加点赞
-	if config.RedisClient != nil {
-		config.RedisClient.Set(redisCtx, likeKey, "1", 30*24*time.Hour)
+	if bs.redisClient != nil {
+		bs.redisClient.Set(ctx, likeKey, "1", 30*24*time.Hour)
 	}
 
-	bs.likeStatsQueue <- &BookLikeStat{
+	bs.enqueueLikeStat(ctx, &BookLikeStat{
 		BookID:    bookID,
 		UserID:    userID,
 		Type:      "like",
 		Timestamp: time.Now(),
-	}
+	})
+
+	// 3. 记录点赞事件（用于通知卖家）
+	utils.Go(ctx, "book_events", func(ctx context.Context) error {
+		if bs.redisClient == nil {
+			return nil
+		}
+		return bs.redisClient.XAdd(ctx, &redis.XAddArgs{
+			Stream: "book_events",
+			Values: map[string]interface{}{
+				"event":     "book_liked",
+				"book_id":   bookID,
+				"user_id":   userID,
+				"timestamp": time.Now().Unix(),
+			},
+		}).Err()
+	})
 
 	return true, nil
 }
@@ -523,18 +546,20 @@ func (bs *BookService) LikeBook(userID, bookID string) (bool, error) {
 // ==================== 推荐方法 ====================
 
 // GetRecommendations 获取推荐书籍
-func (bs *BookService) GetRecommendations(userID string, limit int) ([]models.Book, error) {
+func (bs *BookService) GetRecommendations(ctx context.Context, userID string, limit int) ([]models.Book, error) {
 	cacheKey := fmt.Sprintf("recommendations:%s", userID)
 
 	// 1. 尝试从Redis获取
-	if config.RedisClient != nil {
-		cached, err := config.RedisClient.Get(redisCtx, cacheKey).Result()
+	if bs.redisClient != nil {
+		cached, err := bs.redisClient.Get(ctx, cacheKey).Result()
 		if err == nil {
 			var books []models.Book
 			if json.Unmarshal([]byte(cached), &books) == nil {
+				utils.RecordCacheHit("recommendations")
 				return books, nil
 			}
 		}
+		utils.RecordCacheMiss("recommendations")
 	}
 
 	// 2. 基于用户浏览历史推荐
@@ -542,22 +567,22 @@ func (bs *BookService) GetRecommendations(userID string, limit int) ([]models.Bo
 
 	// 获取用户浏览历史
 	historyKey := fmt.Sprintf("history:view:%s", userID)
-	viewedBooks, _ := config.RedisClient.LRange(redisCtx, historyKey, 0, 9).Result()
+	viewedBooks, _ := bs.redisClient.LRange(ctx, historyKey, 0, 9).Result()
 
 	if len(viewedBooks) > 0 {
 		// 基于浏览过的书籍的类别推荐
 		var categories []string
 		for _, bookID := range viewedBooks {
 			var book models.Book
-			if err := config.DB.Select("category").First(&book, "id = ?", bookID).Error; err == nil {
+			if err := bs.db.WithContext(ctx).Select("category").First(&book, "id = ?", bookID).Error; err == nil {
 				categories = append(categories, book.Category)
 			}
 		}
 
 		// 获取同类别的热门书籍
 		if len(categories) > 0 {
-			if err := config.DB.
-				Where("status = ?", 1).
+			if err := bs.db.WithContext(ctx).
+				Where("status = ?", 1).Scopes(VisibleUsers("books.seller_id")).
 				Where("category IN ?", categories).
 				Not("id", viewedBooks).
 				Order("like_count DESC, view_count DESC").
@@ -566,8 +591,9 @@ func (bs *BookService) GetRecommendations(userID string, limit int) ([]models.Bo
 			} else {
 				// 有推荐结果，缓存并返回
 				go func() {
+					ctx := context.WithoutCancel(ctx)
 					data, _ := json.Marshal(books)
-					config.RedisClient.Set(redisCtx, cacheKey, data, time.Hour)
+					utils.SetTaggedCache(ctx, bs.redisClient, utils.CacheTagRecommendations, cacheKey, data, time.Hour)
 				}()
 				return books, nil
 			}
@@ -575,90 +601,116 @@ func (bs *BookService) GetRecommendations(userID string, limit int) ([]models.Bo
 	}
 
 	// 如果没有历史记录，返回热门书籍
-	return bs.GetHotBooks(limit)
+	return bs.GetHotBooks(ctx, limit)
 }
 
-// ==================== Worker相关方法 ====================
+// ==================== 后台任务 ====================
 
-// startStatsWorkers 启动统计worker池
-func (bs *BookService) startStatsWorkers() {
-	// 浏览统计worker
-	for i := 0; i < 5; i++ {
-		go bs.processViewStats(i)
+// RecordView 提交浏览统计任务
+func (bs *BookService) RecordView(ctx context.Context, bookID, userID string) {
+	stat := &BookViewStat{BookID: bookID, UserID: userID, Timestamp: time.Now()}
+	if err := utils.EnqueueJob(ctx, JobBookView, stat); err != nil {
+		log.Printf("Failed to enqueue view stat for book %s: %v", bookID, err)
 	}
+}
 
-	// 点赞统计worker
-	for i := 0; i < 5; i++ {
-		go bs.processLikeStats(i)
+// enqueueLikeStat 提交点赞统计任务
+func (bs *BookService) enqueueLikeStat(ctx context.Context, stat *BookLikeStat) {
+	if err := utils.EnqueueJob(ctx, JobBookLike, stat); err != nil {
+		log.Printf("Failed to enqueue like stat for book %s: %v", stat.BookID, err)
 	}
 }
 
-// startIndexWorker 启动索引worker
-func (bs *BookService) startIndexWorker() {
-	go func() {
-		for task := range bs.indexQueue {
-			bs.processIndexTask(task)
+// processViewStat 处理浏览统计
+func (bs *BookService) processViewStat(ctx context.Context, stat BookViewStat) error {
+	// 更新数据库（使用原子操作），失败时整个任务重试，避免排行榜重复计数
+	if err := bs.db.WithContext(ctx).Exec("UPDATE books SET view_count = view_count + 1 WHERE id = ?", stat.BookID).Error; err != nil {
+		return err
+	}
+
+	// 更新Redis排行榜（排行榜 most_viewed_books 的来源）
+	// 防刷：只计登录用户浏览他人的书籍，同一用户每天每本书只计一次
+	if bs.redisClient != nil && stat.UserID != "" {
+		seenKey := fmt.Sprintf("rank:book:viewed:%s:%s", stat.BookID, stat.UserID)
+		if first, _ := bs.redisClient.SetNX(ctx, seenKey, 1, 24*time.Hour).Result(); first {
+			var own int64
+			bs.db.WithContext(ctx).Model(&models.Book{}).Where("id = ? AND seller_id = ?", stat.BookID, stat.UserID).Count(&own)
+			if own == 0 {
+				bs.redisClient.ZIncrBy(ctx, rankBookViewsKey, 1, stat.BookID)
+				bs.redisClient.Expire(ctx, rankBookViewsKey, 7*24*time.Hour)
+			}
 		}
-	}()
-}
-
-// processViewStats 处理浏览统计
-func (bs *BookService) processViewStats(workerID int) {
-	for stat := range bs.viewStatsQueue {
-		// 更新数据库（使用原子操作）
-		config.DB.Exec("UPDATE books SET view_count = view_count + 1 WHERE id = ?", stat.BookID)
+	}
 
-		// 更新Redis排行榜
-		if config.RedisClient != nil {
-			config.RedisClient.ZIncrBy(redisCtx, "rank:book:views", 1, stat.BookID)
-			config.RedisClient.Expire(redisCtx, "rank:book:views", 7*24*time.Hour)
-		}
+	// 记录用户浏览历史
+	if bs.redisClient != nil && stat.UserID != "" {
+		historyKey := fmt.Sprintf("history:view:%s", stat.UserID)
+		bs.redisClient.LPush(ctx, historyKey, stat.BookID)
+		bs.redisClient.LTrim(ctx, historyKey, 0, 99) // 保留最近100条
+		bs.redisClient.Expire(ctx, historyKey, 30*24*time.Hour)
+	}
+	return nil
+}
 
-		// 记录用户浏览历史
-		if config.RedisClient != nil && stat.UserID != "" {
-			historyKey := fmt.Sprintf("history:view:%s", stat.UserID)
-			config.RedisClient.LPush(redisCtx, historyKey, stat.BookID)
-			config.RedisClient.LTrim(redisCtx, historyKey, 0, 99) // 保留最近100条
-			config.RedisClient.Expire(redisCtx, historyKey, 30*24*time.Hour)
-		}
+// processLikeStat 处理点赞统计
+func (bs *BookService) processLikeStat(ctx context.Context, stat BookLikeStat) error {
+	delta := 1
+	if stat.Type == "unlike" {
+		delta = -1
 	}
+	if err := bs.db.WithContext(ctx).Exec("UPDATE books SET like_count = like_count + ? WHERE id = ?", delta, stat.BookID).Error; err != nil {
+		return err
+	}
+	if bs.redisClient != nil {
+		bs.redisClient.ZIncrBy(ctx, "rank:book:likes", float64(delta), stat.BookID)
+	}
+	return nil
 }
 
-// processLikeStats 处理点赞统计
-func (bs *BookService) processLikeStats(workerID int) {
-	for stat := range bs.likeStatsQueue {
-		switch stat.Type {
-		case "like":
-			config.DB.Exec("UPDATE books SET like_count = like_count + 1 WHERE id = ?", stat.BookID)
-			if config.RedisClient != nil {
-				config.RedisClient.ZIncrBy(redisCtx, "rank:book:likes", 1, stat.BookID)
-			}
-		case "unlike":
-			config.DB.Exec("UPDATE books SET like_count = like_count - 1 WHERE id = ?", stat.BookID)
-			if config.RedisClient != nil {
-				config.RedisClient.ZIncrBy(redisCtx, "rank:book:likes", -1, stat.BookID)
-			}
+// enqueueIndexTask 提交索引任务，并记录待处理数量用于索引健康检查
+func (bs *BookService) enqueueIndexTask(ctx context.Context, task *BookIndexTask) {
+	if bs.redisClient != nil {
+		bs.redisClient.Incr(ctx, searchIndexPendingKey)
+	}
+	if err := utils.EnqueueJob(ctx, JobBookIndex, task); err != nil {
+		log.Printf("Failed to enqueue %s of book %s: %v", task.Action, task.BookID, err)
+		if bs.redisClient != nil {
+			bs.redisClient.Decr(ctx, searchIndexPendingKey)
 		}
 	}
 }
 
 // processIndexTask 处理索引任务
-func (bs *BookService) processIndexTask(task *BookIndexTask) {
+func (bs *BookService) processIndexTask(ctx context.Context, task BookIndexTask) error {
 	if task.Action == "remove" {
-		bs.removeFromSearchIndex(task.BookID)
+		bs.removeFromSearchIndex(ctx, task.BookID)
 	} else {
 		var book models.Book
-		if err := config.DB.First(&book, "id = ?", task.BookID).Error; err == nil {
-			bs.indexBookForSearch(&book)
+		err := bs.db.WithContext(ctx).First(&book, "id = ?", task.BookID).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil {
+			bs.indexBookForSearch(ctx, &book)
 		}
 	}
+
+	if bs.redisClient != nil {
+		bs.redisClient.Decr(ctx, searchIndexPendingKey)
+	}
+	return nil
 }
 
 // ==================== 辅助方法 ====================
 
 // clearBookCaches 清除书籍相关缓存
-func (bs *BookService) clearBookCaches(bookID string) {
-	if config.RedisClient == nil {
+func (bs *BookService) clearBookCaches(ctx context.Context, bookID string) {
+	invalidateBookCaches(ctx, bs.redisClient, bookID)
+}
+
+// invalidateBookCaches 清除书籍详情、热门、搜索和推荐缓存
+func invalidateBookCaches(ctx context.Context, redisClient *redis.Client, bookID string) {
+	if redisClient == nil {
 		return
 	}
 
@@ -666,75 +718,41 @@ func (bs *BookService) clearBookCaches(bookID string) {
 	var wg sync.WaitGroup
 	cacheKeys := []string{
 		fmt.Sprintf("book:%s", bookID),
-		"hot:books",
 	}
 
 	wg.Add(len(cacheKeys))
 	for _, key := range cacheKeys {
 		go func(k string) {
 			defer wg.Done()
-			config.RedisClient.Del(redisCtx, k)
+			redisClient.Del(ctx, k)
 		}(key)
 	}
 	wg.Wait()
 
-	// 清除搜索缓存（模糊匹配）
-	keys, _ := config.RedisClient.Keys(redisCtx, "search:books:*").Result()
-	for _, key := range keys {
-		config.RedisClient.Del(redisCtx, key)
-	}
-
-	// 清除推荐缓存
-	pattern := "recommendations:*"
-	recKeys, _ := config.RedisClient.Keys(redisCtx, pattern).Result()
-	for _, key := range recKeys {
-		config.RedisClient.Del(redisCtx, key)
-	}
+	// 清除热门、搜索和推荐缓存（按标签删除登记的键）
+	utils.InvalidateCacheTag(ctx, redisClient, utils.CacheTagHotBooks)
+	utils.InvalidateCacheTag(ctx, redisClient, utils.CacheTagSearch)
+	utils.InvalidateCacheTag(ctx, redisClient, utils.CacheTagRecommendations)
 }
 
 // indexBookForSearch 索引书籍用于搜索
-func (bs *BookService) indexBookForSearch(book *models.Book) {
-	if config.RedisClient == nil {
-		return
-	}
-
-	// 将书籍信息存入Redis Hash
-	indexKey := fmt.Sprintf("book:index:%s", book.ID)
-	bookData := map[string]interface{}{
-		"id":         book.ID,
-		"title":      book.Title,
-		"author":     book.Author,
-		"category":   book.Category,
-		"price":      book.Price,
-		"condition":  book.Condition,
-		"seller_id":  book.SellerID,
-		"status":     book.Status,
-		"created_at": book.CreatedAt.Unix(),
-		"updated_at": book.UpdatedAt.Unix(),
-	}
-
-	config.RedisClient.HMSet(redisCtx, indexKey, bookData)
-	config.RedisClient.Expire(redisCtx, indexKey, 24*time.Hour)
+func (bs *BookService) indexBookForSearch(ctx context.Context, book *models.Book) {
+	bs.searchIndex.indexBookDocument(ctx, book)
 }
 
 // removeFromSearchIndex 从搜索索引中移除
-func (bs *BookService) removeFromSearchIndex(bookID string) {
-	if config.RedisClient == nil {
-		return
-	}
-
-	indexKey := fmt.Sprintf("book:index:%s", bookID)
-	config.RedisClient.Del(redisCtx, indexKey)
+func (bs *BookService) removeFromSearchIndex(ctx context.Context, bookID string) {
+	bs.searchIndex.removeBookDocument(ctx, bookID)
 }
 
 // recordSearchKeyword 记录搜索关键词
-func (bs *BookService) recordSearchKeyword(query string) {
-	if config.RedisClient == nil {
+func (bs *BookService) recordSearchKeyword(ctx context.Context, query string) {
+	if bs.redisClient == nil {
 		return
 	}
 
-	config.RedisClient.ZIncrBy(redisCtx, "search:hot", 1, query)
-	config.RedisClient.Expire(redisCtx, "search:hot", 24*time.Hour)
+	bs.redisClient.ZIncrBy(ctx, "search:hot", 1, query)
+	bs.redisClient.Expire(ctx, "search:hot", 24*time.Hour)
 }
 
 // buildBooksCacheKey 构建书籍列表缓存key
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"weoucbookcycle_go/utils"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// cacheScanBatch SCAN 每批返回的key数量
+const cacheScanBatch = 500
+
+var (
+	// ErrUnknownCacheTag 未定义的缓存标签
+	ErrUnknownCacheTag = utils.NewError(http.StatusBadRequest, "unknown cache tag")
+	// ErrProtectedCacheKey 不是缓存key（如封禁标记、索引状态），不允许通过缓存接口删除
+	ErrProtectedCacheKey = utils.NewError(http.StatusBadRequest, "key is not a cache key")
+)
+
+// cacheTag 缓存标签，一个标签对应一组key模式
+type cacheTag struct {
+	Patterns    []string
+	Description string
+}
+
+// cacheTags 可按标签清除的缓存，名称与命中统计的类别一致
+var cacheTags = map[string]cacheTag{
+	"books":           {Patterns: []string{"book:*"}, Description: "书籍详情 book:{id}"},
+	"hot_books":       {Patterns: []string{"hot:books", "hot:books:*"}, Description: "热门书籍列表 hot:books[:{campus_id}]"},
+	"search":          {Patterns: []string{"search:*"}, Description: "搜索结果和搜索建议"},
+	"users":           {Patterns: []string{"user:*"}, Description: "用户资料 user:{id}"},
+	"listings":        {Patterns: []string{"listing:*"}, Description: "发布详情 listing:{id}"},
+	"chats":           {Patterns: []string{"chat:*"}, Description: "聊天详情和消息分页"},
+	"recommendations": {Patterns: []string{"recommendations:*"}, Description: "个性化推荐"},
+	"announcements":   {Patterns: []string{activeAnnouncementsKey}, Description: "展示中的公告"},
+	"share_cards":     {Patterns: []string{"share:*"}, Description: "分享预览页面 share:{books|listings}:{id}:{lang}"},
+	"leaderboards":    {Patterns: []string{leaderboardSnapshotKey}, Description: "公开排行榜快照，清除后下次请求时重新生成"},
+}
+
+// protectedKeyPrefixes 与缓存共用前缀但不是缓存的key，删除会丢失状态
+var protectedKeyPrefixes = []string{
+	"book:index:",
+	"user:disabled:",
+	"user:login_count:",
+	"search:hot",
+	"search:index:",
+	"search:synonyms",
+	"search:vocab",
+	"search:affinity:",
+}
+
+// CacheTagInfo 缓存标签说明
+type CacheTagInfo struct {
+	Name        string   `json:"name"`
+	Patterns    []string `json:"patterns"`
+	Description string   `json:"description"`
+}
+
+// InvalidateCacheRequest 清除缓存请求，keys 和 tags 至少一个
+type InvalidateCacheRequest struct {
+	Keys []string `json:"keys" binding:"max=100"`
+	Tags []string `json:"tags" binding:"max=20"`
+}
+
+// CacheAdminService 缓存管理服务
+type CacheAdminService struct {
+	redisClient    *redis.Client
+	systemSettings *SystemSettingsService
+}
+
+// NewCacheAdminService 创建缓存管理服务实例
+func NewCacheAdminService(deps Deps, systemSettings *SystemSettingsService) *CacheAdminService {
+	return &CacheAdminService{
+		redisClient:    deps.Redis,
+		systemSettings: systemSettings,
+	}
+}
+
+// Tags 返回可清除的缓存标签
+func (cs *CacheAdminService) Tags() []CacheTagInfo {
+	tags := make([]CacheTagInfo, 0, len(cacheTags)+1)
+	for name, tag := range cacheTags {
+		tags = append(tags, CacheTagInfo{Name: name, Patterns: tag.Patterns, Description: tag.Description})
+	}
+	tags = append(tags, CacheTagInfo{Name: "settings", Patterns: []string{systemSettingsCacheKey}, Description: "运行时参数缓存"})
+	slices.SortFunc(tags, func(a, b CacheTagInfo) int { return strings.Compare(a.Name, b.Name) })
+	return tags
+}
+
+// Stats 返回当前实例的缓存命中统计
+func (cs *CacheAdminService) Stats() []utils.CacheStat {
+	return utils.CacheStats()
+}
+
+// ResetStats 清空命中统计
+func (cs *CacheAdminService) ResetStats() {
+	utils.ResetCacheStats()
+}
+
+// Invalidate 按key或标签清除缓存，返回删除的key数量
+func (cs *CacheAdminService) Invalidate(ctx context.Context, req *InvalidateCacheRequest) (int64, error) {
+	if cs.redisClient == nil {
+		return 0, errors.New("redis not available")
+	}
+
+	// 先校验全部参数，避免部分清除
+	for _, key := range req.Keys {
+		if !isCacheKey(key) {
+			return 0, fmt.Errorf("%w: %s", ErrProtectedCacheKey, key)
+		}
+	}
+	for _, name := range req.Tags {
+		if _, ok := cacheTags[name]; !ok && name != "settings" {
+			return 0, fmt.Errorf("%w: %s", ErrUnknownCacheTag, name)
+		}
+	}
+
+	var deleted int64
+	if len(req.Keys) > 0 {
+		n, err := cs.redisClient.Unlink(ctx, req.Keys...).Result()
+		if err != nil {
+			return deleted, err
+		}
+		deleted += n
+	}
+
+	for _, name := range req.Tags {
+		if name == "settings" {
+			cs.systemSettings.invalidate(ctx)
+			deleted++
+			continue
+		}
+		for _, pattern := range cacheTags[name].Patterns {
+			n, err := cs.deleteCachePattern(ctx, pattern)
+			deleted += n
+			if err != nil {
+				return deleted, err
+			}
+		}
+	}
+	return deleted, nil
+}
+
+// isCacheKey key 是否属于某个缓存标签且不是受保护的状态key
+func isCacheKey(key string) bool {
+	for _, prefix := range protectedKeyPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return false
+		}
+	}
+	for _, tag := range cacheTags {
+		for _, pattern := range tag.Patterns {
+			if prefix, wildcard := strings.CutSuffix(pattern, "*"); wildcard {
+				if strings.HasPrefix(key, prefix) && len(key) > len(prefix) {
+					return true
+				}
+			} else if key == pattern {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// deleteCachePattern 用 SCAN 分批删除匹配的缓存key，跳过受保护的key
+func (cs *CacheAdminService) deleteCachePattern(ctx context.Context, pattern string) (int64, error) {
+	if !strings.Contains(pattern, "*") {
+		return cs.redisClient.Unlink(ctx, pattern).Result()
+	}
+
+	var deleted int64
+	var cursor uint64
+	for {
+		keys, next, err := cs.redisClient.Scan(ctx, cursor, pattern, cacheScanBatch).Result()
+		if err != nil {
+			return deleted, err
+		}
+		keys = slices.DeleteFunc(keys, func(key string) bool { return !isCacheKey(key) })
+		if len(keys) > 0 {
+			n, err := cs.redisClient.Unlink(ctx, keys...).Result()
+			if err != nil {
+				return deleted, err
+			}
+			deleted += n
+		}
+		if next == 0 {
+			return deleted, nil
+		}
+		cursor = next
+	}
+}
@@ -0,0 +1,28 @@
+package services
+
+import "testing"
+
+// 只能删除缓存key，封禁标记、索引状态等共用前缀的key受保护
+func TestIsCacheKey(t *testing.T) {
+	cases := map[string]bool{
+		"book:123":                  true,
+		"hot:books":                 true,
+		"hot:books:c-1":             true,
+		"search:books:go:1":         true,
+		"user:abc":                  true,
+		"recommendations:abc":       true,
+		"book:":                     false,
+		"book:index:ids":            false,
+		"user:disabled:abc":         false,
+		"user:login_count:abc":      false,
+		"search:hot":                false,
+		"search:index:last_reindex": false,
+		"system:settings":           false,
+		"task:123":                  false,
+	}
+	for key, want := range cases {
+		if got := isCacheKey(key); got != want {
+			t.Errorf("isCacheKey(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,425 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	// campusesCacheKey 启用的校区列表缓存，几乎不变但每次打开发布页都会请求
+	campusesCacheKey = "campuses"
+	campusesCacheTTL = time.Hour
+	// CampusFilterAll 浏览和搜索书籍、发布时传入 campus_id=all 表示不按校区筛选
+	CampusFilterAll = "all"
+	// hotBooksCacheKey 不限校区的热门书籍缓存，按校区筛选的加上 :校区ID
+	hotBooksCacheKey = "hot:books"
+)
+
+var (
+	ErrCampusNotFound      = utils.NewError(http.StatusNotFound, "campus not found")
+	ErrDormAreaNotFound    = utils.NewError(http.StatusNotFound, "dorm area not found")
+	ErrPickupPointNotFound = utils.NewError(http.StatusNotFound, "pickup point not found")
+	ErrCampusInUse         = utils.NewError(http.StatusConflict, "campus is still referenced by users, books or listings, disable it instead")
+	ErrInvalidLocation     = utils.NewError(http.StatusBadRequest, "invalid campus or dorm area")
+	ErrInvalidPickupPoint  = utils.NewError(http.StatusBadRequest, "pickup point does not belong to the campus")
+)
+
+// CampusRequest 校区创建/更新请求
+type CampusRequest struct {
+	Name      string `json:"name" binding:"required,max=100"`
+	SortOrder int    `json:"sort_order"`
+	Enabled   *bool  `json:"enabled"`
+}
+
+// DormAreaRequest 宿舍区创建/更新请求
+type DormAreaRequest struct {
+	Name      string `json:"name" binding:"required,max=100"`
+	SortOrder int    `json:"sort_order"`
+}
+
+// PickupPointRequest 面交地点创建/更新请求
+type PickupPointRequest struct {
+	Name      string `json:"name" binding:"required,max=100"`
+	SortOrder int    `json:"sort_order"`
+}
+
+// LocationRequest 设置所在校区和宿舍区，均为空表示清除
+type LocationRequest struct {
+	CampusID   string `json:"campus_id"`
+	DormAreaID string `json:"dorm_area_id"`
+}
+
+// CampusService 校区、宿舍区和面交地点管理，以及用户所在位置
+type CampusService struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+}
+
+// NewCampusService 创建校区服务实例
+func NewCampusService(deps Deps) *CampusService {
+	return &CampusService{
+		db:          deps.DB,
+		redisClient: deps.Redis,
+	}
+}
+
+// ==================== 校区列表 ====================
+
+// List 获取校区及其宿舍区和面交地点，按排序值排列；onlyEnabled 时只返回启用的校区（公开接口）
+func (cs *CampusService) List(ctx context.Context, onlyEnabled bool) ([]models.Campus, error) {
+	if onlyEnabled && cs.redisClient != nil {
+		if cached, err := cs.redisClient.Get(ctx, campusesCacheKey).Bytes(); err == nil {
+			var campuses []models.Campus
+			if json.Unmarshal(cached, &campuses) == nil {
+				return campuses, nil
+			}
+		}
+	}
+
+	query := cs.db.WithContext(ctx).
+		Preload("DormAreas", func(db *gorm.DB) *gorm.DB { return db.Order("sort_order, name") }).
+		Preload("PickupPoints", func(db *gorm.DB) *gorm.DB { return db.Order("sort_order, name") }).
+		Order("sort_order, name")
+	if onlyEnabled {
+		query = query.Where("enabled = ?", true)
+	}
+	campuses := []models.Campus{}
+	if err := query.Find(&campuses).Error; err != nil {
+		return nil, fmt.Errorf("failed to list campuses: %w", err)
+	}
+
+	if onlyEnabled && cs.redisClient != nil {
+		if data, err := json.Marshal(campuses); err == nil {
+			cs.redisClient.Set(context.WithoutCancel(ctx), campusesCacheKey, data, campusesCacheTTL)
+		}
+	}
+	return campuses, nil
+}
+
+// CreateCampus 创建校区
+func (cs *CampusService) CreateCampus(ctx context.Context, req *CampusRequest) (*models.Campus, error) {
+	campus := models.Campus{Name: req.Name, SortOrder: req.SortOrder, Enabled: true, DormAreas: []models.DormArea{}, PickupPoints: []models.PickupPoint{}}
+	if err := cs.db.WithContext(ctx).Create(&campus).Error; err != nil {
+		return nil, fmt.Errorf("failed to create campus: %w", err)
+	}
+
+	// 停用状态需要单独更新（零值不会写入）
+	if req.Enabled != nil && !*req.Enabled {
+		cs.db.WithContext(ctx).Model(&campus).Update("enabled", false)
+		campus.Enabled = false
+	}
+
+	cs.invalidate(ctx)
+	return &campus, nil
+}
+
+// UpdateCampus 更新校区
+func (cs *CampusService) UpdateCampus(ctx context.Context, id string, req *CampusRequest) (*models.Campus, error) {
+	updates := map[string]interface{}{"name": req.Name, "sort_order": req.SortOrder}
+	if req.Enabled != nil {
+		updates["enabled"] = *req.Enabled
+	}
+	result := cs.db.WithContext(ctx).Model(&models.Campus{}).Where("id = ?", id).Updates(updates)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to update campus: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		if _, err := cs.getCampus(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	cs.invalidate(ctx)
+	return cs.getCampus(ctx, id)
+}
+
+// DeleteCampus 删除没有被用户、书籍和发布引用的校区及其宿舍区和面交地点
+func (cs *CampusService) DeleteCampus(ctx context.Context, id string) error {
+	return WithTx(ctx, cs.db, func(ctx context.Context, tx *gorm.DB) error {
+		var used int64
+		if err := tx.Model(&models.User{}).Where("campus_id = ?", id).Count(&used).Error; err != nil {
+			return err
+		}
+		if used == 0 {
+			if err := tx.Model(&models.Book{}).Unscoped().Where("campus_id = ?", id).Count(&used).Error; err != nil {
+				return err
+			}
+		}
+		if used == 0 {
+			if err := tx.Model(&models.Listing{}).Unscoped().Where("campus_id = ?", id).Count(&used).Error; err != nil {
+				return err
+			}
+		}
+		if used > 0 {
+			return ErrCampusInUse
+		}
+
+		if err := tx.Where("campus_id = ?", id).Delete(&models.DormArea{}).Error; err != nil {
+			return fmt.Errorf("failed to delete dorm areas: %w", err)
+		}
+		if err := tx.Where("campus_id = ?", id).Delete(&models.PickupPoint{}).Error; err != nil {
+			return fmt.Errorf("failed to delete pickup points: %w", err)
+		}
+		result := tx.Delete(&models.Campus{}, "id = ?", id)
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete campus: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrCampusNotFound
+		}
+		AfterCommit(ctx, func() { cs.invalidate(ctx) })
+		return nil
+	})
+}
+
+// CreateDormArea 在校区下创建宿舍区
+func (cs *CampusService) CreateDormArea(ctx context.Context, campusID string, req *DormAreaRequest) (*models.DormArea, error) {
+	if _, err := cs.getCampus(ctx, campusID); err != nil {
+		return nil, err
+	}
+	area := models.DormArea{CampusID: campusID, Name: req.Name, SortOrder: req.SortOrder}
+	if err := cs.db.WithContext(ctx).Create(&area).Error; err != nil {
+		return nil, fmt.Errorf("failed to create dorm area: %w", err)
+	}
+	cs.invalidate(ctx)
+	return &area, nil
+}
+
+// UpdateDormArea 更新宿舍区
+func (cs *CampusService) UpdateDormArea(ctx context.Context, id string, req *DormAreaRequest) (*models.DormArea, error) {
+	var area models.DormArea
+	if err := cs.db.WithContext(ctx).First(&area, "id = ?", id).Error; err != nil {
+		return nil, ErrDormAreaNotFound
+	}
+	if err := cs.db.WithContext(ctx).Model(&area).
+		Updates(map[string]interface{}{"name": req.Name, "sort_order": req.SortOrder}).Error; err != nil {
+		return nil, fmt.Errorf("failed to update dorm area: %w", err)
+	}
+	area.Name, area.SortOrder = req.Name, req.SortOrder
+	cs.invalidate(ctx)
+	return &area, nil
+}
+
+// DeleteDormArea 删除宿舍区，引用它的用户和发布只保留校区
+func (cs *CampusService) DeleteDormArea(ctx context.Context, id string) error {
+	err := WithTx(ctx, cs.db, func(ctx context.Context, tx *gorm.DB) error {
+		result := tx.Delete(&models.DormArea{}, "id = ?", id)
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete dorm area: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrDormAreaNotFound
+		}
+		if err := tx.Model(&models.User{}).Where("dorm_area_id = ?", id).Update("dorm_area_id", "").Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Listing{}).Unscoped().Where("dorm_area_id = ?", id).Update("dorm_area_id", "").Error
+	})
+	if err != nil {
+		return err
+	}
+	cs.invalidate(ctx)
+	return nil
+}
+
+// CreatePickupPoint 在校区下创建面交地点
+func (cs *CampusService) CreatePickupPoint(ctx context.Context, campusID string, req *PickupPointRequest) (*models.PickupPoint, error) {
+	if _, err := cs.getCampus(ctx, campusID); err != nil {
+		return nil, err
+	}
+	point := models.PickupPoint{CampusID: campusID, Name: req.Name, SortOrder: req.SortOrder}
+	if err := cs.db.WithContext(ctx).Create(&point).Error; err != nil {
+		return nil, fmt.Errorf("failed to create pickup point: %w", err)
+	}
+	cs.invalidate(ctx)
+	return &point, nil
+}
+
+// UpdatePickupPoint 更新面交地点
+func (cs *CampusService) UpdatePickupPoint(ctx context.Context, id string, req *PickupPointRequest) (*models.PickupPoint, error) {
+	var point models.PickupPoint
+	if err := cs.db.WithContext(ctx).First(&point, "id = ?", id).Error; err != nil {
+		return nil, ErrPickupPointNotFound
+	}
+	if err := cs.db.WithContext(ctx).Model(&point).
+		Updates(map[string]interface{}{"name": req.Name, "sort_order": req.SortOrder}).Error; err != nil {
+		return nil, fmt.Errorf("failed to update pickup point: %w", err)
+	}
+	point.Name, point.SortOrder = req.Name, req.SortOrder
+	cs.invalidate(ctx)
+	return &point, nil
+}
+
+// DeletePickupPoint 删除面交地点，引用它的发布只保留校区和宿舍区
+func (cs *CampusService) DeletePickupPoint(ctx context.Context, id string) error {
+	err := WithTx(ctx, cs.db, func(ctx context.Context, tx *gorm.DB) error {
+		result := tx.Delete(&models.PickupPoint{}, "id = ?", id)
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete pickup point: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrPickupPointNotFound
+		}
+		return tx.Model(&models.Listing{}).Unscoped().Where("pickup_point_id = ?", id).Update("pickup_point_id", "").Error
+	})
+	if err != nil {
+		return err
+	}
+	cs.invalidate(ctx)
+	return nil
+}
+
+// getCampus 按ID获取校区（含宿舍区和面交地点）
+func (cs *CampusService) getCampus(ctx context.Context, id string) (*models.Campus, error) {
+	var campus models.Campus
+	if err := cs.db.WithContext(ctx).
+		Preload("DormAreas", func(db *gorm.DB) *gorm.DB { return db.Order("sort_order, name") }).
+		Preload("PickupPoints", func(db *gorm.DB) *gorm.DB { return db.Order("sort_order, name") }).
+		First(&campus, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCampusNotFound
+		}
+		return nil, fmt.Errorf("failed to get campus: %w", err)
+	}
+	return &campus, nil
+}
+
+// invalidate 校区或宿舍区变更后清除列表缓存
+func (cs *CampusService) invalidate(ctx context.Context) {
+	if cs.redisClient != nil {
+		cs.redisClient.Del(context.WithoutCancel(ctx), campusesCacheKey)
+	}
+}
+
+// ==================== 用户位置 ====================
+
+// ValidateLocation 校验校区已启用且宿舍区属于该校区；只填宿舍区不填校区视为无效
+func (cs *CampusService) ValidateLocation(ctx context.Context, campusID, dormAreaID string) error {
+	if campusID == "" {
+		if dormAreaID != "" {
+			return ErrInvalidLocation
+		}
+		return nil
+	}
+
+	var count int64
+	if err := cs.db.WithContext(ctx).Model(&models.Campus{}).
+		Where("id = ? AND enabled = ?", campusID, true).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check campus: %w", err)
+	}
+	if count == 0 {
+		return ErrInvalidLocation
+	}
+	if dormAreaID == "" {
+		return nil
+	}
+	if err := cs.db.WithContext(ctx).Model(&models.DormArea{}).
+		Where("id = ? AND campus_id = ?", dormAreaID, campusID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check dorm area: %w", err)
+	}
+	if count == 0 {
+		return ErrInvalidLocation
+	}
+	return nil
+}
+
+// ValidatePickupPoint 校验面交地点属于发布的面交校区；未选面交地点时不校验
+func (cs *CampusService) ValidatePickupPoint(ctx context.Context, campusID, pickupPointID string) error {
+	if pickupPointID == "" {
+		return nil
+	}
+	if campusID == "" {
+		return ErrInvalidPickupPoint
+	}
+	var count int64
+	if err := cs.db.WithContext(ctx).Model(&models.PickupPoint{}).
+		Where("id = ? AND campus_id = ?", pickupPointID, campusID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check pickup point: %w", err)
+	}
+	if count == 0 {
+		return ErrInvalidPickupPoint
+	}
+	return nil
+}
+
+// SetUserLocation 设置用户所在的校区和宿舍区
+func (cs *CampusService) SetUserLocation(ctx context.Context, userID string, req *LocationRequest) (*models.User, error) {
+	if err := cs.ValidateLocation(ctx, req.CampusID, req.DormAreaID); err != nil {
+		return nil, err
+	}
+	if err := cs.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).
+		Updates(map[string]interface{}{"campus_id": req.CampusID, "dorm_area_id": req.DormAreaID}).Error; err != nil {
+		return nil, fmt.Errorf("failed to update location: %w", err)
+	}
+
+	// 公开资料中包含校区
+	if cs.redisClient != nil {
+		cs.redisClient.Del(context.WithoutCancel(ctx), "user:"+userID)
+	}
+
+	var user models.User
+	if err := cs.db.WithContext(ctx).First(&user, "id = ?", userID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+	return &user, nil
+}
+
+// UserLocation 获取用户所在的校区和宿舍区，未设置或查询失败时为空
+func (cs *CampusService) UserLocation(ctx context.Context, userID string) (campusID, dormAreaID string) {
+	if userID == "" {
+		return "", ""
+	}
+	var user models.User
+	if err := cs.db.WithContext(ctx).Select("campus_id", "dorm_area_id").First(&user, "id = ?", userID).Error; err != nil {
+		return "", ""
+	}
+	return user.CampusID, user.DormAreaID
+}
+
+// PickupCampus 浏览和搜索书籍、发布时使用的校区筛选：显式传入的 campus_id 优先，
+// campus_id=all 表示不筛选，未传时默认为登录用户所在的校区；返回空表示不筛选
+func (cs *CampusService) PickupCampus(ctx context.Context, userID, param string) string {
+	return pickupCampus(param, func() string {
+		campusID, _ := cs.UserLocation(ctx, userID)
+		return campusID
+	})
+}
+
+// pickupCampus 按参数和用户默认校区决定筛选条件，只有需要时才查询默认校区
+func pickupCampus(param string, defaultCampus func() string) string {
+	switch param {
+	case CampusFilterAll:
+		return ""
+	case "":
+		return defaultCampus()
+	default:
+		return param
+	}
+}
+
+// InCampus 按校区筛选的查询条件，campusID 为空时不筛选
+func InCampus(column, campusID string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if campusID == "" {
+			return db
+		}
+		return db.Where(column+" = ?", campusID)
+	}
+}
+
+// HotBooksCacheKey 热门书籍缓存key，campusID 为空表示不限校区；缓存登记在 utils.CacheTagHotBooks 下
+func HotBooksCacheKey(campusID string) string {
+	if campusID == "" {
+		return hotBooksCacheKey
+	}
+	return hotBooksCacheKey + ":" + campusID
+}
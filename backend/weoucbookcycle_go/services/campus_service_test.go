@@ -0,0 +1,40 @@
+package services
+
+import "testing"
+
+func TestPickupCampus(t *testing.T) {
+	cases := []struct {
+		param       string
+		home        string
+		want        string
+		wantLookups int
+	}{
+		{"", "c-home", "c-home", 1},
+		{"", "", "", 1},
+		{"c-other", "c-home", "c-other", 0},
+		{CampusFilterAll, "c-home", "", 0},
+	}
+	for _, tc := range cases {
+		lookups := 0
+		got := pickupCampus(tc.param, func() string {
+			lookups++
+			return tc.home
+		})
+		if got != tc.want {
+			t.Errorf("pickupCampus(%q) with home %q = %q, want %q", tc.param, tc.home, got, tc.want)
+		}
+		// 显式指定时不需要查询用户所在校区
+		if lookups != tc.wantLookups {
+			t.Errorf("pickupCampus(%q) looked up the default %d times, want %d", tc.param, lookups, tc.wantLookups)
+		}
+	}
+}
+
+func TestHotBooksCacheKey(t *testing.T) {
+	if got := HotBooksCacheKey(""); got != "hot:books" {
+		t.Errorf("HotBooksCacheKey(\"\") = %q, want %q", got, "hot:books")
+	}
+	if got := HotBooksCacheKey("c-1"); got != "hot:books:c-1" {
+		t.Errorf("HotBooksCacheKey(\"c-1\") = %q, want %q", got, "hot:books:c-1")
+	}
+}
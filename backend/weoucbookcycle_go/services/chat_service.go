@@ -1,27 +1,45 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"sync"
 	"time"
+	"unicode/utf8"
 	"weoucbookcycle_go/config"
 	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
 
+	"github.com/hibiken/asynq"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
 )
 
 // ChatService 聊天服务
 type ChatService struct {
-	// 消息发送队列
-	messageQueue chan *MessageTask
-	// 消息处理队列
-	processQueue chan *MessageProcessTask
+	db          *gorm.DB
+	redisClient *redis.Client
+	settings    *SystemSettingsService
+	pushService *PushService
 	// 在线用户缓存
 	onlineUsers sync.Map // userID -> LastSeen
 }
 
+// 聊天后台任务类型
+const (
+	JobChatCreateMessage = "chat:create_message"
+	JobChatAfterSend     = "chat:after_send"
+)
+
+// ErrStrangerChatDisabled 对方只接受关注的人发起聊天
+var ErrStrangerChatDisabled = utils.NewError(http.StatusForbidden, "this user only accepts chats from people they follow")
+
 // MessageTask 消息发送任务
 type MessageTask struct {
 	ChatID    string
@@ -30,9 +48,9 @@ type MessageTask struct {
 	Timestamp time.Time
 }
 
-// MessageProcessTask 消息处理任务
+// MessageProcessTask 消息发送后的处理任务（更新会话、未读数、推送）
 type MessageProcessTask struct {
-	Message *models.Message
+	MessageID string
 }
 
 // ChatWithUnread 带未读数的聊天
@@ -41,18 +59,18 @@ type ChatWithUnread struct {
 	UnreadCount int64       `json:"unread_count"`
 }
 
-// NewChatService 创建聊天服务实例
-func NewChatService() *ChatService {
+// NewChatService 创建聊天服务实例，并注册消息后台任务的处理函数
+// 进程内只应创建一次（见 NewServices）
+func NewChatService(deps Deps, settings *SystemSettingsService, pushService *PushService) *ChatService {
 	cs := &ChatService{
-		messageQueue: make(chan *MessageTask, 2000),
-		processQueue: make(chan *MessageProcessTask, 2000),
+		db:          deps.DB,
+		redisClient: deps.Redis,
+		settings:    settings,
+		pushService: pushService,
 	}
 
-	// 启动worker池
-	cs.startWorkers()
-
-	// 启动在线用户清理
-	go cs.cleanupOnlineUsers()
+	utils.HandleJob(JobChatCreateMessage, cs.processMessageJob, asynq.Queue(utils.JobQueueCritical), asynq.MaxRetry(5))
+	utils.HandleJob(JobChatAfterSend, cs.processAfterSend, asynq.Queue(utils.JobQueueCritical), asynq.MaxRetry(5))
 
 	return cs
 }
@@ -60,23 +78,26 @@ func NewChatService() *ChatService {
 // ==================== 聊天管理方法 ====================
 
 // CreateChat 创建聊天
-func (cs *ChatService) CreateChat(initiatorID, targetUserID string) (*models.Chat, error) {
+func (cs *ChatService) CreateChat(ctx context.Context, initiatorID, targetUserID string) (*models.Chat, error) {
 	// 1. 不能创建与自己的聊天
 	if initiatorID == targetUserID {
 		return nil, errors.New("cannot create chat with yourself")
 	}
 
-	// 2. 检查目标用户是否存在
+	// 2. 检查目标用户是否存在（已停用账号的用户视为不存在）
 	var targetUser models.User
-	if err := config.DB.First(&targetUser, "id = ?", targetUserID).Error; err != nil {
+	if err := cs.db.First(&targetUser, "id = ? AND status <> ?", targetUserID, models.UserStatusDeactivated).Error; err != nil {
 		return nil, errors.New("target user not found")
 	}
+	if err := checkBlocked(ctx, cs.db, initiatorID, targetUserID); err != nil {
+		return nil, err
+	}
 
 	// 3. 检查是否已存在这两个用户的聊天
 	var existingChat models.Chat
 	var existingChatUser models.ChatUser
 
-	err := config.DB.
+	err := cs.db.
 		Joins("JOIN chat_users ON chat_users.chat_id = chats.id").
 		Where("chat_users.user_id = ?", initiatorID).
 		Order("chats.updated_at DESC").
@@ -84,7 +105,7 @@ func (cs *ChatService) CreateChat(initiatorID, targetUserID string) (*models.Cha
 
 	if err == nil {
 		// 检查是否也包含目标用户
-		err = config.DB.
+		err = cs.db.
 			Where("chat_id = ? AND user_id = ?", existingChat.ID, targetUserID).
 			First(&existingChatUser).Error
 
@@ -94,96 +115,123 @@ func (cs *ChatService) CreateChat(initiatorID, targetUserID string) (*models.Cha
 		}
 	}
 
-	// 4. 创建新聊天
+	// 4. 对方只接受关注的人发起聊天时检查是否关注了发起者
+	if err := cs.CheckStrangerChat(ctx, initiatorID, targetUserID); err != nil {
+		return nil, err
+	}
+
+	// 5. 在同一事务中创建新聊天和双方的聊天用户
 	chat := models.Chat{}
 	chat.LastMessage = ""
 	chat.UpdatedAt = time.Now()
 
-	if err := config.DB.Create(&chat).Error; err != nil {
+	err = WithTx(ctx, cs.db, func(ctx context.Context, tx *gorm.DB) error {
+		if err := tx.Create(&chat).Error; err != nil {
+			return err
+		}
+		// 添加聊天用户
+		return tx.Create([]models.ChatUser{
+			{ChatID: chat.ID, UserID: initiatorID},
+			{ChatID: chat.ID, UserID: targetUserID},
+		}).Error
+	})
+	if err != nil {
 		return nil, fmt.Errorf("failed to create chat: %w", err)
 	}
 
-	// 5. 添加聊天用户（使用goroutine并发插入）
-	var wg sync.WaitGroup
-	users := []string{initiatorID, targetUserID}
-
-	for _, uid := range users {
-		wg.Add(1)
-		go func(id string) {
-			defer wg.Done()
-			chatUser := models.ChatUser{
-				ChatID:      chat.ID,
-				UserID:      id,
-				UnreadCount: 0,
-			}
-			config.DB.Create(&chatUser)
-		}(uid)
-	}
-	wg.Wait()
-
-	// 6. 异步缓存到Redis
-	go cs.cacheChat(&chat)
+	// 7. 异步缓存到Redis
+	go cs.cacheChat(context.WithoutCancel(ctx), &chat)
 
-	// 7. 异步通知用户（如果有WebSocket连接）
-	go cs.notifyChatCreated(&chat, initiatorID, targetUserID)
+	// 8. 异步通知用户（如果有WebSocket连接）
+	go cs.notifyChatCreated(context.WithoutCancel(ctx), &chat, initiatorID, targetUserID)
 
-	// 8. 记录聊天创建事件
-	go func() {
-		if config.RedisClient != nil {
-			config.RedisClient.XAdd(redisCtx, &redis.XAddArgs{
-				Stream: "chat_events",
-				Values: map[string]interface{}{
-					"event":          "chat_created",
-					"chat_id":        chat.ID,
-					"initiator_id":   initiatorID,
-					"target_user_id": targetUserID,
-					"timestamp":      time.Now().Unix(),
-				},
-			})
+	// 9. 记录聊天创建事件
+	utils.Go(ctx, "chat_events", func(ctx context.Context) error {
+		if cs.redisClient == nil {
+			return nil
 		}
-	}()
+		return cs.redisClient.XAdd(ctx, &redis.XAddArgs{
+			Stream: "chat_events",
+			Values: map[string]interface{}{
+				"event":          "chat_created",
+				"chat_id":        chat.ID,
+				"initiator_id":   initiatorID,
+				"target_user_id": targetUserID,
+				"timestamp":      time.Now().Unix(),
+			},
+		}).Err()
+	})
 
 	return &chat, nil
 }
 
 // DeleteChat 删除聊天
-func (cs *ChatService) DeleteChat(chatID, userID string) error {
+func (cs *ChatService) DeleteChat(ctx context.Context, chatID, userID string) error {
 	// 1. 检查用户是否有权限删除
 	var chatUser models.ChatUser
-	if err := config.DB.Where("chat_id = ? AND user_id = ?", chatID, userID).First(&chatUser).Error; err != nil {
+	if err := cs.db.Where("chat_id = ? AND user_id = ?", chatID, userID).First(&chatUser).Error; err != nil {
 		return errors.New("you don't have permission to delete this chat")
 	}
 
 	// 2. 软删除聊天
-	if err := config.DB.Delete(&models.Chat{}, "id = ?", chatID).Error; err != nil {
+	if err := cs.db.Delete(&models.Chat{}, "id = ?", chatID).Error; err != nil {
 		return fmt.Errorf("failed to delete chat: %w", err)
 	}
 
 	// 3. 清除缓存
-	go cs.clearChatCaches(chatID)
+	go cs.clearChatCaches(context.WithoutCancel(ctx), chatID)
 
 	return nil
 }
 
 // ==================== 消息方法 ====================
 
+// CheckStrangerChat 对方关闭了“允许陌生人发起聊天”且没有关注发起者时返回403
+// 只在创建新聊天时检查，已有的聊天不受影响
+func (cs *ChatService) CheckStrangerChat(ctx context.Context, initiatorID, targetUserID string) error {
+	allowed, err := privacyAllows(ctx, cs.db, targetUserID, "allow_stranger_chat")
+	if err != nil || allowed {
+		return err
+	}
+	var count int64
+	if err := cs.db.WithContext(ctx).Model(&models.Follow{}).
+		Where("follower_id = ? AND followee_id = ?", targetUserID, initiatorID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check follows: %w", err)
+	}
+	if count == 0 {
+		return ErrStrangerChatDisabled
+	}
+	return nil
+}
+
 // SendMessage 发送消息
-func (cs *ChatService) SendMessage(chatID, userID, content string) (*models.Message, error) {
+// 消息创建任务入队后立即返回未保存的消息（ID为空）；无法入队时直接创建并返回保存后的消息
+func (cs *ChatService) SendMessage(ctx context.Context, chatID, userID, content string) (*models.Message, error) {
 	// 1. 验证内容
 	if content == "" {
 		return nil, errors.New("message content cannot be empty")
 	}
-	if len(content) > 1000 {
-		return nil, errors.New("message content is too long (max 1000 characters)")
+	if maxLength := cs.settings.Int(ctx, SettingMaxMessageLength); utf8.RuneCountInString(content) > maxLength {
+		return nil, fmt.Errorf("message content is too long (max %d characters)", maxLength)
 	}
 
 	// 2. 检查用户是否有权限发送消息
 	var chatUser models.ChatUser
-	if err := config.DB.Where("chat_id = ? AND user_id = ?", chatID, userID).First(&chatUser).Error; err != nil {
+	if err := cs.db.Where("chat_id = ? AND user_id = ?", chatID, userID).First(&chatUser).Error; err != nil {
 		return nil, errors.New("you don't have permission to send messages in this chat")
 	}
+	var otherIDs []string
+	if err := cs.db.Model(&models.ChatUser{}).Where("chat_id = ? AND user_id <> ?", chatID, userID).
+		Pluck("user_id", &otherIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to load chat members: %w", err)
+	}
+	if err := checkBlocked(ctx, cs.db, userID, otherIDs...); err != nil {
+		return nil, err
+	}
 
 	// 3. 将消息任务放入队列
+	spanCtx, span := utils.StartSpan(ctx, "chat.send_message", trace.WithAttributes(attribute.String("chat.id", chatID)))
+	defer span.End()
 	task := &MessageTask{
 		ChatID:    chatID,
 		UserID:    userID,
@@ -191,29 +239,29 @@ func (cs *ChatService) SendMessage(chatID, userID, content string) (*models.Mess
 		Timestamp: time.Now(),
 	}
 
-	select {
-	case cs.messageQueue <- task:
-		// 成功放入队列，立即返回消息ID（实际消息由worker创建）
-		message := &models.Message{
-			ChatID:   chatID,
-			SenderID: userID,
-			Content:  content,
-			IsRead:   false,
-		}
-		return message, nil
-	default:
-		// 队列满，直接处理
-		return cs.processMessageDirect(task)
+	if err := utils.EnqueueJob(spanCtx, JobChatCreateMessage, task); err != nil {
+		// 无法入队，直接处理
+		span.RecordError(err)
+		return cs.processMessageDirect(spanCtx, task)
+	}
+
+	// 成功入队，立即返回（实际消息由worker创建）
+	message := &models.Message{
+		ChatID:   chatID,
+		SenderID: userID,
+		Content:  content,
+		IsRead:   false,
 	}
+	return message, nil
 }
 
 // GetMessages 获取聊天消息
-func (cs *ChatService) GetMessages(chatID, userID string, page, limit int) ([]models.Message, int64, error) {
+func (cs *ChatService) GetMessages(ctx context.Context, chatID, userID string, page, limit int) ([]models.Message, int64, error) {
 	offset := (page - 1) * limit
 
 	// 1. 检查权限
 	var chatUser models.ChatUser
-	if err := config.DB.Where("chat_id = ? AND user_id = ?", chatID, userID).First(&chatUser).Error; err != nil {
+	if err := cs.db.WithContext(ctx).Where("chat_id = ? AND user_id = ?", chatID, userID).First(&chatUser).Error; err != nil {
 		return nil, 0, errors.New("you don't have permission to access this chat")
 	}
 
@@ -221,26 +269,29 @@ func (cs *ChatService) GetMessages(chatID, userID string, page, limit int) ([]mo
 	cacheKey := fmt.Sprintf("chat:%s:messages:page:%d", chatID, page)
 
 	// 3. 尝试从Redis获取
-	if config.RedisClient != nil {
-		cached, err := config.RedisClient.Get(redisCtx, cacheKey).Result()
+	if cs.redisClient != nil {
+		cached, err := cs.redisClient.Get(ctx, cacheKey).Result()
 		if err == nil {
 			var result struct {
 				Messages []models.Message `json:"messages"`
 				Total    int64            `json:"total"`
 			}
 			if json.Unmarshal([]byte(cached), &result) == nil {
+				utils.RecordCacheHit("chats")
 				return result.Messages, result.Total, nil
 			}
 		}
+		utils.RecordCacheMiss("chats")
 	}
 
-	// 4. 从数据库查询
+	// 4. 从数据库查询（聊天记录走只读副本）
 	var messages []models.Message
 	var total int64
 
-	config.DB.Model(&models.Message{}).Where("chat_id = ?", chatID).Count(&total)
+	db := config.ReadReplica(cs.db.WithContext(ctx))
+	db.Model(&models.Message{}).Where("chat_id = ?", chatID).Count(&total)
 
-	if err := config.DB.
+	if err := db.
 		Preload("Sender").
 		Where("chat_id = ?", chatID).
 		Order("created_at DESC").
@@ -257,18 +308,19 @@ func (cs *ChatService) GetMessages(chatID, userID string, page, limit int) ([]mo
 
 	// 6. 异步缓存消息
 	go func() {
-		if config.RedisClient != nil {
+		ctx := context.WithoutCancel(ctx)
+		if cs.redisClient != nil {
 			result := struct {
 				Messages []models.Message `json:"messages"`
 				Total    int64            `json:"total"`
 			}{messages, total}
 			data, _ := json.Marshal(result)
-			config.RedisClient.Set(redisCtx, cacheKey, data, 5*time.Minute)
+			utils.SetTaggedCache(ctx, cs.redisClient, utils.ChatCacheTag(chatID), cacheKey, data, 5*time.Minute)
 		}
 	}()
 
 	// 7. 标记消息为已读（异步）
-	go cs.MarkAsRead(chatID, userID)
+	go cs.MarkAsRead(context.WithoutCancel(ctx), chatID, userID)
 
 	return messages, total, nil
 }
@@ -276,10 +328,10 @@ func (cs *ChatService) GetMessages(chatID, userID string, page, limit int) ([]mo
 // ==================== 聊天列表方法 ====================
 
 // GetChats 获取用户的聊天列表
-func (cs *ChatService) GetChats(userID string) ([]ChatWithUnread, error) {
+func (cs *ChatService) GetChats(ctx context.Context, userID string) ([]ChatWithUnread, error) {
 	// 1. 获取用户参与的聊天关系
 	var chatUsers []models.ChatUser
-	if err := config.DB.Where("user_id = ?", userID).Find(&chatUsers).Error; err != nil {
+	if err := cs.db.WithContext(ctx).Where("user_id = ?", userID).Find(&chatUsers).Error; err != nil {
 		return nil, fmt.Errorf("failed to get chats: %w", err)
 	}
 
@@ -304,7 +356,7 @@ func (cs *ChatService) GetChats(userID string) ([]ChatWithUnread, error) {
 			defer wg.Done()
 
 			var chat models.Chat
-			if err := config.DB.
+			if err := cs.db.WithContext(ctx).
 				Preload("Users").
 				Preload("Users.User").
 				Where("id = ?", id).
@@ -312,9 +364,8 @@ func (cs *ChatService) GetChats(userID string) ([]ChatWithUnread, error) {
 
 				// 从Redis获取未读数
 				var unreadCount int64
-				if config.RedisClient != nil {
-					unreadKey := fmt.Sprintf("unread:%s:%s", userID, id)
-					unread, err := config.RedisClient.Get(redisCtx, unreadKey).Int64()
+				if cs.redisClient != nil {
+					unread, err := utils.GetUnread(ctx, cs.redisClient, userID, id)
 					if err == nil {
 						unreadCount = unread
 					}
@@ -350,44 +401,34 @@ func (cs *ChatService) GetChats(userID string) ([]ChatWithUnread, error) {
 // ==================== 未读消息方法 ====================
 
 // MarkAsRead 标记消息为已读
-func (cs *ChatService) MarkAsRead(chatID, userID string) error {
+func (cs *ChatService) MarkAsRead(ctx context.Context, chatID, userID string) error {
 	// 1. 更新数据库
-	if err := config.DB.Model(&models.Message{}).
+	if err := cs.db.WithContext(ctx).Model(&models.Message{}).
 		Where("chat_id = ? AND sender_id != ?", chatID, userID).
 		Update("is_read", true).Error; err != nil {
 		return fmt.Errorf("failed to mark messages as read: %w", err)
 	}
 
 	// 2. 清除Redis中的未读计数
-	if config.RedisClient != nil {
-		unreadKey := fmt.Sprintf("unread:%s:%s", userID, chatID)
-		config.RedisClient.Del(redisCtx, unreadKey)
-	}
+	utils.ClearUnread(ctx, cs.redisClient, userID, chatID)
 
 	return nil
 }
 
 // GetUnreadCount 获取未读消息数
-func (cs *ChatService) GetUnreadCount(userID string) (map[string]int64, int64, error) {
-	if config.RedisClient == nil {
+func (cs *ChatService) GetUnreadCount(ctx context.Context, userID string) (map[string]int64, int64, error) {
+	if cs.redisClient == nil {
 		return nil, 0, errors.New("redis not available")
 	}
 
-	// 获取所有未读key
-	pattern := fmt.Sprintf("unread:%s:*", userID)
-	keys, _ := config.RedisClient.Keys(redisCtx, pattern).Result()
+	chatUnread, err := utils.GetAllUnread(ctx, cs.redisClient, userID)
+	if err != nil {
+		return nil, 0, err
+	}
 
 	totalUnread := int64(0)
-	chatUnread := make(map[string]int64)
-
-	for _, key := range keys {
-		// 提取chat_id
-		chatID := key[len(fmt.Sprintf("unread:%s:", userID)):]
-
-		// 获取未读数
-		count, _ := config.RedisClient.Get(redisCtx, key).Int64()
+	for _, count := range chatUnread {
 		totalUnread += count
-		chatUnread[chatID] = count
 	}
 
 	return chatUnread, totalUnread, nil
@@ -396,90 +437,79 @@ func (cs *ChatService) GetUnreadCount(userID string) (map[string]int64, int64, e
 // ==================== 在线用户方法 ====================
 
 // SetUserOnline 设置用户在线
-func (cs *ChatService) SetUserOnline(userID string) {
+func (cs *ChatService) SetUserOnline(ctx context.Context, userID string) {
 	cs.onlineUsers.Store(userID, time.Now())
 
-	if config.RedisClient != nil {
-		config.RedisClient.Set(redisCtx, "online:"+userID, "1", 5*time.Minute)
-		config.RedisClient.SAdd(redisCtx, "online:users", userID)
+	if cs.redisClient != nil {
+		cs.redisClient.Set(ctx, "online:"+userID, "1", 5*time.Minute)
+		cs.redisClient.SAdd(ctx, "online:users", userID)
 	}
 }
 
 // SetUserOffline 设置用户离线
-func (cs *ChatService) SetUserOffline(userID string) {
+func (cs *ChatService) SetUserOffline(ctx context.Context, userID string) {
 	cs.onlineUsers.Delete(userID)
 
-	if config.RedisClient != nil {
-		config.RedisClient.Del(redisCtx, "online:"+userID)
-		config.RedisClient.SRem(redisCtx, "online:users", userID)
+	if cs.redisClient != nil {
+		cs.redisClient.Del(ctx, "online:"+userID)
+		cs.redisClient.SRem(ctx, "online:users", userID)
 	}
 }
 
 // IsUserOnline 检查用户是否在线
-func (cs *ChatService) IsUserOnline(userID string) bool {
+func (cs *ChatService) IsUserOnline(ctx context.Context, userID string) bool {
 	// 1. 检查内存缓存
 	if _, exists := cs.onlineUsers.Load(userID); exists {
 		return true
 	}
 
 	// 2. 检查Redis
-	if config.RedisClient != nil {
-		exists, _ := config.RedisClient.Exists(redisCtx, "online:"+userID).Result()
+	if cs.redisClient != nil {
+		exists, _ := cs.redisClient.Exists(ctx, "online:"+userID).Result()
 		return exists > 0
 	}
 
 	return false
 }
 
-// GetOnlineUsers 获取在线用户列表
-func (cs *ChatService) GetOnlineUsers() ([]string, error) {
-	if config.RedisClient == nil {
+// GetOnlineUsers 获取在线用户列表，不包含关闭了在线状态显示的用户
+func (cs *ChatService) GetOnlineUsers(ctx context.Context) ([]string, error) {
+	if cs.redisClient == nil {
 		return nil, errors.New("redis not available")
 	}
 
-	return config.RedisClient.SMembers(redisCtx, "online:users").Result()
+	userIDs, err := cs.redisClient.SMembers(ctx, "online:users").Result()
+	if err != nil {
+		return nil, err
+	}
+	return withVisibleOnlineStatus(ctx, cs.db.WithContext(ctx), userIDs)
 }
 
 // GetOnlineUserCount 获取在线用户数
-func (cs *ChatService) GetOnlineUserCount() (int64, error) {
-	if config.RedisClient == nil {
+func (cs *ChatService) GetOnlineUserCount(ctx context.Context) (int64, error) {
+	if cs.redisClient == nil {
 		return 0, errors.New("redis not available")
 	}
 
-	return config.RedisClient.SCard(redisCtx, "online:users").Result()
+	return cs.redisClient.SCard(ctx, "online:users").Result()
 }
 
-// ==================== Worker处理方法 ====================
-
-// startWorkers 启动worker池
-func (cs *ChatService) startWorkers() {
-	// 消息发送worker
-	for i := 0; i < 5; i++ {
-		go cs.messageSender(i)
-	}
-
-	// 消息处理worker
-	for i := 0; i < 3; i++ {
-		go cs.messageProcessor(i)
-	}
-}
-
-// messageSender 消息发送worker
-func (cs *ChatService) messageSender(workerID int) {
-	for task := range cs.messageQueue {
-		cs.processMessageDirect(task)
-	}
-}
+// ==================== 后台任务 ====================
 
-// messageProcessor 消息处理worker
-func (cs *ChatService) messageProcessor(workerID int) {
-	for task := range cs.processQueue {
-		cs.processAfterSend(task)
-	}
+// processMessageJob 消息创建任务
+func (cs *ChatService) processMessageJob(ctx context.Context, task MessageTask) error {
+	_, err := cs.processMessageDirect(ctx, &task)
+	return err
 }
 
 // processMessageDirect 直接处理消息
-func (cs *ChatService) processMessageDirect(task *MessageTask) (*models.Message, error) {
+func (cs *ChatService) processMessageDirect(ctx context.Context, task *MessageTask) (*models.Message, error) {
+	spanCtx, span := utils.StartSpan(ctx, "chat.create_message", trace.WithAttributes(
+		attribute.String("chat.id", task.ChatID),
+		attribute.Int64("queue.wait_ms", time.Since(task.Timestamp).Milliseconds()),
+	))
+	defer span.End()
+
 	// 1. 创建消息
 	message := models.Message{
 		ChatID:   task.ChatID,
@@ -488,22 +518,39 @@ func (cs *ChatService) processMessageDirect(task *MessageTask) (*models.Message,
 		IsRead:   false,
 	}
 
-	if err := config.DB.Create(&message).Error; err != nil {
+	if err := cs.db.WithContext(spanCtx).Create(&message).Error; err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "create message failed")
 		return nil, fmt.Errorf("failed to create message: %w", err)
 	}
+	RecordDailyStat(ctx, cs.redisClient, StatMessages)
 
-	// 2. 将消息处理任务放入队列
-	cs.processQueue <- &MessageProcessTask{Message: &message}
+	// 2. 提交消息处理任务；消息已保存，入队失败只记录，不让创建任务重试产生重复消息
+	if err := utils.EnqueueJob(spanCtx, JobChatAfterSend, &MessageProcessTask{MessageID: message.ID}); err != nil {
+		span.RecordError(err)
+	}
 
 	return &message, nil
 }
 
 // processAfterSend 消息发送后的处理
-func (cs *ChatService) processAfterSend(task *MessageProcessTask) error {
-	message := task.Message
+func (cs *ChatService) processAfterSend(ctx context.Context, task MessageProcessTask) error {
+	spanCtx, span := utils.StartSpan(ctx, "chat.after_send", trace.WithAttributes(attribute.String("message.id", task.MessageID)))
+	defer span.End()
+	db := cs.db.WithContext(spanCtx)
+
+	var message models.Message
+	if err := db.First(&message, "id = ?", task.MessageID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// 消息已被删除，无需处理
+			return nil
+		}
+		return err
+	}
+	span.SetAttributes(attribute.String("chat.id", message.ChatID))
 
 	// 1. 更新聊天的最后消息和时间
-	if err := config.DB.Model(&models.Chat{}).Where("id = ?", message.ChatID).Updates(map[string]interface{}{
+	if err := db.Model(&models.Chat{}).Where("id = ?", message.ChatID).Updates(map[string]interface{}{
 		"last_message": message.Content,
 		"updated_at":   time.Now(),
 	}).Error; err != nil {
@@ -512,82 +559,82 @@ func (cs *ChatService) processAfterSend(task *MessageProcessTask) error {
 
 	// 2. 获取聊天参与者
 	var chatUsers []models.ChatUser
-	if err := config.DB.Where("chat_id = ?", message.ChatID).Find(&chatUsers).Error; err != nil {
+	if err := db.Where("chat_id = ?", message.ChatID).Find(&chatUsers).Error; err != nil {
 		return err
 	}
 
 	// 3. 增加未读计数（给接收者）
 	for _, chatUser := range chatUsers {
 		if chatUser.UserID != message.SenderID {
-			if config.RedisClient != nil {
-				unreadKey := fmt.Sprintf("unread:%s:%s", chatUser.UserID, message.ChatID)
-				config.RedisClient.Incr(redisCtx, unreadKey)
-				config.RedisClient.Expire(redisCtx, unreadKey, 7*24*time.Hour)
-			}
+			utils.IncrUnread(spanCtx, cs.redisClient, chatUser.UserID, message.ChatID)
 		}
 	}
 
-	// 4. 清除聊天列表缓存
-	if config.RedisClient != nil {
-		pattern := "chat:*"
-		keys, _ := config.RedisClient.Keys(redisCtx, pattern).Result()
-		for _, key := range keys {
-			config.RedisClient.Del(redisCtx, key)
-		}
-	}
+	// 4. 推送给不在线的接收者
+	cs.pushMessage(ctx, &message, chatUsers)
 
-	// 5. 发布到Redis PubSub（用于WebSocket推送）
-	if config.RedisClient != nil {
+	// 5. 清除该会话的缓存
+	cs.clearChatCaches(ctx, message.ChatID)
+
+	// 6. 发布到Redis PubSub（用于WebSocket推送）
+	if cs.redisClient != nil {
 		pubMessage := map[string]interface{}{
 			"type":      "message",
 			"chat_id":   message.ChatID,
 			"sender_id": message.SenderID,
 			"content":   message.Content,
 			"timestamp": message.CreatedAt.Unix(),
+			"trace":     utils.InjectTrace(spanCtx),
 		}
 		data, _ := json.Marshal(pubMessage)
-		config.RedisClient.Publish(redisCtx, "chat:message", data)
+		cs.redisClient.Publish(spanCtx, "chat:message", data)
 	}
 
 	return nil
 }
 
+// pushMessage 向聊天中除发送者外的用户发送移动端推送
+func (cs *ChatService) pushMessage(ctx context.Context, message *models.Message, chatUsers []models.ChatUser) {
+	var sender models.User
+	cs.db.Select("id", "username").First(&sender, "id = ?", message.SenderID)
+
+	for _, chatUser := range chatUsers {
+		if chatUser.UserID == message.SenderID {
+			continue
+		}
+		cs.pushService.Enqueue(ctx, chatUser.UserID, "chat_message", sender.Username, truncateRunes(message.Content, 100), map[string]string{
+			"chat_id":    message.ChatID,
+			"message_id": message.ID,
+		})
+	}
+}
+
 // ==================== 辅助方法 ====================
 
 // cacheChat 缓存聊天信息
-func (cs *ChatService) cacheChat(chat *models.Chat) {
-	if config.RedisClient == nil {
+func (cs *ChatService) cacheChat(ctx context.Context, chat *models.Chat) {
+	if cs.redisClient == nil {
 		return
 	}
 
 	cacheKey := fmt.Sprintf("chat:%s", chat.ID)
 	data, _ := json.Marshal(chat)
-	config.RedisClient.Set(redisCtx, cacheKey, data, 10*time.Minute)
+	cs.redisClient.Set(ctx, cacheKey, data, 10*time.Minute)
 }
 
 // clearChatCaches 清除聊天相关缓存
-func (cs *ChatService) clearChatCaches(chatID string) {
-	if config.RedisClient == nil {
+func (cs *ChatService) clearChatCaches(ctx context.Context, chatID string) {
+	if cs.redisClient == nil {
 		return
 	}
 
-	keys := []string{
-		fmt.Sprintf("chat:%s", chatID),
-		fmt.Sprintf("chat:%s:messages:*", chatID),
-	}
-
-	for _, key := range keys {
-		if keys, err := config.RedisClient.Keys(redisCtx, key).Result(); err == nil {
-			for _, k := range keys {
-				config.RedisClient.Del(redisCtx, k)
-			}
-		}
-	}
+	cs.redisClient.Del(ctx, fmt.Sprintf("chat:%s", chatID))
+	utils.InvalidateCacheTag(ctx, cs.redisClient, utils.ChatCacheTag(chatID))
 }
 
 // notifyChatCreated 通知聊天创建
-func (cs *ChatService) notifyChatCreated(chat *models.Chat, initiatorID, targetUserID string) {
-	if config.RedisClient == nil {
+func (cs *ChatService) notifyChatCreated(ctx context.Context, chat *models.Chat, initiatorID, targetUserID string) {
+	if cs.redisClient == nil {
 		return
 	}
 
@@ -600,27 +647,22 @@ func (cs *ChatService) notifyChatCreated(chat *models.Chat, initiatorID, targetU
 		"timestamp":      time.Now().Unix(),
 	}
 	data, _ := json.Marshal(notification)
-	config.RedisClient.Publish(redisCtx, "chat:notification", data)
+	cs.redisClient.Publish(ctx, "chat:notification", data)
 }
 
-// cleanupOnlineUsers 清理过期在线用户
-func (cs *ChatService) cleanupOnlineUsers() {
-	ticker := time.NewTicker(2 * time.Minute)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		cs.onlineUsers.Range(func(key, value interface{}) bool {
-			lastSeen := value.(time.Time)
-			if time.Since(lastSeen) > 5*time.Minute {
-				userID := key.(string)
-				cs.onlineUsers.Delete(key)
-
-				if config.RedisClient != nil {
-					config.RedisClient.Del(redisCtx, "online:"+userID)
-					config.RedisClient.SRem(redisCtx, "online:users", userID)
-				}
+// CleanupOnlineUsers 清理本实例超过5分钟未活跃的在线用户，由定时任务 online_user_cleanup 调用
+func (cs *ChatService) CleanupOnlineUsers(ctx context.Context) {
+	cs.onlineUsers.Range(func(key, value interface{}) bool {
+		lastSeen := value.(time.Time)
+		if time.Since(lastSeen) > 5*time.Minute {
+			userID := key.(string)
+			cs.onlineUsers.Delete(key)
+
+			if cs.redisClient != nil {
+				cs.redisClient.Del(ctx, "online:"+userID)
+				cs.redisClient.SRem(ctx, "online:users", userID)
 			}
-			return true
-		})
-	}
+		}
+		return true
+	})
 }
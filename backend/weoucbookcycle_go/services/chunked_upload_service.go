@@ -0,0 +1,337 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"weoucbookcycle_go/utils"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	uploadSessionTTL       = 24 * time.Hour
+	uploadSessionsIndexKey = "upload:sessions"
+	defaultChunkSize       = 1024 * 1024
+	minChunkSize           = 256 * 1024
+	maxChunkSize           = 5 * 1024 * 1024
+)
+
+var (
+	// ErrUploadSessionNotFound 上传会话不存在、已过期或不属于当前用户
+	ErrUploadSessionNotFound = utils.NewError(http.StatusNotFound, "upload session not found")
+	// ErrUploadIncomplete 还有分片未上传
+	ErrUploadIncomplete = utils.NewError(http.StatusConflict, "upload is incomplete")
+	// ErrUploadCompleting 已有合并请求在处理
+	ErrUploadCompleting = utils.NewError(http.StatusConflict, "upload is already being completed")
+	// ErrInvalidChunk 分片序号或大小不正确
+	ErrInvalidChunk = utils.NewError(http.StatusBadRequest, "invalid chunk")
+)
+
+// ChunkedUploadService 分片上传服务
+// 会话信息保存在Redis中，分片写入存储的 chunks/ 目录，全部上传后合并并按普通上传处理
+type ChunkedUploadService struct {
+	redisClient *redis.Client
+	storage     utils.Storage
+	uploader    *utils.FileUploader
+}
+
+// NewChunkedUploadService 创建分片上传服务实例
+func NewChunkedUploadService(deps Deps) *ChunkedUploadService {
+	return &ChunkedUploadService{
+		redisClient: deps.Redis,
+		storage:     utils.GetStorage(),
+		uploader:    utils.NewFileUploader(deps.Redis),
+	}
+}
+
+// InitChunkedUploadRequest 初始化分片上传请求
+type InitChunkedUploadRequest struct {
+	FileName    string `json:"file_name" binding:"required,max=255"`
+	FileSize    int64  `json:"file_size" binding:"required,min=1"`
+	ContentType string `json:"content_type" binding:"omitempty,max=100"`
+	// ChunkSize 分片大小（字节），默认1MB，范围256KB-5MB
+	ChunkSize int64 `json:"chunk_size" binding:"omitempty,min=0"`
+}
+
+// ChunkedUploadSession 分片上传会话
+type ChunkedUploadSession struct {
+	UploadID       string `json:"upload_id"`
+	TaskID         string `json:"task_id"`
+	FileName       string `json:"file_name"`
+	FileSize       int64  `json:"file_size"`
+	ContentType    string `json:"content_type,omitempty"`
+	ChunkSize      int64  `json:"chunk_size"`
+	TotalChunks    int    `json:"total_chunks"`
+	ReceivedChunks []int  `json:"received_chunks"`
+	ExpiresAt      int64  `json:"expires_at"`
+	userID         string
+}
+
+// InitUpload 创建上传会话
+func (cus *ChunkedUploadService) InitUpload(ctx context.Context, userID string, req *InitChunkedUploadRequest) (*ChunkedUploadSession, error) {
+	if cus.redisClient == nil {
+		return nil, errors.New("redis not available")
+	}
+	if err := cus.uploader.ValidateFile(req.FileName, req.FileSize); err != nil {
+		return nil, err
+	}
+	if err := cus.uploader.CheckQuota(ctx, userID, 1, req.FileSize); err != nil {
+		return nil, err
+	}
+
+	chunkSize := req.ChunkSize
+	if chunkSize == 0 {
+		chunkSize = defaultChunkSize
+	}
+	chunkSize = max(minChunkSize, min(chunkSize, maxChunkSize))
+
+	session := &ChunkedUploadSession{
+		UploadID:       uuid.NewString(),
+		FileName:       req.FileName,
+		FileSize:       req.FileSize,
+		ContentType:    req.ContentType,
+		ChunkSize:      chunkSize,
+		TotalChunks:    int((req.FileSize + chunkSize - 1) / chunkSize),
+		ReceivedChunks: []int{},
+		ExpiresAt:      time.Now().Add(uploadSessionTTL).Unix(),
+		userID:         userID,
+	}
+
+	// 上传进度通过任务状态接口查询
+	session.TaskID = utils.CreateTask(cus.redisClient, userID, "uploading")
+	utils.UpdateTaskProgress(cus.redisClient, session.TaskID, 0, int64(session.TotalChunks))
+
+	sessionKey := uploadSessionKey(session.UploadID)
+	pipe := cus.redisClient.TxPipeline()
+	pipe.HSet(ctx, sessionKey, map[string]interface{}{
+		"user_id":      userID,
+		"task_id":      session.TaskID,
+		"file_name":    session.FileName,
+		"file_size":    session.FileSize,
+		"content_type": session.ContentType,
+		"chunk_size":   session.ChunkSize,
+		"total_chunks": session.TotalChunks,
+		"expires_at":   session.ExpiresAt,
+	})
+	pipe.Expire(ctx, sessionKey, uploadSessionTTL)
+	// 记录会话和分片数量，用于清理过期会话留下的分片
+	pipe.ZAdd(ctx, uploadSessionsIndexKey, redis.Z{
+		Score:  float64(session.ExpiresAt),
+		Member: fmt.Sprintf("%s|%d", session.UploadID, session.TotalChunks),
+	})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create upload session: %w", err)
+	}
+
+	return session, nil
+}
+
+// GetSession 获取上传会话及已上传的分片，用于断点续传
+func (cus *ChunkedUploadService) GetSession(ctx context.Context, userID, uploadID string) (*ChunkedUploadSession, error) {
+	if cus.redisClient == nil {
+		return nil, errors.New("redis not available")
+	}
+
+	fields, err := cus.redisClient.HGetAll(ctx, uploadSessionKey(uploadID)).Result()
+	if err != nil || len(fields) == 0 || fields["user_id"] != userID {
+		return nil, ErrUploadSessionNotFound
+	}
+
+	session := &ChunkedUploadSession{
+		UploadID:    uploadID,
+		TaskID:      fields["task_id"],
+		FileName:    fields["file_name"],
+		ContentType: fields["content_type"],
+		userID:      fields["user_id"],
+	}
+	session.FileSize, _ = strconv.ParseInt(fields["file_size"], 10, 64)
+	session.ChunkSize, _ = strconv.ParseInt(fields["chunk_size"], 10, 64)
+	session.TotalChunks, _ = strconv.Atoi(fields["total_chunks"])
+	session.ExpiresAt, _ = strconv.ParseInt(fields["expires_at"], 10, 64)
+
+	members, _ := cus.redisClient.SMembers(ctx, uploadChunksKey(uploadID)).Result()
+	session.ReceivedChunks = make([]int, 0, len(members))
+	for _, m := range members {
+		if index, err := strconv.Atoi(m); err == nil {
+			session.ReceivedChunks = append(session.ReceivedChunks, index)
+		}
+	}
+	sort.Ints(session.ReceivedChunks)
+
+	return session, nil
+}
+
+// PutChunk 上传一个分片，重复上传同一分片会覆盖
+func (cus *ChunkedUploadService) PutChunk(ctx context.Context, userID, uploadID string, index int, reader io.Reader) (*ChunkedUploadSession, error) {
+	session, err := cus.GetSession(ctx, userID, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 || index >= session.TotalChunks {
+		return nil, fmt.Errorf("%w: index %d out of range [0, %d)", ErrInvalidChunk, index, session.TotalChunks)
+	}
+
+	// 除最后一个分片外大小必须等于分片大小
+	expected := session.ChunkSize
+	if index == session.TotalChunks-1 {
+		expected = session.FileSize - int64(index)*session.ChunkSize
+	}
+	data, err := io.ReadAll(io.LimitReader(reader, expected+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chunk: %w", err)
+	}
+	if int64(len(data)) != expected {
+		return nil, fmt.Errorf("%w: chunk %d should be %d bytes, got %d", ErrInvalidChunk, index, expected, len(data))
+	}
+
+	if _, err := cus.storage.Put(ctx, chunkKey(uploadID, index), bytes.NewReader(data), int64(len(data)), "application/octet-stream"); err != nil {
+		return nil, err
+	}
+
+	pipe := cus.redisClient.TxPipeline()
+	pipe.SAdd(ctx, uploadChunksKey(uploadID), index)
+	pipe.Expire(ctx, uploadChunksKey(uploadID), time.Until(time.Unix(session.ExpiresAt, 0)))
+	received := pipe.SCard(ctx, uploadChunksKey(uploadID))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to record chunk: %w", err)
+	}
+	utils.UpdateTaskProgress(cus.redisClient, session.TaskID, received.Val(), int64(session.TotalChunks))
+
+	return cus.GetSession(ctx, userID, uploadID)
+}
+
+// Complete 合并全部分片并保存文件
+func (cus *ChunkedUploadService) Complete(ctx context.Context, userID, uploadID string) (*utils.UploadResult, error) {
+	session, err := cus.GetSession(ctx, userID, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if len(session.ReceivedChunks) != session.TotalChunks {
+		return nil, fmt.Errorf("%w: %d of %d chunks received", ErrUploadIncomplete, len(session.ReceivedChunks), session.TotalChunks)
+	}
+
+	// 防止重复提交合并
+	sessionKey := uploadSessionKey(uploadID)
+	if ok, _ := cus.redisClient.HSetNX(ctx, sessionKey, "completing", 1).Result(); !ok {
+		return nil, ErrUploadCompleting
+	}
+
+	startTime := time.Now()
+	utils.UpdateTaskStatus(cus.redisClient, session.TaskID, "processing")
+
+	result, err := cus.assemble(ctx, session)
+	if err != nil {
+		cus.redisClient.HDel(ctx, sessionKey, "completing")
+		utils.UpdateTaskStatus(cus.redisClient, session.TaskID, "uploading")
+		return nil, err
+	}
+
+	utils.FinishTask(cus.redisClient, session.TaskID, startTime, nil, map[string]interface{}{
+		"original_url": result.OriginalURL,
+		"thumb_url":    result.ThumbURL,
+		"key":          result.Key,
+	})
+	cus.cleanup(ctx, uploadID, session.TotalChunks)
+
+	return result, nil
+}
+
+// Abort 取消上传并删除已上传的分片
+func (cus *ChunkedUploadService) Abort(ctx context.Context, userID, uploadID string) error {
+	session, err := cus.GetSession(ctx, userID, uploadID)
+	if err != nil {
+		return err
+	}
+
+	utils.FinishTask(cus.redisClient, session.TaskID, time.Now(), errors.New("upload aborted"), nil)
+	cus.cleanup(ctx, uploadID, session.TotalChunks)
+	return nil
+}
+
+// assemble 按顺序读取分片合并为完整文件
+func (cus *ChunkedUploadService) assemble(ctx context.Context, session *ChunkedUploadSession) (*utils.UploadResult, error) {
+	var buf bytes.Buffer
+	buf.Grow(int(session.FileSize))
+
+	for i := 0; i < session.TotalChunks; i++ {
+		src, err := cus.storage.Open(ctx, chunkKey(session.UploadID, i))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open chunk %d: %w", i, err)
+		}
+		_, err = io.Copy(&buf, src)
+		src.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read chunk %d: %w", i, err)
+		}
+	}
+
+	if int64(buf.Len()) != session.FileSize {
+		return nil, fmt.Errorf("%w: assembled %d bytes, expected %d", ErrInvalidChunk, buf.Len(), session.FileSize)
+	}
+
+	return cus.uploader.SaveData(ctx, session.userID, session.FileName, buf.Bytes())
+}
+
+// cleanup 删除会话和分片
+func (cus *ChunkedUploadService) cleanup(ctx context.Context, uploadID string, totalChunks int) {
+	for i := 0; i < totalChunks; i++ {
+		cus.storage.Delete(context.Background(), chunkKey(uploadID, i))
+	}
+	cus.redisClient.Del(ctx, uploadSessionKey(uploadID), uploadChunksKey(uploadID))
+	cus.redisClient.ZRem(ctx, uploadSessionsIndexKey, fmt.Sprintf("%s|%d", uploadID, totalChunks))
+}
+
+// CleanupExpired 删除已过期会话的分片，由定时任务 upload_gc 调用
+func (cus *ChunkedUploadService) CleanupExpired(ctx context.Context) error {
+	if cus.redisClient == nil {
+		return nil
+	}
+
+	expired, err := cus.redisClient.ZRangeByScore(ctx, uploadSessionsIndexKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(time.Now().Unix(), 10),
+	}).Result()
+	if err != nil {
+		return err
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+
+	for _, member := range expired {
+		uploadID, total, ok := strings.Cut(member, "|")
+		totalChunks, err := strconv.Atoi(total)
+		if !ok || err != nil {
+			cus.redisClient.ZRem(ctx, uploadSessionsIndexKey, member)
+			continue
+		}
+		cus.cleanup(ctx, uploadID, totalChunks)
+	}
+	log.Printf("chunked upload cleanup: removed %d expired sessions", len(expired))
+	return nil
+}
+
+// uploadSessionKey 上传会话key
+func uploadSessionKey(uploadID string) string {
+	return fmt.Sprintf("upload:session:%s", uploadID)
+}
+
+// uploadChunksKey 已上传分片集合key
+func uploadChunksKey(uploadID string) string {
+	return fmt.Sprintf("upload:session:%s:chunks", uploadID)
+}
+
+// chunkKey 分片在存储中的key
+func chunkKey(uploadID string, index int) string {
+	return fmt.Sprintf("%s%s/%d", utils.ChunkPrefix, uploadID, index)
+}
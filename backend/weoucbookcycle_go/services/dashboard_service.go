@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+	"weoucbookcycle_go/models"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// dashboardCacheTTL 个人统计中数据库汇总部分的缓存时间
+// 发布和书籍变化时主动失效，收到的收藏数允许短暂滞后
+const dashboardCacheTTL = 5 * time.Minute
+
+// DashboardStats "我的"页面的个人统计
+type DashboardStats struct {
+	ActiveBooks       int64   `json:"active_books"`
+	ActiveListings    int64   `json:"active_listings"`
+	FavoritesReceived int64   `json:"favorites_received"`
+	SoldListings      int64   `json:"sold_listings"`
+	SalesTotal        float64 `json:"sales_total"`
+	Chats             int64   `json:"chats"`
+	// 未读数不缓存，直接取自聊天和通知各自维护的计数
+	UnreadMessages      int64     `json:"unread_messages"`
+	UnreadNotifications int64     `json:"unread_notifications"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// DashboardService 个人统计，汇总自己的书籍、发布、聊天和未读数
+type DashboardService struct {
+	db                  *gorm.DB
+	redisClient         *redis.Client
+	chatService         *ChatService
+	notificationService *NotificationService
+}
+
+// NewDashboardService 创建个人统计服务实例
+func NewDashboardService(deps Deps, chatService *ChatService, notificationService *NotificationService) *DashboardService {
+	return &DashboardService{
+		db:                  deps.DB,
+		redisClient:         deps.Redis,
+		chatService:         chatService,
+		notificationService: notificationService,
+	}
+}
+
+// DashboardCacheKey 个人统计缓存key，发布或书籍变化时删除
+func DashboardCacheKey(userID string) string {
+	return "user:stats:" + userID
+}
+
+// Get 获取用户的个人统计
+func (ds *DashboardService) Get(ctx context.Context, userID string) (*DashboardStats, error) {
+	stats, err := ds.aggregates(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 未读数各自有缓存，Redis不可用时按0返回
+	if _, total, err := ds.chatService.GetUnreadCount(ctx, userID); err == nil {
+		stats.UnreadMessages = total
+	}
+	if count, err := ds.notificationService.GetUnreadCount(ctx, userID); err == nil {
+		stats.UnreadNotifications = count.Total
+	}
+	return stats, nil
+}
+
+// aggregates 从数据库汇总书籍、发布和聊天数量，结果缓存 dashboardCacheTTL
+func (ds *DashboardService) aggregates(ctx context.Context, userID string) (*DashboardStats, error) {
+	cacheKey := DashboardCacheKey(userID)
+	if ds.redisClient != nil {
+		if cached, err := ds.redisClient.Get(ctx, cacheKey).Bytes(); err == nil {
+			var stats DashboardStats
+			if json.Unmarshal(cached, &stats) == nil {
+				return &stats, nil
+			}
+		}
+	}
+
+	stats := &DashboardStats{UpdatedAt: time.Now()}
+	db := ds.db.WithContext(ctx)
+
+	if err := db.Model(&models.Book{}).
+		Where("seller_id = ? AND status = ?", userID, 1).
+		Count(&stats.ActiveBooks).Error; err != nil {
+		return nil, fmt.Errorf("failed to count books: %w", err)
+	}
+
+	// 发布的各项数量一次查询得出
+	var listings struct {
+		Active    int64
+		Favorites int64
+		Sold      int64
+		Sales     float64
+	}
+	if err := db.Model(&models.Listing{}).
+		Select(`COUNT(CASE WHEN status IN ('available', 'reserved') THEN 1 END) AS active,
+			COALESCE(SUM(favorite_count), 0) AS favorites,
+			COUNT(CASE WHEN status = 'sold' THEN 1 END) AS sold,
+			COALESCE(SUM(CASE WHEN status = 'sold' THEN price END), 0) AS sales`).
+		Where("seller_id = ?", userID).
+		Scan(&listings).Error; err != nil {
+		return nil, fmt.Errorf("failed to aggregate listings: %w", err)
+	}
+	stats.ActiveListings = listings.Active
+	stats.FavoritesReceived = listings.Favorites
+	stats.SoldListings = listings.Sold
+	stats.SalesTotal = listings.Sales
+
+	if err := db.Model(&models.ChatUser{}).
+		Joins("JOIN chats ON chats.id = chat_users.chat_id AND chats.deleted_at IS NULL").
+		Where("chat_users.user_id = ?", userID).
+		Count(&stats.Chats).Error; err != nil {
+		return nil, fmt.Errorf("failed to count chats: %w", err)
+	}
+
+	if ds.redisClient != nil {
+		if data, err := json.Marshal(stats); err == nil {
+			ds.redisClient.Set(context.WithoutCancel(ctx), cacheKey, data, dashboardCacheTTL)
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,398 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"github.com/hibiken/asynq"
+	"gorm.io/gorm"
+)
+
+const JobDataExport = "export:user_data"
+
+const (
+	// DataExportInterval 同一用户两次申请导出的最短间隔（失败的导出不计）
+	DataExportInterval = 24 * time.Hour
+	// DataExportRetention 归档生成后保留的时间，过期后不能再下载
+	DataExportRetention = 7 * 24 * time.Hour
+	// dataExportKeyPrefix 个人数据归档按用户存放在导出目录下
+	dataExportKeyPrefix = exportKeyPrefix + "users/"
+	// dataExportCleanupBatch 每批删除的过期归档数
+	dataExportCleanupBatch = 200
+)
+
+var (
+	ErrDataExportNotFound = utils.NewError(http.StatusNotFound, "export not found")
+	ErrDataExportExpired  = utils.NewError(http.StatusGone, "export has expired, please request a new one")
+)
+
+// DataExportTask 生成个人数据归档
+type DataExportTask struct {
+	ExportID string
+}
+
+// dataExportMessage 归档中的聊天消息
+type dataExportMessage struct {
+	ID        string    `json:"id"`
+	ChatID    string    `json:"-"`
+	SenderID  string    `json:"sender_id"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// dataExportChat 归档中的聊天，包含全部成员ID和消息
+type dataExportChat struct {
+	ID        string              `json:"id"`
+	MemberIDs []string            `json:"member_ids"`
+	CreatedAt time.Time           `json:"created_at"`
+	Messages  []dataExportMessage `json:"messages"`
+}
+
+// dataExportUpload 归档中的上传文件，文件内容位于 Path，读取失败时 Missing 为 true
+type dataExportUpload struct {
+	models.UploadedFile
+	Path    string `json:"path,omitempty"`
+	Missing bool   `json:"missing,omitempty"`
+}
+
+// DataExportService 个人数据导出（"下载我的数据"）
+// 申请后由后台任务打包为zip并上传到私有存储，通过有效期15分钟的签名链接下载
+type DataExportService struct {
+	db *gorm.DB
+}
+
+// NewDataExportService 创建个人数据导出服务实例，并注册打包任务的处理函数
+func NewDataExportService(deps Deps) *DataExportService {
+	ds := &DataExportService{db: deps.DB}
+
+	utils.HandleJob(JobDataExport, ds.process, asynq.Queue(utils.JobQueueLow), asynq.MaxRetry(3), asynq.Timeout(30*time.Minute))
+
+	return ds
+}
+
+// Request 申请导出个人数据，距上次申请不足 DataExportInterval 时返回限流错误
+func (ds *DataExportService) Request(ctx context.Context, userID string) (*models.DataExport, error) {
+	var last models.DataExport
+	err := ds.db.WithContext(ctx).Where("user_id = ? AND status <> ?", userID, models.DataExportFailed).
+		Order("created_at DESC").First(&last).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to check previous exports: %w", err)
+	}
+	if err == nil {
+		if wait := dataExportWait(last.CreatedAt, time.Now()); wait > 0 {
+			return nil, &utils.RateLimitError{RetryAfter: wait}
+		}
+	}
+
+	export := models.DataExport{UserID: userID, Status: models.DataExportPending}
+	if err := ds.db.WithContext(ctx).Create(&export).Error; err != nil {
+		return nil, fmt.Errorf("failed to create export: %w", err)
+	}
+	if err := utils.EnqueueJob(ctx, JobDataExport, DataExportTask{ExportID: export.ID}); err != nil {
+		ds.db.WithContext(ctx).Delete(&export)
+		return nil, err
+	}
+	return &export, nil
+}
+
+// Get 获取自己的导出记录
+func (ds *DataExportService) Get(ctx context.Context, userID, exportID string) (*models.DataExport, error) {
+	var export models.DataExport
+	if err := ds.db.WithContext(ctx).First(&export, "id = ? AND user_id = ?", exportID, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDataExportNotFound
+		}
+		return nil, fmt.Errorf("failed to get export: %w", err)
+	}
+	return &export, nil
+}
+
+// DownloadURL 返回已完成归档的临时下载链接
+func (ds *DataExportService) DownloadURL(ctx context.Context, userID, exportID string) (string, error) {
+	export, err := ds.Get(ctx, userID, exportID)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case export.Status == models.DataExportExpired,
+		export.ExpiresAt != nil && time.Now().After(*export.ExpiresAt):
+		return "", ErrDataExportExpired
+	case export.Status != models.DataExportCompleted:
+		return "", ErrExportNotReady
+	}
+	return utils.GetStorage().SignedURL(ctx, export.Key, ExportURLTTL)
+}
+
+// CleanupExpired 删除过期的归档文件并把记录标记为已过期
+func (ds *DataExportService) CleanupExpired(ctx context.Context) error {
+	for {
+		var exports []models.DataExport
+		if err := ds.db.WithContext(ctx).
+			Where("status = ? AND expires_at < ?", models.DataExportCompleted, time.Now()).
+			Limit(dataExportCleanupBatch).Find(&exports).Error; err != nil {
+			return fmt.Errorf("failed to load expired exports: %w", err)
+		}
+
+		for _, export := range exports {
+			if err := utils.GetStorage().Delete(ctx, export.Key); err != nil {
+				return fmt.Errorf("failed to delete export %s: %w", export.ID, err)
+			}
+			if err := ds.db.WithContext(ctx).Model(&export).
+				Updates(map[string]interface{}{"status": models.DataExportExpired, "key": ""}).Error; err != nil {
+				return fmt.Errorf("failed to expire export %s: %w", export.ID, err)
+			}
+		}
+
+		if len(exports) < dataExportCleanupBatch {
+			return nil
+		}
+	}
+}
+
+// process 打包个人数据并上传，最后一次重试仍失败时把记录标记为失败（不占用导出间隔）
+func (ds *DataExportService) process(ctx context.Context, task DataExportTask) error {
+	var export models.DataExport
+	if err := ds.db.WithContext(ctx).First(&export, "id = ?", task.ExportID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if export.Status != models.DataExportPending && export.Status != models.DataExportRunning {
+		return nil
+	}
+	ds.db.WithContext(ctx).Model(&export).Update("status", models.DataExportRunning)
+
+	key, size, err := ds.buildArchive(ctx, &export)
+	if err != nil {
+		if utils.IsFinalJobAttempt(ctx) {
+			ds.db.WithContext(ctx).Model(&export).Updates(map[string]interface{}{
+				"status": models.DataExportFailed,
+				"error":  err.Error(),
+			})
+		}
+		return fmt.Errorf("export data of %s: %w", export.UserID, err)
+	}
+
+	expiresAt := time.Now().Add(DataExportRetention)
+	if err := ds.db.WithContext(ctx).Model(&export).Updates(map[string]interface{}{
+		"status":     models.DataExportCompleted,
+		"key":        key,
+		"size":       size,
+		"expires_at": expiresAt,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to save export %s: %w", export.ID, err)
+	}
+	return nil
+}
+
+// buildArchive 把用户的数据写入临时zip文件后上传到私有存储，返回存储key和大小
+func (ds *DataExportService) buildArchive(ctx context.Context, export *models.DataExport) (string, int64, error) {
+	tmp, err := os.CreateTemp("", "data-export-*.zip")
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	zw := zip.NewWriter(tmp)
+	if err := ds.writeArchive(ctx, zw, export.UserID); err != nil {
+		return "", 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return "", 0, err
+	}
+
+	info, err := tmp.Stat()
+	if err != nil {
+		return "", 0, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return "", 0, err
+	}
+
+	key := fmt.Sprintf("%s%s/%s-%s.zip", dataExportKeyPrefix, export.UserID, time.Now().Format("20060102-150405"), export.ID)
+	if _, err := utils.GetStorage().Put(ctx, key, tmp, info.Size(), "application/zip"); err != nil {
+		return "", 0, fmt.Errorf("failed to store export: %w", err)
+	}
+	return key, info.Size(), nil
+}
+
+// writeArchive 写入 profile.json、books.json、listings.json、chats.json、uploads.json 和 uploads/ 下的文件
+func (ds *DataExportService) writeArchive(ctx context.Context, zw *zip.Writer, userID string) error {
+	db := ds.db.WithContext(ctx)
+
+	var user models.User
+	if err := db.First(&user, "id = ?", userID).Error; err != nil {
+		return fmt.Errorf("failed to load user: %w", err)
+	}
+	profile := map[string]interface{}{"user": user}
+	var settings models.UserSettings
+	if err := db.Where("user_id = ?", userID).Limit(1).Find(&settings).Error; err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+	if settings.UserID != "" {
+		profile["settings"] = settings
+	}
+	if err := writeArchiveJSON(zw, "profile.json", profile); err != nil {
+		return err
+	}
+
+	// 关联字段为空的结构体，归档中不输出
+	var books []models.Book
+	if err := db.Where("seller_id = ?", userID).Order("created_at").Find(&books).Error; err != nil {
+		return fmt.Errorf("failed to load books: %w", err)
+	}
+	if err := writeArchiveJSON(zw, "books.json", omitAssociations(books, "seller")); err != nil {
+		return err
+	}
+
+	var listings []models.Listing
+	if err := db.Where("seller_id = ? OR buyer_id = ?", userID, userID).Order("created_at").Find(&listings).Error; err != nil {
+		return fmt.Errorf("failed to load listings: %w", err)
+	}
+	if err := writeArchiveJSON(zw, "listings.json", omitAssociations(listings, "book", "seller")); err != nil {
+		return err
+	}
+
+	chats, err := ds.loadChats(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := writeArchiveJSON(zw, "chats.json", chats); err != nil {
+		return err
+	}
+
+	var files []models.UploadedFile
+	if err := db.Where("user_id = ?", userID).Order("created_at").Find(&files).Error; err != nil {
+		return fmt.Errorf("failed to load uploads: %w", err)
+	}
+	uploads := make([]dataExportUpload, len(files))
+	for i, f := range files {
+		uploads[i] = dataExportUpload{UploadedFile: f, Path: dataExportUploadPath(f)}
+		if err := copyUploadToArchive(ctx, zw, uploads[i].Path, f.Key); err != nil {
+			log.Printf("data export: skip upload %s of %s: %v", f.Key, userID, err)
+			uploads[i].Path, uploads[i].Missing = "", true
+		}
+	}
+	return writeArchiveJSON(zw, "uploads.json", uploads)
+}
+
+// loadChats 加载用户参与的聊天及其消息
+func (ds *DataExportService) loadChats(ctx context.Context, userID string) ([]dataExportChat, error) {
+	db := ds.db.WithContext(ctx)
+
+	var chatIDs []string
+	if err := db.Model(&models.ChatUser{}).Where("user_id = ?", userID).Pluck("chat_id", &chatIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to load chats: %w", err)
+	}
+	if len(chatIDs) == 0 {
+		return []dataExportChat{}, nil
+	}
+
+	var chats []models.Chat
+	if err := db.Where("id IN ?", chatIDs).Order("created_at").Find(&chats).Error; err != nil {
+		return nil, fmt.Errorf("failed to load chats: %w", err)
+	}
+	var members []models.ChatUser
+	if err := db.Select("chat_id", "user_id").Where("chat_id IN ?", chatIDs).Find(&members).Error; err != nil {
+		return nil, fmt.Errorf("failed to load chat members: %w", err)
+	}
+	var messages []dataExportMessage
+	if err := db.Model(&models.Message{}).Where("chat_id IN ?", chatIDs).Order("created_at").Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("failed to load messages: %w", err)
+	}
+
+	result := make([]dataExportChat, len(chats))
+	index := make(map[string]int, len(chats))
+	for i, c := range chats {
+		result[i] = dataExportChat{ID: c.ID, CreatedAt: c.CreatedAt, MemberIDs: []string{}, Messages: []dataExportMessage{}}
+		index[c.ID] = i
+	}
+	for _, m := range members {
+		if i, ok := index[m.ChatID]; ok {
+			result[i].MemberIDs = append(result[i].MemberIDs, m.UserID)
+		}
+	}
+	for _, m := range messages {
+		if i, ok := index[m.ChatID]; ok {
+			result[i].Messages = append(result[i].Messages, m)
+		}
+	}
+	return result, nil
+}
+
+// writeArchiveJSON 以缩进JSON写入归档中的一个文件
+func writeArchiveJSON(zw *zip.Writer, name string, v interface{}) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// copyUploadToArchive 把存储中的文件复制到归档的 name 处
+func copyUploadToArchive(ctx context.Context, zw *zip.Writer, name, key string) error {
+	r, err := utils.GetStorage().Open(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// dataExportUploadPath 上传文件在归档中的路径，以文件ID开头避免重名
+func dataExportUploadPath(f models.UploadedFile) string {
+	name := path.Base(f.FileName)
+	if name == "." || name == "/" || name == "" {
+		name = path.Base(f.Key)
+	}
+	return "uploads/" + f.ID + "-" + name
+}
+
+// dataExportWait 距上次申请还需等待的时间，可以再次申请时为0
+func dataExportWait(lastRequestedAt, now time.Time) time.Duration {
+	return max(lastRequestedAt.Add(DataExportInterval).Sub(now), 0)
+}
+
+// omitAssociations 把记录转换为JSON对象并去掉未加载的关联字段
+func omitAssociations[T any](rows []T, fields ...string) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		data, err := json.Marshal(row)
+		if err != nil {
+			continue
+		}
+		var obj map[string]interface{}
+		if json.Unmarshal(data, &obj) != nil {
+			continue
+		}
+		for _, f := range fields {
+			delete(obj, f)
+		}
+		result = append(result, obj)
+	}
+	return result
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"testing"
+	"time"
+	"weoucbookcycle_go/models"
+)
+
+func TestDataExportWait(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if got := dataExportWait(now.Add(-time.Hour), now); got != DataExportInterval-time.Hour {
+		t.Errorf("requested an hour ago: wait %v, want %v", got, DataExportInterval-time.Hour)
+	}
+	if got := dataExportWait(now.Add(-DataExportInterval), now); got != 0 {
+		t.Errorf("requested a full interval ago: wait %v, want 0", got)
+	}
+	if got := dataExportWait(now.Add(-48*time.Hour), now); got != 0 {
+		t.Errorf("requested two days ago: wait %v, want 0", got)
+	}
+}
+
+// 归档中的路径以文件ID开头，文件名不能跳出 uploads/ 目录
+func TestDataExportUploadPath(t *testing.T) {
+	cases := []struct {
+		file models.UploadedFile
+		want string
+	}{
+		{models.UploadedFile{ID: "f1", FileName: "cover.jpg", Key: "images/a.jpg"}, "uploads/f1-cover.jpg"},
+		{models.UploadedFile{ID: "f2", FileName: "../../etc/passwd", Key: "images/b.jpg"}, "uploads/f2-passwd"},
+		{models.UploadedFile{ID: "f3", Key: "private/evidence/c.png"}, "uploads/f3-c.png"},
+	}
+	for _, tc := range cases {
+		if got := dataExportUploadPath(tc.file); got != tc.want {
+			t.Errorf("dataExportUploadPath(%+v) = %q, want %q", tc.file, got, tc.want)
+		}
+	}
+}
+
+func TestOmitAssociations(t *testing.T) {
+	rows := omitAssociations([]models.Listing{{ID: "l1", SellerID: "u1", Status: "sold"}}, "book", "seller")
+	if len(rows) != 1 {
+		t.Fatalf("expected 1 row, got %d", len(rows))
+	}
+	if _, ok := rows[0]["book"]; ok {
+		t.Error("book association should be removed")
+	}
+	if _, ok := rows[0]["seller"]; ok {
+		t.Error("seller association should be removed")
+	}
+	if rows[0]["id"] != "l1" || rows[0]["status"] != "sold" {
+		t.Errorf("unexpected row %v", rows[0])
+	}
+}
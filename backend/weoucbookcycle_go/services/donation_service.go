@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// donationClaimLimit 每个用户同时最多认领几本尚未交接的赠书
+const donationClaimLimit = 3
+
+var (
+	ErrNotDonation     = utils.NewError(http.StatusBadRequest, "this listing is not a donation")
+	ErrOwnDonation     = utils.NewError(http.StatusBadRequest, "you cannot claim your own donation")
+	ErrDonationClaimed = utils.NewError(http.StatusConflict, "this book has already been claimed")
+	ErrTooManyClaims   = utils.NewError(http.StatusConflict, "you have too many books waiting to be picked up")
+	ErrNotClaimant     = utils.NewError(http.StatusForbidden, "only the donor or the claimant can cancel this claim")
+	ErrNoClaim         = utils.NewError(http.StatusConflict, "this book has not been claimed")
+)
+
+// DonationStats 用户的赠书统计
+type DonationStats struct {
+	Donated   int64 `json:"donated"`   // 已送出
+	Giving    int64 `json:"giving"`    // 赠送中（可认领或已被认领待交接）
+	Received  int64 `json:"received"`  // 已领到
+	Claimed   int64 `json:"claimed"`   // 已认领待交接
+	Available int64 `json:"available"` // 赠送中且还没有人认领
+}
+
+// DonationService 赠书：免费送出的发布（Listing.IsDonation）
+// 认领后发布变为 reserved 并记录认领人为买家，赠送人当面交接后把状态改为 sold 即完成，不涉及付款
+type DonationService struct {
+	db                  *gorm.DB
+	redisClient         *redis.Client
+	notificationService *NotificationService
+}
+
+// NewDonationService 创建赠书服务实例
+func NewDonationService(deps Deps, notificationService *NotificationService) *DonationService {
+	return &DonationService{
+		db:                  deps.DB,
+		redisClient:         deps.Redis,
+		notificationService: notificationService,
+	}
+}
+
+// Claim 认领赠书，先到先得；同时待交接的认领不能超过 donationClaimLimit
+func (ds *DonationService) Claim(ctx context.Context, userID, listingID string) (*models.Listing, error) {
+	var listing models.Listing
+	err := WithTx(ctx, ds.db, func(ctx context.Context, tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Book").First(&listing, "id = ?", listingID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return utils.NewError(http.StatusNotFound, "Listing not found")
+			}
+			return fmt.Errorf("failed to load listing: %w", err)
+		}
+		switch {
+		case !listing.IsDonation:
+			return ErrNotDonation
+		case listing.SellerID == userID:
+			return ErrOwnDonation
+		case listing.Status != "available":
+			return ErrDonationClaimed
+		}
+
+		var claims int64
+		if err := tx.Model(&models.Listing{}).
+			Where("buyer_id = ? AND is_donation = ? AND status = ?", userID, true, "reserved").
+			Count(&claims).Error; err != nil {
+			return fmt.Errorf("failed to count claims: %w", err)
+		}
+		if claims >= donationClaimLimit {
+			return ErrTooManyClaims
+		}
+
+		if err := tx.Model(&listing).Updates(map[string]interface{}{"status": "reserved", "buyer_id": userID}).Error; err != nil {
+			return fmt.Errorf("failed to claim listing: %w", err)
+		}
+		listing.Status, listing.BuyerID = "reserved", userID
+
+		AfterCommit(ctx, func() {
+			ds.invalidate(ctx, &listing)
+			ds.notifyClaimed(ctx, &listing, userID)
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &listing, nil
+}
+
+// CancelClaim 取消认领，赠送人或认领人都可以取消，取消后赠书重新可认领
+func (ds *DonationService) CancelClaim(ctx context.Context, userID, listingID string) (*models.Listing, error) {
+	var listing models.Listing
+	err := WithTx(ctx, ds.db, func(ctx context.Context, tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&listing, "id = ?", listingID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return utils.NewError(http.StatusNotFound, "Listing not found")
+			}
+			return fmt.Errorf("failed to load listing: %w", err)
+		}
+		if !listing.IsDonation {
+			return ErrNotDonation
+		}
+		if listing.Status != "reserved" || listing.BuyerID == "" {
+			return ErrNoClaim
+		}
+		if userID != listing.SellerID && userID != listing.BuyerID {
+			return ErrNotClaimant
+		}
+
+		if err := tx.Model(&listing).Updates(map[string]interface{}{"status": "available", "buyer_id": ""}).Error; err != nil {
+			return fmt.Errorf("failed to cancel claim: %w", err)
+		}
+		listing.Status, listing.BuyerID = "available", ""
+
+		AfterCommit(ctx, func() {
+			ds.invalidate(ctx, &listing)
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &listing, nil
+}
+
+// Stats 获取用户的赠书统计
+func (ds *DonationService) Stats(ctx context.Context, userID string) (*DonationStats, error) {
+	stats := &DonationStats{}
+	db := ds.db.WithContext(ctx)
+
+	if err := db.Model(&models.Listing{}).
+		Select(`COUNT(CASE WHEN status = 'sold' THEN 1 END) AS donated,
+			COUNT(CASE WHEN status IN ('available', 'reserved') THEN 1 END) AS giving,
+			COUNT(CASE WHEN status = 'available' THEN 1 END) AS available`).
+		Where("seller_id = ? AND is_donation = ?", userID, true).
+		Scan(stats).Error; err != nil {
+		return nil, fmt.Errorf("failed to aggregate donations: %w", err)
+	}
+
+	var received struct {
+		Received int64
+		Claimed  int64
+	}
+	if err := db.Model(&models.Listing{}).
+		Select(`COUNT(CASE WHEN status = 'sold' THEN 1 END) AS received,
+			COUNT(CASE WHEN status = 'reserved' THEN 1 END) AS claimed`).
+		Where("buyer_id = ? AND is_donation = ?", userID, true).
+		Scan(&received).Error; err != nil {
+		return nil, fmt.Errorf("failed to aggregate received donations: %w", err)
+	}
+	stats.Received, stats.Claimed = received.Received, received.Claimed
+	return stats, nil
+}
+
+// invalidate 清除发布和赠送人个人统计的缓存
+func (ds *DonationService) invalidate(ctx context.Context, listing *models.Listing) {
+	if ds.redisClient != nil {
+		ds.redisClient.Del(context.WithoutCancel(ctx), "listing:"+listing.ID, DashboardCacheKey(listing.SellerID))
+	}
+}
+
+// notifyClaimed 通知赠送人书已被认领
+func (ds *DonationService) notifyClaimed(ctx context.Context, listing *models.Listing, claimantID string) {
+	var claimant models.User
+	ds.db.Select("id", "username").First(&claimant, "id = ?", claimantID)
+
+	lang := UserLanguage(ds.db, listing.SellerID)
+	_, err := ds.notificationService.Notify(ctx, listing.SellerID, "donation_claimed", utils.T(lang, "notification.donation_claimed.title"),
+		utils.T(lang, "notification.donation_claimed.content", claimant.Username, listing.Book.Title),
+		map[string]interface{}{"listing_id": listing.ID, "claimant_id": claimantID})
+	if err != nil && !errors.Is(err, ErrNotificationRateLimited) && !errors.Is(err, ErrRecipientDeactivated) {
+		log.Printf("donation: failed to notify donor of %s: %v", listing.ID, err)
+	}
+}
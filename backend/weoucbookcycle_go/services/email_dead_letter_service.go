@@ -0,0 +1,168 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrDeadLetterNotFound 死信邮件不存在
+	ErrDeadLetterNotFound = utils.NewError(http.StatusNotFound, "dead letter not found")
+	// ErrDeadLetterClosed 已重试成功或已放弃的邮件不能再处理
+	ErrDeadLetterClosed = utils.NewError(http.StatusConflict, "dead letter is already closed")
+)
+
+// recordEmailDeadLetter 保存发送失败的邮件
+func recordEmailDeadLetter(db *gorm.DB, task *EmailTask, sendErr error) {
+	if db == nil {
+		return
+	}
+
+	queuedAt := task.Timestamp
+	if queuedAt.IsZero() {
+		queuedAt = time.Now()
+	}
+	letter := models.EmailDeadLetter{
+		Type:     task.Type,
+		ToEmail:  task.ToEmail,
+		Subject:  task.Subject,
+		Body:     task.Body,
+		HTMLBody: task.HTMLBody,
+		Error:    sendErr.Error(),
+		Attempts: task.Retries,
+		Status:   models.EmailDeadLetterFailed,
+		QueuedAt: queuedAt,
+	}
+	if err := db.Create(&letter).Error; err != nil {
+		log.Printf("failed to save email dead letter for %s: %v", task.ToEmail, err)
+	}
+}
+
+// EmailDeadLetterService 死信邮件服务
+type EmailDeadLetterService struct {
+	db          *gorm.DB
+	emailConfig *EmailConfig
+}
+
+// NewEmailDeadLetterService 创建死信邮件服务实例，重试发送使用与认证服务相同的SMTP配置
+func NewEmailDeadLetterService(deps Deps) *EmailDeadLetterService {
+	return &EmailDeadLetterService{
+		db:          deps.DB,
+		emailConfig: newEmailConfig(deps.Config),
+	}
+}
+
+// EmailDeadLetterQuery 死信查询条件
+type EmailDeadLetterQuery struct {
+	Status  string
+	Type    string
+	ToEmail string
+	Page    int
+	Limit   int
+}
+
+// List 分页查询死信邮件，列表不返回正文
+func (ds *EmailDeadLetterService) List(q *EmailDeadLetterQuery) ([]models.EmailDeadLetter, int64, error) {
+	query := ds.db.Model(&models.EmailDeadLetter{})
+	if q.Status != "" {
+		query = query.Where("status = ?", q.Status)
+	}
+	if q.Type != "" {
+		query = query.Where("type = ?", q.Type)
+	}
+	if q.ToEmail != "" {
+		query = query.Where("to_email = ?", q.ToEmail)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var letters []models.EmailDeadLetter
+	if err := query.Omit("body", "html_body").
+		Order("created_at DESC").
+		Offset((q.Page - 1) * q.Limit).Limit(q.Limit).
+		Find(&letters).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+	return letters, total, nil
+}
+
+// Get 获取死信邮件详情（含正文）
+func (ds *EmailDeadLetterService) Get(id string) (*models.EmailDeadLetter, error) {
+	var letter models.EmailDeadLetter
+	if err := ds.db.First(&letter, "id = ?", id).Error; err != nil {
+		return nil, ErrDeadLetterNotFound
+	}
+	return &letter, nil
+}
+
+// Retry 立即重新发送，成功后标记为 retried，失败时记录最新错误
+func (ds *EmailDeadLetterService) Retry(adminID, id string) (*models.EmailDeadLetter, error) {
+	letter, err := ds.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if letter.Status != models.EmailDeadLetterFailed {
+		return nil, ErrDeadLetterClosed
+	}
+
+	sendErr := ds.emailConfig.send(&EmailTask{
+		Type:     letter.Type,
+		ToEmail:  letter.ToEmail,
+		Subject:  letter.Subject,
+		Body:     letter.Body,
+		HTMLBody: letter.HTMLBody,
+	})
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"attempts":      letter.Attempts + 1,
+		"last_retry_at": now,
+		"retried_by":    adminID,
+	}
+	if sendErr != nil {
+		updates["error"] = sendErr.Error()
+		utils.EmailsTotal.WithLabelValues(letter.Type, "failed").Inc()
+	} else {
+		updates["status"] = models.EmailDeadLetterRetried
+		utils.EmailsTotal.WithLabelValues(letter.Type, "sent").Inc()
+	}
+	if err := ds.db.Model(letter).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update dead letter: %w", err)
+	}
+
+	letter, err = ds.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if sendErr != nil {
+		return letter, sendErr
+	}
+	return letter, nil
+}
+
+// Discard 放弃重试
+func (ds *EmailDeadLetterService) Discard(adminID, id string) error {
+	result := ds.db.Model(&models.EmailDeadLetter{}).
+		Where("id = ? AND status = ?", id, models.EmailDeadLetterFailed).
+		Updates(map[string]interface{}{
+			"status":     models.EmailDeadLetterDiscarded,
+			"retried_by": adminID,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to discard dead letter: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		if _, err := ds.Get(id); err != nil {
+			return err
+		}
+		return ErrDeadLetterClosed
+	}
+	return nil
+}
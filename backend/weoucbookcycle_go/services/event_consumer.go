@@ -0,0 +1,262 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 事件消费的时间参数，测试中会调小
+var (
+	// eventConsumerBlock 每次读取事件流的最长等待时间，也是检查待重试消息的最长间隔
+	eventConsumerBlock = 5 * time.Second
+	// eventRetryDelay 未确认的消息空闲超过该时间后重新投递（处理失败的消息，或已退出的消费者留下的消息）
+	eventRetryDelay = time.Minute
+	// eventFailureBackoff 处理失败后暂停读取的时间，避免故障期间持续访问Redis和数据库
+	eventFailureBackoff = time.Second
+)
+
+const (
+	// eventMaxDeliveries 一条消息最多处理的次数，仍然失败时写入死信流并确认
+	eventMaxDeliveries = 5
+	// eventDeadLetterMaxLen 每个死信流保留的消息数
+	eventDeadLetterMaxLen = 10000
+)
+
+// eventHandler 处理一条领域事件，返回错误时不确认，空闲 eventRetryDelay 后重新投递
+type eventHandler func(ctx context.Context, stream string, msg redis.XMessage) error
+
+// eventBatchHandler 处理同一个流中的一批事件，返回错误时整批不确认
+type eventBatchHandler func(ctx context.Context, stream string, msgs []redis.XMessage) error
+
+// deadLetterStream 消费组 group 处理 stream 时放弃的消息写入的流
+func deadLetterStream(stream, group string) string {
+	return fmt.Sprintf("%s:dead:%s", stream, group)
+}
+
+// startEventConsumer 以消费组 group 消费 streams 中的领域事件
+// 消费组不存在时从流的开头建立，因此 handle 需要是幂等的
+// 处理失败的消息空闲 eventRetryDelay 后重试，最多处理 eventMaxDeliveries 次，之后写入死信流（见 deadLetterStream）
+func startEventConsumer(ctx context.Context, redisClient *redis.Client, group string, streams []string, handle eventHandler) {
+	startEventConsumerFrom(ctx, redisClient, group, streams, "0", handle)
+}
+
+// startEventConsumerFrom 与 startEventConsumer 相同，但消费组不存在时从 from 建立，"$" 表示只消费之后写入的事件
+func startEventConsumerFrom(ctx context.Context, redisClient *redis.Client, group string, streams []string, from string, handle eventHandler) {
+	consumeEvents(ctx, redisClient, group, streams, from, 50, func(stream string, msgs []redis.XMessage) bool {
+		ok := true
+		for _, msg := range msgs {
+			if err := handle(ctx, stream, msg); err != nil {
+				log.Printf("%s: %s %s failed: %v", group, stream, msg.ID, err)
+				ok = false
+				continue
+			}
+			redisClient.XAck(ctx, stream, group, msg.ID)
+		}
+		return ok
+	})
+}
+
+// startBatchEventConsumer 与 startEventConsumer 相同，但按批处理，适合写入量大的流
+func startBatchEventConsumer(ctx context.Context, redisClient *redis.Client, group string, streams []string, count int64, handle eventBatchHandler) {
+	consumeEvents(ctx, redisClient, group, streams, "0", count, func(stream string, msgs []redis.XMessage) bool {
+		if err := handle(ctx, stream, msgs); err != nil {
+			log.Printf("%s: %d events from %s failed: %v", group, len(msgs), stream, err)
+			return false
+		}
+		ids := make([]string, len(msgs))
+		for i, msg := range msgs {
+			ids[i] = msg.ID
+		}
+		redisClient.XAck(ctx, stream, group, ids...)
+		return true
+	})
+}
+
+// consumeEvents 建立消费组并在后台循环读取，每个流读到的消息交给 process，process 有消息处理失败时返回 false
+// 新消息用 XREADGROUP > 读取；未确认的消息（包括已退出的消费者留下的）由 XAUTOCLAIM 在空闲 eventRetryDelay 后接管重试
+func consumeEvents(ctx context.Context, redisClient *redis.Client, group string, streams []string, from string, count int64, process func(stream string, msgs []redis.XMessage) bool) {
+	if redisClient == nil {
+		return
+	}
+
+	for _, stream := range streams {
+		err := redisClient.XGroupCreateMkStream(ctx, stream, group, from).Err()
+		if err != nil && !strings.Contains(err.Error(), "BUSYGROUP") {
+			log.Printf("%s: failed to create consumer group on %s: %v", group, stream, err)
+			return
+		}
+	}
+
+	hostname, _ := os.Hostname()
+	c := &eventConsumer{
+		redisClient: redisClient,
+		group:       group,
+		consumer:    fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		streams:     streams,
+		count:       count,
+		process:     process,
+	}
+	go c.run(ctx)
+}
+
+// eventConsumer 一个消费组在本进程中的消费者
+type eventConsumer struct {
+	redisClient *redis.Client
+	group       string
+	consumer    string
+	streams     []string
+	count       int64
+	process     func(stream string, msgs []redis.XMessage) bool
+}
+
+// run 读取循环，ctx 取消后退出
+func (c *eventConsumer) run(ctx context.Context) {
+	var lastClaim time.Time
+	for ctx.Err() == nil {
+		ok := true
+		if time.Since(lastClaim) >= eventConsumerBlock {
+			ok = c.retryPending(ctx)
+			lastClaim = time.Now()
+		}
+		if !c.readNew(ctx) {
+			ok = false
+		}
+		if !ok {
+			sleepContext(ctx, eventFailureBackoff)
+		}
+	}
+}
+
+// readNew 读取并处理新消息
+func (c *eventConsumer) readNew(ctx context.Context) bool {
+	args := make([]string, 0, len(c.streams)*2)
+	args = append(args, c.streams...)
+	for range c.streams {
+		args = append(args, ">")
+	}
+
+	result, err := c.redisClient.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    c.group,
+		Consumer: c.consumer,
+		Streams:  args,
+		Count:    c.count,
+		Block:    eventConsumerBlock,
+	}).Result()
+	if err != nil {
+		if err != redis.Nil && ctx.Err() == nil {
+			log.Printf("%s: read failed: %v", c.group, err)
+			return false
+		}
+		return true
+	}
+
+	ok := true
+	for _, stream := range result {
+		if len(stream.Messages) > 0 && !c.process(stream.Stream, stream.Messages) {
+			ok = false
+		}
+	}
+	return ok
+}
+
+// retryPending 接管空闲超过 eventRetryDelay 的未确认消息并重新处理
+// 已处理 eventMaxDeliveries 次的消息写入死信流并确认，不再重试
+func (c *eventConsumer) retryPending(ctx context.Context) bool {
+	ok := true
+	for _, stream := range c.streams {
+		start := "0-0"
+		for {
+			msgs, next, err := c.redisClient.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+				Stream:   stream,
+				Group:    c.group,
+				Consumer: c.consumer,
+				MinIdle:  eventRetryDelay,
+				Start:    start,
+				Count:    c.count,
+			}).Result()
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("%s: failed to claim pending events on %s: %v", c.group, stream, err)
+				}
+				return false
+			}
+
+			if retry := c.dropExhausted(ctx, stream, msgs); len(retry) > 0 && !c.process(stream, retry) {
+				ok = false
+			}
+			if next == "0-0" || next == "" || next == start {
+				break
+			}
+			start = next
+		}
+	}
+	return ok
+}
+
+// dropExhausted 把投递次数已超过上限的消息写入死信流并确认，返回其余需要重试的消息
+func (c *eventConsumer) dropExhausted(ctx context.Context, stream string, msgs []redis.XMessage) []redis.XMessage {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	pending, err := c.redisClient.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: stream,
+		Group:  c.group,
+		Start:  msgs[0].ID,
+		End:    msgs[len(msgs)-1].ID,
+		Count:  int64(len(msgs)) * 2,
+	}).Result()
+	if err != nil {
+		return msgs
+	}
+	deliveries := make(map[string]int64, len(pending))
+	for _, p := range pending {
+		deliveries[p.ID] = p.RetryCount
+	}
+
+	retry := msgs[:0]
+	for _, msg := range msgs {
+		// XAUTOCLAIM 已把本次接管计入投递次数，之前实际处理了 attempts 次
+		attempts := deliveries[msg.ID] - 1
+		if attempts < eventMaxDeliveries {
+			retry = append(retry, msg)
+			continue
+		}
+
+		values := make(map[string]interface{}, len(msg.Values)+2)
+		for k, v := range msg.Values {
+			values[k] = v
+		}
+		values["dead_source_id"] = msg.ID
+		values["dead_attempts"] = attempts
+		err := c.redisClient.XAdd(ctx, &redis.XAddArgs{
+			Stream: deadLetterStream(stream, c.group),
+			MaxLen: eventDeadLetterMaxLen,
+			Approx: true,
+			Values: values,
+		}).Err()
+		if err != nil {
+			log.Printf("%s: failed to dead-letter %s %s: %v", c.group, stream, msg.ID, err)
+			continue
+		}
+		log.Printf("%s: giving up on %s %s after %d attempts", c.group, stream, msg.ID, attempts)
+		c.redisClient.XAck(ctx, stream, c.group, msg.ID)
+	}
+	return retry
+}
+
+// sleepContext 等待 d，ctx 取消时提前返回
+func sleepContext(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestRedis 启动进程内的Redis，测试结束后关闭
+func newTestRedis(t *testing.T) *redis.Client {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// fastEventRetries 缩短事件重试的等待时间，测试结束后恢复
+func fastEventRetries(t *testing.T) {
+	t.Helper()
+	block, delay, backoff := eventConsumerBlock, eventRetryDelay, eventFailureBackoff
+	eventConsumerBlock, eventRetryDelay, eventFailureBackoff = 20*time.Millisecond, 10*time.Millisecond, 5*time.Millisecond
+	t.Cleanup(func() { eventConsumerBlock, eventRetryDelay, eventFailureBackoff = block, delay, backoff })
+}
+
+// 一直处理失败的事件不阻塞之后的事件，重试次数用完后进入死信流
+func TestEventConsumerSkipsFailingEvent(t *testing.T) {
+	fastEventRetries(t)
+	rdb := newTestRedis(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const stream = "test_events"
+	rdb.XAdd(ctx, &redis.XAddArgs{Stream: stream, Values: map[string]interface{}{"name": "bad"}})
+
+	var mu sync.Mutex
+	attempts := 0
+	handled := make(chan string, 10)
+	startEventConsumer(ctx, rdb, "test_group", []string{stream}, func(ctx context.Context, stream string, msg redis.XMessage) error {
+		if streamString(msg.Values["name"]) == "bad" {
+			mu.Lock()
+			attempts++
+			mu.Unlock()
+			return errors.New("always fails")
+		}
+		handled <- streamString(msg.Values["name"])
+		return nil
+	})
+
+	rdb.XAdd(ctx, &redis.XAddArgs{Stream: stream, Values: map[string]interface{}{"name": "good"}})
+	select {
+	case name := <-handled:
+		if name != "good" {
+			t.Fatalf("unexpected event handled: %s", name)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("new event was not delivered while another event keeps failing")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		dead, _ := rdb.XRange(ctx, deadLetterStream(stream, "test_group"), "-", "+").Result()
+		if len(dead) == 1 {
+			if streamString(dead[0].Values["name"]) != "bad" {
+				t.Fatalf("unexpected dead letter: %+v", dead[0].Values)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("failing event was not moved to the dead-letter stream")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	pending, _ := rdb.XPending(ctx, stream, "test_group").Result()
+	if pending.Count != 0 {
+		t.Fatalf("expected no pending events, got %d", pending.Count)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != eventMaxDeliveries {
+		t.Fatalf("expected %d attempts, got %d", eventMaxDeliveries, attempts)
+	}
+}
@@ -0,0 +1,369 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	// ExportURLTTL 导出文件下载链接的有效期
+	ExportURLTTL = 15 * time.Minute
+	// exportKeyPrefix 导出文件存放在私有目录，只能通过签名URL下载
+	exportKeyPrefix = utils.PrivatePrefix + "exports/"
+)
+
+var (
+	// ErrExportNotReady 导出任务未完成
+	ErrExportNotReady = utils.NewError(http.StatusConflict, "export is not ready")
+)
+
+// ExportRequest 导出请求
+// Orders 为已成交的发布（含买家和卖家）
+type ExportRequest struct {
+	Type   string `json:"type" binding:"required,oneof=users books listings orders"`
+	Format string `json:"format" binding:"omitempty,oneof=csv json"`
+	Status string `json:"status"`
+	UserID string `json:"user_id"`
+	From   string `json:"from"` // YYYY-MM-DD，按创建时间（订单按成交时间）筛选
+	To     string `json:"to"`   // YYYY-MM-DD，包含当天
+}
+
+// exportDataset 可导出的数据集
+type exportDataset struct {
+	// timeColumn 日期筛选使用的列
+	timeColumn string
+	// userColumn user_id 筛选使用的列
+	userColumn string
+	query      func(db *gorm.DB) *gorm.DB
+}
+
+// exportDatasets 导出类型 -> 数据集，只选择可以导出的列（不含密码等敏感字段）
+var exportDatasets = map[string]exportDataset{
+	"users": {
+		timeColumn: "created_at",
+		userColumn: "id",
+		query: func(db *gorm.DB) *gorm.DB {
+			return db.Model(&models.User{}).Select("id", "username", "email", "phone", "status", "role",
+				"email_verified", "trust_score", "login_count", "last_login", "created_at")
+		},
+	},
+	"books": {
+		timeColumn: "created_at",
+		userColumn: "seller_id",
+		query: func(db *gorm.DB) *gorm.DB {
+			return db.Model(&models.Book{}).Select("id", "title", "author", "isbn", "category", "price",
+				"`condition`", "status", "seller_id", "view_count", "like_count", "created_at")
+		},
+	},
+	"listings": {
+		timeColumn: "created_at",
+		userColumn: "seller_id",
+		query: func(db *gorm.DB) *gorm.DB {
+			return db.Model(&models.Listing{}).Select("id", "book_id", "seller_id", "buyer_id", "price",
+				"status", "favorite_count", "created_at", "updated_at")
+		},
+	},
+	"orders": {
+		timeColumn: "listings.updated_at",
+		userColumn: "listings.seller_id",
+		query: func(db *gorm.DB) *gorm.DB {
+			return db.Table("listings").
+				Select("listings.id AS order_id, books.title AS book_title, books.isbn AS isbn, listings.price AS price, "+
+					"listings.seller_id AS seller_id, seller.username AS seller_name, "+
+					"listings.buyer_id AS buyer_id, buyer.username AS buyer_name, listings.updated_at AS sold_at").
+				Joins("LEFT JOIN books ON books.id = listings.book_id").
+				Joins("LEFT JOIN users seller ON seller.id = listings.seller_id").
+				Joins("LEFT JOIN users buyer ON buyer.id = listings.buyer_id").
+				Where("listings.status = ? AND listings.deleted_at IS NULL", "sold")
+		},
+	},
+}
+
+// ExportService 管理后台数据导出服务
+type ExportService struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+}
+
+// NewExportService 创建导出服务实例
+func NewExportService(deps Deps) *ExportService {
+	return &ExportService{
+		db:          deps.DB,
+		redisClient: deps.Redis,
+	}
+}
+
+// StartExport 创建异步导出任务，返回任务ID（通过 /api/tasks/{id} 查询进度）
+func (es *ExportService) StartExport(ctx context.Context, adminID string, req *ExportRequest) (string, error) {
+	dataset, ok := exportDatasets[req.Type]
+	if !ok {
+		return "", fmt.Errorf("unsupported export type: %s", req.Type)
+	}
+	if req.Format == "" {
+		req.Format = "csv"
+	}
+
+	query := dataset.query(es.db.WithContext(ctx))
+	if req.Status != "" && req.Type != "orders" {
+		query = query.Where("status = ?", req.Status)
+	}
+	if req.UserID != "" {
+		query = query.Where(dataset.userColumn+" = ?", req.UserID)
+	}
+	if req.From != "" {
+		from, err := time.ParseInLocation("2006-01-02", req.From, time.Local)
+		if err != nil {
+			return "", errors.New("invalid from date, expected YYYY-MM-DD")
+		}
+		query = query.Where(dataset.timeColumn+" >= ?", from)
+	}
+	if req.To != "" {
+		to, err := time.ParseInLocation("2006-01-02", req.To, time.Local)
+		if err != nil {
+			return "", errors.New("invalid to date, expected YYYY-MM-DD")
+		}
+		query = query.Where(dataset.timeColumn+" < ?", to.AddDate(0, 0, 1))
+	}
+
+	taskID := utils.CreateTask(es.redisClient, adminID, "running")
+	if es.redisClient != nil {
+		es.redisClient.HSet(ctx, "task:"+taskID, "type", "export_"+req.Type)
+	}
+
+	utils.Go(ctx, "export", func(ctx context.Context) error {
+		startTime := time.Now()
+		result, err := es.runExport(taskID, req, query.WithContext(ctx))
+		utils.FinishTask(es.redisClient, taskID, startTime, err, result)
+		if err != nil {
+			return fmt.Errorf("export %s (%s): %w", taskID, req.Type, err)
+		}
+		return nil
+	})
+
+	return taskID, nil
+}
+
+// runExport 把查询结果写入临时文件后上传到私有存储
+func (es *ExportService) runExport(taskID string, req *ExportRequest, query *gorm.DB) (map[string]interface{}, error) {
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count rows: %w", err)
+	}
+
+	tmp, err := os.CreateTemp("", "export-*."+req.Format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	rows, err := query.Rows()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rows: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	w := bufio.NewWriter(tmp)
+	writer := newExportWriter(req.Format, w, columns)
+	if err := writer.begin(); err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	var count int64
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		record := make([]string, len(values))
+		for i, v := range values {
+			record[i] = exportValue(v)
+		}
+		if err := writer.write(record); err != nil {
+			return nil, err
+		}
+
+		count++
+		if count%500 == 0 {
+			utils.UpdateTaskProgress(es.redisClient, taskID, count, total)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := writer.end(); err != nil {
+		return nil, err
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	utils.UpdateTaskProgress(es.redisClient, taskID, count, max(total, count))
+
+	info, err := tmp.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	fileName := fmt.Sprintf("%s-%s.%s", req.Type, time.Now().Format("20060102-150405"), req.Format)
+	key := exportKeyPrefix + taskID + "/" + fileName
+	contentType := "text/csv; charset=utf-8"
+	if req.Format == "json" {
+		contentType = "application/json"
+	}
+	if _, err := utils.GetStorage().Put(context.Background(), key, tmp, info.Size(), contentType); err != nil {
+		return nil, fmt.Errorf("failed to store export: %w", err)
+	}
+
+	return map[string]interface{}{
+		"export_key": key,
+		"file_name":  fileName,
+		"rows":       count,
+		"size":       info.Size(),
+	}, nil
+}
+
+// DownloadURL 返回已完成导出任务的临时下载链接
+func (es *ExportService) DownloadURL(ctx context.Context, taskID string) (string, error) {
+	status, err := utils.CheckTaskStatus(es.redisClient, taskID)
+	if err != nil {
+		return "", ErrAdminTargetNotFound
+	}
+	key := status["export_key"]
+	if status["status"] != "completed" || key == "" {
+		return "", ErrExportNotReady
+	}
+
+	return utils.GetStorage().SignedURL(ctx, key, ExportURLTTL)
+}
+
+// exportValue 把数据库值转换为导出文本
+func exportValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(val)
+	case time.Time:
+		return val.Format(time.RFC3339)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// ==================== 导出格式 ====================
+
+// exportWriter 导出文件写入器
+type exportWriter interface {
+	begin() error
+	write(record []string) error
+	end() error
+}
+
+// newExportWriter 按格式创建写入器
+func newExportWriter(format string, w io.Writer, columns []string) exportWriter {
+	if format == "json" {
+		return &jsonExportWriter{w: w, columns: columns}
+	}
+	return &csvExportWriter{w: csv.NewWriter(w), raw: w, columns: columns}
+}
+
+// csvExportWriter CSV格式，写入BOM以便Excel正确识别UTF-8
+type csvExportWriter struct {
+	w       *csv.Writer
+	raw     io.Writer
+	columns []string
+}
+
+func (cw *csvExportWriter) begin() error {
+	if _, err := cw.raw.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return err
+	}
+	return cw.w.Write(cw.columns)
+}
+
+func (cw *csvExportWriter) write(record []string) error {
+	safe := make([]string, len(record))
+	for i, cell := range record {
+		safe[i] = csvSafeCell(cell)
+	}
+	return cw.w.Write(safe)
+}
+
+// csvSafeCell 以公式字符开头的单元格前加单引号，防止在Excel/WPS中打开时被当作公式执行
+func csvSafeCell(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+func (cw *csvExportWriter) end() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// jsonExportWriter JSON数组格式，逐行写入避免占用大量内存
+type jsonExportWriter struct {
+	w       io.Writer
+	columns []string
+	count   int
+}
+
+func (jw *jsonExportWriter) begin() error {
+	_, err := io.WriteString(jw.w, "[\n")
+	return err
+}
+
+func (jw *jsonExportWriter) write(record []string) error {
+	obj := make(map[string]string, len(record))
+	for i, col := range jw.columns {
+		obj[col] = record[i]
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	if jw.count > 0 {
+		if _, err := io.WriteString(jw.w, ",\n"); err != nil {
+			return err
+		}
+	}
+	jw.count++
+	_, err = jw.w.Write(data)
+	return err
+}
+
+func (jw *jsonExportWriter) end() error {
+	_, err := io.WriteString(jw.w, "\n]\n")
+	return err
+}
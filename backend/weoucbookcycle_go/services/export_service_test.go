@@ -0,0 +1,23 @@
+package services
+
+import "testing"
+
+func TestCSVSafeCell(t *testing.T) {
+	cases := map[string]string{
+		"":                    "",
+		"Go语言":                "Go语言",
+		"=HYPERLINK(\"x\")":   "'=HYPERLINK(\"x\")",
+		"+1":                  "'+1",
+		"-2+3":                "'-2+3",
+		"@SUM(A1)":            "'@SUM(A1)",
+		"\tcmd":               "'\tcmd",
+		"\rcmd":               "'\rcmd",
+		"a=b":                 "a=b",
+		"2024-01-01 10:00:00": "2024-01-01 10:00:00",
+	}
+	for in, want := range cases {
+		if got := csvSafeCell(in); got != want {
+			t.Errorf("csvSafeCell(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
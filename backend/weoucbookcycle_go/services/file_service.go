@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"gorm.io/gorm"
+)
+
+// FileService 上传文件访问服务
+type FileService struct {
+	db      *gorm.DB
+	storage utils.Storage
+}
+
+// NewFileService 创建文件服务实例
+func NewFileService(deps Deps) *FileService {
+	return &FileService{
+		db:      deps.DB,
+		storage: utils.GetStorage(),
+	}
+}
+
+// FileDownload 文件下载地址
+type FileDownload struct {
+	ID          string `json:"id"`
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	ExpiresAt   int64  `json:"expires_at,omitempty"`
+}
+
+// GetDownloadURL 获取文件下载地址
+// 公开文件直接返回URL；私有文件只有上传者和管理员可以访问，返回签名URL
+func (fs *FileService) GetDownloadURL(ctx context.Context, userID string, isAdmin bool, fileID string) (*FileDownload, error) {
+	var file models.UploadedFile
+	if err := fs.db.First(&file, "id = ?", fileID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFileNotFound
+		}
+		return nil, err
+	}
+
+	download := &FileDownload{ID: file.ID, URL: file.URL, ContentType: file.ContentType}
+	if file.Visibility != models.FileVisibilityPrivate {
+		return download, nil
+	}
+
+	// 无权限时与不存在返回相同错误，避免泄露文件是否存在
+	if file.UserID != userID && !isAdmin {
+		return nil, ErrFileNotFound
+	}
+
+	url, err := fs.storage.SignedURL(ctx, file.Key, utils.PrivateURLTTL)
+	if err != nil {
+		return nil, err
+	}
+	download.URL = url
+	download.ExpiresAt = time.Now().Add(utils.PrivateURLTTL).Unix()
+	return download, nil
+}
@@ -0,0 +1,355 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	JobFeedFanout   = "feed:fanout"
+	JobFeedBackfill = "feed:backfill"
+)
+
+const (
+	// feedFanoutBatch 每批写入的关注者数量
+	feedFanoutBatch = 500
+	// feedBackfillLimit 新关注时把对方最近发布的书籍和上架各复制多少条到自己的动态
+	feedBackfillLimit = 20
+)
+
+var (
+	ErrCannotFollowSelf = utils.NewError(http.StatusBadRequest, "you cannot follow yourself")
+	ErrUserNotFound     = utils.NewError(http.StatusNotFound, "user not found")
+)
+
+// FeedFanoutTask 把一条新发布的书籍或上架写入发布者所有关注者的动态
+type FeedFanoutTask struct {
+	ActorID   string
+	Type      string // models.FeedTypeBook / models.FeedTypeListing
+	TargetID  string
+	CreatedAt time.Time
+}
+
+// FeedBackfillTask 新关注后把被关注者最近的发布补到关注者的动态
+type FeedBackfillTask struct {
+	FollowerID string
+	FolloweeID string
+}
+
+// FollowService 关注关系和关注动态
+// 动态采用写扩散：发布时由后台任务写入每个关注者的 feed_items，读取时只查自己的收件箱
+type FollowService struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+}
+
+// NewFollowService 创建关注服务实例，并注册动态写扩散任务的处理函数
+func NewFollowService(deps Deps) *FollowService {
+	fs := &FollowService{
+		db:          deps.DB,
+		redisClient: deps.Redis,
+	}
+
+	utils.HandleJob(JobFeedFanout, fs.processFanout, asynq.MaxRetry(5))
+	utils.HandleJob(JobFeedBackfill, fs.processBackfill, asynq.Queue(utils.JobQueueLow), asynq.MaxRetry(3))
+
+	return fs
+}
+
+// ==================== 关注关系 ====================
+
+// Follow 关注用户，重复关注不报错；任一方屏蔽了对方时不能关注
+func (fs *FollowService) Follow(ctx context.Context, followerID, followeeID string) error {
+	if followerID == followeeID {
+		return ErrCannotFollowSelf
+	}
+
+	var followee models.User
+	if err := fs.db.WithContext(ctx).Select("id").First(&followee, "id = ? AND status = ?", followeeID, 1).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+	if err := checkBlocked(ctx, fs.db, followerID, followeeID); err != nil {
+		return err
+	}
+
+	return WithTx(ctx, fs.db, func(ctx context.Context, tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.Follow{FollowerID: followerID, FolloweeID: followeeID})
+		if result.Error != nil {
+			return fmt.Errorf("failed to follow user: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		if err := fs.adjustCounts(tx, followerID, followeeID, 1); err != nil {
+			return err
+		}
+
+		AfterCommit(ctx, func() {
+			fs.invalidateProfiles(ctx, followerID, followeeID)
+			task := &FeedBackfillTask{FollowerID: followerID, FolloweeID: followeeID}
+			if err := utils.EnqueueJob(context.WithoutCancel(ctx), JobFeedBackfill, task); err != nil {
+				log.Printf("Failed to enqueue feed backfill for %s: %v", followerID, err)
+			}
+		})
+		return nil
+	})
+}
+
+// Unfollow 取消关注，并移除对方在自己动态中的内容；未关注时不报错
+func (fs *FollowService) Unfollow(ctx context.Context, followerID, followeeID string) error {
+	return WithTx(ctx, fs.db, func(ctx context.Context, tx *gorm.DB) error {
+		result := tx.Where("follower_id = ? AND followee_id = ?", followerID, followeeID).Delete(&models.Follow{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to unfollow user: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		if err := fs.adjustCounts(tx, followerID, followeeID, -1); err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ? AND actor_id = ?", followerID, followeeID).Delete(&models.FeedItem{}).Error; err != nil {
+			return fmt.Errorf("failed to clear feed: %w", err)
+		}
+
+		AfterCommit(ctx, func() { fs.invalidateProfiles(ctx, followerID, followeeID) })
+		return nil
+	})
+}
+
+// adjustCounts 同步更新双方的关注数和粉丝数
+func (fs *FollowService) adjustCounts(tx *gorm.DB, followerID, followeeID string, delta int) error {
+	if err := tx.Model(&models.User{}).Where("id = ?", followerID).
+		UpdateColumn("following_count", gorm.Expr("GREATEST(following_count + ?, 0)", delta)).Error; err != nil {
+		return fmt.Errorf("failed to update following count: %w", err)
+	}
+	if err := tx.Model(&models.User{}).Where("id = ?", followeeID).
+		UpdateColumn("follower_count", gorm.Expr("GREATEST(follower_count + ?, 0)", delta)).Error; err != nil {
+		return fmt.Errorf("failed to update follower count: %w", err)
+	}
+	return nil
+}
+
+// invalidateProfiles 清除用户资料缓存，使计数立即可见
+func (fs *FollowService) invalidateProfiles(ctx context.Context, userIDs ...string) {
+	if fs.redisClient == nil {
+		return
+	}
+	keys := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		keys[i] = "user:" + id
+	}
+	fs.redisClient.Del(context.WithoutCancel(ctx), keys...)
+}
+
+// Followers 分页获取用户的粉丝，最近关注的在前
+func (fs *FollowService) Followers(ctx context.Context, userID string, page, limit int) ([]models.PublicUser, int64, error) {
+	return fs.listUsers(ctx, "follows.followee_id = ?", "follows.follower_id", userID, page, limit)
+}
+
+// Following 分页获取用户关注的人，最近关注的在前
+func (fs *FollowService) Following(ctx context.Context, userID string, page, limit int) ([]models.PublicUser, int64, error) {
+	return fs.listUsers(ctx, "follows.follower_id = ?", "follows.followee_id", userID, page, limit)
+}
+
+func (fs *FollowService) listUsers(ctx context.Context, where, joinColumn, userID string, page, limit int) ([]models.PublicUser, int64, error) {
+	query := fs.db.WithContext(ctx).Model(&models.User{}).
+		Joins("JOIN follows ON users.id = "+joinColumn).
+		Where(where, userID).
+		Where("users.status <> ?", models.UserStatusDeactivated)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count follows: %w", err)
+	}
+
+	var users []models.User
+	if err := query.Order("follows.created_at DESC").
+		Offset(utils.PageOffset(page, limit)).Limit(limit).
+		Find(&users).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list follows: %w", err)
+	}
+
+	result := make([]models.PublicUser, len(users))
+	for i := range users {
+		result[i] = users[i].Public()
+	}
+	return result, total, nil
+}
+
+// ==================== 动态 ====================
+
+// PublishToFeed 提交写扩散任务，把新发布的书籍或上架推送到关注者的动态
+func (fs *FollowService) PublishToFeed(ctx context.Context, actorID, itemType, targetID string, createdAt time.Time) {
+	task := &FeedFanoutTask{ActorID: actorID, Type: itemType, TargetID: targetID, CreatedAt: createdAt}
+	if err := utils.EnqueueJob(context.WithoutCancel(ctx), JobFeedFanout, task); err != nil {
+		log.Printf("Failed to enqueue feed fanout of %s %s: %v", itemType, targetID, err)
+	}
+}
+
+// processFanout 按关注者ID分批写入动态，唯一索引保证任务重试时不会重复写入
+func (fs *FollowService) processFanout(ctx context.Context, task FeedFanoutTask) error {
+	lastID := ""
+	for {
+		var followerIDs []string
+		if err := fs.db.WithContext(ctx).Model(&models.Follow{}).
+			Where("followee_id = ? AND follower_id > ?", task.ActorID, lastID).
+			Order("follower_id").Limit(feedFanoutBatch).
+			Pluck("follower_id", &followerIDs).Error; err != nil {
+			return fmt.Errorf("failed to load followers: %w", err)
+		}
+		if len(followerIDs) == 0 {
+			return nil
+		}
+
+		items := fanoutItems(task, followerIDs)
+		if err := fs.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&items).Error; err != nil {
+			return fmt.Errorf("failed to write feed items: %w", err)
+		}
+
+		if len(followerIDs) < feedFanoutBatch {
+			return nil
+		}
+		lastID = followerIDs[len(followerIDs)-1]
+	}
+}
+
+// fanoutItems 为一批关注者生成同一条动态
+func fanoutItems(task FeedFanoutTask, followerIDs []string) []models.FeedItem {
+	items := make([]models.FeedItem, len(followerIDs))
+	for i, followerID := range followerIDs {
+		items[i] = models.FeedItem{
+			UserID:    followerID,
+			ActorID:   task.ActorID,
+			Type:      task.Type,
+			TargetID:  task.TargetID,
+			CreatedAt: task.CreatedAt,
+		}
+	}
+	return items
+}
+
+// processBackfill 把被关注者最近在售的书籍和上架写入关注者的动态
+func (fs *FollowService) processBackfill(ctx context.Context, task FeedBackfillTask) error {
+	db := fs.db.WithContext(ctx)
+
+	var books []models.Book
+	if err := db.Select("id", "created_at").Where("seller_id = ? AND status = ?", task.FolloweeID, 1).
+		Order("created_at DESC").Limit(feedBackfillLimit).Find(&books).Error; err != nil {
+		return fmt.Errorf("failed to load books: %w", err)
+	}
+	var listings []models.Listing
+	if err := db.Select("id", "created_at").Where("seller_id = ? AND status = ?", task.FolloweeID, "available").
+		Order("created_at DESC").Limit(feedBackfillLimit).Find(&listings).Error; err != nil {
+		return fmt.Errorf("failed to load listings: %w", err)
+	}
+
+	items := make([]models.FeedItem, 0, len(books)+len(listings))
+	for _, b := range books {
+		items = append(items, models.FeedItem{UserID: task.FollowerID, ActorID: task.FolloweeID,
+			Type: models.FeedTypeBook, TargetID: b.ID, CreatedAt: b.CreatedAt})
+	}
+	for _, l := range listings {
+		items = append(items, models.FeedItem{UserID: task.FollowerID, ActorID: task.FolloweeID,
+			Type: models.FeedTypeListing, TargetID: l.ID, CreatedAt: l.CreatedAt})
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&items).Error; err != nil {
+		return fmt.Errorf("failed to write feed items: %w", err)
+	}
+	return nil
+}
+
+// Feed 游标分页获取关注动态，多返回一条用于判断是否还有下一页
+// 已删除的书籍和上架不会出现；返回的条目填充了发布者和书籍/上架详情
+func (fs *FollowService) Feed(ctx context.Context, userID string, cursor *utils.Cursor, limit int) ([]models.FeedItem, error) {
+	query := fs.db.WithContext(ctx).Model(&models.FeedItem{}).
+		Where("feed_items.user_id = ?", userID).
+		Where("((feed_items.type = ? AND EXISTS (SELECT 1 FROM books WHERE books.id = feed_items.target_id AND books.deleted_at IS NULL))"+
+			" OR (feed_items.type = ? AND EXISTS (SELECT 1 FROM listings WHERE listings.id = feed_items.target_id AND listings.deleted_at IS NULL)))",
+			models.FeedTypeBook, models.FeedTypeListing).
+		Scopes(VisibleUsers("feed_items.actor_id"))
+
+	var items []models.FeedItem
+	if err := utils.ApplyCursor(query, "feed_items", cursor, limit).Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to get feed: %w", err)
+	}
+	if err := fs.hydrate(ctx, items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// hydrate 批量加载动态引用的发布者、书籍和上架
+func (fs *FollowService) hydrate(ctx context.Context, items []models.FeedItem) error {
+	var actorIDs, bookIDs, listingIDs []string
+	for _, item := range items {
+		actorIDs = append(actorIDs, item.ActorID)
+		switch item.Type {
+		case models.FeedTypeBook:
+			bookIDs = append(bookIDs, item.TargetID)
+		case models.FeedTypeListing:
+			listingIDs = append(listingIDs, item.TargetID)
+		}
+	}
+	db := fs.db.WithContext(ctx)
+
+	actors := make(map[string]models.PublicUser)
+	if len(actorIDs) > 0 {
+		var users []models.User
+		if err := db.Find(&users, "id IN ?", actorIDs).Error; err != nil {
+			return fmt.Errorf("failed to load feed actors: %w", err)
+		}
+		for i := range users {
+			actors[users[i].ID] = users[i].Public()
+		}
+	}
+
+	books := make(map[string]*models.Book)
+	if len(bookIDs) > 0 {
+		var list []models.Book
+		if err := db.Find(&list, "id IN ?", bookIDs).Error; err != nil {
+			return fmt.Errorf("failed to load feed books: %w", err)
+		}
+		for i := range list {
+			books[list[i].ID] = &list[i]
+		}
+	}
+
+	listings := make(map[string]*models.Listing)
+	if len(listingIDs) > 0 {
+		var list []models.Listing
+		if err := db.Preload("Book").Find(&list, "id IN ?", listingIDs).Error; err != nil {
+			return fmt.Errorf("failed to load feed listings: %w", err)
+		}
+		for i := range list {
+			listings[list[i].ID] = &list[i]
+		}
+	}
+
+	for i := range items {
+		if actor, ok := actors[items[i].ActorID]; ok {
+			items[i].Actor = &actor
+		}
+		items[i].Book = books[items[i].TargetID]
+		items[i].Listing = listings[items[i].TargetID]
+	}
+	return nil
+}
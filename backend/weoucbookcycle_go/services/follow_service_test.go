@@ -0,0 +1,34 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"weoucbookcycle_go/models"
+)
+
+func TestFollowSelfRejected(t *testing.T) {
+	fs := &FollowService{}
+	if err := fs.Follow(context.Background(), "u1", "u1"); !errors.Is(err, ErrCannotFollowSelf) {
+		t.Fatalf("got %v, want ErrCannotFollowSelf", err)
+	}
+}
+
+// 同一条动态写入每个关注者，使用发布时间而不是写入时间
+func TestFanoutItems(t *testing.T) {
+	published := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	task := FeedFanoutTask{ActorID: "a", Type: models.FeedTypeListing, TargetID: "l1", CreatedAt: published}
+
+	items := fanoutItems(task, []string{"f1", "f2"})
+	if len(items) != 2 {
+		t.Fatalf("got %d items, want 2", len(items))
+	}
+	for i, want := range []string{"f1", "f2"} {
+		item := items[i]
+		if item.UserID != want || item.ActorID != "a" || item.Type != models.FeedTypeListing ||
+			item.TargetID != "l1" || !item.CreatedAt.Equal(published) {
+			t.Errorf("item %d = %+v", i, item)
+		}
+	}
+}
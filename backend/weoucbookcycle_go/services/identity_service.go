@@ -0,0 +1,406 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// identityCodeTTL 绑定验证码有效期
+	identityCodeTTL = 10 * time.Minute
+	// identityCodeInterval 同一身份两次发送验证码的最短间隔
+	identityCodeInterval = time.Minute
+	// identityCodeMaxAttempts 验证码输错该次数后失效
+	identityCodeMaxAttempts = 5
+)
+
+var (
+	ErrIdentityNotFound  = utils.NewError(http.StatusNotFound, "identity not found")
+	ErrIdentityTaken     = utils.NewError(http.StatusConflict, "this identity is already linked to another account")
+	ErrIdentityLinked    = utils.NewError(http.StatusConflict, "this identity is already linked to your account")
+	ErrInvalidIdentity   = utils.NewError(http.StatusBadRequest, "invalid email or phone number")
+	ErrIdentityCode      = utils.NewError(http.StatusBadRequest, "invalid or expired verification code")
+	ErrLastIdentity      = utils.NewError(http.StatusConflict, "cannot unlink the last way to sign in to this account")
+	ErrPrimaryEmail      = utils.NewError(http.StatusConflict, "link another email before unlinking the primary email")
+	ErrSMSUnavailable    = utils.NewError(http.StatusServiceUnavailable, "SMS verification is not available")
+	ErrIdentityCodeStore = utils.NewError(http.StatusServiceUnavailable, "verification is temporarily unavailable")
+)
+
+// SMSSender 发送短信，由部署时接入的短信服务商实现
+type SMSSender func(ctx context.Context, phone, message string) error
+
+// smsSender 未设置时不能绑定手机号
+var smsSender SMSSender
+
+// SetSMSSender 设置短信发送实现，在启动时调用
+func SetSMSSender(sender SMSSender) {
+	smsSender = sender
+}
+
+// IdentityRequest 绑定邮箱或手机号：不带 code 时发送验证码，带 code 时完成绑定
+type IdentityRequest struct {
+	Provider string `json:"provider" binding:"required,oneof=email phone"`
+	Subject  string `json:"subject" binding:"required,max=191"`
+	Code     string `json:"code" binding:"omitempty,len=6"`
+}
+
+// WeChatIdentityRequest 绑定微信，code 由小程序 wx.login 获取
+type WeChatIdentityRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// IdentityService 账号绑定的登录身份（邮箱、手机号、微信）管理
+type IdentityService struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+	authService *AuthService
+}
+
+// NewIdentityService 创建登录身份服务实例
+func NewIdentityService(deps Deps, authService *AuthService) *IdentityService {
+	return &IdentityService{
+		db:          deps.DB,
+		redisClient: deps.Redis,
+		authService: authService,
+	}
+}
+
+// List 获取账号绑定的全部登录身份
+func (is *IdentityService) List(ctx context.Context, userID string) ([]models.UserIdentity, error) {
+	identities := []models.UserIdentity{}
+	err := WithTx(ctx, is.db, func(ctx context.Context, tx *gorm.DB) error {
+		if _, err := ensureLegacyIdentities(tx, userID); err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Order("created_at").Find(&identities).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list identities: %w", err)
+	}
+	return identities, nil
+}
+
+// SendCode 向要绑定的邮箱或手机号发送验证码
+func (is *IdentityService) SendCode(ctx context.Context, userID, provider, subject, lang string) error {
+	subject, err := normalizeIdentity(provider, subject)
+	if err != nil {
+		return err
+	}
+	if err := checkIdentityAvailable(ctx, is.db, userID, provider, subject); err != nil {
+		return err
+	}
+	if provider == models.IdentityPhone && smsSender == nil {
+		return ErrSMSUnavailable
+	}
+	if is.redisClient == nil {
+		return ErrIdentityCodeStore
+	}
+
+	codeKey := identityCodeKey(userID, provider, subject)
+	if ok, err := is.redisClient.SetNX(ctx, codeKey+":sent", "1", identityCodeInterval).Result(); err != nil {
+		return ErrIdentityCodeStore
+	} else if !ok {
+		wait, _ := is.redisClient.TTL(ctx, codeKey+":sent").Result()
+		return &utils.RateLimitError{RetryAfter: wait}
+	}
+
+	code := is.authService.generateVerificationCode()
+	pipe := is.redisClient.TxPipeline()
+	pipe.Set(ctx, codeKey, code, identityCodeTTL)
+	pipe.Del(ctx, codeKey+":attempts")
+	if _, err := pipe.Exec(ctx); err != nil {
+		return ErrIdentityCodeStore
+	}
+
+	if provider == models.IdentityPhone {
+		if err := smsSender(ctx, subject, utils.T(lang, "sms.identity_code", code)); err != nil {
+			is.redisClient.Del(context.WithoutCancel(ctx), codeKey, codeKey+":sent")
+			return utils.WrapError(http.StatusBadGateway, "failed to send SMS", err)
+		}
+		return nil
+	}
+	is.authService.SendIdentityCode(ctx, subject, code, lang)
+	return nil
+}
+
+// Confirm 校验验证码并绑定邮箱或手机号
+func (is *IdentityService) Confirm(ctx context.Context, userID, provider, subject, code string) (*models.UserIdentity, error) {
+	subject, err := normalizeIdentity(provider, subject)
+	if err != nil {
+		return nil, err
+	}
+	if is.redisClient == nil {
+		return nil, ErrIdentityCodeStore
+	}
+
+	codeKey := identityCodeKey(userID, provider, subject)
+	expected, err := is.redisClient.Get(ctx, codeKey).Result()
+	if err != nil {
+		return nil, ErrIdentityCode
+	}
+	if expected != code {
+		// 多次输错后验证码作废，需要重新发送
+		if attempts, _ := is.redisClient.Incr(ctx, codeKey+":attempts").Result(); attempts >= identityCodeMaxAttempts {
+			is.redisClient.Del(ctx, codeKey, codeKey+":attempts")
+		} else {
+			is.redisClient.Expire(ctx, codeKey+":attempts", identityCodeTTL)
+		}
+		return nil, ErrIdentityCode
+	}
+
+	identity, err := is.link(ctx, userID, provider, subject)
+	if err != nil {
+		return nil, err
+	}
+	is.redisClient.Del(context.WithoutCancel(ctx), codeKey, codeKey+":attempts")
+	return identity, nil
+}
+
+// LinkWeChat 绑定微信
+func (is *IdentityService) LinkWeChat(ctx context.Context, userID, code string) (*models.UserIdentity, error) {
+	openID, err := is.authService.wechatOpenID(code)
+	if err != nil {
+		return nil, utils.WrapError(http.StatusBadRequest, "failed to verify WeChat account", err)
+	}
+	return is.link(ctx, userID, models.IdentityWeChat, openID)
+}
+
+// link 创建登录身份；账号还没有主邮箱时把新绑定的邮箱设为主邮箱，没有微信时同步 openid
+func (is *IdentityService) link(ctx context.Context, userID, provider, subject string) (*models.UserIdentity, error) {
+	identity := &models.UserIdentity{UserID: userID, Provider: provider, Subject: subject}
+	err := WithTx(ctx, is.db, func(ctx context.Context, tx *gorm.DB) error {
+		user, err := ensureLegacyIdentities(tx, userID)
+		if err != nil {
+			return err
+		}
+		if err := checkIdentityAvailable(ctx, tx, userID, provider, subject); err != nil {
+			return err
+		}
+		if err := tx.Create(identity).Error; err != nil {
+			// 检查之后被其他账号抢先绑定时唯一索引冲突
+			if _, findErr := findIdentityUser(ctx, tx, provider, subject); findErr == nil {
+				return ErrIdentityTaken
+			}
+			return fmt.Errorf("failed to link identity: %w", err)
+		}
+
+		switch {
+		case provider == models.IdentityEmail && user.Email == "":
+			now := time.Now()
+			return tx.Model(user).Updates(map[string]interface{}{"email": subject, "email_verified": true, "verified_at": &now}).Error
+		case provider == models.IdentityWeChat && user.WeChatOpenID == "":
+			return tx.Model(user).Update("we_chat_open_id", subject).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	is.invalidateUser(ctx, userID)
+	if provider == models.IdentityEmail {
+		AfterCommit(ctx, func() {
+			recordUserEvent(ctx, is.redisClient, "email_verified", userID)
+		})
+	}
+	return identity, nil
+}
+
+// Unlink 解绑登录身份
+// 解绑后账号必须仍能登录；解绑主邮箱时改用另一个已绑定的邮箱作为主邮箱
+func (is *IdentityService) Unlink(ctx context.Context, userID, identityID string) error {
+	err := WithTx(ctx, is.db, func(ctx context.Context, tx *gorm.DB) error {
+		user, err := ensureLegacyIdentities(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		var identities []models.UserIdentity
+		if err := tx.Where("user_id = ?", userID).Order("created_at").Find(&identities).Error; err != nil {
+			return fmt.Errorf("failed to load identities: %w", err)
+		}
+		var target *models.UserIdentity
+		var remaining []models.UserIdentity
+		for i := range identities {
+			if identities[i].ID == identityID {
+				target = &identities[i]
+			} else {
+				remaining = append(remaining, identities[i])
+			}
+		}
+		if target == nil {
+			return ErrIdentityNotFound
+		}
+		if !canSignIn(remaining, user.Password != "") {
+			return ErrLastIdentity
+		}
+
+		switch {
+		case target.Provider == models.IdentityEmail && strings.EqualFold(target.Subject, user.Email):
+			next := ""
+			for _, identity := range remaining {
+				if identity.Provider == models.IdentityEmail {
+					next = identity.Subject
+					break
+				}
+			}
+			if next == "" {
+				return ErrPrimaryEmail
+			}
+			if err := tx.Model(user).Update("email", next).Error; err != nil {
+				return fmt.Errorf("failed to switch primary email: %w", err)
+			}
+		case target.Provider == models.IdentityWeChat && target.Subject == user.WeChatOpenID:
+			if err := tx.Model(user).Update("we_chat_open_id", gorm.Expr("NULL")).Error; err != nil {
+				return fmt.Errorf("failed to unlink WeChat: %w", err)
+			}
+		}
+
+		return tx.Delete(target).Error
+	})
+	if err != nil {
+		return err
+	}
+	is.invalidateUser(ctx, userID)
+	return nil
+}
+
+// invalidateUser 主邮箱等资料变化后清除用户缓存
+func (is *IdentityService) invalidateUser(ctx context.Context, userID string) {
+	if is.redisClient != nil {
+		is.redisClient.Del(context.WithoutCancel(ctx), "user:"+userID)
+	}
+}
+
+// ==================== 身份查找 ====================
+
+// checkIdentityAvailable 身份未被任何账号绑定
+func checkIdentityAvailable(ctx context.Context, db *gorm.DB, userID, provider, subject string) error {
+	owner, err := findIdentityUser(ctx, db, provider, subject)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check identity: %w", err)
+	}
+	if owner.ID == userID {
+		return ErrIdentityLinked
+	}
+	return ErrIdentityTaken
+}
+
+// findIdentityUser 查找绑定了该身份的用户
+// 身份表之前注册的账号只有 users.email / users.we_chat_open_id，没有对应记录时按这两列查找
+func findIdentityUser(ctx context.Context, db *gorm.DB, provider, subject string) (*models.User, error) {
+	if subject == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+	db = db.WithContext(ctx)
+
+	var user models.User
+	err := db.Joins("JOIN user_identities ON user_identities.user_id = users.id").
+		Where("user_identities.provider = ? AND user_identities.subject = ?", provider, subject).
+		First(&user).Error
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return &user, err
+	}
+
+	switch provider {
+	case models.IdentityEmail:
+		err = db.Where("email = ?", subject).First(&user).Error
+	case models.IdentityWeChat:
+		err = db.Where("we_chat_open_id = ?", subject).First(&user).Error
+	default:
+		return nil, gorm.ErrRecordNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ensureLegacyIdentities 为账号的主邮箱和微信补建身份记录并返回用户，已存在时跳过
+func ensureLegacyIdentities(tx *gorm.DB, userID string) (*models.User, error) {
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, "id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+
+	var legacy []models.UserIdentity
+	if user.Email != "" {
+		legacy = append(legacy, models.UserIdentity{UserID: userID, Provider: models.IdentityEmail, Subject: normalizeEmail(user.Email)})
+	}
+	if user.WeChatOpenID != "" {
+		legacy = append(legacy, models.UserIdentity{UserID: userID, Provider: models.IdentityWeChat, Subject: user.WeChatOpenID})
+	}
+	if len(legacy) > 0 {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&legacy).Error; err != nil {
+			return nil, fmt.Errorf("failed to create identities: %w", err)
+		}
+	}
+	return &user, nil
+}
+
+// touchIdentity 记录身份最后一次用于登录的时间
+func touchIdentity(db *gorm.DB, provider, subject string) {
+	db.Model(&models.UserIdentity{}).Where("provider = ? AND subject = ?", provider, subject).
+		Update("last_used_at", time.Now())
+}
+
+// canSignIn 剩余的身份是否仍能登录：微信可以直接登录，邮箱和手机号需要账号设置了密码
+func canSignIn(remaining []models.UserIdentity, hasPassword bool) bool {
+	for _, identity := range remaining {
+		if identity.Provider == models.IdentityWeChat || hasPassword {
+			return true
+		}
+	}
+	return false
+}
+
+// identityCodeKey 绑定验证码的Redis key
+func identityCodeKey(userID, provider, subject string) string {
+	return fmt.Sprintf("identity:code:%s:%s:%s", userID, provider, subject)
+}
+
+// normalizeIdentity 校验并规范化要绑定的邮箱或手机号
+func normalizeIdentity(provider, subject string) (string, error) {
+	switch provider {
+	case models.IdentityEmail:
+		email := normalizeEmail(subject)
+		if _, err := mail.ParseAddress(email); err != nil || strings.ContainsAny(email, "<> ") {
+			return "", ErrInvalidIdentity
+		}
+		return email, nil
+	case models.IdentityPhone:
+		phone := normalizePhone(subject)
+		if !utils.ValidatePhone(phone) {
+			return "", ErrInvalidIdentity
+		}
+		return phone, nil
+	}
+	return "", ErrInvalidIdentity
+}
+
+// normalizeEmail 邮箱去掉首尾空白并转为小写
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// normalizePhone 去掉空格、短横线和 +86 国家码
+func normalizePhone(phone string) string {
+	phone = strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(phone))
+	return strings.TrimPrefix(strings.TrimPrefix(phone, "+86"), "0086")
+}
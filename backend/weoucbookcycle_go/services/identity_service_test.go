@@ -0,0 +1,62 @@
+package services
+
+import (
+	"testing"
+	"weoucbookcycle_go/models"
+)
+
+func TestNormalizeIdentity(t *testing.T) {
+	cases := []struct {
+		provider, subject, want string
+		wantErr                 bool
+	}{
+		{models.IdentityEmail, "  Reader@OUC.edu.cn ", "reader@ouc.edu.cn", false},
+		{models.IdentityEmail, "not-an-email", "", true},
+		{models.IdentityEmail, "Reader <reader@ouc.edu.cn>", "", true},
+		{models.IdentityPhone, "+86 138-0013-8000", "13800138000", false},
+		{models.IdentityPhone, "008613800138000", "13800138000", false},
+		{models.IdentityPhone, "12345", "", true},
+		{models.IdentityWeChat, "openid", "", true},
+	}
+	for _, tc := range cases {
+		got, err := normalizeIdentity(tc.provider, tc.subject)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("normalizeIdentity(%q, %q) = %q, %v; want %q, error %v", tc.provider, tc.subject, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestLoginRequestIdentity(t *testing.T) {
+	provider, login := (&LoginRequest{Email: "Reader@ouc.edu.cn", Phone: "13800138000"}).identity()
+	if provider != models.IdentityEmail || login != "reader@ouc.edu.cn" {
+		t.Errorf("email login = %s %q, want email login preferred", provider, login)
+	}
+	provider, login = (&LoginRequest{Phone: "+86 13800138000"}).identity()
+	if provider != models.IdentityPhone || login != "13800138000" {
+		t.Errorf("phone login = %s %q", provider, login)
+	}
+}
+
+// 解绑后必须仍能登录：微信可以直接登录，邮箱和手机号需要密码
+func TestCanSignIn(t *testing.T) {
+	email := models.UserIdentity{Provider: models.IdentityEmail}
+	phone := models.UserIdentity{Provider: models.IdentityPhone}
+	wechat := models.UserIdentity{Provider: models.IdentityWeChat}
+
+	cases := []struct {
+		name        string
+		remaining   []models.UserIdentity
+		hasPassword bool
+		want        bool
+	}{
+		{"nothing left", nil, true, false},
+		{"email with password", []models.UserIdentity{email}, true, true},
+		{"phone without password", []models.UserIdentity{phone}, false, false},
+		{"wechat without password", []models.UserIdentity{email, wechat}, false, true},
+	}
+	for _, tc := range cases {
+		if got := canSignIn(tc.remaining, tc.hasPassword); got != tc.want {
+			t.Errorf("%s: canSignIn = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	imageModerationStream  = "image_moderation"
+	imageModerationGroup   = "image_moderators"
+	imageModerationTimeout = 30 * time.Second
+	imageModerationMaxSize = 20 * 1024 * 1024
+)
+
+// StartImageModeration 注册上传回调并启动图片审核消费者
+// 上传成功后图片写入审核队列，由后台异步调用审核服务，违规图片被隔离并进入人工审核队列
+func (ms *ModerationService) StartImageModeration(ctx context.Context) {
+	moderator := ms.imageModerator
+	if moderator == nil || ms.redisClient == nil {
+		return
+	}
+
+	utils.RegisterUploadHook(ms.enqueueImage)
+	log.Printf("image moderation enabled (provider=%s)", moderator.Name())
+
+	startEventConsumer(ctx, ms.redisClient, imageModerationGroup, []string{imageModerationStream},
+		func(ctx context.Context, stream string, msg redis.XMessage) error {
+			// 审核服务不可用时放行（只记录日志），避免阻塞队列
+			if err := ms.moderateImage(ctx, moderator, msg.Values); err != nil {
+				log.Printf("image moderation: %s failed: %v", streamString(msg.Values["key"]), err)
+			}
+			return nil
+		})
+}
+
+// enqueueImage 上传回调：把图片加入审核队列
+// 私有文件（证件照等）不发送给第三方审核服务
+func (ms *ModerationService) enqueueImage(ctx context.Context, userID string, result *utils.UploadResult) {
+	if result.Private || !strings.HasPrefix(result.ContentType, "image/") {
+		return
+	}
+
+	err := ms.redisClient.XAdd(ctx, &redis.XAddArgs{
+		Stream: imageModerationStream,
+		Values: map[string]interface{}{
+			"user_id": userID,
+			"key":     result.Key,
+			"url":     result.OriginalURL,
+		},
+	}).Err()
+	if err != nil {
+		log.Printf("image moderation: failed to enqueue %s: %v", result.Key, err)
+	}
+}
+
+// moderateImage 审核单张图片，违规时隔离图片并加入人工审核队列
+func (ms *ModerationService) moderateImage(ctx context.Context, moderator utils.ImageModerator, values map[string]interface{}) error {
+	userID := streamString(values["user_id"])
+	key := streamString(values["key"])
+	url := streamString(values["url"])
+	if key == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, imageModerationTimeout)
+	defer cancel()
+
+	storage := utils.GetStorage()
+	src, err := storage.Open(ctx, key)
+	if err != nil {
+		// 图片已被删除，无需审核
+		return nil
+	}
+	data, err := io.ReadAll(io.LimitReader(src, imageModerationMaxSize))
+	src.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read image: %w", err)
+	}
+
+	result, err := moderator.Moderate(ctx, url, data)
+	if err != nil {
+		return err
+	}
+	if !result.Flagged {
+		return nil
+	}
+
+	log.Printf("image moderation: %s flagged as %s (%.2f)", key, result.Label, result.Score)
+
+	if err := quarantineImage(ctx, storage, ms.redisClient, key, data); err != nil {
+		return fmt.Errorf("failed to quarantine image: %w", err)
+	}
+
+	items := ms.flaggedImageQueueItems(userID, key, url, result)
+	if err := ms.db.WithContext(ctx).Create(&items).Error; err != nil {
+		return fmt.Errorf("failed to create moderation queue items: %w", err)
+	}
+
+	if userID != "" {
+		lang := UserLanguage(ms.db.WithContext(ctx), userID)
+		ms.notificationService.Notify(ctx, userID, "image_quarantined", utils.T(lang, "notification.image_quarantined.title"),
+			utils.T(lang, "notification.image_quarantined.content"), map[string]interface{}{"key": key})
+	}
+
+	return nil
+}
+
+// quarantineImage 把图片移动到隔离目录，同时删除缩略图
+func quarantineImage(ctx context.Context, storage utils.Storage, redisClient *redis.Client, key string, data []byte) error {
+	contentType := http.DetectContentType(data)
+	if _, err := storage.Put(ctx, utils.QuarantineKey(key), bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		return err
+	}
+	return utils.NewFileUploader(redisClient).DeleteFile(key)
+}
+
+// flaggedImageQueueItems 为引用该图片的书籍/消息生成审核条目
+// 图片通常先上传再发布，尚未被引用时以图片本身作为审核对象
+func (ms *ModerationService) flaggedImageQueueItems(userID, key, url string, result *utils.ModerationResult) []models.ModerationQueueItem {
+	details, _ := json.Marshal(map[string]interface{}{
+		"key":            key,
+		"url":            url,
+		"quarantine_key": utils.QuarantineKey(key),
+		"provider":       result.Provider,
+		"label":          result.Label,
+		"score":          result.Score,
+	})
+
+	newItem := func(targetType, targetID, ownerID string) models.ModerationQueueItem {
+		return models.ModerationQueueItem{
+			TargetType: targetType,
+			TargetID:   targetID,
+			UserID:     ownerID,
+			Source:     "image_moderation",
+			Reason:     result.Label,
+			Details:    details,
+			Status:     models.ModerationStatusPending,
+		}
+	}
+
+	var items []models.ModerationQueueItem
+
+	var books []models.Book
+	ms.db.Select("id", "seller_id").Where("images LIKE ?", "%"+url+"%").Find(&books)
+	for _, b := range books {
+		items = append(items, newItem("book", b.ID, b.SellerID))
+	}
+
+	var messages []models.Message
+	ms.db.Select("id", "sender_id").Where("content LIKE ?", "%"+url+"%").Find(&messages)
+	for _, m := range messages {
+		items = append(items, newItem("message", m.ID, m.SenderID))
+	}
+
+	if len(items) == 0 {
+		items = append(items, newItem("image", key, userID))
+	}
+	return items
+}
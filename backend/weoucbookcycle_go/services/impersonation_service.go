@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+	"weoucbookcycle_go/config"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	// impersonationSessionKey 有效的代登录会话，AuthMiddleware 据此判断会话是否已结束
+	impersonationSessionKey = "impersonation:session:%s"
+	defaultImpersonationTTL = 30 * time.Minute
+	maxImpersonationTTL     = 2 * time.Hour
+)
+
+var (
+	// ErrImpersonateAdmin 不允许代登录管理员账号
+	ErrImpersonateAdmin = utils.NewError(http.StatusForbidden, "cannot impersonate an admin account")
+	// ErrImpersonationNotFound 代登录会话不存在
+	ErrImpersonationNotFound = utils.NewError(http.StatusNotFound, "impersonation session not found")
+)
+
+// ImpersonationService 管理员代登录服务
+type ImpersonationService struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+	jwtService  *config.JWTService
+}
+
+// NewImpersonationService 创建代登录服务实例
+func NewImpersonationService(deps Deps) *ImpersonationService {
+	return &ImpersonationService{
+		db:          deps.DB,
+		redisClient: deps.Redis,
+		jwtService:  config.NewJWTService(&deps.Config.JWT),
+	}
+}
+
+// StartImpersonationRequest 代登录请求
+type StartImpersonationRequest struct {
+	UserID     string `json:"user_id" binding:"required"`
+	Reason     string `json:"reason" binding:"required,min=5,max=500"`
+	Scope      string `json:"scope" binding:"omitempty,oneof=read write"`
+	TTLMinutes int    `json:"ttl_minutes" binding:"omitempty,min=1,max=120"`
+}
+
+// Start 为用户签发代登录token
+// token 不包含 admin 角色、不能刷新，只读范围只允许 GET 请求，期间的每个请求都会记录到审计表
+func (is *ImpersonationService) Start(ctx context.Context, adminID, ip string, req *StartImpersonationRequest) (string, *models.ImpersonationSession, error) {
+	if adminID == req.UserID {
+		return "", nil, ErrAdminSelfOperation
+	}
+
+	var user models.User
+	if err := is.db.WithContext(ctx).First(&user, "id = ?", req.UserID).Error; err != nil {
+		return "", nil, ErrAdminTargetNotFound
+	}
+	if user.Role == "admin" {
+		return "", nil, ErrImpersonateAdmin
+	}
+
+	scope := req.Scope
+	if scope == "" {
+		scope = models.ImpersonationScopeRead
+	}
+	ttl := defaultImpersonationTTL
+	if req.TTLMinutes > 0 {
+		ttl = min(time.Duration(req.TTLMinutes)*time.Minute, maxImpersonationTTL)
+	}
+
+	session := models.ImpersonationSession{
+		ID:        uuid.New().String(),
+		AdminID:   adminID,
+		UserID:    user.ID,
+		Reason:    req.Reason,
+		Scope:     scope,
+		IP:        ip,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := is.db.WithContext(ctx).Create(&session).Error; err != nil {
+		return "", nil, fmt.Errorf("failed to create impersonation session: %w", err)
+	}
+
+	token, err := is.jwtService.GenerateImpersonationToken(user.ID, user.Username, user.Email,
+		[]string{"user"}, adminID, scope, session.ID, ttl)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate impersonation token: %w", err)
+	}
+
+	if is.redisClient != nil {
+		is.redisClient.Set(ctx, fmt.Sprintf(impersonationSessionKey, session.ID), adminID, ttl)
+	}
+
+	log.Printf("impersonation: admin %s started %s session %s for user %s: %s", adminID, scope, session.ID, user.ID, req.Reason)
+	return token, &session, nil
+}
+
+// End 结束代登录会话，已签发的token立即失效
+func (is *ImpersonationService) End(ctx context.Context, adminID, sessionID string) error {
+	var session models.ImpersonationSession
+	if err := is.db.WithContext(ctx).First(&session, "id = ?", sessionID).Error; err != nil {
+		return ErrImpersonationNotFound
+	}
+
+	if session.EndedAt == nil {
+		now := time.Now()
+		if err := is.db.WithContext(ctx).Model(&session).Update("ended_at", now).Error; err != nil {
+			return fmt.Errorf("failed to end impersonation session: %w", err)
+		}
+	}
+	if is.redisClient != nil {
+		is.redisClient.Del(ctx, fmt.Sprintf(impersonationSessionKey, sessionID))
+	}
+
+	log.Printf("impersonation: session %s ended by %s", sessionID, adminID)
+	return nil
+}
+
+// ListSessions 代登录会话列表，可按管理员或用户筛选
+func (is *ImpersonationService) ListSessions(adminID, userID string, page, limit int) ([]models.ImpersonationSession, int64, error) {
+	query := is.db.Model(&models.ImpersonationSession{})
+	if adminID != "" {
+		query = query.Where("admin_id = ?", adminID)
+	}
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count impersonation sessions: %w", err)
+	}
+
+	var sessions []models.ImpersonationSession
+	if err := query.Order("created_at DESC").Offset((page - 1) * limit).Limit(limit).Find(&sessions).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list impersonation sessions: %w", err)
+	}
+	return sessions, total, nil
+}
+
+// ListAuditLogs 代登录会话期间的请求记录
+func (is *ImpersonationService) ListAuditLogs(sessionID string, page, limit int) ([]models.ImpersonationAuditLog, int64, error) {
+	var count int64
+	is.db.Model(&models.ImpersonationSession{}).Where("id = ?", sessionID).Count(&count)
+	if count == 0 {
+		return nil, 0, ErrImpersonationNotFound
+	}
+
+	query := is.db.Model(&models.ImpersonationAuditLog{}).Where("session_id = ?", sessionID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit logs: %w", err)
+	}
+
+	var logs []models.ImpersonationAuditLog
+	if err := query.Order("created_at ASC").Offset((page - 1) * limit).Limit(limit).Find(&logs).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list audit logs: %w", err)
+	}
+	return logs, total, nil
+}
@@ -0,0 +1,311 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+	"weoucbookcycle_go/models"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	// rankBookViewsKey 书籍浏览排行（ZSET），由浏览统计任务写入
+	rankBookViewsKey = "rank:book:views"
+	// rankSellersKeyPrefix/rankRecyclersKeyPrefix 月度排行（ZSET），key 后缀为 YYYY-MM，由刷新任务重建
+	rankSellersKeyPrefix   = "rank:sellers:"
+	rankRecyclersKeyPrefix = "rank:recyclers:"
+	rankMonthlyTTL         = 40 * 24 * time.Hour
+
+	// leaderboardSnapshotKey 对外返回的排行榜快照，由定时任务 leaderboard_refresh 更新
+	leaderboardSnapshotKey = "leaderboards:snapshot"
+	leaderboardSnapshotTTL = time.Hour
+	leaderboardSize        = 10
+
+	// 防刷：同一对买卖双方每月最多计几笔成交，每个用户每天最多计几本新发布的书
+	leaderboardPairLimit         = 3
+	leaderboardDailyListingLimit = 5
+	// leaderboardTransferWeight 回收达人得分中，一次送出（售出或赠送）相当于几本新发布的书
+	leaderboardTransferWeight = 2
+)
+
+// LeaderboardUser 排行榜中的用户
+type LeaderboardUser struct {
+	User  models.PublicUser `json:"user"`
+	Score int64             `json:"score"`
+}
+
+// LeaderboardBook 排行榜中的书籍
+type LeaderboardBook struct {
+	Book  models.Book `json:"book"`
+	Views int64       `json:"views"`
+}
+
+// LeaderboardTotals 本月平台数据（来自每日统计）
+type LeaderboardTotals struct {
+	NewBooks     int64 `json:"new_books"`
+	ListingsSold int64 `json:"listings_sold"`
+}
+
+// Leaderboards 公开排行榜
+type Leaderboards struct {
+	Month           string            `json:"month"`
+	TopSellers      []LeaderboardUser `json:"top_sellers"`           // 本月售出数（不含赠送）
+	MostViewedBooks []LeaderboardBook `json:"most_viewed_books"`     // 近期浏览人数
+	MostActive      []LeaderboardUser `json:"most_active_recyclers"` // 本月新发布 + 送出
+	Totals          LeaderboardTotals `json:"totals"`
+	RefreshedAt     time.Time         `json:"refreshed_at"`
+}
+
+// userScore 用户得分
+type userScore struct {
+	UserID string
+	Score  int64
+}
+
+// LeaderboardService 公开排行榜：本月最佳卖家、浏览最多的书籍和最活跃的回收达人
+// 由定时任务周期性重建月度排行并生成快照，接口只读取快照
+type LeaderboardService struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+}
+
+// NewLeaderboardService 创建排行榜服务实例
+func NewLeaderboardService(deps Deps) *LeaderboardService {
+	return &LeaderboardService{
+		db:          deps.DB,
+		redisClient: deps.Redis,
+	}
+}
+
+// Get 获取排行榜快照，快照不存在时（首次部署或Redis重启）立即生成
+func (ls *LeaderboardService) Get(ctx context.Context) (*Leaderboards, error) {
+	if ls.redisClient != nil {
+		if data, err := ls.redisClient.Get(ctx, leaderboardSnapshotKey).Bytes(); err == nil {
+			var boards Leaderboards
+			if json.Unmarshal(data, &boards) == nil {
+				return &boards, nil
+			}
+		}
+	}
+	return ls.Refresh(ctx)
+}
+
+// Refresh 重建本月的卖家和回收达人排行，并生成新的快照
+// 只统计正常状态且允许被搜索到的用户，自买自卖和同一对买卖双方超出上限的成交不计入
+func (ls *LeaderboardService) Refresh(ctx context.Context) (*Leaderboards, error) {
+	now := time.Now()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	month := monthStart.Format("2006-01")
+
+	sales, err := ls.transferScores(ctx, monthStart, false)
+	if err != nil {
+		return nil, err
+	}
+	transfers, err := ls.transferScores(ctx, monthStart, true)
+	if err != nil {
+		return nil, err
+	}
+	listed, err := ls.listingScores(ctx, monthStart)
+	if err != nil {
+		return nil, err
+	}
+	recyclers := recyclerScores(listed, transfers)
+
+	boards := &Leaderboards{Month: month, RefreshedAt: now}
+	if boards.TopSellers, err = ls.rankUsers(ctx, rankSellersKeyPrefix+month, sales); err != nil {
+		return nil, err
+	}
+	if boards.MostActive, err = ls.rankUsers(ctx, rankRecyclersKeyPrefix+month, recyclers); err != nil {
+		return nil, err
+	}
+	if boards.MostViewedBooks, err = ls.mostViewedBooks(ctx); err != nil {
+		return nil, err
+	}
+	boards.Totals = ls.monthTotals(ctx, monthStart)
+
+	if ls.redisClient != nil {
+		data, _ := json.Marshal(boards)
+		ls.redisClient.Set(ctx, leaderboardSnapshotKey, data, leaderboardSnapshotTTL)
+	}
+	return boards, nil
+}
+
+// transferScores 统计 since 之后每个卖家的成交数；includeDonations 为 false 时只统计售出
+// 同一对买卖双方最多计 leaderboardPairLimit 笔
+func (ls *LeaderboardService) transferScores(ctx context.Context, since time.Time, includeDonations bool) (map[string]int64, error) {
+	pairs := ls.db.WithContext(ctx).Model(&models.Listing{}).
+		Select("seller_id, buyer_id, COUNT(*) AS trades").
+		Where("status = ? AND updated_at >= ?", "sold", since).
+		Where("buyer_id <> '' AND buyer_id <> seller_id").
+		Group("seller_id, buyer_id")
+	if !includeDonations {
+		pairs = pairs.Where("is_donation = ?", false)
+	}
+
+	var rows []userScore
+	if err := ls.db.WithContext(ctx).Table("(?) AS pairs", pairs).
+		Select("seller_id AS user_id, SUM(LEAST(trades, ?)) AS score", leaderboardPairLimit).
+		Group("seller_id").Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to count sales: %w", err)
+	}
+	return scoreMap(rows), nil
+}
+
+// listingScores 统计 since 之后每个用户新发布的书籍数，每天最多计 leaderboardDailyListingLimit 本
+func (ls *LeaderboardService) listingScores(ctx context.Context, since time.Time) (map[string]int64, error) {
+	days := ls.db.WithContext(ctx).Model(&models.Book{}).
+		Select("seller_id, DATE(created_at) AS day, COUNT(*) AS books").
+		Where("created_at >= ?", since).
+		Group("seller_id, DATE(created_at)")
+
+	var rows []userScore
+	if err := ls.db.WithContext(ctx).Table("(?) AS days", days).
+		Select("seller_id AS user_id, SUM(LEAST(books, ?)) AS score", leaderboardDailyListingLimit).
+		Group("seller_id").Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to count new books: %w", err)
+	}
+	return scoreMap(rows), nil
+}
+
+// rankUsers 用得分重建 key 对应的排行，返回前 leaderboardSize 名中可公开展示的用户
+func (ls *LeaderboardService) rankUsers(ctx context.Context, key string, scores map[string]int64) ([]LeaderboardUser, error) {
+	top := topScores(scores, leaderboardSize*2)
+	if ls.redisClient != nil {
+		pipe := ls.redisClient.TxPipeline()
+		pipe.Del(ctx, key)
+		if len(scores) > 0 {
+			members := make([]redis.Z, 0, len(scores))
+			for userID, score := range scores {
+				members = append(members, redis.Z{Score: float64(score), Member: userID})
+			}
+			pipe.ZAdd(ctx, key, members...)
+			pipe.Expire(ctx, key, rankMonthlyTTL)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, fmt.Errorf("failed to store %s: %w", key, err)
+		}
+	}
+
+	result := []LeaderboardUser{}
+	if len(top) == 0 {
+		return result, nil
+	}
+	ids := make([]string, len(top))
+	for i, s := range top {
+		ids[i] = s.UserID
+	}
+	var users []models.User
+	if err := ls.db.WithContext(ctx).Scopes(DiscoverableUsers).Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to load users: %w", err)
+	}
+	byID := make(map[string]*models.User, len(users))
+	for i := range users {
+		byID[users[i].ID] = &users[i]
+	}
+	for _, s := range top {
+		if user, ok := byID[s.UserID]; ok && len(result) < leaderboardSize {
+			result = append(result, LeaderboardUser{User: user.Public(), Score: s.Score})
+		}
+	}
+	return result, nil
+}
+
+// mostViewedBooks 浏览排行中在售且卖家未停用账号的书籍
+func (ls *LeaderboardService) mostViewedBooks(ctx context.Context) ([]LeaderboardBook, error) {
+	result := []LeaderboardBook{}
+	if ls.redisClient == nil {
+		return result, nil
+	}
+	ranked, err := ls.redisClient.ZRevRangeWithScores(ctx, rankBookViewsKey, 0, leaderboardSize*3-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read book views: %w", err)
+	}
+	if len(ranked) == 0 {
+		return result, nil
+	}
+
+	ids := make([]string, len(ranked))
+	for i, z := range ranked {
+		ids[i] = fmt.Sprint(z.Member)
+	}
+	var books []models.Book
+	if err := ls.db.WithContext(ctx).Where("books.id IN ? AND books.status = ?", ids, 1).
+		Scopes(VisibleUsers("books.seller_id")).Find(&books).Error; err != nil {
+		return nil, fmt.Errorf("failed to load books: %w", err)
+	}
+	byID := make(map[string]*models.Book, len(books))
+	for i := range books {
+		byID[books[i].ID] = &books[i]
+	}
+	for i, z := range ranked {
+		if book, ok := byID[ids[i]]; ok && len(result) < leaderboardSize {
+			result = append(result, LeaderboardBook{Book: *book, Views: int64(z.Score)})
+		}
+	}
+	return result, nil
+}
+
+// monthTotals 本月的新书和成交数：已汇总的天数来自 daily_stats，今天来自Redis计数器
+func (ls *LeaderboardService) monthTotals(ctx context.Context, monthStart time.Time) LeaderboardTotals {
+	var totals LeaderboardTotals
+	ls.db.WithContext(ctx).Model(&models.DailyStat{}).
+		Select("COALESCE(SUM(new_books), 0) AS new_books, COALESCE(SUM(listings_sold), 0) AS listings_sold").
+		Where("date >= ? AND date < ?", monthStart.Format(statsDateLayout), time.Now().Format(statsDateLayout)).
+		Scan(&totals)
+
+	if ls.redisClient != nil {
+		today := time.Now().Format(statsDateLayout)
+		newBooks, _ := ls.redisClient.Get(ctx, fmt.Sprintf("stats:%s:%s", StatNewBooks, today)).Int64()
+		sold, _ := ls.redisClient.Get(ctx, fmt.Sprintf("stats:%s:%s", StatListingsSold, today)).Int64()
+		totals.NewBooks += newBooks
+		totals.ListingsSold += sold
+	}
+	return totals
+}
+
+// ==================== 辅助方法 ====================
+
+// recyclerScores 回收达人得分：新发布的书籍数 + 送出次数 × leaderboardTransferWeight
+func recyclerScores(listed, transfers map[string]int64) map[string]int64 {
+	scores := make(map[string]int64, len(listed)+len(transfers))
+	for userID, n := range listed {
+		scores[userID] += n
+	}
+	for userID, n := range transfers {
+		scores[userID] += n * leaderboardTransferWeight
+	}
+	return scores
+}
+
+// topScores 按得分从高到低取前 n 个，得分相同时按用户ID排序保证结果稳定
+func topScores(scores map[string]int64, n int) []userScore {
+	list := make([]userScore, 0, len(scores))
+	for userID, score := range scores {
+		if score > 0 {
+			list = append(list, userScore{UserID: userID, Score: score})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Score != list[j].Score {
+			return list[i].Score > list[j].Score
+		}
+		return list[i].UserID < list[j].UserID
+	})
+	if len(list) > n {
+		list = list[:n]
+	}
+	return list
+}
+
+// scoreMap 把查询结果转换为 用户ID -> 得分
+func scoreMap(rows []userScore) map[string]int64 {
+	scores := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		scores[row.UserID] = row.Score
+	}
+	return scores
+}
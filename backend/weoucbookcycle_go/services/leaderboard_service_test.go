@@ -0,0 +1,28 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRecyclerScores(t *testing.T) {
+	listed := map[string]int64{"u-1": 5, "u-2": 1}
+	transfers := map[string]int64{"u-2": 3, "u-3": 1}
+	want := map[string]int64{"u-1": 5, "u-2": 1 + 3*leaderboardTransferWeight, "u-3": leaderboardTransferWeight}
+	if got := recyclerScores(listed, transfers); !reflect.DeepEqual(got, want) {
+		t.Errorf("recyclerScores = %v, want %v", got, want)
+	}
+}
+
+// 得分相同时按用户ID排序，快照在两次刷新之间不会来回跳动；0分不上榜
+func TestTopScores(t *testing.T) {
+	scores := map[string]int64{"u-b": 3, "u-a": 3, "u-c": 7, "u-d": 1, "u-e": 0}
+	got := topScores(scores, 3)
+	want := []userScore{{"u-c", 7}, {"u-a", 3}, {"u-b", 3}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("topScores = %v, want %v", got, want)
+	}
+	if got := topScores(scores, 10); len(got) != 4 {
+		t.Errorf("topScores should skip zero scores, got %v", got)
+	}
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+)
+
+const listingExpiryBatch = 200
+
+// expireStaleListings 把超过过期天数的在售发布标记为 cancelled 并通知卖家，由定时任务 listing_expiry 调用
+// 过期天数由运行时参数 listing_expiry_days 控制，为0时不执行
+func (s *Scheduler) expireStaleListings(ctx context.Context) error {
+	days := s.settings.Int(ctx, SettingListingExpiryDays)
+	if days <= 0 {
+		return nil
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -days)
+	for {
+		var listings []models.Listing
+		if err := s.db.WithContext(ctx).Preload("Book").
+			Where("status = ? AND updated_at < ?", "available", cutoff).
+			Limit(listingExpiryBatch).Find(&listings).Error; err != nil {
+			return fmt.Errorf("failed to load listings: %w", err)
+		}
+		if len(listings) == 0 {
+			return nil
+		}
+
+		for _, listing := range listings {
+			if err := s.db.WithContext(ctx).Model(&models.Listing{}).Where("id = ?", listing.ID).
+				Update("status", "cancelled").Error; err != nil {
+				return fmt.Errorf("failed to expire %s: %w", listing.ID, err)
+			}
+			if s.redisClient != nil {
+				s.redisClient.Del(ctx, "listing:"+listing.ID)
+			}
+
+			lang := UserLanguage(s.db.WithContext(ctx), listing.SellerID)
+			s.notificationService.Notify(ctx, listing.SellerID, "listing_expired", utils.T(lang, "notification.listing_expired.title"),
+				utils.T(lang, "notification.listing_expired.content", listing.Book.Title),
+				map[string]interface{}{"listing_id": listing.ID, "book_id": listing.BookID})
+		}
+
+		if len(listings) < listingExpiryBatch {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,363 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrModerationItemClosed 审核条目已处理完成
+	ErrModerationItemClosed = utils.NewError(http.StatusConflict, "moderation item has already been resolved")
+	// ErrInvalidAssignee 只能分配给管理员
+	ErrInvalidAssignee = utils.NewError(http.StatusBadRequest, "assignee must be an admin")
+)
+
+// openModerationStatuses 仍在队列中等待处理的状态
+var openModerationStatuses = []string{models.ModerationStatusPending, models.ModerationStatusEscalated}
+
+// ModerationService 统一审核队列服务
+// 用户举报和图片自动审核都进入 moderation_queue，管理员在同一个队列中分配、升级和处理
+type ModerationService struct {
+	db                  *gorm.DB
+	redisClient         *redis.Client
+	adminService        *AdminService
+	notificationService *NotificationService
+	// imageModerator 第三方图片审核服务，未配置 IMAGE_MODERATION_PROVIDER 时为nil
+	imageModerator utils.ImageModerator
+}
+
+// NewModerationService 创建审核队列服务实例
+func NewModerationService(deps Deps, adminService *AdminService, notificationService *NotificationService) *ModerationService {
+	return &ModerationService{
+		db:                  deps.DB,
+		redisClient:         deps.Redis,
+		adminService:        adminService,
+		notificationService: notificationService,
+		imageModerator:      utils.NewImageModerator(&deps.Config.Moderation),
+	}
+}
+
+// ModerationQuery 审核队列查询条件
+type ModerationQuery struct {
+	Page       int
+	Limit      int
+	Status     string // 为空时返回待处理和已升级的条目
+	TargetType string
+	Source     string
+	AssignedTo string
+}
+
+// ResolveModerationRequest 处理审核条目请求
+// Decision 为 approve（内容正常，驳回举报）或 reject（确认违规）
+type ResolveModerationRequest struct {
+	Decision string `json:"decision" binding:"required,oneof=approve reject"`
+	Reason   string `json:"reason" binding:"required,oneof=spam fraud prohibited_item harassment inappropriate other no_violation"`
+	Action   string `json:"action" binding:"omitempty,oneof=none remove ban"`
+	Note     string `json:"note" binding:"max=500"`
+}
+
+// ModerationItemDetail 审核条目及其关联的举报
+type ModerationItemDetail struct {
+	models.ModerationQueueItem
+	Reports []models.Report `json:"reports"`
+}
+
+// ==================== 入队 ====================
+
+// enqueueReport 把举报合并到对象的审核条目中，没有未处理条目时新建
+// 举报次数达到 escalateReports（MODERATION_ESCALATE_REPORTS）时自动升级，0 表示不自动升级
+func enqueueReport(tx *gorm.DB, report *models.Report, escalateReports int) error {
+	var item models.ModerationQueueItem
+	err := tx.Where("target_type = ? AND target_id = ? AND status IN ?",
+		report.TargetType, report.TargetID, openModerationStatuses).
+		Order("created_at DESC").First(&item).Error
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		item = models.ModerationQueueItem{
+			TargetType:  report.TargetType,
+			TargetID:    report.TargetID,
+			UserID:      moderationTargetOwner(tx, report.TargetType, report.TargetID),
+			Source:      "report",
+			Reason:      report.Reason,
+			Status:      models.ModerationStatusPending,
+			ReportCount: 1,
+		}
+		if err := tx.Create(&item).Error; err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	default:
+		updates := map[string]interface{}{"report_count": gorm.Expr("report_count + 1")}
+		if item.Status == models.ModerationStatusPending && escalateReports > 0 && item.ReportCount+1 >= escalateReports {
+			updates["status"] = models.ModerationStatusEscalated
+			updates["escalated_at"] = time.Now()
+		}
+		if err := tx.Model(&item).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+
+	report.QueueItemID = item.ID
+	return tx.Model(report).Update("queue_item_id", item.ID).Error
+}
+
+// moderationTargetOwner 审核对象的所有者（被处理时接收通知的用户）
+func moderationTargetOwner(tx *gorm.DB, targetType, targetID string) string {
+	var owner string
+	switch targetType {
+	case "user":
+		return targetID
+	case "book":
+		tx.Model(&models.Book{}).Select("seller_id").Where("id = ?", targetID).Scan(&owner)
+	case "listing":
+		tx.Model(&models.Listing{}).Select("seller_id").Where("id = ?", targetID).Scan(&owner)
+	case "message":
+		tx.Model(&models.Message{}).Select("sender_id").Where("id = ?", targetID).Scan(&owner)
+	}
+	return owner
+}
+
+// ==================== 查询 ====================
+
+// List 审核队列，已升级的条目优先，其次按举报次数和入队时间排序
+func (ms *ModerationService) List(q *ModerationQuery) ([]models.ModerationQueueItem, int64, error) {
+	query := ms.db.Model(&models.ModerationQueueItem{})
+	if q.Status != "" {
+		query = query.Where("status = ?", q.Status)
+	} else {
+		query = query.Where("status IN ?", openModerationStatuses)
+	}
+	if q.TargetType != "" {
+		query = query.Where("target_type = ?", q.TargetType)
+	}
+	if q.Source != "" {
+		query = query.Where("source = ?", q.Source)
+	}
+	if q.AssignedTo != "" {
+		query = query.Where("assigned_to = ?", q.AssignedTo)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count moderation items: %w", err)
+	}
+
+	var items []models.ModerationQueueItem
+	if err := query.Order("status = 'escalated' DESC, report_count DESC, created_at ASC").
+		Offset((q.Page - 1) * q.Limit).Limit(q.Limit).Find(&items).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list moderation items: %w", err)
+	}
+	return items, total, nil
+}
+
+// Get 审核条目详情，包含关联的举报
+func (ms *ModerationService) Get(itemID string) (*ModerationItemDetail, error) {
+	var item models.ModerationQueueItem
+	if err := ms.db.First(&item, "id = ?", itemID).Error; err != nil {
+		return nil, ErrAdminTargetNotFound
+	}
+
+	detail := &ModerationItemDetail{ModerationQueueItem: item}
+	if err := ms.db.Preload("Reporter").Where("queue_item_id = ?", itemID).
+		Order("created_at ASC").Find(&detail.Reports).Error; err != nil {
+		return nil, fmt.Errorf("failed to load reports: %w", err)
+	}
+	return detail, nil
+}
+
+// ==================== 处理 ====================
+
+// Assign 分配处理人，assigneeID 为空时分配给自己
+func (ms *ModerationService) Assign(adminID, itemID, assigneeID string) (*models.ModerationQueueItem, error) {
+	if assigneeID == "" {
+		assigneeID = adminID
+	}
+
+	var assignee models.User
+	if err := ms.db.Select("id", "role").First(&assignee, "id = ?", assigneeID).Error; err != nil || assignee.Role != "admin" {
+		return nil, ErrInvalidAssignee
+	}
+
+	item, err := ms.openItem(itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := ms.db.Model(item).Updates(map[string]interface{}{
+		"assigned_to": assigneeID,
+		"assigned_at": now,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to assign moderation item: %w", err)
+	}
+	return item, nil
+}
+
+// Escalate 升级条目，交由更高级别复核
+func (ms *ModerationService) Escalate(adminID, itemID, note string) (*models.ModerationQueueItem, error) {
+	item, err := ms.openItem(itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{
+		"status":       models.ModerationStatusEscalated,
+		"escalated_at": time.Now(),
+		// 升级后取消原分配，等待复核人重新认领
+		"assigned_to": "",
+		"assigned_at": nil,
+	}
+	if note != "" {
+		updates["review_note"] = note
+	}
+	if err := ms.db.Model(item).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to escalate moderation item: %w", err)
+	}
+
+	log.Printf("moderation: %s escalated by %s", itemID, adminID)
+	return item, nil
+}
+
+// Resolve 处理审核条目：更新关联举报、执行处理措施并通知举报人和内容所有者
+func (ms *ModerationService) Resolve(ctx context.Context, adminID, itemID string, req *ResolveModerationRequest) (*models.ModerationQueueItem, error) {
+	item, err := ms.openItem(itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	status, reportStatus := models.ModerationStatusRejected, models.ReportStatusResolved
+	action := req.Action
+	if req.Decision == "approve" {
+		status, reportStatus = models.ModerationStatusApproved, models.ReportStatusDismissed
+		action = "none"
+	} else if action == "" {
+		action = "none"
+	}
+
+	var reporterIDs []string
+	now := time.Now()
+	err = WithTx(ctx, ms.db, func(ctx context.Context, tx *gorm.DB) error {
+		if err := tx.Model(item).Updates(map[string]interface{}{
+			"status":      status,
+			"resolution":  req.Reason,
+			"action":      action,
+			"review_note": req.Note,
+			"reviewed_by": adminID,
+			"reviewed_at": now,
+		}).Error; err != nil {
+			return err
+		}
+
+		tx.Model(&models.Report{}).Distinct("reporter_id").
+			Where("queue_item_id = ? AND status = ?", itemID, models.ReportStatusPending).
+			Pluck("reporter_id", &reporterIDs)
+
+		return tx.Model(&models.Report{}).
+			Where("queue_item_id = ? AND status = ?", itemID, models.ReportStatusPending).
+			Updates(map[string]interface{}{
+				"status":     reportStatus,
+				"handled_by": adminID,
+				"handled_at": now,
+				"resolution": req.Note,
+			}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve moderation item: %w", err)
+	}
+
+	if err := ms.applyAction(ctx, adminID, item, action); err != nil {
+		log.Printf("moderation: failed to apply %s on %s %s: %v", action, item.TargetType, item.TargetID, err)
+	}
+
+	go ms.notifyResolution(context.WithoutCancel(ctx), item, reporterIDs, req.Decision, req.Reason, action)
+
+	return item, nil
+}
+
+// openItem 获取仍在队列中的条目
+func (ms *ModerationService) openItem(itemID string) (*models.ModerationQueueItem, error) {
+	var item models.ModerationQueueItem
+	if err := ms.db.First(&item, "id = ?", itemID).Error; err != nil {
+		return nil, ErrAdminTargetNotFound
+	}
+	if item.Status != models.ModerationStatusPending && item.Status != models.ModerationStatusEscalated {
+		return nil, ErrModerationItemClosed
+	}
+	return &item, nil
+}
+
+// applyAction 执行处理措施：remove 移除内容，ban 封禁内容所有者
+func (ms *ModerationService) applyAction(ctx context.Context, adminID string, item *models.ModerationQueueItem, action string) error {
+	switch action {
+	case "remove":
+		switch item.TargetType {
+		case "book":
+			return ms.adminService.DeleteBook(ctx, item.TargetID)
+		case "listing":
+			_, err := ms.adminService.SetListingStatus(ctx, item.TargetID, "cancelled")
+			return err
+		case "message":
+			return ms.adminService.DeleteMessage(item.TargetID)
+		case "chat":
+			return ms.db.Delete(&models.Chat{}, "id = ?", item.TargetID).Error
+		case "user":
+			_, err := ms.adminService.SetUserStatus(ctx, adminID, item.TargetID, 0)
+			return err
+		}
+	case "ban":
+		if item.UserID == "" {
+			return errors.New("target has no owner to ban")
+		}
+		_, err := ms.adminService.SetUserStatus(ctx, adminID, item.UserID, 0)
+		return err
+	}
+	return nil
+}
+
+// notifyResolution 通知举报人处理结果，确认违规时通知内容所有者
+// 文案按每个接收者设置的语言生成
+func (ms *ModerationService) notifyResolution(ctx context.Context, item *models.ModerationQueueItem, reporterIDs []string, decision, reason, action string) {
+	data := map[string]interface{}{
+		"moderation_id": item.ID,
+		"target_type":   item.TargetType,
+		"target_id":     item.TargetID,
+	}
+
+	for _, reporterID := range reporterIDs {
+		lang := UserLanguage(ms.db, reporterID)
+		target := utils.T(lang, "moderation.target."+item.TargetType)
+		content := utils.T(lang, "notification.report_resolved.content", target)
+		if decision == "reject" {
+			content = utils.T(lang, "notification.report_resolved.content_violation", target, utils.T(lang, "moderation.reason."+reason))
+		}
+		if _, err := ms.notificationService.Notify(ctx, reporterID, "report_resolved", utils.T(lang, "notification.report_resolved.title"), content, data); err != nil {
+			log.Printf("moderation: failed to notify reporter %s: %v", reporterID, err)
+		}
+	}
+
+	if decision != "reject" || item.UserID == "" {
+		return
+	}
+
+	lang := UserLanguage(ms.db, item.UserID)
+	key := "notification.moderation_action.content"
+	switch action {
+	case "remove", "ban":
+		key += "_" + action
+	}
+	content := utils.T(lang, key, utils.T(lang, "moderation.target."+item.TargetType), utils.T(lang, "moderation.reason."+reason))
+	if _, err := ms.notificationService.Notify(ctx, item.UserID, "moderation_action", utils.T(lang, "notification.moderation_action.title"), content, data); err != nil {
+		log.Printf("moderation: failed to notify owner %s: %v", item.UserID, err)
+	}
+}
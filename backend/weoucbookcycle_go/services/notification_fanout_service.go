@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	notificationFanoutGroup = "notification_fanout"
+	// notificationFanoutDedupTTL 已处理事件的去重记录保留时间，防止消费者重启重试时重复通知
+	notificationFanoutDedupTTL = 7 * 24 * time.Hour
+)
+
+// notificationEventStreams 领域事件流，由各业务服务写入
+var notificationEventStreams = []string{"user_events", "book_events", "chat_events"}
+
+// ErrUnknownEventStream 不是通知消费的事件流
+var ErrUnknownEventStream = utils.NewError(http.StatusBadRequest, "unknown event stream")
+
+// notificationEventHandler 把一条领域事件转换为通知
+type notificationEventHandler func(ctx context.Context, ns *NotificationService, values map[string]interface{}) error
+
+// notificationEventHandlers 事件名 -> 处理函数，未注册的事件直接确认
+var notificationEventHandlers = map[string]notificationEventHandler{
+	"register":     fanoutUserRegistered,
+	"book_created": fanoutBookCreated,
+	"book_liked":   fanoutBookLiked,
+	"chat_created": fanoutChatCreated,
+}
+
+// Start 启动领域事件通知消费者
+// 业务服务只负责写入 user_events/book_events/chat_events，通知的生成和投递在这里完成；
+// 处理失败的事件保持未确认，下次启动时重试
+func (ns *NotificationService) Start(ctx context.Context) {
+	// 从新事件开始消费，避免首次部署时为历史事件补发通知（需要时可通过 ReplayEvents 回放）
+	startEventConsumerFrom(ctx, ns.redisClient, notificationFanoutGroup, notificationEventStreams, "$",
+		func(ctx context.Context, stream string, msg redis.XMessage) error {
+			return ns.handleEvent(ctx, stream, msg)
+		})
+}
+
+// handleEvent 处理单条事件，同一事件只会生成一次通知
+func (ns *NotificationService) handleEvent(ctx context.Context, stream string, msg redis.XMessage) error {
+	handler, ok := notificationEventHandlers[streamString(msg.Values["event"])]
+	if !ok {
+		return nil
+	}
+
+	dedupKey := fmt.Sprintf("notify:fanout:%s:%s", stream, msg.ID)
+	if exists, _ := ns.redisClient.Exists(ctx, dedupKey).Result(); exists > 0 {
+		return nil
+	}
+
+	// 超出通知上限或接收者已停用账号的事件直接丢弃，不再重试
+	if err := handler(ctx, ns, msg.Values); err != nil && !errors.Is(err, ErrNotificationRateLimited) && !errors.Is(err, ErrRecipientDeactivated) {
+		return err
+	}
+
+	ns.redisClient.Set(ctx, dedupKey, "1", notificationFanoutDedupTTL)
+	return nil
+}
+
+// ReplayEvents 从指定事件ID开始重新投递事件流（"0" 表示从头开始）
+// 已成功生成过通知的事件在去重记录有效期内会被跳过
+func (ns *NotificationService) ReplayEvents(ctx context.Context, stream, fromID string) error {
+	if ns.redisClient == nil {
+		return errors.New("redis not available")
+	}
+	if !isNotificationEventStream(stream) {
+		return ErrUnknownEventStream
+	}
+	if fromID == "" {
+		fromID = "0"
+	}
+
+	// SETID 把消费组的读取位置移到 fromID 之前，新读取(>)会从其后的事件开始
+	return ns.redisClient.XGroupSetID(ctx, stream, notificationFanoutGroup, fromID).Err()
+}
+
+// isNotificationEventStream 是否为通知消费的事件流
+func isNotificationEventStream(stream string) bool {
+	for _, s := range notificationEventStreams {
+		if s == stream {
+			return true
+		}
+	}
+	return false
+}
+
+// fanoutUserRegistered 新用户注册：发送欢迎通知
+func fanoutUserRegistered(ctx context.Context, ns *NotificationService, values map[string]interface{}) error {
+	userID := streamString(values["user_id"])
+	if userID == "" {
+		return nil
+	}
+
+	lang := UserLanguage(ns.db.WithContext(ctx), userID)
+	_, err := ns.Notify(ctx, userID, "welcome", utils.T(lang, "notification.welcome.title"),
+		utils.T(lang, "notification.welcome.content"), nil)
+	return err
+}
+
+// fanoutBookCreated 书籍发布成功：通知卖家
+func fanoutBookCreated(ctx context.Context, ns *NotificationService, values map[string]interface{}) error {
+	sellerID := streamString(values["seller_id"])
+	bookID := streamString(values["book_id"])
+	if sellerID == "" || bookID == "" {
+		return nil
+	}
+
+	lang := UserLanguage(ns.db.WithContext(ctx), sellerID)
+	_, err := ns.Notify(ctx, sellerID, "book_published", utils.T(lang, "notification.book_published.title"),
+		utils.T(lang, "notification.book_published.content", streamString(values["title"])),
+		map[string]interface{}{"book_id": bookID})
+	return err
+}
+
+// fanoutBookLiked 书籍被点赞：通知卖家，一小时内的点赞合并为一条
+func fanoutBookLiked(ctx context.Context, ns *NotificationService, values map[string]interface{}) error {
+	bookID := streamString(values["book_id"])
+	userID := streamString(values["user_id"])
+	if bookID == "" || userID == "" {
+		return nil
+	}
+
+	var book models.Book
+	if err := ns.db.WithContext(ctx).Select("id", "title", "seller_id").First(&book, "id = ?", bookID).Error; err != nil {
+		return nil
+	}
+	if book.SellerID == userID {
+		return nil
+	}
+
+	lang := UserLanguage(ns.db.WithContext(ctx), book.SellerID)
+	_, err := ns.NotifyCollapsed(ctx, book.SellerID, "book_liked", "book_liked:"+bookID, userID,
+		func(count int64) (string, string) {
+			if count == 1 {
+				return utils.T(lang, "notification.book_liked.title"), utils.T(lang, "notification.book_liked.content", book.Title)
+			}
+			return utils.T(lang, "notification.book_liked.title_many", count),
+				utils.T(lang, "notification.book_liked.content_many", count, book.Title)
+		},
+		map[string]interface{}{"book_id": bookID})
+	return err
+}
+
+// fanoutChatCreated 新会话：通知被联系的用户
+func fanoutChatCreated(ctx context.Context, ns *NotificationService, values map[string]interface{}) error {
+	chatID := streamString(values["chat_id"])
+	initiatorID := streamString(values["initiator_id"])
+	targetID := streamString(values["target_user_id"])
+	if chatID == "" || targetID == "" {
+		return nil
+	}
+
+	var initiator models.User
+	ns.db.WithContext(ctx).Select("id", "username").First(&initiator, "id = ?", initiatorID)
+
+	lang := UserLanguage(ns.db.WithContext(ctx), targetID)
+	title := utils.T(lang, "notification.chat_created.title")
+	content := utils.T(lang, "notification.chat_created.content", initiator.Username)
+	if _, err := ns.Notify(ctx, targetID, "chat_created", title, content, map[string]interface{}{
+		"chat_id":      chatID,
+		"initiator_id": initiatorID,
+	}); err != nil {
+		return err
+	}
+
+	ns.pushService.Enqueue(ctx, targetID, "chat_created", title, content, map[string]string{"chat_id": chatID})
+	return nil
+}
@@ -0,0 +1,325 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// unreadCountCacheTTL 未读数缓存时间，写入/已读/删除时主动失效
+const unreadCountCacheTTL = 10 * time.Minute
+
+const (
+	// notificationCollapseWindow 同一对象的通知在该时间内合并为一条
+	notificationCollapseWindow = time.Hour
+)
+
+var (
+	// ErrNotificationNotFound 通知不存在或不属于当前用户
+	ErrNotificationNotFound = utils.NewError(http.StatusNotFound, "notification not found")
+	// ErrNotificationRateLimited 超出每小时通知上限，通知被丢弃
+	ErrNotificationRateLimited = utils.NewError(http.StatusTooManyRequests, "notification rate limit exceeded")
+)
+
+// uncappedNotificationTypes 不受每小时上限限制的通知类型（交易和审核结果不能丢）
+var uncappedNotificationTypes = map[string]bool{
+	"listing_status":    true,
+	"image_quarantined": true,
+	"report_resolved":   true,
+	"moderation_action": true,
+}
+
+// UnreadCount 未读通知数
+type UnreadCount struct {
+	Total  int64            `json:"total"`
+	ByType map[string]int64 `json:"by_type"`
+}
+
+// NotificationService 站内通知服务
+type NotificationService struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+	pushService *PushService
+	// hourlyCap 每个用户每小时最多收到的通知数（NOTIFICATION_HOURLY_CAP）
+	hourlyCap int
+}
+
+// NewNotificationService 创建通知服务实例
+func NewNotificationService(deps Deps, pushService *PushService) *NotificationService {
+	return &NotificationService{
+		db:          deps.DB,
+		redisClient: deps.Redis,
+		pushService: pushService,
+		hourlyCap:   deps.Config.Notification.HourlyCap,
+	}
+}
+
+// Notify 给用户创建一条站内通知
+func (ns *NotificationService) Notify(ctx context.Context, userID, notifType, title, content string, data map[string]interface{}) (*models.Notification, error) {
+	if userID == "" {
+		return nil, errors.New("user id is required")
+	}
+	if isDeactivated(ctx, ns.db.WithContext(ctx), userID) {
+		return nil, ErrRecipientDeactivated
+	}
+	if !uncappedNotificationTypes[notifType] && !ns.reserveHourlyQuota(ctx, userID) {
+		return nil, ErrNotificationRateLimited
+	}
+
+	notification := models.Notification{
+		UserID:  userID,
+		Type:    notifType,
+		Title:   title,
+		Content: content,
+	}
+	if data != nil {
+		payload, _ := json.Marshal(data)
+		notification.Data = payload
+	}
+
+	if err := ns.db.WithContext(ctx).Create(&notification).Error; err != nil {
+		return nil, fmt.Errorf("failed to create notification: %w", err)
+	}
+	ns.clearUnreadCache(ctx, userID)
+
+	return &notification, nil
+}
+
+// NotifyCollapsed 创建可合并的通知
+// 同一用户同一 collapseKey（如 book_liked:{book_id}）在合并窗口内且通知未读时，只更新原通知的内容，
+// render 根据去重后的触发人数生成标题和内容（例如 "20 人赞了你的书"）
+func (ns *NotificationService) NotifyCollapsed(ctx context.Context, userID, notifType, collapseKey, actorID string, render func(count int64) (string, string), data map[string]interface{}) (*models.Notification, error) {
+	if isDeactivated(ctx, ns.db.WithContext(ctx), userID) {
+		return nil, ErrRecipientDeactivated
+	}
+	if ns.redisClient == nil {
+		title, content := render(1)
+		return ns.Notify(ctx, userID, notifType, title, content, data)
+	}
+
+	key := fmt.Sprintf("notify:collapse:%s:%s", userID, collapseKey)
+	actorsKey := key + ":actors"
+
+	ns.redisClient.SAdd(ctx, actorsKey, actorID)
+	ns.redisClient.Expire(ctx, actorsKey, notificationCollapseWindow)
+	count, _ := ns.redisClient.SCard(ctx, actorsKey).Result()
+	if count < 1 {
+		count = 1
+	}
+
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	data["count"] = count
+	payload, _ := json.Marshal(data)
+
+	// 合并到窗口内仍未读的通知，并移到列表最前
+	if notificationID, err := ns.redisClient.Get(ctx, key).Result(); err == nil && notificationID != "" {
+		title, content := render(count)
+		result := ns.db.WithContext(ctx).Model(&models.Notification{}).
+			Where("id = ? AND user_id = ? AND is_read = ?", notificationID, userID, false).
+			Updates(map[string]interface{}{
+				"title":      title,
+				"content":    content,
+				"data":       payload,
+				"created_at": time.Now(),
+			})
+		if result.Error == nil && result.RowsAffected > 0 {
+			ns.clearUnreadCache(ctx, userID)
+			return nil, nil
+		}
+
+		// 原通知已读或已删除，从本次触发重新开始计数
+		ns.redisClient.Del(ctx, actorsKey)
+		ns.redisClient.SAdd(ctx, actorsKey, actorID)
+		ns.redisClient.Expire(ctx, actorsKey, notificationCollapseWindow)
+		count = 1
+		data["count"] = count
+	}
+
+	title, content := render(count)
+	notification, err := ns.Notify(ctx, userID, notifType, title, content, data)
+	if err != nil {
+		return nil, err
+	}
+	ns.redisClient.Set(ctx, key, notification.ID, notificationCollapseWindow)
+	return notification, nil
+}
+
+// reserveHourlyQuota 检查并占用用户本小时的通知配额
+// 上限由 NOTIFICATION_HOURLY_CAP 控制（默认30条，0表示不限制）
+func (ns *NotificationService) reserveHourlyQuota(ctx context.Context, userID string) bool {
+	limit := int64(ns.hourlyCap)
+	if ns.redisClient == nil || limit <= 0 {
+		return true
+	}
+
+	key := fmt.Sprintf("notify:rate:%s:%s", userID, time.Now().Format("2006010215"))
+	count, err := ns.redisClient.Incr(ctx, key).Result()
+	if err != nil {
+		return true
+	}
+	if count == 1 {
+		ns.redisClient.Expire(ctx, key, time.Hour)
+	}
+	return count <= limit
+}
+
+// ListNotifications 分页获取用户通知
+func (ns *NotificationService) ListNotifications(userID string, page, limit int, unreadOnly bool) ([]models.Notification, int64, error) {
+	query := ns.db.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("is_read = ?", false)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count notifications: %w", err)
+	}
+
+	var notifications []models.Notification
+	if err := query.
+		Order("created_at DESC").
+		Limit(limit).
+		Offset((page - 1) * limit).
+		Find(&notifications).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get notifications: %w", err)
+	}
+
+	return notifications, total, nil
+}
+
+// ListNotificationsAfter 游标分页获取通知，多返回一条用于判断是否还有下一页
+func (ns *NotificationService) ListNotificationsAfter(userID string, cursor *utils.Cursor, limit int, unreadOnly bool) ([]models.Notification, error) {
+	query := ns.db.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("is_read = ?", false)
+	}
+
+	var notifications []models.Notification
+	if err := utils.ApplyCursor(query, "notifications", cursor, limit).Find(&notifications).Error; err != nil {
+		return nil, fmt.Errorf("failed to get notifications: %w", err)
+	}
+	return notifications, nil
+}
+
+// MarkAsRead 将单条通知标记为已读
+func (ns *NotificationService) MarkAsRead(ctx context.Context, userID, notificationID string) error {
+	result := ns.db.Model(&models.Notification{}).
+		Where("id = ? AND user_id = ?", notificationID, userID).
+		Updates(map[string]interface{}{
+			"is_read": true,
+			"read_at": time.Now(),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to mark notification as read: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotificationNotFound
+	}
+	ns.clearUnreadCache(ctx, userID)
+	return nil
+}
+
+// MarkAllAsRead 将用户的未读通知全部标记为已读，notifType 非空时只标记该类型
+func (ns *NotificationService) MarkAllAsRead(ctx context.Context, userID, notifType string) (int64, error) {
+	query := ns.db.Model(&models.Notification{}).Where("user_id = ? AND is_read = ?", userID, false)
+	if notifType != "" {
+		query = query.Where("type = ?", notifType)
+	}
+
+	result := query.Updates(map[string]interface{}{
+		"is_read": true,
+		"read_at": time.Now(),
+	})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to mark notifications as read: %w", result.Error)
+	}
+	ns.clearUnreadCache(ctx, userID)
+	return result.RowsAffected, nil
+}
+
+// DeleteNotification 删除单条通知
+func (ns *NotificationService) DeleteNotification(ctx context.Context, userID, notificationID string) error {
+	result := ns.db.Where("id = ? AND user_id = ?", notificationID, userID).Delete(&models.Notification{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete notification: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotificationNotFound
+	}
+	ns.clearUnreadCache(ctx, userID)
+	return nil
+}
+
+// DeleteReadNotifications 清空用户的已读通知，notifType 非空时只删除该类型
+func (ns *NotificationService) DeleteReadNotifications(userID, notifType string) (int64, error) {
+	query := ns.db.Where("user_id = ? AND is_read = ?", userID, true)
+	if notifType != "" {
+		query = query.Where("type = ?", notifType)
+	}
+
+	result := query.Delete(&models.Notification{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete notifications: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// GetUnreadCount 获取未读通知数（按类型分组），用于通知角标
+// 查询走 (user_id, is_read) 索引，结果缓存在Redis中
+func (ns *NotificationService) GetUnreadCount(ctx context.Context, userID string) (*UnreadCount, error) {
+	cacheKey := unreadCountCacheKey(userID)
+	if ns.redisClient != nil {
+		if cached, err := ns.redisClient.Get(ctx, cacheKey).Result(); err == nil {
+			var count UnreadCount
+			if json.Unmarshal([]byte(cached), &count) == nil {
+				return &count, nil
+			}
+		}
+	}
+
+	var rows []struct {
+		Type  string
+		Count int64
+	}
+	if err := ns.db.WithContext(ctx).Model(&models.Notification{}).
+		Select("type, COUNT(*) AS count").
+		Where("user_id = ? AND is_read = ?", userID, false).
+		Group("type").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+
+	count := &UnreadCount{ByType: make(map[string]int64, len(rows))}
+	for _, row := range rows {
+		count.ByType[row.Type] = row.Count
+		count.Total += row.Count
+	}
+
+	if ns.redisClient != nil {
+		data, _ := json.Marshal(count)
+		ns.redisClient.Set(ctx, cacheKey, data, unreadCountCacheTTL)
+	}
+	return count, nil
+}
+
+// clearUnreadCache 清除未读数缓存
+func (ns *NotificationService) clearUnreadCache(ctx context.Context, userID string) {
+	if ns.redisClient != nil {
+		ns.redisClient.Del(ctx, unreadCountCacheKey(userID))
+	}
+}
+
+// unreadCountCacheKey 未读数缓存key
+func unreadCountCacheKey(userID string) string {
+	return "notify:unread:" + userID
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+	"weoucbookcycle_go/models"
+)
+
+const (
+	affinityCacheTTL      = 10 * time.Minute
+	affinityHistoryLimit  = 50
+	affinityPurchaseLimit = 20
+	// 购买比浏览更能代表兴趣
+	affinityPurchaseWeight = 2.0
+	// 个性化分数在重排时的权重（原始排序分数范围为0-1）
+	defaultBoostWeight = 0.5
+)
+
+// PersonalizeBooks 根据用户最近浏览/购买的分类对搜索结果重排
+// 未登录或关闭了个性化搜索的用户保持原始顺序，返回是否进行了重排
+func (uss *UserSettingsService) PersonalizeBooks(ctx context.Context, userID string, books []models.Book) bool {
+	if userID == "" || len(books) < 2 {
+		return false
+	}
+
+	affinity := uss.categoryAffinity(ctx, userID)
+	if len(affinity) == 0 {
+		return false
+	}
+
+	rerankByAffinity(books, affinity, defaultBoostWeight)
+	return true
+}
+
+// InvalidateAffinity 清除用户的分类偏好缓存
+func (uss *UserSettingsService) InvalidateAffinity(ctx context.Context, userID string) {
+	if uss.redisClient == nil {
+		return
+	}
+	uss.redisClient.Del(ctx, affinityCacheKey(userID))
+}
+
+// categoryAffinity 计算用户对各分类的偏好（0-1），结果缓存在Redis中
+func (uss *UserSettingsService) categoryAffinity(ctx context.Context, userID string) map[string]float64 {
+	cacheKey := affinityCacheKey(userID)
+	if uss.redisClient != nil {
+		if cached, err := uss.redisClient.Get(ctx, cacheKey).Result(); err == nil {
+			var affinity map[string]float64
+			if json.Unmarshal([]byte(cached), &affinity) == nil {
+				return affinity
+			}
+		}
+	}
+
+	affinity := map[string]float64{}
+
+	// 关闭个性化搜索的用户缓存空结果，避免每次搜索都查询设置
+	settings, err := uss.GetSettings(userID)
+	if err == nil && settings.PersonalizedSearch {
+		affinity = uss.computeCategoryAffinity(ctx, userID)
+	}
+
+	if uss.redisClient != nil {
+		data, _ := json.Marshal(affinity)
+		uss.redisClient.Set(ctx, cacheKey, data, affinityCacheTTL)
+	}
+
+	return affinity
+}
+
+// computeCategoryAffinity 基于浏览历史和购买记录统计分类偏好
+func (uss *UserSettingsService) computeCategoryAffinity(ctx context.Context, userID string) map[string]float64 {
+	scores := map[string]float64{}
+
+	// 1. 浏览历史（越新权重越高）
+	if uss.redisClient != nil {
+		historyKey := fmt.Sprintf("history:view:%s", userID)
+		viewed, _ := uss.redisClient.LRange(ctx, historyKey, 0, affinityHistoryLimit-1).Result()
+		if len(viewed) > 0 {
+			var books []models.Book
+			uss.db.WithContext(ctx).Select("id", "category").Where("id IN ?", viewed).Find(&books)
+
+			categories := make(map[string]string, len(books))
+			for _, b := range books {
+				categories[b.ID] = b.Category
+			}
+			for i, bookID := range viewed {
+				if category := categories[bookID]; category != "" {
+					scores[category] += 1.0 - float64(i)/float64(len(viewed))
+				}
+			}
+		}
+	}
+
+	// 2. 购买记录
+	var purchased []string
+	uss.db.WithContext(ctx).Model(&models.Listing{}).
+		Joins("JOIN books ON listings.book_id = books.id").
+		Where("listings.buyer_id = ? AND listings.status = ?", userID, "sold").
+		Order("listings.updated_at DESC").
+		Limit(affinityPurchaseLimit).
+		Pluck("books.category", &purchased)
+	for _, category := range purchased {
+		if category != "" {
+			scores[category] += affinityPurchaseWeight
+		}
+	}
+
+	// 3. 归一化到0-1
+	var maxScore float64
+	for _, s := range scores {
+		if s > maxScore {
+			maxScore = s
+		}
+	}
+	if maxScore > 0 {
+		for category, s := range scores {
+			scores[category] = s / maxScore
+		}
+	}
+
+	return scores
+}
+
+// rerankByAffinity 在原始排序基础上按分类偏好加权重排
+func rerankByAffinity(books []models.Book, affinity map[string]float64, weight float64) {
+	n := len(books)
+	scores := make(map[string]float64, n)
+	for i, b := range books {
+		base := 1.0 - float64(i)/float64(n)
+		scores[b.ID] = base + weight*affinity[b.Category]
+	}
+
+	sort.SliceStable(books, func(i, j int) bool {
+		return scores[books[i].ID] > scores[books[j].ID]
+	})
+}
+
+// affinityCacheKey 用户分类偏好缓存key
+func affinityCacheKey(userID string) string {
+	return fmt.Sprintf("search:affinity:%s", userID)
+}
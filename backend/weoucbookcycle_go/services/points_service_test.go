@@ -0,0 +1,68 @@
+package services
+
+import (
+	"testing"
+	"time"
+	"weoucbookcycle_go/models"
+)
+
+func TestSalePoints(t *testing.T) {
+	earnings := salePoints(map[string]interface{}{"listing_id": "l1", "seller_id": "s1", "buyer_id": "b1"})
+	if len(earnings) != 2 {
+		t.Fatalf("got %d earnings, want 2", len(earnings))
+	}
+	if e := earnings[0]; e.UserID != "s1" || e.Reason != models.PointsSale || e.CounterpartyID != "b1" || e.Amount != pointsForSale {
+		t.Errorf("seller earning = %+v", e)
+	}
+	if e := earnings[1]; e.UserID != "b1" || e.Reason != models.PointsPurchase || e.CounterpartyID != "s1" || e.Amount != pointsForPurchase {
+		t.Errorf("buyer earning = %+v", e)
+	}
+
+	donation := salePoints(map[string]interface{}{"listing_id": "l2", "seller_id": "s1", "buyer_id": "b1", "donation": "true"})
+	if len(donation) != 1 || donation[0].UserID != "s1" || donation[0].Reason != models.PointsDonation || donation[0].Amount != pointsForDonation {
+		t.Errorf("donation earnings = %+v", donation)
+	}
+
+	// 没有买家或自己买自己的发布不计分
+	if got := salePoints(map[string]interface{}{"listing_id": "l1", "seller_id": "s1"}); got != nil {
+		t.Errorf("no buyer: got %+v", got)
+	}
+	if got := salePoints(map[string]interface{}{"listing_id": "l1", "seller_id": "s1", "buyer_id": "s1"}); got != nil {
+		t.Errorf("self purchase: got %+v", got)
+	}
+}
+
+func TestVerifyBuyerRejectsSelfAndMissingBuyer(t *testing.T) {
+	// 不需要查询数据库就能拒绝的情况
+	if err := VerifyBuyer(nil, "s1", "", true); err != ErrBuyerNotVerified {
+		t.Errorf("no buyer: err = %v", err)
+	}
+	if err := VerifyBuyer(nil, "s1", "s1", true); err != ErrBuyerNotVerified {
+		t.Errorf("self purchase: err = %v", err)
+	}
+}
+
+func TestCappedPoints(t *testing.T) {
+	cases := []struct {
+		amount, earned, cap, want int
+	}{
+		{10, 0, 100, 10},
+		{10, 95, 100, 5},
+		{10, 100, 100, 0},
+		{10, 120, 100, 0},
+		{10, 0, 0, 0},
+	}
+	for _, tc := range cases {
+		if got := cappedPoints(tc.amount, tc.earned, tc.cap); got != tc.want {
+			t.Errorf("cappedPoints(%d, %d, %d) = %d, want %d", tc.amount, tc.earned, tc.cap, got, tc.want)
+		}
+	}
+}
+
+func TestStartOfDay(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	got := startOfDay(time.Date(2024, 9, 1, 23, 59, 0, 0, loc))
+	if want := time.Date(2024, 9, 1, 0, 0, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("startOfDay = %v, want %v", got, want)
+	}
+}
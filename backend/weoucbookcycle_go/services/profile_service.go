@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNoProfileChanges = utils.NewError(http.StatusBadRequest, "No fields to update")
+	ErrInvalidPhone     = utils.NewError(http.StatusBadRequest, "invalid phone number")
+)
+
+// ProfileRequest 更新用户资料请求，未填写的字段保持不变
+type ProfileRequest struct {
+	Username string `json:"username" binding:"omitempty,username"`
+	Avatar   string `json:"avatar" binding:"omitempty,max=255"`
+	Phone    string `json:"phone" binding:"omitempty"`
+	Bio      string `json:"bio" binding:"omitempty,max=500"`
+}
+
+// ProfileService 用户资料更新：校验和清洗字段，在一个事务中写入，提交后清除缓存
+type ProfileService struct {
+	db              *gorm.DB
+	redisClient     *redis.Client
+	usernameService *UsernameService
+}
+
+// NewProfileService 创建用户资料服务实例
+func NewProfileService(deps Deps, usernameService *UsernameService) *ProfileService {
+	return &ProfileService{
+		db:              deps.DB,
+		redisClient:     deps.Redis,
+		usernameService: usernameService,
+	}
+}
+
+// Update 更新用户资料并返回更新后的用户
+// 修改用户名与单独修改时规则相同（唯一性和冷却期），任一字段校验或写入失败时全部不生效
+func (ps *ProfileService) Update(ctx context.Context, userID string, req *ProfileRequest) (*models.User, error) {
+	updates, err := profileUpdates(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(updates) == 0 && req.Username == "" {
+		return nil, ErrNoProfileChanges
+	}
+
+	var user *models.User
+	err = WithTx(ctx, ps.db, func(ctx context.Context, tx *gorm.DB) error {
+		if req.Username != "" {
+			// 用户名与当前相同时忽略
+			if _, err := ps.usernameService.Change(ctx, userID, req.Username); err != nil && !errors.Is(err, ErrUsernameUnchanged) {
+				return err
+			}
+		}
+		var err error
+		user, err = ps.apply(ctx, tx, userID, updates)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// SetAvatar 设置头像
+func (ps *ProfileService) SetAvatar(ctx context.Context, userID, avatar string) (*models.User, error) {
+	var user *models.User
+	err := WithTx(ctx, ps.db, func(ctx context.Context, tx *gorm.DB) error {
+		var err error
+		user, err = ps.apply(ctx, tx, userID, map[string]interface{}{"avatar": avatar})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// apply 写入资料字段并重新加载用户，提交后清除公开资料缓存
+func (ps *ProfileService) apply(ctx context.Context, tx *gorm.DB, userID string, updates map[string]interface{}) (*models.User, error) {
+	if len(updates) > 0 {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("failed to update profile: %w", err)
+		}
+	}
+
+	var user models.User
+	if err := tx.First(&user, "id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+
+	AfterCommit(ctx, func() {
+		if ps.redisClient != nil {
+			ps.redisClient.Del(context.WithoutCancel(ctx), "user:"+userID)
+		}
+	})
+	return &user, nil
+}
+
+// profileUpdates 校验并清洗请求中的资料字段，返回要写入的列
+func profileUpdates(req *ProfileRequest) (map[string]interface{}, error) {
+	updates := make(map[string]interface{})
+	if avatar := strings.TrimSpace(req.Avatar); avatar != "" {
+		updates["avatar"] = avatar
+	}
+	if req.Phone != "" {
+		phone := normalizePhone(req.Phone)
+		if !utils.ValidatePhone(phone) {
+			return nil, ErrInvalidPhone
+		}
+		updates["phone"] = phone
+	}
+	if bio := strings.TrimSpace(utils.SanitizeString(req.Bio)); bio != "" {
+		updates["bio"] = bio
+	}
+	return updates, nil
+}
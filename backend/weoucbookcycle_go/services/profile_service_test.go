@@ -0,0 +1,37 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestProfileUpdates(t *testing.T) {
+	updates, err := profileUpdates(&ProfileRequest{
+		Avatar: " https://cdn.example.com/a.webp ",
+		Phone:  "+86 138-0013-8000",
+		Bio:    "  <b>二手教材</b>，东区面交<script>alert(1)</script> ",
+	})
+	if err != nil {
+		t.Fatalf("profileUpdates: %v", err)
+	}
+	want := map[string]string{
+		"avatar": "https://cdn.example.com/a.webp",
+		"phone":  "13800138000",
+		"bio":    "二手教材，东区面交alert(1)",
+	}
+	for field, value := range want {
+		if updates[field] != value {
+			t.Errorf("%s = %q, want %q", field, updates[field], value)
+		}
+	}
+
+	// 只有标签的简介清洗后为空，不写入
+	updates, err = profileUpdates(&ProfileRequest{Bio: "<p></p>"})
+	if err != nil || len(updates) != 0 {
+		t.Errorf("empty bio: updates %v, err %v", updates, err)
+	}
+
+	if _, err := profileUpdates(&ProfileRequest{Phone: "12345"}); !errors.Is(err, ErrInvalidPhone) {
+		t.Errorf("invalid phone: err %v, want ErrInvalidPhone", err)
+	}
+}
@@ -0,0 +1,338 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"weoucbookcycle_go/config"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	pushStream      = "push_notifications"
+	pushGroup       = "push_dispatchers"
+	pushSendTimeout = 15 * time.Second
+	maxUserDevices  = 10
+)
+
+// ErrUnsupportedPlatform 不支持的推送平台
+var ErrUnsupportedPlatform = utils.NewError(http.StatusBadRequest, "platform must be ios or android")
+
+// pushSenders 各平台推送实现，由 PushService.Start 初始化
+var pushSenders map[string]utils.PushSender
+
+// wechatSender 微信小程序订阅消息，未配置模板时为nil
+var wechatSender *utils.WeChatSubscribeSender
+
+// webPushSender 浏览器推送，未配置 VAPID 密钥时为nil
+var webPushSender *utils.WebPushSender
+
+// PushService 移动端推送服务
+type PushService struct {
+	db                  *gorm.DB
+	redisClient         *redis.Client
+	userSettingsService *UserSettingsService
+	// 推送渠道配置，Start 时据此创建发送器
+	pushConfig   *config.PushConfig
+	wechatConfig *config.WeChatConfig
+}
+
+// NewPushService 创建推送服务实例
+func NewPushService(deps Deps, userSettingsService *UserSettingsService) *PushService {
+	return &PushService{
+		db:                  deps.DB,
+		redisClient:         deps.Redis,
+		userSettingsService: userSettingsService,
+		pushConfig:          &deps.Config.Push,
+		wechatConfig:        &deps.Config.WeChat,
+	}
+}
+
+// RegisterDevice 登记设备令牌，同一令牌换账号登录时归属新用户
+func (ps *PushService) RegisterDevice(userID, platform, token, appVersion string) (*models.DeviceToken, error) {
+	if platform != models.PushPlatformIOS && platform != models.PushPlatformAndroid {
+		return nil, ErrUnsupportedPlatform
+	}
+	if token == "" {
+		return nil, errors.New("token is required")
+	}
+
+	var device models.DeviceToken
+	err := ps.db.Where("token = ?", token).First(&device).Error
+	if err == nil {
+		device.UserID = userID
+		device.Platform = platform
+		device.AppVersion = appVersion
+		device.LastSeenAt = time.Now()
+		if err := ps.db.Save(&device).Error; err != nil {
+			return nil, fmt.Errorf("failed to update device: %w", err)
+		}
+		return &device, nil
+	}
+
+	device = models.DeviceToken{
+		UserID:     userID,
+		Platform:   platform,
+		Token:      token,
+		AppVersion: appVersion,
+		LastSeenAt: time.Now(),
+	}
+	if err := ps.db.Create(&device).Error; err != nil {
+		return nil, fmt.Errorf("failed to register device: %w", err)
+	}
+
+	// 只保留最近活跃的设备
+	var stale []string
+	ps.db.Model(&models.DeviceToken{}).
+		Where("user_id = ?", userID).
+		Order("last_seen_at DESC").
+		Offset(maxUserDevices).
+		Pluck("id", &stale)
+	if len(stale) > 0 {
+		ps.db.Where("id IN ?", stale).Delete(&models.DeviceToken{})
+	}
+
+	return &device, nil
+}
+
+// UnregisterDevice 注销设备令牌（退出登录时调用）
+func (ps *PushService) UnregisterDevice(userID, token string) error {
+	return ps.db.Where("user_id = ? AND token = ?", userID, token).Delete(&models.DeviceToken{}).Error
+}
+
+// WebPushPublicKey 返回 VAPID 公钥，未启用 Web Push 时为空
+func (ps *PushService) WebPushPublicKey() string {
+	if webPushSender == nil {
+		return ""
+	}
+	return webPushSender.PublicKey()
+}
+
+// SubscribeWebPush 保存浏览器推送订阅，同一 endpoint 换账号登录时归属新用户
+func (ps *PushService) SubscribeWebPush(userID, endpoint, p256dh, auth, userAgent string) (*models.WebPushSubscription, error) {
+	if !strings.HasPrefix(endpoint, "https://") {
+		return nil, errors.New("endpoint must be an https url")
+	}
+
+	var sub models.WebPushSubscription
+	if err := ps.db.Where("endpoint = ?", endpoint).First(&sub).Error; err == nil {
+		sub.UserID = userID
+		sub.P256dh = p256dh
+		sub.Auth = auth
+		sub.UserAgent = truncateRunes(userAgent, 250)
+		if err := ps.db.Save(&sub).Error; err != nil {
+			return nil, fmt.Errorf("failed to update subscription: %w", err)
+		}
+		return &sub, nil
+	}
+
+	sub = models.WebPushSubscription{
+		UserID:    userID,
+		Endpoint:  endpoint,
+		P256dh:    p256dh,
+		Auth:      auth,
+		UserAgent: truncateRunes(userAgent, 250),
+	}
+	if err := ps.db.Create(&sub).Error; err != nil {
+		return nil, fmt.Errorf("failed to save subscription: %w", err)
+	}
+	return &sub, nil
+}
+
+// UnsubscribeWebPush 删除浏览器推送订阅
+func (ps *PushService) UnsubscribeWebPush(userID, endpoint string) error {
+	return ps.db.Where("user_id = ? AND endpoint = ?", userID, endpoint).Delete(&models.WebPushSubscription{}).Error
+}
+
+// Enqueue 把推送加入发送队列，由后台按用户在线状态决定是否发送
+// notifType 为通知类型（chat_message、listing_status 等），会作为 type 透传给客户端
+func (ps *PushService) Enqueue(ctx context.Context, userID, notifType, title, body string, data map[string]string) {
+	if userID == "" || !pushEnabled() {
+		return
+	}
+
+	payload, _ := json.Marshal(data)
+	values := map[string]interface{}{
+		"user_id": userID,
+		"type":    notifType,
+		"title":   title,
+		"body":    body,
+		"data":    string(payload),
+	}
+
+	if ps.redisClient == nil {
+		go ps.dispatch(context.WithoutCancel(ctx), values)
+		return
+	}
+
+	err := ps.redisClient.XAdd(ctx, &redis.XAddArgs{
+		Stream: pushStream,
+		MaxLen: 100000,
+		Approx: true,
+		Values: values,
+	}).Err()
+	if err != nil {
+		log.Printf("push: failed to enqueue for %s: %v", userID, err)
+	}
+}
+
+// listingStatusText 交易状态对应的推送文案和简短状态名
+var listingStatusText = map[string][2]string{
+	"reserved":  {"卖家已为你预留这本书", "已预留"},
+	"sold":      {"交易已完成", "已售出"},
+	"cancelled": {"卖家取消了这笔交易", "已取消"},
+	"available": {"这本书重新开放出售", "在售"},
+}
+
+// PushListingStatus 交易状态变化时推送给买家
+func (ps *PushService) PushListingStatus(ctx context.Context, buyerID, listingID, status string) {
+	text, ok := listingStatusText[status]
+	if !ok {
+		return
+	}
+	ps.Enqueue(ctx, buyerID, "listing_status", "交易状态更新", text[0], map[string]string{
+		"listing_id":   listingID,
+		"status":       status,
+		"status_label": text[1],
+	})
+}
+
+// pushEnabled 是否配置了任一推送渠道
+func pushEnabled() bool {
+	return len(pushSenders) > 0 || wechatSender != nil || webPushSender != nil
+}
+
+// Start 启动推送发送消费者
+// 需配置 FCM_CREDENTIALS_FILE、APNS_KEY_FILE、微信订阅消息模板或 VAPID 密钥，用户有活跃的 WebSocket 连接时不发送推送
+func (ps *PushService) Start(ctx context.Context) {
+	pushSenders = utils.NewPushSenders(ps.pushConfig)
+	wechatSender = utils.NewWeChatSubscribeSender(ps.wechatConfig, ps.redisClient)
+	webPushSender = utils.NewWebPushSender(ps.pushConfig)
+	if !pushEnabled() || ps.redisClient == nil {
+		return
+	}
+
+	startEventConsumer(ctx, ps.redisClient, pushGroup, []string{pushStream},
+		func(ctx context.Context, stream string, msg redis.XMessage) error {
+			ps.dispatch(ctx, msg.Values)
+			return nil
+		})
+}
+
+// dispatch 向离线用户的所有设备和微信小程序发送推送，失效的令牌会被删除
+func (ps *PushService) dispatch(ctx context.Context, values map[string]interface{}) {
+	userID := streamString(values["user_id"])
+	if userID == "" || ps.isUserConnected(ctx, userID) || isDeactivated(ctx, ps.db.WithContext(ctx), userID) {
+		return
+	}
+
+	notifType := streamString(values["type"])
+	data := map[string]string{}
+	json.Unmarshal([]byte(streamString(values["data"])), &data)
+	data["type"] = notifType
+
+	msg := &utils.PushMessage{
+		Title: streamString(values["title"]),
+		Body:  streamString(values["body"]),
+		Data:  data,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pushSendTimeout)
+	defer cancel()
+
+	// 按用户的通知渠道偏好发送
+	settings, err := ps.userSettingsService.GetSettings(userID)
+	if err != nil {
+		settings = models.DefaultUserSettings(userID)
+	}
+
+	if settings.PushWeChat && wechatSender != nil && wechatSender.Supports(notifType) {
+		ps.sendWeChatSubscribe(ctx, userID, notifType, msg)
+	}
+	if settings.PushWeb && webPushSender != nil {
+		ps.sendWebPush(ctx, userID, msg)
+	}
+	if !settings.PushMobile || len(pushSenders) == 0 {
+		return
+	}
+
+	var devices []models.DeviceToken
+	if err := ps.db.WithContext(ctx).Where("user_id = ?", userID).Find(&devices).Error; err != nil {
+		return
+	}
+
+	for _, device := range devices {
+		sender, ok := pushSenders[device.Platform]
+		if !ok {
+			continue
+		}
+		err := sender.Send(ctx, device.Token, msg)
+		if errors.Is(err, utils.ErrInvalidPushToken) {
+			ps.db.WithContext(ctx).Delete(&device)
+			continue
+		}
+		if err != nil {
+			log.Printf("push: %s send to %s failed: %v", sender.Name(), userID, err)
+		}
+	}
+}
+
+// sendWeChatSubscribe 给通过小程序登录（有 openid）的用户发送订阅消息
+func (ps *PushService) sendWeChatSubscribe(ctx context.Context, userID, notifType string, msg *utils.PushMessage) {
+	var user models.User
+	if err := ps.db.Select("id", "we_chat_open_id").First(&user, "id = ?", userID).Error; err != nil || user.WeChatOpenID == "" {
+		return
+	}
+
+	// 用户未订阅或一次性订阅已用完属于正常情况
+	err := wechatSender.Send(ctx, user.WeChatOpenID, notifType, msg)
+	if err != nil && !errors.Is(err, utils.ErrWeChatNotSubscribed) {
+		log.Printf("push: wechat send to %s failed: %v", userID, err)
+	}
+}
+
+// sendWebPush 向用户的所有浏览器订阅发送推送
+func (ps *PushService) sendWebPush(ctx context.Context, userID string, msg *utils.PushMessage) {
+	var subscriptions []models.WebPushSubscription
+	if err := ps.db.Where("user_id = ?", userID).Find(&subscriptions).Error; err != nil {
+		return
+	}
+
+	for _, sub := range subscriptions {
+		err := webPushSender.Send(ctx, sub.Endpoint, sub.P256dh, sub.Auth, msg)
+		if errors.Is(err, utils.ErrInvalidPushToken) {
+			ps.db.Delete(&sub)
+			continue
+		}
+		if err != nil {
+			log.Printf("push: web push to %s failed: %v", userID, err)
+		}
+	}
+}
+
+// isUserConnected 用户是否有活跃的 WebSocket 连接（连接时写入 online:{user_id}）
+func (ps *PushService) isUserConnected(ctx context.Context, userID string) bool {
+	if ps.redisClient == nil {
+		return false
+	}
+	exists, _ := ps.redisClient.Exists(ctx, "online:"+userID).Result()
+	return exists > 0
+}
+
+// truncateRunes 截断推送内容，避免超过推送服务的负载限制
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "…"
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"weoucbookcycle_go/config"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	qrcode "github.com/skip2/go-qrcode"
+	"gorm.io/gorm"
+)
+
+// 可生成分享二维码的对象，同时也是分享链接中的路径
+const (
+	ShareTargetBook    = "books"
+	ShareTargetListing = "listings"
+)
+
+// 二维码边长（像素）
+const (
+	qrCodeDefaultSize = 256
+	qrCodeMinSize     = 128
+	qrCodeMaxSize     = 1024
+)
+
+// QRCodeService 书籍和发布的分享二维码，生成的图片保存在文件存储中重复使用
+type QRCodeService struct {
+	db        *gorm.DB
+	shareBase string
+	storage   utils.Storage
+}
+
+// NewQRCodeService 创建二维码服务实例
+func NewQRCodeService(deps Deps) *QRCodeService {
+	return &QRCodeService{
+		db:        deps.DB,
+		shareBase: shareBaseURL(deps.Config),
+		storage:   utils.GetStorage(),
+	}
+}
+
+// ShareURL 书籍或发布的分享链接
+func (qs *QRCodeService) ShareURL(target, id string) string {
+	return shareURL(qs.shareBase, target, id)
+}
+
+// QRCode 获取指向分享链接的二维码PNG，size 超出范围时取边界值
+// 已下架或卖家停用账号的书籍和发布返回404
+func (qs *QRCodeService) QRCode(ctx context.Context, target, id string, size int) ([]byte, error) {
+	if err := qs.checkVisible(ctx, target, id); err != nil {
+		return nil, err
+	}
+
+	size = qrCodeSize(size)
+	url := qs.ShareURL(target, id)
+	key := qrCodeKey(target, id, url, size)
+
+	if file, err := qs.storage.Open(ctx, key); err == nil {
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err == nil {
+			return data, nil
+		}
+	}
+
+	png, err := qrcode.Encode(url, qrcode.Medium, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate qr code: %w", err)
+	}
+	// 保存失败只影响下次是否需要重新生成
+	if _, err := qs.storage.Put(ctx, key, bytes.NewReader(png), int64(len(png)), "image/png"); err != nil {
+		log.Printf("qrcode: failed to store %s: %v", key, err)
+	}
+	return png, nil
+}
+
+// checkVisible 只为存在且卖家未停用账号的书籍和发布生成二维码
+func (qs *QRCodeService) checkVisible(ctx context.Context, target, id string) error {
+	var model interface{}
+	var notFound error
+	switch target {
+	case ShareTargetBook:
+		model, notFound = &models.Book{}, utils.NewError(http.StatusNotFound, "Book not found")
+	case ShareTargetListing:
+		model, notFound = &models.Listing{}, utils.NewError(http.StatusNotFound, "Listing not found")
+	default:
+		return fmt.Errorf("unknown share target %q", target)
+	}
+
+	var count int64
+	if err := qs.db.WithContext(ctx).Model(model).Where(target+".id = ?", id).
+		Scopes(VisibleUsers(target + ".seller_id")).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to load %s: %w", target, err)
+	}
+	if count == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// shareBaseURL 分享链接的前端地址，未配置 SHARE_BASE 时使用 API_BASE
+func shareBaseURL(cfg *config.Config) string {
+	if cfg.ShareBase != "" {
+		return cfg.ShareBase
+	}
+	return cfg.APIBase
+}
+
+// shareURL 拼接分享链接，例如 https://book.example.com/listings/{id}
+func shareURL(base, target, id string) string {
+	return strings.TrimRight(base, "/") + "/" + target + "/" + id
+}
+
+// qrCodeSize 二维码边长，未指定时使用默认值
+func qrCodeSize(size int) int {
+	switch {
+	case size <= 0:
+		return qrCodeDefaultSize
+	case size < qrCodeMinSize:
+		return qrCodeMinSize
+	case size > qrCodeMaxSize:
+		return qrCodeMaxSize
+	default:
+		return size
+	}
+}
+
+// qrCodeKey 二维码在存储中的key，包含链接的哈希，修改 SHARE_BASE 后会重新生成
+func qrCodeKey(target, id, url string, size int) string {
+	sum := sha1.Sum([]byte(url))
+	return fmt.Sprintf("qrcodes/%s/%s-%d-%s.png", target, id, size, hex.EncodeToString(sum[:4]))
+}
@@ -0,0 +1,36 @@
+package services
+
+import "testing"
+
+func TestShareURL(t *testing.T) {
+	cases := map[string]string{
+		"https://book.example.com":  "https://book.example.com/listings/l-1",
+		"https://book.example.com/": "https://book.example.com/listings/l-1",
+	}
+	for base, want := range cases {
+		if got := shareURL(base, ShareTargetListing, "l-1"); got != want {
+			t.Errorf("shareURL(%q) = %q, want %q", base, got, want)
+		}
+	}
+}
+
+func TestQRCodeSize(t *testing.T) {
+	cases := map[int]int{0: 256, -5: 256, 64: 128, 300: 300, 4096: 1024}
+	for size, want := range cases {
+		if got := qrCodeSize(size); got != want {
+			t.Errorf("qrCodeSize(%d) = %d, want %d", size, got, want)
+		}
+	}
+}
+
+// 修改分享地址后使用新的key，不会读到旧链接的二维码
+func TestQRCodeKey(t *testing.T) {
+	a := qrCodeKey(ShareTargetListing, "l-1", "https://a.example.com/listings/l-1", 256)
+	b := qrCodeKey(ShareTargetListing, "l-1", "https://b.example.com/listings/l-1", 256)
+	if a == b {
+		t.Errorf("qrCodeKey ignored the share URL: %q", a)
+	}
+	if got := qrCodeKey(ShareTargetListing, "l-1", "https://a.example.com/listings/l-1", 256); got != a {
+		t.Errorf("qrCodeKey is not stable: %q != %q", got, a)
+	}
+}
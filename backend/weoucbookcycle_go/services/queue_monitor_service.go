@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"weoucbookcycle_go/utils"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrMonitorUnavailable Redis 未连接时无法读取流信息
+var ErrMonitorUnavailable = utils.NewError(http.StatusServiceUnavailable, "redis is not available")
+
+// StreamGroupStat 消费组的消费进度
+type StreamGroupStat struct {
+	Name            string `json:"name"`
+	Consumers       int64  `json:"consumers"`
+	Pending         int64  `json:"pending"`           // 已投递未确认
+	Lag             int64  `json:"lag"`               // 尚未投递给该组的消息数，-1 表示无法计算（如流被裁剪过）
+	LastDeliveredID string `json:"last_delivered_id"` // 组最后投递的消息ID
+	// OldestPendingSeconds 最早一条未确认消息的等待时长，持续增长说明worker卡住
+	OldestPendingSeconds int64 `json:"oldest_pending_seconds"`
+}
+
+// StreamStat Redis流的长度和消费组情况，没有消费组的流只是审计记录
+type StreamStat struct {
+	Name   string            `json:"name"`
+	Length int64             `json:"length"`
+	Groups []StreamGroupStat `json:"groups"`
+}
+
+// QueueMonitorReport 队列监控快照
+type QueueMonitorReport struct {
+	Streams   []StreamStat         `json:"streams"`
+	Jobs      []utils.JobQueueStat `json:"jobs"`   // 持久化后台任务队列
+	Queues    []utils.QueueStat    `json:"queues"` // 当前实例的进程内队列
+	CheckedAt time.Time            `json:"checked_at"`
+}
+
+// QueueMonitorService 队列监控服务
+type QueueMonitorService struct {
+	redisClient *redis.Client
+}
+
+// NewQueueMonitorService 创建队列监控服务实例
+func NewQueueMonitorService(deps Deps) *QueueMonitorService {
+	return &QueueMonitorService{
+		redisClient: deps.Redis,
+	}
+}
+
+// monitoredStreams 返回需要监控的Redis流
+func monitoredStreams() []string {
+	streams := []string{"access_logs", "login_logs", searchEventsStream, imageModerationStream, pushStream}
+	streams = append(streams, notificationEventStreams...)
+	streams = append(streams, securityEventStreams...)
+	slices.Sort(streams)
+	return slices.Compact(streams)
+}
+
+// Snapshot 读取所有Redis流、后台任务队列和进程内队列的积压情况
+func (qs *QueueMonitorService) Snapshot(ctx context.Context) (*QueueMonitorReport, error) {
+	report := &QueueMonitorReport{
+		Queues:    utils.QueueStats(),
+		CheckedAt: time.Now(),
+	}
+	if qs.redisClient == nil {
+		return report, ErrMonitorUnavailable
+	}
+
+	for _, stream := range monitoredStreams() {
+		stat, err := qs.streamStat(ctx, stream)
+		if err != nil {
+			return report, err
+		}
+		report.Streams = append(report.Streams, *stat)
+	}
+
+	jobs, err := utils.JobQueueStats()
+	if err != nil {
+		return report, err
+	}
+	report.Jobs = jobs
+	return report, nil
+}
+
+// streamStat 读取单个流的长度和消费组进度
+func (qs *QueueMonitorService) streamStat(ctx context.Context, stream string) (*StreamStat, error) {
+	length, err := qs.redisClient.XLen(ctx, stream).Result()
+	if err != nil {
+		return nil, err
+	}
+	stat := &StreamStat{Name: stream, Length: length, Groups: []StreamGroupStat{}}
+	if length == 0 {
+		// 流不存在时 XINFO GROUPS 会报错
+		if exists, _ := qs.redisClient.Exists(ctx, stream).Result(); exists == 0 {
+			return stat, nil
+		}
+	}
+
+	groups, err := qs.redisClient.XInfoGroups(ctx, stream).Result()
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range groups {
+		group := StreamGroupStat{
+			Name:            g.Name,
+			Consumers:       g.Consumers,
+			Pending:         g.Pending,
+			Lag:             g.Lag,
+			LastDeliveredID: g.LastDeliveredID,
+		}
+		if g.Pending > 0 {
+			if pending, err := qs.redisClient.XPending(ctx, stream, g.Name).Result(); err == nil {
+				if ts, ok := streamIDTime(pending.Lower); ok {
+					group.OldestPendingSeconds = int64(time.Since(ts).Seconds())
+				}
+			}
+		}
+		stat.Groups = append(stat.Groups, group)
+	}
+	return stat, nil
+}
+
+// streamIDTime 解析流消息ID中的毫秒时间戳
+func streamIDTime(id string) (time.Time, bool) {
+	ms, _, _ := strings.Cut(id, "-")
+	n, err := strconv.ParseInt(ms, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(n), true
+}
@@ -0,0 +1,351 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	referralGroup = "referrals"
+
+	// 被邀请人完成首笔交易后双方获得的积分，不计入每日上限
+	referralInviterPoints = 30
+	referralInviteePoints = 20
+
+	// referralFraudWindow 防刷检查回看的时间范围
+	referralFraudWindow = 30 * 24 * time.Hour
+	// referralSameIPLimit 同一邀请人的被邀请人中最多几人使用相同IP（宿舍共用网络）
+	referralSameIPLimit = 2
+
+	// 邀请码字符集去掉了容易混淆的 0/O、1/I/L
+	referralCodeAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+	referralCodeLength   = 8
+)
+
+var ErrInvalidInviteCode = utils.NewError(http.StatusBadRequest, "invalid invite code")
+
+// referralEventStreams 邀请奖励消费的领域事件流
+var referralEventStreams = []string{"listing_events"}
+
+// ReferralStats 我的邀请码和邀请情况
+type ReferralStats struct {
+	Code         string `json:"code"`
+	Invited      int64  `json:"invited"`
+	Pending      int64  `json:"pending"`
+	Rewarded     int64  `json:"rewarded"`
+	Rejected     int64  `json:"rejected"`
+	PointsEarned int    `json:"points_earned"`
+	InviterBonus int    `json:"inviter_bonus"`
+	InviteeBonus int    `json:"invitee_bonus"`
+}
+
+// ReferralInvitee 我邀请的用户
+type ReferralInvitee struct {
+	ID         string            `json:"id"`
+	Invitee    models.PublicUser `json:"invitee"`
+	Status     string            `json:"status"`
+	RewardedAt *time.Time        `json:"rewarded_at,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+// ReferralService 邀请码：注册时记录邀请关系，被邀请人完成首笔交易后为双方发放积分
+// 与邀请人或其他被邀请人共用IP、设备重复使用的邀请在注册时标记为 rejected，不发放积分
+type ReferralService struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+}
+
+// NewReferralService 创建邀请服务实例
+func NewReferralService(deps Deps) *ReferralService {
+	return &ReferralService{
+		db:          deps.DB,
+		redisClient: deps.Redis,
+	}
+}
+
+// Start 启动邀请奖励消费者，奖励通过邀请状态的条件更新保证只发放一次
+func (rs *ReferralService) Start(ctx context.Context) {
+	startEventConsumer(ctx, rs.redisClient, referralGroup, referralEventStreams, rs.handleEvent)
+}
+
+// Stats 获取我的邀请码（没有时生成）和邀请情况
+func (rs *ReferralService) Stats(ctx context.Context, userID string) (*ReferralStats, error) {
+	code, err := rs.code(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	stats := &ReferralStats{Code: code, InviterBonus: referralInviterPoints, InviteeBonus: referralInviteePoints}
+
+	var counts []struct {
+		Status string
+		Count  int64
+	}
+	if err := rs.db.WithContext(ctx).Model(&models.Referral{}).Select("status, COUNT(*) AS count").
+		Where("inviter_id = ?", userID).Group("status").Scan(&counts).Error; err != nil {
+		return nil, fmt.Errorf("failed to count referrals: %w", err)
+	}
+	for _, c := range counts {
+		stats.Invited += c.Count
+		switch c.Status {
+		case models.ReferralPending:
+			stats.Pending = c.Count
+		case models.ReferralRewarded:
+			stats.Rewarded = c.Count
+		case models.ReferralRejected:
+			stats.Rejected = c.Count
+		}
+	}
+
+	if err := rs.db.WithContext(ctx).Model(&models.PointsEntry{}).Select("COALESCE(SUM(amount), 0)").
+		Where("user_id = ? AND reason = ?", userID, models.PointsReferral).
+		Scan(&stats.PointsEarned).Error; err != nil {
+		return nil, fmt.Errorf("failed to sum referral points: %w", err)
+	}
+	return stats, nil
+}
+
+// Invitees 分页获取我邀请的用户，最近的在前
+func (rs *ReferralService) Invitees(ctx context.Context, userID string, page, limit int) ([]ReferralInvitee, int64, error) {
+	query := rs.db.WithContext(ctx).Model(&models.Referral{}).Where("inviter_id = ?", userID)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count referrals: %w", err)
+	}
+
+	var referrals []models.Referral
+	if err := query.Preload("Invitee").Order("created_at DESC").
+		Offset(utils.PageOffset(page, limit)).Limit(limit).Find(&referrals).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list referrals: %w", err)
+	}
+	invitees := make([]ReferralInvitee, len(referrals))
+	for i, r := range referrals {
+		invitees[i] = ReferralInvitee{
+			ID:         r.ID,
+			Invitee:    r.Invitee.Public(),
+			Status:     r.Status,
+			RewardedAt: r.RewardedAt,
+			CreatedAt:  r.CreatedAt,
+		}
+	}
+	return invitees, total, nil
+}
+
+// code 获取用户的邀请码，没有时生成；生成的码与已有的重复时重试
+func (rs *ReferralService) code(ctx context.Context, userID string) (string, error) {
+	db := rs.db.WithContext(ctx)
+	var existing models.ReferralCode
+	if err := db.First(&existing, "user_id = ?", userID).Error; err == nil {
+		return existing.Code, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", fmt.Errorf("failed to load invite code: %w", err)
+	}
+
+	for range 5 {
+		code := &models.ReferralCode{UserID: userID, Code: generateInviteCode()}
+		result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(code)
+		if result.Error != nil {
+			return "", fmt.Errorf("failed to create invite code: %w", result.Error)
+		}
+		if result.RowsAffected == 1 {
+			return code.Code, nil
+		}
+		// 并发请求已为该用户生成了邀请码
+		if err := db.First(&existing, "user_id = ?", userID).Error; err == nil {
+			return existing.Code, nil
+		}
+	}
+	return "", errors.New("failed to generate a unique invite code")
+}
+
+// ==================== 注册和奖励 ====================
+
+// resolveInviteCode 查找邀请码对应的邀请人，code 为空时返回空字符串
+func resolveInviteCode(ctx context.Context, db *gorm.DB, code string) (string, error) {
+	code = normalizeInviteCode(code)
+	if code == "" {
+		return "", nil
+	}
+	var rc models.ReferralCode
+	if err := db.WithContext(ctx).
+		Where("code = ? AND EXISTS (SELECT 1 FROM users WHERE users.id = referral_codes.user_id AND users.status = ?)", code, models.UserStatusActive).
+		First(&rc).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrInvalidInviteCode
+		}
+		return "", fmt.Errorf("failed to load invite code: %w", err)
+	}
+	return rc.UserID, nil
+}
+
+// createReferral 在注册事务中记录邀请关系，未通过防刷检查的记录为 rejected
+func createReferral(tx *gorm.DB, inviterID, inviteeID, code, ip, deviceID string) error {
+	reason, err := referralRejectReason(tx, inviterID, ip, deviceID)
+	if err != nil {
+		return err
+	}
+	referral := &models.Referral{
+		InviterID:    inviterID,
+		InviteeID:    inviteeID,
+		Code:         normalizeInviteCode(code),
+		Status:       models.ReferralPending,
+		RejectReason: reason,
+		RegisterIP:   ip,
+		DeviceID:     deviceID,
+	}
+	if reason != "" {
+		referral.Status = models.ReferralRejected
+	}
+	if err := tx.Create(referral).Error; err != nil {
+		return fmt.Errorf("failed to record referral: %w", err)
+	}
+	return nil
+}
+
+// referralRejectReason 防刷检查，通过时返回空字符串：
+// 注册IP是邀请人近期登录或本人注册时用过的、同一邀请人已有 referralSameIPLimit 个被邀请人使用该IP、
+// 设备已用于其他邀请注册
+func referralRejectReason(tx *gorm.DB, inviterID, ip, deviceID string) (string, error) {
+	since := time.Now().Add(-referralFraudWindow)
+	count := func(query *gorm.DB) (int64, error) {
+		var n int64
+		if err := query.Count(&n).Error; err != nil {
+			return 0, fmt.Errorf("failed to check referral: %w", err)
+		}
+		return n, nil
+	}
+
+	if deviceID != "" {
+		n, err := count(tx.Model(&models.Referral{}).Where("device_id = ?", deviceID))
+		if err != nil || n > 0 {
+			return models.ReferralRejectDeviceReuse, err
+		}
+	}
+	if ip == "" {
+		return "", nil
+	}
+
+	n, err := count(tx.Model(&models.ReportLogin{}).Where("user_id = ? AND ip = ? AND occurred_at >= ?", inviterID, ip, since))
+	if err != nil || n > 0 {
+		return models.ReferralRejectIPReuse, err
+	}
+	n, err = count(tx.Model(&models.Referral{}).Where("invitee_id = ? AND register_ip = ?", inviterID, ip))
+	if err != nil || n > 0 {
+		return models.ReferralRejectIPReuse, err
+	}
+	n, err = count(tx.Model(&models.Referral{}).Where("inviter_id = ? AND register_ip = ? AND created_at >= ?", inviterID, ip, since))
+	if err != nil || n >= referralSameIPLimit {
+		return models.ReferralRejectIPReuse, err
+	}
+	return "", nil
+}
+
+// handleEvent 成交后检查买卖双方是否有待奖励的邀请
+func (rs *ReferralService) handleEvent(ctx context.Context, stream string, msg redis.XMessage) error {
+	if streamString(msg.Values["event"]) != "listing_sold" {
+		return nil
+	}
+	listingID := streamString(msg.Values["listing_id"])
+	sellerID := streamString(msg.Values["seller_id"])
+	buyerID := streamString(msg.Values["buyer_id"])
+	if listingID == "" || sellerID == "" || buyerID == "" || sellerID == buyerID {
+		return nil
+	}
+	// 买家由卖家填写，核实不了的成交不发放奖励，防止被邀请人虚构成交领取奖励
+	if err := verifySale(ctx, rs.db, msg.Values); err != nil {
+		if errors.Is(err, ErrBuyerNotVerified) {
+			return nil
+		}
+		return err
+	}
+	if err := rs.reward(ctx, sellerID, buyerID, listingID); err != nil {
+		return err
+	}
+	return rs.reward(ctx, buyerID, sellerID, listingID)
+}
+
+// reward 被邀请人 inviteeID 与 counterpartyID 完成交易后为邀请双方发放积分
+// 与邀请人之间的交易不算；邀请状态从 pending 更新成功的事务才发放，重复事件不会重复奖励
+func (rs *ReferralService) reward(ctx context.Context, inviteeID, counterpartyID, listingID string) error {
+	return WithTx(ctx, rs.db, func(ctx context.Context, tx *gorm.DB) error {
+		var referral models.Referral
+		if err := tx.Where("invitee_id = ? AND status = ?", inviteeID, models.ReferralPending).
+			First(&referral).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return fmt.Errorf("failed to load referral: %w", err)
+		}
+		if referral.InviterID == counterpartyID {
+			return nil
+		}
+
+		now := time.Now()
+		result := tx.Model(&models.Referral{}).
+			Where("id = ? AND status = ?", referral.ID, models.ReferralPending).
+			Updates(map[string]interface{}{
+				"status":                models.ReferralRewarded,
+				"qualifying_listing_id": listingID,
+				"rewarded_at":           &now,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to update referral: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		// 按用户ID顺序加锁，避免与其他积分变动死锁
+		grants := []models.PointsEntry{
+			{UserID: referral.InviterID, Amount: referralInviterPoints, CounterpartyID: referral.InviteeID},
+			{UserID: referral.InviteeID, Amount: referralInviteePoints, CounterpartyID: referral.InviterID},
+		}
+		if grants[1].UserID < grants[0].UserID {
+			grants[0], grants[1] = grants[1], grants[0]
+		}
+		for i := range grants {
+			user, err := lockPointsUser(tx, grants[i].UserID)
+			if errors.Is(err, ErrUserNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			grants[i].Reason = models.PointsReferral
+			grants[i].RefID = referral.ID
+			if err := applyPoints(tx, user, &grants[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ==================== 辅助方法 ====================
+
+// generateInviteCode 生成随机邀请码
+func generateInviteCode() string {
+	max := big.NewInt(int64(len(referralCodeAlphabet)))
+	b := make([]byte, referralCodeLength)
+	for i := range b {
+		n, _ := rand.Int(rand.Reader, max)
+		b[i] = referralCodeAlphabet[n.Int64()]
+	}
+	return string(b)
+}
+
+// normalizeInviteCode 邀请码不区分大小写，忽略首尾空格
+func normalizeInviteCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
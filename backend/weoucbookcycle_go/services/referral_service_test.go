@@ -0,0 +1,33 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateInviteCode(t *testing.T) {
+	seen := make(map[string]bool)
+	for range 100 {
+		code := generateInviteCode()
+		if len(code) != referralCodeLength {
+			t.Fatalf("invite code %q has length %d, want %d", code, len(code), referralCodeLength)
+		}
+		// 不含容易混淆的字符，手动输入时不会填错
+		if strings.ContainsAny(code, "01OIL") {
+			t.Fatalf("invite code %q contains ambiguous characters", code)
+		}
+		seen[code] = true
+	}
+	if len(seen) < 99 {
+		t.Errorf("expected random invite codes, got %d distinct out of 100", len(seen))
+	}
+}
+
+func TestNormalizeInviteCode(t *testing.T) {
+	if got := normalizeInviteCode("  ab3kq7xz "); got != "AB3KQ7XZ" {
+		t.Errorf("normalizeInviteCode = %q", got)
+	}
+	if got := normalizeInviteCode("   "); got != "" {
+		t.Errorf("blank invite code should normalize to empty, got %q", got)
+	}
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrReportTargetNotFound 举报对象不存在
+	ErrReportTargetNotFound = utils.NewError(http.StatusNotFound, "report target not found")
+	// ErrDuplicateReport 同一用户对同一对象已有待处理的举报
+	ErrDuplicateReport = utils.NewError(http.StatusConflict, "you have already reported this content")
+)
+
+// reportTargetModels 可举报的对象类型 -> 对应模型
+var reportTargetModels = map[string]func() interface{}{
+	"user":    func() interface{} { return &models.User{} },
+	"book":    func() interface{} { return &models.Book{} },
+	"listing": func() interface{} { return &models.Listing{} },
+	"chat":    func() interface{} { return &models.Chat{} },
+	"message": func() interface{} { return &models.Message{} },
+}
+
+// ReportService 举报服务
+type ReportService struct {
+	db *gorm.DB
+	// escalateReports 审核条目自动升级的举报次数（MODERATION_ESCALATE_REPORTS）
+	escalateReports int
+}
+
+// NewReportService 创建举报服务实例
+func NewReportService(deps Deps) *ReportService {
+	return &ReportService{
+		db:              deps.DB,
+		escalateReports: deps.Config.Moderation.EscalateReports,
+	}
+}
+
+// CreateReportRequest 举报请求
+type CreateReportRequest struct {
+	TargetType  string `json:"target_type" binding:"required,oneof=user book listing chat message"`
+	TargetID    string `json:"target_id" binding:"required,max=36"`
+	Reason      string `json:"reason" binding:"required,max=50"`
+	Description string `json:"description" binding:"max=1000"`
+}
+
+// CreateReport 提交举报
+func (rs *ReportService) CreateReport(ctx context.Context, reporterID string, req *CreateReportRequest) (*models.Report, error) {
+	newTarget, ok := reportTargetModels[req.TargetType]
+	if !ok {
+		return nil, ErrReportTargetNotFound
+	}
+	if req.TargetType == "user" && req.TargetID == reporterID {
+		return nil, errors.New("you cannot report yourself")
+	}
+
+	var count int64
+	rs.db.Model(newTarget()).Where("id = ?", req.TargetID).Count(&count)
+	if count == 0 {
+		return nil, ErrReportTargetNotFound
+	}
+
+	rs.db.Model(&models.Report{}).
+		Where("reporter_id = ? AND target_type = ? AND target_id = ? AND status = ?",
+			reporterID, req.TargetType, req.TargetID, models.ReportStatusPending).
+		Count(&count)
+	if count > 0 {
+		return nil, ErrDuplicateReport
+	}
+
+	report := models.Report{
+		ReporterID:  reporterID,
+		TargetType:  req.TargetType,
+		TargetID:    req.TargetID,
+		Reason:      req.Reason,
+		Description: req.Description,
+		Status:      models.ReportStatusPending,
+	}
+	// 举报同时进入统一审核队列，同一对象的举报合并为一个条目
+	err := WithTx(ctx, rs.db, func(ctx context.Context, tx *gorm.DB) error {
+		if err := tx.Create(&report).Error; err != nil {
+			return err
+		}
+		return enqueueReport(tx, &report, rs.escalateReports)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create report: %w", err)
+	}
+
+	return &report, nil
+}
@@ -0,0 +1,340 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	reportingGroup = "reporting_etl"
+	// reportingBatchSize 每次从流中读取并写入的条数
+	reportingBatchSize = 500
+	// reportingStreamRetention 导入后Redis中保留的时间窗口，供实时查询（如访问日志查询）使用
+	reportingStreamRetention = 24 * time.Hour
+	// reportingTrimInterval 同一个流两次裁剪的最小间隔
+	reportingTrimInterval = time.Minute
+)
+
+// 导入报表表的流
+var (
+	reportingEventStreams = []string{"user_events", "book_events", "chat_events"}
+	reportingStreams      = append([]string{"login_logs", accessLogStream}, reportingEventStreams...)
+)
+
+// ReportingQuery 报表事件查询条件
+type ReportingQuery struct {
+	Stream string
+	Event  string
+	UserID string
+	From   time.Time
+	To     time.Time
+	Page   int
+	Limit  int
+}
+
+// ReportingEventCount 每天每种领域事件的数量
+type ReportingEventCount struct {
+	Day    string `json:"day"`
+	Stream string `json:"stream"`
+	Event  string `json:"event"`
+	Count  int64  `json:"count"`
+}
+
+// ReportingLoginCount 每天的登录情况
+type ReportingLoginCount struct {
+	Day         string `json:"day"`
+	Succeeded   int64  `json:"succeeded"`
+	Failed      int64  `json:"failed"`
+	UniqueUsers int64  `json:"unique_users"`
+}
+
+// ReportingRequestCount 每天的请求情况
+type ReportingRequestCount struct {
+	Day          string  `json:"day"`
+	Requests     int64   `json:"requests"`
+	ClientErrors int64   `json:"client_errors"`
+	ServerErrors int64   `json:"server_errors"`
+	AvgLatency   float64 `json:"avg_latency_ms"`
+}
+
+// ReportingSummary 时间范围内按天汇总的报表
+type ReportingSummary struct {
+	From     time.Time               `json:"from"`
+	To       time.Time               `json:"to"`
+	Events   []ReportingEventCount   `json:"events"`
+	Logins   []ReportingLoginCount   `json:"logins"`
+	Requests []ReportingRequestCount `json:"requests"`
+}
+
+// ReportingETLService 把领域事件、登录和访问日志从Redis流导入MySQL报表表
+// 导入并确认后裁剪流：只删除所有消费组都已确认、且超出保留窗口的消息
+type ReportingETLService struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+	lastTrim    map[string]time.Time
+}
+
+// NewReportingETLService 创建报表导入服务实例
+func NewReportingETLService(deps Deps) *ReportingETLService {
+	return &ReportingETLService{
+		db:          deps.DB,
+		redisClient: deps.Redis,
+		lastTrim:    make(map[string]time.Time),
+	}
+}
+
+// Start 启动导入消费者；消费组首次建立时导入流中已有的消息，重复导入按流消息ID去重
+func (rs *ReportingETLService) Start(ctx context.Context) {
+	startBatchEventConsumer(ctx, rs.redisClient, reportingGroup, reportingStreams, reportingBatchSize, rs.load)
+}
+
+// load 导入一批消息，成功后由消费者确认，再按间隔裁剪流
+// 只在消费者协程中调用，lastTrim 不需要加锁
+func (rs *ReportingETLService) load(ctx context.Context, stream string, msgs []redis.XMessage) error {
+	var rows interface{}
+	switch stream {
+	case "login_logs":
+		logins := make([]models.ReportLogin, len(msgs))
+		for i, msg := range msgs {
+			logins[i] = reportLoginFromMessage(msg)
+		}
+		rows = &logins
+	case accessLogStream:
+		logs := make([]models.ReportAccessLog, len(msgs))
+		for i, msg := range msgs {
+			logs[i] = reportAccessLogFromMessage(msg)
+		}
+		rows = &logs
+	default:
+		events := make([]models.ReportEvent, len(msgs))
+		for i, msg := range msgs {
+			events[i] = reportEventFromMessage(stream, msg)
+		}
+		rows = &events
+	}
+
+	if err := rs.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		CreateInBatches(rows, 200).Error; err != nil {
+		return fmt.Errorf("failed to load %s: %w", stream, err)
+	}
+
+	if time.Since(rs.lastTrim[stream]) >= reportingTrimInterval {
+		rs.lastTrim[stream] = time.Now()
+		// 本批在返回后才确认，裁剪点最多到上一批，下次裁剪时会包含本批
+		if err := trimAcknowledged(ctx, rs.redisClient, stream, reportingStreamRetention); err != nil {
+			log.Printf("%s: failed to trim %s: %v", reportingGroup, stream, err)
+		}
+	}
+	return nil
+}
+
+// Events 分页查询导入的领域事件（按时间倒序）
+func (rs *ReportingETLService) Events(ctx context.Context, q *ReportingQuery) ([]models.ReportEvent, int64, error) {
+	query := rs.db.WithContext(ctx).Model(&models.ReportEvent{})
+	if q.Stream != "" {
+		query = query.Where("stream = ?", q.Stream)
+	}
+	if q.Event != "" {
+		query = query.Where("event = ?", q.Event)
+	}
+	if q.UserID != "" {
+		query = query.Where("user_id = ?", q.UserID)
+	}
+	if !q.From.IsZero() {
+		query = query.Where("occurred_at >= ?", q.From)
+	}
+	if !q.To.IsZero() {
+		query = query.Where("occurred_at < ?", q.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count report events: %w", err)
+	}
+
+	events := []models.ReportEvent{}
+	if err := query.Order("occurred_at DESC").Offset(utils.PageOffset(q.Page, q.Limit)).Limit(q.Limit).
+		Find(&events).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list report events: %w", err)
+	}
+	return events, total, nil
+}
+
+// Summary 按天汇总 [from, to) 内的领域事件、登录和请求
+func (rs *ReportingETLService) Summary(ctx context.Context, from, to time.Time) (*ReportingSummary, error) {
+	db := rs.db.WithContext(ctx)
+	summary := &ReportingSummary{
+		From:     from,
+		To:       to,
+		Events:   []ReportingEventCount{},
+		Logins:   []ReportingLoginCount{},
+		Requests: []ReportingRequestCount{},
+	}
+
+	if err := db.Model(&models.ReportEvent{}).
+		Select("DATE_FORMAT(occurred_at, '%Y-%m-%d') AS day, stream, event, COUNT(*) AS count").
+		Where("occurred_at >= ? AND occurred_at < ?", from, to).
+		Group("day, stream, event").Order("day, stream, event").
+		Scan(&summary.Events).Error; err != nil {
+		return nil, fmt.Errorf("failed to summarize report events: %w", err)
+	}
+
+	if err := db.Model(&models.ReportLogin{}).
+		Select("DATE_FORMAT(occurred_at, '%Y-%m-%d') AS day, "+
+			"SUM(CASE WHEN success THEN 1 ELSE 0 END) AS succeeded, "+
+			"SUM(CASE WHEN success THEN 0 ELSE 1 END) AS failed, "+
+			"COUNT(DISTINCT CASE WHEN success THEN user_id END) AS unique_users").
+		Where("occurred_at >= ? AND occurred_at < ?", from, to).
+		Group("day").Order("day").
+		Scan(&summary.Logins).Error; err != nil {
+		return nil, fmt.Errorf("failed to summarize logins: %w", err)
+	}
+
+	if err := db.Model(&models.ReportAccessLog{}).
+		Select("DATE_FORMAT(occurred_at, '%Y-%m-%d') AS day, COUNT(*) AS requests, "+
+			"SUM(CASE WHEN status_code >= 400 AND status_code < 500 THEN 1 ELSE 0 END) AS client_errors, "+
+			"SUM(CASE WHEN status_code >= 500 THEN 1 ELSE 0 END) AS server_errors, "+
+			"AVG(latency_ms) AS avg_latency").
+		Where("occurred_at >= ? AND occurred_at < ?", from, to).
+		Group("day").Order("day").
+		Scan(&summary.Requests).Error; err != nil {
+		return nil, fmt.Errorf("failed to summarize requests: %w", err)
+	}
+
+	return summary, nil
+}
+
+// ==================== 辅助方法 ====================
+
+// trimAcknowledged 裁剪流中所有消费组都已确认、且早于 keep 的消息
+// 每个组的安全点为最早未确认的消息，没有未确认消息时为最后投递的消息
+func trimAcknowledged(ctx context.Context, redisClient *redis.Client, stream string, keep time.Duration) error {
+	groups, err := redisClient.XInfoGroups(ctx, stream).Result()
+	if err != nil {
+		return err
+	}
+	ids := []string{fmt.Sprintf("%d-0", time.Now().Add(-keep).UnixMilli())}
+	for _, g := range groups {
+		if g.Pending == 0 {
+			ids = append(ids, g.LastDeliveredID)
+			continue
+		}
+		pending, err := redisClient.XPending(ctx, stream, g.Name).Result()
+		if err != nil {
+			return err
+		}
+		ids = append(ids, pending.Lower)
+	}
+	return redisClient.XTrimMinIDApprox(ctx, stream, oldestStreamID(ids), 0).Err()
+}
+
+// oldestStreamID 返回最早的流消息ID（形如 1700000000000-0）
+func oldestStreamID(ids []string) string {
+	oldest := ids[0]
+	for _, id := range ids[1:] {
+		if compareStreamIDs(id, oldest) < 0 {
+			oldest = id
+		}
+	}
+	return oldest
+}
+
+// compareStreamIDs 按时间戳和序号比较两个流消息ID
+func compareStreamIDs(a, b string) int {
+	aMs, aSeq := splitStreamID(a)
+	bMs, bSeq := splitStreamID(b)
+	if c := cmp.Compare(aMs, bMs); c != 0 {
+		return c
+	}
+	return cmp.Compare(aSeq, bSeq)
+}
+
+// splitStreamID 拆分流消息ID的时间戳和序号
+func splitStreamID(id string) (uint64, uint64) {
+	msPart, seqPart, _ := strings.Cut(id, "-")
+	ms, _ := strconv.ParseUint(msPart, 10, 64)
+	seq, _ := strconv.ParseUint(seqPart, 10, 64)
+	return ms, seq
+}
+
+// eventOccurredAt 事件时间：优先使用 timestamp 字段，否则取流消息ID中的时间
+func eventOccurredAt(msg redis.XMessage) time.Time {
+	if ts := streamInt64(msg.Values["timestamp"]); ts > 0 {
+		return time.Unix(ts, 0)
+	}
+	if t, ok := streamIDTime(msg.ID); ok {
+		return t
+	}
+	return time.Now()
+}
+
+// reportEventFromMessage 把领域事件转换为报表行
+// 不同事件的用户和对象字段名不同，按顺序取第一个存在的
+func reportEventFromMessage(stream string, msg redis.XMessage) models.ReportEvent {
+	details, _ := json.Marshal(msg.Values)
+	return models.ReportEvent{
+		Stream:     stream,
+		StreamID:   msg.ID,
+		Event:      streamString(msg.Values["event"]),
+		UserID:     firstStreamValue(msg.Values, "user_id", "seller_id", "initiator_id"),
+		ObjectID:   firstStreamValue(msg.Values, "book_id", "chat_id", "listing_id"),
+		Details:    details,
+		OccurredAt: eventOccurredAt(msg),
+	}
+}
+
+// reportLoginFromMessage 把登录日志转换为报表行（success 由客户端写为 1/0）
+func reportLoginFromMessage(msg redis.XMessage) models.ReportLogin {
+	success := streamString(msg.Values["success"])
+	return models.ReportLogin{
+		StreamID:   msg.ID,
+		UserID:     streamString(msg.Values["user_id"]),
+		Username:   streamString(msg.Values["username"]),
+		IP:         streamString(msg.Values["ip"]),
+		UserAgent:  truncateRunes(streamString(msg.Values["user_agent"]), 254),
+		Success:    success == "1" || success == "true",
+		OccurredAt: eventOccurredAt(msg),
+	}
+}
+
+// reportAccessLogFromMessage 把访问日志转换为报表行
+func reportAccessLogFromMessage(msg redis.XMessage) models.ReportAccessLog {
+	entry := accessLogFromMessage(msg)
+	occurredAt := entry.Time
+	if occurredAt.IsZero() {
+		occurredAt = eventOccurredAt(msg)
+	}
+	return models.ReportAccessLog{
+		StreamID:   msg.ID,
+		Method:     entry.Method,
+		Path:       truncateRunes(entry.Path, 254),
+		StatusCode: entry.StatusCode,
+		LatencyMs:  entry.Latency,
+		IP:         entry.IP,
+		UserID:     entry.UserID,
+		TraceID:    entry.TraceID,
+		OccurredAt: occurredAt,
+	}
+}
+
+// firstStreamValue 返回 keys 中第一个非空的字段值
+func firstStreamValue(values map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if v := streamString(values[key]); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 按数值比较，字符串比较会把 999-0 排在 1000-0 之后
+func TestOldestStreamID(t *testing.T) {
+	cases := []struct {
+		ids  []string
+		want string
+	}{
+		{[]string{"1700000000000-0"}, "1700000000000-0"},
+		{[]string{"1000-0", "999-0"}, "999-0"},
+		{[]string{"1700000000000-12", "1700000000000-9"}, "1700000000000-9"},
+		{[]string{"1700000000000-0", "0-0"}, "0-0"},
+	}
+	for _, tc := range cases {
+		if got := oldestStreamID(tc.ids); got != tc.want {
+			t.Errorf("oldestStreamID(%v) = %q, want %q", tc.ids, got, tc.want)
+		}
+	}
+}
+
+func TestReportEventFromMessage(t *testing.T) {
+	event := reportEventFromMessage("chat_events", redis.XMessage{
+		ID: "1700000000000-0",
+		Values: map[string]interface{}{
+			"event": "chat_created", "chat_id": "c-1", "initiator_id": "u-1", "target_user_id": "u-2",
+		},
+	})
+	if event.Event != "chat_created" || event.UserID != "u-1" || event.ObjectID != "c-1" {
+		t.Errorf("reportEventFromMessage = %+v", event)
+	}
+	// 没有 timestamp 字段时使用消息ID中的时间
+	if want := time.UnixMilli(1700000000000); !event.OccurredAt.Equal(want) {
+		t.Errorf("OccurredAt = %v, want %v", event.OccurredAt, want)
+	}
+}
+
+func TestReportLoginFromMessage(t *testing.T) {
+	for value, want := range map[string]bool{"1": true, "true": true, "0": false, "false": false} {
+		login := reportLoginFromMessage(redis.XMessage{
+			ID:     "1700000000000-0",
+			Values: map[string]interface{}{"user_id": "u-1", "success": value, "timestamp": "1700000000"},
+		})
+		if login.Success != want {
+			t.Errorf("success %q parsed as %v, want %v", value, login.Success, want)
+		}
+		if !login.OccurredAt.Equal(time.Unix(1700000000, 0)) {
+			t.Errorf("OccurredAt = %v", login.OccurredAt)
+		}
+	}
+}
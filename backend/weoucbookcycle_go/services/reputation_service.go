@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+	"weoucbookcycle_go/models"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 信誉分各项的权重，合计为1
+const (
+	reputationOrdersWeight   = 0.25
+	reputationRatingsWeight  = 0.35
+	reputationResponseWeight = 0.20
+	reputationDisputesWeight = 0.20
+)
+
+const (
+	// reputationOrdersForFull 完成该数量的订单后成交项满分
+	reputationOrdersForFull = 20
+	// reputationResponseWindow 统计回复速度的时间范围
+	reputationResponseWindow = 90 * 24 * time.Hour
+	// reputationFastReply / reputationSlowReply 首次回复不超过1小时满分，超过48小时（或一直未回复）0分
+	reputationFastReply = 60.0
+	reputationSlowReply = 48 * 60.0
+	// reputationDisputeWindow 统计成立举报的时间范围，每条成立的举报扣25分
+	reputationDisputeWindow  = 365 * 24 * time.Hour
+	reputationDisputePenalty = 25
+	// reputationBatch 全量计算时每批处理的卖家数
+	reputationBatch = 200
+)
+
+// ReputationService 卖家信誉分
+// 信誉分由成交数、有效评价、聊天回复速度和成立的举报加权得出，定时任务每天全量计算一次
+type ReputationService struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+}
+
+// NewReputationService 创建信誉分服务实例
+func NewReputationService(deps Deps) *ReputationService {
+	return &ReputationService{db: deps.DB, redisClient: deps.Redis}
+}
+
+// reputationInputs 计算信誉分所需的原始数据
+type reputationInputs struct {
+	CompletedOrders         int64
+	GoodRatings, BadRatings int64
+	ResponseMinutes         []float64 // 每个聊天的首次回复耗时，未回复的按 reputationSlowReply 计
+	UpheldDisputes          int64
+}
+
+// Rate 记录买家对卖家的评价，同一买家再次评价时覆盖之前的结果
+func (rs *ReputationService) Rate(ctx context.Context, raterID, sellerID string, isGood bool) error {
+	rating := models.SellerRating{SellerID: sellerID, RaterID: raterID, IsGood: isGood}
+	if err := rs.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "seller_id"}, {Name: "rater_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"is_good", "updated_at"}),
+	}).Create(&rating).Error; err != nil {
+		return fmt.Errorf("failed to save rating: %w", err)
+	}
+	return nil
+}
+
+// Breakdown 获取用户的信誉分明细，还没有计算过时立即计算一次
+func (rs *ReputationService) Breakdown(ctx context.Context, userID string) (*models.SellerReputation, error) {
+	var user models.User
+	if err := rs.db.WithContext(ctx).Select("id").First(&user, "id = ? AND status <> ?", userID, models.UserStatusDeactivated).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	var rep models.SellerReputation
+	err := rs.db.WithContext(ctx).First(&rep, "user_id = ?", userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return rs.Recompute(ctx, userID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reputation: %w", err)
+	}
+	return &rep, nil
+}
+
+// RecomputeAll 重新计算所有发布过书籍的用户的信誉分
+func (rs *ReputationService) RecomputeAll(ctx context.Context) error {
+	lastID := ""
+	for {
+		var sellerIDs []string
+		if err := rs.db.WithContext(ctx).Model(&models.Book{}).Unscoped().
+			Distinct("seller_id").Where("seller_id > ?", lastID).
+			Order("seller_id").Limit(reputationBatch).
+			Pluck("seller_id", &sellerIDs).Error; err != nil {
+			return fmt.Errorf("failed to load sellers: %w", err)
+		}
+
+		for _, sellerID := range sellerIDs {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if _, err := rs.Recompute(ctx, sellerID); err != nil {
+				return err
+			}
+		}
+
+		if len(sellerIDs) < reputationBatch {
+			return nil
+		}
+		lastID = sellerIDs[len(sellerIDs)-1]
+	}
+}
+
+// Recompute 计算单个用户的信誉分，保存明细并更新 users.reputation_score
+// 保存后记录 reputation_updated 事件，徽章规则据此检查回复速度
+func (rs *ReputationService) Recompute(ctx context.Context, userID string) (*models.SellerReputation, error) {
+	in, err := rs.loadInputs(ctx, userID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load reputation inputs of %s: %w", userID, err)
+	}
+	rep := computeReputation(userID, in)
+
+	err = WithTx(ctx, rs.db, func(ctx context.Context, tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&rep).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).Where("id = ?", userID).UpdateColumn("reputation_score", rep.Score).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save reputation of %s: %w", userID, err)
+	}
+	recordUserEvent(ctx, rs.redisClient, "reputation_updated", userID)
+	return &rep, nil
+}
+
+// loadInputs 查询成交、有效评价、回复耗时和成立的举报
+func (rs *ReputationService) loadInputs(ctx context.Context, userID string, now time.Time) (reputationInputs, error) {
+	var in reputationInputs
+	db := rs.db.WithContext(ctx)
+
+	if err := db.Model(&models.Listing{}).Where("seller_id = ? AND status = ?", userID, "sold").
+		Count(&in.CompletedOrders).Error; err != nil {
+		return in, err
+	}
+
+	// 只统计在该卖家处买过书的用户的评价
+	ratings := func(isGood bool, count *int64) error {
+		return db.Model(&models.SellerRating{}).
+			Where("seller_ratings.seller_id = ? AND seller_ratings.is_good = ?", userID, isGood).
+			Where("EXISTS (SELECT 1 FROM listings WHERE listings.seller_id = seller_ratings.seller_id" +
+				" AND listings.buyer_id = seller_ratings.rater_id AND listings.status = 'sold')").
+			Count(count).Error
+	}
+	if err := ratings(true, &in.GoodRatings); err != nil {
+		return in, err
+	}
+	if err := ratings(false, &in.BadRatings); err != nil {
+		return in, err
+	}
+
+	// 每个聊天中对方第一条消息到该用户第一次回复的耗时
+	var replies []struct {
+		FirstIn    time.Time
+		FirstReply *time.Time
+	}
+	if err := db.Raw(`SELECT i.first_in, MIN(r.created_at) AS first_reply
+FROM (SELECT m.chat_id, MIN(m.created_at) AS first_in FROM messages m
+      JOIN chat_users cu ON cu.chat_id = m.chat_id AND cu.user_id = ?
+      WHERE m.sender_id <> ? AND m.created_at >= ? AND m.deleted_at IS NULL
+      GROUP BY m.chat_id) i
+LEFT JOIN messages r ON r.chat_id = i.chat_id AND r.sender_id = ? AND r.created_at >= i.first_in AND r.deleted_at IS NULL
+GROUP BY i.chat_id, i.first_in`, userID, userID, now.Add(-reputationResponseWindow), userID).
+		Scan(&replies).Error; err != nil {
+		return in, err
+	}
+	for _, r := range replies {
+		switch {
+		case r.FirstReply != nil:
+			in.ResponseMinutes = append(in.ResponseMinutes, r.FirstReply.Sub(r.FirstIn).Minutes())
+		case now.Sub(r.FirstIn).Minutes() >= reputationSlowReply:
+			in.ResponseMinutes = append(in.ResponseMinutes, reputationSlowReply)
+		}
+		// 还在等待回复且未超时的聊天不计入
+	}
+
+	if err := db.Model(&models.Report{}).
+		Where("status = ? AND created_at >= ?", models.ReportStatusResolved, now.Add(-reputationDisputeWindow)).
+		Where("(target_type = 'user' AND target_id = ?)"+
+			" OR (target_type = 'listing' AND target_id IN (SELECT id FROM listings WHERE seller_id = ?))"+
+			" OR (target_type = 'book' AND target_id IN (SELECT id FROM books WHERE seller_id = ?))", userID, userID, userID).
+		Count(&in.UpheldDisputes).Error; err != nil {
+		return in, err
+	}
+	return in, nil
+}
+
+// computeReputation 按权重计算总分和各项得分（均为0-100）
+func computeReputation(userID string, in reputationInputs) models.SellerReputation {
+	rep := models.SellerReputation{
+		UserID:          userID,
+		CompletedOrders: in.CompletedOrders,
+		GoodRatings:     in.GoodRatings,
+		BadRatings:      in.BadRatings,
+		ResponseChats:   int64(len(in.ResponseMinutes)),
+		UpheldDisputes:  in.UpheldDisputes,
+	}
+
+	rep.OrdersScore = int(math.Round(math.Min(float64(in.CompletedOrders), reputationOrdersForFull) / reputationOrdersForFull * 100))
+
+	// 好评率做平滑处理，评价很少时接近50分
+	rep.RatingsScore = int(math.Round(float64(in.GoodRatings+1) / float64(in.GoodRatings+in.BadRatings+2) * 100))
+
+	rep.ResponseScore = 50
+	if len(in.ResponseMinutes) > 0 {
+		var sum float64
+		for _, m := range in.ResponseMinutes {
+			sum += m
+		}
+		avg := sum / float64(len(in.ResponseMinutes))
+		rep.AvgResponseMinutes = &avg
+		ratio := (reputationSlowReply - math.Max(avg, reputationFastReply)) / (reputationSlowReply - reputationFastReply)
+		rep.ResponseScore = int(math.Round(math.Max(ratio, 0) * 100))
+	}
+
+	rep.DisputesScore = int(math.Max(float64(100-reputationDisputePenalty*in.UpheldDisputes), 0))
+
+	rep.Score = int(math.Round(reputationOrdersWeight*float64(rep.OrdersScore) +
+		reputationRatingsWeight*float64(rep.RatingsScore) +
+		reputationResponseWeight*float64(rep.ResponseScore) +
+		reputationDisputesWeight*float64(rep.DisputesScore)))
+	return rep
+}
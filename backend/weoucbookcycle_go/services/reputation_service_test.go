@@ -0,0 +1,35 @@
+package services
+
+import "testing"
+
+func TestComputeReputation(t *testing.T) {
+	// 新卖家：没有成交和评价，回复速度按中性分计算，没有举报
+	rep := computeReputation("u1", reputationInputs{})
+	if rep.OrdersScore != 0 || rep.RatingsScore != 50 || rep.ResponseScore != 50 || rep.DisputesScore != 100 {
+		t.Fatalf("unexpected new seller breakdown: %+v", rep)
+	}
+	if rep.Score != 48 || rep.AvgResponseMinutes != nil {
+		t.Fatalf("unexpected new seller score: %+v", rep)
+	}
+
+	rep = computeReputation("u2", reputationInputs{
+		CompletedOrders: 40,
+		GoodRatings:     8,
+		ResponseMinutes: []float64{10, 50},
+	})
+	if rep.OrdersScore != 100 || rep.RatingsScore != 90 || rep.ResponseScore != 100 || rep.Score != 97 {
+		t.Fatalf("unexpected active seller breakdown: %+v", rep)
+	}
+	if rep.ResponseChats != 2 || rep.AvgResponseMinutes == nil || *rep.AvgResponseMinutes != 30 {
+		t.Fatalf("unexpected response stats: %+v", rep)
+	}
+
+	// 一直不回复、举报成立的次数超过上限时对应项为0
+	rep = computeReputation("u3", reputationInputs{
+		ResponseMinutes: []float64{reputationSlowReply, reputationSlowReply * 2},
+		UpheldDisputes:  5,
+	})
+	if rep.ResponseScore != 0 || rep.DisputesScore != 0 {
+		t.Fatalf("unexpected penalties: %+v", rep)
+	}
+}
@@ -0,0 +1,318 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	maxSavedSearchesPerUser = 20
+	savedSearchMatchLimit   = 20
+)
+
+// savedSearchIntervals 各频率对应的最短通知间隔
+var savedSearchIntervals = map[string]time.Duration{
+	"hourly": time.Hour,
+	"daily":  24 * time.Hour,
+	"weekly": 7 * 24 * time.Hour,
+}
+
+// SavedSearchService 保存的搜索服务
+type SavedSearchService struct {
+	db                  *gorm.DB
+	redisClient         *redis.Client
+	notificationService *NotificationService
+	synonyms            *SynonymService
+	// dailyCap 每个用户每天最多收到的保存搜索通知数（SAVED_SEARCH_DAILY_CAP）
+	dailyCap int
+}
+
+// NewSavedSearchService 创建保存的搜索服务实例
+func NewSavedSearchService(deps Deps, notificationService *NotificationService, synonyms *SynonymService) *SavedSearchService {
+	return &SavedSearchService{
+		db:                  deps.DB,
+		redisClient:         deps.Redis,
+		notificationService: notificationService,
+		synonyms:            synonyms,
+		dailyCap:            deps.Config.Notification.SavedSearchDailyCap,
+	}
+}
+
+// SavedSearchFilters 保存的搜索筛选条件
+type SavedSearchFilters struct {
+	Category  string  `json:"category,omitempty"`
+	Condition string  `json:"condition,omitempty"`
+	MinPrice  float64 `json:"min_price,omitempty" binding:"omitempty,min=0"`
+	MaxPrice  float64 `json:"max_price,omitempty" binding:"omitempty,min=0"`
+}
+
+// SavedSearchRequest 保存搜索请求
+type SavedSearchRequest struct {
+	Name      string             `json:"name" binding:"max=100"`
+	Query     string             `json:"query" binding:"required,min=1,max=200"`
+	Filters   SavedSearchFilters `json:"filters"`
+	Frequency string             `json:"frequency" binding:"omitempty,oneof=hourly daily weekly"`
+	Enabled   *bool              `json:"enabled"`
+}
+
+// ==================== 用户方法 ====================
+
+// List 获取用户保存的搜索
+func (ss *SavedSearchService) List(userID string) ([]models.SavedSearch, error) {
+	var searches []models.SavedSearch
+	if err := ss.db.Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&searches).Error; err != nil {
+		return nil, fmt.Errorf("failed to list saved searches: %w", err)
+	}
+	return searches, nil
+}
+
+// Create 保存一个搜索
+func (ss *SavedSearchService) Create(userID string, req *SavedSearchRequest) (*models.SavedSearch, error) {
+	var count int64
+	ss.db.Model(&models.SavedSearch{}).Where("user_id = ?", userID).Count(&count)
+	if count >= maxSavedSearchesPerUser {
+		return nil, fmt.Errorf("you can save at most %d searches", maxSavedSearchesPerUser)
+	}
+
+	if err := validateSavedSearchFilters(&req.Filters); err != nil {
+		return nil, err
+	}
+
+	filtersJSON, _ := json.Marshal(req.Filters)
+	// 从保存时刻开始检查新书，避免把历史结果当作新结果通知
+	now := time.Now()
+	search := models.SavedSearch{
+		UserID:        userID,
+		Name:          strings.TrimSpace(req.Name),
+		Query:         strings.TrimSpace(req.Query),
+		Filters:       filtersJSON,
+		Frequency:     req.Frequency,
+		Enabled:       true,
+		LastCheckedAt: &now,
+	}
+	if search.Name == "" {
+		search.Name = search.Query
+	}
+	if search.Frequency == "" {
+		search.Frequency = "daily"
+	}
+
+	if err := ss.db.Create(&search).Error; err != nil {
+		return nil, fmt.Errorf("failed to save search: %w", err)
+	}
+
+	// 禁用状态需要单独更新（零值不会写入）
+	if req.Enabled != nil && !*req.Enabled {
+		ss.db.Model(&search).Update("enabled", false)
+		search.Enabled = false
+	}
+
+	return &search, nil
+}
+
+// Update 更新保存的搜索
+func (ss *SavedSearchService) Update(userID, id string, req *SavedSearchRequest) (*models.SavedSearch, error) {
+	var search models.SavedSearch
+	if err := ss.db.First(&search, "id = ? AND user_id = ?", id, userID).Error; err != nil {
+		return nil, errors.New("saved search not found")
+	}
+
+	if err := validateSavedSearchFilters(&req.Filters); err != nil {
+		return nil, err
+	}
+
+	filtersJSON, _ := json.Marshal(req.Filters)
+	updates := map[string]interface{}{
+		"query":   strings.TrimSpace(req.Query),
+		"filters": filtersJSON,
+	}
+	if name := strings.TrimSpace(req.Name); name != "" {
+		updates["name"] = name
+	}
+	if req.Frequency != "" {
+		updates["frequency"] = req.Frequency
+	}
+	if req.Enabled != nil {
+		updates["enabled"] = *req.Enabled
+	}
+
+	if err := ss.db.Model(&search).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update saved search: %w", err)
+	}
+
+	if err := ss.db.First(&search, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+
+	return &search, nil
+}
+
+// Delete 删除保存的搜索
+func (ss *SavedSearchService) Delete(userID, id string) error {
+	result := ss.db.Delete(&models.SavedSearch{}, "id = ? AND user_id = ?", id, userID)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete saved search: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("saved search not found")
+	}
+	return nil
+}
+
+// ==================== 定时任务 ====================
+
+// RunDue 运行所有到期的保存搜索，由定时任务 saved_search 调用
+// 检查间隔由 SAVED_SEARCH_INTERVAL_MINUTES 控制（默认10分钟）
+func (ss *SavedSearchService) RunDue(ctx context.Context) error {
+	var searches []models.SavedSearch
+	// 已停用账号的用户不再收到通知，搜索暂停到重新启用
+	if err := ss.db.Where("enabled = ?", true).Scopes(VisibleUsers("saved_searches.user_id")).Find(&searches).Error; err != nil {
+		return fmt.Errorf("failed to load saved searches: %w", err)
+	}
+
+	now := time.Now()
+	for i := range searches {
+		search := &searches[i]
+		if !savedSearchDue(search, now) {
+			continue
+		}
+		if err := ss.runSavedSearch(ctx, search, now); err != nil {
+			log.Printf("saved search job: search %s failed: %v", search.ID, err)
+		}
+	}
+	return nil
+}
+
+// runSavedSearch 对上次检查后新上架的书籍执行保存的搜索
+func (ss *SavedSearchService) runSavedSearch(ctx context.Context, search *models.SavedSearch, now time.Time) error {
+	since := search.CreatedAt
+	if search.LastCheckedAt != nil {
+		since = *search.LastCheckedAt
+	}
+
+	var filters SavedSearchFilters
+	if len(search.Filters) > 0 {
+		json.Unmarshal(search.Filters, &filters)
+	}
+
+	condition, args := KeywordCondition(ss.synonyms.ExpandQuery(ctx, search.Query), "title", "author", "description", "category")
+	query := ss.db.Model(&models.Book{}).
+		Where("status = ? AND seller_id <> ?", 1, search.UserID).Scopes(VisibleUsers("books.seller_id")).
+		Where("created_at > ? AND created_at <= ?", since, now).
+		Where(condition, args...)
+
+	if filters.Category != "" {
+		query = query.Where("category = ?", filters.Category)
+	}
+	if filters.Condition != "" {
+		query = query.Where("`condition` = ?", filters.Condition)
+	}
+	if filters.MinPrice > 0 {
+		query = query.Where("price >= ?", filters.MinPrice)
+	}
+	if filters.MaxPrice > 0 {
+		query = query.Where("price <= ?", filters.MaxPrice)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{"last_checked_at": now}
+
+	if total > 0 {
+		// 超出当日配额时不推进检查时间，结果留到下次通知
+		if !ss.reserveNotificationQuota(ctx, search.UserID) {
+			return nil
+		}
+
+		var books []models.Book
+		if err := query.Order("created_at DESC").Limit(savedSearchMatchLimit).Find(&books).Error; err != nil {
+			return err
+		}
+
+		bookIDs := make([]string, 0, len(books))
+		titles := make([]string, 0, 3)
+		for _, b := range books {
+			bookIDs = append(bookIDs, b.ID)
+			if len(titles) < 3 {
+				titles = append(titles, b.Title)
+			}
+		}
+
+		lang := UserLanguage(ss.db, search.UserID)
+		title := utils.T(lang, "notification.saved_search.title", search.Name, total)
+		content := strings.Join(titles, utils.T(lang, "notification.saved_search.separator"))
+		if _, err := ss.notificationService.Notify(ctx, search.UserID, "saved_search", title, content, map[string]interface{}{
+			"saved_search_id": search.ID,
+			"query":           search.Query,
+			"total":           total,
+			"book_ids":        bookIDs,
+		}); err != nil {
+			// 超出每小时通知上限时同样留到下次通知
+			if errors.Is(err, ErrNotificationRateLimited) {
+				return nil
+			}
+			return err
+		}
+
+		updates["last_notified_at"] = now
+	}
+
+	return ss.db.Model(search).Updates(updates).Error
+}
+
+// reserveNotificationQuota 检查并占用用户当天的保存搜索通知配额
+// 每日上限由 SAVED_SEARCH_DAILY_CAP 控制（默认5条）
+func (ss *SavedSearchService) reserveNotificationQuota(ctx context.Context, userID string) bool {
+	if ss.redisClient == nil {
+		return true
+	}
+
+	limit := int64(ss.dailyCap)
+	key := fmt.Sprintf("saved_search:quota:%s:%s", userID, time.Now().Format("20060102"))
+
+	count, err := ss.redisClient.Incr(ctx, key).Result()
+	if err != nil {
+		return true
+	}
+	if count == 1 {
+		ss.redisClient.Expire(ctx, key, 24*time.Hour)
+	}
+	return count <= limit
+}
+
+// savedSearchDue 判断保存的搜索是否已到通知时间
+func savedSearchDue(search *models.SavedSearch, now time.Time) bool {
+	if search.LastNotifiedAt == nil {
+		return true
+	}
+	interval, ok := savedSearchIntervals[search.Frequency]
+	if !ok {
+		interval = savedSearchIntervals["daily"]
+	}
+	return now.Sub(*search.LastNotifiedAt) >= interval
+}
+
+// validateSavedSearchFilters 校验筛选条件
+func validateSavedSearchFilters(filters *SavedSearchFilters) error {
+	filters.Category = strings.TrimSpace(filters.Category)
+	filters.Condition = strings.TrimSpace(filters.Condition)
+	if filters.MaxPrice > 0 && filters.MinPrice > filters.MaxPrice {
+		return errors.New("min_price cannot be greater than max_price")
+	}
+	return nil
+}
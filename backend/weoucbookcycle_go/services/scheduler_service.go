@@ -0,0 +1,305 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+	"weoucbookcycle_go/utils"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+)
+
+// 定时任务名称，同时用作开关参数 cron_<name>_enabled 和状态记录的key
+const (
+	CronIPBlockCleanup    = "ip_block_cleanup"
+	CronOnlineUserCleanup = "online_user_cleanup"
+	CronSavedSearch       = "saved_search"
+	CronStatsRollup       = "stats_rollup"
+	CronListingExpiry     = "listing_expiry"
+	CronUploadGC          = "upload_gc"
+	CronSoftDeletePurge   = "soft_delete_purge"
+	CronReputationRollup  = "reputation_rollup"
+	CronDataExportCleanup = "data_export_cleanup"
+	CronLeaderboard       = "leaderboard_refresh"
+)
+
+const (
+	// cronStatusKey 各任务最近一次执行结果（hash: 任务名 -> JSON），所有实例共享
+	cronStatusKey  = "cron:status"
+	cronLockPrefix = "lock:cron:"
+)
+
+// CronJob 定时任务定义
+type CronJob struct {
+	Name        string
+	Spec        string // cron 表达式（分 时 日 月 周）或 "@every 15m"
+	Description string
+	// Cluster 为 true 时多实例部署只由抢到锁的实例执行，锁在执行结束或 LockTTL 后释放；
+	// 为 false 时每个实例都执行（清理进程内缓存）
+	Cluster bool
+	LockTTL time.Duration
+	Run     func(ctx context.Context) error
+}
+
+// CronRun 一次执行的结果
+type CronRun struct {
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+	Instance   string    `json:"instance"`
+}
+
+// CronJobStatus 定时任务状态
+type CronJobStatus struct {
+	Name        string `json:"name"`
+	Spec        string `json:"spec"`
+	Description string `json:"description"`
+	Cluster     bool   `json:"cluster"`
+	Enabled     bool   `json:"enabled"`
+	// NextRunAt 本实例下一次触发的时间（任务关闭时仍会触发，但会直接跳过）
+	NextRunAt *time.Time `json:"next_run_at,omitempty"`
+	LastRun   *CronRun   `json:"last_run,omitempty"`
+}
+
+// Scheduler 定时任务调度器，托管所有周期性任务
+// 每个任务可通过运行时参数 cron_<name>_enabled 单独关闭，最近一次执行结果记录在Redis中
+type Scheduler struct {
+	db                  *gorm.DB
+	redisClient         *redis.Client
+	settings            *SystemSettingsService
+	notificationService *NotificationService
+
+	cron     *cron.Cron
+	jobs     []*CronJob
+	entries  map[string]cron.EntryID
+	instance string
+	ctx      context.Context
+	cancel   context.CancelFunc
+
+	// lastRuns 没有Redis时在进程内记录执行结果
+	lastRuns   map[string]*CronRun
+	lastRunsMu sync.Mutex
+}
+
+// NewScheduler 创建调度器并注册所有定时任务，Start 后开始执行
+func NewScheduler(deps Deps, svc *Services) *Scheduler {
+	hostname, _ := os.Hostname()
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Scheduler{
+		db:                  deps.DB,
+		redisClient:         deps.Redis,
+		settings:            svc.SystemSettings,
+		notificationService: svc.Notification,
+		cron:                cron.New(cron.WithChain(cron.Recover(cron.DefaultLogger))),
+		entries:             make(map[string]cron.EntryID),
+		instance:            fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		ctx:                 ctx,
+		cancel:              cancel,
+		lastRuns:            make(map[string]*CronRun),
+	}
+
+	savedSearchInterval := time.Duration(deps.Config.Notification.SavedSearchIntervalMinutes) * time.Minute
+	jobs := []*CronJob{
+		{
+			Name: CronIPBlockCleanup, Spec: "@every 5m", Description: "清理本实例已过期的IP封禁缓存",
+			Run: func(ctx context.Context) error { svc.Auth.CleanupIPBlocks(); return nil },
+		},
+		{
+			Name: CronOnlineUserCleanup, Spec: "@every 2m", Description: "清理本实例超过5分钟未活跃的在线用户",
+			Run: func(ctx context.Context) error { svc.Chat.CleanupOnlineUsers(ctx); return nil },
+		},
+		{
+			Name: CronSavedSearch, Spec: fmt.Sprintf("@every %s", savedSearchInterval), Description: "检查到期的保存搜索并通知新上架的书籍",
+			Cluster: true, LockTTL: savedSearchInterval,
+			Run: func(ctx context.Context) error { return svc.SavedSearch.RunDue(ctx) },
+		},
+		{
+			Name: CronStatsRollup, Spec: "@every 15m", Description: "把Redis中今天和昨天的计数器汇总到 daily_stats",
+			Cluster: true, LockTTL: 15 * time.Minute,
+			Run: func(ctx context.Context) error { return svc.Stats.RollupRecent(ctx) },
+		},
+		{
+			Name: CronListingExpiry, Spec: "@hourly", Description: "下架超过 listing_expiry_days 天未更新的在售发布",
+			Cluster: true, LockTTL: time.Hour,
+			Run: func(ctx context.Context) error { return s.expireStaleListings(ctx) },
+		},
+		{
+			Name: CronUploadGC, Spec: "@hourly", Description: "删除过期分片上传会话留下的分片",
+			Cluster: true, LockTTL: time.Hour,
+			Run: func(ctx context.Context) error { return svc.ChunkedUpload.CleanupExpired(ctx) },
+		},
+		{
+			Name: CronSoftDeletePurge, Spec: "30 3 * * *", Description: "彻底删除软删除超过 soft_delete_retention_days 天的记录",
+			Cluster: true, LockTTL: time.Hour,
+			Run: s.purgeSoftDeleted,
+		},
+		{
+			Name: CronReputationRollup, Spec: "0 4 * * *", Description: "根据成交、评价、回复速度和举报重新计算卖家信誉分",
+			Cluster: true, LockTTL: 2 * time.Hour,
+			Run: func(ctx context.Context) error { return svc.Reputation.RecomputeAll(ctx) },
+		},
+		{
+			Name: CronDataExportCleanup, Spec: "@hourly", Description: "删除过期的个人数据导出归档",
+			Cluster: true, LockTTL: time.Hour,
+			Run: func(ctx context.Context) error { return svc.DataExport.CleanupExpired(ctx) },
+		},
+		{
+			Name: CronLeaderboard, Spec: "@every 10m", Description: "重建本月卖家和回收达人排行并刷新公开排行榜",
+			Cluster: true, LockTTL: 10 * time.Minute,
+			Run: func(ctx context.Context) error { _, err := svc.Leaderboard.Refresh(ctx); return err },
+		},
+	}
+	for _, job := range jobs {
+		if err := s.Register(job); err != nil {
+			log.Printf("scheduler: %v", err)
+		}
+	}
+	return s
+}
+
+// Register 注册定时任务
+func (s *Scheduler) Register(job *CronJob) error {
+	id, err := s.cron.AddFunc(job.Spec, func() { s.run(job) })
+	if err != nil {
+		return fmt.Errorf("invalid schedule %q for %s: %w", job.Spec, job.Name, err)
+	}
+	s.jobs = append(s.jobs, job)
+	s.entries[job.Name] = id
+	return nil
+}
+
+// Start 开始按计划执行任务
+func (s *Scheduler) Start() {
+	s.cron.Start()
+}
+
+// Stop 停止触发新的执行并等待执行中的任务结束，ctx 超时后不再等待
+func (s *Scheduler) Stop(ctx context.Context) {
+	done := s.cron.Stop()
+	select {
+	case <-done.Done():
+	case <-ctx.Done():
+		s.cancel()
+	}
+}
+
+// Status 返回所有任务的开关、下一次触发时间和最近一次执行结果
+func (s *Scheduler) Status(ctx context.Context) []CronJobStatus {
+	lastRuns := s.loadLastRuns(ctx)
+
+	statuses := make([]CronJobStatus, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		status := CronJobStatus{
+			Name:        job.Name,
+			Spec:        job.Spec,
+			Description: job.Description,
+			Cluster:     job.Cluster,
+			Enabled:     s.jobEnabled(ctx, job.Name),
+			LastRun:     lastRuns[job.Name],
+		}
+		if next := s.cron.Entry(s.entries[job.Name]).Next; !next.IsZero() {
+			status.NextRunAt = &next
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// run 执行一次任务：检查开关，集群任务先抢锁，记录结果
+func (s *Scheduler) run(job *CronJob) {
+	if !s.jobEnabled(s.ctx, job.Name) {
+		return
+	}
+
+	if job.Cluster && s.redisClient != nil {
+		ok, err := s.redisClient.SetNX(s.ctx, cronLockPrefix+job.Name, s.instance, job.LockTTL).Result()
+		if err != nil || !ok {
+			// 其他实例正在执行或Redis不可用，本次跳过
+			return
+		}
+		defer s.redisClient.Del(context.Background(), cronLockPrefix+job.Name)
+	}
+
+	started := time.Now()
+	err := job.Run(s.ctx)
+	run := &CronRun{
+		StartedAt:  started,
+		DurationMs: time.Since(started).Milliseconds(),
+		Success:    err == nil,
+		Instance:   s.instance,
+	}
+	result := "success"
+	if err != nil {
+		run.Error = err.Error()
+		result = "failed"
+		log.Printf("cron %s failed: %v", job.Name, err)
+		utils.CaptureError(s.ctx, err, map[string]string{"cron": job.Name, "instance": s.instance})
+	}
+	utils.CronRunsTotal.WithLabelValues(job.Name, result).Inc()
+	s.saveLastRun(job.Name, run)
+}
+
+// saveLastRun 记录执行结果；只有集群任务写入Redis，各实例自己的清理任务只在本实例可见
+func (s *Scheduler) saveLastRun(name string, run *CronRun) {
+	s.lastRunsMu.Lock()
+	s.lastRuns[name] = run
+	s.lastRunsMu.Unlock()
+
+	if s.redisClient == nil {
+		return
+	}
+	for _, job := range s.jobs {
+		if job.Name == name && job.Cluster {
+			data, _ := json.Marshal(run)
+			s.redisClient.HSet(context.Background(), cronStatusKey, name, data)
+		}
+	}
+}
+
+// loadLastRuns 读取最近一次执行结果：集群任务以Redis中的记录为准（可能由其他实例执行）
+func (s *Scheduler) loadLastRuns(ctx context.Context) map[string]*CronRun {
+	s.lastRunsMu.Lock()
+	runs := make(map[string]*CronRun, len(s.lastRuns))
+	for name, run := range s.lastRuns {
+		runs[name] = run
+	}
+	s.lastRunsMu.Unlock()
+
+	if s.redisClient == nil {
+		return runs
+	}
+	stored, err := s.redisClient.HGetAll(ctx, cronStatusKey).Result()
+	if err != nil {
+		return runs
+	}
+	for name, data := range stored {
+		var run CronRun
+		if json.Unmarshal([]byte(data), &run) == nil {
+			runs[name] = &run
+		}
+	}
+	return runs
+}
+
+// jobEnabled 读取任务开关参数
+func (s *Scheduler) jobEnabled(ctx context.Context, name string) bool {
+	key := cronSettingKey(name)
+	if _, ok := settingDefinitions[key]; !ok {
+		return true
+	}
+	return s.settings.Bool(ctx, key)
+}
+
+// cronSettingKey 任务开关对应的运行时参数
+func cronSettingKey(name string) string {
+	return "cron_" + name + "_enabled"
+}
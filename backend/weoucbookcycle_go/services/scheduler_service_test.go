@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"weoucbookcycle_go/config"
+)
+
+// testDeps 按当前环境变量构造配置，不连接数据库和Redis
+func testDeps(t *testing.T) Deps {
+	t.Helper()
+	cfg, err := config.FromEnv()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	return Deps{Config: cfg}
+}
+
+// 关闭的任务不执行；执行结果（包括失败原因）出现在状态中
+func TestSchedulerRunRecordsStatus(t *testing.T) {
+	settings := NewSystemSettingsService(Deps{})
+	settings.cache.values = map[string]string{cronSettingKey(CronUploadGC): "false"}
+	settings.cache.loadedAt = time.Now()
+
+	s := NewScheduler(testDeps(t), &Services{SystemSettings: settings})
+	ran := false
+	failing := &CronJob{Name: "test_failing", Spec: "@hourly", Run: func(ctx context.Context) error { return errors.New("boom") }}
+	disabled := &CronJob{Name: CronUploadGC, Spec: "@hourly", Run: func(ctx context.Context) error { ran = true; return nil }}
+	s.run(failing)
+	s.run(disabled)
+	if ran {
+		t.Fatal("disabled job should not run")
+	}
+
+	var found bool
+	for _, status := range s.Status(context.Background()) {
+		switch status.Name {
+		case "test_failing":
+			t.Fatalf("unregistered job should not be reported: %+v", status)
+		case CronUploadGC:
+			if status.Enabled || status.NextRunAt != nil {
+				t.Fatalf("unexpected status before start: %+v", status)
+			}
+		case CronStatsRollup:
+			found = status.Enabled && status.Cluster
+		}
+	}
+	if !found {
+		t.Fatal("stats rollup job not reported as enabled cluster job")
+	}
+
+	if run := s.loadLastRuns(context.Background())["test_failing"]; run == nil || run.Success || run.Error != "boom" {
+		t.Fatalf("expected failed run to be recorded, got %+v", run)
+	}
+}
+
+// 非法的执行计划在注册时报错
+func TestSchedulerRegisterRejectsInvalidSpec(t *testing.T) {
+	s := NewScheduler(testDeps(t), &Services{SystemSettings: NewSystemSettingsService(Deps{})})
+	if err := s.Register(&CronJob{Name: "bad", Spec: "every minute"}); err == nil {
+		t.Fatal("expected error for invalid spec")
+	}
+}
@@ -0,0 +1,286 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	searchEventsStream   = "search_events"
+	searchAnalyticsGroup = "search_analytics"
+	searchAnalyticsBatch = 100
+)
+
+// SearchAnalyticsService 搜索分析服务
+type SearchAnalyticsService struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+}
+
+// NewSearchAnalyticsService 创建搜索分析服务实例
+func NewSearchAnalyticsService(deps Deps) *SearchAnalyticsService {
+	return &SearchAnalyticsService{
+		db:          deps.DB,
+		redisClient: deps.Redis,
+	}
+}
+
+// SearchClickRequest 搜索结果点击上报请求
+type SearchClickRequest struct {
+	SearchID   string `json:"search_id" binding:"required,max=36"`
+	ResultType string `json:"result_type" binding:"required,oneof=book user listing"`
+	ResultID   string `json:"result_id" binding:"required,max=36"`
+	Position   int    `json:"position" binding:"omitempty,min=0"`
+}
+
+// QueryStat 查询统计
+type QueryStat struct {
+	Query           string  `json:"query"`
+	Searches        int64   `json:"searches"`
+	AvgResults      float64 `json:"avg_results"`
+	ZeroResults     int64   `json:"zero_results"`
+	ClickedSearches int64   `json:"clicked_searches"`
+	CTR             float64 `json:"ctr"`
+}
+
+// ==================== 事件采集 ====================
+
+// RecordSearch 记录一次搜索，返回本次搜索的 search_id
+// 事件写入 search_events 流，由消费者异步落库
+func (sas *SearchAnalyticsService) RecordSearch(ctx context.Context, userID, query, searchType string, resultCount int64) string {
+	searchID := uuid.New().String()
+
+	values := map[string]interface{}{
+		"event":        "search",
+		"search_id":    searchID,
+		"user_id":      userID,
+		"query":        query,
+		"search_type":  searchType,
+		"result_count": resultCount,
+		"timestamp":    time.Now().Unix(),
+	}
+
+	utils.Go(ctx, "search_events", func(ctx context.Context) error {
+		if sas.redisClient != nil {
+			return sas.redisClient.XAdd(ctx, &redis.XAddArgs{
+				Stream: searchEventsStream,
+				MaxLen: 100000,
+				Approx: true,
+				Values: values,
+			}).Err()
+		}
+		// 没有Redis时直接落库
+		if sas.db != nil {
+			return sas.db.WithContext(ctx).Create(searchEventFromValues(values)).Error
+		}
+		return nil
+	})
+
+	return searchID
+}
+
+// RecordClick 记录一次搜索结果点击
+func (sas *SearchAnalyticsService) RecordClick(ctx context.Context, userID string, req *SearchClickRequest) error {
+	values := map[string]interface{}{
+		"event":       "click",
+		"search_id":   req.SearchID,
+		"user_id":     userID,
+		"result_type": req.ResultType,
+		"result_id":   req.ResultID,
+		"position":    req.Position,
+		"timestamp":   time.Now().Unix(),
+	}
+
+	if sas.redisClient == nil {
+		return sas.db.WithContext(ctx).Create(searchClickFromValues(values)).Error
+	}
+
+	return sas.redisClient.XAdd(ctx, &redis.XAddArgs{
+		Stream: searchEventsStream,
+		MaxLen: 100000,
+		Approx: true,
+		Values: values,
+	}).Err()
+}
+
+// ==================== 事件消费 ====================
+
+// Start 启动搜索事件消费者，把 search_events 流写入数据库
+func (sas *SearchAnalyticsService) Start(ctx context.Context) {
+	startBatchEventConsumer(ctx, sas.redisClient, searchAnalyticsGroup, []string{searchEventsStream}, searchAnalyticsBatch, sas.handleEvents)
+}
+
+// handleEvents 批量落库，写入失败时整批保持未确认
+func (sas *SearchAnalyticsService) handleEvents(ctx context.Context, stream string, messages []redis.XMessage) error {
+	var events []*models.SearchEvent
+	var clicks []*models.SearchClick
+
+	for _, msg := range messages {
+		switch msg.Values["event"] {
+		case "search":
+			events = append(events, searchEventFromValues(msg.Values))
+		case "click":
+			clicks = append(clicks, searchClickFromValues(msg.Values))
+		}
+	}
+
+	if len(events) > 0 {
+		if err := sas.db.CreateInBatches(events, searchAnalyticsBatch).Error; err != nil {
+			return fmt.Errorf("failed to save search events: %w", err)
+		}
+	}
+	if len(clicks) > 0 {
+		if err := sas.db.CreateInBatches(clicks, searchAnalyticsBatch).Error; err != nil {
+			return fmt.Errorf("failed to save search clicks: %w", err)
+		}
+	}
+	return nil
+}
+
+// ==================== 管理员报表 ====================
+
+// TopQueries 获取搜索次数最多的查询
+func (sas *SearchAnalyticsService) TopQueries(since time.Time, limit int) ([]QueryStat, error) {
+	var stats []QueryStat
+	err := sas.db.Model(&models.SearchEvent{}).
+		Select("normalized_query AS query, COUNT(*) AS searches, AVG(result_count) AS avg_results, "+
+			"SUM(CASE WHEN result_count = 0 THEN 1 ELSE 0 END) AS zero_results").
+		Where("created_at >= ?", since).
+		Group("normalized_query").
+		Order("searches DESC").
+		Limit(limit).
+		Scan(&stats).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top queries: %w", err)
+	}
+	return stats, nil
+}
+
+// ZeroResultQueries 获取没有结果的查询
+func (sas *SearchAnalyticsService) ZeroResultQueries(since time.Time, limit int) ([]QueryStat, error) {
+	var stats []QueryStat
+	err := sas.db.Model(&models.SearchEvent{}).
+		Select("normalized_query AS query, COUNT(*) AS searches, COUNT(*) AS zero_results").
+		Where("created_at >= ? AND result_count = 0", since).
+		Group("normalized_query").
+		Order("searches DESC").
+		Limit(limit).
+		Scan(&stats).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get zero-result queries: %w", err)
+	}
+	return stats, nil
+}
+
+// ClickThroughRate 获取整体和各查询的点击率
+func (sas *SearchAnalyticsService) ClickThroughRate(since time.Time, limit int) (map[string]interface{}, error) {
+	var overall struct {
+		Searches        int64
+		ClickedSearches int64
+	}
+	err := sas.db.Table("search_events AS e").
+		Select("COUNT(DISTINCT e.id) AS searches, COUNT(DISTINCT c.search_id) AS clicked_searches").
+		Joins("LEFT JOIN search_clicks AS c ON c.search_id = e.id").
+		Where("e.created_at >= ?", since).
+		Scan(&overall).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get click-through rate: %w", err)
+	}
+
+	var stats []QueryStat
+	err = sas.db.Table("search_events AS e").
+		Select("e.normalized_query AS query, COUNT(DISTINCT e.id) AS searches, "+
+			"COUNT(DISTINCT c.search_id) AS clicked_searches").
+		Joins("LEFT JOIN search_clicks AS c ON c.search_id = e.id").
+		Where("e.created_at >= ?", since).
+		Group("e.normalized_query").
+		Order("searches DESC").
+		Limit(limit).
+		Scan(&stats).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get click-through rate: %w", err)
+	}
+
+	for i := range stats {
+		stats[i].CTR = ratio(stats[i].ClickedSearches, stats[i].Searches)
+	}
+
+	return map[string]interface{}{
+		"searches":         overall.Searches,
+		"clicked_searches": overall.ClickedSearches,
+		"ctr":              ratio(overall.ClickedSearches, overall.Searches),
+		"queries":          stats,
+	}, nil
+}
+
+// ==================== 辅助方法 ====================
+
+// searchEventFromValues 将流消息转换为搜索事件
+func searchEventFromValues(values map[string]interface{}) *models.SearchEvent {
+	query := streamString(values["query"])
+	return &models.SearchEvent{
+		ID:              streamString(values["search_id"]),
+		UserID:          streamString(values["user_id"]),
+		Query:           query,
+		NormalizedQuery: normalizeQuery(query),
+		SearchType:      streamString(values["search_type"]),
+		ResultCount:     streamInt64(values["result_count"]),
+		CreatedAt:       streamTime(values["timestamp"]),
+	}
+}
+
+// searchClickFromValues 将流消息转换为点击记录
+func searchClickFromValues(values map[string]interface{}) *models.SearchClick {
+	return &models.SearchClick{
+		SearchID:   streamString(values["search_id"]),
+		UserID:     streamString(values["user_id"]),
+		ResultType: streamString(values["result_type"]),
+		ResultID:   streamString(values["result_id"]),
+		Position:   int(streamInt64(values["position"])),
+		CreatedAt:  streamTime(values["timestamp"]),
+	}
+}
+
+// normalizeQuery 统一查询格式，便于聚合
+func normalizeQuery(query string) string {
+	return strings.ToLower(strings.Join(strings.Fields(query), " "))
+}
+
+// streamString 读取流字段（Redis返回字符串，直接落库时为原始类型）
+func streamString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// streamInt64 读取整型流字段
+func streamInt64(v interface{}) int64 {
+	n, _ := strconv.ParseInt(streamString(v), 10, 64)
+	return n
+}
+
+// streamTime 读取Unix时间戳流字段
+func streamTime(v interface{}) time.Time {
+	if ts := streamInt64(v); ts > 0 {
+		return time.Unix(ts, 0)
+	}
+	return time.Now()
+}
+
+// ratio 计算比率，分母为0时返回0
+func ratio(numerator, denominator int64) float64 {
+	if denominator == 0 {
+		return 0
+	}
+	return float64(numerator) / float64(denominator)
+}
@@ -0,0 +1,271 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	searchIndexIDsKey      = "book:index:ids"
+	searchIndexPendingKey  = "search:index:pending"
+	searchIndexLastRunKey  = "search:index:last_reindex"
+	searchReindexLockKey   = "lock:search_reindex"
+	searchReindexLockTTL   = time.Hour
+	searchReindexBatchSize = 500
+)
+
+// ErrReindexRunning 已有重建索引任务在运行
+var ErrReindexRunning = utils.NewError(http.StatusConflict, "a reindex job is already running")
+
+// SearchIndexService 搜索索引管理服务
+// 纠错词表缓存保存在实例上，书籍和管理服务共享 Services.SearchIndex
+type SearchIndexService struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+	synonyms    *SynonymService
+	vocabulary  searchVocabulary
+	// correctionThreshold 命中数低于该值时给出纠错建议（SEARCH_CORRECTION_THRESHOLD）
+	correctionThreshold int
+}
+
+// NewSearchIndexService 创建搜索索引服务实例
+func NewSearchIndexService(deps Deps, synonyms *SynonymService) *SearchIndexService {
+	return &SearchIndexService{
+		db:          deps.DB,
+		redisClient: deps.Redis,
+		synonyms:    synonyms,
+
+		correctionThreshold: deps.Config.Search.CorrectionThreshold,
+	}
+}
+
+// ReindexRequest 重建索引请求
+type ReindexRequest struct {
+	// Entity 重建范围：all（默认）、books、vocabulary
+	Entity string `json:"entity" binding:"omitempty,oneof=all books vocabulary"`
+	// BookID 仅重建单本书的索引
+	BookID string `json:"book_id" binding:"omitempty,max=36"`
+}
+
+// IndexHealth 索引健康状况
+type IndexHealth struct {
+	Books            int64             `json:"books"`
+	IndexedDocuments int64             `json:"indexed_documents"`
+	MissingDocuments int64             `json:"missing_documents"`
+	VocabularySize   int64             `json:"vocabulary_size"`
+	SynonymGroups    int               `json:"synonym_groups"`
+	PendingQueue     int64             `json:"pending_queue"`
+	ReindexRunning   bool              `json:"reindex_running"`
+	LastReindex      map[string]string `json:"last_reindex,omitempty"`
+}
+
+// ==================== 重建索引 ====================
+
+// AcquireReindexLock 获取重建索引锁，同一时间只允许一个任务
+func (sis *SearchIndexService) AcquireReindexLock(ctx context.Context) error {
+	if sis.redisClient == nil {
+		return errors.New("redis not available")
+	}
+	ok, err := sis.redisClient.SetNX(ctx, searchReindexLockKey, time.Now().Unix(), searchReindexLockTTL).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrReindexRunning
+	}
+	return nil
+}
+
+// Reindex 按请求重建索引，需先获取重建索引锁，完成后自动释放
+func (sis *SearchIndexService) Reindex(ctx context.Context, req *ReindexRequest, progress func(done, total int64)) error {
+	defer sis.redisClient.Del(ctx, searchReindexLockKey)
+
+	startedAt := time.Now()
+	var err error
+
+	switch {
+	case req.BookID != "":
+		err = sis.reindexBook(ctx, req.BookID)
+		progress(1, 1)
+	case req.Entity == "vocabulary":
+		_, err = sis.RebuildVocabulary(ctx)
+		progress(1, 1)
+	default:
+		if err = sis.reindexBooks(ctx, progress); err == nil {
+			_, err = sis.RebuildVocabulary(ctx)
+		}
+	}
+
+	status := "completed"
+	errorMsg := ""
+	if err != nil {
+		status = "failed"
+		errorMsg = err.Error()
+	}
+	entity := req.Entity
+	if entity == "" {
+		entity = "all"
+	}
+	sis.redisClient.HSet(ctx, searchIndexLastRunKey, map[string]interface{}{
+		"entity":      entity,
+		"book_id":     req.BookID,
+		"status":      status,
+		"error":       errorMsg,
+		"started_at":  startedAt.Unix(),
+		"finished_at": time.Now().Unix(),
+	})
+
+	return err
+}
+
+// reindexBooks 分批重建全部书籍索引，并清理已不存在的文档
+func (sis *SearchIndexService) reindexBooks(ctx context.Context, progress func(done, total int64)) error {
+	var total int64
+	if err := sis.db.WithContext(ctx).Model(&models.Book{}).Count(&total).Error; err != nil {
+		return fmt.Errorf("failed to count books: %w", err)
+	}
+
+	// 本次重建写入的文档ID记录在临时集合中，用于找出残留文档
+	rebuildKey := searchIndexIDsKey + ":rebuild"
+	sis.redisClient.Del(ctx, rebuildKey)
+
+	var done int64
+	var books []models.Book
+	result := sis.db.WithContext(ctx).Model(&models.Book{}).FindInBatches(&books, searchReindexBatchSize, func(tx *gorm.DB, batch int) error {
+		for i := range books {
+			if books[i].Status == 1 {
+				sis.indexBookDocument(ctx, &books[i])
+				sis.redisClient.SAdd(ctx, rebuildKey, books[i].ID)
+			} else {
+				sis.removeBookDocument(ctx, books[i].ID)
+			}
+		}
+		done += int64(len(books))
+		progress(done, total)
+		return nil
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to reindex books: %w", result.Error)
+	}
+
+	// 删除已被删除或下架的书籍留下的文档
+	// 重建期间新上架的书籍也不在临时集合中，需要以数据库为准
+	stale, _ := sis.redisClient.SDiff(ctx, searchIndexIDsKey, rebuildKey).Result()
+	if len(stale) > 0 {
+		var alive []string
+		sis.db.WithContext(ctx).Model(&models.Book{}).Where("id IN ? AND status = ?", stale, 1).Pluck("id", &alive)
+		keep := make(map[string]bool, len(alive))
+		for _, id := range alive {
+			keep[id] = true
+		}
+		for _, id := range stale {
+			if !keep[id] {
+				sis.removeBookDocument(ctx, id)
+			}
+		}
+	}
+	sis.redisClient.Del(ctx, rebuildKey)
+
+	return nil
+}
+
+// reindexBook 重建单本书的索引
+func (sis *SearchIndexService) reindexBook(ctx context.Context, bookID string) error {
+	var book models.Book
+	err := sis.db.First(&book, "id = ?", bookID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		sis.removeBookDocument(ctx, bookID)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load book: %w", err)
+	}
+
+	if book.Status == 1 {
+		sis.indexBookDocument(ctx, &book)
+	} else {
+		sis.removeBookDocument(ctx, book.ID)
+	}
+	return nil
+}
+
+// ==================== 健康检查 ====================
+
+// Health 获取索引健康状况
+func (sis *SearchIndexService) Health(ctx context.Context) (*IndexHealth, error) {
+	health := &IndexHealth{
+		SynonymGroups: len(sis.synonyms.currentGroups(ctx)),
+	}
+
+	if err := sis.db.WithContext(ctx).Model(&models.Book{}).Where("status = ?", 1).Count(&health.Books).Error; err != nil {
+		return nil, fmt.Errorf("failed to count books: %w", err)
+	}
+
+	if sis.redisClient != nil {
+		health.IndexedDocuments, _ = sis.redisClient.SCard(ctx, searchIndexIDsKey).Result()
+		health.VocabularySize, _ = sis.redisClient.ZCard(ctx, searchVocabKey).Result()
+		health.PendingQueue, _ = sis.redisClient.Get(ctx, searchIndexPendingKey).Int64()
+		if health.PendingQueue < 0 {
+			health.PendingQueue = 0
+		}
+		running, _ := sis.redisClient.Exists(ctx, searchReindexLockKey).Result()
+		health.ReindexRunning = running > 0
+		if last, err := sis.redisClient.HGetAll(ctx, searchIndexLastRunKey).Result(); err == nil && len(last) > 0 {
+			health.LastReindex = last
+		}
+	}
+
+	if health.Books > health.IndexedDocuments {
+		health.MissingDocuments = health.Books - health.IndexedDocuments
+	}
+
+	return health, nil
+}
+
+// ==================== 索引文档 ====================
+
+// indexBookDocument 将书籍写入搜索索引（Redis Hash）并加入词表
+func (sis *SearchIndexService) indexBookDocument(ctx context.Context, book *models.Book) {
+	if sis.redisClient == nil {
+		return
+	}
+
+	indexKey := fmt.Sprintf("book:index:%s", book.ID)
+	bookData := map[string]interface{}{
+		"id":         book.ID,
+		"title":      book.Title,
+		"author":     book.Author,
+		"category":   book.Category,
+		"price":      book.Price,
+		"condition":  book.Condition,
+		"seller_id":  book.SellerID,
+		"status":     book.Status,
+		"created_at": book.CreatedAt.Unix(),
+		"updated_at": book.UpdatedAt.Unix(),
+	}
+
+	// 索引文档不再设置过期时间，由重建索引任务清理残留文档
+	sis.redisClient.HSet(ctx, indexKey, bookData)
+	sis.redisClient.SAdd(ctx, searchIndexIDsKey, book.ID)
+
+	// 加入搜索纠错词表
+	sis.AddToVocabulary(ctx, book.Title, book.Author)
+}
+
+// removeBookDocument 从搜索索引中移除书籍
+func (sis *SearchIndexService) removeBookDocument(ctx context.Context, bookID string) {
+	if sis.redisClient == nil {
+		return
+	}
+
+	sis.redisClient.Del(ctx, fmt.Sprintf("book:index:%s", bookID))
+	sis.redisClient.SRem(ctx, searchIndexIDsKey, bookID)
+}
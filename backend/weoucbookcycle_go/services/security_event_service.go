@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	securityArchiveGroup = "security_archivers"
+	// securityStreamMaxLen 归档后Redis流只保留最近的事件，长期数据在MySQL中
+	securityStreamMaxLen = 100000
+)
+
+// securityEventStreams 需要归档的安全事件流
+var securityEventStreams = []string{"security_events", "login_failures"}
+
+// ErrUnknownSecurityStream 不是安全事件流
+var ErrUnknownSecurityStream = utils.NewError(http.StatusBadRequest, "unknown security event stream")
+
+// SecurityEventService 安全事件服务
+type SecurityEventService struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+}
+
+// NewSecurityEventService 创建安全事件服务实例
+func NewSecurityEventService(deps Deps) *SecurityEventService {
+	return &SecurityEventService{
+		db:          deps.DB,
+		redisClient: deps.Redis,
+	}
+}
+
+// SecurityEventQuery 安全事件查询条件
+type SecurityEventQuery struct {
+	Stream string
+	Event  string
+	IP     string
+	Email  string
+	UserID string
+	From   time.Time
+	To     time.Time
+	Page   int
+	Limit  int
+}
+
+// LiveSecurityEvents Redis流中的近期事件，NextCursor 为下一页的起始ID
+type LiveSecurityEvents struct {
+	Events     []models.SecurityEvent `json:"events"`
+	NextCursor string                 `json:"next_cursor,omitempty"`
+}
+
+// Start 启动安全事件归档消费者，把流中的事件持久化到MySQL
+func (ses *SecurityEventService) Start(ctx context.Context) {
+	// 从头开始消费，首次部署时归档流中已有的事件
+	startBatchEventConsumer(ctx, ses.redisClient, securityArchiveGroup, securityEventStreams, 200, ses.archive)
+}
+
+// archive 批量写入归档表，重复投递的事件按 stream+stream_id 去重
+// 归档成功后裁剪Redis流，只保留最近的事件
+func (ses *SecurityEventService) archive(ctx context.Context, stream string, messages []redis.XMessage) error {
+	events := make([]models.SecurityEvent, len(messages))
+	for i, msg := range messages {
+		events[i] = securityEventFromMessage(stream, msg)
+	}
+	if err := ses.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&events).Error; err != nil {
+		return err
+	}
+	ses.redisClient.XTrimMaxLenApprox(ctx, stream, securityStreamMaxLen, 0)
+	return nil
+}
+
+// securityEventFromMessage 把流消息转换为安全事件
+func securityEventFromMessage(stream string, msg redis.XMessage) models.SecurityEvent {
+	event := streamString(msg.Values["event"])
+	if event == "" && stream == "login_failures" {
+		event = "login_failure"
+	}
+
+	details, _ := json.Marshal(msg.Values)
+	return models.SecurityEvent{
+		Stream:     stream,
+		StreamID:   msg.ID,
+		Event:      event,
+		IP:         streamString(msg.Values["ip"]),
+		Email:      streamString(msg.Values["email"]),
+		UserID:     streamString(msg.Values["user_id"]),
+		Reason:     truncateRunes(streamString(msg.Values["reason"]), 255),
+		Details:    details,
+		OccurredAt: streamTime(msg.Values["timestamp"]),
+	}
+}
+
+// isSecurityEventStream 是否为安全事件流
+func isSecurityEventStream(stream string) bool {
+	for _, s := range securityEventStreams {
+		if s == stream {
+			return true
+		}
+	}
+	return false
+}
+
+// List 分页查询归档的安全事件（按时间倒序）
+func (ses *SecurityEventService) List(q *SecurityEventQuery) ([]models.SecurityEvent, int64, error) {
+	query := ses.db.Model(&models.SecurityEvent{})
+	if q.Stream != "" {
+		query = query.Where("stream = ?", q.Stream)
+	}
+	if q.Event != "" {
+		query = query.Where("event = ?", q.Event)
+	}
+	if q.IP != "" {
+		query = query.Where("ip = ?", q.IP)
+	}
+	if q.Email != "" {
+		query = query.Where("email = ?", q.Email)
+	}
+	if q.UserID != "" {
+		query = query.Where("user_id = ?", q.UserID)
+	}
+	if !q.From.IsZero() {
+		query = query.Where("occurred_at >= ?", q.From)
+	}
+	if !q.To.IsZero() {
+		query = query.Where("occurred_at < ?", q.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count security events: %w", err)
+	}
+
+	var events []models.SecurityEvent
+	if err := query.Order("occurred_at DESC").Offset((q.Page - 1) * q.Limit).Limit(q.Limit).Find(&events).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list security events: %w", err)
+	}
+	return events, total, nil
+}
+
+// Live 直接从Redis流倒序读取近期事件（含尚未归档的），cursor 为上一页返回的 NextCursor
+// event/ip 过滤在读取后进行，因此一页可能少于 limit 条
+func (ses *SecurityEventService) Live(ctx context.Context, stream, cursor, event, ip string, limit int) (*LiveSecurityEvents, error) {
+	if ses.redisClient == nil {
+		return nil, errors.New("redis not available")
+	}
+	if !isSecurityEventStream(stream) {
+		return nil, ErrUnknownSecurityStream
+	}
+
+	end := "+"
+	if cursor != "" {
+		// 排他区间：从游标之前的消息开始
+		end = "(" + cursor
+	}
+
+	messages, err := ses.redisClient.XRevRangeN(ctx, stream, end, "-", int64(limit)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", stream, err)
+	}
+
+	result := &LiveSecurityEvents{Events: make([]models.SecurityEvent, 0, len(messages))}
+	for _, msg := range messages {
+		e := securityEventFromMessage(stream, msg)
+		if (event != "" && e.Event != event) || (ip != "" && e.IP != ip) {
+			continue
+		}
+		result.Events = append(result.Events, e)
+	}
+	if len(messages) == limit {
+		result.NextCursor = messages[len(messages)-1].ID
+	}
+
+	return result, nil
+}
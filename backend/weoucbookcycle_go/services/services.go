@@ -0,0 +1,121 @@
+package services
+
+import (
+	"weoucbookcycle_go/config"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// Deps 服务依赖的配置和基础设施连接，由 main 在加载配置、初始化数据库和Redis后传入
+type Deps struct {
+	Config *config.Config
+	DB     *gorm.DB
+	Redis  *redis.Client
+}
+
+// Services 进程内共享的服务实例，由 NewServices 统一创建后注入控制器
+// 认证、书籍、聊天服务在构造时注册后台任务处理函数并启动清理goroutine，因此只能通过这里获取
+type Services struct {
+	AccessLog       *AccessLogService
+	Account         *AccountService
+	Admin           *AdminService
+	Announcement    *AnnouncementService
+	Auth            *AuthService
+	Badge           *BadgeService
+	Block           *BlockService
+	Book            *BookService
+	CacheAdmin      *CacheAdminService
+	Campus          *CampusService
+	Chat            *ChatService
+	ChunkedUpload   *ChunkedUploadService
+	Dashboard       *DashboardService
+	DataExport      *DataExportService
+	Donation        *DonationService
+	EmailDeadLetter *EmailDeadLetterService
+	Export          *ExportService
+	File            *FileService
+	Follow          *FollowService
+	Identity        *IdentityService
+	Impersonation   *ImpersonationService
+	Leaderboard     *LeaderboardService
+	Moderation      *ModerationService
+	Notification    *NotificationService
+	Points          *PointsService
+	Profile         *ProfileService
+	Push            *PushService
+	QRCode          *QRCodeService
+	QueueMonitor    *QueueMonitorService
+	Referral        *ReferralService
+	Report          *ReportService
+	Reporting       *ReportingETLService
+	Reputation      *ReputationService
+	SavedSearch     *SavedSearchService
+	Scheduler       *Scheduler
+	SearchAnalytics *SearchAnalyticsService
+	SearchIndex     *SearchIndexService
+	SecurityEvent   *SecurityEventService
+	ShareCard       *ShareCardService
+	Stats           *StatsService
+	StorageUsage    *StorageUsageService
+	Synonym         *SynonymService
+	SystemSettings  *SystemSettingsService
+	Thumbnail       *ThumbnailService
+	UserSettings    *UserSettingsService
+	Username        *UsernameService
+	Webhook         *WebhookService
+}
+
+// NewServices 创建全部服务，定时任务调度器需要调用 Scheduler.Start 后才开始执行
+func NewServices(deps Deps) *Services {
+	svc := &Services{
+		AccessLog:       NewAccessLogService(deps),
+		Account:         NewAccountService(deps),
+		Announcement:    NewAnnouncementService(deps),
+		Campus:          NewCampusService(deps),
+		ChunkedUpload:   NewChunkedUploadService(deps),
+		DataExport:      NewDataExportService(deps),
+		EmailDeadLetter: NewEmailDeadLetterService(deps),
+		Export:          NewExportService(deps),
+		File:            NewFileService(deps),
+		Follow:          NewFollowService(deps),
+		Impersonation:   NewImpersonationService(deps),
+		Leaderboard:     NewLeaderboardService(deps),
+		QRCode:          NewQRCodeService(deps),
+		QueueMonitor:    NewQueueMonitorService(deps),
+		Referral:        NewReferralService(deps),
+		Report:          NewReportService(deps),
+		Reporting:       NewReportingETLService(deps),
+		Reputation:      NewReputationService(deps),
+		SearchAnalytics: NewSearchAnalyticsService(deps),
+		SecurityEvent:   NewSecurityEventService(deps),
+		ShareCard:       NewShareCardService(deps),
+		Stats:           NewStatsService(deps),
+		StorageUsage:    NewStorageUsageService(deps),
+		Synonym:         NewSynonymService(deps),
+		SystemSettings:  NewSystemSettingsService(deps),
+		Thumbnail:       NewThumbnailService(deps),
+		UserSettings:    NewUserSettingsService(deps),
+		Username:        NewUsernameService(deps),
+		Webhook:         NewWebhookService(deps),
+	}
+	svc.Auth = NewAuthService(deps, svc.SystemSettings)
+	svc.CacheAdmin = NewCacheAdminService(deps, svc.SystemSettings)
+	svc.Points = NewPointsService(deps, svc.SystemSettings)
+	svc.SearchIndex = NewSearchIndexService(deps, svc.Synonym)
+	svc.Admin = NewAdminService(deps, svc.SearchIndex)
+	svc.Book = NewBookService(deps, svc.SearchIndex, svc.Synonym, svc.SystemSettings)
+	svc.Push = NewPushService(deps, svc.UserSettings)
+	svc.Chat = NewChatService(deps, svc.SystemSettings, svc.Push)
+	svc.Notification = NewNotificationService(deps, svc.Push)
+	svc.Moderation = NewModerationService(deps, svc.Admin, svc.Notification)
+	svc.SavedSearch = NewSavedSearchService(deps, svc.Notification, svc.Synonym)
+	svc.Badge = NewBadgeService(deps, svc.Notification)
+	svc.Block = NewBlockService(deps, svc.Follow)
+	svc.Dashboard = NewDashboardService(deps, svc.Chat, svc.Notification)
+	svc.Donation = NewDonationService(deps, svc.Notification)
+	svc.Identity = NewIdentityService(deps, svc.Auth)
+	svc.Profile = NewProfileService(deps, svc.Username)
+	svc.Scheduler = NewScheduler(deps, svc)
+	return svc
+}
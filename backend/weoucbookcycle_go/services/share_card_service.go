@@ -0,0 +1,187 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// shareCardCacheTTL 渲染后的分享卡片缓存时间，价格等变化最多延迟这么久出现在预览中
+const shareCardCacheTTL = 30 * time.Minute
+
+// ShareCard 分享链接的预览信息（Open Graph）
+type ShareCard struct {
+	Title       string  `json:"title"`
+	Description string  `json:"description"`
+	Image       string  `json:"image,omitempty"`
+	URL         string  `json:"url"`
+	Price       float64 `json:"price"`
+	SiteName    string  `json:"site_name"`
+}
+
+// shareCardTemplate 供微信、QQ等抓取预览的页面，浏览器打开时跳转到分享链接
+var shareCardTemplate = template.Must(template.New("share_card").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<meta name="description" content="{{.Description}}">
+<meta property="og:type" content="product">
+<meta property="og:site_name" content="{{.SiteName}}">
+<meta property="og:title" content="{{.Title}}">
+<meta property="og:description" content="{{.Description}}">
+<meta property="og:url" content="{{.URL}}">
+{{- if .Image}}
+<meta property="og:image" content="{{.Image}}">
+<meta itemprop="image" content="{{.Image}}">
+{{- end}}
+<meta property="product:price:amount" content="{{printf "%.2f" .Price}}">
+<meta property="product:price:currency" content="CNY">
+<meta itemprop="name" content="{{.Title}}">
+<meta itemprop="description" content="{{.Description}}">
+<meta name="twitter:card" content="summary">
+<meta http-equiv="refresh" content="0; url={{.URL}}">
+</head>
+<body><a href="{{.URL}}">{{.Title}}</a></body>
+</html>
+`))
+
+// ShareCardService 渲染书籍和发布的分享预览页面，结果缓存在 Redis 中
+type ShareCardService struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+	storage     utils.Storage
+	shareBase   string
+}
+
+// NewShareCardService 创建分享卡片服务实例
+func NewShareCardService(deps Deps) *ShareCardService {
+	return &ShareCardService{
+		db:          deps.DB,
+		redisClient: deps.Redis,
+		storage:     utils.GetStorage(),
+		shareBase:   shareBaseURL(deps.Config),
+	}
+}
+
+// Render 获取书籍或发布分享预览页面的HTML，lang 为卡片文案的语言
+func (ss *ShareCardService) Render(ctx context.Context, target, id, lang string) ([]byte, error) {
+	cacheKey := fmt.Sprintf("share:%s:%s:%s", target, id, lang)
+	if ss.redisClient != nil {
+		if cached, err := ss.redisClient.Get(ctx, cacheKey).Bytes(); err == nil {
+			utils.RecordCacheHit("share_cards")
+			return cached, nil
+		}
+		utils.RecordCacheMiss("share_cards")
+	}
+
+	card, err := ss.Card(ctx, target, id, lang)
+	if err != nil {
+		return nil, err
+	}
+	page, err := renderShareCard(card)
+	if err != nil {
+		return nil, err
+	}
+
+	if ss.redisClient != nil {
+		ss.redisClient.Set(context.WithoutCancel(ctx), cacheKey, page, shareCardCacheTTL)
+	}
+	return page, nil
+}
+
+// Card 获取书籍或发布的分享预览信息，已删除或卖家停用账号时返回404
+func (ss *ShareCardService) Card(ctx context.Context, target, id, lang string) (*ShareCard, error) {
+	db := ss.db.WithContext(ctx)
+	var book models.Book
+	var price float64
+	var donation bool
+
+	switch target {
+	case ShareTargetBook:
+		if err := db.Scopes(VisibleUsers("books.seller_id")).First(&book, "books.id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, utils.NewError(http.StatusNotFound, "Book not found")
+			}
+			return nil, fmt.Errorf("failed to load book: %w", err)
+		}
+		price = book.Price
+	case ShareTargetListing:
+		var listing models.Listing
+		if err := db.Preload("Book").Scopes(VisibleUsers("listings.seller_id")).First(&listing, "listings.id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, utils.NewError(http.StatusNotFound, "Listing not found")
+			}
+			return nil, fmt.Errorf("failed to load listing: %w", err)
+		}
+		book, price, donation = listing.Book, listing.Price, listing.IsDonation
+	default:
+		return nil, fmt.Errorf("unknown share target %q", target)
+	}
+
+	return &ShareCard{
+		Title:       book.Title,
+		Description: shareCardDescription(lang, &book, price, donation),
+		Image:       ss.coverThumbnail(ctx, book.Images),
+		URL:         shareURL(ss.shareBase, target, id),
+		Price:       price,
+		SiteName:    utils.T(lang, "share.site_name"),
+	}, nil
+}
+
+// coverThumbnail 封面（第一张图片）的缩略图URL，没有缩略图时使用原图
+func (ss *ShareCardService) coverThumbnail(ctx context.Context, images string) string {
+	var urls []string
+	if json.Unmarshal([]byte(images), &urls) != nil || len(urls) == 0 {
+		return ""
+	}
+	cover := urls[0]
+	key, ok := utils.KeyFromURL(ss.storage, cover)
+	if !ok || utils.IsThumbnailKey(key) {
+		return cover
+	}
+	for _, thumbKey := range utils.ThumbnailKeys(key) {
+		if f, err := ss.storage.Open(ctx, thumbKey); err == nil {
+			f.Close()
+			return ss.storage.URL(thumbKey)
+		}
+	}
+	return cover
+}
+
+// shareCardDescription 预览的描述：价格（赠书为免费）· 成色 · 作者
+func shareCardDescription(lang string, book *models.Book, price float64, donation bool) string {
+	parts := make([]string, 0, 3)
+	if donation {
+		parts = append(parts, utils.T(lang, "share.free"))
+	} else {
+		parts = append(parts, utils.T(lang, "share.price", price))
+	}
+	if book.Condition != "" {
+		parts = append(parts, book.Condition)
+	}
+	if book.Author != "" {
+		parts = append(parts, book.Author)
+	}
+	return strings.Join(parts, " · ")
+}
+
+// renderShareCard 渲染预览页面，内容经过HTML转义
+func renderShareCard(card *ShareCard) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := shareCardTemplate.Execute(&buf, card); err != nil {
+		return nil, fmt.Errorf("failed to render share card: %w", err)
+	}
+	return buf.Bytes(), nil
+}
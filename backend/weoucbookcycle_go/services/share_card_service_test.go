@@ -0,0 +1,41 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"weoucbookcycle_go/models"
+)
+
+func TestShareCardDescription(t *testing.T) {
+	book := &models.Book{Author: "同济大学数学系", Condition: "九成新"}
+	if got, want := shareCardDescription("en", book, 25, false), "¥25.00 · 九成新 · 同济大学数学系"; got != want {
+		t.Errorf("shareCardDescription = %q, want %q", got, want)
+	}
+	if got, want := shareCardDescription("en", &models.Book{}, 0, true), "Free"; got != want {
+		t.Errorf("shareCardDescription for a donation = %q, want %q", got, want)
+	}
+}
+
+// 书名等由用户填写，需要转义后才能放进页面
+func TestRenderShareCardEscapes(t *testing.T) {
+	page, err := renderShareCard(&ShareCard{
+		Title: `高等数学"><script>alert(1)</script>`,
+		URL:   "https://book.example.com/listings/l-1",
+		Price: 25,
+	})
+	if err != nil {
+		t.Fatalf("renderShareCard: %v", err)
+	}
+	html := string(page)
+	if strings.Contains(html, "<script>") {
+		t.Errorf("title was not escaped: %s", html)
+	}
+	for _, want := range []string{`property="og:url" content="https://book.example.com/listings/l-1"`, `content="25.00"`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("page is missing %s", want)
+		}
+	}
+	if strings.Contains(html, "og:image") {
+		t.Errorf("page without a cover should not have og:image")
+	}
+}
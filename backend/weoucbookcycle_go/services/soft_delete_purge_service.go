@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"weoucbookcycle_go/models"
+
+	"gorm.io/gorm"
+)
+
+const softDeletePurgeBatch = 500
+
+// softDeletePurgeModels 参与清理的表，按引用关系从子表到父表排列
+// 用户和会话的删除涉及关联数据和账号注销流程，不在这里清理
+var softDeletePurgeModels = []interface{}{
+	&models.Message{},
+	&models.Listing{},
+	&models.Book{},
+	&models.UploadedFile{}, // 存储中的文件在软删除时已经删除
+}
+
+// purgeSoftDeleted 彻底删除软删除超过保留天数的记录，保留天数为0时不执行
+func (s *Scheduler) purgeSoftDeleted(ctx context.Context) error {
+	days := s.settings.Int(ctx, SettingSoftDeleteRetention)
+	if days <= 0 {
+		return nil
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -days)
+	var errs []error
+	for _, model := range softDeletePurgeModels {
+		purged, err := purgeModel(ctx, s.db, model, cutoff)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if purged > 0 {
+			log.Printf("soft delete purge: removed %d rows from %T", purged, model)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// purgeModel 分批删除一张表中过期的软删除记录，避免长时间锁表
+func purgeModel(ctx context.Context, db *gorm.DB, model interface{}, cutoff time.Time) (int64, error) {
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		result := db.WithContext(ctx).Unscoped().
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+			Limit(softDeletePurgeBatch).Delete(model)
+		if result.Error != nil {
+			return total, fmt.Errorf("purge %T: %w", model, result.Error)
+		}
+		total += result.RowsAffected
+		if result.RowsAffected < softDeletePurgeBatch {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+	"weoucbookcycle_go/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	searchVocabKey       = "search:vocab"
+	searchVocabMaxSize   = 50000
+	vocabRefreshInterval = 5 * time.Minute
+)
+
+// vocabTerm 词表中的一个词及其出现次数
+type vocabTerm struct {
+	text  []rune
+	score float64
+}
+
+// searchVocabulary 进程内词表缓存，保存在 SearchIndexService 实例上
+type searchVocabulary struct {
+	sync.RWMutex
+	terms    []vocabTerm
+	loadedAt time.Time
+}
+
+// ==================== 词表维护 ====================
+
+// AddToVocabulary 把书名、作者等加入搜索词表
+func (sis *SearchIndexService) AddToVocabulary(ctx context.Context, texts ...string) {
+	if sis.redisClient == nil {
+		return
+	}
+
+	pipe := sis.redisClient.Pipeline()
+	for _, text := range texts {
+		for _, word := range vocabularyWords(text) {
+			pipe.ZIncrBy(ctx, searchVocabKey, 1, word)
+		}
+	}
+	pipe.Exec(ctx)
+}
+
+// RebuildVocabulary 从数据库重建搜索词表，返回词条数量
+func (sis *SearchIndexService) RebuildVocabulary(ctx context.Context) (int, error) {
+	var books []models.Book
+	if err := sis.db.WithContext(ctx).Select("title", "author").
+		Where("status = ?", 1).
+		Limit(searchVocabMaxSize).
+		Find(&books).Error; err != nil {
+		return 0, err
+	}
+
+	counts := make(map[string]float64)
+	for _, b := range books {
+		for _, word := range vocabularyWords(b.Title) {
+			counts[word]++
+		}
+		for _, word := range vocabularyWords(b.Author) {
+			counts[word]++
+		}
+	}
+
+	if sis.redisClient != nil {
+		members := make([]redis.Z, 0, len(counts))
+		for word, score := range counts {
+			members = append(members, redis.Z{Score: score, Member: word})
+		}
+
+		pipe := sis.redisClient.TxPipeline()
+		pipe.Del(ctx, searchVocabKey)
+		if len(members) > 0 {
+			pipe.ZAdd(ctx, searchVocabKey, members...)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return 0, err
+		}
+	}
+
+	terms := make([]vocabTerm, 0, len(counts))
+	for word, score := range counts {
+		terms = append(terms, vocabTerm{text: []rune(word), score: score})
+	}
+
+	sis.vocabulary.Lock()
+	sis.vocabulary.terms = terms
+	sis.vocabulary.loadedAt = time.Now()
+	sis.vocabulary.Unlock()
+
+	return len(terms), nil
+}
+
+// ==================== 纠错 ====================
+
+// SuggestCorrection 查询结果过少时，基于编辑距离给出纠正后的查询
+// 命中数阈值由 SEARCH_CORRECTION_THRESHOLD 控制（默认3），没有合适建议时返回空字符串
+func (sis *SearchIndexService) SuggestCorrection(ctx context.Context, query string, hits int64) string {
+	if hits >= int64(sis.correctionThreshold) {
+		return ""
+	}
+
+	terms := sis.currentVocabulary(ctx)
+	if len(terms) == 0 {
+		return ""
+	}
+
+	return bestCorrection(query, terms)
+}
+
+// currentVocabulary 获取词表（必要时从Redis或数据库刷新）
+func (sis *SearchIndexService) currentVocabulary(ctx context.Context) []vocabTerm {
+	sis.vocabulary.RLock()
+	terms := sis.vocabulary.terms
+	fresh := !sis.vocabulary.loadedAt.IsZero() && time.Since(sis.vocabulary.loadedAt) < vocabRefreshInterval
+	sis.vocabulary.RUnlock()
+
+	if fresh {
+		return terms
+	}
+
+	sis.vocabulary.Lock()
+	defer sis.vocabulary.Unlock()
+
+	if !sis.vocabulary.loadedAt.IsZero() && time.Since(sis.vocabulary.loadedAt) < vocabRefreshInterval {
+		return sis.vocabulary.terms
+	}
+	sis.vocabulary.loadedAt = time.Now()
+
+	if sis.redisClient != nil {
+		entries, err := sis.redisClient.ZRevRangeWithScores(ctx, searchVocabKey, 0, searchVocabMaxSize-1).Result()
+		if err == nil && len(entries) > 0 {
+			loaded := make([]vocabTerm, 0, len(entries))
+			for _, e := range entries {
+				if word, ok := e.Member.(string); ok {
+					loaded = append(loaded, vocabTerm{text: []rune(word), score: e.Score})
+				}
+			}
+			sis.vocabulary.terms = loaded
+			return loaded
+		}
+	}
+
+	// 词表为空时异步从数据库重建，本次先使用旧词表
+	if sis.db != nil {
+		go sis.RebuildVocabulary(context.WithoutCancel(ctx))
+	}
+	return sis.vocabulary.terms
+}
+
+// bestCorrection 先尝试整句纠错，再逐词纠错
+func bestCorrection(query string, terms []vocabTerm) string {
+	normalized := normalizeQuery(query)
+	if normalized == "" {
+		return ""
+	}
+
+	if match, ok := closestTerm([]rune(normalized), terms); ok {
+		return match
+	}
+
+	words := strings.Fields(normalized)
+	if len(words) < 2 {
+		return ""
+	}
+
+	corrected := false
+	for i, word := range words {
+		if match, ok := closestTerm([]rune(word), terms); ok {
+			words[i] = match
+			corrected = true
+		}
+	}
+	if !corrected {
+		return ""
+	}
+	return strings.Join(words, " ")
+}
+
+// closestTerm 查找编辑距离最小的词，距离相同时选择出现次数多的
+// 词本身就在词表中时不做纠正
+func closestTerm(word []rune, terms []vocabTerm) (string, bool) {
+	maxDist := maxEditDistance(len(word))
+	if maxDist == 0 {
+		return "", false
+	}
+
+	best := -1
+	bestDist := maxDist + 1
+	for i, term := range terms {
+		diff := len(term.text) - len(word)
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff > maxDist {
+			continue
+		}
+
+		dist := levenshtein(word, term.text)
+		if dist == 0 {
+			return "", false
+		}
+		if dist < bestDist || (dist == bestDist && best >= 0 && term.score > terms[best].score) {
+			best = i
+			bestDist = dist
+		}
+	}
+
+	if best < 0 {
+		return "", false
+	}
+	return string(terms[best].text), true
+}
+
+// maxEditDistance 按词长允许的最大编辑距离，太短的词不纠错
+func maxEditDistance(length int) int {
+	switch {
+	case length <= 2:
+		return 0
+	case length <= 5:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// levenshtein 计算两个字符序列的编辑距离（按rune计算，支持中文）
+func levenshtein(a, b []rune) int {
+	if len(a) == 0 {
+		return len(b)
+	}
+	if len(b) == 0 {
+		return len(a)
+	}
+
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
+
+// vocabularyWords 把文本拆分为词表条目：完整文本和其中的单词
+func vocabularyWords(text string) []string {
+	normalized := normalizeQuery(text)
+	if normalized == "" {
+		return nil
+	}
+
+	words := []string{normalized}
+	fields := strings.Fields(normalized)
+	if len(fields) > 1 {
+		for _, f := range fields {
+			if len([]rune(f)) >= 2 {
+				words = append(words, f)
+			}
+		}
+	}
+	return words
+}
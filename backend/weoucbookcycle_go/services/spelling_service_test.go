@@ -0,0 +1,44 @@
+package services
+
+import "testing"
+
+func TestLevenshtein(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"高等数学", "高等数写", 1},
+		{"高等数学", "高等数学", 0},
+	}
+
+	for _, c := range cases {
+		if got := levenshtein([]rune(c.a), []rune(c.b)); got != c.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestBestCorrection(t *testing.T) {
+	terms := []vocabTerm{
+		{text: []rune("高等数学"), score: 10},
+		{text: []rune("线性代数"), score: 5},
+		{text: []rune("algorithms"), score: 3},
+		{text: []rune("introduction"), score: 2},
+	}
+
+	if got := bestCorrection("高等数写", terms); got != "高等数学" {
+		t.Errorf("expected 高等数学, got %q", got)
+	}
+	if got := bestCorrection("高等数学", terms); got != "" {
+		t.Errorf("exact match should not be corrected, got %q", got)
+	}
+	if got := bestCorrection("Introductoin to Algoritms", terms); got != "introduction to algorithms" {
+		t.Errorf("expected per-word correction, got %q", got)
+	}
+	if got := bestCorrection("物理", terms); got != "" {
+		t.Errorf("short unrelated query should not be corrected, got %q", got)
+	}
+}
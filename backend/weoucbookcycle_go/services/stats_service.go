@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"weoucbookcycle_go/models"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 每日计数器名称，Redis key 为 stats:{name}:{YYYY-MM-DD}
+const (
+	StatRegistrations = "register"
+	StatNewBooks      = "books"
+	StatListingsSold  = "listings_sold"
+	StatMessages      = "messages"
+	// StatActiveUsers 为 HyperLogLog，由 AuthMiddleware 写入
+	StatActiveUsers = "active"
+)
+
+const (
+	statsDateLayout = "2006-01-02"
+	statsCounterTTL = 40 * 24 * time.Hour
+	// maxStatsRange 单次查询的最大天数
+	maxStatsRange = 366
+)
+
+// RecordDailyStat 当日计数器加一
+func RecordDailyStat(ctx context.Context, redisClient *redis.Client, name string) {
+	if redisClient == nil {
+		return
+	}
+
+	key := fmt.Sprintf("stats:%s:%s", name, time.Now().Format(statsDateLayout))
+	if count, err := redisClient.Incr(ctx, key).Result(); err == nil && count == 1 {
+		redisClient.Expire(ctx, key, statsCounterTTL)
+	}
+}
+
+// StatsService 平台统计服务
+type StatsService struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+}
+
+// NewStatsService 创建统计服务实例
+func NewStatsService(deps Deps) *StatsService {
+	return &StatsService{
+		db:          deps.DB,
+		redisClient: deps.Redis,
+	}
+}
+
+// PlatformOverview 平台概览
+type PlatformOverview struct {
+	TotalUsers        int64             `json:"total_users"`
+	TotalBooks        int64             `json:"total_books"`
+	AvailableListings int64             `json:"available_listings"`
+	SoldListings      int64             `json:"sold_listings"`
+	OnlineUsers       int64             `json:"online_users"`
+	PendingReviews    int64             `json:"pending_reviews"`
+	Today             *models.DailyStat `json:"today"`
+}
+
+// RollupRecent 把Redis计数器汇总到 daily_stats 表，由定时任务 stats_rollup 调用
+// 每次汇总今天和昨天，保证跨天时昨天的数据完整
+func (ss *StatsService) RollupRecent(ctx context.Context) error {
+	var errs []error
+	now := time.Now()
+	for _, day := range []time.Time{now.AddDate(0, 0, -1), now} {
+		if err := ss.Rollup(ctx, day); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", day.Format(statsDateLayout), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Rollup 汇总指定日期的统计并写入 daily_stats
+func (ss *StatsService) Rollup(ctx context.Context, day time.Time) error {
+	stat := ss.collect(ctx, day)
+	return ss.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"registrations", "active_users", "new_books", "listings_sold", "messages", "updated_at"}),
+	}).Create(stat).Error
+}
+
+// collect 读取指定日期的统计
+// 优先使用Redis计数器，计数器缺失时（如Redis重启或超过保留期）从数据库统计
+func (ss *StatsService) collect(ctx context.Context, day time.Time) *models.DailyStat {
+	date := day.Format(statsDateLayout)
+	start, _ := time.ParseInLocation(statsDateLayout, date, time.Local)
+	end := start.AddDate(0, 0, 1)
+
+	count := func(name string, fallback func() int64) int64 {
+		if ss.redisClient != nil {
+			if v, err := ss.redisClient.Get(ctx, fmt.Sprintf("stats:%s:%s", name, date)).Int64(); err == nil {
+				return v
+			}
+		}
+		return fallback()
+	}
+	countRows := func(model interface{}, column string) func() int64 {
+		return func() int64 {
+			var n int64
+			ss.db.WithContext(ctx).Model(model).Where(column+" >= ? AND "+column+" < ?", start, end).Count(&n)
+			return n
+		}
+	}
+
+	stat := &models.DailyStat{
+		Date:          date,
+		Registrations: count(StatRegistrations, countRows(&models.User{}, "created_at")),
+		NewBooks:      count(StatNewBooks, countRows(&models.Book{}, "created_at")),
+		Messages:      count(StatMessages, countRows(&models.Message{}, "created_at")),
+		ListingsSold: count(StatListingsSold, func() int64 {
+			var n int64
+			ss.db.WithContext(ctx).Model(&models.Listing{}).
+				Where("status = ? AND updated_at >= ? AND updated_at < ?", "sold", start, end).Count(&n)
+			return n
+		}),
+	}
+
+	if ss.redisClient != nil {
+		stat.ActiveUsers, _ = ss.redisClient.PFCount(ctx, fmt.Sprintf("stats:%s:%s", StatActiveUsers, date)).Result()
+	}
+	// HyperLogLog 过期后保留已汇总的值
+	if stat.ActiveUsers == 0 {
+		var existing models.DailyStat
+		if ss.db.WithContext(ctx).First(&existing, "date = ?", date).Error == nil {
+			stat.ActiveUsers = existing.ActiveUsers
+		}
+	}
+
+	stat.UpdatedAt = time.Now()
+	return stat
+}
+
+// Daily 获取日期区间内的每日统计，今天的数据实时计算
+func (ss *StatsService) Daily(ctx context.Context, from, to time.Time) ([]models.DailyStat, error) {
+	if to.Before(from) {
+		from, to = to, from
+	}
+	if to.Sub(from) > maxStatsRange*24*time.Hour {
+		from = to.AddDate(0, 0, -maxStatsRange)
+	}
+
+	var rows []models.DailyStat
+	if err := ss.db.Where("date >= ? AND date <= ?", from.Format(statsDateLayout), to.Format(statsDateLayout)).
+		Order("date ASC").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load daily stats: %w", err)
+	}
+
+	// 汇总任务有延迟，今天的数据直接读取计数器
+	today := time.Now().Format(statsDateLayout)
+	if today >= from.Format(statsDateLayout) && today <= to.Format(statsDateLayout) {
+		live := ss.collect(ctx, time.Now())
+		if n := len(rows); n > 0 && rows[n-1].Date == today {
+			rows[n-1] = *live
+		} else {
+			rows = append(rows, *live)
+		}
+	}
+
+	return rows, nil
+}
+
+// Overview 平台概览
+func (ss *StatsService) Overview(ctx context.Context) (*PlatformOverview, error) {
+	overview := &PlatformOverview{Today: ss.collect(ctx, time.Now())}
+
+	if err := ss.db.WithContext(ctx).Model(&models.User{}).Count(&overview.TotalUsers).Error; err != nil {
+		return nil, fmt.Errorf("failed to count users: %w", err)
+	}
+	ss.db.WithContext(ctx).Model(&models.Book{}).Count(&overview.TotalBooks)
+	ss.db.WithContext(ctx).Model(&models.Listing{}).Where("status = ?", "available").Count(&overview.AvailableListings)
+	ss.db.WithContext(ctx).Model(&models.Listing{}).Where("status = ?", "sold").Count(&overview.SoldListings)
+	ss.db.WithContext(ctx).Model(&models.ModerationQueueItem{}).Where("status IN ?", openModerationStatuses).Count(&overview.PendingReviews)
+
+	if ss.redisClient != nil {
+		overview.OnlineUsers, _ = ss.redisClient.SCard(ctx, "online:users").Result()
+	}
+
+	return overview, nil
+}
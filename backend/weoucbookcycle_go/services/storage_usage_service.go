@@ -0,0 +1,245 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// storageUsageTTL Redis中的用量计数过期后按数据库重新统计，修正保存后大小变化（如压缩）带来的偏差
+const storageUsageTTL = 10 * time.Minute
+
+var (
+	// ErrUploadQuotaExceeded 超出上传配额
+	ErrUploadQuotaExceeded = utils.NewError(http.StatusForbidden, "upload quota exceeded")
+	// ErrFileNotFound 文件不存在或不属于当前用户
+	ErrFileNotFound = utils.NewError(http.StatusNotFound, "file not found")
+	// ErrFileInUse 文件仍被书籍引用
+	ErrFileInUse = utils.NewError(http.StatusConflict, "file is still used by a book or as the avatar")
+)
+
+// StorageUsageService 用户存储用量服务
+type StorageUsageService struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+	// quotaBytes/quotaFiles 每个用户的配额，0 表示不限制
+	quotaBytes int64
+	quotaFiles int64
+}
+
+// NewStorageUsageService 创建存储用量服务实例
+func NewStorageUsageService(deps Deps) *StorageUsageService {
+	return &StorageUsageService{
+		db:          deps.DB,
+		redisClient: deps.Redis,
+		quotaBytes:  int64(deps.Config.Upload.QuotaMB) * 1024 * 1024,
+		quotaFiles:  int64(deps.Config.Upload.QuotaFiles),
+	}
+}
+
+// StorageUsage 用户存储用量
+type StorageUsage struct {
+	UsedBytes  int64 `json:"used_bytes"`
+	FileCount  int64 `json:"file_count"`
+	QuotaBytes int64 `json:"quota_bytes"`
+	QuotaFiles int64 `json:"quota_files"`
+}
+
+// StartUploadTracking 注册上传记录回调和配额预留
+// 配额由 UPLOAD_QUOTA_MB（默认200）和 UPLOAD_QUOTA_FILES（默认1000）控制，设为0表示不限制
+func (sus *StorageUsageService) StartUploadTracking() {
+	utils.SetUploadQuota(sus.ReserveQuota, sus.ReleaseQuota)
+	utils.RegisterUploadHook(sus.recordUpload)
+}
+
+// GetUsage 获取用户存储用量
+func (sus *StorageUsageService) GetUsage(userID string) (*StorageUsage, error) {
+	usage := &StorageUsage{QuotaBytes: sus.quotaBytes, QuotaFiles: sus.quotaFiles}
+
+	err := sus.db.Model(&models.UploadedFile{}).
+		Select("COALESCE(SUM(size), 0) AS used_bytes, COUNT(*) AS file_count").
+		Where("user_id = ?", userID).
+		Scan(usage).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage usage: %w", err)
+	}
+
+	return usage, nil
+}
+
+// ReserveQuota 原子地预留上传配额，超出配额或无法统计用量时拒绝上传
+// 用量（含进行中的上传）计在Redis中，键不存在时按数据库中的用量初始化并在 storageUsageTTL 后过期重新统计，
+// 并发上传不会同时通过检查；Redis 不可用时退化为按数据库用量检查
+func (sus *StorageUsageService) ReserveQuota(ctx context.Context, userID string, count int, size int64) error {
+	usage, err := sus.GetUsage(userID)
+	if err != nil {
+		log.Printf("upload quota: %v", err)
+		return utils.NewError(http.StatusServiceUnavailable, "failed to check upload quota")
+	}
+	if usage.QuotaFiles <= 0 && usage.QuotaBytes <= 0 {
+		return nil
+	}
+
+	if sus.redisClient != nil {
+		reserved, err := reserveStorageScript.Run(ctx, sus.redisClient, []string{storageUsageKey(userID)},
+			usage.UsedBytes, usage.FileCount, usage.QuotaBytes, usage.QuotaFiles, size, count, storageUsageTTL.Milliseconds()).Int()
+		if err != nil {
+			log.Printf("upload quota: %v", err)
+			return utils.NewError(http.StatusServiceUnavailable, "failed to check upload quota")
+		}
+		if reserved == 0 {
+			return fmt.Errorf("%w: %d bytes / %d files allowed", ErrUploadQuotaExceeded, usage.QuotaBytes, usage.QuotaFiles)
+		}
+		return nil
+	}
+
+	if usage.QuotaFiles > 0 && usage.FileCount+int64(count) > usage.QuotaFiles {
+		return fmt.Errorf("%w: file count limit is %d", ErrUploadQuotaExceeded, usage.QuotaFiles)
+	}
+	if usage.QuotaBytes > 0 && usage.UsedBytes+size > usage.QuotaBytes {
+		return fmt.Errorf("%w: %d of %d bytes used", ErrUploadQuotaExceeded, usage.UsedBytes, usage.QuotaBytes)
+	}
+	return nil
+}
+
+// ReleaseQuota 退回没有保存成功的文件预留的配额
+func (sus *StorageUsageService) ReleaseQuota(ctx context.Context, userID string, count int, size int64) {
+	if sus.redisClient == nil {
+		return
+	}
+	if err := releaseStorageScript.Run(ctx, sus.redisClient, []string{storageUsageKey(userID)}, size, count).Err(); err != nil {
+		log.Printf("upload quota: failed to release reservation for %s: %v", userID, err)
+	}
+}
+
+// storageUsageKey 用户存储用量（含预留）的Redis键
+func storageUsageKey(userID string) string {
+	return "upload:usage:" + userID
+}
+
+// reserveStorageScript 键不存在时用数据库用量初始化，预留后不超出配额才计入
+// KEYS[1] 用量；ARGV 依次为已用字节、已用文件数、字节配额、文件数配额（0为不限）、本次字节数、本次文件数、过期毫秒数
+var reserveStorageScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	redis.call('HSET', KEYS[1], 'bytes', ARGV[1], 'files', ARGV[2])
+	redis.call('PEXPIRE', KEYS[1], ARGV[7])
+end
+local bytes = tonumber(redis.call('HGET', KEYS[1], 'bytes')) + tonumber(ARGV[5])
+local files = tonumber(redis.call('HGET', KEYS[1], 'files')) + tonumber(ARGV[6])
+if (tonumber(ARGV[3]) > 0 and bytes > tonumber(ARGV[3])) or (tonumber(ARGV[4]) > 0 and files > tonumber(ARGV[4])) then
+	return 0
+end
+redis.call('HINCRBY', KEYS[1], 'bytes', ARGV[5])
+redis.call('HINCRBY', KEYS[1], 'files', ARGV[6])
+return 1
+`)
+
+// releaseStorageScript 退回预留，键已过期时无需处理（下次按数据库重新统计）
+var releaseStorageScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	redis.call('HINCRBY', KEYS[1], 'bytes', -tonumber(ARGV[1]))
+	redis.call('HINCRBY', KEYS[1], 'files', -tonumber(ARGV[2]))
+end
+return 1
+`)
+
+// ListFiles 分页获取用户上传的文件
+func (sus *StorageUsageService) ListFiles(userID string, page, limit int) ([]models.UploadedFile, int64, error) {
+	query := sus.db.Model(&models.UploadedFile{}).Where("user_id = ?", userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count files: %w", err)
+	}
+
+	var files []models.UploadedFile
+	if err := query.
+		Order("created_at DESC").
+		Limit(limit).
+		Offset((page - 1) * limit).
+		Find(&files).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get files: %w", err)
+	}
+
+	return files, total, nil
+}
+
+// DeleteFile 删除用户自己的文件，仍被书籍引用或正用作头像的文件不能删除
+func (sus *StorageUsageService) DeleteFile(ctx context.Context, userID, fileID string) error {
+	var file models.UploadedFile
+	if err := sus.db.WithContext(ctx).Where("id = ? AND user_id = ?", fileID, userID).First(&file).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrFileNotFound
+		}
+		return err
+	}
+
+	if file.URL != "" {
+		var inUse int64
+		sus.db.WithContext(ctx).Model(&models.Book{}).
+			Where("seller_id = ? AND images LIKE ?", userID, "%"+escapeLike(file.URL)+"%").
+			Count(&inUse)
+		if inUse == 0 {
+			sus.db.WithContext(ctx).Model(&models.User{}).
+				Where("id = ? AND avatar IN ?", userID, []string{file.URL, file.ThumbURL}).
+				Count(&inUse)
+		}
+		if inUse > 0 {
+			return ErrFileInUse
+		}
+	}
+
+	if err := utils.NewFileUploader(sus.redisClient).DeleteFile(file.Key); err != nil {
+		return err
+	}
+	if err := sus.db.WithContext(ctx).Delete(&file).Error; err != nil {
+		return err
+	}
+	if sus.redisClient != nil {
+		sus.redisClient.Del(ctx, storageUsageKey(userID))
+	}
+	return nil
+}
+
+// escapeLike 转义 LIKE 模式中的通配符，按字面匹配
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// recordUpload 上传回调：记录文件用于统计用量
+func (sus *StorageUsageService) recordUpload(ctx context.Context, userID string, result *utils.UploadResult) {
+	if userID == "" {
+		return
+	}
+
+	file := &models.UploadedFile{
+		UserID:      userID,
+		Key:         result.Key,
+		URL:         result.OriginalURL,
+		ThumbURL:    result.ThumbURL,
+		FileName:    result.FileName,
+		ContentType: result.ContentType,
+		Size:        result.FileSize,
+		Visibility:  models.FileVisibilityPublic,
+		Width:       result.Width,
+		Height:      result.Height,
+	}
+	// 私有文件的签名URL会过期，不保存
+	if result.Private {
+		file.URL = ""
+		file.Visibility = models.FileVisibilityPrivate
+		file.Purpose = result.Purpose
+	}
+	if err := sus.db.WithContext(ctx).Create(file).Error; err != nil {
+		log.Printf("Failed to record upload %s: %v", result.Key, err)
+	}
+}
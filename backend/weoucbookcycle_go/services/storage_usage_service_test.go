@@ -0,0 +1,16 @@
+package services
+
+import "testing"
+
+func TestEscapeLike(t *testing.T) {
+	cases := map[string]string{
+		"/uploads/a.jpg":      "/uploads/a.jpg",
+		"/uploads/100%_b.jpg": `/uploads/100\%\_b.jpg`,
+		`C:\x`:                `C:\\x`,
+	}
+	for in, want := range cases {
+		if got := escapeLike(in); got != want {
+			t.Errorf("escapeLike(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
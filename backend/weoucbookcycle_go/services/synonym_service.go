@@ -0,0 +1,333 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	synonymCacheKey      = "search:synonyms"
+	synonymVersionKey    = "search:synonyms:version"
+	synonymCheckInterval = 30 * time.Second
+	maxQueryExpansions   = 10
+)
+
+// synonymDictionary 进程内同义词缓存
+// 通过Redis中的版本号在多实例之间同步
+type synonymDictionary struct {
+	sync.RWMutex
+	groups    [][]string
+	version   int64
+	checkedAt time.Time
+	loaded    bool
+}
+
+// SynonymService 同义词服务
+// 进程内缓存保存在实例上，搜索相关的服务共享 Services.Synonym
+type SynonymService struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+	dictionary  synonymDictionary
+}
+
+// NewSynonymService 创建同义词服务实例
+func NewSynonymService(deps Deps) *SynonymService {
+	return &SynonymService{
+		db:          deps.DB,
+		redisClient: deps.Redis,
+	}
+}
+
+// SynonymRequest 同义词组请求
+type SynonymRequest struct {
+	Terms   []string `json:"terms" binding:"required,min=2,max=20"`
+	Note    string   `json:"note" binding:"max=255"`
+	Enabled *bool    `json:"enabled"`
+}
+
+// ==================== 管理方法 ====================
+
+// List 获取全部同义词组
+func (ss *SynonymService) List() ([]models.SearchSynonym, error) {
+	var synonyms []models.SearchSynonym
+	if err := ss.db.Order("created_at DESC").Find(&synonyms).Error; err != nil {
+		return nil, fmt.Errorf("failed to list synonyms: %w", err)
+	}
+	return synonyms, nil
+}
+
+// Create 创建同义词组
+func (ss *SynonymService) Create(ctx context.Context, req *SynonymRequest) (*models.SearchSynonym, error) {
+	terms := normalizeTerms(req.Terms)
+	if len(terms) < 2 {
+		return nil, errors.New("a synonym group needs at least two distinct terms")
+	}
+
+	termsJSON, _ := json.Marshal(terms)
+	synonym := models.SearchSynonym{
+		Terms:   termsJSON,
+		Note:    req.Note,
+		Enabled: true,
+	}
+
+	if err := ss.db.Create(&synonym).Error; err != nil {
+		return nil, fmt.Errorf("failed to create synonym: %w", err)
+	}
+
+	// 禁用状态需要单独更新（零值不会写入）
+	if req.Enabled != nil && !*req.Enabled {
+		ss.db.Model(&synonym).Update("enabled", false)
+		synonym.Enabled = false
+	}
+
+	go ss.Reload(context.WithoutCancel(ctx))
+
+	return &synonym, nil
+}
+
+// Update 更新同义词组
+func (ss *SynonymService) Update(ctx context.Context, id string, req *SynonymRequest) (*models.SearchSynonym, error) {
+	var synonym models.SearchSynonym
+	if err := ss.db.First(&synonym, "id = ?", id).Error; err != nil {
+		return nil, errors.New("synonym not found")
+	}
+
+	terms := normalizeTerms(req.Terms)
+	if len(terms) < 2 {
+		return nil, errors.New("a synonym group needs at least two distinct terms")
+	}
+
+	termsJSON, _ := json.Marshal(terms)
+	updates := map[string]interface{}{
+		"terms": termsJSON,
+		"note":  req.Note,
+	}
+	if req.Enabled != nil {
+		updates["enabled"] = *req.Enabled
+	}
+
+	if err := ss.db.Model(&synonym).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update synonym: %w", err)
+	}
+
+	if err := ss.db.First(&synonym, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+
+	go ss.Reload(context.WithoutCancel(ctx))
+
+	return &synonym, nil
+}
+
+// Delete 删除同义词组
+func (ss *SynonymService) Delete(ctx context.Context, id string) error {
+	result := ss.db.Delete(&models.SearchSynonym{}, "id = ?", id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete synonym: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("synonym not found")
+	}
+
+	go ss.Reload(context.WithoutCancel(ctx))
+
+	return nil
+}
+
+// Reload 从数据库重新加载同义词并通知其他实例
+func (ss *SynonymService) Reload(ctx context.Context) (int, error) {
+	groups, err := ss.loadGroups()
+	if err != nil {
+		return 0, err
+	}
+
+	var version int64
+	if ss.redisClient != nil {
+		data, _ := json.Marshal(groups)
+		ss.redisClient.Set(ctx, synonymCacheKey, data, 0)
+		version, _ = ss.redisClient.Incr(ctx, synonymVersionKey).Result()
+
+		// 同义词变化后旧的搜索结果缓存不再准确
+		utils.InvalidateCacheTag(ctx, ss.redisClient, utils.CacheTagSearch)
+	}
+
+	ss.dictionary.Lock()
+	ss.dictionary.groups = groups
+	ss.dictionary.version = version
+	ss.dictionary.checkedAt = time.Now()
+	ss.dictionary.loaded = true
+	ss.dictionary.Unlock()
+
+	return len(groups), nil
+}
+
+// ==================== 查询扩展 ====================
+
+// ExpandQuery 使用同义词扩展搜索词，返回值总是包含原始查询
+func (ss *SynonymService) ExpandQuery(ctx context.Context, query string) []string {
+	return expandWithGroups(query, ss.currentGroups(ctx))
+}
+
+// KeywordCondition 为一组搜索词构建 LIKE 查询条件
+// 任一搜索词命中任一字段即匹配
+func KeywordCondition(terms []string, columns ...string) (string, []interface{}) {
+	clauses := make([]string, 0, len(terms)*len(columns))
+	args := make([]interface{}, 0, len(terms)*len(columns))
+
+	for _, term := range terms {
+		pattern := "%" + term + "%"
+		for _, column := range columns {
+			clauses = append(clauses, column+" LIKE ?")
+			args = append(args, pattern)
+		}
+	}
+
+	return "(" + strings.Join(clauses, " OR ") + ")", args
+}
+
+// currentGroups 获取当前同义词组（必要时从Redis/数据库刷新）
+func (ss *SynonymService) currentGroups(ctx context.Context) [][]string {
+	ss.dictionary.RLock()
+	groups := ss.dictionary.groups
+	fresh := ss.dictionary.loaded && time.Since(ss.dictionary.checkedAt) < synonymCheckInterval
+	ss.dictionary.RUnlock()
+
+	if fresh {
+		return groups
+	}
+
+	ss.refresh(ctx)
+
+	ss.dictionary.RLock()
+	defer ss.dictionary.RUnlock()
+	return ss.dictionary.groups
+}
+
+// refresh 检查Redis版本号，有变化时重新加载
+func (ss *SynonymService) refresh(ctx context.Context) {
+	ss.dictionary.Lock()
+	defer ss.dictionary.Unlock()
+
+	if ss.dictionary.loaded && time.Since(ss.dictionary.checkedAt) < synonymCheckInterval {
+		return
+	}
+	ss.dictionary.checkedAt = time.Now()
+
+	if ss.redisClient != nil {
+		version, _ := ss.redisClient.Get(ctx, synonymVersionKey).Int64()
+		if ss.dictionary.loaded && version == ss.dictionary.version {
+			return
+		}
+
+		cached, err := ss.redisClient.Get(ctx, synonymCacheKey).Result()
+		if err == nil {
+			var groups [][]string
+			if json.Unmarshal([]byte(cached), &groups) == nil {
+				ss.dictionary.groups = groups
+				ss.dictionary.version = version
+				ss.dictionary.loaded = true
+				return
+			}
+		}
+	}
+
+	if ss.db == nil {
+		return
+	}
+
+	groups, err := ss.loadGroups()
+	if err != nil {
+		return
+	}
+	ss.dictionary.groups = groups
+	ss.dictionary.loaded = true
+
+	if ss.redisClient != nil {
+		data, _ := json.Marshal(groups)
+		ss.redisClient.Set(ctx, synonymCacheKey, data, 0)
+	}
+}
+
+// loadGroups 从数据库读取启用的同义词组
+func (ss *SynonymService) loadGroups() ([][]string, error) {
+	var synonyms []models.SearchSynonym
+	if err := ss.db.Where("enabled = ?", true).Find(&synonyms).Error; err != nil {
+		return nil, fmt.Errorf("failed to load synonyms: %w", err)
+	}
+
+	groups := make([][]string, 0, len(synonyms))
+	for _, s := range synonyms {
+		var terms []string
+		if err := json.Unmarshal(s.Terms, &terms); err != nil {
+			continue
+		}
+		if terms = normalizeTerms(terms); len(terms) >= 2 {
+			groups = append(groups, terms)
+		}
+	}
+	return groups, nil
+}
+
+// expandWithGroups 将查询中出现的同义词替换为组内其他词
+func expandWithGroups(query string, groups [][]string) []string {
+	query = strings.TrimSpace(query)
+	expansions := []string{query}
+	if query == "" {
+		return expansions
+	}
+
+	seen := map[string]bool{strings.ToLower(query): true}
+	lowerQuery := strings.ToLower(query)
+
+	for _, group := range groups {
+		for _, term := range group {
+			idx := strings.Index(lowerQuery, strings.ToLower(term))
+			if idx < 0 {
+				continue
+			}
+
+			for _, alt := range group {
+				if strings.EqualFold(alt, term) {
+					continue
+				}
+				expanded := query[:idx] + alt + query[idx+len(term):]
+				if key := strings.ToLower(expanded); !seen[key] {
+					seen[key] = true
+					expansions = append(expansions, expanded)
+					if len(expansions) >= maxQueryExpansions {
+						return expansions
+					}
+				}
+			}
+			break
+		}
+	}
+
+	return expansions
+}
+
+// normalizeTerms 去除空白和重复的词
+func normalizeTerms(terms []string) []string {
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(terms))
+	for _, t := range terms {
+		t = strings.TrimSpace(t)
+		key := strings.ToLower(t)
+		if t == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, t)
+	}
+	return result
+}
@@ -0,0 +1,26 @@
+package services
+
+import "testing"
+
+// 同义词扩展：命中组内任一词时生成其余写法
+func TestExpandWithGroups(t *testing.T) {
+	groups := [][]string{
+		{"高数", "高等数学"},
+		{"C语言", "C程序设计"},
+	}
+
+	got := expandWithGroups("高数 上册", groups)
+	if len(got) != 2 || got[0] != "高数 上册" || got[1] != "高等数学 上册" {
+		t.Fatalf("unexpected expansion: %v", got)
+	}
+
+	got = expandWithGroups("c语言", groups)
+	if len(got) != 2 || got[1] != "C程序设计" {
+		t.Fatalf("expected case-insensitive match, got %v", got)
+	}
+
+	got = expandWithGroups("线性代数", groups)
+	if len(got) != 1 {
+		t.Fatalf("expected no expansion, got %v", got)
+	}
+}
@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 运行时参数
+const (
+	SettingRegisterLimitPerHour = "register_limit_per_hour"
+	SettingMaxMessageLength     = "max_message_length"
+	SettingHotBooksTTLSeconds   = "hot_books_ttl_seconds"
+	SettingListingExpiryDays    = "listing_expiry_days"
+	SettingSoftDeleteRetention  = "soft_delete_retention_days"
+	SettingPointsDailyCap       = "points_daily_cap"
+	SettingPointsPairLimit      = "points_pair_limit"
+	SettingPointsBumpCost       = "points_bump_cost"
+)
+
+const (
+	systemSettingsCacheKey = "system:settings"
+	// systemSettingsRefresh 进程内缓存的有效期，其他实例修改后最迟在此时间后生效
+	systemSettingsRefresh = 30 * time.Second
+)
+
+var (
+	// ErrUnknownSetting 未定义的参数
+	ErrUnknownSetting = utils.NewError(http.StatusNotFound, "unknown setting")
+	// ErrInvalidSettingValue 参数值类型或范围不正确
+	ErrInvalidSettingValue = utils.NewError(http.StatusUnprocessableEntity, "invalid setting value")
+)
+
+// settingDefinition 参数定义
+type settingDefinition struct {
+	Type        string // int 或 bool
+	Default     string
+	Min, Max    int // 仅 int 类型
+	Description string
+}
+
+// settingDefinitions 所有可在运行时调整的参数
+var settingDefinitions = map[string]settingDefinition{
+	SettingRegisterLimitPerHour: {Type: "int", Default: "3", Min: 1, Max: 1000, Description: "每个IP每小时最多注册次数"},
+	SettingMaxMessageLength:     {Type: "int", Default: "1000", Min: 1, Max: 5000, Description: "聊天消息最大长度（字符）"},
+	SettingHotBooksTTLSeconds:   {Type: "int", Default: "600", Min: 10, Max: 86400, Description: "热门书籍缓存时间（秒）"},
+	SettingListingExpiryDays:    {Type: "int", Default: "0", Min: 0, Max: 3650, Description: "在售发布超过该天数未更新自动下架，0 表示不过期"},
+	SettingSoftDeleteRetention:  {Type: "int", Default: "0", Min: 0, Max: 3650, Description: "软删除的记录保留该天数后彻底删除，0 表示永久保留"},
+	SettingPointsDailyCap:       {Type: "int", Default: "100", Min: 0, Max: 100000, Description: "每个用户每天最多获得的绿色积分"},
+	SettingPointsPairLimit:      {Type: "int", Default: "3", Min: 0, Max: 1000, Description: "同一对买卖双方30天内最多有几笔交易计积分"},
+	SettingPointsBumpCost:       {Type: "int", Default: "20", Min: 0, Max: 100000, Description: "擦亮一次发布消耗的绿色积分"},
+
+	// 定时任务开关，见 Scheduler
+	cronSettingKey(CronIPBlockCleanup):    {Type: "bool", Default: "true", Description: "定时任务：清理过期的IP封禁缓存"},
+	cronSettingKey(CronOnlineUserCleanup): {Type: "bool", Default: "true", Description: "定时任务：清理不活跃的在线用户"},
+	cronSettingKey(CronSavedSearch):       {Type: "bool", Default: "true", Description: "定时任务：保存搜索的新书提醒"},
+	cronSettingKey(CronStatsRollup):       {Type: "bool", Default: "true", Description: "定时任务：每日统计汇总"},
+	cronSettingKey(CronListingExpiry):     {Type: "bool", Default: "true", Description: "定时任务：过期发布自动下架"},
+	cronSettingKey(CronUploadGC):          {Type: "bool", Default: "true", Description: "定时任务：清理过期的分片上传"},
+	cronSettingKey(CronSoftDeletePurge):   {Type: "bool", Default: "true", Description: "定时任务：彻底删除过期的软删除记录"},
+	cronSettingKey(CronReputationRollup):  {Type: "bool", Default: "true", Description: "定时任务：卖家信誉分计算"},
+	cronSettingKey(CronDataExportCleanup): {Type: "bool", Default: "true", Description: "定时任务：删除过期的个人数据导出"},
+	cronSettingKey(CronLeaderboard):       {Type: "bool", Default: "true", Description: "定时任务：刷新公开排行榜"},
+}
+
+// systemSettingsCache 进程内参数缓存
+type systemSettingsCache struct {
+	sync.RWMutex
+	values   map[string]string
+	loadedAt time.Time
+}
+
+// SystemSettingsService 运行时参数服务
+// 进程内缓存保存在实例上，需要读取参数的服务共享 Services.SystemSettings
+type SystemSettingsService struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+	cache       systemSettingsCache
+}
+
+// NewSystemSettingsService 创建运行时参数服务实例
+func NewSystemSettingsService(deps Deps) *SystemSettingsService {
+	return &SystemSettingsService{
+		db:          deps.DB,
+		redisClient: deps.Redis,
+	}
+}
+
+// SystemSettingView 参数及其当前值
+type SystemSettingView struct {
+	Key         string     `json:"key"`
+	Value       string     `json:"value"`
+	Default     string     `json:"default"`
+	Type        string     `json:"type"`
+	Min         *int       `json:"min,omitempty"`
+	Max         *int       `json:"max,omitempty"`
+	Description string     `json:"description"`
+	Overridden  bool       `json:"overridden"`
+	UpdatedBy   string     `json:"updated_by,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+}
+
+// ==================== 类型化读取 ====================
+
+// Int 读取整型参数，读取失败时返回默认值
+func (ss *SystemSettingsService) Int(ctx context.Context, key string) int {
+	def := settingDefinitions[key]
+	if n, err := strconv.Atoi(ss.value(ctx, key)); err == nil {
+		return n
+	}
+	n, _ := strconv.Atoi(def.Default)
+	return n
+}
+
+// Bool 读取布尔参数，读取失败时返回默认值
+func (ss *SystemSettingsService) Bool(ctx context.Context, key string) bool {
+	def := settingDefinitions[key]
+	if b, err := strconv.ParseBool(ss.value(ctx, key)); err == nil {
+		return b
+	}
+	b, _ := strconv.ParseBool(def.Default)
+	return b
+}
+
+// value 读取参数的字符串值：进程内缓存 -> Redis -> 数据库
+func (ss *SystemSettingsService) value(ctx context.Context, key string) string {
+	ss.cache.RLock()
+	fresh := ss.cache.values != nil && time.Since(ss.cache.loadedAt) < systemSettingsRefresh
+	value, ok := ss.cache.values[key]
+	ss.cache.RUnlock()
+
+	if !fresh {
+		values := ss.load(ctx)
+		ss.cache.Lock()
+		ss.cache.values = values
+		ss.cache.loadedAt = time.Now()
+		ss.cache.Unlock()
+		value, ok = values[key]
+	}
+
+	if !ok {
+		return settingDefinitions[key].Default
+	}
+	return value
+}
+
+// load 加载所有已覆盖的参数
+func (ss *SystemSettingsService) load(ctx context.Context) map[string]string {
+	if ss.redisClient != nil {
+		values, err := ss.redisClient.HGetAll(ctx, systemSettingsCacheKey).Result()
+		// 缓存中总会写入占位字段，空结果表示缓存不存在
+		if err == nil && len(values) > 0 {
+			delete(values, "_loaded")
+			return values
+		}
+	}
+
+	values := make(map[string]string)
+	if ss.db == nil {
+		return values
+	}
+	var settings []models.SystemSetting
+	if err := ss.db.WithContext(ctx).Find(&settings).Error; err != nil {
+		log.Printf("system settings: failed to load: %v", err)
+		return values
+	}
+	for _, s := range settings {
+		values[s.Key] = s.Value
+	}
+
+	if ss.redisClient != nil {
+		fields := map[string]interface{}{"_loaded": "1"}
+		for k, v := range values {
+			fields[k] = v
+		}
+		ss.redisClient.HSet(ctx, systemSettingsCacheKey, fields)
+		ss.redisClient.Expire(ctx, systemSettingsCacheKey, time.Hour)
+	}
+	return values
+}
+
+// invalidate 参数修改后清除缓存
+func (ss *SystemSettingsService) invalidate(ctx context.Context) {
+	if ss.redisClient != nil {
+		ss.redisClient.Del(ctx, systemSettingsCacheKey)
+	}
+	ss.cache.Lock()
+	ss.cache.values = nil
+	ss.cache.Unlock()
+}
+
+// ==================== 管理方法 ====================
+
+// List 列出所有参数及当前值
+func (ss *SystemSettingsService) List() ([]SystemSettingView, error) {
+	var settings []models.SystemSetting
+	if err := ss.db.Find(&settings).Error; err != nil {
+		return nil, fmt.Errorf("failed to list settings: %w", err)
+	}
+	overrides := make(map[string]models.SystemSetting, len(settings))
+	for _, s := range settings {
+		overrides[s.Key] = s
+	}
+
+	views := make([]SystemSettingView, 0, len(settingDefinitions))
+	for key, def := range settingDefinitions {
+		view := SystemSettingView{
+			Key:         key,
+			Value:       def.Default,
+			Default:     def.Default,
+			Type:        def.Type,
+			Description: def.Description,
+		}
+		if def.Type == "int" {
+			view.Min, view.Max = &def.Min, &def.Max
+		}
+		if s, ok := overrides[key]; ok {
+			updatedAt := s.UpdatedAt
+			view.Value = s.Value
+			view.Overridden = true
+			view.UpdatedBy = s.UpdatedBy
+			view.UpdatedAt = &updatedAt
+		}
+		views = append(views, view)
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].Key < views[j].Key })
+
+	return views, nil
+}
+
+// Update 修改参数，立即对当前实例生效
+func (ss *SystemSettingsService) Update(ctx context.Context, adminID, key, value string) error {
+	def, ok := settingDefinitions[key]
+	if !ok {
+		return ErrUnknownSetting
+	}
+
+	switch def.Type {
+	case "int":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < def.Min || n > def.Max {
+			return fmt.Errorf("%w: %s must be an integer between %d and %d", ErrInvalidSettingValue, key, def.Min, def.Max)
+		}
+		value = strconv.Itoa(n)
+	case "bool":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%w: %s must be true or false", ErrInvalidSettingValue, key)
+		}
+		value = strconv.FormatBool(b)
+	}
+
+	setting := models.SystemSetting{Key: key, Value: value, UpdatedBy: adminID}
+	if err := ss.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_by", "updated_at"}),
+	}).Create(&setting).Error; err != nil {
+		return fmt.Errorf("failed to update setting: %w", err)
+	}
+
+	ss.invalidate(ctx)
+	log.Printf("system settings: %s set to %s by %s", key, value, adminID)
+	return nil
+}
+
+// Reset 恢复参数默认值
+func (ss *SystemSettingsService) Reset(ctx context.Context, adminID, key string) error {
+	if _, ok := settingDefinitions[key]; !ok {
+		return ErrUnknownSetting
+	}
+	if err := ss.db.Delete(&models.SystemSetting{}, "`key` = ?", key).Error; err != nil {
+		return fmt.Errorf("failed to reset setting: %w", err)
+	}
+
+	ss.invalidate(ctx)
+	log.Printf("system settings: %s reset to default by %s", key, adminID)
+	return nil
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// 未覆盖的参数返回默认值，非法的覆盖值回退到默认值
+func TestSettingIntFallsBackToDefault(t *testing.T) {
+	ss := NewSystemSettingsService(Deps{})
+	ctx := context.Background()
+	ss.cache.values = map[string]string{SettingHotBooksTTLSeconds: "not-a-number"}
+	ss.cache.loadedAt = time.Now()
+
+	if got := ss.Int(ctx, SettingMaxMessageLength); got != 1000 {
+		t.Fatalf("expected default 1000, got %d", got)
+	}
+	if got := ss.Int(ctx, SettingHotBooksTTLSeconds); got != 600 {
+		t.Fatalf("expected fallback 600, got %d", got)
+	}
+}
+
+// 修改参数前校验参数名和取值范围
+func TestUpdateSettingValidation(t *testing.T) {
+	ss := NewSystemSettingsService(Deps{})
+	ctx := context.Background()
+
+	if err := ss.Update(ctx, "admin", "no_such_setting", "1"); !errors.Is(err, ErrUnknownSetting) {
+		t.Fatalf("expected ErrUnknownSetting, got %v", err)
+	}
+	if err := ss.Update(ctx, "admin", SettingRegisterLimitPerHour, "0"); !errors.Is(err, ErrInvalidSettingValue) {
+		t.Fatalf("expected ErrInvalidSettingValue for out-of-range value, got %v", err)
+	}
+	if err := ss.Update(ctx, "admin", SettingMaxMessageLength, "abc"); !errors.Is(err, ErrInvalidSettingValue) {
+		t.Fatalf("expected ErrInvalidSettingValue for non-integer value, got %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	thumbnailBackfillLockKey = "lock:thumbnail_backfill"
+	thumbnailBackfillLockTTL = 2 * time.Hour
+	// 补生成时读取原图的大小上限
+	thumbnailSourceMaxSize = 20 * 1024 * 1024
+)
+
+// ErrThumbnailBackfillRunning 已有缩略图补生成任务在运行
+var ErrThumbnailBackfillRunning = utils.NewError(http.StatusConflict, "a thumbnail backfill job is already running")
+
+// ThumbnailService 缩略图服务
+type ThumbnailService struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+	storage     utils.Storage
+	uploader    *utils.FileUploader
+}
+
+// NewThumbnailService 创建缩略图服务实例
+func NewThumbnailService(deps Deps) *ThumbnailService {
+	return &ThumbnailService{
+		db:          deps.DB,
+		redisClient: deps.Redis,
+		storage:     utils.GetStorage(),
+		uploader:    utils.NewFileUploader(deps.Redis),
+	}
+}
+
+// AcquireBackfillLock 获取补生成任务锁，同一时间只允许一个任务
+func (ts *ThumbnailService) AcquireBackfillLock(ctx context.Context) error {
+	if ts.redisClient == nil {
+		return errors.New("redis not available")
+	}
+	ok, err := ts.redisClient.SetNX(ctx, thumbnailBackfillLockKey, time.Now().Unix(), thumbnailBackfillLockTTL).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrThumbnailBackfillRunning
+	}
+	return nil
+}
+
+// Backfill 为书籍图片中缺少缩略图的文件补生成缩略图，需先获取任务锁
+func (ts *ThumbnailService) Backfill(ctx context.Context, progress func(done, total int64)) error {
+	defer ts.redisClient.Del(ctx, thumbnailBackfillLockKey)
+
+	// 1. 收集书籍引用的、属于当前存储的图片
+	keys, err := ts.collectImageKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	// 2. 逐个补生成
+	total := int64(len(keys))
+	var generated, skipped, failed int
+
+	for i, key := range keys {
+		switch created, err := ts.ensureThumbnail(ctx, key); {
+		case err != nil:
+			failed++
+			log.Printf("thumbnail backfill: %s failed: %v", key, err)
+		case created:
+			generated++
+		default:
+			skipped++
+		}
+		progress(int64(i+1), total)
+	}
+
+	log.Printf("thumbnail backfill finished: %d generated, %d skipped, %d failed", generated, skipped, failed)
+	return nil
+}
+
+// collectImageKeys 从书籍图片中收集存储key（去重）
+func (ts *ThumbnailService) collectImageKeys(ctx context.Context) ([]string, error) {
+	seen := make(map[string]bool)
+	var keys []string
+
+	var books []models.Book
+	result := ts.db.WithContext(ctx).Select("id", "images").
+		Where("images <> ''").
+		FindInBatches(&books, 500, func(tx *gorm.DB, batch int) error {
+			for _, b := range books {
+				var urls []string
+				if json.Unmarshal([]byte(b.Images), &urls) != nil {
+					continue
+				}
+				for _, url := range urls {
+					key, ok := utils.KeyFromURL(ts.storage, url)
+					if !ok || utils.IsThumbnailKey(key) || seen[key] {
+						continue
+					}
+					seen[key] = true
+					keys = append(keys, key)
+				}
+			}
+			return nil
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to load book images: %w", result.Error)
+	}
+
+	return keys, nil
+}
+
+// ensureThumbnail 缩略图不存在时生成，返回是否新生成
+func (ts *ThumbnailService) ensureThumbnail(ctx context.Context, key string) (bool, error) {
+	for _, thumbKey := range utils.ThumbnailKeys(key) {
+		if f, err := ts.storage.Open(ctx, thumbKey); err == nil {
+			f.Close()
+			return false, nil
+		}
+	}
+
+	src, err := ts.storage.Open(ctx, key)
+	if err != nil {
+		return false, fmt.Errorf("failed to open original: %w", err)
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, thumbnailSourceMaxSize))
+	if err != nil {
+		return false, fmt.Errorf("failed to read original: %w", err)
+	}
+
+	if _, err := ts.uploader.CreateThumbnail(ctx, key, data); err != nil {
+		return false, err
+	}
+	return true, nil
+}
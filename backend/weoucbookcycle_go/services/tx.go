@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// txKey context 中保存当前事务的键
+type txKey struct{}
+
+// unitOfWork 一次事务：tx 为事务连接，afterCommit 为提交后才执行的副作用
+type unitOfWork struct {
+	tx          *gorm.DB
+	afterCommit []func()
+}
+
+// WithTx 在事务中执行 fn：fn 返回错误或 panic 时回滚，否则提交
+// ctx 中已有事务时（被另一个 WithTx 调用）直接加入外层事务，由最外层统一提交或回滚，
+// 因此服务方法可以各自使用 WithTx，组合调用时仍然是一个原子操作
+// fn 中的查询必须使用传入的 tx，调用其他服务方法时传入 fn 收到的 ctx
+func WithTx(ctx context.Context, db *gorm.DB, fn func(ctx context.Context, tx *gorm.DB) error) error {
+	if uow, ok := ctx.Value(txKey{}).(*unitOfWork); ok {
+		return fn(ctx, uow.tx)
+	}
+
+	uow := &unitOfWork{}
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		uow.tx = tx
+		return fn(context.WithValue(ctx, txKey{}, uow), tx)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, f := range uow.afterCommit {
+		f()
+	}
+	return nil
+}
+
+// AfterCommit 注册事务提交后执行的副作用（清缓存、更新索引、发通知等），回滚时不执行
+// ctx 不在事务中时立即执行
+func AfterCommit(ctx context.Context, f func()) {
+	if uow, ok := ctx.Value(txKey{}).(*unitOfWork); ok {
+		uow.afterCommit = append(uow.afterCommit, f)
+		return
+	}
+	f()
+}
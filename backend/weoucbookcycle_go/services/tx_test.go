@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// txRecorder 只记录事务开始、提交和回滚次数的 database/sql 驱动
+type txRecorder struct {
+	begins, commits, rollbacks int
+}
+
+func (r *txRecorder) Open(string) (driver.Conn, error) { return txConn{r}, nil }
+
+type txConn struct{ r *txRecorder }
+
+func (c txConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c txConn) Close() error                        { return nil }
+func (c txConn) Begin() (driver.Tx, error) {
+	c.r.begins++
+	return c, nil
+}
+func (c txConn) Commit() error {
+	c.r.commits++
+	return nil
+}
+func (c txConn) Rollback() error {
+	c.r.rollbacks++
+	return nil
+}
+
+func openRecorderDB(t *testing.T) (*gorm.DB, *txRecorder) {
+	t.Helper()
+	r := &txRecorder{}
+	name := "txrecorder-" + t.Name()
+	sql.Register(name, r)
+	sqlDB, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: sqlDB, SkipInitializeWithVersion: true}),
+		&gorm.Config{Logger: logger.Discard, SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, r
+}
+
+func TestWithTxNestedJoinsOuter(t *testing.T) {
+	db, r := openRecorderDB(t)
+
+	var order []string
+	err := WithTx(context.Background(), db, func(ctx context.Context, outer *gorm.DB) error {
+		return WithTx(ctx, db, func(ctx context.Context, inner *gorm.DB) error {
+			if inner != outer {
+				t.Error("nested WithTx should reuse the outer transaction")
+			}
+			AfterCommit(ctx, func() { order = append(order, "inner") })
+			order = append(order, "body")
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.begins != 1 || r.commits != 1 || r.rollbacks != 0 {
+		t.Errorf("begins=%d commits=%d rollbacks=%d, want one committed transaction", r.begins, r.commits, r.rollbacks)
+	}
+	if len(order) != 2 || order[1] != "inner" {
+		t.Errorf("after-commit hooks should run after the body: %v", order)
+	}
+}
+
+func TestWithTxRollback(t *testing.T) {
+	db, r := openRecorderDB(t)
+	boom := errors.New("boom")
+
+	ran := false
+	err := WithTx(context.Background(), db, func(ctx context.Context, tx *gorm.DB) error {
+		AfterCommit(ctx, func() { ran = true })
+		return WithTx(ctx, db, func(context.Context, *gorm.DB) error { return boom })
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("got %v, want the inner error", err)
+	}
+	if r.commits != 0 || r.rollbacks != 1 {
+		t.Errorf("commits=%d rollbacks=%d, want a single rollback", r.commits, r.rollbacks)
+	}
+	if ran {
+		t.Error("after-commit hook ran for a rolled back transaction")
+	}
+
+	// 不在事务中时立即执行
+	AfterCommit(context.Background(), func() { ran = true })
+	if !ran {
+		t.Error("AfterCommit outside a transaction should run immediately")
+	}
+}
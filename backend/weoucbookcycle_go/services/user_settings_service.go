@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UserSettingsService 用户设置服务
+type UserSettingsService struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+}
+
+// NewUserSettingsService 创建用户设置服务实例
+func NewUserSettingsService(deps Deps) *UserSettingsService {
+	return &UserSettingsService{
+		db:          deps.DB,
+		redisClient: deps.Redis,
+	}
+}
+
+// UpdateSettingsRequest 更新用户设置请求，未提供的字段保持不变
+type UpdateSettingsRequest struct {
+	DiscoverableInSearch *bool   `json:"discoverable_in_search"`
+	PersonalizedSearch   *bool   `json:"personalized_search"`
+	ShowOnlineStatus     *bool   `json:"show_online_status"`
+	ShowBooksOnProfile   *bool   `json:"show_books_on_profile"`
+	AllowStrangerChat    *bool   `json:"allow_stranger_chat"`
+	PushMobile           *bool   `json:"push_mobile"`
+	PushWeb              *bool   `json:"push_web"`
+	PushWeChat           *bool   `json:"push_wechat"`
+	Language             *string `json:"language" binding:"omitempty,oneof=zh-CN en"`
+}
+
+// GetSettings 获取用户设置，没有记录时返回默认值
+func (uss *UserSettingsService) GetSettings(userID string) (*models.UserSettings, error) {
+	var settings models.UserSettings
+	err := uss.db.First(&settings, "user_id = ?", userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.DefaultUserSettings(userID), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get settings: %w", err)
+	}
+	return &settings, nil
+}
+
+// UpdateSettings 更新用户设置
+func (uss *UserSettingsService) UpdateSettings(ctx context.Context, userID string, req *UpdateSettingsRequest) (*models.UserSettings, error) {
+	settings, err := uss.GetSettings(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if req.DiscoverableInSearch != nil {
+		settings.DiscoverableInSearch = *req.DiscoverableInSearch
+		updates["discoverable_in_search"] = *req.DiscoverableInSearch
+	}
+	if req.PersonalizedSearch != nil {
+		settings.PersonalizedSearch = *req.PersonalizedSearch
+		updates["personalized_search"] = *req.PersonalizedSearch
+	}
+	if req.ShowOnlineStatus != nil {
+		settings.ShowOnlineStatus = *req.ShowOnlineStatus
+		updates["show_online_status"] = *req.ShowOnlineStatus
+	}
+	if req.ShowBooksOnProfile != nil {
+		settings.ShowBooksOnProfile = *req.ShowBooksOnProfile
+		updates["show_books_on_profile"] = *req.ShowBooksOnProfile
+	}
+	if req.AllowStrangerChat != nil {
+		settings.AllowStrangerChat = *req.AllowStrangerChat
+		updates["allow_stranger_chat"] = *req.AllowStrangerChat
+	}
+	if req.PushMobile != nil {
+		settings.PushMobile = *req.PushMobile
+		updates["push_mobile"] = *req.PushMobile
+	}
+	if req.PushWeb != nil {
+		settings.PushWeb = *req.PushWeb
+		updates["push_web"] = *req.PushWeb
+	}
+	if req.PushWeChat != nil {
+		settings.PushWeChat = *req.PushWeChat
+		updates["push_we_chat"] = *req.PushWeChat
+	}
+	if req.Language != nil {
+		settings.Language = *req.Language
+		updates["language"] = *req.Language
+	}
+	if len(updates) == 0 {
+		return settings, nil
+	}
+
+	columns := make([]string, 0, len(updates)+1)
+	for column := range updates {
+		columns = append(columns, column)
+	}
+	columns = append(columns, "updated_at")
+
+	// 首次修改时创建记录，之后只更新提交的字段
+	// 使用map写入，避免带默认值的false字段被gorm忽略
+	now := time.Now()
+	updates["user_id"] = userID
+	updates["created_at"] = now
+	updates["updated_at"] = now
+	if err := uss.db.WithContext(ctx).Model(&models.UserSettings{}).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns(columns),
+	}).Create(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update settings: %w", err)
+	}
+	settings.UpdatedAt = now
+
+	if req.PersonalizedSearch != nil {
+		uss.InvalidateAffinity(ctx, userID)
+	}
+	// 公开资料缓存中包含书籍和发布
+	if req.ShowBooksOnProfile != nil && uss.redisClient != nil {
+		uss.redisClient.Del(ctx, "user:"+userID)
+	}
+
+	return settings, nil
+}
+
+// UserLanguage 用户设置的通知语言，未设置时使用默认语言
+// 异步生成通知、推送等文案时没有请求上下文，按接收者的设置选择语言
+func UserLanguage(db *gorm.DB, userID string) string {
+	var language string
+	if db != nil {
+		db.Model(&models.UserSettings{}).Select("language").Where("user_id = ?", userID).Limit(1).Scan(&language)
+	}
+	if lang := utils.NormalizeLang(language); lang != "" {
+		return lang
+	}
+	return utils.DefaultLang()
+}
+
+// DiscoverableUsers 查询作用域：只包含正常状态且允许被搜索到的用户
+func DiscoverableUsers(db *gorm.DB) *gorm.DB {
+	return db.Where("users.status = ?", 1).
+		Where("NOT EXISTS (SELECT 1 FROM user_settings WHERE user_settings.user_id = users.id AND user_settings.discoverable_in_search = ?)", false)
+}
+
+// privacyAllows 用户是否开启了某个隐私开关（column 为 user_settings 的布尔列），没有设置记录时为默认开启
+func privacyAllows(ctx context.Context, db *gorm.DB, userID, column string) (bool, error) {
+	var count int64
+	if err := db.WithContext(ctx).Model(&models.UserSettings{}).
+		Where("user_id = ? AND "+column+" = ?", userID, false).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check privacy settings: %w", err)
+	}
+	return count == 0, nil
+}
+
+// withVisibleOnlineStatus 去掉关闭了在线状态显示的用户和已停用账号的用户
+func withVisibleOnlineStatus(ctx context.Context, db *gorm.DB, userIDs []string) ([]string, error) {
+	if len(userIDs) == 0 {
+		return userIDs, nil
+	}
+	var hidden, deactivated []string
+	if err := db.WithContext(ctx).Model(&models.UserSettings{}).
+		Where("user_id IN ? AND show_online_status = ?", userIDs, false).
+		Pluck("user_id", &hidden).Error; err != nil {
+		return nil, fmt.Errorf("failed to check privacy settings: %w", err)
+	}
+	if err := db.WithContext(ctx).Model(&models.User{}).
+		Where("id IN ? AND status = ?", userIDs, models.UserStatusDeactivated).
+		Pluck("id", &deactivated).Error; err != nil {
+		return nil, fmt.Errorf("failed to check account status: %w", err)
+	}
+	hidden = append(hidden, deactivated...)
+	if len(hidden) == 0 {
+		return userIDs, nil
+	}
+	skip := make(map[string]bool, len(hidden))
+	for _, id := range hidden {
+		skip[id] = true
+	}
+	visible := make([]string, 0, len(userIDs))
+	for _, id := range userIDs {
+		if !skip[id] {
+			visible = append(visible, id)
+		}
+	}
+	return visible, nil
+}
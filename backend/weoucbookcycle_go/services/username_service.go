@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UsernameCooldown 两次修改用户名的最短间隔
+const UsernameCooldown = 30 * 24 * time.Hour
+
+// 用户名不可用的原因
+const (
+	UsernameInvalid   = "invalid"
+	UsernameTaken     = "taken"
+	UsernameUnchanged = "unchanged"
+)
+
+var (
+	ErrUsernameTaken     = utils.NewError(http.StatusConflict, "username already exists")
+	ErrUsernameUnchanged = utils.NewError(http.StatusBadRequest, "new username is the same as the current one")
+)
+
+// UsernameRequest 修改用户名请求
+type UsernameRequest struct {
+	Username string `json:"username" binding:"required,username"`
+}
+
+// UsernameAvailability 用户名可用性检查结果，不可用时 Reason 为 invalid/taken/unchanged
+type UsernameAvailability struct {
+	Username  string `json:"username"`
+	Available bool   `json:"available"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// UsernameService 用户名修改，保证唯一并限制修改频率
+type UsernameService struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+}
+
+// NewUsernameService 创建用户名服务实例
+func NewUsernameService(deps Deps) *UsernameService {
+	return &UsernameService{
+		db:          deps.DB,
+		redisClient: deps.Redis,
+	}
+}
+
+// Check 检查用户名是否可用，userID 为当前用户（未登录时为空）
+func (us *UsernameService) Check(ctx context.Context, userID, username string) (*UsernameAvailability, error) {
+	result := &UsernameAvailability{Username: username}
+	if !utils.ValidUsername(username) {
+		result.Reason = UsernameInvalid
+		return result, nil
+	}
+
+	var owner models.User
+	err := us.db.WithContext(ctx).Select("id").Where("username = ?", username).First(&owner).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		result.Available = true
+	case err != nil:
+		return nil, fmt.Errorf("failed to check username: %w", err)
+	case owner.ID == userID:
+		result.Reason = UsernameUnchanged
+	default:
+		result.Reason = UsernameTaken
+	}
+	return result, nil
+}
+
+// Change 修改用户名并记录历史，距上次修改不足 UsernameCooldown 时返回限流错误
+func (us *UsernameService) Change(ctx context.Context, userID, username string) (*models.User, error) {
+	var user models.User
+	err := WithTx(ctx, us.db, func(ctx context.Context, tx *gorm.DB) error {
+		// 锁住用户行，同一用户的并发修改依次执行
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, "id = ?", userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrUserNotFound
+			}
+			return fmt.Errorf("failed to load user: %w", err)
+		}
+		if user.Username == username {
+			return ErrUsernameUnchanged
+		}
+
+		var last models.UsernameChange
+		err := tx.Where("user_id = ?", userID).Order("created_at DESC").First(&last).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to check previous changes: %w", err)
+		}
+		if err == nil {
+			if wait := usernameCooldownLeft(last.CreatedAt, time.Now()); wait > 0 {
+				return &utils.RateLimitError{RetryAfter: wait}
+			}
+		}
+
+		var taken int64
+		if err := tx.Model(&models.User{}).Where("username = ? AND id <> ?", username, userID).Count(&taken).Error; err != nil {
+			return fmt.Errorf("failed to check username: %w", err)
+		}
+		if taken > 0 {
+			return ErrUsernameTaken
+		}
+
+		if err := tx.Model(&user).Update("username", username).Error; err != nil {
+			// 检查之后被别人抢先使用时唯一索引冲突
+			if tx.Model(&models.User{}).Where("username = ? AND id <> ?", username, userID).Count(&taken); taken > 0 {
+				return ErrUsernameTaken
+			}
+			return fmt.Errorf("failed to update username: %w", err)
+		}
+		change := models.UsernameChange{UserID: userID, OldUsername: user.Username, NewUsername: username}
+		if err := tx.Create(&change).Error; err != nil {
+			return fmt.Errorf("failed to record username change: %w", err)
+		}
+		user.Username = username
+
+		AfterCommit(ctx, func() {
+			if us.redisClient != nil {
+				us.redisClient.Del(context.WithoutCancel(ctx), "user:"+userID)
+			}
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// History 获取用户的改名记录，最近的在前
+func (us *UsernameService) History(ctx context.Context, userID string) ([]models.UsernameChange, error) {
+	changes := []models.UsernameChange{}
+	if err := us.db.WithContext(ctx).Where("user_id = ?", userID).
+		Order("created_at DESC").Find(&changes).Error; err != nil {
+		return nil, fmt.Errorf("failed to get username history: %w", err)
+	}
+	return changes, nil
+}
+
+// usernameCooldownLeft 距离可以再次修改用户名还需等待的时间
+func usernameCooldownLeft(lastChange, now time.Time) time.Duration {
+	if wait := lastChange.Add(UsernameCooldown).Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
@@ -0,0 +1,19 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUsernameCooldownLeft(t *testing.T) {
+	now := time.Date(2024, 9, 1, 8, 0, 0, 0, time.UTC)
+	if got := usernameCooldownLeft(now.Add(-24*time.Hour), now); got != UsernameCooldown-24*time.Hour {
+		t.Errorf("renamed a day ago: wait %v, want %v", got, UsernameCooldown-24*time.Hour)
+	}
+	if got := usernameCooldownLeft(now.Add(-UsernameCooldown), now); got != 0 {
+		t.Errorf("renamed exactly one cooldown ago: wait %v, want 0", got)
+	}
+	if got := usernameCooldownLeft(now.AddDate(0, -3, 0), now); got != 0 {
+		t.Errorf("renamed three months ago: wait %v, want 0", got)
+	}
+}
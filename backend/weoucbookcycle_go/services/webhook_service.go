@@ -0,0 +1,519 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+	"weoucbookcycle_go/models"
+	"weoucbookcycle_go/utils"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// JobWebhookDeliver 投递一次 webhook，失败后由任务队列按退避间隔重试
+	JobWebhookDeliver = "webhook:deliver"
+
+	webhookGroup = "webhooks"
+	// webhookMaxRetry 失败后的重试次数，按任务队列的默认退避（约 n^4 秒）共持续约数小时
+	webhookMaxRetry = 8
+	// webhookResponseLimit 读取对方响应的上限，只用于复用连接
+	webhookResponseLimit = 64 * 1024
+)
+
+var (
+	ErrWebhookNotFound       = utils.NewError(http.StatusNotFound, "webhook subscription not found")
+	ErrInvalidWebhookURL     = utils.NewError(http.StatusBadRequest, "webhook url must use https (http is allowed only in development)")
+	ErrWebhookAddressBlocked = utils.NewError(http.StatusBadRequest, "webhook url must resolve to a public address")
+)
+
+// webhookEventStreams webhook 转发的领域事件流
+var webhookEventStreams = []string{"user_events", "book_events", "listing_events"}
+
+// webhookDialer 投递连接使用的拨号器，只通过 dialPublicAddr 使用
+var webhookDialer = &net.Dialer{Timeout: 5 * time.Second}
+
+// webhookHTTPClient 投递使用的客户端，不跟随重定向（3xx 视为失败）
+// 不使用环境变量中的代理，连接时由 dialPublicAddr 检查目标地址，防止投递到内网服务
+var webhookHTTPClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext:         dialPublicAddr,
+		TLSHandshakeTimeout: 5 * time.Second,
+		MaxIdleConnsPerHost: 2,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// WebhookRequest 创建/更新 webhook 订阅请求
+type WebhookRequest struct {
+	Name    string   `json:"name" binding:"required,max=100"`
+	URL     string   `json:"url" binding:"required,url,max=500"`
+	Events  []string `json:"events" binding:"required,min=1,dive,oneof=user.registered book.created order.completed"`
+	Enabled *bool    `json:"enabled"`
+}
+
+// WebhookWithSecret 创建或重置密钥后返回的订阅，secret 只在此时返回
+type WebhookWithSecret struct {
+	*models.WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+// WebhookDeliverJob 投递任务载荷
+type WebhookDeliverJob struct {
+	DeliveryID string `json:"delivery_id"`
+}
+
+// webhookEnvelope 发送给订阅方的请求体
+type webhookEnvelope struct {
+	ID        string                 `json:"id"`
+	Event     string                 `json:"event"`
+	CreatedAt time.Time              `json:"created_at"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+// WebhookService 向外部应用推送平台事件
+// 领域事件由消费组转换为每个订阅的投递记录，再由后台任务签名后 POST 到订阅地址，每次尝试都记录结果
+type WebhookService struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+	// allowHTTP 开发环境允许投递到 http 地址
+	allowHTTP bool
+}
+
+// NewWebhookService 创建 webhook 服务实例，并注册投递任务的处理函数
+func NewWebhookService(deps Deps) *WebhookService {
+	ws := &WebhookService{
+		db:          deps.DB,
+		redisClient: deps.Redis,
+		allowHTTP:   deps.Config.Env == "development",
+	}
+
+	utils.HandleJob(JobWebhookDeliver, ws.processDelivery, asynq.MaxRetry(webhookMaxRetry), asynq.Timeout(30*time.Second))
+
+	return ws
+}
+
+// Start 启动事件消费者，把领域事件转换为投递；投递按 (订阅, 事件) 去重，事件可以重复处理
+func (ws *WebhookService) Start(ctx context.Context) {
+	startEventConsumer(ctx, ws.redisClient, webhookGroup, webhookEventStreams, ws.handleEvent)
+}
+
+// ==================== 订阅管理 ====================
+
+// List 获取全部订阅
+func (ws *WebhookService) List(ctx context.Context) ([]models.WebhookSubscription, error) {
+	subs := []models.WebhookSubscription{}
+	if err := ws.db.WithContext(ctx).Order("created_at").Find(&subs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	return subs, nil
+}
+
+// Create 登记订阅并生成签名密钥
+func (ws *WebhookService) Create(ctx context.Context, adminID string, req *WebhookRequest) (*WebhookWithSecret, error) {
+	if err := ws.checkURL(ctx, req.URL); err != nil {
+		return nil, err
+	}
+	events, _ := json.Marshal(req.Events)
+	sub := &models.WebhookSubscription{
+		Name:      req.Name,
+		URL:       req.URL,
+		Secret:    generateRandomToken(32),
+		Events:    events,
+		Enabled:   true,
+		CreatedBy: adminID,
+	}
+	if err := ws.db.WithContext(ctx).Create(sub).Error; err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	// 停用状态需要单独更新（零值不会写入）
+	if req.Enabled != nil && !*req.Enabled {
+		ws.db.WithContext(ctx).Model(sub).Update("enabled", false)
+		sub.Enabled = false
+	}
+	return &WebhookWithSecret{WebhookSubscription: sub, Secret: sub.Secret}, nil
+}
+
+// Update 更新订阅，停用后不再产生新的投递，待重试的投递也不再发送
+func (ws *WebhookService) Update(ctx context.Context, id string, req *WebhookRequest) (*models.WebhookSubscription, error) {
+	if err := ws.checkURL(ctx, req.URL); err != nil {
+		return nil, err
+	}
+	sub, err := ws.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	events, _ := json.Marshal(req.Events)
+	updates := map[string]interface{}{"name": req.Name, "url": req.URL, "events": datatypes.JSON(events)}
+	if req.Enabled != nil {
+		updates["enabled"] = *req.Enabled
+	}
+	if err := ws.db.WithContext(ctx).Model(sub).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return ws.get(ctx, id)
+}
+
+// RotateSecret 重新生成签名密钥，旧密钥立即失效
+func (ws *WebhookService) RotateSecret(ctx context.Context, id string) (*WebhookWithSecret, error) {
+	sub, err := ws.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	secret := generateRandomToken(32)
+	if err := ws.db.WithContext(ctx).Model(sub).Update("secret", secret).Error; err != nil {
+		return nil, fmt.Errorf("failed to rotate webhook secret: %w", err)
+	}
+	return &WebhookWithSecret{WebhookSubscription: sub, Secret: secret}, nil
+}
+
+// Delete 删除订阅，投递记录保留
+func (ws *WebhookService) Delete(ctx context.Context, id string) error {
+	result := ws.db.WithContext(ctx).Delete(&models.WebhookSubscription{}, "id = ?", id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete webhook: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// Deliveries 获取订阅的投递记录（含每次尝试），最近的在前；status 为空时返回全部
+// 已删除订阅的投递记录仍可查询
+func (ws *WebhookService) Deliveries(ctx context.Context, subscriptionID, status string, page, limit int) ([]models.WebhookDelivery, int64, error) {
+	var subs int64
+	if err := ws.db.WithContext(ctx).Unscoped().Model(&models.WebhookSubscription{}).
+		Where("id = ?", subscriptionID).Count(&subs).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	if subs == 0 {
+		return nil, 0, ErrWebhookNotFound
+	}
+
+	query := ws.db.WithContext(ctx).Model(&models.WebhookDelivery{}).Where("subscription_id = ?", subscriptionID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count webhook deliveries: %w", err)
+	}
+	deliveries := []models.WebhookDelivery{}
+	if err := query.Preload("AttemptLog", func(db *gorm.DB) *gorm.DB { return db.Order("attempt") }).
+		Order("created_at DESC").Limit(limit).Offset(utils.PageOffset(page, limit)).
+		Find(&deliveries).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	return deliveries, total, nil
+}
+
+// get 按ID获取订阅
+func (ws *WebhookService) get(ctx context.Context, id string) (*models.WebhookSubscription, error) {
+	var sub models.WebhookSubscription
+	if err := ws.db.WithContext(ctx).First(&sub, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return &sub, nil
+}
+
+// ==================== 投递 ====================
+
+// handleEvent 为订阅了该事件的启用中的订阅创建投递并提交投递任务
+// 只投递订阅创建之后发生的事件，消费组首次建立时不会把历史事件发给新订阅
+func (ws *WebhookService) handleEvent(ctx context.Context, stream string, msg redis.XMessage) error {
+	event, data, ok := webhookEvent(msg.Values)
+	if !ok {
+		return nil
+	}
+	occurredAt, ok := streamIDTime(msg.ID)
+	if !ok {
+		occurredAt = time.Now()
+	}
+
+	var subs []models.WebhookSubscription
+	if err := ws.db.WithContext(ctx).Where("enabled = ? AND created_at <= ?", true, occurredAt).
+		Find(&subs).Error; err != nil {
+		return fmt.Errorf("failed to load webhooks: %w", err)
+	}
+
+	body, err := json.Marshal(webhookEnvelope{ID: msg.ID, Event: event, CreatedAt: occurredAt, Data: data})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	for _, sub := range subs {
+		if !subscribesTo(sub.Events, event) {
+			continue
+		}
+		delivery := models.WebhookDelivery{
+			SubscriptionID: sub.ID,
+			EventID:        msg.ID,
+			Event:          event,
+			Payload:        body,
+			Status:         models.WebhookDeliveryPending,
+		}
+		result := ws.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&delivery)
+		if result.Error != nil {
+			return fmt.Errorf("failed to create webhook delivery: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		if err := utils.EnqueueJob(ctx, JobWebhookDeliver, WebhookDeliverJob{DeliveryID: delivery.ID}); err != nil {
+			log.Printf("webhook: failed to enqueue delivery %s: %v", delivery.ID, err)
+		}
+	}
+	return nil
+}
+
+// processDelivery 投递一次并记录结果；失败时返回错误由任务队列重试，重试用尽后标记为失败
+func (ws *WebhookService) processDelivery(ctx context.Context, job WebhookDeliverJob) error {
+	var delivery models.WebhookDelivery
+	if err := ws.db.WithContext(ctx).First(&delivery, "id = ?", job.DeliveryID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to load webhook delivery: %w", err)
+	}
+	if delivery.Status != models.WebhookDeliveryPending {
+		return nil
+	}
+
+	var sub models.WebhookSubscription
+	if err := ws.db.WithContext(ctx).First(&sub, "id = ?", delivery.SubscriptionID).Error; err != nil || !sub.Enabled {
+		return ws.db.WithContext(ctx).Model(&delivery).Updates(map[string]interface{}{
+			"status":     models.WebhookDeliveryFailed,
+			"last_error": "subscription was disabled or deleted",
+		}).Error
+	}
+
+	start := time.Now()
+	statusCode, sendErr := ws.send(ctx, &sub, &delivery)
+	attempt := models.WebhookDeliveryAttempt{
+		DeliveryID:     delivery.ID,
+		SubscriptionID: sub.ID,
+		Attempt:        delivery.Attempts + 1,
+		StatusCode:     statusCode,
+		DurationMs:     time.Since(start).Milliseconds(),
+	}
+	updates := map[string]interface{}{
+		"attempts":         attempt.Attempt,
+		"last_status_code": statusCode,
+		"last_error":       "",
+	}
+	switch {
+	case sendErr == nil:
+		now := time.Now()
+		updates["status"] = models.WebhookDeliverySucceeded
+		updates["delivered_at"] = &now
+	case utils.IsFinalJobAttempt(ctx):
+		updates["status"] = models.WebhookDeliveryFailed
+		fallthrough
+	default:
+		attempt.Error = truncateRunes(sendErr.Error(), 400)
+		updates["last_error"] = attempt.Error
+	}
+
+	err := WithTx(ctx, ws.db, func(ctx context.Context, tx *gorm.DB) error {
+		if err := tx.Create(&attempt).Error; err != nil {
+			return err
+		}
+		return tx.Model(&delivery).Updates(updates).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record webhook attempt: %w", err)
+	}
+
+	// 重试用尽的失败已记录在投递中，不再作为任务失败上报
+	if sendErr != nil && updates["status"] != models.WebhookDeliveryFailed {
+		return sendErr
+	}
+	return nil
+}
+
+// send 签名并发送请求，返回对方的状态码；非2xx视为失败
+func (ws *WebhookService) send(ctx context.Context, sub *models.WebhookSubscription, delivery *models.WebhookDelivery) (int, error) {
+	// 地址在登记时已校验，这里防止配置变化后继续向 http 地址投递；目标IP在连接时检查
+	if !isWebhookURL(sub.URL, ws.allowHTTP) {
+		return 0, ErrInvalidWebhookURL
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "WeOUC-BookCycle-Webhook/1.0")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", delivery.ID)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", webhookSignature(sub.Secret, timestamp, delivery.Payload))
+
+	resp, err := webhookHTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, webhookResponseLimit))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// ==================== 辅助方法 ====================
+
+// webhookEvent 把领域事件转换为 webhook 事件名和数据，不转发的事件返回 false
+// 数据只包含ID和公开字段，不转发邮箱、IP等个人信息
+func webhookEvent(values map[string]interface{}) (string, map[string]interface{}, bool) {
+	switch streamString(values["event"]) {
+	case "register":
+		return models.WebhookUserRegistered, map[string]interface{}{
+			"user_id":  streamString(values["user_id"]),
+			"username": streamString(values["username"]),
+		}, true
+	case "book_created":
+		return models.WebhookBookCreated, map[string]interface{}{
+			"book_id":   streamString(values["book_id"]),
+			"title":     streamString(values["title"]),
+			"seller_id": streamString(values["seller_id"]),
+		}, true
+	case "listing_sold":
+		return models.WebhookOrderCompleted, map[string]interface{}{
+			"listing_id": streamString(values["listing_id"]),
+			"seller_id":  streamString(values["seller_id"]),
+			"buyer_id":   streamString(values["buyer_id"]),
+			"donation":   streamString(values["donation"]) == "true",
+		}, true
+	default:
+		return "", nil, false
+	}
+}
+
+// webhookSignature 请求签名：sha256=hex(HMAC-SHA256(secret, timestamp + "." + body))
+// 订阅方应校验签名并拒绝时间戳过旧的请求，防止重放
+func webhookSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// subscribesTo 订阅的事件列表（JSON数组）中是否包含 event
+func subscribesTo(events []byte, event string) bool {
+	var list []string
+	if json.Unmarshal(events, &list) != nil {
+		return false
+	}
+	return slices.Contains(list, event)
+}
+
+// checkURL 校验订阅地址：必须是 https（开发环境允许 http），且主机名只解析到公网地址
+func (ws *WebhookService) checkURL(ctx context.Context, rawURL string) error {
+	if !isWebhookURL(rawURL, ws.allowHTTP) {
+		return ErrInvalidWebhookURL
+	}
+	u, _ := url.Parse(rawURL)
+	if _, err := resolvePublicAddrs(ctx, u.Hostname()); err != nil {
+		return err
+	}
+	return nil
+}
+
+// isWebhookURL 是否为带主机名的 https 地址，allowHTTP 时也允许 http
+func isWebhookURL(rawURL string, allowHTTP bool) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" || u.User != nil {
+		return false
+	}
+	return u.Scheme == "https" || (allowHTTP && u.Scheme == "http")
+}
+
+// dialPublicAddr 解析主机名并只连接公网地址
+// 检查在每次连接时进行，登记后把域名改为解析到内网（DNS重绑定）也无法绕过
+func dialPublicAddr(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := resolvePublicAddrs(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, ip := range ips {
+		conn, err := webhookDialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// resolvePublicAddrs 解析主机名，任一地址不是公网地址时拒绝
+func resolvePublicAddrs(ctx context.Context, host string) ([]netip.Addr, error) {
+	ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrWebhookAddressBlocked, err)
+	}
+	if len(ips) == 0 {
+		return nil, ErrWebhookAddressBlocked
+	}
+	for _, ip := range ips {
+		if !isPublicAddr(ip) {
+			return nil, fmt.Errorf("%w: %s resolves to %s", ErrWebhookAddressBlocked, host, ip)
+		}
+	}
+	return ips, nil
+}
+
+// reservedPrefixes 不在 netip 私有地址判断中的保留地址：本网络（0.0.0.0/8）和运营商级NAT（100.64.0.0/10）
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+}
+
+// isPublicAddr 是否为公网地址：排除回环、私有（RFC 1918/ULA）、链路本地（含 169.254.169.254 元数据地址）、
+// 组播、未指定地址和 reservedPrefixes
+func isPublicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || !ip.IsGlobalUnicast() || ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() {
+		return false
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"sort"
+	"sync"
+	"weoucbookcycle_go/config"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sony/gobreaker/v2"
+)
+
+// 熔断器：依赖（Redis、SMTP）连续失败后一段时间内直接返回 ErrCircuitOpen，
+// 调用方按失败处理（跳过缓存、邮件延后发送），不再每次都等待连接超时
+
+// 熔断器名称
+const (
+	BreakerRedis = "redis"
+	BreakerSMTP  = "smtp"
+)
+
+// ErrCircuitOpen 熔断期间的调用直接返回该错误
+var ErrCircuitOpen = gobreaker.ErrOpenState
+
+// Breaker 一个依赖的熔断器
+type Breaker struct {
+	cb *gobreaker.CircuitBreaker[struct{}]
+}
+
+var (
+	breakers   = make(map[string]*Breaker)
+	breakersMu sync.Mutex
+)
+
+// GetBreaker 返回指定名称的熔断器，第一次调用时按 cfg 创建
+// isFailure 判断哪些错误计入连续失败（业务错误不应导致熔断），为 nil 时所有错误都计入
+func GetBreaker(name string, cfg *config.BreakerConfig, isFailure func(error) bool) *Breaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	if b, ok := breakers[name]; ok {
+		return b
+	}
+
+	threshold := uint32(cfg.FailureThreshold)
+	settings := gobreaker.Settings{
+		Name:        name,
+		MaxRequests: 1,
+		Timeout:     cfg.OpenTimeout,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= threshold
+		},
+		OnStateChange: func(name string, from, to gobreaker.State) {
+			log.Printf("circuit breaker %s: %s -> %s", name, from, to)
+			CircuitBreakerState.WithLabelValues(name).Set(float64(to))
+		},
+	}
+	if isFailure != nil {
+		settings.IsSuccessful = func(err error) bool { return err == nil || !isFailure(err) }
+	}
+
+	b := &Breaker{cb: gobreaker.NewCircuitBreaker[struct{}](settings)}
+	breakers[name] = b
+	CircuitBreakerState.WithLabelValues(name).Set(float64(gobreaker.StateClosed))
+	return b
+}
+
+// Execute 通过熔断器执行调用，熔断期间不执行 fn 直接返回 ErrCircuitOpen
+func (b *Breaker) Execute(fn func() error) error {
+	_, err := b.cb.Execute(func() (struct{}, error) {
+		return struct{}{}, fn()
+	})
+	if errors.Is(err, gobreaker.ErrTooManyRequests) {
+		// 半开状态下已有探测请求在执行，按熔断处理
+		return ErrCircuitOpen
+	}
+	return err
+}
+
+// IsCircuitOpen 错误是否由熔断导致（调用没有真正发出）
+func IsCircuitOpen(err error) bool {
+	return errors.Is(err, ErrCircuitOpen)
+}
+
+// BreakerState 熔断器状态
+type BreakerState struct {
+	Name                string `json:"name"`
+	State               string `json:"state"` // closed, half-open, open
+	ConsecutiveFailures uint32 `json:"consecutive_failures"`
+	TotalFailures       uint32 `json:"total_failures"`
+}
+
+// BreakerStates 返回所有熔断器的状态
+func BreakerStates() []BreakerState {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+
+	states := make([]BreakerState, 0, len(breakers))
+	for name, b := range breakers {
+		counts := b.cb.Counts()
+		states = append(states, BreakerState{
+			Name:                name,
+			State:               b.cb.State().String(),
+			ConsecutiveFailures: counts.ConsecutiveFailures,
+			TotalFailures:       counts.TotalFailures,
+		})
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	return states
+}
+
+// ProtectRedis 为 Redis 客户端的所有命令加上熔断
+// 熔断期间命令立即返回 ErrCircuitOpen，调用方按Redis不可用处理（缓存未命中、跳过计数）
+func ProtectRedis(client *redis.Client, cfg *config.BreakerConfig) {
+	if client != nil {
+		client.AddHook(redisBreakerHook{breaker: GetBreaker(BreakerRedis, cfg, isRedisFailure)})
+	}
+}
+
+// isRedisFailure 只有连接层面的错误计入失败；key 不存在、服务端返回的命令错误、
+// 调用方取消请求都说明Redis本身可用
+func isRedisFailure(err error) bool {
+	var redisErr redis.Error
+	return !errors.Is(err, redis.Nil) && !errors.Is(err, context.Canceled) && !errors.As(err, &redisErr)
+}
+
+// redisBreakerHook go-redis 钩子
+type redisBreakerHook struct {
+	breaker *Breaker
+}
+
+func (h redisBreakerHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (h redisBreakerHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		err := h.breaker.Execute(func() error { return next(ctx, cmd) })
+		if IsCircuitOpen(err) {
+			// 命令没有发出，调用方从 cmd 读取结果，需要把错误写回
+			cmd.SetErr(err)
+		}
+		return err
+	}
+}
+
+func (h redisBreakerHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		err := h.breaker.Execute(func() error { return next(ctx, cmds) })
+		if IsCircuitOpen(err) {
+			for _, cmd := range cmds {
+				cmd.SetErr(err)
+			}
+		}
+		return err
+	}
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"weoucbookcycle_go/config"
+)
+
+// 连续失败达到阈值后熔断，不计入失败的错误不会触发熔断
+func TestBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	ignored := errors.New("rejected")
+	b := GetBreaker("test", &config.BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute},
+		func(err error) bool { return !errors.Is(err, ignored) })
+
+	calls := 0
+	fail := func() error { calls++; return errors.New("dial tcp: connection refused") }
+	for i := 0; i < 3; i++ {
+		_ = b.Execute(func() error { calls++; return ignored })
+	}
+	_ = b.Execute(fail)
+	if err := b.Execute(fail); IsCircuitOpen(err) {
+		t.Fatal("breaker opened before reaching the threshold")
+	}
+	if err := b.Execute(fail); !IsCircuitOpen(err) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if calls != 5 {
+		t.Fatalf("call should not run while open, got %d calls", calls)
+	}
+
+	for _, state := range BreakerStates() {
+		if state.Name == "test" && state.State != "open" {
+			t.Fatalf("unexpected state: %+v", state)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// CacheStat 缓存命中统计
+type CacheStat struct {
+	Name    string  `json:"name"`
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+}
+
+// cacheCounter 单类缓存的命中/未命中计数
+type cacheCounter struct {
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// cacheCounters 缓存类别 -> 计数器，只统计当前实例，重启后清零
+var cacheCounters sync.Map
+
+func getCacheCounter(name string) *cacheCounter {
+	if counter, ok := cacheCounters.Load(name); ok {
+		return counter.(*cacheCounter)
+	}
+	counter, _ := cacheCounters.LoadOrStore(name, &cacheCounter{})
+	return counter.(*cacheCounter)
+}
+
+// RecordCacheHit 记录一次缓存命中
+func RecordCacheHit(name string) {
+	getCacheCounter(name).hits.Add(1)
+}
+
+// RecordCacheMiss 记录一次缓存未命中
+func RecordCacheMiss(name string) {
+	getCacheCounter(name).misses.Add(1)
+}
+
+// CacheStats 返回各类缓存的命中统计，按名称排序
+func CacheStats() []CacheStat {
+	stats := []CacheStat{}
+	cacheCounters.Range(func(key, value interface{}) bool {
+		counter := value.(*cacheCounter)
+		stat := CacheStat{
+			Name:   key.(string),
+			Hits:   counter.hits.Load(),
+			Misses: counter.misses.Load(),
+		}
+		if total := stat.Hits + stat.Misses; total > 0 {
+			stat.HitRate = float64(stat.Hits) / float64(total)
+		}
+		stats = append(stats, stat)
+		return true
+	})
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
+// ResetCacheStats 清空缓存命中统计
+func ResetCacheStats() {
+	cacheCounters.Clear()
+}
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 缓存标签：写入模糊匹配的缓存（搜索结果、推荐、消息分页等）时同时把键登记到标签集合，
+// 失效时只删除集合中登记的键，不再用 KEYS 扫描整个库
+
+const (
+	// CacheTagSearch 搜索结果和搜索建议，书籍或同义词变化时失效
+	CacheTagSearch = "search"
+	// CacheTagRecommendations 个性化推荐，书籍变化时失效
+	CacheTagRecommendations = "recommendations"
+	// CacheTagHotBooks 不限校区和各校区的热门书籍，书籍变化时失效
+	CacheTagHotBooks = "hot_books"
+
+	cacheTagPrefix   = "cachetag:"
+	cacheTagPopBatch = 500
+	// cacheTagTTL 标签集合的过期时间，需要长于登记到标签的缓存的过期时间
+	cacheTagTTL = 24 * time.Hour
+)
+
+// ChatCacheTag 单个会话的消息分页缓存
+func ChatCacheTag(chatID string) string {
+	return "chat:" + chatID
+}
+
+// SetTaggedCache 写入缓存并登记到标签，ttl 不能超过 cacheTagTTL
+// 集合中已过期的键会在下次失效时一并清理
+func SetTaggedCache(ctx context.Context, client *redis.Client, tag, key string, value interface{}, ttl time.Duration) error {
+	if client == nil {
+		return nil
+	}
+	tagKey := cacheTagPrefix + tag
+	_, err := client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, key, value, ttl)
+		pipe.SAdd(ctx, tagKey, key)
+		pipe.Expire(ctx, tagKey, cacheTagTTL)
+		return nil
+	})
+	return err
+}
+
+// InvalidateCacheTag 删除标签下登记的全部缓存，返回删除的键数
+// 使用 SPOP 分批取出，失效过程中新写入的键会登记到集合中，不会被漏掉或误删
+func InvalidateCacheTag(ctx context.Context, client *redis.Client, tag string) (int64, error) {
+	if client == nil {
+		return 0, nil
+	}
+	tagKey := cacheTagPrefix + tag
+
+	var deleted int64
+	for {
+		keys, err := client.SPopN(ctx, tagKey, cacheTagPopBatch).Result()
+		if err != nil {
+			return deleted, err
+		}
+		if len(keys) == 0 {
+			return deleted, nil
+		}
+		n, err := client.Unlink(ctx, keys...).Result()
+		if err != nil {
+			return deleted, err
+		}
+		deleted += n
+	}
+}
@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"testing"
+	"weoucbookcycle_go/config"
+)
+
+// withConfig 按当前环境变量构造配置，修改后应用到存储、签名URL和默认语言设置，测试结束后恢复
+func withConfig(t *testing.T, mutate func(cfg *config.Config)) {
+	t.Helper()
+	apply := func(mutate func(cfg *config.Config)) {
+		cfg, err := config.FromEnv()
+		if err != nil {
+			t.Fatalf("failed to load config: %v", err)
+		}
+		mutate(cfg)
+		InitStorage(cfg)
+		SetDefaultLang(cfg.DefaultLanguage)
+	}
+	apply(mutate)
+	t.Cleanup(func() { apply(func(*config.Config) {}) })
+}
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCursor 游标无法解析
+var ErrInvalidCursor = NewError(http.StatusBadRequest, "invalid cursor")
+
+// Cursor 游标分页位置：上一页最后一条记录的创建时间和ID
+// 列表按 created_at DESC, id DESC 排序，翻页时新插入的记录不会导致重复或遗漏
+type Cursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// CursorPage 游标分页响应（v2 列表接口）
+type CursorPage struct {
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	HasMore    bool        `json:"has_more"`
+}
+
+// EncodeCursor 生成不透明的游标字符串
+func EncodeCursor(createdAt time.Time, id string) string {
+	raw := strconv.FormatInt(createdAt.UnixNano(), 10) + ":" + id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor 解析游标，空串表示第一页
+func DecodeCursor(s string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	nanos, id, ok := strings.Cut(string(raw), ":")
+	if !ok || id == "" {
+		return nil, ErrInvalidCursor
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &Cursor{CreatedAt: time.Unix(0, n), ID: id}, nil
+}
+
+// CursorParams 读取 cursor 和 limit 查询参数，limit 取值 1~MaxPageLimit，默认 DefaultPageLimit
+func CursorParams(c *gin.Context) (*Cursor, int, error) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(DefaultPageLimit)))
+	if limit < 1 || limit > MaxPageLimit {
+		limit = DefaultPageLimit
+	}
+	cursor, err := DecodeCursor(c.Query("cursor"))
+	return cursor, limit, err
+}
+
+// ApplyCursor 按 created_at DESC, id DESC 排序并从游标之后开始取，多取一条用于判断是否还有下一页
+// table 为列所属的表名，联表查询时避免列名歧义
+func ApplyCursor(query *gorm.DB, table string, cursor *Cursor, limit int) *gorm.DB {
+	return ApplyCursorBy(query, table+".created_at", table+".id", cursor, limit)
+}
+
+// ApplyCursorBy 同 ApplyCursor，按 sortExpr（时间列或表达式）排序，游标中的时间为该表达式的值
+func ApplyCursorBy(query *gorm.DB, sortExpr, id string, cursor *Cursor, limit int) *gorm.DB {
+	createdAt := sortExpr
+	if cursor != nil {
+		query = query.Where("("+createdAt+" < ? OR ("+createdAt+" = ? AND "+id+" < ?))",
+			cursor.CreatedAt, cursor.CreatedAt, cursor.ID)
+	}
+	return query.Order(createdAt + " DESC").Order(id + " DESC").Limit(limit + 1)
+}
+
+// NewCursorPage 截掉 ApplyCursor 多取的一条，用本页最后一条记录生成下一页游标
+func NewCursorPage[T any](items []T, limit int, key func(T) (time.Time, string)) CursorPage {
+	page := CursorPage{Items: items}
+	if len(items) > limit {
+		items = items[:limit]
+		createdAt, id := key(items[len(items)-1])
+		page.Items, page.NextCursor, page.HasMore = items, EncodeCursor(createdAt, id), true
+	}
+	return page
+}
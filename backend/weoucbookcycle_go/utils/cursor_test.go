@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// 游标可往返编码，下一页游标取本页最后一条
+func TestCursorPage(t *testing.T) {
+	createdAt := time.Date(2024, 3, 1, 8, 30, 0, 123000000, time.UTC)
+	cursor, err := DecodeCursor(EncodeCursor(createdAt, "book-2"))
+	if err != nil || !cursor.CreatedAt.Equal(createdAt) || cursor.ID != "book-2" {
+		t.Fatalf("unexpected cursor %+v, err %v", cursor, err)
+	}
+	if cursor, err := DecodeCursor(""); cursor != nil || err != nil {
+		t.Fatalf("empty cursor should be first page, got %+v %v", cursor, err)
+	}
+	if _, err := DecodeCursor("not a cursor"); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("expected ErrInvalidCursor, got %v", err)
+	}
+
+	type item struct {
+		id string
+		at time.Time
+	}
+	key := func(i item) (time.Time, string) { return i.at, i.id }
+	items := []item{{"c", createdAt}, {"b", createdAt}, {"a", createdAt.Add(-time.Second)}}
+
+	page := NewCursorPage(items, 2, key)
+	if !page.HasMore || len(page.Items.([]item)) != 2 {
+		t.Fatalf("expected 2 items and more pages, got %+v", page)
+	}
+	next, _ := DecodeCursor(page.NextCursor)
+	if next.ID != "b" {
+		t.Fatalf("expected next cursor after b, got %s", next.ID)
+	}
+
+	if last := NewCursorPage(items, 3, key); last.HasMore || last.NextCursor != "" {
+		t.Fatalf("expected last page, got %+v", last)
+	}
+}
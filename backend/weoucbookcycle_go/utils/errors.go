@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+)
+
+// AppError 带HTTP状态码和业务码的错误
+// 控制器通过 c.Error(err) 上报，由 middleware.ErrorHandler 统一转换为 Response
+type AppError struct {
+	Status  int    // HTTP状态码
+	Code    int    // 业务状态码，默认为 Status*100（如 404 -> 40400）
+	Message string // 返回给客户端的消息
+	Err     error  // 原始错误，只记录日志，不返回给客户端
+	// Data 附加数据，在 Response.Data 中返回给客户端（如屏蔽状态），没有时为 nil
+	Data interface{}
+}
+
+// NewError 创建指定HTTP状态码的错误
+func NewError(status int, message string) *AppError {
+	return &AppError{Status: status, Code: status * 100, Message: message}
+}
+
+// WrapError 用指定状态码和消息包装底层错误
+func WrapError(status int, message string, err error) *AppError {
+	return &AppError{Status: status, Code: status * 100, Message: message, Err: err}
+}
+
+func (e *AppError) Error() string {
+	return e.Message
+}
+
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+// WithDefaultStatus 非 AppError 的错误包装为指定状态码，AppError 保持原状态码
+// 用于服务层把参数校验失败等普通错误直接返回的场景
+func WithDefaultStatus(err error, status int) error {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return err
+	}
+	return NewError(status, err.Error())
+}
+
+// ErrorStatus 返回错误对应的HTTP状态码，非 AppError 视为500
+func ErrorStatus(err error) int {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return appErr.Status
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// 包装后的 AppError 仍保留状态码，普通错误视为500或使用指定的默认状态码
+func TestErrorStatus(t *testing.T) {
+	errNotFound := NewError(http.StatusNotFound, "book not found")
+	if errNotFound.Code != CodeNotFound {
+		t.Fatalf("expected code %d, got %d", CodeNotFound, errNotFound.Code)
+	}
+
+	wrapped := fmt.Errorf("%w: id 42", errNotFound)
+	if got := ErrorStatus(wrapped); got != http.StatusNotFound {
+		t.Fatalf("expected 404 for wrapped AppError, got %d", got)
+	}
+	if !errors.Is(wrapped, errNotFound) {
+		t.Fatal("expected wrapped error to match sentinel")
+	}
+
+	plain := errors.New("boom")
+	if got := ErrorStatus(plain); got != http.StatusInternalServerError {
+		t.Fatalf("expected 500 for plain error, got %d", got)
+	}
+	if got := ErrorStatus(WithDefaultStatus(plain, http.StatusBadRequest)); got != http.StatusBadRequest {
+		t.Fatalf("expected default status 400, got %d", got)
+	}
+	if got := WithDefaultStatus(wrapped, http.StatusBadRequest); got != wrapped {
+		t.Fatalf("expected AppError to be returned unchanged, got %v", got)
+	}
+
+	cause := errors.New("connection refused")
+	if err := WrapError(http.StatusServiceUnavailable, "search is unavailable", cause); !errors.Is(err, cause) || err.Error() != "search is unavailable" {
+		t.Fatalf("unexpected wrapped error: %v", err)
+	}
+}
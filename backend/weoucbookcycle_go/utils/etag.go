@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ETag 根据JSON内容生成强 ETag，内容不变时ETag不变（各实例一致）
+func ETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches If-None-Match 中是否包含 etag，按 RFC 9110 使用弱比较（忽略 W/ 前缀）
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ServeJSONWithETag 以 data（已序列化的JSON）的哈希作为 ETag 响应，
+// 请求的 If-None-Match 与之匹配时返回304不带响应体
+// 内容可能随时变化，客户端和CDN可以缓存但每次使用前需要重新验证
+// 响应内容可能取决于请求用户（联系方式、默认校区等），因此带 Vary: Authorization；
+// 已登录用户的响应为 private，ETag 同时包含用户ID，不会被共享缓存或其他用户的 If-None-Match 复用
+func ServeJSONWithETag(c *gin.Context, data []byte) {
+	c.Writer.Header().Add("Vary", "Authorization")
+	etag, cacheControl := ETag(data), "public, no-cache"
+	if viewerID := c.GetString("user_id"); viewerID != "" {
+		etag, cacheControl = ETag(append([]byte(viewerID+"\n"), data...)), "private, no-cache"
+	}
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", cacheControl)
+	if match := c.GetHeader("If-None-Match"); match != "" && etagMatches(match, etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+}
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func serveWithETag(ifNoneMatch string, data []byte) *httptest.ResponseRecorder {
+	return serveWithETagAs("", ifNoneMatch, data)
+}
+
+// serveWithETagAs 以 userID 登录（为空时未登录）请求
+func serveWithETagAs(userID, ifNoneMatch string, data []byte) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	r := gin.New()
+	r.GET("/", func(c *gin.Context) {
+		if userID != "" {
+			c.Set("user_id", userID)
+		}
+		ServeJSONWithETag(c, data)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestServeJSONWithETag(t *testing.T) {
+	data := []byte(`{"id":"b1","title":"Go"}`)
+	etag := ETag(data)
+
+	w := serveWithETag("", data)
+	if w.Code != http.StatusOK || w.Body.String() != string(data) || w.Header().Get("ETag") != etag {
+		t.Fatalf("first request: status %d, body %q, etag %q", w.Code, w.Body.String(), w.Header().Get("ETag"))
+	}
+
+	for _, header := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		w := serveWithETag(header, data)
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("If-None-Match %s: status %d, body %q, want 304 without body", header, w.Code, w.Body.String())
+		}
+		if w.Header().Get("ETag") != etag {
+			t.Errorf("If-None-Match %s: 304 must repeat the ETag", header)
+		}
+	}
+
+	// 内容变化后旧的 ETag 不再匹配
+	if w := serveWithETag(etag, []byte(`{"id":"b1","title":"Go 2"}`)); w.Code != http.StatusOK {
+		t.Errorf("changed content: status %d, want 200", w.Code)
+	}
+}
+
+// 已登录用户的响应为 private，ETag 包含用户ID，不会与未登录或其他用户的 ETag 匹配
+func TestServeJSONWithETagPerViewer(t *testing.T) {
+	data := []byte(`{"id":"b1","title":"Go"}`)
+
+	anonymous := serveWithETagAs("", "", data)
+	if got := anonymous.Header().Get("Cache-Control"); got != "public, no-cache" {
+		t.Errorf("anonymous Cache-Control = %q", got)
+	}
+	alice := serveWithETagAs("alice", "", data)
+	if got := alice.Header().Get("Cache-Control"); got != "private, no-cache" {
+		t.Errorf("signed-in Cache-Control = %q", got)
+	}
+	for name, w := range map[string]*httptest.ResponseRecorder{"anonymous": anonymous, "signed-in": alice} {
+		if got := w.Header().Get("Vary"); got != "Authorization" {
+			t.Errorf("%s Vary = %q, want Authorization", name, got)
+		}
+	}
+
+	etag := alice.Header().Get("ETag")
+	if etag == anonymous.Header().Get("ETag") {
+		t.Fatal("signed-in ETag must differ from the anonymous one")
+	}
+	if w := serveWithETagAs("alice", etag, data); w.Code != http.StatusNotModified {
+		t.Errorf("same viewer: status %d, want 304", w.Code)
+	}
+	for _, userID := range []string{"", "bob"} {
+		if w := serveWithETagAs(userID, etag, data); w.Code != http.StatusOK {
+			t.Errorf("viewer %q reusing another viewer's ETag: status %d, want 200", userID, w.Code)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package utils
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 支持的语言
+const (
+	LangZhCN = "zh-CN"
+	LangEn   = "en"
+)
+
+// localeFS 消息目录，每种语言一个 JSON 文件（key -> 文案）
+// key 分两类：点分隔的键（如 email.welcome.subject）在每种语言中都应存在；
+// 英文原文作为 key 的错误消息只需要在非英文目录中提供译文
+//
+//go:embed locales/*.json
+var localeFS embed.FS
+
+var catalogs = loadCatalogs()
+
+type langContextKey struct{}
+
+// loadCatalogs 加载内嵌的消息目录，文件名（去掉扩展名）为语言标签
+func loadCatalogs() map[string]map[string]string {
+	entries, err := localeFS.ReadDir("locales")
+	if err != nil {
+		panic(fmt.Sprintf("i18n: failed to read locales: %v", err))
+	}
+
+	result := make(map[string]map[string]string, len(entries))
+	for _, entry := range entries {
+		data, err := localeFS.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			panic(fmt.Sprintf("i18n: failed to read %s: %v", entry.Name(), err))
+		}
+		catalog := make(map[string]string)
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			panic(fmt.Sprintf("i18n: invalid catalog %s: %v", entry.Name(), err))
+		}
+		result[strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))] = catalog
+	}
+	return result
+}
+
+// defaultLang 由 SetDefaultLang 在启动时设置
+var defaultLang = LangZhCN
+
+// SetDefaultLang 设置默认语言（DEFAULT_LANGUAGE），为空或不支持时使用 zh-CN
+func SetDefaultLang(lang string) {
+	if lang = NormalizeLang(lang); lang == "" {
+		lang = LangZhCN
+	}
+	defaultLang = lang
+}
+
+// DefaultLang 未指定或不支持的语言使用的默认语言
+func DefaultLang() string {
+	return defaultLang
+}
+
+// NormalizeLang 把语言标签映射到支持的语言（zh、zh-Hans、zh-CN -> zh-CN；en-US -> en），不支持时返回空串
+func NormalizeLang(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" {
+		return ""
+	}
+	base, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	switch base {
+	case "zh":
+		return LangZhCN
+	case "en":
+		return LangEn
+	}
+	return ""
+}
+
+// ParseAcceptLanguage 按 q 值从 Accept-Language 中选出支持的语言，没有匹配时返回默认语言
+func ParseAcceptLanguage(header string) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if lang := NormalizeLang(tag); lang != "" && q > 0 {
+			candidates = append(candidates, candidate{lang: lang, q: q})
+		}
+	}
+	if len(candidates) == 0 {
+		return DefaultLang()
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].lang
+}
+
+// RequestLang 当前请求的语言，由 middleware.I18n 根据 Accept-Language 解析
+func RequestLang(c *gin.Context) string {
+	if lang := c.GetString("lang"); lang != "" {
+		return lang
+	}
+	return ParseAcceptLanguage(c.GetHeader("Accept-Language"))
+}
+
+// WithLang 把语言写入 context，供服务层生成邮件等文案时使用
+func WithLang(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, langContextKey{}, lang)
+}
+
+// LangFromContext 读取 context 中的语言，没有时返回默认语言
+func LangFromContext(ctx context.Context) string {
+	if ctx != nil {
+		if lang, ok := ctx.Value(langContextKey{}).(string); ok && lang != "" {
+			return lang
+		}
+	}
+	return DefaultLang()
+}
+
+// lookup 按 请求语言 -> 默认语言 -> 中文 的顺序查找文案
+func lookup(lang, key string) (string, bool) {
+	for _, l := range []string{NormalizeLang(lang), DefaultLang(), LangZhCN} {
+		if msg, ok := catalogs[l][key]; ok {
+			return msg, true
+		}
+	}
+	return "", false
+}
+
+// T 返回 key 在指定语言下的文案，带参数时按 fmt 格式化；所有目录都没有时返回 key 本身
+func T(lang, key string, args ...interface{}) string {
+	msg, ok := lookup(lang, key)
+	if !ok {
+		msg = key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// TranslateMessage 翻译以英文原文为 key 的消息（如错误信息），英文或没有译文时原样返回
+func TranslateMessage(lang, message string) string {
+	lang = NormalizeLang(lang)
+	if lang == "" || lang == LangEn {
+		return message
+	}
+	if msg, ok := catalogs[lang][message]; ok {
+		return msg
+	}
+	return message
+}
+
+// CodeMessageIn 返回业务状态码在指定语言下的消息
+func CodeMessageIn(lang string, code int) string {
+	if msg, ok := lookup(lang, fmt.Sprintf("code.%d", code)); ok {
+		return msg
+	}
+	return GetCodeMessage(code)
+}
@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"weoucbookcycle_go/config"
+)
+
+// Accept-Language 按 q 值选择支持的语言，不支持时回退到默认语言
+func TestParseAcceptLanguage(t *testing.T) {
+	withConfig(t, func(cfg *config.Config) { cfg.DefaultLanguage = LangZhCN })
+
+	cases := map[string]string{
+		"":                            LangZhCN,
+		"en-US,en;q=0.9":              LangEn,
+		"zh-TW":                       LangZhCN,
+		"fr-FR, en;q=0.5, zh;q=0.8":   LangZhCN,
+		"fr-FR, en;q=0.5":             LangEn,
+		"de":                          LangZhCN,
+		"en;q=0, zh-Hans-CN;q=0.3":    LangZhCN,
+		"EN_gb;q=0.7, ja;q=0.9, *":    LangEn,
+		"zh-CN;q=abc, en-US;q=0.4000": LangEn,
+	}
+	for header, want := range cases {
+		if got := ParseAcceptLanguage(header); got != want {
+			t.Errorf("ParseAcceptLanguage(%q) = %s, want %s", header, got, want)
+		}
+	}
+
+	withConfig(t, func(cfg *config.Config) { cfg.DefaultLanguage = LangEn })
+	if got := ParseAcceptLanguage("ja"); got != LangEn {
+		t.Fatalf("expected configured default language, got %s", got)
+	}
+}
+
+// 点分隔的键在每种语言中都要有文案，占位符数量一致
+func TestCatalogsComplete(t *testing.T) {
+	for lang, catalog := range catalogs {
+		for key, msg := range catalogs[LangZhCN] {
+			if !strings.Contains(key, ".") || strings.Contains(key, " ") {
+				continue
+			}
+			translated, ok := catalog[key]
+			if !ok {
+				t.Errorf("%s: missing key %s", lang, key)
+				continue
+			}
+			if strings.Count(translated, "%") != strings.Count(msg, "%") {
+				t.Errorf("%s: placeholders of %s do not match zh-CN", lang, key)
+			}
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	withConfig(t, func(cfg *config.Config) { cfg.DefaultLanguage = LangZhCN })
+
+	if got := T(LangEn, "notification.book_published.content", "Go"); got != `"Go" is published and buyers can now find it` {
+		t.Fatalf("unexpected english text: %s", got)
+	}
+	if got := T("fr", "notification.book_published.title"); got != "书籍已发布" {
+		t.Fatalf("expected fallback to default language, got %s", got)
+	}
+	if got := T(LangEn, "no.such.key"); got != "no.such.key" {
+		t.Fatalf("expected key for missing message, got %s", got)
+	}
+
+	if got := TranslateMessage(LangZhCN, "Book not found"); got != "书籍不存在" {
+		t.Fatalf("unexpected translation: %s", got)
+	}
+	if got := TranslateMessage(LangEn, "Book not found"); got != "Book not found" {
+		t.Fatalf("english message should be unchanged, got %s", got)
+	}
+	if got := TranslateMessage(LangZhCN, "invalid chunk: index 9 out of range"); got != "invalid chunk: index 9 out of range" {
+		t.Fatalf("untranslated message should be unchanged, got %s", got)
+	}
+	if got := CodeMessageIn(LangEn, CodeValidationError); got != "Validation failed" {
+		t.Fatalf("unexpected code message: %s", got)
+	}
+}
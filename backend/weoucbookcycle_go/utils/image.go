@@ -0,0 +1,199 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"mime"
+	"path"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	// 同时注册 webp 解码器，imaging 默认只支持 jpeg/png/gif/bmp/tiff
+	"github.com/gen2brain/webp"
+)
+
+const (
+	// thumbPrefix 缩略图文件名前缀，与原图放在同一目录
+	thumbPrefix = "thumb_"
+	// QuarantinePrefix 审核不通过的图片隔离目录，不对外提供访问
+	QuarantinePrefix = "quarantine/"
+	// ChunkPrefix 分片上传的临时分片目录，不对外提供访问
+	ChunkPrefix = "chunks/"
+)
+
+// ImageInfo 图片基础信息
+type ImageInfo struct {
+	Width  int
+	Height int
+	Format string
+}
+
+// DecodeImage 解码图片并按 EXIF 方向信息校正，返回图片对象和格式
+// 返回的尺寸为校正后的尺寸
+func DecodeImage(data []byte) (image.Image, *ImageInfo, error) {
+	_, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	img, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	bounds := img.Bounds()
+	return img, &ImageInfo{Width: bounds.Dx(), Height: bounds.Dy(), Format: format}, nil
+}
+
+// EncodeImage 按原格式重新编码图片，重新编码后不再包含 EXIF 等元数据
+// 只支持 jpeg/png/webp，返回编码数据和 Content-Type
+func EncodeImage(img image.Image, format string, webpQuality int) ([]byte, string, error) {
+	var buf bytes.Buffer
+	var contentType string
+	var err error
+
+	switch format {
+	case "jpeg":
+		contentType = "image/jpeg"
+		err = imaging.Encode(&buf, img, imaging.JPEG, imaging.JPEGQuality(90))
+	case "png":
+		contentType = "image/png"
+		err = imaging.Encode(&buf, img, imaging.PNG)
+	case "webp":
+		contentType = "image/webp"
+		err = webp.Encode(&buf, img, webp.Options{Quality: webpQuality})
+	default:
+		return nil, "", fmt.Errorf("unsupported image format: %s", format)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode image: %w", err)
+	}
+	return buf.Bytes(), contentType, nil
+}
+
+// IsSanitizable 是否需要重新编码以去除元数据（gif 可能是动图且不含 EXIF，保持原样）
+func IsSanitizable(format string) bool {
+	return format == "jpeg" || format == "png" || format == "webp"
+}
+
+// EncodeWebP 将图片编码为 WebP（有损，保留透明通道）
+func EncodeWebP(img image.Image, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := webp.Encode(&buf, img, webp.Options{Quality: quality}); err != nil {
+		return nil, fmt.Errorf("failed to encode webp: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// IsWebPConvertible 是否为可转换为 WebP 的格式（gif 可能是动图，不转换）
+func IsWebPConvertible(format string) bool {
+	return format == "jpeg" || format == "png"
+}
+
+// GenerateThumbnail 生成不超过 width x height 的等比缩略图
+// webpQuality 大于0时输出 WebP；否则 png/gif 输出为 png 以保留透明度，其余格式输出为 jpeg
+func GenerateThumbnail(img image.Image, format string, width, height, webpQuality int) ([]byte, string, error) {
+	thumb := imaging.Fit(img, width, height, imaging.Lanczos)
+
+	if webpQuality > 0 {
+		data, err := EncodeWebP(thumb, webpQuality)
+		if err != nil {
+			return nil, "", err
+		}
+		return data, "image/webp", nil
+	}
+
+	var buf bytes.Buffer
+	outFormat, contentType := imaging.JPEG, "image/jpeg"
+	if format == "png" || format == "gif" {
+		outFormat, contentType = imaging.PNG, "image/png"
+	}
+
+	if err := imaging.Encode(&buf, thumb, outFormat, imaging.JPEGQuality(85)); err != nil {
+		return nil, "", fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return buf.Bytes(), contentType, nil
+}
+
+// StoreThumbnail 为已存储的原图生成缩略图并写入同一存储，返回缩略图URL和原图信息
+// webpQuality 大于0时缩略图编码为 WebP
+func StoreThumbnail(ctx context.Context, storage Storage, key string, data []byte, width, height, webpQuality int) (string, *ImageInfo, error) {
+	img, info, err := DecodeImage(data)
+	if err != nil {
+		return "", nil, err
+	}
+
+	url, err := PutThumbnail(ctx, storage, key, img, info.Format, width, height, webpQuality)
+	return url, info, err
+}
+
+// PutThumbnail 用已解码的原图生成缩略图并写入存储，返回缩略图URL
+func PutThumbnail(ctx context.Context, storage Storage, key string, img image.Image, format string, width, height, webpQuality int) (string, error) {
+	thumb, contentType, err := GenerateThumbnail(img, format, width, height, webpQuality)
+	if err != nil {
+		return "", err
+	}
+
+	return storage.Put(ctx, ThumbnailKey(key, contentType), bytes.NewReader(thumb), int64(len(thumb)), contentType)
+}
+
+// ThumbnailKeys 原图可能对应的缩略图key（jpeg/png/webp三种编码）
+func ThumbnailKeys(key string) []string {
+	return []string{ThumbnailKey(key, "image/jpeg"), ThumbnailKey(key, "image/png"), ThumbnailKey(key, "image/webp")}
+}
+
+// ThumbnailKey 根据原图key生成缩略图key，扩展名与缩略图编码格式一致
+func ThumbnailKey(key, contentType string) string {
+	dir, name := path.Split(key)
+	ext := ".jpg"
+	switch contentType {
+	case "image/png":
+		ext = ".png"
+	case "image/webp":
+		ext = ".webp"
+	}
+	return dir + thumbPrefix + strings.TrimSuffix(name, path.Ext(name)) + ext
+}
+
+// SourceKeys 转换为 WebP 的图片保留的原图可能对应的key
+func SourceKeys(key string) []string {
+	if path.Ext(key) != ".webp" {
+		return nil
+	}
+	base := strings.TrimSuffix(key, ".webp")
+	return []string{base + ".jpg", base + ".jpeg", base + ".png"}
+}
+
+// QuarantineKey 图片隔离后的key
+func QuarantineKey(key string) string {
+	return QuarantinePrefix + strings.TrimPrefix(key, "/")
+}
+
+// IsPrivateKey 是否为不能通过公开URL访问的key（隔离图片、上传分片、私有文件）
+func IsPrivateKey(key string) bool {
+	// 先规范化路径，避免通过 a/../quarantine/ 绕过
+	key = strings.TrimLeft(path.Clean("/"+key), "/")
+	return strings.HasPrefix(key, QuarantinePrefix) || strings.HasPrefix(key, ChunkPrefix) || strings.HasPrefix(key, PrivatePrefix)
+}
+
+// RegisterImageMimeTypes 注册图片扩展名对应的 Content-Type
+// 部分精简系统的 mime 表中没有 webp，静态文件会被当作 application/octet-stream 返回
+func RegisterImageMimeTypes() {
+	mime.AddExtensionType(".webp", "image/webp")
+}
+
+// IsThumbnailKey 判断key是否为缩略图
+func IsThumbnailKey(key string) bool {
+	return strings.HasPrefix(path.Base(key), thumbPrefix)
+}
+
+// KeyFromURL 从公开URL中解析出存储key，不属于当前存储的URL返回false
+func KeyFromURL(storage Storage, url string) (string, bool) {
+	base := strings.TrimSuffix(storage.URL(""), "/") + "/"
+	if !strings.HasPrefix(url, base) {
+		return "", false
+	}
+	return strings.TrimPrefix(url, base), true
+}
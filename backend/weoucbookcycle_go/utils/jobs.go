@@ -0,0 +1,234 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+	"weoucbookcycle_go/config"
+
+	"github.com/hibiken/asynq"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// 持久化后台任务：任务保存在Redis（asynq），进程重启不会丢失，失败后按退避间隔重试
+// 服务在创建时用 HandleJob 注册任务类型，业务代码用 EnqueueJob 提交；
+// PROCESS_MODE 为 all 或 worker 的进程通过 StartJobWorker 处理任务
+
+// 任务队列，按权重分配worker：critical 6 : default 3 : low 1
+const (
+	JobQueueCritical = "critical" // 用户在等待结果的任务（邮件、聊天消息）
+	JobQueueDefault  = "default"
+	JobQueueLow      = "low" // 统计类任务，积压时最后处理
+)
+
+var jobQueueWeights = map[string]int{
+	JobQueueCritical: 6,
+	JobQueueDefault:  3,
+	JobQueueLow:      1,
+}
+
+// jobHandler 处理已解码载荷之前的原始任务
+type jobHandler struct {
+	handle func(ctx context.Context, payload []byte) error
+	opts   []asynq.Option
+}
+
+var (
+	jobHandlers   = make(map[string]jobHandler)
+	jobHandlersMu sync.RWMutex
+
+	jobClient    *asynq.Client
+	jobInspector *asynq.Inspector
+)
+
+// HandleJob 注册任务类型的处理函数和默认选项（队列、重试次数、超时等）
+// 载荷以JSON传递；无法解码的任务不会重试。同一类型重复注册时后注册的生效
+func HandleJob[T any](taskType string, handler func(ctx context.Context, payload T) error, opts ...asynq.Option) {
+	jobHandlersMu.Lock()
+	defer jobHandlersMu.Unlock()
+	jobHandlers[taskType] = jobHandler{
+		handle: func(ctx context.Context, data []byte) error {
+			var payload T
+			if err := json.Unmarshal(data, &payload); err != nil {
+				return fmt.Errorf("decode %s payload: %v: %w", taskType, err, asynq.SkipRetry)
+			}
+			return handler(ctx, payload)
+		},
+		opts: opts,
+	}
+}
+
+// jobRedisOpt 任务队列使用独立的Redis连接，轮询命令不计入应用的Redis指标和追踪
+func jobRedisOpt(cfg *config.RedisConfig) asynq.RedisClientOpt {
+	return asynq.RedisClientOpt{Addr: cfg.Addr, Password: cfg.Password, DB: cfg.DB}
+}
+
+// InitJobs 连接任务队列
+// 未调用时（单元测试、只跑脚本）EnqueueJob 在当前进程中直接异步执行任务，不持久化也不重试
+func InitJobs(cfg *config.RedisConfig) {
+	jobClient = asynq.NewClient(jobRedisOpt(cfg))
+	jobInspector = asynq.NewInspector(jobRedisOpt(cfg))
+}
+
+// CloseJobs 关闭任务队列连接
+func CloseJobs() error {
+	if jobClient == nil {
+		return nil
+	}
+	return errors.Join(jobClient.Close(), jobInspector.Close())
+}
+
+// EnqueueJob 提交任务，opts 覆盖注册时的默认选项
+// ctx 中的trace随任务传递，worker处理时在同一条trace下创建span
+func EnqueueJob(ctx context.Context, taskType string, payload interface{}, opts ...asynq.Option) error {
+	jobHandlersMu.RLock()
+	handler, ok := jobHandlers[taskType]
+	jobHandlersMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("job type %s is not registered", taskType)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encode %s payload: %w", taskType, err)
+	}
+
+	if jobClient == nil {
+		// 请求结束后任务仍在执行，只保留trace不继承取消
+		go runJobInline(context.WithoutCancel(ctx), taskType, handler, data)
+		return nil
+	}
+
+	task := asynq.NewTaskWithHeaders(taskType, data, InjectTrace(ctx), append(handler.opts, opts...)...)
+	if _, err := jobClient.EnqueueContext(ctx, task); err != nil {
+		JobsTotal.WithLabelValues(taskType, "enqueue_failed").Inc()
+		return fmt.Errorf("enqueue %s: %w", taskType, err)
+	}
+	JobsTotal.WithLabelValues(taskType, "enqueued").Inc()
+	return nil
+}
+
+// runJobInline 没有任务队列时在进程内执行一次
+func runJobInline(ctx context.Context, taskType string, handler jobHandler, data []byte) {
+	if err := observeJob(ctx, taskType, handler, data); err != nil {
+		log.Printf("job %s failed: %v", taskType, err)
+	}
+}
+
+// observeJob 执行任务并记录耗时和结果，重试用尽的失败上报到 Sentry
+func observeJob(ctx context.Context, taskType string, handler jobHandler, data []byte) error {
+	start := time.Now()
+	err := handler.handle(ctx, data)
+	JobDuration.WithLabelValues(taskType).Observe(time.Since(start).Seconds())
+
+	result := "succeeded"
+	if err != nil {
+		result = "retry"
+		if IsFinalJobAttempt(ctx) || errors.Is(err, asynq.SkipRetry) {
+			// 只上报最终失败，会重试的失败只计数
+			result = "failed"
+			CaptureError(ctx, err, map[string]string{"job": taskType, "attempt": strconv.Itoa(JobAttempt(ctx))})
+		}
+	}
+	JobsTotal.WithLabelValues(taskType, result).Inc()
+	return err
+}
+
+// processJob asynq 的任务入口：恢复trace后分发给注册的处理函数
+func processJob(ctx context.Context, task *asynq.Task) error {
+	jobHandlersMu.RLock()
+	handler, ok := jobHandlers[task.Type()]
+	jobHandlersMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("no handler for job type %s: %w", task.Type(), asynq.SkipRetry)
+	}
+
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(task.Headers()))
+	return observeJob(ctx, task.Type(), handler, task.Payload())
+}
+
+// StartJobWorker 启动任务worker，返回的函数停止接收新任务并等待处理中的任务完成
+// 超过 SHUTDOWN_TIMEOUT_SECONDS 仍未完成的任务会回到队列，由下一个worker重新执行
+func StartJobWorker(cfg *config.Config) (func(), error) {
+	srv := asynq.NewServer(jobRedisOpt(&cfg.Redis), asynq.Config{
+		Concurrency:     cfg.Jobs.Concurrency,
+		Queues:          jobQueueWeights,
+		ShutdownTimeout: time.Duration(cfg.Server.ShutdownTimeout) * time.Second,
+		LogLevel:        asynq.WarnLevel,
+	})
+	if err := srv.Start(asynq.HandlerFunc(processJob)); err != nil {
+		return nil, fmt.Errorf("start job worker: %w", err)
+	}
+	return srv.Shutdown, nil
+}
+
+// JobAttempt 当前是第几次执行（从1开始），进程内直接执行时为1
+func JobAttempt(ctx context.Context) int {
+	retried, _ := asynq.GetRetryCount(ctx)
+	return retried + 1
+}
+
+// IsFinalJobAttempt 本次失败后是否不再重试，处理函数据此决定是否写入死信等最终失败处理
+func IsFinalJobAttempt(ctx context.Context) bool {
+	retried, ok := asynq.GetRetryCount(ctx)
+	if !ok {
+		return true
+	}
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
+	return retried >= maxRetry
+}
+
+// JobQueueStat 持久化任务队列的积压情况（所有实例共享）
+type JobQueueStat struct {
+	Name      string `json:"name"`
+	Pending   int    `json:"pending"`
+	Active    int    `json:"active"`
+	Scheduled int    `json:"scheduled"`
+	Retry     int    `json:"retry"`    // 等待重试
+	Archived  int    `json:"archived"` // 超过重试次数后放弃的任务
+	// LatencySeconds 最早一个待处理任务的等待时长，持续增长说明worker处理不过来或没有运行
+	LatencySeconds float64 `json:"latency_seconds"`
+	ProcessedToday int     `json:"processed_today"`
+	FailedToday    int     `json:"failed_today"`
+	Paused         bool    `json:"paused"`
+}
+
+// JobQueueStats 读取所有任务队列的积压，未连接任务队列时返回空
+func JobQueueStats() ([]JobQueueStat, error) {
+	if jobInspector == nil {
+		return nil, nil
+	}
+	queues, err := jobInspector.Queues()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(queues)
+
+	stats := make([]JobQueueStat, 0, len(queues))
+	for _, queue := range queues {
+		info, err := jobInspector.GetQueueInfo(queue)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, JobQueueStat{
+			Name:           info.Queue,
+			Pending:        info.Pending,
+			Active:         info.Active,
+			Scheduled:      info.Scheduled,
+			Retry:          info.Retry,
+			Archived:       info.Archived,
+			LatencySeconds: info.Latency.Seconds(),
+			ProcessedToday: info.Processed,
+			FailedToday:    info.Failed,
+			Paused:         info.Paused,
+		})
+	}
+	return stats, nil
+}
@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type testJobPayload struct {
+	BookID string
+	Count  int
+}
+
+// 未连接任务队列时任务在进程内执行一次，载荷经过JSON编解码
+func TestEnqueueJobRunsInlineWithoutQueue(t *testing.T) {
+	done := make(chan testJobPayload, 1)
+	HandleJob("test:inline", func(ctx context.Context, p testJobPayload) error {
+		if !IsFinalJobAttempt(ctx) || JobAttempt(ctx) != 1 {
+			t.Errorf("inline job should be a single final attempt")
+		}
+		done <- p
+		return nil
+	})
+
+	if err := EnqueueJob(context.Background(), "test:inline", &testJobPayload{BookID: "b1", Count: 2}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-done:
+		if got.BookID != "b1" || got.Count != 2 {
+			t.Fatalf("unexpected payload: %+v", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("job did not run")
+	}
+
+	if err := EnqueueJob(context.Background(), "test:unknown", nil); err == nil {
+		t.Fatal("expected error for unregistered job type")
+	}
+}
@@ -0,0 +1,229 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// metricsNamespace 所有指标的前缀
+const metricsNamespace = "bookcycle"
+
+var (
+	// HTTPRequestsTotal 按路由模板统计的请求数
+	HTTPRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestDuration 请求耗时
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	// HTTPRequestsInFlight 处理中的请求数
+	HTTPRequestsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "http_requests_in_flight",
+		Help:      "HTTP requests currently being served.",
+	})
+
+	dbQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "GORM query latency by operation and table.",
+		Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table"})
+
+	dbQueryErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "db_query_errors_total",
+		Help:      "GORM queries that returned an error other than record not found.",
+	}, []string{"operation", "table"})
+
+	redisCommandDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "redis_command_duration_seconds",
+		Help:      "Redis command latency by command name (pipelines are reported as \"pipeline\").",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5},
+	}, []string{"command"})
+
+	redisCommandErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "redis_command_errors_total",
+		Help:      "Redis commands that failed (redis.Nil is not counted).",
+	}, []string{"command"})
+
+	// EmailsTotal 邮件发送结果：sent, retry, deferred（SMTP熔断，延后发送）, failed, dropped（无法入队）
+	EmailsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "emails_total",
+		Help:      "Email send attempts by email type and result.",
+	}, []string{"type", "result"})
+
+	// JobsTotal 后台任务：enqueued, enqueue_failed, succeeded, retry, failed（不再重试）
+	JobsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "jobs_total",
+		Help:      "Background jobs by task type and result.",
+	}, []string{"type", "result"})
+
+	// CronRunsTotal 定时任务执行结果：success, failed（关闭或未抢到锁的不计）
+	CronRunsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "cron_runs_total",
+		Help:      "Scheduled job runs by job name and result.",
+	}, []string{"job", "result"})
+
+	// JobDuration 后台任务处理耗时
+	JobDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "job_duration_seconds",
+		Help:      "Background job processing time by task type.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"type"})
+
+	// RateLimitedTotal 被限流拒绝的请求，dimension 为 user 或 ip
+	RateLimitedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "rate_limited_total",
+		Help:      "Requests rejected by rate limiting by policy and dimension.",
+	}, []string{"policy", "dimension"})
+
+	// CircuitBreakerState 熔断器状态：0 closed, 1 half-open, 2 open
+	CircuitBreakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "circuit_breaker_state",
+		Help:      "Circuit breaker state by dependency (0 closed, 1 half-open, 2 open).",
+	}, []string{"name"})
+)
+
+func init() {
+	prometheus.MustRegister(queueCollector{})
+}
+
+// ==================== 进程内队列 ====================
+
+var (
+	queueDepthDesc = prometheus.NewDesc(metricsNamespace+"_queue_depth",
+		"Tasks waiting in in-memory worker queues.", []string{"queue"}, nil)
+	queueCapacityDesc = prometheus.NewDesc(metricsNamespace+"_queue_capacity",
+		"Capacity of in-memory worker queues.", []string{"queue"}, nil)
+)
+
+// queueCollector 抓取时读取 RegisterQueue 注册的队列深度
+type queueCollector struct{}
+
+func (queueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- queueDepthDesc
+	ch <- queueCapacityDesc
+}
+
+func (queueCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, stat := range QueueStats() {
+		ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(stat.Depth), stat.Name)
+		ch <- prometheus.MustNewConstMetric(queueCapacityDesc, prometheus.GaugeValue, float64(stat.Capacity), stat.Name)
+	}
+}
+
+// ==================== GORM ====================
+
+// metricsStartKey 查询开始时间在 gorm.Statement 中的key
+const metricsStartKey = "metrics:start"
+
+// InstrumentGORM 为数据库查询注册耗时和错误统计回调
+func InstrumentGORM(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []struct {
+		operation string
+		before    func(string, func(*gorm.DB)) error
+		after     func(string, func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+
+	for _, h := range hooks {
+		operation := h.operation
+		if err := h.before("metrics:before_"+operation, func(tx *gorm.DB) {
+			tx.InstanceSet(metricsStartKey, time.Now())
+		}); err != nil {
+			return err
+		}
+		if err := h.after("metrics:after_"+operation, func(tx *gorm.DB) {
+			start, ok := tx.InstanceGet(metricsStartKey)
+			if !ok {
+				return
+			}
+			table := tx.Statement.Table
+			if table == "" {
+				table = "unknown"
+			}
+			dbQueryDuration.WithLabelValues(operation, table).Observe(time.Since(start.(time.Time)).Seconds())
+			if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+				dbQueryErrors.WithLabelValues(operation, table).Inc()
+			}
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ==================== Redis ====================
+
+// InstrumentRedis 为Redis命令添加耗时和错误统计
+func InstrumentRedis(client *redis.Client) {
+	if client != nil {
+		client.AddHook(redisMetricsHook{})
+	}
+}
+
+// redisMetricsHook go-redis 钩子
+type redisMetricsHook struct{}
+
+func (redisMetricsHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (redisMetricsHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		observeRedis(strings.ToLower(cmd.Name()), start, err)
+		return err
+	}
+}
+
+func (redisMetricsHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		observeRedis("pipeline", start, err)
+		return err
+	}
+}
+
+func observeRedis(command string, start time.Time, err error) {
+	redisCommandDuration.WithLabelValues(command).Observe(time.Since(start).Seconds())
+	if err != nil && !errors.Is(err, redis.Nil) {
+		redisCommandErrors.WithLabelValues(command).Inc()
+	}
+}
@@ -0,0 +1,315 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"weoucbookcycle_go/config"
+
+	"github.com/google/uuid"
+)
+
+// ModerationResult 图片审核结果
+type ModerationResult struct {
+	Flagged  bool    `json:"flagged"`
+	Label    string  `json:"label"`
+	Score    float64 `json:"score"`
+	Provider string  `json:"provider"`
+}
+
+// ImageModerator 图片内容审核接口
+// imageURL 为图片公开地址，data 为图片内容，不同服务按需使用其中之一
+type ImageModerator interface {
+	Moderate(ctx context.Context, imageURL string, data []byte) (*ModerationResult, error)
+	Name() string
+}
+
+var moderationHTTPClient = &http.Client{Timeout: 15 * time.Second}
+
+// NewImageModerator 按 IMAGE_MODERATION_PROVIDER 创建审核实现
+// 支持 aliyun（阿里云内容安全）、tencent（腾讯云数据万象）、local（本地NSFW模型服务），未配置时返回nil
+func NewImageModerator(cfg *config.ModerationConfig) ImageModerator {
+	switch strings.ToLower(cfg.ImageProvider) {
+	case "aliyun":
+		return &AliyunGreenModerator{
+			AccessKeyID:     cfg.AliyunAccessKeyID,
+			AccessKeySecret: cfg.AliyunAccessKeySecret,
+			Region:          cfg.AliyunGreenRegion,
+		}
+	case "tencent":
+		return &TencentCIModerator{
+			SecretID:  cfg.TencentSecretID,
+			SecretKey: cfg.TencentSecretKey,
+			Bucket:    cfg.TencentCIBucket,
+			Region:    cfg.TencentCIRegion,
+		}
+	case "local":
+		return &LocalNSFWModerator{
+			Endpoint:  cfg.ImageEndpoint,
+			Threshold: float64(cfg.ImageThreshold) / 100,
+		}
+	default:
+		return nil
+	}
+}
+
+// ==================== 阿里云内容安全 ====================
+
+// AliyunGreenModerator 阿里云内容安全（图片审核增强版 ImageModeration）
+type AliyunGreenModerator struct {
+	AccessKeyID     string
+	AccessKeySecret string
+	Region          string
+}
+
+// Name 审核服务名称
+func (m *AliyunGreenModerator) Name() string {
+	return "aliyun"
+}
+
+// Moderate 提交图片URL进行审核，风险等级为 high 时判定为违规
+func (m *AliyunGreenModerator) Moderate(ctx context.Context, imageURL string, data []byte) (*ModerationResult, error) {
+	serviceParams, _ := json.Marshal(map[string]string{"imageUrl": imageURL})
+	params := map[string]string{
+		"Action":            "ImageModeration",
+		"Version":           "2022-03-02",
+		"Format":            "JSON",
+		"AccessKeyId":       m.AccessKeyID,
+		"SignatureMethod":   "HMAC-SHA1",
+		"SignatureVersion":  "1.0",
+		"SignatureNonce":    uuid.NewString(),
+		"Timestamp":         time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+		"Service":           "baselineCheck",
+		"ServiceParameters": string(serviceParams),
+	}
+	params["Signature"] = aliyunSignature(http.MethodPost, params, m.AccessKeySecret)
+
+	form := url.Values{}
+	for k, v := range params {
+		form.Set(k, v)
+	}
+
+	endpoint := fmt.Sprintf("https://green-cip.%s.aliyuncs.com/", m.Region)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var resp struct {
+		Code int    `json:"Code"`
+		Msg  string `json:"Msg"`
+		Data struct {
+			RiskLevel string `json:"RiskLevel"`
+			Result    []struct {
+				Label      string  `json:"Label"`
+				Confidence float64 `json:"Confidence"`
+			} `json:"Result"`
+		} `json:"Data"`
+	}
+	if err := doModerationRequest(req, func(body []byte) error { return json.Unmarshal(body, &resp) }); err != nil {
+		return nil, err
+	}
+	if resp.Code != http.StatusOK {
+		return nil, fmt.Errorf("aliyun green error %d: %s", resp.Code, resp.Msg)
+	}
+
+	result := &ModerationResult{Provider: m.Name(), Flagged: resp.Data.RiskLevel == "high"}
+	for _, r := range resp.Data.Result {
+		if r.Confidence/100 > result.Score {
+			result.Label = r.Label
+			result.Score = r.Confidence / 100
+		}
+	}
+	return result, nil
+}
+
+// aliyunSignature 阿里云RPC风格接口签名
+func aliyunSignature(method string, params map[string]string, secret string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, aliyunEncode(k)+"="+aliyunEncode(params[k]))
+	}
+	stringToSign := method + "&" + aliyunEncode("/") + "&" + aliyunEncode(strings.Join(pairs, "&"))
+
+	mac := hmac.New(sha1.New, []byte(secret+"&"))
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// aliyunEncode 阿里云要求的URL编码（空格为%20，保留~）
+func aliyunEncode(s string) string {
+	encoded := url.QueryEscape(s)
+	encoded = strings.ReplaceAll(encoded, "+", "%20")
+	encoded = strings.ReplaceAll(encoded, "*", "%2A")
+	return strings.ReplaceAll(encoded, "%7E", "~")
+}
+
+// ==================== 腾讯云数据万象 ====================
+
+// TencentCIModerator 腾讯云数据万象图片审核（同步审核，按URL）
+type TencentCIModerator struct {
+	SecretID  string
+	SecretKey string
+	Bucket    string // 格式为 BucketName-APPID
+	Region    string
+}
+
+// Name 审核服务名称
+func (m *TencentCIModerator) Name() string {
+	return "tencent"
+}
+
+// Moderate 提交图片URL进行审核，Result 为1（确认违规）时判定为违规
+func (m *TencentCIModerator) Moderate(ctx context.Context, imageURL string, data []byte) (*ModerationResult, error) {
+	host := fmt.Sprintf("%s.cos.%s.myqcloud.com", m.Bucket, m.Region)
+	query := map[string]string{
+		"ci-process": "sensitive-content-recognition",
+		"detect-url": imageURL,
+	}
+
+	values := url.Values{}
+	for k, v := range query {
+		values.Set(k, v)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://"+host+"/?"+values.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", cosSignature(m.SecretID, m.SecretKey, http.MethodGet, "/", query, host, time.Hour))
+
+	var resp struct {
+		Result int    `xml:"Result"`
+		Label  string `xml:"Label"`
+		Score  int    `xml:"Score"`
+	}
+	if err := doModerationRequest(req, func(body []byte) error { return xml.Unmarshal(body, &resp) }); err != nil {
+		return nil, err
+	}
+
+	return &ModerationResult{
+		Provider: m.Name(),
+		Flagged:  resp.Result == 1,
+		Label:    resp.Label,
+		Score:    float64(resp.Score) / 100,
+	}, nil
+}
+
+// cosSignature 腾讯云COS请求签名（只签名 host 请求头）
+func cosSignature(secretID, secretKey, method, path string, query map[string]string, host string, ttl time.Duration) string {
+	now := time.Now()
+	keyTime := fmt.Sprintf("%d;%d", now.Unix(), now.Add(ttl).Unix())
+
+	paramKeys := make([]string, 0, len(query))
+	encoded := make(map[string]string, len(query))
+	for k, v := range query {
+		key := strings.ToLower(url.QueryEscape(k))
+		paramKeys = append(paramKeys, key)
+		encoded[key] = url.QueryEscape(v)
+	}
+	sort.Strings(paramKeys)
+
+	params := make([]string, 0, len(paramKeys))
+	for _, k := range paramKeys {
+		params = append(params, k+"="+encoded[k])
+	}
+
+	httpString := fmt.Sprintf("%s\n%s\n%s\nhost=%s\n", strings.ToLower(method), path, strings.Join(params, "&"), url.QueryEscape(host))
+	httpHash := sha1.Sum([]byte(httpString))
+	stringToSign := fmt.Sprintf("sha1\n%s\n%s\n", keyTime, hex.EncodeToString(httpHash[:]))
+
+	signKey := hmacSHA1Hex(secretKey, keyTime)
+	signature := hmacSHA1Hex(signKey, stringToSign)
+
+	return fmt.Sprintf("q-sign-algorithm=sha1&q-ak=%s&q-sign-time=%s&q-key-time=%s&q-header-list=host&q-url-param-list=%s&q-signature=%s",
+		secretID, keyTime, keyTime, strings.Join(paramKeys, ";"), signature)
+}
+
+// hmacSHA1Hex HMAC-SHA1 并返回十六进制字符串
+func hmacSHA1Hex(key, data string) string {
+	mac := hmac.New(sha1.New, []byte(key))
+	mac.Write([]byte(data))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ==================== 本地NSFW模型 ====================
+
+// LocalNSFWModerator 本地部署的NSFW分类模型服务
+// 以请求体上传图片，服务返回 {"label": "porn", "score": 0.97}
+type LocalNSFWModerator struct {
+	Endpoint  string
+	Threshold float64
+}
+
+// safeNSFWLabels 模型返回的安全类别
+var safeNSFWLabels = map[string]bool{"": true, "neutral": true, "drawings": true, "normal": true, "safe": true}
+
+// Name 审核服务名称
+func (m *LocalNSFWModerator) Name() string {
+	return "local"
+}
+
+// Moderate 上传图片内容进行分类，非安全类别且分数超过阈值时判定为违规
+func (m *LocalNSFWModerator) Moderate(ctx context.Context, imageURL string, data []byte) (*ModerationResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.Endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", http.DetectContentType(data))
+
+	var resp struct {
+		Label string  `json:"label"`
+		Score float64 `json:"score"`
+	}
+	if err := doModerationRequest(req, func(body []byte) error { return json.Unmarshal(body, &resp) }); err != nil {
+		return nil, err
+	}
+
+	label := strings.ToLower(resp.Label)
+	return &ModerationResult{
+		Provider: m.Name(),
+		Flagged:  !safeNSFWLabels[label] && resp.Score >= m.Threshold,
+		Label:    label,
+		Score:    resp.Score,
+	}, nil
+}
+
+// doModerationRequest 发送审核请求并解析响应
+func doModerationRequest(req *http.Request, decode func(body []byte) error) error {
+	resp, err := moderationHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("moderation request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read moderation response: %w", err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("moderation service returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := decode(body); err != nil {
+		return fmt.Errorf("failed to decode moderation response: %w", err)
+	}
+	return nil
+}
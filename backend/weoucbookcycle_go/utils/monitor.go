@@ -1,14 +1,100 @@
 package utils
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"weoucbookcycle_go/config"
+
+	"github.com/getsentry/sentry-go"
 )
 
-// CaptureError 是一个错误监控占位符，后续可以集成 Sentry/其他监控服务
-func CaptureError(context string, err error) {
+// 错误上报：配置 SENTRY_DSN 时把 panic、5xx 响应、后台任务和定时任务的失败发送到 Sentry
+// 请求中的错误由 middleware.Recovery 带上 request_id、user_id、route 标签；
+// 后台goroutine 使用 Go 启动，错误和 panic 都会记录日志并上报，不再静默丢弃
+// 未配置时各函数只记录日志
+
+// InitErrorReporting 初始化 Sentry，未配置 SENTRY_DSN 时不做任何事
+// 返回的函数在关闭时调用，用于发送缓冲中的事件
+func InitErrorReporting(cfg *config.Config) (func(context.Context), error) {
+	if cfg.Sentry.DSN == "" {
+		return func(context.Context) {}, nil
+	}
+
+	environment := cfg.Sentry.Environment
+	if environment == "" {
+		environment = cfg.Env
+	}
+	err := sentry.Init(sentry.ClientOptions{
+		Dsn:              cfg.Sentry.DSN,
+		Environment:      environment,
+		Release:          cfg.Sentry.Release,
+		SampleRate:       cfg.Sentry.SampleRate,
+		ServerName:       cfg.Tracing.ServiceName,
+		AttachStacktrace: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("init sentry: %w", err)
+	}
+	log.Println("✅ Sentry error reporting enabled")
+
+	return func(ctx context.Context) { sentry.FlushWithContext(ctx) }, nil
+}
+
+// ReportHub 返回 ctx 中的 Sentry hub（请求中由 middleware.Recovery 设置），没有时返回全局hub的副本
+func ReportHub(ctx context.Context) *sentry.Hub {
+	if ctx != nil {
+		if hub := sentry.GetHubFromContext(ctx); hub != nil {
+			return hub
+		}
+	}
+	return sentry.CurrentHub().Clone()
+}
+
+// CaptureError 上报错误，tags 为附加的标签（如任务名），ctx 中的trace ID 一并上报
+func CaptureError(ctx context.Context, err error, tags map[string]string) {
 	if err == nil {
 		return
 	}
-	// 目前仅记录为日志，避免输出敏感信息
-	log.Printf("[ERROR] %s: %v", context, err)
+	hub := ReportHub(ctx)
+	hub.WithScope(func(scope *sentry.Scope) {
+		scope.SetTags(tags)
+		if traceID := TraceID(ctx); traceID != "" {
+			scope.SetTag("trace_id", traceID)
+		}
+		hub.CaptureException(err)
+	})
+}
+
+// CapturePanic 上报 recover() 得到的值（附带堆栈）
+func CapturePanic(ctx context.Context, recovered interface{}, tags map[string]string) {
+	hub := ReportHub(ctx)
+	hub.WithScope(func(scope *sentry.Scope) {
+		scope.SetTags(tags)
+		scope.SetLevel(sentry.LevelFatal)
+		if traceID := TraceID(ctx); traceID != "" {
+			scope.SetTag("trace_id", traceID)
+		}
+		hub.RecoverWithContext(ctx, recovered)
+	})
+}
+
+// Go 在后台goroutine中执行 fn（如写入事件流、更新计数），返回的错误和 panic 记录日志并上报，不影响调用方
+// ctx 只用于传递trace和上报信息，请求结束后 fn 仍会执行完
+func Go(ctx context.Context, name string, fn func(ctx context.Context) error) {
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		tags := map[string]string{"task": name}
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("background task %s panicked: %v", name, r)
+				CapturePanic(ctx, r, tags)
+			}
+		}()
+
+		if err := fn(ctx); err != nil {
+			log.Printf("background task %s failed: %v", name, err)
+			CaptureError(ctx, err, tags)
+		}
+	}()
 }
@@ -0,0 +1,245 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 列表接口统一使用的分页响应：
+//
+//	{"code": 20000, "message": "Success", "data": [...], "pagination": {...}}
+//
+// 页码分页（page/limit）给出 total；游标分页（cursor/limit，v2 接口）给出 next_cursor，不统计总数。
+// links 是带上分页参数的同一接口地址，客户端可以直接请求 links.next 翻页；
+// 列表之外的信息（如搜索的纠错建议）放在 meta 中。
+//
+// 统一分页响应随 v2 引入：/api 和 /api/v1 上在此之前就有的列表接口通过 LegacyList 保持原来的响应格式，
+// 处理器本身不区分版本
+
+// 每页数量的默认值和上限
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// PageResponse 分页响应结构
+type PageResponse struct {
+	Code       int         `json:"code"`
+	Message    string      `json:"message"`
+	Data       interface{} `json:"data"` // 本页数据
+	Pagination Pagination  `json:"pagination"`
+	Meta       interface{} `json:"meta,omitempty"`
+}
+
+// Pagination 分页信息
+type Pagination struct {
+	Page  int    `json:"page,omitempty"`  // 当前页，游标分页时省略
+	Limit int    `json:"limit"`           // 每页数量
+	Total *int64 `json:"total,omitempty"` // 总数，游标分页时省略
+	// HasNext / HasPrev 是否有下一页、上一页（游标分页只能向后翻页，HasPrev 表示不是第一页）
+	HasNext    bool      `json:"has_next"`
+	HasPrev    bool      `json:"has_prev"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+	Links      PageLinks `json:"links"`
+}
+
+// PageLinks 当前页、下一页、上一页的地址（路径加查询参数），没有对应页时省略
+type PageLinks struct {
+	Self string `json:"self"`
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// PageParams 读取 page 和 limit 查询参数：page 最小为1，limit 取值 1~MaxPageLimit，
+// 未传或不合法时使用 defaultLimit
+func PageParams(c *gin.Context, defaultLimit int) (page, limit int) {
+	page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultLimit)))
+	if limit < 1 || limit > MaxPageLimit {
+		limit = defaultLimit
+	}
+	return page, limit
+}
+
+// PageOffset 页码对应的查询偏移量
+func PageOffset(page, limit int) int {
+	return (page - 1) * limit
+}
+
+// Paginate 页码分页响应，items 为本页数据，total 为符合条件的总数
+func Paginate[T any](c *gin.Context, items []T, total int64, page, limit int) {
+	WritePage(c, items, NewPagination(c, "page", page, limit, total), nil)
+}
+
+// NewPagination 页码分页信息，pageParam 为链接中页码的查询参数名（同一接口有多组分页时各不相同）
+func NewPagination(c *gin.Context, pageParam string, page, limit int, total int64) Pagination {
+	p := Pagination{
+		Page:    page,
+		Limit:   limit,
+		Total:   &total,
+		HasNext: int64(page)*int64(limit) < total,
+		HasPrev: page > 1,
+		Links:   PageLinks{Self: pageLink(c, pageParam, strconv.Itoa(page))},
+	}
+	if p.HasNext {
+		p.Links.Next = pageLink(c, pageParam, strconv.Itoa(page+1))
+	}
+	if p.HasPrev {
+		p.Links.Prev = pageLink(c, pageParam, strconv.Itoa(page-1))
+	}
+	return p
+}
+
+// PaginateCursor 游标分页响应，items 为按 ApplyCursor 查询的结果（多取一条），
+// key 返回记录的创建时间和ID，用于生成下一页游标
+func PaginateCursor[T any](c *gin.Context, items []T, limit int, key func(T) (time.Time, string)) {
+	page := NewCursorPage(items, limit, key)
+	WritePage(c, page.Items.([]T), NewCursorPagination(c, limit, page.NextCursor), nil)
+}
+
+// NewCursorPagination 游标分页信息，nextCursor 为下一页的游标，为空表示没有下一页
+// 游标不是 ApplyCursor 生成的（如Redis流的消息ID）时直接使用，配合 WritePage 输出
+func NewCursorPagination(c *gin.Context, limit int, nextCursor string) Pagination {
+	p := Pagination{
+		Limit:      limit,
+		HasNext:    nextCursor != "",
+		HasPrev:    c.Query("cursor") != "",
+		NextCursor: nextCursor,
+		Links:      PageLinks{Self: pageLink(c, "cursor", c.Query("cursor"))},
+	}
+	if p.HasNext {
+		p.Links.Next = pageLink(c, "cursor", nextCursor)
+	}
+	return p
+}
+
+// PaginateAll 不分页的列表（数量有限，一次全部返回）也使用统一的分页响应
+func PaginateAll[T any](c *gin.Context, items []T) {
+	if items == nil {
+		items = []T{}
+	}
+	total := int64(len(items))
+	p := Pagination{
+		Page:  1,
+		Limit: len(items),
+		Total: &total,
+		Links: PageLinks{Self: c.Request.URL.RequestURI()},
+	}
+	if legacy, ok := legacyListOf(c); ok {
+		legacy.write(c, items, p, nil, false)
+		return
+	}
+	WritePage(c, items, p, nil)
+}
+
+// WritePage 输出分页响应，meta 为列表之外的附加信息，没有时传 nil
+func WritePage[T any](c *gin.Context, items []T, p Pagination, meta interface{}) {
+	if items == nil {
+		items = []T{}
+	}
+	if legacy, ok := legacyListOf(c); ok {
+		legacy.write(c, items, p, meta, true)
+		return
+	}
+	c.JSON(http.StatusOK, PageResponse{
+		Code:       CodeSuccess,
+		Message:    "Success",
+		Data:       items,
+		Pagination: p,
+		Meta:       meta,
+	})
+}
+
+// pageLink 当前请求地址把分页参数 key 换成 value（value 为空时去掉该参数）
+func pageLink(c *gin.Context, key, value string) string {
+	u := *c.Request.URL
+	query := u.Query()
+	if value == "" {
+		query.Del(key)
+	} else {
+		query.Set(key, value)
+	}
+	u.RawQuery = query.Encode()
+	return u.RequestURI()
+}
+
+// legacyListKey 上下文中 v1 列表响应格式的键
+const legacyListKey = "legacy_list"
+
+// LegacyList v1 列表接口原来的响应格式，例如
+//
+//	{"books": [...], "total": 57, "page": 2, "limit": 20}
+//	{"code": 20000, "message": "Success", "data": {"notifications": [...], "total": 57, "page": 2, "limit": 20}}
+//
+// 分页列表返回 total/page/limit，游标分页的列表返回 next_cursor，不分页的列表（PaginateAll）按 Count 返回数量；
+// meta 中的字段（如搜索的 query、did_you_mean）与列表放在同一层
+type LegacyList struct {
+	Key   string // 列表字段名
+	Wrap  bool   // 放在 {"code","message","data"} 的 data 中，否则直接放在顶层
+	Count string // 不分页列表的数量字段名（total 或 count），为空时不返回数量
+}
+
+// Handler 包装 v1 路由的处理器，处理器中的 Paginate/PaginateAll/WritePage 按原格式输出
+func (l LegacyList) Handler(handler gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(legacyListKey, l)
+		handler(c)
+	}
+}
+
+// IsLegacyList 当前请求是否按 v1 原格式输出（列表之外的响应结构有版本差异时使用）
+func IsLegacyList(c *gin.Context) bool {
+	_, ok := legacyListOf(c)
+	return ok
+}
+
+func legacyListOf(c *gin.Context) (LegacyList, bool) {
+	v, ok := c.Get(legacyListKey)
+	if !ok {
+		return LegacyList{}, false
+	}
+	l, ok := v.(LegacyList)
+	return l, ok
+}
+
+// write 按原格式输出列表，paged 为 false 时是 PaginateAll 的不分页列表
+func (l LegacyList) write(c *gin.Context, items interface{}, p Pagination, meta interface{}, paged bool) {
+	body := gin.H{}
+	// meta 是结构体或 map，字段展开到列表所在的一层
+	if meta != nil {
+		if raw, err := json.Marshal(meta); err == nil {
+			var fields map[string]json.RawMessage
+			if json.Unmarshal(raw, &fields) == nil {
+				for k, v := range fields {
+					body[k] = v
+				}
+			}
+		}
+	}
+	body[l.Key] = items
+	switch {
+	case paged && p.Total == nil:
+		if p.NextCursor != "" {
+			body["next_cursor"] = p.NextCursor
+		}
+	case paged:
+		body["total"] = p.Total
+		body["page"] = p.Page
+		body["limit"] = p.Limit
+	case l.Count != "":
+		body[l.Count] = p.Total
+	}
+
+	if l.Wrap {
+		c.JSON(http.StatusOK, gin.H{"code": CodeSuccess, "message": "Success", "data": body})
+		return
+	}
+	c.JSON(http.StatusOK, body)
+}
@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type pageBody struct {
+	Data       []string   `json:"data"`
+	Pagination Pagination `json:"pagination"`
+}
+
+func servePage(t *testing.T, target string, handler gin.HandlerFunc) pageBody {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	r := gin.New()
+	r.GET("/items", handler)
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s: status %d", target, w.Code)
+	}
+	var body pageBody
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("GET %s: %v", target, err)
+	}
+	return body
+}
+
+func TestPaginate(t *testing.T) {
+	body := servePage(t, "/items?q=go&page=2&limit=2", func(c *gin.Context) {
+		page, limit := PageParams(c, DefaultPageLimit)
+		Paginate(c, []string{"c", "d"}, 5, page, limit)
+	})
+
+	p := body.Pagination
+	if p.Page != 2 || p.Limit != 2 || p.Total == nil || *p.Total != 5 || !p.HasNext || !p.HasPrev {
+		t.Fatalf("unexpected pagination %+v", p)
+	}
+	if p.Links.Self != "/items?limit=2&page=2&q=go" ||
+		p.Links.Next != "/items?limit=2&page=3&q=go" ||
+		p.Links.Prev != "/items?limit=2&page=1&q=go" {
+		t.Errorf("unexpected links %+v", p.Links)
+	}
+
+	// 最后一页没有下一页
+	body = servePage(t, "/items?page=3&limit=2", func(c *gin.Context) {
+		page, limit := PageParams(c, DefaultPageLimit)
+		Paginate(c, []string{"e"}, 5, page, limit)
+	})
+	if body.Pagination.HasNext || body.Pagination.Links.Next != "" {
+		t.Errorf("last page should not have next: %+v", body.Pagination)
+	}
+}
+
+func TestPageParams(t *testing.T) {
+	cases := map[string][2]int{
+		"/items":                   {1, DefaultPageLimit},
+		"/items?page=0&limit=0":    {1, DefaultPageLimit},
+		"/items?page=-3&limit=500": {1, DefaultPageLimit},
+		"/items?page=4&limit=50":   {4, 50},
+	}
+	for target, want := range cases {
+		servePage(t, target, func(c *gin.Context) {
+			page, limit := PageParams(c, DefaultPageLimit)
+			if page != want[0] || limit != want[1] {
+				t.Errorf("%s: got page %d limit %d, want %v", target, page, limit, want)
+			}
+			PaginateAll(c, []string{})
+		})
+	}
+}
+
+func TestPaginateCursor(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	key := func(s string) (time.Time, string) { return base, s }
+
+	// 多取一条表示还有下一页
+	body := servePage(t, "/items?limit=2", func(c *gin.Context) {
+		PaginateCursor(c, []string{"a", "b", "c"}, 2, key)
+	})
+	p := body.Pagination
+	if len(body.Data) != 2 || !p.HasNext || p.HasPrev || p.NextCursor == "" || p.Total != nil {
+		t.Fatalf("unexpected first page: data %v, pagination %+v", body.Data, p)
+	}
+	if p.Links.Next == "" || p.Links.Self != "/items?limit=2" {
+		t.Errorf("unexpected links %+v", p.Links)
+	}
+
+	body = servePage(t, "/items?limit=2&cursor="+p.NextCursor, func(c *gin.Context) {
+		PaginateCursor(c, []string{"c"}, 2, key)
+	})
+	if body.Pagination.HasNext || !body.Pagination.HasPrev || body.Pagination.NextCursor != "" {
+		t.Errorf("unexpected last page %+v", body.Pagination)
+	}
+}
+
+func TestWritePageNilItems(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	r := gin.New()
+	r.GET("/items", func(c *gin.Context) { Paginate[string](c, nil, 0, 1, DefaultPageLimit) })
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/items", nil))
+
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if string(body["data"]) != "[]" {
+		t.Errorf("empty page data = %s, want []", body["data"])
+	}
+}
+
+func TestLegacyList(t *testing.T) {
+	serve := func(l LegacyList, handler gin.HandlerFunc) string {
+		t.Helper()
+		gin.SetMode(gin.TestMode)
+		w := httptest.NewRecorder()
+		r := gin.New()
+		r.GET("/items", l.Handler(handler))
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/items?page=2&limit=2", nil))
+		return w.Body.String()
+	}
+
+	cases := []struct {
+		name    string
+		legacy  LegacyList
+		handler gin.HandlerFunc
+		want    string
+	}{
+		{"top level", LegacyList{Key: "books"}, func(c *gin.Context) {
+			Paginate(c, []string{"c", "d"}, 5, 2, 2)
+		}, `{"books":["c","d"],"limit":2,"page":2,"total":5}`},
+		{"wrapped", LegacyList{Key: "notifications", Wrap: true}, func(c *gin.Context) {
+			Paginate(c, []string{"c"}, 3, 2, 2)
+		}, `{"code":20000,"data":{"limit":2,"notifications":["c"],"page":2,"total":3},"message":"Success"}`},
+		{"meta", LegacyList{Key: "books"}, func(c *gin.Context) {
+			WritePage(c, []string{"c"}, NewPagination(c, "page", 2, 2, 3), gin.H{"query": "go"})
+		}, `{"books":["c"],"limit":2,"page":2,"query":"go","total":3}`},
+		{"cursor", LegacyList{Key: "logs", Wrap: true}, func(c *gin.Context) {
+			WritePage(c, []string{"c"}, NewCursorPagination(c, 2, "1700000000000-0"), gin.H{"scanned": 9})
+		}, `{"code":20000,"data":{"logs":["c"],"next_cursor":"1700000000000-0","scanned":9},"message":"Success"}`},
+		{"all with count", LegacyList{Key: "online_users", Wrap: true, Count: "count"}, func(c *gin.Context) {
+			PaginateAll(c, []string{"a", "b"})
+		}, `{"code":20000,"data":{"count":2,"online_users":["a","b"]},"message":"Success"}`},
+		{"all without count", LegacyList{Key: "chats"}, func(c *gin.Context) {
+			PaginateAll[string](c, nil)
+		}, `{"chats":[]}`},
+	}
+	for _, tc := range cases {
+		if got := serve(tc.legacy, tc.handler); got != tc.want {
+			t.Errorf("%s: got %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
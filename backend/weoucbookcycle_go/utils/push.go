@@ -0,0 +1,345 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"weoucbookcycle_go/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrInvalidPushToken 设备令牌已失效（应用卸载或令牌过期），调用方应删除该令牌
+var ErrInvalidPushToken = errors.New("invalid push token")
+
+// PushMessage 推送消息
+type PushMessage struct {
+	Title string            `json:"title"`
+	Body  string            `json:"body"`
+	Data  map[string]string `json:"data,omitempty"`  // 透传给客户端的数据（如 type、chat_id）
+	Badge int               `json:"badge,omitempty"` // iOS 角标数
+}
+
+// PushSender 移动端推送发送接口
+type PushSender interface {
+	Send(ctx context.Context, token string, msg *PushMessage) error
+	Name() string
+}
+
+var pushHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// NewPushSenders 按环境变量创建各平台的推送实现，返回 platform -> sender
+// Android 使用 FCM（FCM_CREDENTIALS_FILE 为服务账号JSON），
+// iOS 配置了 APNS_KEY_FILE 时直连 APNs，否则也通过 FCM 发送
+func NewPushSenders(cfg *config.PushConfig) map[string]PushSender {
+	senders := make(map[string]PushSender)
+
+	if path := cfg.FCMCredentialsFile; path != "" {
+		fcm, err := NewFCMSender(path)
+		if err != nil {
+			log.Printf("push: FCM disabled: %v", err)
+		} else {
+			senders["android"] = fcm
+			senders["ios"] = fcm
+		}
+	}
+
+	if path := cfg.APNSKeyFile; path != "" {
+		apns, err := NewAPNsSender(path, cfg.APNSKeyID, cfg.APNSTeamID, cfg.APNSTopic, cfg.APNSProduction)
+		if err != nil {
+			log.Printf("push: APNs disabled: %v", err)
+		} else {
+			senders["ios"] = apns
+		}
+	}
+
+	return senders
+}
+
+// ==================== FCM ====================
+
+// FCMSender Firebase Cloud Messaging（HTTP v1 接口）
+type FCMSender struct {
+	projectID   string
+	clientEmail string
+	tokenURI    string
+	privateKey  *rsa.PrivateKey
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewFCMSender 从服务账号JSON文件创建 FCM 发送器
+func NewFCMSender(credentialsFile string) (*FCMSender, error) {
+	raw, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials: %w", err)
+	}
+
+	var creds struct {
+		ProjectID   string `json:"project_id"`
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+		TokenURI    string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(raw, &creds); err != nil {
+		return nil, fmt.Errorf("invalid credentials: %w", err)
+	}
+
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(creds.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	if creds.TokenURI == "" {
+		creds.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	return &FCMSender{
+		projectID:   creds.ProjectID,
+		clientEmail: creds.ClientEmail,
+		tokenURI:    creds.TokenURI,
+		privateKey:  key,
+	}, nil
+}
+
+// Name 推送服务名称
+func (s *FCMSender) Name() string {
+	return "fcm"
+}
+
+// Send 发送推送，令牌失效时返回 ErrInvalidPushToken
+func (s *FCMSender) Send(ctx context.Context, token string, msg *PushMessage) error {
+	accessToken, err := s.getAccessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	payload := map[string]interface{}{
+		"message": map[string]interface{}{
+			"token":        token,
+			"notification": map[string]string{"title": msg.Title, "body": msg.Body},
+			"data":         msg.Data,
+			"android":      map[string]string{"priority": "high"},
+			"apns": map[string]interface{}{
+				"payload": map[string]interface{}{
+					"aps": map[string]interface{}{"sound": "default", "badge": msg.Badge},
+				},
+			},
+		},
+	}
+	body, _ := json.Marshal(payload)
+
+	endpoint := fmt.Sprintf("https://fcm.googleapis.com/v1/projects/%s/messages:send", s.projectID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	status, respBody, err := doPushRequest(req)
+	if err != nil {
+		return err
+	}
+	switch {
+	case status == http.StatusOK:
+		return nil
+	case status == http.StatusNotFound || strings.Contains(respBody, "UNREGISTERED"):
+		return ErrInvalidPushToken
+	case status == http.StatusBadRequest && strings.Contains(respBody, "registration token"):
+		return ErrInvalidPushToken
+	default:
+		return fmt.Errorf("fcm returned %d: %s", status, respBody)
+	}
+}
+
+// getAccessToken 用服务账号换取 OAuth2 访问令牌（缓存到过期前1分钟）
+func (s *FCMSender) getAccessToken(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.accessToken != "" && time.Now().Before(s.expiresAt) {
+		return s.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   s.clientEmail,
+		"scope": "https://www.googleapis.com/auth/firebase.messaging",
+		"aud":   s.tokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(s.privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign assertion: %w", err)
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	form.Set("assertion", assertion)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.tokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	status, respBody, err := doPushRequest(req)
+	if err != nil {
+		return "", err
+	}
+	if status != http.StatusOK {
+		return "", fmt.Errorf("fcm token exchange returned %d: %s", status, respBody)
+	}
+
+	var resp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal([]byte(respBody), &resp); err != nil {
+		return "", fmt.Errorf("invalid token response: %w", err)
+	}
+
+	s.accessToken = resp.AccessToken
+	s.expiresAt = now.Add(time.Duration(resp.ExpiresIn)*time.Second - time.Minute)
+	return s.accessToken, nil
+}
+
+// ==================== APNs ====================
+
+// APNsSender Apple Push Notification service（基于 .p8 密钥的令牌认证，HTTP/2）
+type APNsSender struct {
+	keyID      string
+	teamID     string
+	topic      string // App 的 Bundle ID
+	host       string
+	privateKey *ecdsa.PrivateKey
+
+	mu       sync.Mutex
+	jwtToken string
+	issuedAt time.Time
+}
+
+// NewAPNsSender 创建 APNs 发送器，production 为 false 时使用沙盒环境
+func NewAPNsSender(keyFile, keyID, teamID, topic string, production bool) (*APNsSender, error) {
+	raw, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key: %w", err)
+	}
+	key, err := jwt.ParseECPrivateKeyFromPEM(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %w", err)
+	}
+	if keyID == "" || teamID == "" || topic == "" {
+		return nil, errors.New("APNS_KEY_ID, APNS_TEAM_ID and APNS_TOPIC are required")
+	}
+
+	host := "https://api.sandbox.push.apple.com"
+	if production {
+		host = "https://api.push.apple.com"
+	}
+
+	return &APNsSender{keyID: keyID, teamID: teamID, topic: topic, host: host, privateKey: key}, nil
+}
+
+// Name 推送服务名称
+func (s *APNsSender) Name() string {
+	return "apns"
+}
+
+// Send 发送推送，令牌失效时返回 ErrInvalidPushToken
+func (s *APNsSender) Send(ctx context.Context, token string, msg *PushMessage) error {
+	authToken, err := s.getAuthToken()
+	if err != nil {
+		return err
+	}
+
+	aps := map[string]interface{}{
+		"alert": map[string]string{"title": msg.Title, "body": msg.Body},
+		"sound": "default",
+	}
+	if msg.Badge > 0 {
+		aps["badge"] = msg.Badge
+	}
+	payload := map[string]interface{}{"aps": aps}
+	for k, v := range msg.Data {
+		payload[k] = v
+	}
+	body, _ := json.Marshal(payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.host+"/3/device/"+token, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "bearer "+authToken)
+	req.Header.Set("apns-topic", s.topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+
+	status, respBody, err := doPushRequest(req)
+	if err != nil {
+		return err
+	}
+	switch {
+	case status == http.StatusOK:
+		return nil
+	case status == http.StatusGone || strings.Contains(respBody, "BadDeviceToken"):
+		return ErrInvalidPushToken
+	default:
+		return fmt.Errorf("apns returned %d: %s", status, respBody)
+	}
+}
+
+// getAuthToken 生成 APNs 认证令牌，Apple 要求每20~60分钟刷新一次
+func (s *APNsSender) getAuthToken() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.jwtToken != "" && time.Since(s.issuedAt) < 50*time.Minute {
+		return s.jwtToken, nil
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": s.teamID,
+		"iat": now.Unix(),
+	})
+	token.Header["kid"] = s.keyID
+
+	signed, err := token.SignedString(s.privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign apns token: %w", err)
+	}
+
+	s.jwtToken = signed
+	s.issuedAt = now
+	return signed, nil
+}
+
+// doPushRequest 发送推送请求，返回状态码和响应内容
+func doPushRequest(req *http.Request) (int, string, error) {
+	resp, err := pushHTTPClient.Do(req)
+	if err != nil {
+		return 0, "", fmt.Errorf("push request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to read push response: %w", err)
+	}
+	return resp.StatusCode, strings.TrimSpace(string(body)), nil
+}
@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// QueueStat 进程内队列（channel）的积压情况
+type QueueStat struct {
+	Name      string `json:"name"`
+	Depth     int    `json:"depth"`
+	Capacity  int    `json:"capacity"`
+	Instances int    `json:"instances"` // 同名队列的实例数（服务被多次创建时会有多个）
+}
+
+// queueProbe 读取单个channel长度和容量的探针
+type queueProbe struct {
+	depth    func() int
+	capacity int
+}
+
+var (
+	queueProbes   = make(map[string][]queueProbe)
+	queueProbesMu sync.RWMutex
+)
+
+// RegisterQueue 注册需要监控的channel，同名队列的积压会合并统计
+func RegisterQueue[T any](name string, ch chan T) {
+	queueProbesMu.Lock()
+	defer queueProbesMu.Unlock()
+	queueProbes[name] = append(queueProbes[name], queueProbe{
+		depth:    func() int { return len(ch) },
+		capacity: cap(ch),
+	})
+}
+
+// QueueStats 返回所有已注册队列的当前积压，按名称排序
+func QueueStats() []QueueStat {
+	queueProbesMu.RLock()
+	defer queueProbesMu.RUnlock()
+
+	stats := make([]QueueStat, 0, len(queueProbes))
+	for name, probes := range queueProbes {
+		stat := QueueStat{Name: name, Instances: len(probes)}
+		for _, p := range probes {
+			stat.Depth += p.depth()
+			stat.Capacity += p.capacity
+		}
+		stats = append(stats, stat)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
+// WaitQueuesDrained 等待所有已注册队列清空，用于优雅关闭时让worker处理完积压的任务
+// ctx 超时后返回仍有积压的队列
+func WaitQueuesDrained(ctx context.Context) []QueueStat {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		var pending []QueueStat
+		for _, stat := range QueueStats() {
+			if stat.Depth > 0 {
+				pending = append(pending, stat)
+			}
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return pending
+		case <-ticker.C:
+		}
+	}
+}
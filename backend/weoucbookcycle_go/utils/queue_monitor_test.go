@@ -0,0 +1,25 @@
+package utils
+
+import "testing"
+
+// 同名队列的深度和容量合并统计
+func TestQueueStatsMergesInstances(t *testing.T) {
+	a := make(chan int, 10)
+	b := make(chan int, 5)
+	RegisterQueue("test.queue", a)
+	RegisterQueue("test.queue", b)
+	a <- 1
+	a <- 2
+	b <- 3
+
+	for _, stat := range QueueStats() {
+		if stat.Name != "test.queue" {
+			continue
+		}
+		if stat.Depth != 3 || stat.Capacity != 15 || stat.Instances != 2 {
+			t.Fatalf("unexpected stat: %+v", stat)
+		}
+		return
+	}
+	t.Fatal("test.queue not reported")
+}
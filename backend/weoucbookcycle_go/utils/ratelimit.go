@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Rate 令牌桶：容量为 Limit，每个 Period 补满 Limit 个令牌（匀速补充）
+type Rate struct {
+	Limit  int
+	Period time.Duration
+}
+
+// RateLimitResult 一次取令牌的结果
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// ResetAfter 令牌桶补满需要的时间
+	ResetAfter time.Duration
+	// RetryAfter 被拒绝时下一个令牌可用需要等待的时间
+	RetryAfter time.Duration
+}
+
+// tokenBucketScript 原子地补充并取走一个令牌，时间以Redis服务器为准，避免各实例时钟不一致
+// KEYS[1] 令牌桶；ARGV[1] 容量；ARGV[2] 每毫秒补充的令牌数
+// 返回 {是否允许, 剩余令牌数（字符串，保留小数）}
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = t[1] * 1000 + math.floor(t[2] / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil((capacity - tokens) / rate) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// TakeToken 从 key 对应的令牌桶取一个令牌
+func TakeToken(ctx context.Context, client *redis.Client, key string, rate Rate) (*RateLimitResult, error) {
+	perMs := float64(rate.Limit) / float64(rate.Period.Milliseconds())
+	reply, err := tokenBucketScript.Run(ctx, client, []string{key}, rate.Limit, strconv.FormatFloat(perMs, 'f', -1, 64)).Slice()
+	if err != nil {
+		return nil, err
+	}
+	if len(reply) != 2 {
+		return nil, fmt.Errorf("unexpected token bucket reply %v", reply)
+	}
+	allowed, _ := reply[0].(int64)
+	raw, _ := reply[1].(string)
+	tokens, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token count %q: %w", raw, err)
+	}
+
+	return newRateLimitResult(allowed == 1, tokens, rate), nil
+}
+
+// newRateLimitResult 由取令牌后桶中剩余的令牌数计算剩余次数、补满时间和重试等待时间
+func newRateLimitResult(allowed bool, tokens float64, rate Rate) *RateLimitResult {
+	perMs := float64(rate.Limit) / float64(rate.Period.Milliseconds())
+	result := &RateLimitResult{
+		Allowed:    allowed,
+		Limit:      rate.Limit,
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: time.Duration((float64(rate.Limit) - tokens) / perMs * float64(time.Millisecond)),
+	}
+	if !result.Allowed {
+		result.RetryAfter = time.Duration((1 - tokens) / perMs * float64(time.Millisecond))
+	}
+	return result
+}
+
+// RateLimitError 请求超过限流，由 middleware.ErrorHandler 转换为429并在 data.retry_after 中给出等待秒数
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return "Too many requests"
+}
+
+// RetryAfterSeconds 向上取整的等待秒数，至少为1
+func (e *RateLimitError) RetryAfterSeconds() int {
+	return max(1, int(math.Ceil(e.RetryAfter.Seconds())))
+}
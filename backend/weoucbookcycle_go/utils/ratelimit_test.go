@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewRateLimitResult(t *testing.T) {
+	// 10次/秒：每100ms补充一个令牌
+	rate := Rate{Limit: 10, Period: time.Second}
+
+	allowed := newRateLimitResult(true, 7.5, rate)
+	if !allowed.Allowed || allowed.Limit != 10 || allowed.Remaining != 7 {
+		t.Errorf("allowed = %+v", allowed)
+	}
+	if allowed.ResetAfter != 250*time.Millisecond {
+		t.Errorf("ResetAfter = %v, want 250ms", allowed.ResetAfter)
+	}
+	if allowed.RetryAfter != 0 {
+		t.Errorf("RetryAfter = %v, want 0 when allowed", allowed.RetryAfter)
+	}
+
+	denied := newRateLimitResult(false, 0.25, rate)
+	if denied.Allowed || denied.Remaining != 0 {
+		t.Errorf("denied = %+v", denied)
+	}
+	if denied.ResetAfter != 975*time.Millisecond {
+		t.Errorf("ResetAfter = %v, want 975ms", denied.ResetAfter)
+	}
+	if denied.RetryAfter != 75*time.Millisecond {
+		t.Errorf("RetryAfter = %v, want 75ms", denied.RetryAfter)
+	}
+}
+
+func TestRateLimitErrorRetryAfterSeconds(t *testing.T) {
+	cases := map[time.Duration]int{
+		0:                       1,
+		75 * time.Millisecond:   1,
+		time.Second:             1,
+		1001 * time.Millisecond: 2,
+		90 * time.Second:        90,
+	}
+	for retry, want := range cases {
+		if got := (&RateLimitError{RetryAfter: retry}).RetryAfterSeconds(); got != want {
+			t.Errorf("RetryAfterSeconds(%v) = %d, want %d", retry, got, want)
+		}
+	}
+}
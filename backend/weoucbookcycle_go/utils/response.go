@@ -2,10 +2,10 @@ package utils
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"time"
-	"weoucbookcycle_go/config"
 
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
@@ -19,16 +19,6 @@ type Response struct {
 	Error   string      `json:"error,omitempty"` // 错误信息
 }
 
-// PageResponse 分页响应结构
-type PageResponse struct {
-	Code    int         `json:"code"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data"`
-	Total   int64       `json:"total"` // 总数
-	Page    int         `json:"page"`  // 当前页
-	Limit   int         `json:"limit"` // 每页数量
-}
-
 // 业务状态码常量
 const (
 	CodeSuccess             = 20000 // 成功
@@ -36,8 +26,13 @@ const (
 	CodeUnauthorized        = 40100 // 未授权
 	CodeForbidden           = 40300 // 禁止访问
 	CodeNotFound            = 40400 // 资源不存在
+	CodeRequestTimeout      = 40800 // 请求超时
+	CodeConflict            = 40900 // 状态冲突
+	CodeRequestTooLarge     = 41300 // 请求体过大
 	CodeValidationError     = 42200 // 验证错误
+	CodeTooManyRequests     = 42900 // 请求过于频繁
 	CodeInternalServerError = 50000 // 内部错误
+	CodeServiceUnavailable  = 50300 // 服务暂不可用
 )
 
 // 业务状态码对应的消息
@@ -47,8 +42,13 @@ var codeMessages = map[int]string{
 	CodeUnauthorized:        "未授权，请重新登录",
 	CodeForbidden:           "禁止访问",
 	CodeNotFound:            "资源不存在",
+	CodeRequestTimeout:      "请求超时，请稍后再试",
+	CodeConflict:            "资源状态冲突",
+	CodeRequestTooLarge:     "请求内容过大",
 	CodeValidationError:     "参数验证失败",
+	CodeTooManyRequests:     "请求过于频繁，请稍后再试",
 	CodeInternalServerError: "服务器内部错误",
+	CodeServiceUnavailable:  "服务暂不可用",
 }
 
 // GetCodeMessage 获取状态码对应的消息
@@ -148,21 +148,9 @@ func InternalError(c *gin.Context, message string) {
 	})
 }
 
-// Paginate 分页响应
-func Paginate(c *gin.Context, data interface{}, total int64, page, limit int) {
-	c.JSON(http.StatusOK, PageResponse{
-		Code:    CodeSuccess,
-		Message: GetCodeMessage(CodeSuccess),
-		Data:    data,
-		Total:   total,
-		Page:    page,
-		Limit:   limit,
-	})
-}
-
 // AsyncResponse 异步响应（使用goroutine处理）
 // 适用于耗时操作，立即返回，实际处理在后台进行
-func AsyncResponse(c *gin.Context, task func() error, successMsg string) {
+func AsyncResponse(c *gin.Context, redisClient *redis.Client, task func() error, successMsg string) {
 	// 创建任务ID
 	taskID := generateTaskID()
 
@@ -183,7 +171,7 @@ func AsyncResponse(c *gin.Context, task func() error, successMsg string) {
 		err := task()
 
 		// 记录任务状态到Redis
-		if config.RedisClient != nil {
+		if redisClient != nil {
 			taskStatus := "completed"
 			errorMsg := ""
 			if err != nil {
@@ -201,23 +189,134 @@ func AsyncResponse(c *gin.Context, task func() error, successMsg string) {
 				"duration_ms":  time.Since(startTime).Milliseconds(),
 			}
 
-			config.RedisClient.HSet(ctx, taskKey, taskData)
-			config.RedisClient.Expire(ctx, taskKey, 24*time.Hour)
+			redisClient.HSet(ctx, taskKey, taskData)
+			redisClient.Expire(ctx, taskKey, 24*time.Hour)
 		}
 	}()
 }
 
+// ProgressFunc 任务进度回调，done/total 为已处理和总数量
+type ProgressFunc func(done, total int64)
+
+// AsyncTaskResponse 带进度的异步任务响应
+// 与 AsyncResponse 相同立即返回 task_id，任务运行期间通过 progress 回调上报进度，
+// 可通过 CheckTaskStatus 查询 status/done/total/progress
+func AsyncTaskResponse(c *gin.Context, redisClient *redis.Client, task func(progress ProgressFunc) error) string {
+	taskID := CreateTask(redisClient, c.GetString("user_id"), "running")
+
+	c.JSON(http.StatusAccepted, Response{
+		Code:    CodeSuccess,
+		Message: "任务已提交，正在处理中",
+		Data: gin.H{
+			"task_id": taskID,
+		},
+	})
+
+	go func() {
+		startTime := time.Now()
+		err := task(func(done, total int64) {
+			UpdateTaskProgress(redisClient, taskID, done, total)
+		})
+		FinishTask(redisClient, taskID, startTime, err, nil)
+	}()
+
+	return taskID
+}
+
+// CreateTask 创建任务状态记录并返回任务ID，供不经过 AsyncTaskResponse 的长流程（如分片上传）使用
+func CreateTask(redisClient *redis.Client, userID, status string) string {
+	taskID := generateTaskID()
+	if redisClient == nil {
+		return taskID
+	}
+
+	ctx := context.Background()
+	taskKey := fmt.Sprintf("task:%s", taskID)
+	redisClient.HSet(ctx, taskKey, map[string]interface{}{
+		"status":     status,
+		"user_id":    userID,
+		"started_at": time.Now().Unix(),
+	})
+	redisClient.Expire(ctx, taskKey, 24*time.Hour)
+	return taskID
+}
+
+// UpdateTaskProgress 更新任务进度
+func UpdateTaskProgress(redisClient *redis.Client, taskID string, done, total int64) {
+	if redisClient == nil {
+		return
+	}
+	progress := 0.0
+	if total > 0 {
+		progress = float64(done) / float64(total) * 100
+	}
+	redisClient.HSet(context.Background(), fmt.Sprintf("task:%s", taskID), map[string]interface{}{
+		"done":     done,
+		"total":    total,
+		"progress": fmt.Sprintf("%.1f", progress),
+	})
+}
+
+// UpdateTaskResults 以JSON保存任务的逐项结果（例如批量上传中每个文件的状态）
+func UpdateTaskResults(redisClient *redis.Client, taskID string, results interface{}) {
+	if redisClient == nil {
+		return
+	}
+	data, err := json.Marshal(results)
+	if err != nil {
+		return
+	}
+	redisClient.HSet(context.Background(), fmt.Sprintf("task:%s", taskID), "results", string(data))
+}
+
+// UpdateTaskStatus 更新任务状态（例如 uploading -> processing）
+func UpdateTaskStatus(redisClient *redis.Client, taskID, status string) {
+	if redisClient == nil {
+		return
+	}
+	redisClient.HSet(context.Background(), fmt.Sprintf("task:%s", taskID), "status", status)
+}
+
+// FinishTask 记录任务完成或失败，result 为附加的结果字段
+func FinishTask(redisClient *redis.Client, taskID string, startTime time.Time, err error, result map[string]interface{}) {
+	if redisClient == nil {
+		return
+	}
+
+	taskStatus := "completed"
+	errorMsg := ""
+	if err != nil {
+		taskStatus = "failed"
+		errorMsg = err.Error()
+	}
+
+	fields := map[string]interface{}{
+		"status":       taskStatus,
+		"error":        errorMsg,
+		"completed_at": time.Now().Unix(),
+		"duration_ms":  time.Since(startTime).Milliseconds(),
+	}
+	for k, v := range result {
+		fields[k] = v
+	}
+
+	ctx := context.Background()
+	taskKey := fmt.Sprintf("task:%s", taskID)
+	redisClient.HSet(ctx, taskKey, fields)
+	redisClient.Expire(ctx, taskKey, 24*time.Hour)
+}
+
 // CheckTaskStatus 检查任务状态
-func CheckTaskStatus(taskID string) (map[string]string, error) {
-	if config.RedisClient == nil {
+func CheckTaskStatus(redisClient *redis.Client, taskID string) (map[string]string, error) {
+	if redisClient == nil {
 		return nil, fmt.Errorf("redis not available")
 	}
 
 	ctx := context.Background()
 	taskKey := fmt.Sprintf("task:%s", taskID)
 
-	status, err := config.RedisClient.HGetAll(ctx, taskKey).Result()
-	if err == redis.Nil {
+	status, err := redisClient.HGetAll(ctx, taskKey).Result()
+	if err == redis.Nil || (err == nil && len(status) == 0) {
 		return nil, fmt.Errorf("task not found")
 	}
 	if err != nil {
@@ -243,8 +342,8 @@ func randomString(length int) string {
 }
 
 // APIRateLimit API限流（使用Redis）
-func APIRateLimit(c *gin.Context, userID string, limit int, duration time.Duration) bool {
-	if config.RedisClient == nil {
+func APIRateLimit(c *gin.Context, redisClient *redis.Client, userID string, limit int, duration time.Duration) bool {
+	if redisClient == nil {
 		return true // Redis不可用时，不限流
 	}
 
@@ -252,14 +351,14 @@ func APIRateLimit(c *gin.Context, userID string, limit int, duration time.Durati
 	key := fmt.Sprintf("ratelimit:api:%s", userID)
 
 	// 使用Redis的INCR和EXPIRE实现限流
-	count, err := config.RedisClient.Incr(ctx, key).Result()
+	count, err := redisClient.Incr(ctx, key).Result()
 	if err != nil {
 		return true
 	}
 
 	// 如果是第一次请求，设置过期时间
 	if count == 1 {
-		config.RedisClient.Expire(ctx, key, duration)
+		redisClient.Expire(ctx, key, duration)
 	}
 
 	return count <= int64(limit)
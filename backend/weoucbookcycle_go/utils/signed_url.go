@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"weoucbookcycle_go/config"
+)
+
+// PrivatePrefix 私有文件目录，只能通过签名URL访问
+const PrivatePrefix = "private/"
+
+// 签名URL设置，由 InitStorage 写入
+var (
+	fileURLBase   string
+	fileURLSecret string
+)
+
+var (
+	// ErrSignatureExpired 签名URL已过期
+	ErrSignatureExpired = errors.New("signed url has expired")
+	// ErrSignatureInvalid 签名不正确
+	ErrSignatureInvalid = errors.New("invalid signature")
+)
+
+// IsPrivateFileKey 是否为私有文件
+func IsPrivateFileKey(key string) bool {
+	return strings.HasPrefix(strings.TrimLeft(key, "/"), PrivatePrefix)
+}
+
+// SignFileURL 生成本地文件的签名URL（/api/files/signed/{key}?expires=&signature=）
+func SignFileURL(key string, ttl time.Duration) string {
+	key = strings.TrimLeft(key, "/")
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", fileSignature(key, expires))
+
+	return fmt.Sprintf("%s/api/files/signed/%s?%s", fileURLBase, key, query.Encode())
+}
+
+// initFileSigning 保存签名URL的地址前缀（API_BASE）和密钥
+// 密钥为 FILE_URL_SECRET，未配置时使用 JWT_SECRET
+func initFileSigning(cfg *config.Config) {
+	fileURLBase = strings.TrimRight(cfg.APIBase, "/")
+	fileURLSecret = cfg.Upload.FileURLSecret
+	if fileURLSecret == "" {
+		fileURLSecret = cfg.JWT.SecretKey
+	}
+}
+
+// VerifyFileSignature 校验签名URL的参数
+func VerifyFileSignature(key, expires, signature string) error {
+	key = strings.TrimLeft(key, "/")
+
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrSignatureInvalid
+	}
+	if time.Now().Unix() > expiresAt {
+		return ErrSignatureExpired
+	}
+
+	expected := fileSignature(key, expires)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrSignatureInvalid
+	}
+	return nil
+}
+
+// fileSignature HMAC-SHA256(key + "\n" + expires)
+func fileSignature(key, expires string) string {
+	mac := hmac.New(sha256.New, []byte(fileURLSecret))
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"weoucbookcycle_go/config"
+)
+
+// 签名URL：正确签名可通过，篡改key或过期后拒绝
+func TestSignedFileURL(t *testing.T) {
+	withConfig(t, func(cfg *config.Config) {
+		cfg.Upload.FileURLSecret = "test-secret"
+		cfg.APIBase = "http://localhost:8080"
+	})
+
+	key := "private/2024/01/02/id_card.jpg"
+	signed, err := url.Parse(SignFileURL(key, time.Minute))
+	if err != nil {
+		t.Fatalf("invalid signed url: %v", err)
+	}
+	if got := strings.TrimPrefix(signed.Path, "/api/files/signed/"); got != key {
+		t.Fatalf("unexpected key in path: %s", got)
+	}
+
+	expires, signature := signed.Query().Get("expires"), signed.Query().Get("signature")
+	if err := VerifyFileSignature(key, expires, signature); err != nil {
+		t.Fatalf("expected valid signature, got %v", err)
+	}
+
+	if err := VerifyFileSignature("private/2024/01/02/other.jpg", expires, signature); !errors.Is(err, ErrSignatureInvalid) {
+		t.Fatalf("expected invalid signature for other key, got %v", err)
+	}
+
+	past := SignFileURL(key, -time.Minute)
+	expired, _ := url.Parse(past)
+	if err := VerifyFileSignature(key, expired.Query().Get("expires"), expired.Query().Get("signature")); !errors.Is(err, ErrSignatureExpired) {
+		t.Fatalf("expected expired signature, got %v", err)
+	}
+}
@@ -0,0 +1,275 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"weoucbookcycle_go/config"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// Storage 文件存储接口
+// key 为存储内的相对路径（例如 2024/01/02/xxx.jpg），所有实现都返回可直接访问的公开URL
+type Storage interface {
+	// Put 写入文件并返回公开URL
+	Put(ctx context.Context, key string, reader io.Reader, size int64, contentType string) (string, error)
+	// Open 读取文件
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 删除文件，文件不存在时不返回错误
+	Delete(ctx context.Context, key string) error
+	// URL 获取文件的公开URL
+	URL(key string) string
+	// SignedURL 获取带签名、会过期的访问URL，用于私有文件
+	SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error)
+	// Name 存储类型名称
+	Name() string
+	// Walk 遍历所有文件（包括私有文件），用于备份
+	Walk(ctx context.Context, fn func(key string, size int64, modTime time.Time) error) error
+}
+
+var (
+	defaultStorage  Storage
+	localUploadPath = "./uploads"
+	// uploadSettings 应用配置中的上传设置，由 InitStorage 写入
+	uploadSettings = &config.UploadConfig{Path: localUploadPath}
+)
+
+// InitStorage 按配置选择存储实现，并保存上传和签名URL的设置，需在 config.InitializeStorage 之后调用
+// 配置了对象存储（STORAGE_PROVIDER 等）时使用 S3 兼容存储，否则使用本地磁盘（UPLOAD_PATH）
+func InitStorage(cfg *config.Config) {
+	uploadSettings = &cfg.Upload
+	initFileSigning(cfg)
+
+	if client, ok := config.StorageClient.(*minio.Client); ok && client != nil {
+		defaultStorage = NewS3Storage(client, &cfg.Storage)
+		return
+	}
+	// 未配置 UPLOAD_PUBLIC_URL 时使用 API_BASE + /uploads
+	baseURL := cfg.Upload.PublicURL
+	if baseURL == "" {
+		baseURL = strings.TrimRight(cfg.APIBase, "/") + "/uploads"
+	}
+	defaultStorage = NewLocalStorage(cfg.Upload.Path, baseURL)
+}
+
+// GetStorage 获取 InitStorage 选择的存储实现
+func GetStorage() Storage {
+	return defaultStorage
+}
+
+// ==================== 本地存储 ====================
+
+// LocalStorage 本地磁盘存储，文件通过 /uploads 静态路由访问
+type LocalStorage struct {
+	baseDir string
+	baseURL string
+}
+
+// NewLocalStorage 创建本地存储，baseURL 为文件的访问地址前缀，可配置为 CDN 地址
+func NewLocalStorage(baseDir, baseURL string) *LocalStorage {
+	return &LocalStorage{
+		baseDir: baseDir,
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}
+}
+
+// Put 写入本地文件
+func (ls *LocalStorage) Put(ctx context.Context, key string, reader io.Reader, size int64, contentType string) (string, error) {
+	path, err := ls.path(key)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create upload directory: %w", err)
+	}
+
+	dst, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, reader); err != nil {
+		return "", fmt.Errorf("failed to save file: %w", err)
+	}
+
+	return ls.URL(key), nil
+}
+
+// Open 打开本地文件
+func (ls *LocalStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := ls.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// Delete 删除本地文件
+func (ls *LocalStorage) Delete(ctx context.Context, key string) error {
+	path, err := ls.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
+
+// URL 本地文件的公开URL
+func (ls *LocalStorage) URL(key string) string {
+	return ls.baseURL + "/" + strings.TrimLeft(key, "/")
+}
+
+// SignedURL 本地文件的签名URL，由 /api/files/signed 接口校验签名后返回文件
+func (ls *LocalStorage) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	return SignFileURL(key, ttl), nil
+}
+
+// Name 存储类型名称
+func (ls *LocalStorage) Name() string {
+	return "local"
+}
+
+// Walk 遍历上传目录中的文件，目录不存在时视为空
+func (ls *LocalStorage) Walk(ctx context.Context, fn func(key string, size int64, modTime time.Time) error) error {
+	err := filepath.WalkDir(ls.baseDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(ls.baseDir, path)
+		if err != nil {
+			return err
+		}
+		return fn(filepath.ToSlash(rel), info.Size(), info.ModTime())
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// path 将key转换为本地路径，并防止跳出上传目录
+func (ls *LocalStorage) path(key string) (string, error) {
+	cleaned := filepath.Clean("/" + key)
+	if cleaned == "/" {
+		return "", fmt.Errorf("invalid file key: %q", key)
+	}
+	return filepath.Join(ls.baseDir, cleaned), nil
+}
+
+// ==================== S3兼容存储 ====================
+
+// S3Storage S3兼容对象存储（AWS S3、MinIO、阿里云OSS等）
+type S3Storage struct {
+	client *minio.Client
+	cfg    *config.StorageConfig
+}
+
+// NewS3Storage 创建S3兼容存储
+func NewS3Storage(client *minio.Client, cfg *config.StorageConfig) *S3Storage {
+	return &S3Storage{client: client, cfg: cfg}
+}
+
+// bucket 私有文件、隔离图片和上传分片放在私有存储桶，不能通过公开URL访问
+func (s *S3Storage) bucket(key string) string {
+	if s.cfg.PrivateBucket != "" && IsPrivateKey(key) {
+		return s.cfg.PrivateBucket
+	}
+	return s.cfg.Bucket
+}
+
+// Put 上传对象
+func (s *S3Storage) Put(ctx context.Context, key string, reader io.Reader, size int64, contentType string) (string, error) {
+	opts := minio.PutObjectOptions{ContentType: contentType}
+	if _, err := s.client.PutObject(ctx, s.bucket(key), key, reader, size, opts); err != nil {
+		return "", fmt.Errorf("storage upload failed: %w", err)
+	}
+	return s.URL(key), nil
+}
+
+// Open 读取对象
+func (s *S3Storage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	obj, err := s.client.GetObject(ctx, s.bucket(key), key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	// GetObject 不会立即请求，Stat 用于确认对象存在
+	if _, err := obj.Stat(); err != nil {
+		obj.Close()
+		return nil, err
+	}
+	return obj, nil
+}
+
+// Delete 删除对象
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	return s.client.RemoveObject(ctx, s.bucket(key), key, minio.RemoveObjectOptions{})
+}
+
+// SignedURL 生成预签名下载URL
+func (s *S3Storage) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	u, err := s.client.PresignedGetObject(ctx, s.bucket(key), key, ttl, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign url: %w", err)
+	}
+	return u.String(), nil
+}
+
+// URL 对象的公开URL，配置了 STORAGE_PUBLIC_URL（CDN）时优先使用
+func (s *S3Storage) URL(key string) string {
+	if s.cfg.PublicURL != "" {
+		return fmt.Sprintf("%s/%s", strings.TrimRight(s.cfg.PublicURL, "/"), key)
+	}
+	protocol := "https"
+	if !s.cfg.UseSSL {
+		protocol = "http"
+	}
+	return fmt.Sprintf("%s://%s/%s/%s", protocol, s.cfg.Endpoint, s.cfg.Bucket, key)
+}
+
+// Name 存储类型名称
+func (s *S3Storage) Name() string {
+	return s.cfg.Provider
+}
+
+// Walk 遍历存储桶中的对象，配置了私有存储桶时也遍历私有存储桶
+func (s *S3Storage) Walk(ctx context.Context, fn func(key string, size int64, modTime time.Time) error) error {
+	// 提前返回时取消 ctx，结束 ListObjects 的后台协程
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	buckets := []string{s.cfg.Bucket}
+	if s.cfg.PrivateBucket != "" && s.cfg.PrivateBucket != s.cfg.Bucket {
+		buckets = append(buckets, s.cfg.PrivateBucket)
+	}
+	for _, bucket := range buckets {
+		for obj := range s.client.ListObjects(ctx, bucket, minio.ListObjectsOptions{Recursive: true}) {
+			if obj.Err != nil {
+				return fmt.Errorf("failed to list %s: %w", bucket, obj.Err)
+			}
+			if err := fn(obj.Key, obj.Size, obj.LastModified); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"context"
+	"log"
+	"weoucbookcycle_go/config"
+
+	"github.com/redis/go-redis/extra/redisotel/v9"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	gormtracing "gorm.io/plugin/opentelemetry/tracing"
+)
+
+// tracerName 业务代码手动创建span时使用的tracer
+const tracerName = "weoucbookcycle_go"
+
+// InitTracing 初始化 OpenTelemetry
+// 配置 OTEL_EXPORTER_OTLP_ENDPOINT（或 OTEL_EXPORTER_OTLP_TRACES_ENDPOINT）时通过 OTLP/HTTP 导出，
+// 否则只在进程内生成trace ID（用于日志和响应头关联），不上报
+// 返回的函数在关闭时调用，用于导出缓冲中的span
+func InitTracing(ctx context.Context, cfg *config.Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		semconv.ServiceName(cfg.Tracing.ServiceName),
+		semconv.DeploymentEnvironmentName(cfg.Env),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Tracing.SampleRatio))),
+	}
+	if cfg.Tracing.Endpoint != "" || cfg.Tracing.TracesEndpoint != "" {
+		// 端点、头部、TLS等由标准的 OTEL_EXPORTER_OTLP_* 环境变量配置
+		exporter, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+		log.Println("✅ OpenTelemetry tracing enabled (OTLP/HTTP)")
+	}
+
+	provider := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// StartSpan 创建子span，ctx 中没有span时创建新的trace
+func StartSpan(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return otel.Tracer(tracerName).Start(ctx, name, opts...)
+}
+
+// TraceID 返回 ctx 中的trace ID，没有时返回空字符串
+func TraceID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		return sc.TraceID().String()
+	}
+	return ""
+}
+
+// InjectTrace 把 ctx 中的追踪上下文写入map，用于跨Redis PubSub/Stream传递
+func InjectTrace(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+	return carrier
+}
+
+// ExtractTrace 从 InjectTrace 写入的map中恢复追踪上下文
+func ExtractTrace(carrier map[string]string) context.Context {
+	return otel.GetTextMapPropagator().Extract(context.Background(), propagation.MapCarrier(carrier))
+}
+
+// TraceGORM 为数据库查询创建span（需要调用方使用 DB.WithContext(ctx) 才能挂到请求的trace下）
+func TraceGORM(db *gorm.DB) error {
+	// 耗时指标已由 InstrumentGORM 采集；不记录SQL参数，避免把用户数据写入追踪后端
+	return db.Use(gormtracing.NewPlugin(gormtracing.WithoutMetrics(), gormtracing.WithoutQueryVariables()))
+}
+
+// TraceRedis 为Redis命令创建span
+func TraceRedis(client *redis.Client) error {
+	if client == nil {
+		return nil
+	}
+	return redisotel.InstrumentTracing(client, redisotel.WithDBStatement(false))
+}
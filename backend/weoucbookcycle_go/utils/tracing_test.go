@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"context"
+	"testing"
+	"weoucbookcycle_go/config"
+)
+
+// 追踪上下文经map传递后trace ID保持不变
+func TestInjectExtractTrace(t *testing.T) {
+	cfg := &config.Config{Tracing: config.TracingConfig{ServiceName: "test", SampleRatio: 1}}
+	shutdown, err := InitTracing(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(context.Background())
+
+	ctx, span := StartSpan(context.Background(), "test")
+	defer span.End()
+
+	carrier := InjectTrace(ctx)
+	if carrier["traceparent"] == "" {
+		t.Fatalf("traceparent not injected: %v", carrier)
+	}
+	if got, want := TraceID(ExtractTrace(carrier)), TraceID(ctx); got == "" || got != want {
+		t.Fatalf("trace id = %q, want %q", got, want)
+	}
+	if TraceID(ExtractTrace(nil)) != "" {
+		t.Fatal("empty carrier should not carry a trace")
+	}
+}
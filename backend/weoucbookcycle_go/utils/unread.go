@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 未读计数按用户存放在一个hash中（unread:<user_id>，field 为 chat_id），
+// 获取用户全部未读数时用 HGETALL，不需要 KEYS unread:<user_id>:*
+
+const unreadTTL = 7 * 24 * time.Hour
+
+func unreadKey(userID string) string {
+	return "unread:" + userID
+}
+
+// IncrUnread 接收者在会话中的未读数加一
+func IncrUnread(ctx context.Context, client *redis.Client, userID, chatID string) {
+	if client == nil {
+		return
+	}
+	key := unreadKey(userID)
+	client.HIncrBy(ctx, key, chatID, 1)
+	client.Expire(ctx, key, unreadTTL)
+}
+
+// ClearUnread 清除用户在会话中的未读数
+func ClearUnread(ctx context.Context, client *redis.Client, userID, chatID string) {
+	if client == nil {
+		return
+	}
+	client.HDel(ctx, unreadKey(userID), chatID)
+}
+
+// GetUnread 获取用户在会话中的未读数，没有记录时返回 redis.Nil
+func GetUnread(ctx context.Context, client *redis.Client, userID, chatID string) (int64, error) {
+	return client.HGet(ctx, unreadKey(userID), chatID).Int64()
+}
+
+// GetAllUnread 获取用户各会话的未读数
+func GetAllUnread(ctx context.Context, client *redis.Client, userID string) (map[string]int64, error) {
+	values, err := client.HGetAll(ctx, unreadKey(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(values))
+	for chatID, v := range values {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			counts[chatID] = n
+		}
+	}
+	return counts, nil
+}
@@ -1,11 +1,15 @@
 package utils
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"image"
 	"io"
 	"log"
 	"mime/multipart"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -14,7 +18,7 @@ import (
 	"weoucbookcycle_go/config"
 
 	"github.com/gin-gonic/gin"
-	"github.com/minio/minio-go/v7"
+	"github.com/redis/go-redis/v9"
 )
 
 // UploadConfig 上传配置
@@ -25,6 +29,11 @@ type UploadConfig struct {
 	GenerateThumb  bool     // 是否生成缩略图
 	ThumbWidth     int      // 缩略图宽度
 	ThumbHeight    int      // 缩略图高度
+	MinDimension   int      // 图片最小边长（像素）
+	MaxDimension   int      // 图片最大边长（像素）
+	ConvertToWebP  bool     // 是否将 JPEG/PNG 转换为 WebP
+	WebPQuality    int      // WebP 质量（1-100）
+	KeepOriginal   bool     // 转换为 WebP 时是否保留原图
 	UseRedisCache  bool     // 是否使用Redis缓存
 }
 
@@ -32,135 +41,182 @@ type UploadConfig struct {
 var DefaultUploadConfig = &UploadConfig{
 	MaxFileSize:    10 * 1024 * 1024, // 10MB
 	AllowedFormats: []string{".jpg", ".jpeg", ".png", ".gif", ".webp"},
-	UploadPath:     "./uploads",
+	UploadPath:     localUploadPath,
 	GenerateThumb:  true,
 	ThumbWidth:     300,
 	ThumbHeight:    300,
+	MinDimension:   100,
+	MaxDimension:   10000,
+	ConvertToWebP:  true,
+	WebPQuality:    80,
+	KeepOriginal:   false,
 	UseRedisCache:  true,
 }
 
+// allowedMimeTypes 扩展名对应的真实文件类型（按文件内容检测）
+var allowedMimeTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+}
+
+// uploadConfigFrom 在默认配置基础上应用应用配置中的上传设置
+// UPLOAD_WEBP 是否转换为 WebP，UPLOAD_WEBP_QUALITY 质量，UPLOAD_KEEP_ORIGINAL 是否保留原图，
+// UPLOAD_MIN_DIMENSION / UPLOAD_MAX_DIMENSION 图片边长限制
+func uploadConfigFrom(settings *config.UploadConfig) *UploadConfig {
+	cfg := *DefaultUploadConfig
+	cfg.UploadPath = settings.Path
+	cfg.MinDimension = settings.MinDimension
+	cfg.MaxDimension = settings.MaxDimension
+	cfg.ConvertToWebP = settings.ConvertToWebP
+	cfg.WebPQuality = settings.WebPQuality
+	cfg.KeepOriginal = settings.KeepOriginal
+	if cfg.WebPQuality < 1 || cfg.WebPQuality > 100 {
+		cfg.WebPQuality = DefaultUploadConfig.WebPQuality
+	}
+	return &cfg
+}
+
 // UploadResult 上传结果
 type UploadResult struct {
-	OriginalURL string `json:"original_url"` // 原始图片URL
-	ThumbURL    string `json:"thumb_url"`    // 缩略图URL
-	FileSize    int64  `json:"file_size"`    // 文件大小
-	FileName    string `json:"file_name"`    // 文件名
-	Width       int    `json:"width"`        // 图片宽度
-	Height      int    `json:"height"`       // 图片高度
+	OriginalURL string `json:"original_url"`         // 原始图片URL（转换为 WebP 时为 WebP 图片）
+	SourceURL   string `json:"source_url,omitempty"` // 保留的转换前原图URL
+	Key         string `json:"key"`                  // 存储中的文件key，用于删除
+	ContentType string `json:"content_type"`         // 文件类型
+	Private     bool   `json:"private,omitempty"`    // 是否为私有文件（URL为签名URL）
+	Purpose     string `json:"purpose,omitempty"`    // 私有文件用途
+	ExpiresAt   int64  `json:"expires_at,omitempty"` // 签名URL过期时间
+	ThumbURL    string `json:"thumb_url"`            // 缩略图URL
+	FileSize    int64  `json:"file_size"`            // 文件大小
+	FileName    string `json:"file_name"`            // 文件名
+	Width       int    `json:"width"`                // 图片宽度
+	Height      int    `json:"height"`               // 图片高度
 }
 
-// FileUploader 文件上传器
-type FileUploader struct {
-	config *UploadConfig
+// UploadHook 上传成功后的回调（例如内容审核），在上传请求中同步调用，耗时操作需自行异步处理
+type UploadHook func(ctx context.Context, userID string, result *UploadResult)
+
+// uploadHooks 已注册的上传回调，仅在启动时注册
+var uploadHooks []UploadHook
+
+// RegisterUploadHook 注册上传回调
+func RegisterUploadHook(hook UploadHook) {
+	uploadHooks = append(uploadHooks, hook)
 }
 
-// NewFileUploader 创建文件上传器实例
-func NewFileUploader(config ...*UploadConfig) *FileUploader {
-	cfg := DefaultUploadConfig
-	if len(config) > 0 && config[0] != nil {
-		cfg = config[0]
+// runUploadHooks 执行上传回调
+func runUploadHooks(ctx context.Context, userID string, result *UploadResult) {
+	for _, hook := range uploadHooks {
+		hook(ctx, userID, result)
 	}
-	return &FileUploader{config: cfg}
 }
 
-// uploadToStorage sends data to configured object storage and returns public URL
-func (fu *FileUploader) uploadToStorage(ctx context.Context, reader io.Reader, size int64, fileName, contentType string) (string, error) {
-	cfg := config.GetStorageConfig()
-	client, ok := config.StorageClient.(*minio.Client)
-	if !ok || client == nil {
-		return "", fmt.Errorf("storage client not available")
-	}
-	// organize by date folder
-	dir := time.Now().Format("2006/01/02")
-	objectName := fmt.Sprintf("%s/%s", dir, fileName)
-	opts := minio.PutObjectOptions{ContentType: contentType}
-	_, err := client.PutObject(ctx, cfg.Bucket, objectName, reader, size, opts)
-	if err != nil {
-		return "", err
-	}
-	// build URL
-	if cfg.PublicURL != "" {
-		return fmt.Sprintf("%s/%s", strings.TrimRight(cfg.PublicURL, "/"), objectName), nil
-	}
-	protocol := "https"
-	if !cfg.UseSSL {
-		protocol = "http"
-	}
-	return fmt.Sprintf("%s://%s/%s/%s", protocol, cfg.Endpoint, cfg.Bucket, objectName), nil
+// UploadQuotaFunc 上传前预留配额，count/size 为本次上传的文件数和字节数
+type UploadQuotaFunc func(ctx context.Context, userID string, count int, size int64) error
+
+// UploadQuotaReleaseFunc 退回预留但没有保存成功的文件占用的配额
+type UploadQuotaReleaseFunc func(ctx context.Context, userID string, count int, size int64)
+
+// uploadQuota/releaseUploadQuota 当前的配额预留和退回，未设置时不限制
+var (
+	uploadQuota        UploadQuotaFunc
+	releaseUploadQuota UploadQuotaReleaseFunc
+)
+
+// SetUploadQuota 设置配额预留和退回，仅在启动时调用
+func SetUploadQuota(reserve UploadQuotaFunc, release UploadQuotaReleaseFunc) {
+	uploadQuota = reserve
+	releaseUploadQuota = release
 }
 
-// UploadFile 上传单个文件
-func (fu *FileUploader) UploadFile(c *gin.Context, fieldName string) (*UploadResult, error) {
-	file, err := c.FormFile(fieldName)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get file: %w", err)
+// ReserveQuota 为 count 个共 size 字节的文件预留配额，超出配额时返回错误
+// 保存失败的文件需要调用 ReleaseQuota 退回，保存成功的文件由上传记录计入用量
+func (fu *FileUploader) ReserveQuota(ctx context.Context, userID string, count int, size int64) error {
+	if uploadQuota == nil || userID == "" {
+		return nil
 	}
+	return uploadQuota(ctx, userID, count, size)
+}
 
-	// 验证文件大小
-	if file.Size > fu.config.MaxFileSize {
-		return nil, fmt.Errorf("file size exceeds maximum allowed size of %d bytes", fu.config.MaxFileSize)
+// ReleaseQuota 退回 ReserveQuota 预留的配额
+func (fu *FileUploader) ReleaseQuota(ctx context.Context, userID string, count int, size int64) {
+	if releaseUploadQuota == nil || userID == "" {
+		return
 	}
+	releaseUploadQuota(ctx, userID, count, size)
+}
 
-	// 验证文件格式
-	ext := strings.ToLower(filepath.Ext(file.Filename))
-	if !fu.isAllowedFormat(ext) {
-		return nil, fmt.Errorf("file format %s is not allowed", ext)
+// CheckQuota 检查用户是否还能上传 count 个共 size 字节的文件，不占用配额（如分片上传开始前）
+func (fu *FileUploader) CheckQuota(ctx context.Context, userID string, count int, size int64) error {
+	if err := fu.ReserveQuota(ctx, userID, count, size); err != nil {
+		return err
 	}
+	fu.ReleaseQuota(ctx, userID, count, size)
+	return nil
+}
 
-	// 打开文件
-	src, err := file.Open()
-	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
-	}
-	defer src.Close()
+// FileUploader 文件上传器
+type FileUploader struct {
+	config  *UploadConfig
+	storage Storage
+	scanner VirusScanner
+	// failClosed 扫描服务不可用时拒绝上传（CLAMD_FAIL_CLOSED）
+	failClosed bool
+	private    bool
+	purpose    string
+	// redisClient 保存文件元数据缓存、批量上传的任务状态和安全事件，为nil时跳过
+	redisClient *redis.Client
+}
 
-	// 生成文件名
-	fileName := generateFileName(file.Filename)
+// PrivateURLTTL 私有文件签名URL的有效期
+const PrivateURLTTL = 10 * time.Minute
 
-	result := &UploadResult{
-		FileSize: file.Size,
-		FileName: fileName,
-	}
+// Private 返回上传私有文件的上传器（学生证照片、纠纷证据等）
+// 文件写入 private/ 目录，不生成缩略图，返回的URL为会过期的签名URL
+func (fu *FileUploader) Private(purpose string) *FileUploader {
+	private := *fu
+	private.private = true
+	private.purpose = purpose
+	return &private
+}
 
-	// 如果已配置对象存储则直接上传
-	if config.StorageClient != nil {
-		url, err := fu.uploadToStorage(c.Request.Context(), src, file.Size, fileName, file.Header.Get("Content-Type"))
-		if err != nil {
-			return nil, fmt.Errorf("storage upload failed: %w", err)
-		}
-		result.OriginalURL = url
-		return result, nil
+// NewFileUploader 创建文件上传器实例，未指定配置时使用默认配置和应用配置中的上传设置
+func NewFileUploader(redisClient *redis.Client, uploadConfig ...*UploadConfig) *FileUploader {
+	settings := uploadSettings
+	cfg := uploadConfigFrom(settings)
+	if len(uploadConfig) > 0 && uploadConfig[0] != nil {
+		cfg = uploadConfig[0]
 	}
-
-	// fallback to local disk
-	filePath := filepath.Join(fu.config.UploadPath, fileName)
-	// 创建目录
-	if err := os.MkdirAll(fu.config.UploadPath, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create upload directory: %w", err)
+	return &FileUploader{
+		config:      cfg,
+		storage:     GetStorage(),
+		scanner:     NewVirusScanner(settings),
+		failClosed:  settings.ClamdFailClosed,
+		redisClient: redisClient,
 	}
+}
 
-	// 保存文件
-	dst, err := os.Create(filePath)
+// UploadFile 上传单个文件
+func (fu *FileUploader) UploadFile(c *gin.Context, fieldName string) (*UploadResult, error) {
+	file, err := c.FormFile(fieldName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create file: %w", err)
+		return nil, fmt.Errorf("failed to get file: %w", err)
 	}
-	defer dst.Close()
 
-	// reset reader to beginning by reopening
-	src2, _ := file.Open()
-	defer src2.Close()
-	if _, err := io.Copy(dst, src2); err != nil {
-		return nil, fmt.Errorf("failed to save file: %w", err)
+	if err := fu.ReserveQuota(c.Request.Context(), c.GetString("user_id"), 1, file.Size); err != nil {
+		return nil, err
 	}
 
-	// 构建结果
-	result.OriginalURL = fmt.Sprintf("/uploads/%s", fileName)
-
-	// 异步缓存文件信息到Redis
-	if fu.config.UseRedisCache && config.RedisClient != nil {
-		go fu.cacheFileMetadata(fileName, result)
+	result, err := fu.saveFile(c.Request.Context(), file)
+	if err != nil {
+		fu.ReleaseQuota(c.Request.Context(), c.GetString("user_id"), 1, file.Size)
+		fu.logInfectedUpload(c.GetString("user_id"), c.ClientIP(), err)
+		return nil, err
 	}
 
+	runUploadHooks(c.Request.Context(), c.GetString("user_id"), result)
 	return result, nil
 }
 
@@ -176,7 +232,17 @@ func (fu *FileUploader) UploadFiles(c *gin.Context, fieldName string) ([]*Upload
 		return nil, fmt.Errorf("no files found for field: %s", fieldName)
 	}
 
+	// 按本次上传的总量检查配额
+	var totalSize int64
+	for _, file := range files {
+		totalSize += file.Size
+	}
+	if err := fu.ReserveQuota(c.Request.Context(), c.GetString("user_id"), len(files), totalSize); err != nil {
+		return nil, err
+	}
+
 	// 使用goroutine并发上传多个文件
+	userID, ip := c.GetString("user_id"), c.ClientIP()
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 	results := make([]*UploadResult, 0, len(files))
@@ -188,76 +254,18 @@ func (fu *FileUploader) UploadFiles(c *gin.Context, fieldName string) ([]*Upload
 		go func(f *multipart.FileHeader) {
 			defer wg.Done()
 
-			// 打开文件
-			src, err := f.Open()
+			result, err := fu.saveFile(context.Background(), f)
 			if err != nil {
-				errorChan <- fmt.Errorf("failed to open file %s: %w", f.Filename, err)
-				return
-			}
-			defer src.Close()
-
-			// 验证文件大小
-			if f.Size > fu.config.MaxFileSize {
-				errorChan <- fmt.Errorf("file %s exceeds maximum size", f.Filename)
-				return
-			}
-
-			// 验证文件格式
-			ext := strings.ToLower(filepath.Ext(f.Filename))
-			if !fu.isAllowedFormat(ext) {
-				errorChan <- fmt.Errorf("file format %s not allowed for %s", ext, f.Filename)
+				fu.ReleaseQuota(c.Request.Context(), userID, 1, f.Size)
+				fu.logInfectedUpload(userID, ip, err)
+				errorChan <- fmt.Errorf("%s: %w", f.Filename, err)
 				return
 			}
 
-			// 生成文件名
-			fileName := generateFileName(f.Filename)
-			filePath := filepath.Join(fu.config.UploadPath, fileName)
-
-			// 创建目录
-			if err := os.MkdirAll(fu.config.UploadPath, 0755); err != nil {
-				errorChan <- fmt.Errorf("failed to create directory for %s: %w", f.Filename, err)
-				return
-			}
-
-			// 如果配置了对象存储则先上传
-			result := &UploadResult{
-				FileSize: f.Size,
-				FileName: fileName,
-			}
-			if config.StorageClient != nil {
-				url, err := fu.uploadToStorage(context.Background(), src, f.Size, fileName, f.Header.Get("Content-Type"))
-				if err != nil {
-					errorChan <- fmt.Errorf("storage upload failed for %s: %w", f.Filename, err)
-					return
-				}
-				result.OriginalURL = url
-			} else {
-				// 保存文件到本地
-				dst, err := os.Create(filePath)
-				if err != nil {
-					errorChan <- fmt.Errorf("failed to create file %s: %w", f.Filename, err)
-					return
-				}
-				defer dst.Close()
-
-				src2, _ := f.Open()
-				defer src2.Close()
-				if _, err := io.Copy(dst, src2); err != nil {
-					errorChan <- fmt.Errorf("failed to save file %s: %w", f.Filename, err)
-					return
-				}
-				result.OriginalURL = fmt.Sprintf("/uploads/%s", fileName)
-			}
-
 			// 添加到结果列表（加锁）
 			mu.Lock()
 			results = append(results, result)
 			mu.Unlock()
-
-			// 异步缓存到Redis
-			if fu.config.UseRedisCache && config.RedisClient != nil {
-				go fu.cacheFileMetadata(fileName, result)
-			}
 		}(file)
 	}
 
@@ -265,6 +273,10 @@ func (fu *FileUploader) UploadFiles(c *gin.Context, fieldName string) ([]*Upload
 	wg.Wait()
 	close(errorChan)
 
+	for _, result := range results {
+		runUploadHooks(c.Request.Context(), userID, result)
+	}
+
 	// 收集错误
 	for err := range errorChan {
 		errors = append(errors, err)
@@ -278,9 +290,347 @@ func (fu *FileUploader) UploadFiles(c *gin.Context, fieldName string) ([]*Upload
 	return results, nil
 }
 
+// MaxAsyncBatchFiles 异步批量上传单次最多文件数（文件需要先读入内存）
+const MaxAsyncBatchFiles = 20
+
+// BatchFileResult 批量上传中单个文件的处理结果
+type BatchFileResult struct {
+	FileName string        `json:"file_name"`
+	Status   string        `json:"status"` // pending/completed/failed
+	Result   *UploadResult `json:"result,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// UploadFilesAsync 异步批量上传，立即返回任务ID
+// 文件在请求内读入内存（请求结束后临时文件会被清理），随后在后台逐个处理，
+// 进度（done/total）和每个文件的结果（results）写入任务状态，通过 CheckTaskStatus 查询
+func (fu *FileUploader) UploadFilesAsync(c *gin.Context, fieldName string) (string, int, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to get multipart form: %w", err)
+	}
+
+	files := form.File[fieldName]
+	if len(files) == 0 {
+		return "", 0, fmt.Errorf("no files found for field: %s", fieldName)
+	}
+	if len(files) > MaxAsyncBatchFiles {
+		return "", 0, fmt.Errorf("too many files, maximum is %d", MaxAsyncBatchFiles)
+	}
+
+	var totalSize int64
+	for _, file := range files {
+		totalSize += file.Size
+	}
+	userID, ip := c.GetString("user_id"), c.ClientIP()
+	if err := fu.ReserveQuota(c.Request.Context(), userID, len(files), totalSize); err != nil {
+		return "", 0, err
+	}
+
+	// 校验不通过的文件直接标记为失败，不影响其他文件
+	results := make([]BatchFileResult, len(files))
+	contents := make([][]byte, len(files))
+	for i, file := range files {
+		results[i] = BatchFileResult{FileName: file.Filename, Status: "pending"}
+		data, err := fu.readFormFile(file)
+		if err != nil {
+			fu.ReleaseQuota(c.Request.Context(), userID, 1, file.Size)
+			results[i].Status = "failed"
+			results[i].Error = err.Error()
+			continue
+		}
+		contents[i] = data
+	}
+
+	taskID := CreateTask(fu.redisClient, userID, "running")
+	total := int64(len(files))
+	UpdateTaskProgress(fu.redisClient, taskID, 0, total)
+	UpdateTaskResults(fu.redisClient, taskID, results)
+
+	// 上传在请求结束后继续执行
+	ctx := context.WithoutCancel(c.Request.Context())
+	go func() {
+		startTime := time.Now()
+		var done, failed int64
+		for i := range results {
+			if contents[i] != nil {
+				result, err := fu.saveData(ctx, results[i].FileName, contents[i])
+				size := int64(len(contents[i]))
+				contents[i] = nil
+				if err != nil {
+					fu.ReleaseQuota(ctx, userID, 1, size)
+					fu.logInfectedUpload(userID, ip, err)
+					results[i].Status = "failed"
+					results[i].Error = err.Error()
+				} else {
+					runUploadHooks(ctx, userID, result)
+					results[i].Status = "completed"
+					results[i].Result = result
+				}
+			}
+			if results[i].Status == "failed" {
+				failed++
+			}
+
+			done++
+			UpdateTaskResults(fu.redisClient, taskID, results)
+			UpdateTaskProgress(fu.redisClient, taskID, done, total)
+		}
+
+		var err error
+		if failed == total {
+			err = fmt.Errorf("all %d upload(s) failed", failed)
+		}
+		FinishTask(fu.redisClient, taskID, startTime, err, map[string]interface{}{
+			"succeeded": total - failed,
+			"failed":    failed,
+		})
+	}()
+
+	return taskID, len(files), nil
+}
+
+// readFormFile 校验并读取表单文件
+func (fu *FileUploader) readFormFile(file *multipart.FileHeader) ([]byte, error) {
+	if err := fu.ValidateFile(file.Filename, file.Size); err != nil {
+		return nil, err
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer src.Close()
+
+	// 读入内存（大小已校验）
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return data, nil
+}
+
+// ValidateFile 校验文件大小和格式
+func (fu *FileUploader) ValidateFile(fileName string, size int64) error {
+	if size > fu.config.MaxFileSize {
+		return fmt.Errorf("file size exceeds maximum allowed size of %d bytes", fu.config.MaxFileSize)
+	}
+
+	ext := strings.ToLower(filepath.Ext(fileName))
+	if !fu.isAllowedFormat(ext) {
+		return fmt.Errorf("file format %s is not allowed", ext)
+	}
+	return nil
+}
+
+// SaveData 保存已读入内存的文件（例如合并后的分片上传）并执行上传回调
+func (fu *FileUploader) SaveData(ctx context.Context, userID, fileName string, data []byte) (*UploadResult, error) {
+	if err := fu.ValidateFile(fileName, int64(len(data))); err != nil {
+		return nil, err
+	}
+	if err := fu.ReserveQuota(ctx, userID, 1, int64(len(data))); err != nil {
+		return nil, err
+	}
+
+	result, err := fu.saveData(ctx, fileName, data)
+	if err != nil {
+		fu.ReleaseQuota(ctx, userID, 1, int64(len(data)))
+		fu.logInfectedUpload(userID, "", err)
+		return nil, err
+	}
+
+	runUploadHooks(ctx, userID, result)
+	return result, nil
+}
+
+// saveFile 校验并写入存储
+func (fu *FileUploader) saveFile(ctx context.Context, file *multipart.FileHeader) (*UploadResult, error) {
+	data, err := fu.readFormFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return fu.saveData(ctx, file.Filename, data)
+}
+
+// validateContent 按文件内容检测真实类型，必须与扩展名一致，并检查图片尺寸
+// 返回检测到的 Content-Type（不信任客户端提供的类型）
+func (fu *FileUploader) validateContent(fileName string, data []byte) (string, error) {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	detected := http.DetectContentType(data)
+	if expected, ok := allowedMimeTypes[ext]; !ok || detected != expected {
+		return "", fmt.Errorf("file content (%s) does not match extension %s", detected, ext)
+	}
+
+	// 只解析文件头获取尺寸，避免完整解码超大图片
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("invalid image file: %w", err)
+	}
+	if fu.config.MinDimension > 0 && (cfg.Width < fu.config.MinDimension || cfg.Height < fu.config.MinDimension) {
+		return "", fmt.Errorf("image is too small (%dx%d), minimum is %dx%d", cfg.Width, cfg.Height, fu.config.MinDimension, fu.config.MinDimension)
+	}
+	if fu.config.MaxDimension > 0 && (cfg.Width > fu.config.MaxDimension || cfg.Height > fu.config.MaxDimension) {
+		return "", fmt.Errorf("image is too large (%dx%d), maximum is %dx%d", cfg.Width, cfg.Height, fu.config.MaxDimension, fu.config.MaxDimension)
+	}
+
+	return detected, nil
+}
+
+// saveData 处理图片（去除元数据、转换格式、生成缩略图）并写入存储
+func (fu *FileUploader) saveData(ctx context.Context, originalName string, data []byte) (*UploadResult, error) {
+	contentType, err := fu.validateContent(originalName, data)
+	if err != nil {
+		return nil, err
+	}
+
+	// 解码图片，按 EXIF 方向信息校正
+	img, info, err := DecodeImage(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image file: %w", err)
+	}
+
+	// 生成文件名，按日期分目录存放
+	fileName := generateFileName(originalName)
+	key := fmt.Sprintf("%s/%s", time.Now().Format("2006/01/02"), fileName)
+	if fu.private {
+		key = PrivatePrefix + key
+	}
+	stored := data
+
+	// 重新编码以去除 EXIF 元数据（手机照片中的 GPS 坐标等）
+	if IsSanitizable(info.Format) {
+		stored, contentType, err = EncodeImage(img, info.Format, fu.config.WebPQuality)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := &UploadResult{
+		Width:  info.Width,
+		Height: info.Height,
+	}
+
+	// JPEG/PNG 转换为 WebP，转换失败或体积没有变小时按原格式保存
+	if fu.config.ConvertToWebP && IsWebPConvertible(info.Format) {
+		converted, err := EncodeWebP(img, fu.config.WebPQuality)
+		if err != nil {
+			log.Printf("Failed to convert %s to webp: %v", key, err)
+		} else if len(converted) < len(stored) {
+			// 保留的原图同样是去除元数据后的版本
+			if fu.config.KeepOriginal {
+				sourceURL, err := fu.storage.Put(ctx, key, bytes.NewReader(stored), int64(len(stored)), contentType)
+				if err != nil {
+					return nil, err
+				}
+				result.SourceURL = sourceURL
+			}
+			key = strings.TrimSuffix(key, filepath.Ext(key)) + ".webp"
+			fileName = strings.TrimSuffix(fileName, filepath.Ext(fileName)) + ".webp"
+			contentType = "image/webp"
+			stored = converted
+		}
+	}
+
+	url, err := fu.storage.Put(ctx, key, bytes.NewReader(stored), int64(len(stored)), contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	// 病毒扫描（扫描原始上传内容），命中时删除已保存的文件并拒绝上传
+	if err := fu.scan(ctx, originalName, data); err != nil {
+		fu.DeleteFile(key)
+		return nil, err
+	}
+
+	result.OriginalURL = url
+	result.Key = key
+	result.ContentType = contentType
+	result.FileSize = int64(len(stored))
+	result.FileName = fileName
+
+	// 私有文件返回签名URL
+	if fu.private {
+		signed, err := fu.storage.SignedURL(ctx, key, PrivateURLTTL)
+		if err != nil {
+			return nil, err
+		}
+		result.OriginalURL = signed
+		result.Private = true
+		result.Purpose = fu.purpose
+		result.ExpiresAt = time.Now().Add(PrivateURLTTL).Unix()
+	}
+
+	// 生成缩略图，失败时不影响原图上传
+	if fu.config.GenerateThumb && !fu.private {
+		thumbURL, err := PutThumbnail(ctx, fu.storage, key, img, info.Format, fu.config.ThumbWidth, fu.config.ThumbHeight, fu.thumbWebPQuality())
+		if err != nil {
+			log.Printf("Failed to generate thumbnail for %s: %v", key, err)
+		}
+		result.ThumbURL = thumbURL
+	}
+
+	// 异步缓存文件信息到Redis
+	if fu.config.UseRedisCache && fu.redisClient != nil {
+		go fu.cacheFileMetadata(key, result)
+	}
+
+	return result, nil
+}
+
+// scan 使用配置的扫描器扫描文件
+// 扫描服务不可用时默认放行，CLAMD_FAIL_CLOSED=true 时拒绝上传
+func (fu *FileUploader) scan(ctx context.Context, fileName string, data []byte) error {
+	if fu.scanner == nil {
+		return nil
+	}
+
+	signature, err := fu.scanner.Scan(ctx, data)
+	if err != nil {
+		log.Printf("Virus scan failed for %s: %v", fileName, err)
+		if fu.failClosed {
+			return fmt.Errorf("virus scan unavailable: %w", err)
+		}
+		return nil
+	}
+	if signature != "" {
+		return &InfectedFileError{FileName: fileName, Signature: signature}
+	}
+	return nil
+}
+
+// logInfectedUpload 病毒扫描命中时记录安全事件
+func (fu *FileUploader) logInfectedUpload(userID, ip string, err error) {
+	var infected *InfectedFileError
+	if !errors.As(err, &infected) {
+		return
+	}
+	log.Printf("Rejected infected upload %s from user %s: %s", infected.FileName, userID, infected.Signature)
+	LogSecurityEvent(fu.redisClient, "upload_infected", map[string]interface{}{
+		"user_id":   userID,
+		"ip":        ip,
+		"file_name": infected.FileName,
+		"signature": infected.Signature,
+	})
+}
+
+// CreateThumbnail 为已存储的图片生成缩略图，返回缩略图URL
+func (fu *FileUploader) CreateThumbnail(ctx context.Context, key string, data []byte) (string, error) {
+	thumbURL, _, err := StoreThumbnail(ctx, fu.storage, key, data, fu.config.ThumbWidth, fu.config.ThumbHeight, fu.thumbWebPQuality())
+	return thumbURL, err
+}
+
+// thumbWebPQuality 开启 WebP 转换时缩略图也使用 WebP
+func (fu *FileUploader) thumbWebPQuality() int {
+	if fu.config.ConvertToWebP {
+		return fu.config.WebPQuality
+	}
+	return 0
+}
+
 // cacheFileMetadata 缓存文件元数据到Redis
 func (fu *FileUploader) cacheFileMetadata(fileName string, result *UploadResult) {
-	if config.RedisClient == nil {
+	if fu.redisClient == nil {
 		return
 	}
 
@@ -289,26 +639,27 @@ func (fu *FileUploader) cacheFileMetadata(fileName string, result *UploadResult)
 
 	metadata := map[string]interface{}{
 		"original_url": result.OriginalURL,
+		"thumb_url":    result.ThumbURL,
 		"file_size":    result.FileSize,
 		"file_name":    result.FileName,
 		"cached_at":    time.Now().Unix(),
 	}
 
 	// 设置过期时间（24小时）
-	config.RedisClient.HSet(ctx, key, metadata)
-	config.RedisClient.Expire(ctx, key, 24*time.Hour)
+	fu.redisClient.HSet(ctx, key, metadata)
+	fu.redisClient.Expire(ctx, key, 24*time.Hour)
 }
 
 // GetFileMetadata 从Redis获取文件元数据
 func (fu *FileUploader) GetFileMetadata(fileName string) (map[string]string, error) {
-	if config.RedisClient == nil {
+	if fu.redisClient == nil {
 		return nil, fmt.Errorf("redis not available")
 	}
 
 	ctx := context.Background()
 	key := fmt.Sprintf("file:metadata:%s", fileName)
 
-	return config.RedisClient.HGetAll(ctx, key).Result()
+	return fu.redisClient.HGetAll(ctx, key).Result()
 }
 
 // isAllowedFormat 检查文件格式是否允许
@@ -331,27 +682,29 @@ func generateFileName(originalName string) string {
 }
 
 // DeleteFile 删除文件
-func (fu *FileUploader) DeleteFile(fileName string) error {
-	filePath := filepath.Join(fu.config.UploadPath, fileName)
-
-	// 删除文件
-	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+func (fu *FileUploader) DeleteFile(key string) error {
+	if err := fu.storage.Delete(context.Background(), key); err != nil {
 		return fmt.Errorf("failed to delete file: %w", err)
 	}
 
+	// 同时删除缩略图和转换前保留的原图
+	for _, thumbKey := range append(ThumbnailKeys(key), SourceKeys(key)...) {
+		fu.storage.Delete(context.Background(), thumbKey)
+	}
+
 	// 删除Redis缓存
-	if fu.config.UseRedisCache && config.RedisClient != nil {
+	if fu.config.UseRedisCache && fu.redisClient != nil {
 		go func() {
 			ctx := context.Background()
-			key := fmt.Sprintf("file:metadata:%s", fileName)
-			config.RedisClient.Del(ctx, key)
+			key := fmt.Sprintf("file:metadata:%s", key)
+			fu.redisClient.Del(ctx, key)
 		}()
 	}
 
 	return nil
 }
 
-// GetFileStats 获取文件统计信息
+// GetFileStats 获取文件统计信息（仅统计本地上传目录）
 func (fu *FileUploader) GetFileStats() map[string]interface{} {
 	var totalSize int64
 	var fileCount int
@@ -378,7 +731,7 @@ func (fu *FileUploader) GetFileStats() map[string]interface{} {
 	}
 }
 
-// CleanupOldFiles 清理旧文件（异步任务）
+// CleanupOldFiles 清理旧文件（异步任务，仅清理本地上传目录）
 func (fu *FileUploader) CleanupOldFiles(days int) error {
 	cutoffTime := time.Now().AddDate(0, 0, -days)
 	var deletedCount int
@@ -3,27 +3,53 @@ package utils
 import (
 	"errors"
 	"fmt"
+	"net/http"
+	"reflect"
 	"regexp"
+	"strings"
+	"sync"
 	"unicode"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
 )
 
 var (
-	validate = validator.New()
-	// 自定义验证错误缓存
-	validationErrorsCache = make(map[string]string)
+	// validate 与 Gin 绑定时使用同一个实例，binding 标签中的自定义规则才会生效
+	validate = ginValidator()
+	// 自定义验证错误缓存，key 为 字段_规则_参数
+	validationErrorsCache sync.Map
 )
 
 // 初始化验证器
 func init() {
+	// 错误信息中使用 json 字段名，与请求体保持一致
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" || name == "" {
+			return field.Name
+		}
+		return name
+	})
+
 	// 注册自定义验证规则
 	validate.RegisterValidation("password", validatePassword)
 	validate.RegisterValidation("username", validateUsername)
 	validate.RegisterValidation("isbn", validateISBN)
 }
 
+// ginValidator 返回 Gin 默认绑定器内部的验证器实例
+func ginValidator() *validator.Validate {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.SetTagName("binding")
+		return v
+	}
+	v := validator.New()
+	v.SetTagName("binding")
+	return v
+}
+
 // Validator 验证器结构
 type Validator struct {
 	validator *validator.Validate
@@ -48,83 +74,71 @@ func (v *Validator) Validate(obj interface{}) error {
 	return nil
 }
 
-// formatValidationErrors 格式化验证错误信息
+// formatValidationErrors 格式化验证错误信息，默认语言的文案写入 Errors
 func formatValidationErrors(errors []validator.FieldError) error {
-	errorMap := make(map[string]string)
+	ve := &ValidationError{fields: errors}
+	ve.Errors = ve.messages(DefaultLang())
+	return ve
+}
 
-	for _, err := range errors {
+// ValidationError 验证错误结构，由 middleware.ErrorHandler 返回422，data.errors 为 字段->错误信息
+type ValidationError struct {
+	Errors map[string]string `json:"errors"`
+	fields []validator.FieldError
+}
+
+func (ve *ValidationError) Error() string {
+	return fmt.Sprintf("Validation failed: %v", ve.Errors)
+}
+
+// Localize 返回指定语言的验证错误
+func (ve *ValidationError) Localize(lang string) *ValidationError {
+	if len(ve.fields) == 0 {
+		return ve
+	}
+	return &ValidationError{Errors: ve.messages(lang), fields: ve.fields}
+}
+
+// messages 生成每个字段的错误信息
+func (ve *ValidationError) messages(lang string) map[string]string {
+	errorMap := make(map[string]string, len(ve.fields))
+
+	for _, err := range ve.fields {
 		field := err.Field()
 		tag := err.Tag()
 		param := err.Param()
 
 		// 先尝试从缓存中获取错误信息
-		cacheKey := fmt.Sprintf("%s_%s", field, tag)
-		if msg, exists := validationErrorsCache[cacheKey]; exists {
-			errorMap[field] = msg
+		cacheKey := fmt.Sprintf("%s_%s_%s_%s", lang, field, tag, param)
+		if msg, exists := validationErrorsCache.Load(cacheKey); exists {
+			errorMap[field] = msg.(string)
 			continue
 		}
 
 		// 生成自定义错误信息
-		msg := getErrorMessage(field, tag, param)
-		validationErrorsCache[cacheKey] = msg
+		msg := getErrorMessage(lang, field, tag, param)
+		validationErrorsCache.Store(cacheKey, msg)
 		errorMap[field] = msg
 	}
 
-	return &ValidationError{Errors: errorMap}
+	return errorMap
 }
 
-// ValidationError 验证错误结构
-type ValidationError struct {
-	Errors map[string]string `json:"errors"`
-}
-
-func (ve *ValidationError) Error() string {
-	return fmt.Sprintf("Validation failed: %v", ve.Errors)
-}
-
-// getErrorMessage 获取错误消息
-func getErrorMessage(field, tag, param string) string {
-	// 中文错误消息映射
-	errorMessages := map[string]string{
-		"required": "%s不能为空",
-		"email":    "%s格式不正确",
-		"min":      "%s长度不能小于%s",
-		"max":      "%s长度不能大于%s",
-		"gt":       "%s必须大于%s",
-		"gte":      "%s必须大于或等于%s",
-		"lt":       "%s必须小于%s",
-		"lte":      "%s必须小于或等于%s",
-		"oneof":    "%s必须是以下值之一: %s",
-		"alpha":    "%s只能包含字母",
-		"alphanum": "%s只能包含字母和数字",
-		"numeric":  "%s必须是数字",
-		"e164":     "%s必须是有效的手机号",
-		"password": "%s格式不正确，必须包含大小写字母、数字和特殊字符",
-		"username": "%s只能包含字母、数字和下划线，且以字母开头",
-		"isbn":     "%s格式不正确",
-	}
-
-	fieldNames := map[string]string{
-		"username": "用户名",
-		"email":    "邮箱",
-		"password": "密码",
-		"phone":    "手机号",
-		"title":    "标题",
-		"price":    "价格",
-		"content":  "内容",
-	}
-
-	fieldName, _ := fieldNames[field]
-	if fieldName == "" {
+// getErrorMessage 获取错误消息，规则和字段名的文案来自消息目录
+func getErrorMessage(lang, field, tag, param string) string {
+	fieldName := T(lang, "field."+field)
+	if fieldName == "field."+field {
 		fieldName = field
 	}
 
-	template, exists := errorMessages[tag]
-	if !exists {
-		return fmt.Sprintf("%s验证失败", fieldName)
+	template := T(lang, "validation."+tag)
+	if template == "validation."+tag {
+		template = T(lang, "validation.default")
 	}
 
-	return fmt.Sprintf(template, fieldName, param)
+	// 部分规则的文案不需要参数
+	args := []interface{}{fieldName, param}
+	return fmt.Sprintf(template, args[:min(strings.Count(template, "%s"), len(args))]...)
 }
 
 // 自定义验证规则
@@ -162,8 +176,11 @@ func validatePassword(fl validator.FieldLevel) bool {
 
 // validateUsername 用户名验证
 func validateUsername(fl validator.FieldLevel) bool {
-	username := fl.Field().String()
+	return ValidUsername(fl.Field().String())
+}
 
+// ValidUsername 用户名是否符合格式：3-20位，字母开头，只包含字母、数字和下划线
+func ValidUsername(username string) bool {
 	if len(username) < 3 || len(username) > 20 {
 		return false
 	}
@@ -181,27 +198,25 @@ func validateISBN(fl validator.FieldLevel) bool {
 		return true // 允许为空
 	}
 
-	// ISBN-10 或 ISBN-13
-	isbn10Regex := `^(?:\d[\d-]{8}[\dX])$`
-	isbn13Regex := `^(?:\d[\d-]{12}[\dX])$`
-
-	matched10, _ := regexp.MatchString(isbn10Regex, isbn)
-	matched13, _ := regexp.MatchString(isbn13Regex, isbn)
+	// ISBN-10 或 ISBN-13，允许用连字符或空格分组
+	digits := strings.NewReplacer("-", "", " ", "").Replace(isbn)
+	matched10, _ := regexp.MatchString(`^\d{9}[\dXx]$`, digits)
+	matched13, _ := regexp.MatchString(`^\d{13}$`, digits)
 
 	return matched10 || matched13
 }
 
-// BindAndValidate 绑定并验证请求
+// BindAndValidate 绑定并验证JSON请求体
+// 校验失败返回 *ValidationError（字段级错误信息），请求体无法解析返回400的 AppError，
+// 控制器直接 c.Error(err) 即可
 func BindAndValidate(c *gin.Context, obj interface{}) error {
 	if err := c.ShouldBindJSON(obj); err != nil {
-		return err
-	}
-
-	v := NewValidator()
-	if err := v.Validate(obj); err != nil {
-		return err
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			return formatValidationErrors(validationErrors)
+		}
+		return WrapError(http.StatusBadRequest, err.Error(), err)
 	}
-
 	return nil
 }
 
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type validatorTestRequest struct {
+	Username string `json:"username" binding:"required,username"`
+	Password string `json:"password" binding:"required,password"`
+	ISBN     string `json:"isbn" binding:"omitempty,isbn"`
+}
+
+func bindTestRequest(t *testing.T, body string) error {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	var req validatorTestRequest
+	return BindAndValidate(c, &req)
+}
+
+// 自定义规则通过 Gin 绑定生效，错误信息按 json 字段名给出
+func TestBindAndValidate(t *testing.T) {
+	if err := bindTestRequest(t, `{"username":"reader_01","password":"Passw0rd!","isbn":"978-7-111-54742-6"}`); err != nil {
+		t.Fatalf("expected valid request, got %v", err)
+	}
+
+	err := bindTestRequest(t, `{"username":"1reader","password":"password","isbn":"12345"}`)
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+	for _, field := range []string{"username", "password", "isbn"} {
+		if validationErr.Errors[field] == "" {
+			t.Fatalf("expected error for %s, got %v", field, validationErr.Errors)
+		}
+	}
+
+	if got := validationErr.Localize(LangEn).Errors["password"]; !strings.HasPrefix(got, "Password must be") {
+		t.Fatalf("expected english message, got %s", got)
+	}
+	if got := validationErr.Localize(LangZhCN).Errors["username"]; got != "用户名只能包含字母、数字和下划线，且以字母开头" {
+		t.Fatalf("unexpected chinese message: %s", got)
+	}
+
+	if err := bindTestRequest(t, `{"username":`); ErrorStatus(err) != http.StatusBadRequest {
+		t.Fatalf("expected 400 for malformed body, got %v", err)
+	}
+}
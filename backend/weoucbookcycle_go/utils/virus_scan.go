@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+	"weoucbookcycle_go/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// clamdChunkSize INSTREAM 每次发送的数据块大小
+const clamdChunkSize = 64 * 1024
+
+// ErrInfectedFile 文件未通过病毒扫描
+var ErrInfectedFile = NewError(http.StatusUnprocessableEntity, "file is infected")
+
+// InfectedFileError 病毒扫描命中，Signature 为病毒特征名
+type InfectedFileError struct {
+	FileName  string
+	Signature string
+}
+
+func (e *InfectedFileError) Error() string {
+	return fmt.Sprintf("%s: %s (%s)", ErrInfectedFile, e.FileName, e.Signature)
+}
+
+// Unwrap 支持 errors.Is(err, ErrInfectedFile)
+func (e *InfectedFileError) Unwrap() error {
+	return ErrInfectedFile
+}
+
+// VirusScanner 病毒扫描接口，返回命中的病毒特征名，未命中时返回空字符串
+type VirusScanner interface {
+	Scan(ctx context.Context, data []byte) (string, error)
+}
+
+// NewVirusScanner 按 CLAMD_ADDR（例如 localhost:3310）创建 clamd 扫描器，未配置时返回nil
+func NewVirusScanner(cfg *config.UploadConfig) VirusScanner {
+	if cfg.ClamdAddr == "" {
+		return nil
+	}
+	return &ClamdScanner{
+		Addr:    cfg.ClamdAddr,
+		Timeout: time.Duration(cfg.ClamdTimeoutSeconds) * time.Second,
+	}
+}
+
+// ClamdScanner 通过 TCP 连接 clamd，使用 INSTREAM 命令扫描
+type ClamdScanner struct {
+	Addr    string
+	Timeout time.Duration
+}
+
+// Scan 扫描数据
+func (cs *ClamdScanner) Scan(ctx context.Context, data []byte) (string, error) {
+	dialer := net.Dialer{Timeout: cs.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", cs.Addr)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(cs.Timeout))
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return "", fmt.Errorf("failed to send clamd command: %w", err)
+	}
+
+	// 数据按块发送：4字节大端长度 + 数据，长度为0表示结束
+	var size [4]byte
+	reader := bytes.NewReader(data)
+	buf := make([]byte, clamdChunkSize)
+	for {
+		n, readErr := reader.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size[:], uint32(n))
+			if _, err := conn.Write(size[:]); err != nil {
+				return "", fmt.Errorf("failed to stream to clamd: %w", err)
+			}
+			if _, err := conn.Write(buf[:n]); err != nil {
+				return "", fmt.Errorf("failed to stream to clamd: %w", err)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+	}
+	binary.BigEndian.PutUint32(size[:], 0)
+	if _, err := conn.Write(size[:]); err != nil {
+		return "", fmt.Errorf("failed to stream to clamd: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+	return parseClamdReply(reply)
+}
+
+// parseClamdReply 解析 clamd 回复：stream: OK / stream: <name> FOUND / <msg> ERROR
+func parseClamdReply(reply string) (string, error) {
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+	reply = strings.TrimPrefix(reply, "stream: ")
+
+	switch {
+	case reply == "OK":
+		return "", nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return strings.TrimSuffix(reply, " FOUND"), nil
+	default:
+		return "", fmt.Errorf("clamd error: %s", reply)
+	}
+}
+
+// LogSecurityEvent 把安全事件写入 security_events 流
+func LogSecurityEvent(redisClient *redis.Client, event string, values map[string]interface{}) {
+	if redisClient == nil {
+		return
+	}
+
+	fields := map[string]interface{}{
+		"event":     event,
+		"timestamp": time.Now().Unix(),
+	}
+	for k, v := range values {
+		fields[k] = v
+	}
+
+	redisClient.XAdd(context.Background(), &redis.XAddArgs{
+		Stream: "security_events",
+		Values: fields,
+	})
+}
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"weoucbookcycle_go/config"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+)
+
+// WebPushSender 浏览器 Web Push 发送器（VAPID）
+type WebPushSender struct {
+	publicKey  string
+	privateKey string
+	subject    string
+}
+
+// NewWebPushSender 按 VAPID_PUBLIC_KEY / VAPID_PRIVATE_KEY / VAPID_SUBJECT 创建发送器，未配置时返回nil
+// 密钥可用 webpush.GenerateVAPIDKeys 生成，subject 为联系方式（mailto: 或 https:）
+func NewWebPushSender(cfg *config.PushConfig) *WebPushSender {
+	if cfg.VAPIDPublicKey == "" || cfg.VAPIDPrivateKey == "" {
+		return nil
+	}
+	return &WebPushSender{
+		publicKey:  cfg.VAPIDPublicKey,
+		privateKey: cfg.VAPIDPrivateKey,
+		subject:    cfg.VAPIDSubject,
+	}
+}
+
+// PublicKey 前端调用 pushManager.subscribe 时使用的 applicationServerKey
+func (s *WebPushSender) PublicKey() string {
+	return s.publicKey
+}
+
+// Send 发送推送，订阅已失效（404/410）时返回 ErrInvalidPushToken
+// 负载为 PushMessage 的JSON，由 Service Worker 解析后调用 showNotification
+func (s *WebPushSender) Send(ctx context.Context, endpoint, p256dh, auth string, msg *PushMessage) error {
+	payload, _ := json.Marshal(msg)
+
+	resp, err := webpush.SendNotificationWithContext(ctx, payload, &webpush.Subscription{
+		Endpoint: endpoint,
+		Keys:     webpush.Keys{P256dh: p256dh, Auth: auth},
+	}, &webpush.Options{
+		HTTPClient:      pushHTTPClient,
+		Subscriber:      s.subject,
+		VAPIDPublicKey:  s.publicKey,
+		VAPIDPrivateKey: s.privateKey,
+		TTL:             24 * 3600,
+		Urgency:         webpush.UrgencyHigh,
+	})
+	if err != nil {
+		return fmt.Errorf("web push request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < http.StatusMultipleChoices:
+		return nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrInvalidPushToken
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return fmt.Errorf("web push returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+}
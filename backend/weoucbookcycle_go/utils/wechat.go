@@ -0,0 +1,274 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"weoucbookcycle_go/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrWeChatNotSubscribed 用户未订阅该模板或订阅次数已用完（微信错误码 43101）
+var ErrWeChatNotSubscribed = errors.New("wechat user has not subscribed to this template")
+
+// wechatAccessTokenKey 小程序 access_token 在Redis中的缓存key（多实例共享，避免互相刷新导致失效）
+const wechatAccessTokenKey = "wechat:access_token"
+
+// WeChatTemplate 订阅消息模板
+// Fields 为模板字段到消息内容的映射，值可以是 title、body、time 或 PushMessage.Data 中的key
+type WeChatTemplate struct {
+	ID     string
+	Fields map[string]string
+}
+
+// WeChatSubscribeSender 微信小程序订阅消息发送器
+type WeChatSubscribeSender struct {
+	appID     string
+	secret    string
+	page      string                    // 点击消息后打开的小程序页面
+	state     string                    // 跳转的小程序版本：developer/trial/formal
+	templates map[string]WeChatTemplate // 通知类型 -> 模板
+
+	// redisClient 在多个实例间共享 access_token，为nil时只缓存在本进程
+	redisClient *redis.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewWeChatSubscribeSender 按环境变量创建订阅消息发送器，未配置任何模板时返回nil
+// WECHAT_TEMPLATE_CHAT / WECHAT_TEMPLATE_ORDER 为模板ID，
+// WECHAT_TEMPLATE_CHAT_FIELDS / WECHAT_TEMPLATE_ORDER_FIELDS 为字段映射，如 "thing1=title,thing2=body,time3=time"
+func NewWeChatSubscribeSender(cfg *config.WeChatConfig, redisClient *redis.Client) *WeChatSubscribeSender {
+	if cfg.AppID == "" || cfg.Secret == "" {
+		return nil
+	}
+
+	templates := make(map[string]WeChatTemplate)
+	if cfg.TemplateChat != "" {
+		templates["chat_message"] = WeChatTemplate{
+			ID:     cfg.TemplateChat,
+			Fields: parseTemplateFields(cfg.TemplateChatFields),
+		}
+	}
+	if cfg.TemplateOrder != "" {
+		templates["listing_status"] = WeChatTemplate{
+			ID:     cfg.TemplateOrder,
+			Fields: parseTemplateFields(cfg.TemplateOrderFields),
+		}
+	}
+	if len(templates) == 0 {
+		return nil
+	}
+
+	return &WeChatSubscribeSender{
+		appID:       cfg.AppID,
+		secret:      cfg.Secret,
+		page:        cfg.SubscribePage,
+		state:       cfg.MiniProgramState,
+		templates:   templates,
+		redisClient: redisClient,
+	}
+}
+
+// parseTemplateFields 解析 "thing1=title,thing2=body" 格式的字段映射
+func parseTemplateFields(spec string) map[string]string {
+	fields := make(map[string]string)
+	for _, pair := range strings.Split(spec, ",") {
+		key, source, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok && key != "" && source != "" {
+			fields[key] = source
+		}
+	}
+	return fields
+}
+
+// Supports 是否为该通知类型配置了模板
+func (s *WeChatSubscribeSender) Supports(notifType string) bool {
+	_, ok := s.templates[notifType]
+	return ok
+}
+
+// Send 向 openid 发送订阅消息，用户未订阅时返回 ErrWeChatNotSubscribed
+func (s *WeChatSubscribeSender) Send(ctx context.Context, openID, notifType string, msg *PushMessage) error {
+	tmpl, ok := s.templates[notifType]
+	if !ok {
+		return nil
+	}
+
+	data := make(map[string]map[string]string, len(tmpl.Fields))
+	for key, source := range tmpl.Fields {
+		data[key] = map[string]string{"value": templateValue(key, source, msg)}
+	}
+
+	page := s.page
+	if len(msg.Data) > 0 {
+		query := url.Values{}
+		for k, v := range msg.Data {
+			query.Set(k, v)
+		}
+		page += "?" + query.Encode()
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"touser":            openID,
+		"template_id":       tmpl.ID,
+		"page":              page,
+		"miniprogram_state": s.state,
+		"lang":              "zh_CN",
+		"data":              data,
+	})
+
+	err := s.send(ctx, body)
+	if errors.Is(err, errWeChatTokenExpired) {
+		// access_token 被其他实例刷新或提前失效，重新获取后重试一次
+		s.invalidateAccessToken()
+		err = s.send(ctx, body)
+	}
+	return err
+}
+
+var errWeChatTokenExpired = errors.New("wechat access token expired")
+
+// send 调用订阅消息接口
+func (s *WeChatSubscribeSender) send(ctx context.Context, body []byte) error {
+	accessToken, err := s.getAccessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	endpoint := "https://api.weixin.qq.com/cgi-bin/message/subscribe/send?access_token=" + url.QueryEscape(accessToken)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	status, respBody, err := doPushRequest(req)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("wechat returned %d: %s", status, respBody)
+	}
+
+	var resp struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err := json.Unmarshal([]byte(respBody), &resp); err != nil {
+		return fmt.Errorf("invalid wechat response: %w", err)
+	}
+	switch resp.ErrCode {
+	case 0:
+		return nil
+	case 43101:
+		return ErrWeChatNotSubscribed
+	case 40001, 42001:
+		return errWeChatTokenExpired
+	default:
+		return fmt.Errorf("wechat subscribe error %d: %s", resp.ErrCode, resp.ErrMsg)
+	}
+}
+
+// getAccessToken 获取小程序 access_token，优先使用Redis中的缓存
+func (s *WeChatSubscribeSender) getAccessToken(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.accessToken != "" && time.Now().Before(s.expiresAt) {
+		return s.accessToken, nil
+	}
+	if s.redisClient != nil {
+		if token, err := s.redisClient.Get(ctx, wechatAccessTokenKey).Result(); err == nil && token != "" {
+			s.accessToken = token
+			s.expiresAt = time.Now().Add(time.Minute)
+			return token, nil
+		}
+	}
+
+	endpoint := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/token?grant_type=client_credential&appid=%s&secret=%s",
+		url.QueryEscape(s.appID), url.QueryEscape(s.secret))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+
+	_, respBody, err := doPushRequest(req)
+	if err != nil {
+		return "", err
+	}
+
+	var resp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+		ErrCode     int    `json:"errcode"`
+		ErrMsg      string `json:"errmsg"`
+	}
+	if err := json.Unmarshal([]byte(respBody), &resp); err != nil {
+		return "", fmt.Errorf("invalid wechat token response: %w", err)
+	}
+	if resp.ErrCode != 0 || resp.AccessToken == "" {
+		return "", fmt.Errorf("wechat token error %d: %s", resp.ErrCode, resp.ErrMsg)
+	}
+
+	// 提前5分钟过期
+	ttl := time.Duration(resp.ExpiresIn)*time.Second - 5*time.Minute
+	if s.redisClient != nil {
+		s.redisClient.Set(ctx, wechatAccessTokenKey, resp.AccessToken, ttl)
+	}
+	s.accessToken = resp.AccessToken
+	s.expiresAt = time.Now().Add(ttl)
+	return s.accessToken, nil
+}
+
+// invalidateAccessToken 清除缓存的 access_token
+func (s *WeChatSubscribeSender) invalidateAccessToken() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.accessToken = ""
+	if s.redisClient != nil {
+		s.redisClient.Del(context.Background(), wechatAccessTokenKey)
+	}
+}
+
+// templateValue 按字段类型生成模板值，微信对 thing/phrase 等类型有长度限制
+func templateValue(key, source string, msg *PushMessage) string {
+	var value string
+	switch source {
+	case "title":
+		value = msg.Title
+	case "body":
+		value = msg.Body
+	case "time":
+		value = time.Now().Format("2006-01-02 15:04")
+	default:
+		value = msg.Data[source]
+	}
+
+	limit := 20
+	switch {
+	case strings.HasPrefix(key, "phrase"):
+		limit = 5
+	case strings.HasPrefix(key, "name"):
+		limit = 10
+	case strings.HasPrefix(key, "character_string"):
+		limit = 32
+	}
+
+	runes := []rune(value)
+	if len(runes) > limit {
+		return string(runes[:limit-1]) + "…"
+	}
+	return value
+}
@@ -8,11 +8,13 @@ import (
 	"net/http"
 	"sync"
 	"time"
-	"weoucbookcycle_go/config"
+	"weoucbookcycle_go/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
@@ -38,8 +40,8 @@ var (
 	broadcastQueue = make(chan *BroadcastMessage, 1000)
 
 	// Redis订阅
+	redisClient *redis.Client // 由 InitWebSocket 设置，为nil时只在本实例内广播
 	redisPubSub *redis.PubSub
-	redisCtx    = context.Background()
 )
 
 // Client WebSocket客户端
@@ -49,6 +51,8 @@ type Client struct {
 	Send       chan *WSMessage // 发送消息队列
 	ChatRooms  map[string]bool // 用户所在的聊天室
 	mu         sync.Mutex      // 客户端锁
+	// ctx 连接建立时的请求上下文（不随请求取消），用于该连接的Redis操作
+	ctx context.Context
 }
 
 // WSMessage WebSocket消息结构
@@ -73,20 +77,26 @@ type BroadcastMessage struct {
 	Type   string      `json:"type"`
 	ChatID string      `json:"chat_id"`
 	Data   interface{} `json:"data"`
+	// Trace 发送端的追踪上下文（W3C traceparent），经Redis转发后继续同一条trace
+	Trace map[string]string `json:"trace,omitempty"`
 }
 
-// InitWebSocket 初始化WebSocket服务
-func InitWebSocket() error {
+// InitWebSocket 初始化WebSocket服务，client 用于在线状态、未读数和多实例之间的消息转发
+// ctx 为后台订阅和心跳检测使用的上下文
+func InitWebSocket(ctx context.Context, client *redis.Client) error {
+	redisClient = client
+	utils.RegisterQueue("websocket.broadcast", broadcastQueue)
+
 	// 启动广播worker
 	go startBroadcastWorker()
 
 	// 启动Redis PubSub监听（用于多服务器场景）
-	if config.RedisClient != nil {
-		go subscribeToRedis()
+	if redisClient != nil {
+		go subscribeToRedis(ctx)
 	}
 
 	// 启动心跳检测
-	go heartbeatChecker()
+	go heartbeatChecker(ctx)
 
 	log.Println("✅ WebSocket service initialized")
 	return nil
@@ -96,7 +106,7 @@ func InitWebSocket() error {
 func HandleConnection(c *gin.Context) {
 	userID := c.Query("user_id")
 	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "User ID is required"})
+		c.Error(utils.NewError(http.StatusBadRequest, "User ID is required"))
 		return
 	}
 
@@ -113,6 +123,7 @@ func HandleConnection(c *gin.Context) {
 		Connection: conn,
 		Send:       make(chan *WSMessage, 256),
 		ChatRooms:  make(map[string]bool),
+		ctx:        context.WithoutCancel(c.Request.Context()),
 	}
 
 	// 添加到客户端列表
@@ -121,10 +132,10 @@ func HandleConnection(c *gin.Context) {
 	clientsMutex.Unlock()
 
 	// 设置用户在线状态到Redis
-	if config.RedisClient != nil {
+	if redisClient != nil {
 		go func() {
-			config.RedisClient.Set(redisCtx, "online:"+userID, "1", time.Minute*5)
-			config.RedisClient.SAdd(redisCtx, "online:users", userID)
+			redisClient.Set(client.ctx, "online:"+userID, "1", time.Minute*5)
+			redisClient.SAdd(client.ctx, "online:users", userID)
 		}()
 	}
 
@@ -250,11 +261,18 @@ func (c *Client) handleChatMessage(message *WSMessage) {
 		return
 	}
 
+	spanCtx, span := utils.StartSpan(context.Background(), "websocket.chat_message", trace.WithAttributes(
+		attribute.String("chat.id", message.ChatID),
+		attribute.String("user.id", c.ID),
+	))
+	defer span.End()
+
 	// 广播消息到聊天室
 	broadcastMessage := &BroadcastMessage{
 		Type:   "message",
 		ChatID: message.ChatID,
 		Data:   message,
+		Trace:  utils.InjectTrace(spanCtx),
 	}
 
 	// 放入广播队列
@@ -266,10 +284,10 @@ func (c *Client) handleChatMessage(message *WSMessage) {
 	}
 
 	// 同时发布到Redis（用于多服务器同步）
-	if config.RedisClient != nil {
+	if redisClient != nil {
 		go func() {
 			data, _ := json.Marshal(broadcastMessage)
-			config.RedisClient.Publish(redisCtx, "chat:broadcast", data)
+			redisClient.Publish(spanCtx, "chat:broadcast", data)
 		}()
 	}
 }
@@ -304,9 +322,9 @@ func (c *Client) handleReadMessage(message *WSMessage) {
 	}
 
 	// 清除Redis中的未读计数
-	if config.RedisClient != nil {
+	if redisClient != nil {
 		go func() {
-			config.RedisClient.Del(redisCtx, "unread:"+c.ID+":"+message.ChatID)
+			utils.ClearUnread(c.ctx, redisClient, c.ID, message.ChatID)
 		}()
 	}
 
@@ -372,36 +390,47 @@ func (c *Client) handleLeaveChat(message *WSMessage) {
 // startBroadcastWorker 启动广播worker
 func startBroadcastWorker() {
 	for broadcast := range broadcastQueue {
-		chatRoom, exists := getChatRoom(broadcast.ChatID)
-		if !exists {
-			continue
-		}
+		deliverBroadcast(broadcast)
+	}
+}
 
-		// 向聊天室中的所有客户端广播消息
-		chatRoom.mu.RLock()
-		defer chatRoom.mu.RUnlock()
+// deliverBroadcast 向聊天室中的所有客户端广播消息
+func deliverBroadcast(broadcast *BroadcastMessage) {
+	chatRoom, exists := getChatRoom(broadcast.ChatID)
+	if !exists {
+		return
+	}
 
-		var wg sync.WaitGroup
-		for _, client := range chatRoom.Clients {
-			wg.Add(1)
-			go func(c *Client, data interface{}) {
-				defer wg.Done()
-				select {
-				case c.Send <- &WSMessage{
-					Type:      broadcast.Type,
-					ChatID:    broadcast.ChatID,
-					Data:      data,
-					Timestamp: time.Now().Unix(),
-				}:
-				default:
-					// 发送队列满了，断开连接
-					log.Printf("Client %s send queue is full, closing connection", c.ID)
-					c.Connection.Close()
-				}
-			}(client, broadcast.Data)
-		}
-		wg.Wait()
+	_, span := utils.StartSpan(utils.ExtractTrace(broadcast.Trace), "websocket.broadcast", trace.WithAttributes(
+		attribute.String("chat.id", broadcast.ChatID),
+		attribute.String("broadcast.type", broadcast.Type),
+	))
+	defer span.End()
+
+	chatRoom.mu.RLock()
+	defer chatRoom.mu.RUnlock()
+	span.SetAttributes(attribute.Int("chat.clients", len(chatRoom.Clients)))
+
+	var wg sync.WaitGroup
+	for _, client := range chatRoom.Clients {
+		wg.Add(1)
+		go func(c *Client, data interface{}) {
+			defer wg.Done()
+			select {
+			case c.Send <- &WSMessage{
+				Type:      broadcast.Type,
+				ChatID:    broadcast.ChatID,
+				Data:      data,
+				Timestamp: time.Now().Unix(),
+			}:
+			default:
+				// 发送队列满了，断开连接
+				log.Printf("Client %s send queue is full, closing connection", c.ID)
+				c.Connection.Close()
+			}
+		}(client, broadcast.Data)
 	}
+	wg.Wait()
 }
 
 // getOrCreateChatRoom 获取或创建聊天室
@@ -435,8 +464,8 @@ func getChatRoom(chatID string) (*ChatRoom, bool) {
 }
 
 // subscribeToRedis 订阅Redis频道（多服务器同步）
-func subscribeToRedis() {
-	pubsub := config.RedisClient.Subscribe(redisCtx, "chat:broadcast")
+func subscribeToRedis(ctx context.Context) {
+	pubsub := redisClient.Subscribe(ctx, "chat:broadcast")
 	redisPubSub = pubsub
 
 	ch := pubsub.Channel()
@@ -455,7 +484,7 @@ func subscribeToRedis() {
 }
 
 // heartbeatChecker 心跳检测
-func heartbeatChecker() {
+func heartbeatChecker(ctx context.Context) {
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
 
@@ -484,9 +513,9 @@ func heartbeatChecker() {
 				delete(clients, userID)
 
 				// 更新Redis在线状态
-				if config.RedisClient != nil {
-					config.RedisClient.Del(redisCtx, "online:"+userID)
-					config.RedisClient.SRem(redisCtx, "online:users", userID)
+				if redisClient != nil {
+					redisClient.Del(ctx, "online:"+userID)
+					redisClient.SRem(ctx, "online:users", userID)
 				}
 			}
 		}
@@ -497,21 +526,17 @@ func heartbeatChecker() {
 
 // sendUnreadMessages 发送未读消息
 func (c *Client) sendUnreadMessages() {
-	if config.RedisClient == nil {
+	if redisClient == nil {
 		return
 	}
 
-	// 获取用户所有聊天室的未读消息
-	pattern := "unread:" + c.ID + ":*"
-	keys, _ := config.RedisClient.Keys(redisCtx, pattern).Result()
-
-	for _, key := range keys {
-		// 提取chatID
-		chatID := key[len("unread:"+c.ID+":"):]
+	// 获取用户有未读消息的聊天室
+	unread, _ := utils.GetAllUnread(c.ctx, redisClient, c.ID)
 
+	for chatID := range unread {
 		// 获取缓存的消息
 		cacheKey := "chat:" + chatID + ":last_messages"
-		cachedMessages, err := config.RedisClient.LRange(redisCtx, cacheKey, 0, -1).Result()
+		cachedMessages, err := redisClient.LRange(c.ctx, cacheKey, 0, -1).Result()
 		if err != nil {
 			continue
 		}
@@ -530,21 +555,21 @@ func (c *Client) sendUnreadMessages() {
 }
 
 // GetOnlineUsers 获取在线用户列表
-func GetOnlineUsers() ([]string, error) {
-	if config.RedisClient == nil {
+func GetOnlineUsers(ctx context.Context) ([]string, error) {
+	if redisClient == nil {
 		return nil, fmt.Errorf("redis not available")
 	}
 
-	return config.RedisClient.SMembers(redisCtx, "online:users").Result()
+	return redisClient.SMembers(ctx, "online:users").Result()
 }
 
 // GetOnlineUserCount 获取在线用户数
-func GetOnlineUserCount() (int64, error) {
-	if config.RedisClient == nil {
+func GetOnlineUserCount(ctx context.Context) (int64, error) {
+	if redisClient == nil {
 		return 0, fmt.Errorf("redis not available")
 	}
 
-	return config.RedisClient.SCard(redisCtx, "online:users").Result()
+	return redisClient.SCard(ctx, "online:users").Result()
 }
 
 // BroadcastToAll 广播消息给所有在线用户
@@ -578,8 +603,13 @@ func CloseWebSocket() {
 		redisPubSub.Close()
 	}
 
+	// 通知客户端服务器正在关闭，客户端收到 1001 后可以重连到其他实例
+	closeMessage := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	deadline := time.Now().Add(time.Second)
+
 	clientsMutex.Lock()
 	for _, client := range clients {
+		client.Connection.WriteControl(websocket.CloseMessage, closeMessage, deadline)
 		client.Connection.Close()
 	}
 	clientsMutex.Unlock()